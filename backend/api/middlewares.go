@@ -2,9 +2,28 @@ package api
 
 import (
 	"context"
-	"log"
+	"crypto/subtle"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+	v1 "todo_app_backend/api/v1"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
 	"todo_app_backend/internal/app/utils"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/websocket"
+)
+
+var (
+	errTokenNotFound     = services.NewUnauthorizedError("token_not_found", "authorization token not found")
+	errInvalidToken      = services.NewUnauthorizedError("invalid_token", "invalid authorization token")
+	errAdminRequired     = services.NewForbiddenError("admin_required", "admin privileges required")
+	errSessionRequired   = services.NewForbiddenError("session_required", "this endpoint cannot be used with an access token")
+	errInsufficientScope = services.NewForbiddenError("insufficient_scope", "access token lacks the required scope")
+	errInvalidSCIMToken  = services.NewUnauthorizedError("invalid_token", "invalid SCIM token")
 )
 
 // type UserKey string
@@ -20,55 +39,261 @@ func RespondJsonMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func UserOnlyMiddleware(h http.Handler) http.Handler {
+// TimeoutExcept applies middleware.Timeout to every request except those to
+// the long-lived streaming endpoints at paths.
+func TimeoutExcept(timeout time.Duration, paths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := middleware.Timeout(timeout)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, path := range paths {
+				if r.URL.Path == path {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			limited.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RealIP replaces the RemoteAddr of requests from trusted proxies with the
+// client address they forwarded in X-Forwarded-For or X-Real-IP. The
+// headers of other peers are ignored: clients could otherwise pick the
+// address they are throttled and audited by.
+func RealIP(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	trusted := func(addr netip.Addr) bool {
+		for _, prefix := range trustedProxies {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, err := netip.ParseAddrPort(r.RemoteAddr)
+			if err != nil || !trusted(peer.Addr().Unmap()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Proxies append the address they received the request from,
+			// so the client is the last address not added by one of ours
+			ip := peer.Addr().Unmap()
+			if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+				addrs := strings.Split(strings.Join(forwarded, ","), ",")
+				for i := len(addrs) - 1; i >= 0; i-- {
+					addr, err := netip.ParseAddr(strings.TrimSpace(addrs[i]))
+					if err != nil {
+						break
+					}
+					ip = addr.Unmap()
+					if !trusted(ip) {
+						break
+					}
+				}
+			} else if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+				ip = addr.Unmap()
+			}
+			r.RemoteAddr = ip.String()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequestInfo makes the client address and request ID available to the
+// services, which record them in the audit log. It must run after the
+// RequestID and RealIP middlewares.
+func RequestInfo(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// check for token in request header
-		tokenStr := r.Header.Get("Authorization")
-		if len(tokenStr) <= len("Bearer ") {
-			http.Error(w, "Unauthorized [token not found]", http.StatusUnauthorized)
-			return
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			ip = host
 		}
+		ctx := services.WithRequestInfo(r.Context(), services.RequestInfo{IP: ip, RequestID: middleware.GetReqID(r.Context())})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		log.Println("\n\n Token : ", tokenStr)
+// Authenticator provides the middlewares that authenticate requests.
+//
+// Authenticated requests carry "userID" in their context. Requests made with
+// a session token also carry "sessionID"; requests made with a personal
+// access token or an OAuth2 access token carry its "scopes" instead.
+type Authenticator struct {
+	SessionService     services.SessionServiceInterface
+	AccessTokenService services.AccessTokenServiceInterface
+	OAuthService       services.OAuthServiceInterface
+	UserService        services.UserServiceInterface
+	// SCIMToken is the bearer token of identity providers provisioning
+	// users over SCIM. SCIM is disabled when it is empty.
+	SCIMToken string
+}
 
-		tokenStr = tokenStr[len("Bearer "):]
-		payload, err := utils.ValidateToken(tokenStr)
-		if err != nil {
-			log.Println("\n\n Error Validating token : ", err)
-			http.Error(w, "Unauthorized [Invalid token]", http.StatusUnauthorized)
+// NewAuthenticator initializes a new Authenticator.
+func NewAuthenticator(sessionService services.SessionServiceInterface, accessTokenService services.AccessTokenServiceInterface, oauthService services.OAuthServiceInterface, userService services.UserServiceInterface) *Authenticator {
+	return &Authenticator{SessionService: sessionService, AccessTokenService: accessTokenService, OAuthService: oauthService, UserService: userService}
+}
+
+// UserOnly accepts requests carrying a session token whose session has not
+// been revoked, or a valid personal or OAuth2 access token.
+func (a *Authenticator) UserOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenStr, ok := bearerToken(r)
+		if !ok {
+			v1.RespondError(w, r, errTokenNotFound)
 			return
 		}
 
-		log.Println("\n\n Payload : ", payload)
-
-		ctx := context.WithValue(r.Context(), "userID", payload.UserID)
-		r = r.WithContext(ctx)
+		ctx, err := a.authenticateUser(r.Context(), tokenStr)
+		if err != nil {
+			v1.RespondError(w, r, err)
+			return
+		}
 
 		// call next handler
-		h.ServeHTTP(w, r)
+		h.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func AdminOnlyMiddleware(h http.Handler) http.Handler {
+// AdminOnly accepts requests carrying the admin token, or the credentials
+// of a user with admin privileges. Access tokens also need the admin scope.
+func (a *Authenticator) AdminOnly(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// check for token in request header
-		tokenStr := r.Header.Get("Authorization")
-		if len(tokenStr) <= len("Bearer ") {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		tokenStr, ok := bearerToken(r)
+		if !ok {
+			v1.RespondError(w, r, errTokenNotFound)
 			return
 		}
 
-		tokenStr = tokenStr[len("Bearer "):]
-		payload, err := utils.ValidateToken(tokenStr)
-		if err != nil || payload.UserID != -1 {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if payload, err := utils.ValidateToken(tokenStr); err == nil && payload.UserID == -1 && payload.Purpose == "" {
+			ctx := context.WithValue(r.Context(), "userID", payload.UserID)
+			h.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), "userID", payload.UserID)
-		r = r.WithContext(ctx)
+		ctx, err := a.authenticateUser(r.Context(), tokenStr)
+		if err != nil {
+			v1.RespondError(w, r, err)
+			return
+		}
+		if scopes, ok := ctx.Value("scopes").([]string); ok && !hasScope(scopes, models.ScopeAdmin) {
+			v1.RespondError(w, r, errInsufficientScope)
+			return
+		}
+
+		user, err := a.UserService.GetUserByID(ctx, ctx.Value("userID").(int))
+		if err != nil {
+			v1.RespondError(w, r, err)
+			return
+		}
+		if !user.IsAdmin {
+			v1.RespondError(w, r, errAdminRequired)
+			return
+		}
 
 		// call next handler
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticateUser validates a session, personal access or OAuth2 access
+// token and returns ctx extended with the identity it carries.
+func (a *Authenticator) authenticateUser(ctx context.Context, tokenStr string) (context.Context, error) {
+	switch {
+	case strings.HasPrefix(tokenStr, models.AccessTokenPrefix):
+		token, err := a.AccessTokenService.Authenticate(ctx, tokenStr)
+		if err != nil {
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, "userID", token.UserID)
+		return context.WithValue(ctx, "scopes", token.Scopes), nil
+	case strings.HasPrefix(tokenStr, models.OAuthAccessTokenPrefix):
+		token, err := a.OAuthService.AuthenticateAccessToken(ctx, tokenStr)
+		if err != nil {
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, "userID", token.UserID)
+		return context.WithValue(ctx, "scopes", token.Scopes), nil
+	}
+
+	payload, err := utils.ValidateToken(tokenStr)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	if err := a.SessionService.ValidateSession(ctx, payload); err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, "userID", payload.UserID)
+	return context.WithValue(ctx, "sessionID", payload.SessionID), nil
+}
+
+// SCIMOnly accepts requests carrying the SCIM token. Errors are reported in
+// the SCIM format.
+func (a *Authenticator) SCIMOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenStr, ok := bearerToken(r)
+		if !ok || a.SCIMToken == "" ||
+			subtle.ConstantTimeCompare([]byte(utils.HashToken(tokenStr)), []byte(utils.HashToken(a.SCIMToken))) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			v1.RespondSCIMError(w, r, errInvalidSCIMToken)
+			return
+		}
 		h.ServeHTTP(w, r)
 	})
 }
+
+// RequireSession rejects requests authenticated with an access token, so
+// that a leaked token cannot be used to take over the account.
+func RequireSession(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value("sessionID").(int); !ok {
+			v1.RespondError(w, r, errSessionRequired)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope rejects requests made with an access token that lacks scope.
+// Session tokens are not limited by scopes.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, ok := r.Context().Value("scopes").([]string); ok && !hasScope(scopes, scope) {
+				v1.RespondError(w, r, errInsufficientScope)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bearerToken returns the token of the Authorization header of r. Browsers
+// cannot set the header when opening a WebSocket, so the token of a
+// WebSocket handshake may be offered as a subprotocol instead.
+func bearerToken(r *http.Request) (string, bool) {
+	tokenStr := r.Header.Get("Authorization")
+	if len(tokenStr) <= len("Bearer ") {
+		if websocket.IsWebSocketUpgrade(r) {
+			for _, protocol := range websocket.Subprotocols(r) {
+				if token := strings.TrimPrefix(protocol, v1.WebSocketTokenPrefix); token != protocol && token != "" {
+					return token, true
+				}
+			}
+		}
+		return "", false
+	}
+	return tokenStr[len("Bearer "):], true
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
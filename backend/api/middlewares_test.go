@@ -0,0 +1,256 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	v1 "todo_app_backend/api/v1"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAccessTokens accepts the tokens in its map.
+type fakeAccessTokens map[string]*models.PersonalAccessToken
+
+func (f fakeAccessTokens) Authenticate(ctx context.Context, secret string) (*models.PersonalAccessToken, error) {
+	if token, ok := f[secret]; ok {
+		return token, nil
+	}
+	return nil, services.NewUnauthorizedError("invalid_token", "invalid authorization token")
+}
+
+func (f fakeAccessTokens) CreateToken(context.Context, int, string, []string, *time.Time) (*models.PersonalAccessToken, string, error) {
+	panic("not implemented")
+}
+
+func (f fakeAccessTokens) ListTokens(context.Context, int) ([]models.PersonalAccessToken, error) {
+	panic("not implemented")
+}
+
+func (f fakeAccessTokens) DeleteToken(context.Context, int, int) error {
+	panic("not implemented")
+}
+
+// fakeUsers is a UserServiceInterface that only knows users by ID.
+type fakeUsers struct {
+	services.UserServiceInterface
+	users map[int]*models.User
+}
+
+func (f fakeUsers) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	if user, ok := f.users[id]; ok {
+		return user, nil
+	}
+	return nil, services.NewNotFoundError("user_not_found", "user not found")
+}
+
+// fakeOAuth is an OAuthServiceInterface that only knows its access tokens.
+type fakeOAuth struct {
+	services.OAuthServiceInterface
+	tokens map[string]*models.OAuthToken
+}
+
+func (f fakeOAuth) AuthenticateAccessToken(ctx context.Context, secret string) (*models.OAuthToken, error) {
+	if token, ok := f.tokens[secret]; ok {
+		return token, nil
+	}
+	return nil, services.NewUnauthorizedError("invalid_token", "invalid authorization token")
+}
+
+func newTestAuthenticator() *Authenticator {
+	tokens := fakeAccessTokens{
+		models.AccessTokenPrefix + "reader":     {UserID: 1, Scopes: []string{models.ScopeTodosRead}},
+		models.AccessTokenPrefix + "admin":      {UserID: 2, Scopes: []string{models.ScopeAdmin}},
+		models.AccessTokenPrefix + "fake-admin": {UserID: 1, Scopes: []string{models.ScopeAdmin}},
+	}
+	oauth := fakeOAuth{tokens: map[string]*models.OAuthToken{
+		models.OAuthAccessTokenPrefix + "writer": {UserID: 1, Scopes: []string{models.ScopeTodosWrite}},
+	}}
+	users := fakeUsers{users: map[int]*models.User{1: {ID: 1}, 2: {ID: 2, IsAdmin: true}}}
+	return NewAuthenticator(nil, tokens, oauth, users)
+}
+
+func serve(handler http.Handler, token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestUserOnly_AccessTokenScopes(t *testing.T) {
+	auth := newTestAuthenticator()
+	read := auth.UserOnly(RequireScope(models.ScopeTodosRead)(okHandler))
+	write := auth.UserOnly(RequireScope(models.ScopeTodosWrite)(okHandler))
+	profile := auth.UserOnly(RequireSession(okHandler))
+
+	assert.Equal(t, http.StatusOK, serve(read, models.AccessTokenPrefix+"reader"))
+	assert.Equal(t, http.StatusForbidden, serve(write, models.AccessTokenPrefix+"reader"))
+	assert.Equal(t, http.StatusForbidden, serve(profile, models.AccessTokenPrefix+"reader"))
+	assert.Equal(t, http.StatusUnauthorized, serve(read, models.AccessTokenPrefix+"unknown"))
+	assert.Equal(t, http.StatusUnauthorized, serve(read, ""))
+}
+
+func TestAdminOnly_AccessToken(t *testing.T) {
+	auth := newTestAuthenticator()
+	admin := auth.AdminOnly(okHandler)
+
+	assert.Equal(t, http.StatusOK, serve(admin, models.AccessTokenPrefix+"admin"))
+	assert.Equal(t, http.StatusForbidden, serve(admin, models.AccessTokenPrefix+"fake-admin"), "admin scope requires an admin user")
+	assert.Equal(t, http.StatusForbidden, serve(admin, models.AccessTokenPrefix+"reader"))
+}
+
+func TestUserOnly_OAuthAccessTokenScopes(t *testing.T) {
+	auth := newTestAuthenticator()
+	read := auth.UserOnly(RequireScope(models.ScopeTodosRead)(okHandler))
+	write := auth.UserOnly(RequireScope(models.ScopeTodosWrite)(okHandler))
+	profile := auth.UserOnly(RequireSession(okHandler))
+	admin := auth.AdminOnly(okHandler)
+
+	assert.Equal(t, http.StatusOK, serve(write, models.OAuthAccessTokenPrefix+"writer"))
+	assert.Equal(t, http.StatusForbidden, serve(read, models.OAuthAccessTokenPrefix+"writer"))
+	assert.Equal(t, http.StatusForbidden, serve(profile, models.OAuthAccessTokenPrefix+"writer"), "apps cannot manage the account")
+	assert.Equal(t, http.StatusForbidden, serve(admin, models.OAuthAccessTokenPrefix+"writer"))
+	assert.Equal(t, http.StatusUnauthorized, serve(write, models.OAuthAccessTokenPrefix+"revoked"))
+}
+
+func TestSCIMOnly(t *testing.T) {
+	auth := newTestAuthenticator()
+	scimOnly := auth.SCIMOnly(okHandler)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(scimOnly, ""), "SCIM is disabled without a token")
+	assert.Equal(t, http.StatusUnauthorized, serve(scimOnly, "anything"))
+
+	auth.SCIMToken = "scim-secret"
+	assert.Equal(t, http.StatusOK, serve(scimOnly, "scim-secret"))
+	assert.Equal(t, http.StatusUnauthorized, serve(scimOnly, "scim-secret2"))
+	assert.Equal(t, http.StatusUnauthorized, serve(scimOnly, models.AccessTokenPrefix+"admin"), "user tokens cannot provision users")
+}
+
+// fakeSessionRepo keeps sessions in memory.
+type fakeSessionRepo struct {
+	sessions map[int]*models.Session
+}
+
+func (f *fakeSessionRepo) CreateSession(ctx context.Context, session *models.Session) error {
+	session.ID = len(f.sessions) + 1
+	f.sessions[session.ID] = session
+	return nil
+}
+
+func (f *fakeSessionRepo) GetSession(ctx context.Context, id int) (*models.Session, error) {
+	if session, ok := f.sessions[id]; ok {
+		return session, nil
+	}
+	return nil, repositories.ErrSessionNotFound
+}
+
+func (f *fakeSessionRepo) RevokeUserSessions(ctx context.Context, userID int, exceptID int) error {
+	return nil
+}
+
+func (f *fakeSessionRepo) ListActiveSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	return nil, nil
+}
+
+func (f *fakeSessionRepo) TouchSession(ctx context.Context, id int) error {
+	return nil
+}
+
+func (f *fakeSessionRepo) RevokeSession(ctx context.Context, userID int, id int) error {
+	session, ok := f.sessions[id]
+	if !ok || session.UserID != userID {
+		return repositories.ErrSessionNotFound
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	return nil
+}
+
+func TestUserOnly_RevokedSession(t *testing.T) {
+	t.Setenv("DB_URI", "postgres://test")
+	t.Setenv("MAX_IDLE_CONNS", "1")
+	t.Setenv("MAX_OPEN_CONNS", "1")
+
+	sessions := services.NewSessionService(&fakeSessionRepo{sessions: map[int]*models.Session{}})
+	auth := newTestAuthenticator()
+	auth.SessionService = sessions
+	handler := auth.UserOnly(okHandler)
+
+	token, err := sessions.StartSession(context.Background(), 1, "10.0.0.1", "curl/8.5.0")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serve(handler, token))
+
+	assert.NoError(t, sessions.RevokeSession(context.Background(), 1, 1))
+	assert.Equal(t, http.StatusUnauthorized, serve(handler, token), "revoked sessions are rejected immediately")
+}
+
+func TestTimeoutExcept(t *testing.T) {
+	hasDeadline := func(path string) bool {
+		var ok bool
+		handler := TimeoutExcept(time.Minute, "/api/v1/events")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok = r.Context().Deadline()
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		return ok
+	}
+
+	assert.True(t, hasDeadline("/api/v1/todos"))
+	assert.False(t, hasDeadline("/api/v1/events"), "streams are not cut off")
+}
+
+func TestRealIP(t *testing.T) {
+	handler := RealIP([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	clientIP := func(remoteAddr string, headers map[string]string) string {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+		req.RemoteAddr = remoteAddr
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Body.String()
+	}
+
+	assert.Equal(t, "203.0.113.9:4321", clientIP("203.0.113.9:4321", map[string]string{"X-Forwarded-For": "198.51.100.1"}),
+		"headers of untrusted peers are ignored")
+	assert.Equal(t, "203.0.113.9:4321", clientIP("203.0.113.9:4321", map[string]string{"X-Real-IP": "198.51.100.1"}))
+	assert.Equal(t, "198.51.100.1", clientIP("10.0.0.2:4321", map[string]string{"X-Real-IP": "198.51.100.1"}))
+	assert.Equal(t, "198.51.100.1", clientIP("10.0.0.2:4321", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 10.0.0.3"}),
+		"addresses prepended by the client are ignored")
+	assert.Equal(t, "10.0.0.2", clientIP("10.0.0.2:4321", map[string]string{"X-Forwarded-For": "not-an-ip"}))
+}
+
+func TestUserOnly_WebSocketProtocolToken(t *testing.T) {
+	auth := newTestAuthenticator()
+	handler := auth.UserOnly(okHandler)
+
+	handshake := func(protocols string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ws", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Protocol", protocols)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, handshake(v1.WebSocketProtocol+", "+v1.WebSocketTokenPrefix+models.AccessTokenPrefix+"reader"))
+	assert.Equal(t, http.StatusUnauthorized, handshake(v1.WebSocketProtocol+", "+v1.WebSocketTokenPrefix+models.AccessTokenPrefix+"unknown"))
+	assert.Equal(t, http.StatusUnauthorized, handshake(v1.WebSocketProtocol))
+}
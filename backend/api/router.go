@@ -2,22 +2,51 @@ package api
 
 import (
 	"net/http"
+	"net/netip"
 	"time"
 	v1 "todo_app_backend/api/v1"
+	"todo_app_backend/internal/app/models"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 )
 
-// SetupRouter initializes the API routes.
-func SetupRouter(userHandler v1.UserHandlerInterface, todoHandler v1.TodoHandlerInterface) http.Handler {
+// Handlers groups the handlers served by the router.
+type Handlers struct {
+	User          v1.UserHandlerInterface
+	Todo          v1.TodoHandlerInterface
+	Account       v1.AccountHandlerInterface
+	Profile       v1.ProfileHandlerInterface
+	Tokens        v1.AccessTokenHandlerInterface
+	TwoFactor     v1.TwoFactorHandlerInterface
+	OIDC          v1.OIDCHandlerInterface
+	OAuth         v1.OAuthHandlerInterface
+	SCIM          v1.SCIMHandlerInterface
+	Sessions      v1.SessionHandlerInterface
+	Audit         v1.AuditHandlerInterface
+	Registration  v1.RegistrationHandlerInterface
+	Events        v1.EventHandlerInterface
+	WebSocket     v1.WebSocketHandlerInterface
+	Webhooks      v1.WebhookHandlerInterface
+	Inbound       v1.InboundWebhookHandlerInterface
+	Jobs          v1.JobHandlerInterface
+	Schedules     v1.ScheduleHandlerInterface
+	Reminders     v1.ReminderHandlerInterface
+	Push          v1.PushHandlerInterface
+	Notifications v1.NotificationHandlerInterface
+	Digests       v1.DigestHandlerInterface
+}
+
+// SetupRouter initializes the API routes. Client addresses forwarded by
+// trustedProxies are believed.
+func SetupRouter(h Handlers, auth *Authenticator, trustedProxies []netip.Prefix) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{"https://*", "http://*"},
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
@@ -26,42 +55,211 @@ func SetupRouter(userHandler v1.UserHandlerInterface, todoHandler v1.TodoHandler
 
 	// A good base middleware stack
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(RealIP(trustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(RespondJsonMiddleware)
+	r.Use(RequestInfo)
 	// r.Use()
 
 	// Set a timeout value on the request context (ctx), that will signal
 	// through ctx.Done() that the request has timed out and further
-	// processing should be stopped.
-	r.Use(middleware.Timeout(60 * time.Second))
+	// processing should be stopped. Event streams and WebSocket connections
+	// stay open until the client disconnects.
+	r.Use(TimeoutExcept(60*time.Second, "/api/v1/events", "/api/v1/ws"))
 
 	r.Route("/api/v1", func(r chi.Router) {
 
 		// auth routes
 		r.Route("/auth", func(r chi.Router) {
-			r.Post("/login", userHandler.LoginHandler)
-			r.Post("/signup", userHandler.CreateUser)
+			r.Post("/login", h.User.LoginHandler)
+			r.Post("/login/mfa", h.User.LoginMFAHandler)
+			r.Post("/signup", h.User.CreateUser)
+			r.Get("/registration", h.Registration.GetSettings)
+			r.Post("/forgot", h.Account.ForgotPassword)
+			r.Post("/reset", h.Account.ResetPassword)
+			r.Post("/verify", h.Account.VerifyEmail)
+			r.Post("/verify/resend", h.Account.ResendVerification)
+
+			r.Get("/oidc", h.OIDC.ListProviders)
+			r.Get("/oidc/{provider}/login", h.OIDC.Login)
+			r.Get("/oidc/{provider}/callback", h.OIDC.Callback)
+		})
+
+		// OAuth2 authorization server for third-party applications
+		r.Route("/oauth", func(r chi.Router) {
+			r.Get("/authorize", h.OAuth.Authorize)
+			r.Post("/token", h.OAuth.Token)
+
+			r.Group(func(r chi.Router) {
+				r.Use(auth.UserOnly)
+				r.Use(RequireSession)
+				r.Get("/consent", h.OAuth.GetConsent)
+				r.Post("/consent", h.OAuth.Consent)
+			})
+		})
+
+		// SCIM provisioning for identity providers
+		r.Route("/scim/v2", func(r chi.Router) {
+			r.Use(auth.SCIMOnly)
+			r.Get("/ServiceProviderConfig", h.SCIM.GetServiceProviderConfig)
+			r.Get("/Users", h.SCIM.ListUsers)
+			r.Post("/Users", h.SCIM.CreateUser)
+			r.Get("/Users/{id}", h.SCIM.GetUser)
+			r.Put("/Users/{id}", h.SCIM.ReplaceUser)
+			r.Patch("/Users/{id}", h.SCIM.PatchUser)
+			r.Delete("/Users/{id}", h.SCIM.DeleteUser)
 		})
 
 		// user routes
 		r.Route("/users", func(r chi.Router) {
-			r.Use(AdminOnlyMiddleware)
-			r.Get("/", userHandler.GetAllUsers)
-			r.Get("/{id}", userHandler.GetUserByID)
-			r.Delete("/{id}", userHandler.DeleteUser)
+			r.Use(auth.AdminOnly)
+			r.Get("/", h.User.GetAllUsers)
+			r.Get("/pending", h.User.ListPendingUsers)
+			r.Get("/{id}", h.User.GetUserByID)
+			r.Delete("/{id}", h.User.DeleteUser)
+			r.Put("/{id}/role", h.User.SetRole)
+			r.Post("/{id}/approve", h.User.ApproveUser)
+			r.Post("/{id}/reject", h.User.RejectUser)
+			r.Post("/{id}/unlock", h.User.UnlockUser)
+			r.Delete("/{id}/2fa", h.User.ResetTwoFactor)
+			r.Get("/{id}/sessions", h.Sessions.ListUserSessions)
+			r.Delete("/{id}/sessions/{session_id}", h.Sessions.RevokeUserSession)
+		})
+
+		// invite codes of invite-only registration
+		r.Route("/invites", func(r chi.Router) {
+			r.Use(auth.AdminOnly)
+			r.Get("/", h.Registration.ListInvites)
+			r.Post("/", h.Registration.CreateInvite)
+			r.Delete("/{id}", h.Registration.DeleteInvite)
 		})
 
+		// audit log of security and admin actions
+		r.Route("/audit", func(r chi.Router) {
+			r.Use(auth.AdminOnly)
+			r.Get("/", h.Audit.ListEntries)
+			r.Get("/export", h.Audit.Export)
+			r.Get("/verify", h.Audit.Verify)
+		})
+
+		// background jobs
+		r.Route("/jobs", func(r chi.Router) {
+			r.Use(auth.AdminOnly)
+			r.Get("/", h.Jobs.ListJobs)
+			r.Get("/{id}", h.Jobs.GetJob)
+			r.Post("/{id}/retry", h.Jobs.RetryJob)
+		})
+
+		// periodic tasks
+		r.Route("/schedules", func(r chi.Router) {
+			r.Use(auth.AdminOnly)
+			r.Get("/", h.Schedules.ListSchedules)
+		})
+
+		// profile routes of the authenticated user
+		r.Route("/me", func(r chi.Router) {
+			r.Use(auth.UserOnly)
+			r.Use(RequireSession)
+
+			r.Get("/", h.Profile.GetProfile)
+			r.Patch("/", h.Profile.UpdateProfile)
+			r.Delete("/", h.Profile.DeleteAccount)
+			r.Put("/password", h.Profile.ChangePassword)
+			r.Post("/restore", h.Profile.RestoreAccount)
+
+			r.Get("/sessions", h.Sessions.ListSessions)
+			r.Delete("/sessions/{session_id}", h.Sessions.RevokeSession)
+
+			r.Get("/tokens", h.Tokens.ListTokens)
+			r.Post("/tokens", h.Tokens.CreateToken)
+			r.Delete("/tokens/{id}", h.Tokens.DeleteToken)
+
+			r.Get("/2fa", h.TwoFactor.GetStatus)
+			r.Delete("/2fa", h.TwoFactor.Disable)
+			r.Post("/2fa/totp", h.TwoFactor.BeginEnrollment)
+			r.Post("/2fa/totp/confirm", h.TwoFactor.ConfirmEnrollment)
+			r.Post("/2fa/recovery-codes", h.TwoFactor.RegenerateRecoveryCodes)
+
+			r.Get("/oauth/clients", h.OAuth.ListClients)
+			r.Post("/oauth/clients", h.OAuth.CreateClient)
+			r.Delete("/oauth/clients/{client_id}", h.OAuth.DeleteClient)
+
+			r.Get("/authorized-apps", h.OAuth.ListAuthorizedApps)
+			r.Delete("/authorized-apps/{client_id}", h.OAuth.RevokeAuthorizedApp)
+
+			r.Get("/notification-preferences", h.Reminders.GetPreferences)
+			r.Put("/notification-preferences", h.Reminders.UpdatePreferences)
+
+			r.Get("/push-subscriptions", h.Push.ListSubscriptions)
+			r.Post("/push-subscriptions", h.Push.Subscribe)
+			r.Delete("/push-subscriptions/{id}", h.Push.Unsubscribe)
+		})
+
+		// outgoing webhooks of the authenticated user
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(auth.UserOnly)
+			r.Use(RequireSession)
+
+			r.Get("/", h.Webhooks.ListWebhooks)
+			r.Post("/", h.Webhooks.CreateWebhook)
+			r.Get("/{id}", h.Webhooks.GetWebhook)
+			r.Put("/{id}", h.Webhooks.UpdateWebhook)
+			r.Delete("/{id}", h.Webhooks.DeleteWebhook)
+			r.Get("/{id}/deliveries", h.Webhooks.ListDeliveries)
+			r.Post("/{id}/deliveries/{delivery_id}/redeliver", h.Webhooks.Redeliver)
+		})
+
+		// inbound webhooks of the authenticated user, and the endpoint
+		// they receive payloads on, authenticated by the token in its path
+		r.Route("/inbound-webhooks", func(r chi.Router) {
+			r.Use(auth.UserOnly)
+			r.Use(RequireSession)
+
+			r.Get("/", h.Inbound.ListInboundWebhooks)
+			r.Post("/", h.Inbound.CreateInboundWebhook)
+			r.Get("/{id}", h.Inbound.GetInboundWebhook)
+			r.Put("/{id}", h.Inbound.UpdateInboundWebhook)
+			r.Delete("/{id}", h.Inbound.DeleteInboundWebhook)
+		})
+		r.Post("/inbound/{token}", h.Inbound.Receive)
+
+		// in-app inbox of the authenticated user
+		r.Route("/notifications", func(r chi.Router) {
+			r.Use(auth.UserOnly)
+			r.Use(RequireSession)
+
+			r.Get("/", h.Notifications.ListNotifications)
+			r.Get("/unread-count", h.Notifications.UnreadCount)
+			r.Post("/read-all", h.Notifications.MarkAllRead)
+			r.Post("/{id}/read", h.Notifications.MarkRead)
+		})
+
+		// one-click unsubscription from the email digest, authenticated by
+		// the signed token of the unsubscribe link
+		r.Post("/digest/unsubscribe", h.Digests.Unsubscribe)
+
+		// live changes of the todos of the authenticated user
+		r.With(auth.UserOnly, RequireScope(models.ScopeTodosRead)).Get("/events", h.Events.Stream)
+		r.With(auth.UserOnly, RequireScope(models.ScopeTodosRead)).Get("/ws", h.WebSocket.Serve)
+
 		// todo routes
 		r.Route("/todos", func(r chi.Router) {
-			r.Use(UserOnlyMiddleware)
+			r.Use(auth.UserOnly)
+
+			read := r.With(RequireScope(models.ScopeTodosRead))
+			write := r.With(RequireScope(models.ScopeTodosWrite))
+
+			write.Post("/", h.Todo.CreateTodo)
+			read.Get("/", h.Todo.GetAllTodos)
+			read.Get("/{id}", h.Todo.GetTodoByID)
+			write.Delete("/{id}", h.Todo.DeleteTodo)
+			write.Put("/{id}", h.Todo.UpdateTodo)
 
-			r.Post("/", todoHandler.CreateTodo)
-			r.Get("/", todoHandler.GetAllTodos)
-			r.Get("/{id}", todoHandler.GetTodoByID)
-			r.Delete("/{id}", todoHandler.DeleteTodo)
-			r.Put("/{id}", todoHandler.UpdateTodo)
+			read.Get("/{id}/reminders", h.Reminders.ListReminders)
+			write.Post("/{id}/reminders", h.Reminders.CreateReminder)
+			write.Delete("/{id}/reminders/{reminder_id}", h.Reminders.DeleteReminder)
+			write.Post("/{id}/reminders/{reminder_id}/snooze", h.Reminders.SnoozeReminder)
 		})
 
 	})
@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// createAccessTokenRequest is the body accepted by CreateToken.
+type createAccessTokenRequest struct {
+	Name      string     `json:"name" validate:"required,max=255"`
+	Scopes    []string   `json:"scopes" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// createdAccessToken is the response of CreateToken. It is the only time
+// the secret token is revealed.
+type createdAccessToken struct {
+	*models.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+type AccessTokenHandler struct {
+	Service services.AccessTokenServiceInterface
+}
+
+// NewAccessTokenHandler initializes a new AccessTokenHandler.
+func NewAccessTokenHandler(service services.AccessTokenServiceInterface) *AccessTokenHandler {
+	return &AccessTokenHandler{Service: service}
+}
+
+// CreateToken issues a personal access token for the authenticated user.
+func (h *AccessTokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req createAccessTokenRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	token, secret, err := h.Service.CreateToken(r.Context(), userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdAccessToken{PersonalAccessToken: token, Token: secret})
+}
+
+// ListTokens lists the personal access tokens of the authenticated user.
+func (h *AccessTokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	tokens, err := h.Service.ListTokens(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// DeleteToken revokes a personal access token of the authenticated user.
+func (h *AccessTokenHandler) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	if err := h.Service.DeleteToken(r.Context(), userID, id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var _ AccessTokenHandlerInterface = (*AccessTokenHandler)(nil)
@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAccessTokenService is a mock implementation of AccessTokenServiceInterface.
+type MockAccessTokenService struct {
+	mock.Mock
+}
+
+func (m *MockAccessTokenService) CreateToken(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*models.PersonalAccessToken, string, error) {
+	args := m.Called(ctx, userID, name, scopes, expiresAt)
+	return args.Get(0).(*models.PersonalAccessToken), args.String(1), args.Error(2)
+}
+
+func (m *MockAccessTokenService) ListTokens(ctx context.Context, userID int) ([]models.PersonalAccessToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockAccessTokenService) DeleteToken(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockAccessTokenService) Authenticate(ctx context.Context, secret string) (*models.PersonalAccessToken, error) {
+	args := m.Called(ctx, secret)
+	return args.Get(0).(*models.PersonalAccessToken), args.Error(1)
+}
+
+func TestCreateAccessToken(t *testing.T) {
+	mockService := new(MockAccessTokenService)
+	handler := NewAccessTokenHandler(mockService)
+
+	scopes := []string{models.ScopeTodosWrite}
+	mockService.On("CreateToken", mock.Anything, 1, "ci", scopes, (*time.Time)(nil)).
+		Return(&models.PersonalAccessToken{ID: 3, Name: "ci", Scopes: scopes, TokenHash: "hash"}, "todo_pat_secret", nil)
+
+	rr := httptest.NewRecorder()
+	handler.CreateToken(rr, withSession(jsonRequest(http.MethodPost, "/me/tokens", createAccessTokenRequest{Name: "ci", Scopes: scopes})))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "todo_pat_secret", body["token"])
+	assert.Equal(t, float64(3), body["id"])
+	assert.NotContains(t, body, "token_hash")
+}
+
+func TestDeleteAccessToken_NotFound(t *testing.T) {
+	mockService := new(MockAccessTokenService)
+	handler := NewAccessTokenHandler(mockService)
+
+	mockService.On("DeleteToken", mock.Anything, 1, 3).Return(services.NewNotFoundError("access_token_not_found", "access token not found"))
+
+	req := withSession(httptest.NewRequest(http.MethodDelete, "/me/tokens/3", nil))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "3")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler.DeleteToken(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"todo_app_backend/internal/app/services"
+)
+
+// emailRequest is the body accepted by ForgotPassword and ResendVerification.
+type emailRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// resetPasswordRequest is the body accepted by ResetPassword.
+type resetPasswordRequest struct {
+	Token    string `json:"token" validate:"required,max=255"`
+	Password string `json:"password" validate:"required,max=255"`
+}
+
+// verifyEmailRequest is the body accepted by VerifyEmail.
+type verifyEmailRequest struct {
+	Token string `json:"token" validate:"required,max=255"`
+}
+
+type AccountHandler struct {
+	AccountService services.AccountServiceInterface
+}
+
+// NewAccountHandler initializes a new AccountHandler.
+func NewAccountHandler(accountService services.AccountServiceInterface) *AccountHandler {
+	return &AccountHandler{AccountService: accountService}
+}
+
+// ForgotPassword emails a password reset link. It always responds with 202
+// so that callers cannot probe which emails are registered.
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req emailRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	if err := h.AccountService.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the email is registered, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using a reset token.
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	if err := h.AccountService.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail confirms an email address using a verification token.
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	if err := h.AccountService.VerifyEmail(r.Context(), req.Token); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification sends a new verification link. Like ForgotPassword it
+// always responds with 202.
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req emailRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	if err := h.AccountService.ResendVerificationEmail(r.Context(), req.Email); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the email is registered and unverified, a verification link has been sent"})
+}
+
+var _ AccountHandlerInterface = (*AccountHandler)(nil)
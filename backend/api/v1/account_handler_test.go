@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAccountService is a mock implementation of AccountServiceInterface.
+type MockAccountService struct {
+	mock.Mock
+}
+
+func (m *MockAccountService) SendVerificationEmail(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockAccountService) ResendVerificationEmail(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockAccountService) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockAccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockAccountService) ResetPassword(ctx context.Context, token, password string) error {
+	args := m.Called(ctx, token, password)
+	return args.Error(0)
+}
+
+func (m *MockAccountService) RequestEmailChange(ctx context.Context, user *models.User, newEmail string) error {
+	args := m.Called(ctx, user, newEmail)
+	return args.Error(0)
+}
+
+func jsonRequest(method, target string, body interface{}) *http.Request {
+	data, _ := json.Marshal(body)
+	return httptest.NewRequest(method, target, bytes.NewReader(data))
+}
+
+func TestForgotPassword(t *testing.T) {
+	mockService := new(MockAccountService)
+	handler := NewAccountHandler(mockService)
+
+	mockService.On("RequestPasswordReset", mock.Anything, "john@example.com").Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.ForgotPassword(rr, jsonRequest(http.MethodPost, "/auth/forgot", emailRequest{Email: "john@example.com"}))
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestForgotPassword_InvalidEmail(t *testing.T) {
+	mockService := new(MockAccountService)
+	handler := NewAccountHandler(mockService)
+
+	rr := httptest.NewRecorder()
+	handler.ForgotPassword(rr, jsonRequest(http.MethodPost, "/auth/forgot", emailRequest{Email: "john"}))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "RequestPasswordReset", mock.Anything, mock.Anything)
+}
+
+func TestResetPassword(t *testing.T) {
+	mockService := new(MockAccountService)
+	handler := NewAccountHandler(mockService)
+
+	mockService.On("ResetPassword", mock.Anything, "good", "new-password").Return(nil)
+	mockService.On("ResetPassword", mock.Anything, "used", "new-password").
+		Return(services.NewValidationError("token is invalid or has expired", services.FieldError{Field: "token", Message: "is invalid or has expired"}))
+
+	rr := httptest.NewRecorder()
+	handler.ResetPassword(rr, jsonRequest(http.MethodPost, "/auth/reset", resetPasswordRequest{Token: "good", Password: "new-password"}))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ResetPassword(rr, jsonRequest(http.MethodPost, "/auth/reset", resetPasswordRequest{Token: "used", Password: "new-password"}))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	mockService := new(MockAccountService)
+	handler := NewAccountHandler(mockService)
+
+	mockService.On("VerifyEmail", mock.Anything, "token").Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.VerifyEmail(rr, jsonRequest(http.MethodPost, "/auth/verify", verifyEmailRequest{Token: "token"}))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// auditExportFlushInterval is the number of exported entries written
+// between flushes of the response.
+const auditExportFlushInterval = 100
+
+type AuditHandler struct {
+	Service services.AuditServiceInterface
+}
+
+// NewAuditHandler initializes a new AuditHandler.
+func NewAuditHandler(service services.AuditServiceInterface) *AuditHandler {
+	return &AuditHandler{Service: service}
+}
+
+// ListEntries lists audit log entries, newest first. Entries can be
+// filtered by action, actor_id, target_type, target_id and a since/until
+// time range, and paged through with limit and before, the ID returned as
+// next_before.
+func (h *AuditHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	entries, err := h.Service.ListEntries(r.Context(), filter, limit)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{"entries": entries}
+	if limit <= 0 {
+		limit = services.DefaultAuditPageSize
+	}
+	if len(entries) > 0 && len(entries) >= limit {
+		response["next_before"] = entries[len(entries)-1].ID
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Export streams the audit log entries matching the filters of ListEntries
+// as newline-delimited JSON, oldest first.
+func (h *AuditHandler) Export(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-log.ndjson"`)
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	written := 0
+	err = h.Service.Export(r.Context(), filter, func(entry *models.AuditEntry) error {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+		if written++; flusher != nil && written%auditExportFlushInterval == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	// The status has been sent, so a failed export can only be cut short.
+	if err != nil {
+		log.Printf("audit export failed [request_id=%s]: %v", middleware.GetReqID(r.Context()), err)
+	}
+}
+
+// Verify checks the hash chain of the audit log.
+func (h *AuditHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	result, err := h.Service.Verify(r.Context())
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// parseAuditFilter reads the audit log filters from the query string.
+func parseAuditFilter(r *http.Request) (models.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := models.AuditFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+	}
+
+	var fields []services.FieldError
+	var err error
+	if filter.ActorID, err = queryInt(r, "actor_id"); err != nil {
+		fields = append(fields, services.FieldError{Field: "actor_id", Message: "must be an integer"})
+	}
+	before, err := queryInt(r, "before")
+	if err != nil {
+		fields = append(fields, services.FieldError{Field: "before", Message: "must be an integer"})
+	}
+	filter.BeforeID = int64(before)
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		if value := query.Get(param.name); value != "" {
+			if *param.dst, err = time.Parse(time.RFC3339, value); err != nil {
+				fields = append(fields, services.FieldError{Field: param.name, Message: "must be an RFC 3339 timestamp"})
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return filter, services.NewValidationError("invalid audit log filter", fields...)
+	}
+	return filter, nil
+}
+
+// queryInt returns the integer query parameter name, or 0 if it is absent.
+func queryInt(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, services.NewValidationError("invalid query parameter",
+			services.FieldError{Field: name, Message: "must be an integer"})
+	}
+	return n, nil
+}
+
+var _ AuditHandlerInterface = (*AuditHandler)(nil)
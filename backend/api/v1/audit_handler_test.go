@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditService is a mock implementation of AuditServiceInterface.
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) Record(ctx context.Context, entry *models.AuditEntry) {
+	m.Called(ctx, entry)
+}
+
+func (m *MockAuditService) ListEntries(ctx context.Context, filter models.AuditFilter, limit int) ([]models.AuditEntry, error) {
+	args := m.Called(ctx, filter, limit)
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+func (m *MockAuditService) Export(ctx context.Context, filter models.AuditFilter, fn func(entry *models.AuditEntry) error) error {
+	args := m.Called(ctx, filter, fn)
+	for _, entry := range args.Get(0).([]models.AuditEntry) {
+		entry := entry
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockAuditService) Verify(ctx context.Context) (*models.AuditVerification, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*models.AuditVerification), args.Error(1)
+}
+
+func TestListAuditEntries(t *testing.T) {
+	mockService := new(MockAuditService)
+	handler := NewAuditHandler(mockService)
+
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	filter := models.AuditFilter{Action: models.AuditLoginFailed, ActorID: 3, Since: since, BeforeID: 100}
+	mockService.On("ListEntries", mock.Anything, filter, 2).
+		Return([]models.AuditEntry{{ID: 99, Action: models.AuditLoginFailed}, {ID: 98, Action: models.AuditLoginFailed}}, nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/audit?action=auth.login_failed&actor_id=3&since=2024-05-01T00:00:00Z&before=100&limit=2", nil)
+	handler.ListEntries(rr, withSession(req))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body struct {
+		Entries    []models.AuditEntry `json:"entries"`
+		NextBefore int64               `json:"next_before"`
+	}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Len(t, body.Entries, 2)
+	assert.Equal(t, int64(98), body.NextBefore)
+}
+
+func TestListAuditEntries_InvalidFilter(t *testing.T) {
+	mockService := new(MockAuditService)
+	handler := NewAuditHandler(mockService)
+
+	rr := httptest.NewRecorder()
+	handler.ListEntries(rr, withSession(httptest.NewRequest(http.MethodGet, "/audit?since=yesterday&actor_id=x", nil)))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var problem Problem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	assert.Len(t, problem.Errors, 2)
+	mockService.AssertNotCalled(t, "ListEntries", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExportAuditLog(t *testing.T) {
+	mockService := new(MockAuditService)
+	handler := NewAuditHandler(mockService)
+
+	mockService.On("Export", mock.Anything, models.AuditFilter{TargetType: "user", TargetID: "7"}, mock.Anything).
+		Return([]models.AuditEntry{{ID: 1, Action: models.AuditLogin}, {ID: 2, Action: models.AuditUserDeleted}}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.Export(rr, withSession(httptest.NewRequest(http.MethodGet, "/audit/export?target_type=user&target_id=7", nil)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+
+	var actions []string
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		var entry models.AuditEntry
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		actions = append(actions, entry.Action)
+	}
+	assert.Equal(t, []string{models.AuditLogin, models.AuditUserDeleted}, actions)
+}
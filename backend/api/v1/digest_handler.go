@@ -0,0 +1,37 @@
+package v1
+
+import (
+	"net/http"
+	"todo_app_backend/internal/app/services"
+)
+
+type DigestHandler struct {
+	Service services.DigestServiceInterface
+}
+
+// NewDigestHandler initializes a new DigestHandler.
+func NewDigestHandler(service services.DigestServiceInterface) *DigestHandler {
+	return &DigestHandler{Service: service}
+}
+
+// Unsubscribe turns off the email digest of the user the token of an
+// unsubscribe link was sent to. The token is taken from the query so that
+// mail clients can post to the List-Unsubscribe URL as is, as specified in
+// RFC 8058.
+func (h *DigestHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		RespondError(w, r, services.NewValidationError("invalid query parameter",
+			services.FieldError{Field: "token", Message: "is required"}))
+		return
+	}
+
+	if err := h.Service.Unsubscribe(r.Context(), token); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var _ DigestHandlerInterface = (*DigestHandler)(nil)
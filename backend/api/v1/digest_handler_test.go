@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo_app_backend/internal/app/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockDigestService is a mock implementation of DigestServiceInterface.
+type MockDigestService struct {
+	mock.Mock
+}
+
+func (m *MockDigestService) Unsubscribe(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func TestUnsubscribeDigest(t *testing.T) {
+	mockService := new(MockDigestService)
+	handler := NewDigestHandler(mockService)
+
+	mockService.On("Unsubscribe", mock.Anything, "MQ.signature").Return(nil)
+	mockService.On("Unsubscribe", mock.Anything, "MQ.tampered").
+		Return(services.NewValidationError("token is invalid or has expired"))
+
+	for token, code := range map[string]int{"MQ.signature": http.StatusNoContent, "MQ.tampered": http.StatusBadRequest, "": http.StatusBadRequest} {
+		// Mail clients post the form of RFC 8058 to the List-Unsubscribe URL
+		req := httptest.NewRequest(http.MethodPost, "/digest/unsubscribe?token="+token, strings.NewReader("List-Unsubscribe=One-Click"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		handler.Unsubscribe(rr, req)
+		assert.Equal(t, code, rr.Code, "token %q", token)
+	}
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ProblemContentType is the media type of RFC 7807 error responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type      string                `json:"type"`
+	Title     string                `json:"title"`
+	Status    int                   `json:"status"`
+	Detail    string                `json:"detail,omitempty"`
+	Instance  string                `json:"instance,omitempty"`
+	Code      string                `json:"code"`
+	RequestID string                `json:"request_id,omitempty"`
+	Errors    []services.FieldError `json:"errors,omitempty"`
+}
+
+// statusByKind maps domain error kinds to HTTP status codes.
+var statusByKind = map[services.ErrorKind]int{
+	services.KindValidation:      http.StatusBadRequest,
+	services.KindNotFound:        http.StatusNotFound,
+	services.KindConflict:        http.StatusConflict,
+	services.KindForbidden:       http.StatusForbidden,
+	services.KindUnauthorized:    http.StatusUnauthorized,
+	services.KindTooManyRequests: http.StatusTooManyRequests,
+}
+
+// RespondError writes err as a problem+json response. Errors that are not
+// domain errors are logged and reported as a generic internal error so that
+// internals never leak to clients.
+func RespondError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := Problem{
+		Type:      "about:blank",
+		Instance:  r.URL.Path,
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+
+	var domainErr *services.Error
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		problem.Status = http.StatusRequestEntityTooLarge
+		problem.Code = "body_too_large"
+		problem.Detail = fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit)
+	} else if errors.As(err, &domainErr) && domainErr.Kind != services.KindInternal {
+		problem.Status = statusByKind[domainErr.Kind]
+		problem.Code = domainErr.Code
+		problem.Detail = domainErr.Message
+		problem.Errors = domainErr.Fields
+		if domainErr.RetryAfter > 0 {
+			seconds := int(math.Ceil(domainErr.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+	} else {
+		log.Printf("internal error [request_id=%s]: %v", problem.RequestID, err)
+		problem.Status = http.StatusInternalServerError
+		problem.Code = "internal_error"
+		problem.Detail = "an unexpected error occurred"
+	}
+	problem.Title = http.StatusText(problem.Status)
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+var (
+	errInvalidJSON = &services.Error{Kind: services.KindValidation, Code: "invalid_json", Message: "invalid json"}
+	errInvalidID   = &services.Error{Kind: services.KindValidation, Code: "invalid_id", Message: "invalid id"}
+)
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondError_Validation(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/todos", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "req-1"))
+	rr := httptest.NewRecorder()
+
+	RespondError(rr, req, services.NewValidationError("invalid input",
+		services.FieldError{Field: "title", Message: "is required"},
+		services.FieldError{Field: "status", Message: "is too long"},
+	))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+
+	var problem Problem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	assert.Equal(t, "validation_failed", problem.Code)
+	assert.Equal(t, "req-1", problem.RequestID)
+	assert.Equal(t, "/api/v1/todos", problem.Instance)
+	assert.Len(t, problem.Errors, 2)
+	assert.Equal(t, "title", problem.Errors[0].Field)
+}
+
+func TestRespondError_Kinds(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"Not Found", services.NewNotFoundError("todo_not_found", "todo not found"), http.StatusNotFound},
+		{"Conflict", services.NewConflictError("email_taken", "email is already registered"), http.StatusConflict},
+		{"Forbidden", services.NewForbiddenError("forbidden", "forbidden"), http.StatusForbidden},
+		{"Unauthorized", services.NewUnauthorizedError("invalid_token", "invalid token"), http.StatusUnauthorized},
+		{"Wrapped", fmt.Errorf("wrapped: %w", services.NewNotFoundError("user_not_found", "user not found")), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			RespondError(rr, httptest.NewRequest(http.MethodGet, "/", nil), tt.err)
+			assert.Equal(t, tt.status, rr.Code)
+		})
+	}
+}
+
+func TestRespondError_InternalDoesNotLeak(t *testing.T) {
+	rr := httptest.NewRecorder()
+	RespondError(rr, httptest.NewRequest(http.MethodGet, "/", nil), errors.New(`pq: relation "users" does not exist`))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	body, _ := io.ReadAll(rr.Body)
+	assert.NotContains(t, string(body), "relation")
+
+	var problem Problem
+	json.Unmarshal(body, &problem)
+	assert.Equal(t, "internal_error", problem.Code)
+}
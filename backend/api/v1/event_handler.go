@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+)
+
+const (
+	// DefaultEventKeepAlive is how often a comment is sent on idle event
+	// streams so that proxies do not close them.
+	DefaultEventKeepAlive = 25 * time.Second
+	// eventRetry is how long clients wait before reconnecting, in milliseconds.
+	eventRetry = 3000
+)
+
+type EventHandler struct {
+	Broker    services.EventBrokerInterface
+	KeepAlive time.Duration
+}
+
+// NewEventHandler initializes a new EventHandler.
+func NewEventHandler(broker services.EventBrokerInterface) *EventHandler {
+	return &EventHandler{Broker: broker, KeepAlive: DefaultEventKeepAlive}
+}
+
+// Stream sends the changes to the todos of the authenticated user as
+// server-sent events until the client disconnects. Clients resume a stream
+// by sending the ID of the last event they received in the Last-Event-ID
+// header, or the last_event_id query parameter.
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	lastEventID, err := parseLastEventID(r)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	// Streams outlive the write timeout of the server
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	sub, replay := h.Broker.Subscribe(userID, lastEventID)
+	defer h.Broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", eventRetry); err != nil {
+		return
+	}
+	for _, event := range replay {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	rc.Flush()
+
+	keepAlive := time.NewTicker(h.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// The client resumes from its last event, or resyncs after
+				// a reset, when it reconnects
+				if sub.End() == services.EventSubscriptionDropped {
+					log.Printf("dropped slow event stream of user %d", userID)
+				}
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes event in the text/event-stream format.
+func writeEvent(w http.ResponseWriter, event models.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+	return err
+}
+
+// parseLastEventID returns the ID of the last event received by a client
+// resuming a stream, or 0 for a new stream.
+func parseLastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, services.NewValidationError("invalid last event ID",
+			services.FieldError{Field: "Last-Event-ID", Message: "must be a positive integer"})
+	}
+	return id, nil
+}
+
+var _ EventHandlerInterface = (*EventHandler)(nil)
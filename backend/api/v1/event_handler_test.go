@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvents reads n events from an event stream, skipping comments and
+// the retry field, and returns their "event: data" lines.
+func readEvents(t *testing.T, scanner *bufio.Scanner, n int) []string {
+	t.Helper()
+	var events []string
+	var eventType string
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			events = append(events, eventType+" "+strings.TrimPrefix(line, "data: "))
+		}
+	}
+	require.Len(t, events, n)
+	return events
+}
+
+func TestStreamEvents(t *testing.T) {
+	broker := services.NewEventBroker(16)
+	handler := NewEventHandler(broker)
+
+	// The stream must outlive the timeouts of the server
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.Stream(w, withSession(r))
+	}))
+	server.Config.ReadTimeout = 50 * time.Millisecond
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	time.Sleep(150 * time.Millisecond)
+	broker.Publish(context.Background(), &models.Event{Type: models.EventTodoCreated, UserID: 1, Data: []byte(`{"id":5}`)})
+	broker.Publish(context.Background(), &models.Event{Type: models.EventTodoCreated, UserID: 2, Data: []byte(`{"id":6}`)})
+	broker.Publish(context.Background(), &models.Event{Type: models.EventTodoDeleted, UserID: 1, Data: []byte(`{"id":5}`)})
+
+	events := readEvents(t, bufio.NewScanner(resp.Body), 2)
+	assert.Equal(t, []string{`todo.created {"id":5}`, `todo.deleted {"id":5}`}, events)
+}
+
+func TestStreamEvents_Resume(t *testing.T) {
+	broker := services.NewEventBroker(16)
+	handler := NewEventHandler(broker)
+
+	first := &models.Event{Type: models.EventTodoCreated, UserID: 1, Data: []byte(`{"id":5}`)}
+	broker.Publish(context.Background(), first)
+	broker.Publish(context.Background(), &models.Event{Type: models.EventTodoUpdated, UserID: 1, Data: []byte(`{"id":5}`)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := withSession(httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx))
+	req.Header.Set("Last-Event-ID", "1")
+	// An ID from before a restart cannot be replayed
+	rr := httptest.NewRecorder()
+	cancel()
+	handler.Stream(rr, req)
+	assert.Contains(t, rr.Body.String(), "event: resync")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	req = withSession(httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx))
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(first.ID, 10))
+	rr = httptest.NewRecorder()
+	cancel()
+	handler.Stream(rr, req)
+	assert.Contains(t, rr.Body.String(), "event: todo.updated")
+	assert.NotContains(t, rr.Body.String(), "event: todo.created")
+
+	rr = httptest.NewRecorder()
+	req = withSession(httptest.NewRequest(http.MethodGet, "/events?last_event_id=abc", nil))
+	handler.Stream(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestStreamEvents_EndedByReset(t *testing.T) {
+	broker := services.NewEventBroker(16)
+	handler := NewEventHandler(broker)
+
+	var logs strings.Builder
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.Stream(httptest.NewRecorder(), withSession(httptest.NewRequest(http.MethodGet, "/events", nil)))
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Events lost by the bus end every stream, which is not the fault of
+	// slow clients
+	broker.Deliver(models.Event{Type: models.EventResync})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the stream did not end")
+	}
+	assert.NotContains(t, logs.String(), "dropped slow event stream")
+}
@@ -3,11 +3,18 @@ package v1
 import "net/http"
 
 type UserHandlerInterface interface {
+	ApproveUser(w http.ResponseWriter, r *http.Request)
 	CreateUser(w http.ResponseWriter, r *http.Request)
 	DeleteUser(w http.ResponseWriter, r *http.Request)
 	GetAllUsers(w http.ResponseWriter, r *http.Request)
 	GetUserByID(w http.ResponseWriter, r *http.Request)
 	LoginHandler(w http.ResponseWriter, r *http.Request)
+	ListPendingUsers(w http.ResponseWriter, r *http.Request)
+	LoginMFAHandler(w http.ResponseWriter, r *http.Request)
+	RejectUser(w http.ResponseWriter, r *http.Request)
+	ResetTwoFactor(w http.ResponseWriter, r *http.Request)
+	SetRole(w http.ResponseWriter, r *http.Request)
+	UnlockUser(w http.ResponseWriter, r *http.Request)
 }
 
 type TodoHandlerInterface interface {
@@ -17,3 +24,143 @@ type TodoHandlerInterface interface {
 	GetTodoByID(w http.ResponseWriter, r *http.Request)
 	UpdateTodo(w http.ResponseWriter, r *http.Request)
 }
+
+type AccountHandlerInterface interface {
+	ForgotPassword(w http.ResponseWriter, r *http.Request)
+	ResendVerification(w http.ResponseWriter, r *http.Request)
+	ResetPassword(w http.ResponseWriter, r *http.Request)
+	VerifyEmail(w http.ResponseWriter, r *http.Request)
+}
+
+type ProfileHandlerInterface interface {
+	ChangePassword(w http.ResponseWriter, r *http.Request)
+	DeleteAccount(w http.ResponseWriter, r *http.Request)
+	GetProfile(w http.ResponseWriter, r *http.Request)
+	RestoreAccount(w http.ResponseWriter, r *http.Request)
+	UpdateProfile(w http.ResponseWriter, r *http.Request)
+}
+
+type AccessTokenHandlerInterface interface {
+	CreateToken(w http.ResponseWriter, r *http.Request)
+	DeleteToken(w http.ResponseWriter, r *http.Request)
+	ListTokens(w http.ResponseWriter, r *http.Request)
+}
+
+type TwoFactorHandlerInterface interface {
+	BeginEnrollment(w http.ResponseWriter, r *http.Request)
+	ConfirmEnrollment(w http.ResponseWriter, r *http.Request)
+	Disable(w http.ResponseWriter, r *http.Request)
+	GetStatus(w http.ResponseWriter, r *http.Request)
+	RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request)
+}
+
+type OIDCHandlerInterface interface {
+	Callback(w http.ResponseWriter, r *http.Request)
+	ListProviders(w http.ResponseWriter, r *http.Request)
+	Login(w http.ResponseWriter, r *http.Request)
+}
+
+type OAuthHandlerInterface interface {
+	Authorize(w http.ResponseWriter, r *http.Request)
+	Consent(w http.ResponseWriter, r *http.Request)
+	CreateClient(w http.ResponseWriter, r *http.Request)
+	DeleteClient(w http.ResponseWriter, r *http.Request)
+	GetConsent(w http.ResponseWriter, r *http.Request)
+	ListAuthorizedApps(w http.ResponseWriter, r *http.Request)
+	ListClients(w http.ResponseWriter, r *http.Request)
+	RevokeAuthorizedApp(w http.ResponseWriter, r *http.Request)
+	Token(w http.ResponseWriter, r *http.Request)
+}
+
+type SCIMHandlerInterface interface {
+	CreateUser(w http.ResponseWriter, r *http.Request)
+	DeleteUser(w http.ResponseWriter, r *http.Request)
+	GetServiceProviderConfig(w http.ResponseWriter, r *http.Request)
+	GetUser(w http.ResponseWriter, r *http.Request)
+	ListUsers(w http.ResponseWriter, r *http.Request)
+	PatchUser(w http.ResponseWriter, r *http.Request)
+	ReplaceUser(w http.ResponseWriter, r *http.Request)
+}
+
+type SessionHandlerInterface interface {
+	ListSessions(w http.ResponseWriter, r *http.Request)
+	ListUserSessions(w http.ResponseWriter, r *http.Request)
+	RevokeSession(w http.ResponseWriter, r *http.Request)
+	RevokeUserSession(w http.ResponseWriter, r *http.Request)
+}
+
+type AuditHandlerInterface interface {
+	Export(w http.ResponseWriter, r *http.Request)
+	ListEntries(w http.ResponseWriter, r *http.Request)
+	Verify(w http.ResponseWriter, r *http.Request)
+}
+
+type RegistrationHandlerInterface interface {
+	CreateInvite(w http.ResponseWriter, r *http.Request)
+	DeleteInvite(w http.ResponseWriter, r *http.Request)
+	GetSettings(w http.ResponseWriter, r *http.Request)
+	ListInvites(w http.ResponseWriter, r *http.Request)
+}
+
+type EventHandlerInterface interface {
+	Stream(w http.ResponseWriter, r *http.Request)
+}
+
+type WebSocketHandlerInterface interface {
+	Serve(w http.ResponseWriter, r *http.Request)
+}
+
+type WebhookHandlerInterface interface {
+	CreateWebhook(w http.ResponseWriter, r *http.Request)
+	ListWebhooks(w http.ResponseWriter, r *http.Request)
+	GetWebhook(w http.ResponseWriter, r *http.Request)
+	UpdateWebhook(w http.ResponseWriter, r *http.Request)
+	DeleteWebhook(w http.ResponseWriter, r *http.Request)
+	ListDeliveries(w http.ResponseWriter, r *http.Request)
+	Redeliver(w http.ResponseWriter, r *http.Request)
+}
+
+type InboundWebhookHandlerInterface interface {
+	CreateInboundWebhook(w http.ResponseWriter, r *http.Request)
+	ListInboundWebhooks(w http.ResponseWriter, r *http.Request)
+	GetInboundWebhook(w http.ResponseWriter, r *http.Request)
+	UpdateInboundWebhook(w http.ResponseWriter, r *http.Request)
+	DeleteInboundWebhook(w http.ResponseWriter, r *http.Request)
+	Receive(w http.ResponseWriter, r *http.Request)
+}
+
+type JobHandlerInterface interface {
+	ListJobs(w http.ResponseWriter, r *http.Request)
+	GetJob(w http.ResponseWriter, r *http.Request)
+	RetryJob(w http.ResponseWriter, r *http.Request)
+}
+
+type ScheduleHandlerInterface interface {
+	ListSchedules(w http.ResponseWriter, r *http.Request)
+}
+
+type ReminderHandlerInterface interface {
+	ListReminders(w http.ResponseWriter, r *http.Request)
+	CreateReminder(w http.ResponseWriter, r *http.Request)
+	DeleteReminder(w http.ResponseWriter, r *http.Request)
+	SnoozeReminder(w http.ResponseWriter, r *http.Request)
+	GetPreferences(w http.ResponseWriter, r *http.Request)
+	UpdatePreferences(w http.ResponseWriter, r *http.Request)
+}
+
+type PushHandlerInterface interface {
+	ListSubscriptions(w http.ResponseWriter, r *http.Request)
+	Subscribe(w http.ResponseWriter, r *http.Request)
+	Unsubscribe(w http.ResponseWriter, r *http.Request)
+}
+
+type NotificationHandlerInterface interface {
+	ListNotifications(w http.ResponseWriter, r *http.Request)
+	UnreadCount(w http.ResponseWriter, r *http.Request)
+	MarkRead(w http.ResponseWriter, r *http.Request)
+	MarkAllRead(w http.ResponseWriter, r *http.Request)
+}
+
+type DigestHandlerInterface interface {
+	Unsubscribe(w http.ResponseWriter, r *http.Request)
+}
@@ -0,0 +1,167 @@
+package v1
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// InboundWebhookPath is the path payloads are posted to, followed by the
+// token of the inbound webhook.
+const InboundWebhookPath = "/api/v1/inbound/"
+
+// inboundWebhookRequest is the body accepted when creating or updating an
+// inbound webhook.
+type inboundWebhookRequest struct {
+	Name    string                `json:"name" validate:"required,max=255"`
+	Mapping models.InboundMapping `json:"mapping"`
+	Signed  bool                  `json:"signed"`
+}
+
+// createdInboundWebhook is the response of CreateInboundWebhook and, when a
+// secret is generated, of UpdateInboundWebhook. It is the only time the
+// token and the secret are revealed.
+type createdInboundWebhook struct {
+	*models.InboundWebhook
+	Token  string `json:"token,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+type InboundWebhookHandler struct {
+	Service services.InboundWebhookServiceInterface
+}
+
+// NewInboundWebhookHandler initializes a new InboundWebhookHandler.
+func NewInboundWebhookHandler(service services.InboundWebhookServiceInterface) *InboundWebhookHandler {
+	return &InboundWebhookHandler{Service: service}
+}
+
+// CreateInboundWebhook adds an inbound webhook of the authenticated user.
+func (h *InboundWebhookHandler) CreateInboundWebhook(w http.ResponseWriter, r *http.Request) {
+	var req inboundWebhookRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	webhook, token, secret, err := h.Service.CreateInboundWebhook(r.Context(), userID, req.Name, req.Mapping, req.Signed)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdInboundWebhook{
+		InboundWebhook: webhook,
+		Token:          token,
+		Path:           InboundWebhookPath + token,
+		Secret:         secret,
+	})
+}
+
+// ListInboundWebhooks lists the inbound webhooks of the authenticated user.
+func (h *InboundWebhookHandler) ListInboundWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	webhooks, err := h.Service.ListInboundWebhooks(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// GetInboundWebhook retrieves an inbound webhook of the authenticated user.
+func (h *InboundWebhookHandler) GetInboundWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	webhook, err := h.Service.GetInboundWebhook(r.Context(), userID, id)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// UpdateInboundWebhook changes an inbound webhook of the authenticated user.
+func (h *InboundWebhookHandler) UpdateInboundWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	var req inboundWebhookRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	webhook, secret, err := h.Service.UpdateInboundWebhook(r.Context(), userID, id, req.Name, req.Mapping, req.Signed)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(createdInboundWebhook{InboundWebhook: webhook, Secret: secret})
+}
+
+// DeleteInboundWebhook removes an inbound webhook of the authenticated user.
+func (h *InboundWebhookHandler) DeleteInboundWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	if err := h.Service.DeleteInboundWebhook(r.Context(), userID, id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Receive creates or updates a todo from a payload posted by an external
+// system. The token in the path authenticates the request.
+func (h *InboundWebhookHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		RespondError(w, r, decodeError(err))
+		return
+	}
+
+	todo, created, err := h.Service.Receive(r.Context(), chi.URLParam(r, "token"), body,
+		r.Header.Get(services.WebhookTimestampHeader), r.Header.Get(services.WebhookSignatureHeader))
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": todo.ID, "created": created})
+}
+
+var _ InboundWebhookHandlerInterface = (*InboundWebhookHandler)(nil)
@@ -0,0 +1,115 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockInboundWebhookService is a mock implementation of InboundWebhookServiceInterface.
+type MockInboundWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockInboundWebhookService) CreateInboundWebhook(ctx context.Context, userID int, name string, mapping models.InboundMapping, signed bool) (*models.InboundWebhook, string, string, error) {
+	args := m.Called(ctx, userID, name, mapping, signed)
+	webhook, _ := args.Get(0).(*models.InboundWebhook)
+	return webhook, args.String(1), args.String(2), args.Error(3)
+}
+
+func (m *MockInboundWebhookService) ListInboundWebhooks(ctx context.Context, userID int) ([]models.InboundWebhook, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.InboundWebhook), args.Error(1)
+}
+
+func (m *MockInboundWebhookService) GetInboundWebhook(ctx context.Context, userID int, id int) (*models.InboundWebhook, error) {
+	args := m.Called(ctx, userID, id)
+	webhook, _ := args.Get(0).(*models.InboundWebhook)
+	return webhook, args.Error(1)
+}
+
+func (m *MockInboundWebhookService) UpdateInboundWebhook(ctx context.Context, userID int, id int, name string, mapping models.InboundMapping, signed bool) (*models.InboundWebhook, string, error) {
+	args := m.Called(ctx, userID, id, name, mapping, signed)
+	webhook, _ := args.Get(0).(*models.InboundWebhook)
+	return webhook, args.String(1), args.Error(2)
+}
+
+func (m *MockInboundWebhookService) DeleteInboundWebhook(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockInboundWebhookService) Receive(ctx context.Context, token string, body []byte, timestamp, signature string) (*models.Todo, bool, error) {
+	args := m.Called(ctx, token, body, timestamp, signature)
+	todo, _ := args.Get(0).(*models.Todo)
+	return todo, args.Bool(1), args.Error(2)
+}
+
+func TestCreateInboundWebhook(t *testing.T) {
+	mockService := new(MockInboundWebhookService)
+	handler := NewInboundWebhookHandler(mockService)
+
+	mapping := models.InboundMapping{Title: "{{.title}}"}
+	mockService.On("CreateInboundWebhook", mock.Anything, 1, "Forms", mapping, false).
+		Return(&models.InboundWebhook{ID: 4, Name: "Forms", TokenHash: "hash", Mapping: mapping}, "whin_token", "", nil)
+
+	rr := httptest.NewRecorder()
+	req := jsonRequest(http.MethodPost, "/inbound-webhooks", inboundWebhookRequest{Name: "Forms", Mapping: mapping})
+	handler.CreateInboundWebhook(rr, withSession(req))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "whin_token", body["token"])
+	assert.Equal(t, "/api/v1/inbound/whin_token", body["path"])
+	assert.NotContains(t, body, "secret")
+	assert.NotContains(t, body, "token_hash")
+}
+
+func TestReceiveInboundWebhook(t *testing.T) {
+	mockService := new(MockInboundWebhookService)
+	handler := NewInboundWebhookHandler(mockService)
+
+	receive := func(token, payload string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/inbound/"+token, strings.NewReader(payload))
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("token", token)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		handler.Receive(rr, req)
+		return rr
+	}
+
+	mockService.On("Receive", mock.Anything, "whin_token", []byte(`{"title":"Call back"}`), "1700000000", "sha256=abc").
+		Return(&models.Todo{ID: 9}, true, nil).Once()
+	rr := receive("whin_token", `{"title":"Call back"}`, map[string]string{
+		services.WebhookTimestampHeader: "1700000000",
+		services.WebhookSignatureHeader: "sha256=abc",
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.JSONEq(t, `{"id":9,"created":true}`, rr.Body.String())
+
+	mockService.On("Receive", mock.Anything, "whin_token", mock.Anything, "", "").Return(&models.Todo{ID: 9}, false, nil).Once()
+	rr = receive("whin_token", `{"title":"Call back"}`, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	mockService.On("Receive", mock.Anything, "whin_other", mock.Anything, "", "").
+		Return(nil, false, services.NewNotFoundError("inbound_webhook_not_found", "inbound webhook not found")).Once()
+	rr = receive("whin_other", `{}`, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type JobHandler struct {
+	Service services.JobServiceInterface
+}
+
+// NewJobHandler initializes a new JobHandler.
+func NewJobHandler(service services.JobServiceInterface) *JobHandler {
+	return &JobHandler{Service: service}
+}
+
+// ListJobs lists background jobs, newest first. Jobs can be filtered by
+// status and type, and paged through with limit and before, the ID returned
+// as next_before.
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	filter := models.JobFilter{Status: r.URL.Query().Get("status"), Type: r.URL.Query().Get("type")}
+	before, err := queryInt(r, "before")
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+	filter.BeforeID = int64(before)
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	jobs, err := h.Service.ListJobs(r.Context(), filter, limit)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{"jobs": jobs}
+	if limit <= 0 {
+		limit = services.DefaultJobPageSize
+	}
+	if len(jobs) > 0 && len(jobs) >= limit {
+		response["next_before"] = jobs[len(jobs)-1].ID
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetJob retrieves a background job.
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	job, err := h.Service.GetJob(r.Context(), id)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
+
+// RetryJob runs a dead or pending background job again right away.
+func (h *JobHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	job, err := h.Service.RetryJob(r.Context(), id)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+var _ JobHandlerInterface = (*JobHandler)(nil)
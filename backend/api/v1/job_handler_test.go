@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockJobService is a mock implementation of JobServiceInterface.
+type MockJobService struct {
+	mock.Mock
+}
+
+func (m *MockJobService) ListJobs(ctx context.Context, filter models.JobFilter, limit int) ([]models.Job, error) {
+	args := m.Called(ctx, filter, limit)
+	return args.Get(0).([]models.Job), args.Error(1)
+}
+
+func (m *MockJobService) GetJob(ctx context.Context, id int64) (*models.Job, error) {
+	args := m.Called(ctx, id)
+	job, _ := args.Get(0).(*models.Job)
+	return job, args.Error(1)
+}
+
+func (m *MockJobService) RetryJob(ctx context.Context, id int64) (*models.Job, error) {
+	args := m.Called(ctx, id)
+	job, _ := args.Get(0).(*models.Job)
+	return job, args.Error(1)
+}
+
+func jobRequest(method, target, id string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	return withSession(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+}
+
+func TestListJobs(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := NewJobHandler(mockService)
+
+	filter := models.JobFilter{Status: models.JobDead, Type: "email.send", BeforeID: 100}
+	mockService.On("ListJobs", mock.Anything, filter, 2).
+		Return([]models.Job{{ID: 99, Status: models.JobDead}, {ID: 98, Status: models.JobDead}}, nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/jobs?status=dead&type=email.send&before=100&limit=2", nil)
+	handler.ListJobs(rr, withSession(req))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body struct {
+		Jobs       []models.Job `json:"jobs"`
+		NextBefore int64        `json:"next_before"`
+	}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Len(t, body.Jobs, 2)
+	assert.Equal(t, int64(98), body.NextBefore)
+}
+
+func TestGetJob(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := NewJobHandler(mockService)
+
+	mockService.On("GetJob", mock.Anything, int64(5)).Return(&models.Job{ID: 5, Type: "email.send"}, nil)
+	mockService.On("GetJob", mock.Anything, int64(6)).Return(nil, services.NewNotFoundError("job_not_found", "job not found"))
+
+	rr := httptest.NewRecorder()
+	handler.GetJob(rr, jobRequest(http.MethodGet, "/jobs/5", "5"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.GetJob(rr, jobRequest(http.MethodGet, "/jobs/6", "6"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.GetJob(rr, jobRequest(http.MethodGet, "/jobs/x", "x"))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestRetryJob(t *testing.T) {
+	mockService := new(MockJobService)
+	handler := NewJobHandler(mockService)
+
+	mockService.On("RetryJob", mock.Anything, int64(5)).Return(&models.Job{ID: 5, Status: models.JobPending}, nil)
+	mockService.On("RetryJob", mock.Anything, int64(6)).
+		Return(nil, services.NewConflictError("job_not_retryable", "only dead and pending jobs can be retried"))
+
+	rr := httptest.NewRecorder()
+	handler.RetryJob(rr, jobRequest(http.MethodPost, "/jobs/5/retry", "5"))
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	var job models.Job
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&job))
+	assert.Equal(t, models.JobPending, job.Status)
+
+	rr = httptest.NewRecorder()
+	handler.RetryJob(rr, jobRequest(http.MethodPost, "/jobs/6/retry", "6"))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
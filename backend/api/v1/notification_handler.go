@@ -0,0 +1,118 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type NotificationHandler struct {
+	Service services.NotificationServiceInterface
+}
+
+// NewNotificationHandler initializes a new NotificationHandler.
+func NewNotificationHandler(service services.NotificationServiceInterface) *NotificationHandler {
+	return &NotificationHandler{Service: service}
+}
+
+// ListNotifications lists the notifications of the authenticated user,
+// newest first, with the number of unread ones. unread=true lists only
+// unread notifications. They are paged through with limit and before, the
+// ID returned as next_before.
+func (h *NotificationHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	unreadOnly := false
+	if value := r.URL.Query().Get("unread"); value != "" {
+		var err error
+		if unreadOnly, err = strconv.ParseBool(value); err != nil {
+			RespondError(w, r, services.NewValidationError("invalid query parameter",
+				services.FieldError{Field: "unread", Message: "must be a boolean"}))
+			return
+		}
+	}
+	before, err := queryInt(r, "before")
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	notifications, unread, err := h.Service.ListNotifications(r.Context(), userID, unreadOnly, int64(before), limit)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{"notifications": notifications, "unread_count": unread}
+	if limit <= 0 {
+		limit = services.DefaultNotificationPageSize
+	}
+	if len(notifications) > 0 && len(notifications) >= limit {
+		response["next_before"] = notifications[len(notifications)-1].ID
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UnreadCount returns the number of unread notifications of the
+// authenticated user.
+func (h *NotificationHandler) UnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	unread, err := h.Service.UnreadCount(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int{"unread_count": unread})
+}
+
+// MarkRead marks a notification of the authenticated user read.
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	notification, err := h.Service.MarkRead(r.Context(), userID, id)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notification)
+}
+
+// MarkAllRead marks the notifications of the authenticated user read. With
+// up_to, only the notifications up to that ID are marked, so that ones the
+// client has not seen yet stay unread.
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	upTo, err := queryInt(r, "up_to")
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	marked, err := h.Service.MarkAllRead(r.Context(), userID, int64(upTo))
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]int64{"marked": marked})
+}
+
+var _ NotificationHandlerInterface = (*NotificationHandler)(nil)
@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockNotificationService is a mock implementation of
+// NotificationServiceInterface.
+type MockNotificationService struct {
+	mock.Mock
+}
+
+func (m *MockNotificationService) ListNotifications(ctx context.Context, userID int, unreadOnly bool, beforeID int64, limit int) ([]models.Notification, int, error) {
+	args := m.Called(ctx, userID, unreadOnly, beforeID, limit)
+	return args.Get(0).([]models.Notification), args.Int(1), args.Error(2)
+}
+
+func (m *MockNotificationService) UnreadCount(ctx context.Context, userID int) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockNotificationService) MarkRead(ctx context.Context, userID int, id int64) (*models.Notification, error) {
+	args := m.Called(ctx, userID, id)
+	notification, _ := args.Get(0).(*models.Notification)
+	return notification, args.Error(1)
+}
+
+func (m *MockNotificationService) MarkAllRead(ctx context.Context, userID int, upToID int64) (int64, error) {
+	args := m.Called(ctx, userID, upToID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestListNotifications(t *testing.T) {
+	mockService := new(MockNotificationService)
+	handler := NewNotificationHandler(mockService)
+
+	mockService.On("ListNotifications", mock.Anything, 1, true, int64(30), 2).
+		Return([]models.Notification{{ID: 29, Type: models.EventTodoOverdue}, {ID: 25, Type: models.EventTodoReminder}}, 7, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ListNotifications(rr, withSession(httptest.NewRequest(http.MethodGet, "/notifications?unread=true&before=30&limit=2", nil)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body struct {
+		Notifications []models.Notification `json:"notifications"`
+		UnreadCount   int                   `json:"unread_count"`
+		NextBefore    int64                 `json:"next_before"`
+	}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Len(t, body.Notifications, 2)
+	assert.Equal(t, 7, body.UnreadCount)
+	assert.Equal(t, int64(25), body.NextBefore)
+
+	rr = httptest.NewRecorder()
+	handler.ListNotifications(rr, withSession(httptest.NewRequest(http.MethodGet, "/notifications?unread=maybe", nil)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	mockService := new(MockNotificationService)
+	handler := NewNotificationHandler(mockService)
+
+	mockService.On("MarkRead", mock.Anything, 1, int64(12)).Return(&models.Notification{ID: 12}, nil)
+	mockService.On("MarkRead", mock.Anything, 1, int64(13)).
+		Return(nil, services.NewNotFoundError("notification_not_found", "notification not found"))
+
+	for id, code := range map[string]int{"12": http.StatusOK, "13": http.StatusNotFound, "x": http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodPost, "/notifications/"+id+"/read", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		rr := httptest.NewRecorder()
+		handler.MarkRead(rr, withSession(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))))
+		assert.Equal(t, code, rr.Code, "notification %s", id)
+	}
+}
+
+func TestMarkAllNotificationsRead(t *testing.T) {
+	mockService := new(MockNotificationService)
+	handler := NewNotificationHandler(mockService)
+
+	mockService.On("MarkAllRead", mock.Anything, 1, int64(29)).Return(int64(4), nil)
+
+	rr := httptest.NewRecorder()
+	handler.MarkAllRead(rr, withSession(httptest.NewRequest(http.MethodPost, "/notifications/read-all?up_to=29", nil)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"marked":4}`, rr.Body.String())
+}
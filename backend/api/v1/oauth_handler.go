@@ -0,0 +1,260 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// oauthConsentPath is the frontend page that asks users to approve an
+// authorization request.
+const oauthConsentPath = "/oauth/consent"
+
+// createOAuthClientRequest is the body accepted by CreateClient.
+type createOAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=255"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required"`
+	// Confidential clients run on a server and get a secret. Browser and
+	// mobile apps cannot keep a secret and rely on PKCE alone.
+	Confidential bool `json:"confidential"`
+}
+
+// createdOAuthClient is the response of CreateClient. It is the only time
+// the client secret is revealed.
+type createdOAuthClient struct {
+	*models.OAuthClient
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// consentRequest is the body accepted by Consent: the parameters of the
+// authorization request and the decision of the user.
+type consentRequest struct {
+	services.OAuthAuthorizationRequest
+	Approve bool `json:"approve"`
+}
+
+// oauthErrorResponse is the error body of the token endpoint (RFC 6749).
+type oauthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type OAuthHandler struct {
+	Service services.OAuthServiceInterface
+	// FrontendURL is the base URL of the consent screen.
+	FrontendURL string
+}
+
+// NewOAuthHandler initializes a new OAuthHandler.
+func NewOAuthHandler(service services.OAuthServiceInterface, frontendURL string) *OAuthHandler {
+	return &OAuthHandler{Service: service, FrontendURL: frontendURL}
+}
+
+// Authorize is the authorization endpoint that clients send users to. Valid
+// requests are passed on to the consent screen of the frontend; invalid ones
+// are sent back to the client when its redirect URI can be trusted.
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	req := authorizationRequest(r.URL.Query())
+	err := h.Service.ValidateAuthorizationRequest(r.Context(), req)
+	var oauthErr *services.OAuthError
+	if errors.As(err, &oauthErr) {
+		http.Redirect(w, r, oauthErr.RedirectURI, http.StatusFound)
+		return
+	} else if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, h.FrontendURL+oauthConsentPath+"?"+r.URL.RawQuery, http.StatusFound)
+}
+
+// GetConsent describes the client and scopes of an authorization request to
+// the authenticated user.
+func (h *OAuthHandler) GetConsent(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	consent, err := h.Service.DescribeConsent(r.Context(), userID, authorizationRequest(r.URL.Query()))
+	if err != nil {
+		RespondError(w, r, consentError(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(consent)
+}
+
+// Consent records whether the authenticated user approves an authorization
+// request and returns the client URL to send the browser to.
+func (h *OAuthHandler) Consent(w http.ResponseWriter, r *http.Request) {
+	var req consentRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	redirectTo, err := h.Service.Authorize(r.Context(), userID, &req.OAuthAuthorizationRequest, req.Approve)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"redirect_to": redirectTo})
+}
+
+// Token is the token endpoint. It accepts form encoded requests and client
+// credentials in either the Authorization header or the form, and answers
+// in the format of RFC 6749 rather than with problem details.
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, r, &services.OAuthError{Code: "invalid_request", Description: "invalid form body"})
+		return
+	}
+
+	req := &services.OAuthTokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		Scope:        r.PostForm.Get("scope"),
+	}
+	// Credentials in the Authorization header are form encoded (RFC 6749 2.3.1)
+	if clientID, secret, ok := r.BasicAuth(); ok {
+		req.ClientID, _ = url.QueryUnescape(clientID)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	token, err := h.Service.Token(r.Context(), req)
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(token)
+}
+
+// CreateClient registers an application owned by the authenticated user.
+func (h *OAuthHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var req createOAuthClientRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	client, secret, err := h.Service.RegisterClient(r.Context(), userID, req.Name, req.RedirectURIs, req.Confidential)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdOAuthClient{OAuthClient: client, ClientSecret: secret})
+}
+
+// ListClients lists the applications registered by the authenticated user.
+func (h *OAuthHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	clients, err := h.Service.ListClients(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(clients)
+}
+
+// DeleteClient removes an application registered by the authenticated user.
+func (h *OAuthHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	if err := h.Service.DeleteClient(r.Context(), userID, chi.URLParam(r, "client_id")); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAuthorizedApps lists the applications the authenticated user has
+// given access to their account.
+func (h *OAuthHandler) ListAuthorizedApps(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	apps, err := h.Service.ListAuthorizedApps(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(apps)
+}
+
+// RevokeAuthorizedApp revokes the access of an application to the account
+// of the authenticated user.
+func (h *OAuthHandler) RevokeAuthorizedApp(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	if err := h.Service.RevokeAuthorizedApp(r.Context(), userID, chi.URLParam(r, "client_id")); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizationRequest reads the parameters of an authorization request.
+func authorizationRequest(q url.Values) *services.OAuthAuthorizationRequest {
+	return &services.OAuthAuthorizationRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scope:               q.Get("scope"),
+		State:               q.Get("state"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+	}
+}
+
+// consentError reports an invalid authorization request to the consent
+// screen. The authorization endpoint has already sent such requests back to
+// the client, so they can only reach it by tampering with the URL.
+func consentError(err error) error {
+	var oauthErr *services.OAuthError
+	if errors.As(err, &oauthErr) {
+		return &services.Error{Kind: services.KindValidation, Code: oauthErr.Code, Message: oauthErr.Description}
+	}
+	return err
+}
+
+// writeOAuthError writes err in the format of RFC 6749. Errors that are not
+// *services.OAuthError are handled by RespondError.
+func writeOAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		RespondError(w, r, err)
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		status = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(oauthErrorResponse{Error: oauthErr.Code, ErrorDescription: oauthErr.Description})
+}
+
+var _ OAuthHandlerInterface = (*OAuthHandler)(nil)
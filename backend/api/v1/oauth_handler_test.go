@@ -0,0 +1,256 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOAuthService is a mock implementation of OAuthServiceInterface.
+type MockOAuthService struct {
+	mock.Mock
+}
+
+func (m *MockOAuthService) RegisterClient(ctx context.Context, ownerID int, name string, redirectURIs []string, confidential bool) (*models.OAuthClient, string, error) {
+	args := m.Called(ctx, ownerID, name, redirectURIs, confidential)
+	return args.Get(0).(*models.OAuthClient), args.String(1), args.Error(2)
+}
+
+func (m *MockOAuthService) ListClients(ctx context.Context, ownerID int) ([]models.OAuthClient, error) {
+	args := m.Called(ctx, ownerID)
+	return args.Get(0).([]models.OAuthClient), args.Error(1)
+}
+
+func (m *MockOAuthService) DeleteClient(ctx context.Context, ownerID int, clientID string) error {
+	args := m.Called(ctx, ownerID, clientID)
+	return args.Error(0)
+}
+
+func (m *MockOAuthService) ValidateAuthorizationRequest(ctx context.Context, req *services.OAuthAuthorizationRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockOAuthService) DescribeConsent(ctx context.Context, userID int, req *services.OAuthAuthorizationRequest) (*models.OAuthConsent, error) {
+	args := m.Called(ctx, userID, req)
+	return args.Get(0).(*models.OAuthConsent), args.Error(1)
+}
+
+func (m *MockOAuthService) Authorize(ctx context.Context, userID int, req *services.OAuthAuthorizationRequest, approve bool) (string, error) {
+	args := m.Called(ctx, userID, req, approve)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockOAuthService) Token(ctx context.Context, req *services.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*models.OAuthTokenResponse), args.Error(1)
+}
+
+func (m *MockOAuthService) AuthenticateAccessToken(ctx context.Context, secret string) (*models.OAuthToken, error) {
+	args := m.Called(ctx, secret)
+	return args.Get(0).(*models.OAuthToken), args.Error(1)
+}
+
+func (m *MockOAuthService) ListAuthorizedApps(ctx context.Context, userID int) ([]models.AuthorizedApp, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.AuthorizedApp), args.Error(1)
+}
+
+func (m *MockOAuthService) RevokeAuthorizedApp(ctx context.Context, userID int, clientID string) error {
+	args := m.Called(ctx, userID, clientID)
+	return args.Error(0)
+}
+
+const testAuthorizeQuery = "response_type=code&client_id=client-1&redirect_uri=https%3A%2F%2Fapp.example.com%2Fcb&scope=todos%3Aread&state=xyz&code_challenge=abc&code_challenge_method=S256"
+
+func testAuthorizationRequest() *services.OAuthAuthorizationRequest {
+	return &services.OAuthAuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            "client-1",
+		RedirectURI:         "https://app.example.com/cb",
+		Scope:               models.ScopeTodosRead,
+		State:               "xyz",
+		CodeChallenge:       "abc",
+		CodeChallengeMethod: "S256",
+	}
+}
+
+func TestOAuthAuthorize_RedirectsToConsent(t *testing.T) {
+	mockService := new(MockOAuthService)
+	handler := NewOAuthHandler(mockService, "http://app.test")
+	mockService.On("ValidateAuthorizationRequest", mock.Anything, testAuthorizationRequest()).Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.Authorize(rr, httptest.NewRequest(http.MethodGet, "/api/v1/oauth/authorize?"+testAuthorizeQuery, nil))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "http://app.test/oauth/consent?"+testAuthorizeQuery, rr.Header().Get("Location"))
+}
+
+func TestOAuthAuthorize_Errors(t *testing.T) {
+	mockService := new(MockOAuthService)
+	handler := NewOAuthHandler(mockService, "http://app.test")
+
+	// Errors with a trusted redirect URI go back to the client
+	mockService.On("ValidateAuthorizationRequest", mock.Anything, mock.Anything).
+		Return(&services.OAuthError{Code: "invalid_scope", RedirectURI: "https://app.example.com/cb?error=invalid_scope"}).Once()
+	rr := httptest.NewRecorder()
+	handler.Authorize(rr, httptest.NewRequest(http.MethodGet, "/api/v1/oauth/authorize?"+testAuthorizeQuery, nil))
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://app.example.com/cb?error=invalid_scope", rr.Header().Get("Location"))
+
+	// Others are shown to the user
+	mockService.On("ValidateAuthorizationRequest", mock.Anything, mock.Anything).
+		Return(services.NewNotFoundError("oauth_client_not_found", "oauth client not found")).Once()
+	rr = httptest.NewRecorder()
+	handler.Authorize(rr, httptest.NewRequest(http.MethodGet, "/api/v1/oauth/authorize?"+testAuthorizeQuery, nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Empty(t, rr.Header().Get("Location"))
+}
+
+func TestOAuthGetConsent(t *testing.T) {
+	mockService := new(MockOAuthService)
+	handler := NewOAuthHandler(mockService, "http://app.test")
+	consent := &models.OAuthConsent{ClientID: "client-1", ClientName: "Todo Sync", Scopes: []models.OAuthScope{{Name: models.ScopeTodosRead}}}
+	mockService.On("DescribeConsent", mock.Anything, 1, testAuthorizationRequest()).Return(consent, nil)
+
+	rr := httptest.NewRecorder()
+	handler.GetConsent(rr, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/oauth/consent?"+testAuthorizeQuery, nil)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body models.OAuthConsent
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, *consent, body)
+}
+
+func TestOAuthGetConsent_InvalidRequest(t *testing.T) {
+	mockService := new(MockOAuthService)
+	handler := NewOAuthHandler(mockService, "http://app.test")
+	mockService.On("DescribeConsent", mock.Anything, 1, mock.Anything).
+		Return((*models.OAuthConsent)(nil), &services.OAuthError{Code: "invalid_scope", Description: "unknown scope", RedirectURI: "https://app.example.com/cb"})
+
+	rr := httptest.NewRecorder()
+	handler.GetConsent(rr, withSession(httptest.NewRequest(http.MethodGet, "/api/v1/oauth/consent?"+testAuthorizeQuery, nil)))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var problem Problem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	assert.Equal(t, "invalid_scope", problem.Code)
+}
+
+func TestOAuthConsent(t *testing.T) {
+	mockService := new(MockOAuthService)
+	handler := NewOAuthHandler(mockService, "http://app.test")
+	mockService.On("Authorize", mock.Anything, 1, testAuthorizationRequest(), true).Return("https://app.example.com/cb?code=c&state=xyz", nil)
+
+	body := consentRequest{OAuthAuthorizationRequest: *testAuthorizationRequest(), Approve: true}
+	rr := httptest.NewRecorder()
+	handler.Consent(rr, withSession(jsonRequest(http.MethodPost, "/api/v1/oauth/consent", body)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp map[string]string
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, "https://app.example.com/cb?code=c&state=xyz", resp["redirect_to"])
+}
+
+func TestOAuthToken(t *testing.T) {
+	mockService := new(MockOAuthService)
+	handler := NewOAuthHandler(mockService, "http://app.test")
+	mockService.On("Token", mock.Anything, &services.OAuthTokenRequest{
+		GrantType:    "authorization_code",
+		ClientID:     "server app",
+		ClientSecret: "s3cret+",
+		Code:         "c",
+		RedirectURI:  "https://app.example.com/cb",
+		CodeVerifier: "v",
+	}).Return(&models.OAuthTokenResponse{AccessToken: "todo_oat_a", TokenType: "Bearer", ExpiresIn: 3600, RefreshToken: "todo_ort_r", Scope: "todos:read"}, nil)
+
+	form := url.Values{"grant_type": {"authorization_code"}, "code": {"c"}, "redirect_uri": {"https://app.example.com/cb"}, "code_verifier": {"v"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape("server app"), url.QueryEscape("s3cret+"))
+
+	rr := httptest.NewRecorder()
+	handler.Token(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "todo_oat_a", body["access_token"])
+	assert.Equal(t, "todo_ort_r", body["refresh_token"])
+	assert.Equal(t, float64(3600), body["expires_in"])
+}
+
+func TestOAuthToken_Errors(t *testing.T) {
+	mockService := new(MockOAuthService)
+	handler := NewOAuthHandler(mockService, "http://app.test")
+
+	tests := []struct {
+		err    *services.OAuthError
+		status int
+	}{
+		{&services.OAuthError{Code: "invalid_client", Description: "client authentication failed"}, http.StatusUnauthorized},
+		{&services.OAuthError{Code: "invalid_grant", Description: "PKCE verification failed"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		mockService.On("Token", mock.Anything, mock.Anything).Return((*models.OAuthTokenResponse)(nil), tt.err).Once()
+
+		form := url.Values{"grant_type": {"authorization_code"}, "client_id": {"client-1"}}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		handler.Token(rr, req)
+
+		assert.Equal(t, tt.status, rr.Code)
+		var body oauthErrorResponse
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+		assert.Equal(t, oauthErrorResponse{Error: tt.err.Code, ErrorDescription: tt.err.Description}, body)
+	}
+}
+
+func TestCreateOAuthClient(t *testing.T) {
+	mockService := new(MockOAuthService)
+	handler := NewOAuthHandler(mockService, "http://app.test")
+	uris := []string{"https://app.example.com/cb"}
+	mockService.On("RegisterClient", mock.Anything, 1, "Todo Sync", uris, true).
+		Return(&models.OAuthClient{ClientID: "client-1", Name: "Todo Sync", RedirectURIs: uris, Confidential: true, SecretHash: "hash"}, "todo_ocs_secret", nil)
+
+	rr := httptest.NewRecorder()
+	handler.CreateClient(rr, withSession(jsonRequest(http.MethodPost, "/me/oauth/clients", createOAuthClientRequest{Name: "Todo Sync", RedirectURIs: uris, Confidential: true})))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "client-1", body["client_id"])
+	assert.Equal(t, "todo_ocs_secret", body["client_secret"])
+	assert.NotContains(t, body, "secret_hash")
+}
+
+func TestRevokeAuthorizedApp(t *testing.T) {
+	mockService := new(MockOAuthService)
+	handler := NewOAuthHandler(mockService, "http://app.test")
+	mockService.On("RevokeAuthorizedApp", mock.Anything, 1, "client-1").Return(nil)
+
+	req := withSession(httptest.NewRequest(http.MethodDelete, "/me/authorized-apps/client-1", nil))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("client_id", "client-1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	handler.RevokeAuthorizedApp(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// oidcStateCookie binds a login to the browser that started it, so that
+	// nobody can be signed in by following a callback link of someone else.
+	oidcStateCookie = "oidc_state"
+	// oidcCallbackPath is the frontend page that receives the login outcome
+	// in its URL fragment.
+	oidcCallbackPath = "/login/oidc"
+)
+
+type OIDCHandler struct {
+	OIDCService      services.OIDCServiceInterface
+	SessionService   services.SessionServiceInterface
+	TwoFactorService services.TwoFactorServiceInterface
+	// FrontendURL is the base URL that users are sent back to after a login.
+	FrontendURL string
+}
+
+// NewOIDCHandler initializes a new OIDCHandler.
+func NewOIDCHandler(oidcService services.OIDCServiceInterface, sessionService services.SessionServiceInterface, twoFactorService services.TwoFactorServiceInterface, frontendURL string) *OIDCHandler {
+	return &OIDCHandler{
+		OIDCService:      oidcService,
+		SessionService:   sessionService,
+		TwoFactorService: twoFactorService,
+		FrontendURL:      frontendURL,
+	}
+}
+
+// ListProviders returns the identity providers users can sign in with.
+func (h *OIDCHandler) ListProviders(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.OIDCService.ListProviders())
+}
+
+// Login redirects the browser to the login page of a provider.
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	authURL, state, err := h.OIDCService.BeginLogin(r.Context(), provider)
+	if err != nil {
+		h.redirectError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     path.Dir(r.URL.Path),
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		// Lax cookies are sent on the top-level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback completes a login when the provider redirects back. The browser
+// is sent to the frontend with either a session token, a two-factor
+// challenge or an error code in the URL fragment.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: path.Dir(r.URL.Path), MaxAge: -1, HttpOnly: true, Secure: isHTTPS(r)})
+
+	q := r.URL.Query()
+	if code := q.Get("error"); code != "" {
+		h.redirect(w, r, url.Values{"error": {code}})
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	state := q.Get("state")
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		h.redirectError(w, r, services.NewUnauthorizedError("invalid_oidc_state", "login request is invalid or has expired"))
+		return
+	}
+
+	user, err := h.OIDCService.CompleteLogin(r.Context(), provider, state, q.Get("code"))
+	if err != nil {
+		h.redirectError(w, r, err)
+		return
+	}
+
+	// Accounts with two-factor authentication still need their second factor
+	mfaEnabled, err := h.TwoFactorService.IsEnabled(r.Context(), user.ID)
+	if err != nil {
+		h.redirectError(w, r, err)
+		return
+	}
+	if mfaEnabled {
+		challenge, err := h.TwoFactorService.NewChallenge(r.Context(), user.ID)
+		if err != nil {
+			h.redirectError(w, r, err)
+			return
+		}
+		h.redirect(w, r, url.Values{"mfa_token": {challenge}})
+		return
+	}
+
+	token, err := h.SessionService.StartSession(r.Context(), user.ID, clientIP(r), r.UserAgent())
+	if err != nil {
+		h.redirectError(w, r, err)
+		return
+	}
+	h.redirect(w, r, url.Values{"token": {token}})
+}
+
+// redirect sends the browser to the frontend login page with params in the
+// URL fragment, which browsers never send to servers.
+func (h *OIDCHandler) redirect(w http.ResponseWriter, r *http.Request, params url.Values) {
+	http.Redirect(w, r, h.FrontendURL+oidcCallbackPath+"#"+params.Encode(), http.StatusFound)
+}
+
+// redirectError sends the browser to the frontend with the code of err.
+// Like RespondError, it never reveals internal errors to the client.
+func (h *OIDCHandler) redirectError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID := middleware.GetReqID(r.Context())
+	var domainErr *services.Error
+	if !errors.As(err, &domainErr) || domainErr.Kind == services.KindInternal {
+		log.Printf("internal error [request_id=%s]: %v", requestID, err)
+		h.redirect(w, r, url.Values{"error": {"internal_error"}})
+		return
+	}
+
+	// Keep the reason why a provider rejected the login for debugging
+	if domainErr.Err != nil {
+		log.Printf("oidc login failed [request_id=%s]: %v", requestID, domainErr.Err)
+	}
+	h.redirect(w, r, url.Values{"error": {domainErr.Code}})
+}
+
+// isHTTPS reports whether the client connected over HTTPS, directly or
+// through a proxy.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+var _ OIDCHandlerInterface = (*OIDCHandler)(nil)
@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockOIDCService is a mock implementation of OIDCServiceInterface.
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) ListProviders() []models.OIDCProvider {
+	args := m.Called()
+	return args.Get(0).([]models.OIDCProvider)
+}
+
+func (m *MockOIDCService) BeginLogin(ctx context.Context, providerName string) (string, string, error) {
+	args := m.Called(ctx, providerName)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockOIDCService) CompleteLogin(ctx context.Context, providerName, state, code string) (*models.User, error) {
+	args := m.Called(ctx, providerName, state, code)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+// newOIDCRequest returns a request to target routed with the given provider.
+func newOIDCRequest(target, provider string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("provider", provider)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+// fragment returns the parameters in the fragment of the redirect location.
+func fragment(t *testing.T, rr *httptest.ResponseRecorder) url.Values {
+	t.Helper()
+	location, err := url.Parse(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/login/oidc", location.Path)
+	params, err := url.ParseQuery(location.Fragment)
+	require.NoError(t, err)
+	return params
+}
+
+func TestOIDCLogin(t *testing.T) {
+	mockService := new(MockOIDCService)
+	handler := NewOIDCHandler(mockService, nil, nil, "http://app.test")
+
+	mockService.On("BeginLogin", mock.Anything, "corp").Return("https://idp.test/authorize?state=abc", "abc", nil)
+
+	rr := httptest.NewRecorder()
+	handler.Login(rr, newOIDCRequest("/api/v1/auth/oidc/corp/login", "corp"))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://idp.test/authorize?state=abc", rr.Header().Get("Location"))
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, oidcStateCookie, cookies[0].Name)
+	assert.Equal(t, "abc", cookies[0].Value)
+	assert.Equal(t, "/api/v1/auth/oidc/corp", cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+}
+
+func TestOIDCCallback(t *testing.T) {
+	mockService := new(MockOIDCService)
+	mockSessions := new(MockSessionService)
+	mockTwoFactor := new(MockTwoFactorService)
+	handler := NewOIDCHandler(mockService, mockSessions, mockTwoFactor, "http://app.test")
+
+	mockService.On("CompleteLogin", mock.Anything, "corp", "abc", "the-code").Return(&models.User{ID: 7}, nil)
+	mockTwoFactor.On("IsEnabled", mock.Anything, 7).Return(false, nil)
+	mockSessions.On("StartSession", mock.Anything, 7, mock.Anything, mock.Anything).Return("session-token", nil)
+
+	req := newOIDCRequest("/api/v1/auth/oidc/corp/callback?state=abc&code=the-code", "corp")
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "abc"})
+	rr := httptest.NewRecorder()
+	handler.Callback(rr, req)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "session-token", fragment(t, rr).Get("token"))
+	mockSessions.AssertExpectations(t)
+}
+
+func TestOIDCCallback_MFARequired(t *testing.T) {
+	mockService := new(MockOIDCService)
+	mockSessions := new(MockSessionService)
+	mockTwoFactor := new(MockTwoFactorService)
+	handler := NewOIDCHandler(mockService, mockSessions, mockTwoFactor, "http://app.test")
+
+	mockService.On("CompleteLogin", mock.Anything, "corp", "abc", "the-code").Return(&models.User{ID: 7}, nil)
+	mockTwoFactor.On("IsEnabled", mock.Anything, 7).Return(true, nil)
+	mockTwoFactor.On("NewChallenge", mock.Anything, 7).Return("mfa-challenge", nil)
+
+	req := newOIDCRequest("/api/v1/auth/oidc/corp/callback?state=abc&code=the-code", "corp")
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "abc"})
+	rr := httptest.NewRecorder()
+	handler.Callback(rr, req)
+
+	params := fragment(t, rr)
+	assert.Equal(t, "mfa-challenge", params.Get("mfa_token"))
+	assert.Empty(t, params.Get("token"))
+	mockSessions.AssertNotCalled(t, "StartSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOIDCCallback_StateMismatch(t *testing.T) {
+	mockService := new(MockOIDCService)
+	handler := NewOIDCHandler(mockService, nil, nil, "http://app.test")
+
+	// A callback link started in another browser has no matching cookie
+	req := newOIDCRequest("/api/v1/auth/oidc/corp/callback?state=abc&code=the-code", "corp")
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "xyz"})
+	rr := httptest.NewRecorder()
+	handler.Callback(rr, req)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "invalid_oidc_state", fragment(t, rr).Get("error"))
+	mockService.AssertNotCalled(t, "CompleteLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOIDCCallback_Error(t *testing.T) {
+	mockService := new(MockOIDCService)
+	handler := NewOIDCHandler(mockService, nil, nil, "http://app.test")
+
+	mockService.On("CompleteLogin", mock.Anything, "corp", "abc", "the-code").
+		Return((*models.User)(nil), services.NewForbiddenError("oidc_email_unverified", "unverified"))
+
+	req := newOIDCRequest("/api/v1/auth/oidc/corp/callback?state=abc&code=the-code", "corp")
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "abc"})
+	rr := httptest.NewRecorder()
+	handler.Callback(rr, req)
+
+	assert.Equal(t, "oidc_email_unverified", fragment(t, rr).Get("error"))
+}
@@ -0,0 +1,125 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"todo_app_backend/internal/app/services"
+)
+
+// updateProfileRequest is the body accepted by UpdateProfile. Omitted fields
+// are left unchanged.
+type updateProfileRequest struct {
+	Name  *string `json:"name" validate:"max=255"`
+	Email *string `json:"email" validate:"email,max=255"`
+}
+
+// changePasswordRequest is the body accepted by ChangePassword.
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=255"`
+	NewPassword     string `json:"new_password" validate:"required,max=255"`
+}
+
+// deleteAccountRequest is the body accepted by DeleteAccount.
+type deleteAccountRequest struct {
+	Password string `json:"password" validate:"required,max=255"`
+}
+
+type ProfileHandler struct {
+	ProfileService services.ProfileServiceInterface
+}
+
+// NewProfileHandler initializes a new ProfileHandler.
+func NewProfileHandler(profileService services.ProfileServiceInterface) *ProfileHandler {
+	return &ProfileHandler{ProfileService: profileService}
+}
+
+// GetProfile returns the authenticated user's account.
+func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	user, err := h.ProfileService.GetProfile(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateProfile changes the name and/or email of the authenticated user.
+// It responds with 202 when an email change awaits confirmation.
+func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	var req updateProfileRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	user, emailPending, err := h.ProfileService.UpdateProfile(r.Context(), userID, services.ProfileUpdate{Name: req.Name, Email: req.Email})
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	if emailPending {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(user)
+}
+
+// ChangePassword sets a new password for the authenticated user and signs
+// out their other sessions.
+func (h *ProfileHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req changePasswordRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	sessionID, _ := r.Context().Value("sessionID").(int)
+	if err := h.ProfileService.ChangePassword(r.Context(), userID, sessionID, req.CurrentPassword, req.NewPassword); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAccount schedules the authenticated user's account for deletion.
+func (h *ProfileHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	var req deleteAccountRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	sessionID, _ := r.Context().Value("sessionID").(int)
+	user, err := h.ProfileService.DeleteAccount(r.Context(), userID, sessionID, req.Password)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(user)
+}
+
+// RestoreAccount cancels a scheduled deletion of the authenticated user's account.
+func (h *ProfileHandler) RestoreAccount(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	user, err := h.ProfileService.CancelDeletion(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+var _ ProfileHandlerInterface = (*ProfileHandler)(nil)
@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockProfileService is a mock implementation of ProfileServiceInterface.
+type MockProfileService struct {
+	mock.Mock
+}
+
+func (m *MockProfileService) GetProfile(ctx context.Context, userID int) (*models.User, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockProfileService) UpdateProfile(ctx context.Context, userID int, update services.ProfileUpdate) (*models.User, bool, error) {
+	args := m.Called(ctx, userID, update)
+	return args.Get(0).(*models.User), args.Bool(1), args.Error(2)
+}
+
+func (m *MockProfileService) ChangePassword(ctx context.Context, userID int, sessionID int, currentPassword, newPassword string) error {
+	args := m.Called(ctx, userID, sessionID, currentPassword, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockProfileService) DeleteAccount(ctx context.Context, userID int, sessionID int, password string) (*models.User, error) {
+	args := m.Called(ctx, userID, sessionID, password)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockProfileService) CancelDeletion(ctx context.Context, userID int) (*models.User, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockProfileService) PurgeDeletedAccounts(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// withSession returns r as authenticated by user 1 in session 10.
+func withSession(r *http.Request) *http.Request {
+	ctx := context.WithValue(r.Context(), "userID", 1)
+	ctx = context.WithValue(ctx, "sessionID", 10)
+	return r.WithContext(ctx)
+}
+
+func TestGetProfile(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("GetProfile", mock.Anything, 1).Return(&models.User{ID: 1, Name: "John", Password: "hash"}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.GetProfile(rr, withSession(httptest.NewRequest(http.MethodGet, "/me", nil)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "hash", "password hashes must never be serialized")
+}
+
+func TestUpdateProfile(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	email := "new@example.com"
+	mockService.On("UpdateProfile", mock.Anything, 1, services.ProfileUpdate{Email: &email}).
+		Return(&models.User{ID: 1, Email: "old@example.com"}, true, nil)
+
+	rr := httptest.NewRecorder()
+	handler.UpdateProfile(rr, withSession(jsonRequest(http.MethodPatch, "/me", map[string]string{"email": email})))
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.UpdateProfile(rr, withSession(jsonRequest(http.MethodPatch, "/me", map[string]string{"email": "invalid"})))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestChangePassword(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("ChangePassword", mock.Anything, 1, 10, "old", "new").Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.ChangePassword(rr, withSession(jsonRequest(http.MethodPut, "/me/password", changePasswordRequest{CurrentPassword: "old", NewPassword: "new"})))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	at := time.Now().Add(14 * 24 * time.Hour)
+	mockService.On("DeleteAccount", mock.Anything, 1, 10, "password").Return(&models.User{ID: 1, DeletionScheduledAt: &at}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.DeleteAccount(rr, withSession(jsonRequest(http.MethodDelete, "/me", deleteAccountRequest{Password: "password"})))
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	var user models.User
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&user))
+	assert.NotNil(t, user.DeletionScheduledAt)
+}
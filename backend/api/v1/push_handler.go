@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// pushSubscriptionRequest is the body accepted when subscribing a browser,
+// the result of PushSubscription.toJSON().
+type pushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" validate:"required,max=2048"`
+	// ExpirationTime is sent by browsers but not used
+	ExpirationTime *int64 `json:"expirationTime"`
+	// Keys are checked by the service, as nested fields are not validated
+	Keys struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+type PushHandler struct {
+	Service services.PushServiceInterface
+}
+
+// NewPushHandler initializes a new PushHandler.
+func NewPushHandler(service services.PushServiceInterface) *PushHandler {
+	return &PushHandler{Service: service}
+}
+
+// ListSubscriptions lists the browsers subscribed by the authenticated user,
+// along with the VAPID public key browsers subscribe with. The key is empty
+// when Web Push is not configured.
+func (h *PushHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	subs, err := h.Service.ListSubscriptions(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"public_key": h.Service.PublicKey(), "subscriptions": subs})
+}
+
+// Subscribe registers a browser of the authenticated user for push
+// notifications.
+func (h *PushHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	var req pushSubscriptionRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	sub, err := h.Service.Subscribe(r.Context(), userID, req.Endpoint, req.Keys.P256dh, req.Keys.Auth)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
+
+// Unsubscribe removes a browser subscription of the authenticated user.
+func (h *PushHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	if err := h.Service.Unsubscribe(r.Context(), userID, id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var _ PushHandlerInterface = (*PushHandler)(nil)
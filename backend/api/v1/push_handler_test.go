@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPushService is a mock implementation of PushServiceInterface.
+type MockPushService struct {
+	mock.Mock
+}
+
+func (m *MockPushService) PublicKey() string {
+	return m.Called().String(0)
+}
+
+func (m *MockPushService) ListSubscriptions(ctx context.Context, userID int) ([]models.PushSubscription, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.PushSubscription), args.Error(1)
+}
+
+func (m *MockPushService) Subscribe(ctx context.Context, userID int, endpoint, p256dh, auth string) (*models.PushSubscription, error) {
+	args := m.Called(ctx, userID, endpoint, p256dh, auth)
+	sub, _ := args.Get(0).(*models.PushSubscription)
+	return sub, args.Error(1)
+}
+
+func (m *MockPushService) Unsubscribe(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func TestListPushSubscriptions(t *testing.T) {
+	mockService := new(MockPushService)
+	handler := NewPushHandler(mockService)
+
+	mockService.On("PublicKey").Return("BPublicKey")
+	mockService.On("ListSubscriptions", mock.Anything, 1).
+		Return([]models.PushSubscription{{ID: 5, Endpoint: "https://push.example.com/abc", P256dh: "key", Auth: "secret"}}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ListSubscriptions(rr, withSession(httptest.NewRequest(http.MethodGet, "/me/push-subscriptions", nil)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "BPublicKey", body["public_key"])
+	// Keys are never returned
+	assert.NotContains(t, rr.Body.String(), "secret")
+}
+
+func TestSubscribePush(t *testing.T) {
+	mockService := new(MockPushService)
+	handler := NewPushHandler(mockService)
+
+	mockService.On("Subscribe", mock.Anything, 1, "https://push.example.com/abc", "key", "secret").
+		Return(&models.PushSubscription{ID: 5, Endpoint: "https://push.example.com/abc"}, nil)
+
+	// The body is what PushSubscription.toJSON() returns in browsers
+	body := map[string]interface{}{
+		"endpoint":       "https://push.example.com/abc",
+		"expirationTime": nil,
+		"keys":           map[string]string{"p256dh": "key", "auth": "secret"},
+	}
+	rr := httptest.NewRecorder()
+	handler.Subscribe(rr, withSession(jsonRequest(http.MethodPost, "/me/push-subscriptions", body)))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,89 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// createInviteRequest is the body accepted by CreateInvite.
+type createInviteRequest struct {
+	Note      string     `json:"note" validate:"max=255"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// createdInvite is the response of CreateInvite. It is the only time the
+// invite code is revealed.
+type createdInvite struct {
+	*models.InviteCode
+	Code string `json:"code"`
+}
+
+type RegistrationHandler struct {
+	Service services.RegistrationServiceInterface
+}
+
+// NewRegistrationHandler initializes a new RegistrationHandler.
+func NewRegistrationHandler(service services.RegistrationServiceInterface) *RegistrationHandler {
+	return &RegistrationHandler{Service: service}
+}
+
+// GetSettings tells clients how users sign up, e.g. whether the signup
+// form should ask for an invite code.
+func (h *RegistrationHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"mode": h.Service.Mode()})
+}
+
+// CreateInvite issues a single-use invite code.
+func (h *RegistrationHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	var req createInviteRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	adminID, _ := r.Context().Value("userID").(int)
+	invite, code, err := h.Service.CreateInvite(r.Context(), adminID, req.Note, req.ExpiresAt)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdInvite{InviteCode: invite, Code: code})
+}
+
+// ListInvites lists every invite code, used or not.
+func (h *RegistrationHandler) ListInvites(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.Service.ListInvites(r.Context())
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invites)
+}
+
+// DeleteInvite revokes an invite code.
+func (h *RegistrationHandler) DeleteInvite(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.Service.DeleteInvite(r.Context(), id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var _ RegistrationHandlerInterface = (*RegistrationHandler)(nil)
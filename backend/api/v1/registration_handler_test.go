@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRegistrationService is a mock implementation of RegistrationServiceInterface.
+type MockRegistrationService struct {
+	mock.Mock
+}
+
+func (m *MockRegistrationService) Mode() string {
+	return m.Called().String(0)
+}
+
+func (m *MockRegistrationService) CreateInvite(ctx context.Context, adminID int, note string, expiresAt *time.Time) (*models.InviteCode, string, error) {
+	args := m.Called(ctx, adminID, note, expiresAt)
+	return args.Get(0).(*models.InviteCode), args.String(1), args.Error(2)
+}
+
+func (m *MockRegistrationService) ListInvites(ctx context.Context) ([]models.InviteCode, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.InviteCode), args.Error(1)
+}
+
+func (m *MockRegistrationService) DeleteInvite(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestGetRegistrationSettings(t *testing.T) {
+	mockService := new(MockRegistrationService)
+	handler := NewRegistrationHandler(mockService)
+
+	mockService.On("Mode").Return(models.RegistrationInviteOnly)
+
+	rr := httptest.NewRecorder()
+	handler.GetSettings(rr, httptest.NewRequest(http.MethodGet, "/auth/registration", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"mode":"invite_only"}`, rr.Body.String())
+}
+
+func TestCreateInvite(t *testing.T) {
+	mockService := new(MockRegistrationService)
+	handler := NewRegistrationHandler(mockService)
+
+	adminID := 1
+	mockService.On("CreateInvite", mock.Anything, 1, "for Jane", (*time.Time)(nil)).
+		Return(&models.InviteCode{ID: 4, Prefix: "todo_inv_abcdef", Note: "for Jane", CreatedBy: &adminID}, "todo_inv_abcdefsecret", nil)
+
+	rr := httptest.NewRecorder()
+	handler.CreateInvite(rr, withSession(jsonRequest(http.MethodPost, "/invites", map[string]string{"note": "for Jane"})))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "todo_inv_abcdefsecret", body["code"])
+	assert.Equal(t, "todo_inv_abcdef", body["prefix"])
+	assert.NotContains(t, body, "code_hash")
+}
+
+func TestDeleteInvite(t *testing.T) {
+	mockService := new(MockRegistrationService)
+	handler := NewRegistrationHandler(mockService)
+
+	mockService.On("DeleteInvite", mock.Anything, 4).Return(nil)
+	mockService.On("DeleteInvite", mock.Anything, 5).Return(services.NewNotFoundError("invite_not_found", "invite code not found"))
+
+	rr := httptest.NewRecorder()
+	handler.DeleteInvite(rr, withSession(withURLParams(httptest.NewRequest(http.MethodDelete, "/invites/4", nil), map[string]string{"id": "4"})))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.DeleteInvite(rr, withSession(withURLParams(httptest.NewRequest(http.MethodDelete, "/invites/5", nil), map[string]string{"id": "5"})))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
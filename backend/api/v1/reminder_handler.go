@@ -0,0 +1,179 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// reminderRequest is the body accepted when creating a reminder: either a
+// time or a number of minutes before the due date of the todo.
+type reminderRequest struct {
+	RemindAt      *time.Time `json:"remind_at"`
+	BeforeMinutes *int       `json:"before_minutes"`
+}
+
+// preferencesRequest is the body accepted when updating notification
+// preferences.
+type preferencesRequest struct {
+	Channels        []string `json:"channels" validate:"required"`
+	Timezone        string   `json:"timezone" validate:"max=64"`
+	QuietHoursStart string   `json:"quiet_hours_start" validate:"max=5"`
+	QuietHoursEnd   string   `json:"quiet_hours_end" validate:"max=5"`
+	DigestFrequency string   `json:"digest_frequency" validate:"max=16"`
+}
+
+type ReminderHandler struct {
+	Service services.ReminderServiceInterface
+}
+
+// NewReminderHandler initializes a new ReminderHandler.
+func NewReminderHandler(service services.ReminderServiceInterface) *ReminderHandler {
+	return &ReminderHandler{Service: service}
+}
+
+// ListReminders lists the reminders of a todo of the authenticated user.
+func (h *ReminderHandler) ListReminders(w http.ResponseWriter, r *http.Request) {
+	todoID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	reminders, err := h.Service.ListReminders(r.Context(), userID, todoID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reminders)
+}
+
+// CreateReminder adds a reminder to a todo of the authenticated user.
+func (h *ReminderHandler) CreateReminder(w http.ResponseWriter, r *http.Request) {
+	todoID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	var req reminderRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	reminder, err := h.Service.CreateReminder(r.Context(), userID, todoID, req.RemindAt, req.BeforeMinutes)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reminder)
+}
+
+// DeleteReminder removes a reminder of a todo of the authenticated user.
+func (h *ReminderHandler) DeleteReminder(w http.ResponseWriter, r *http.Request) {
+	todoID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "reminder_id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	if err := h.Service.DeleteReminder(r.Context(), userID, todoID, id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SnoozeReminder makes a reminder of the authenticated user go off again
+// after the number of minutes given by the minutes query parameter, by
+// default services.DefaultSnooze. It takes no body, so that the snooze
+// action of push notifications can call it as is.
+func (h *ReminderHandler) SnoozeReminder(w http.ResponseWriter, r *http.Request) {
+	todoID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+	id, err := strconv.Atoi(chi.URLParam(r, "reminder_id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+	minutes, err := queryInt(r, "minutes")
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	reminder, err := h.Service.SnoozeReminder(r.Context(), userID, todoID, id, time.Duration(minutes)*time.Minute)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reminder)
+}
+
+// GetPreferences retrieves the notification preferences of the
+// authenticated user.
+func (h *ReminderHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	prefs, err := h.Service.GetPreferences(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// UpdatePreferences replaces the notification preferences of the
+// authenticated user.
+func (h *ReminderHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var req preferencesRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	prefs, err := h.Service.UpdatePreferences(r.Context(), &models.NotificationPreferences{
+		UserID:          userID,
+		Channels:        req.Channels,
+		Timezone:        req.Timezone,
+		QuietHoursStart: req.QuietHoursStart,
+		QuietHoursEnd:   req.QuietHoursEnd,
+		DigestFrequency: req.DigestFrequency,
+	})
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(prefs)
+}
+
+var _ ReminderHandlerInterface = (*ReminderHandler)(nil)
@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReminderService is a mock implementation of ReminderServiceInterface.
+type MockReminderService struct {
+	mock.Mock
+}
+
+func (m *MockReminderService) ListReminders(ctx context.Context, userID int, todoID int) ([]models.Reminder, error) {
+	args := m.Called(ctx, userID, todoID)
+	return args.Get(0).([]models.Reminder), args.Error(1)
+}
+
+func (m *MockReminderService) CreateReminder(ctx context.Context, userID int, todoID int, remindAt *time.Time, beforeMinutes *int) (*models.Reminder, error) {
+	args := m.Called(ctx, userID, todoID, remindAt, beforeMinutes)
+	reminder, _ := args.Get(0).(*models.Reminder)
+	return reminder, args.Error(1)
+}
+
+func (m *MockReminderService) DeleteReminder(ctx context.Context, userID int, todoID int, id int) error {
+	args := m.Called(ctx, userID, todoID, id)
+	return args.Error(0)
+}
+
+func (m *MockReminderService) SnoozeReminder(ctx context.Context, userID int, todoID int, id int, d time.Duration) (*models.Reminder, error) {
+	args := m.Called(ctx, userID, todoID, id, d)
+	reminder, _ := args.Get(0).(*models.Reminder)
+	return reminder, args.Error(1)
+}
+
+func (m *MockReminderService) GetPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error) {
+	args := m.Called(ctx, userID)
+	prefs, _ := args.Get(0).(*models.NotificationPreferences)
+	return prefs, args.Error(1)
+}
+
+func (m *MockReminderService) UpdatePreferences(ctx context.Context, prefs *models.NotificationPreferences) (*models.NotificationPreferences, error) {
+	args := m.Called(ctx, prefs)
+	updated, _ := args.Get(0).(*models.NotificationPreferences)
+	return updated, args.Error(1)
+}
+
+// newReminderRequest returns a request of the session user with the todo and
+// reminder IDs as URL parameters.
+func newReminderRequest(req *http.Request, todoID, reminderID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", todoID)
+	if reminderID != "" {
+		rctx.URLParams.Add("reminder_id", reminderID)
+	}
+	return withSession(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+}
+
+func TestCreateReminder(t *testing.T) {
+	mockService := new(MockReminderService)
+	handler := NewReminderHandler(mockService)
+
+	before := 30
+	mockService.On("CreateReminder", mock.Anything, 1, 3, (*time.Time)(nil), &before).
+		Return(&models.Reminder{ID: 7, TodoID: 3, BeforeMinutes: &before}, nil)
+	mockService.On("CreateReminder", mock.Anything, 1, 4, (*time.Time)(nil), &before).
+		Return(nil, services.NewNotFoundError("todo_not_found", "todo not found"))
+
+	rr := httptest.NewRecorder()
+	handler.CreateReminder(rr, newReminderRequest(jsonRequest(http.MethodPost, "/todos/3/reminders", map[string]int{"before_minutes": 30}), "3", ""))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var reminder models.Reminder
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&reminder))
+	assert.Equal(t, 7, reminder.ID)
+
+	rr = httptest.NewRecorder()
+	handler.CreateReminder(rr, newReminderRequest(jsonRequest(http.MethodPost, "/todos/4/reminders", map[string]int{"before_minutes": 30}), "4", ""))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestSnoozeReminder(t *testing.T) {
+	mockService := new(MockReminderService)
+	handler := NewReminderHandler(mockService)
+
+	mockService.On("SnoozeReminder", mock.Anything, 1, 3, 7, time.Duration(0)).Return(&models.Reminder{ID: 7}, nil)
+	mockService.On("SnoozeReminder", mock.Anything, 1, 3, 7, 15*time.Minute).Return(&models.Reminder{ID: 7}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.SnoozeReminder(rr, newReminderRequest(httptest.NewRequest(http.MethodPost, "/todos/3/reminders/7/snooze", nil), "3", "7"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.SnoozeReminder(rr, newReminderRequest(httptest.NewRequest(http.MethodPost, "/todos/3/reminders/7/snooze?minutes=15", nil), "3", "7"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockService.AssertExpectations(t)
+
+	rr = httptest.NewRecorder()
+	handler.SnoozeReminder(rr, newReminderRequest(httptest.NewRequest(http.MethodPost, "/todos/3/reminders/x/snooze", nil), "3", "x"))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestUpdateNotificationPreferences(t *testing.T) {
+	mockService := new(MockReminderService)
+	handler := NewReminderHandler(mockService)
+
+	mockService.On("UpdatePreferences", mock.Anything, &models.NotificationPreferences{
+		UserID: 1, Channels: []string{"push"}, Timezone: "Europe/Paris", QuietHoursStart: "22:00", QuietHoursEnd: "07:00",
+		DigestFrequency: "daily",
+	}).Return(&models.NotificationPreferences{Channels: []string{"push"}, Timezone: "Europe/Paris"}, nil)
+
+	rr := httptest.NewRecorder()
+	body := map[string]interface{}{
+		"channels": []string{"push"}, "timezone": "Europe/Paris", "quiet_hours_start": "22:00", "quiet_hours_end": "07:00",
+		"digest_frequency": "daily",
+	}
+	handler.UpdatePreferences(rr, withSession(jsonRequest(http.MethodPut, "/me/notification-preferences", body)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Channels must be given, if only as an empty list
+	rr = httptest.NewRecorder()
+	handler.UpdatePreferences(rr, withSession(jsonRequest(http.MethodPut, "/me/notification-preferences", map[string]string{"timezone": "UTC"})))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"todo_app_backend/internal/app/services"
+)
+
+// maxBodyBytes is the largest request body accepted by decodeJSON.
+const maxBodyBytes = 1 << 20
+
+// decodeJSON reads a single JSON object from the request body into dst and
+// validates it. Bodies larger than maxBodyBytes and unknown fields are
+// rejected. The returned error can be passed straight to RespondError.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return errInvalidJSON
+	}
+
+	if fieldErrors := validateStruct(dst); len(fieldErrors) > 0 {
+		return services.NewValidationError("request validation failed", fieldErrors...)
+	}
+	return nil
+}
+
+// decodeError converts a json decoding error into a domain error.
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return err
+	case errors.As(err, &typeErr):
+		return services.NewValidationError("request validation failed",
+			services.FieldError{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return services.NewValidationError("request validation failed",
+			services.FieldError{Field: field, Message: "is not allowed"})
+	}
+	return errInvalidJSON
+}
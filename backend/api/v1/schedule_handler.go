@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"todo_app_backend/internal/app/services"
+)
+
+type ScheduleHandler struct {
+	Service services.SchedulerServiceInterface
+}
+
+// NewScheduleHandler initializes a new ScheduleHandler.
+func NewScheduleHandler(service services.SchedulerServiceInterface) *ScheduleHandler {
+	return &ScheduleHandler{Service: service}
+}
+
+// ListSchedules lists the periodic tasks with their last and next runs.
+func (h *ScheduleHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.Service.ListSchedules(r.Context())
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"schedules": schedules})
+}
+
+var _ ScheduleHandlerInterface = (*ScheduleHandler)(nil)
@@ -0,0 +1,47 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSchedulerService is a mock implementation of SchedulerServiceInterface.
+type MockSchedulerService struct {
+	mock.Mock
+}
+
+func (m *MockSchedulerService) ListSchedules(ctx context.Context) ([]models.Schedule, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Schedule), args.Error(1)
+}
+
+func TestListSchedules(t *testing.T) {
+	mockService := new(MockSchedulerService)
+	handler := NewScheduleHandler(mockService)
+
+	next := time.Date(2024, 5, 15, 11, 0, 0, 0, time.UTC)
+	mockService.On("ListSchedules", mock.Anything).
+		Return([]models.Schedule{{Name: "purge_deleted_accounts", Spec: "@hourly", JobType: models.JobPurgeDeletedAccounts, NextRunAt: &next}}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ListSchedules(rr, withSession(httptest.NewRequest(http.MethodGet, "/schedules", nil)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body struct {
+		Schedules []map[string]interface{} `json:"schedules"`
+	}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	if assert.Len(t, body.Schedules, 1) {
+		assert.Equal(t, "2024-05-15T11:00:00Z", body.Schedules[0]["next_run_at"])
+		assert.Nil(t, body.Schedules[0]["last_run_at"])
+	}
+}
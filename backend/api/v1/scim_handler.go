@@ -0,0 +1,241 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"todo_app_backend/internal/app/services"
+	"todo_app_backend/internal/scim"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// scimTypeByCode maps domain error codes to SCIM error types (RFC 7644
+// section 3.12).
+var scimTypeByCode = map[string]string{
+	services.SCIMInvalidFilter: "invalidFilter",
+	services.SCIMInvalidValue:  "invalidValue",
+	services.SCIMInvalidPath:   "invalidPath",
+	services.SCIMInvalidSyntax: "invalidSyntax",
+	services.SCIMMutability:    "mutability",
+	"email_taken":              "uniqueness",
+	"external_id_taken":        "uniqueness",
+}
+
+var errSCIMInvalidSyntax = &services.Error{Kind: services.KindValidation, Code: services.SCIMInvalidSyntax, Message: "request body is not a valid SCIM resource"}
+
+// serviceProviderConfig describes the supported SCIM features.
+var serviceProviderConfig = map[string]interface{}{
+	"schemas":        []string{scim.ServiceProviderConfigSchema},
+	"patch":          map[string]bool{"supported": true},
+	"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+	"filter":         map[string]interface{}{"supported": true, "maxResults": services.SCIMMaxCount},
+	"changePassword": map[string]bool{"supported": true},
+	"sort":           map[string]bool{"supported": false},
+	"etag":           map[string]bool{"supported": false},
+	"authenticationSchemes": []map[string]interface{}{{
+		"type":        "oauthbearertoken",
+		"name":        "Bearer Token",
+		"description": "Authentication with the SCIM token of the server",
+		"primary":     true,
+	}},
+}
+
+type SCIMHandler struct {
+	Service services.SCIMServiceInterface
+	// BaseURL is the public URL of the SCIM endpoints, used to build
+	// resource locations.
+	BaseURL string
+}
+
+// NewSCIMHandler initializes a new SCIMHandler.
+func NewSCIMHandler(service services.SCIMServiceInterface, baseURL string) *SCIMHandler {
+	return &SCIMHandler{Service: service, BaseURL: baseURL}
+}
+
+// ListUsers lists the users matching the filter query parameter.
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	startIndex, _ := strconv.Atoi(query.Get("startIndex"))
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil {
+		count = -1
+	}
+
+	list, err := h.Service.ListUsers(r.Context(), query.Get("filter"), startIndex, count)
+	if err != nil {
+		RespondSCIMError(w, r, err)
+		return
+	}
+
+	for i := range list.Resources {
+		h.setLocation(&list.Resources[i])
+	}
+	h.respond(w, http.StatusOK, list)
+}
+
+// GetUser returns a single user.
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.Service.GetUser(r.Context(), id)
+	if err != nil {
+		RespondSCIMError(w, r, err)
+		return
+	}
+	h.respondUser(w, http.StatusOK, user)
+}
+
+// CreateUser provisions a user.
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var in scim.User
+	if err := decodeSCIM(w, r, &in); err != nil {
+		RespondSCIMError(w, r, err)
+		return
+	}
+
+	user, err := h.Service.CreateUser(r.Context(), &in)
+	if err != nil {
+		RespondSCIMError(w, r, err)
+		return
+	}
+	h.respondUser(w, http.StatusCreated, user)
+}
+
+// ReplaceUser replaces all attributes of a user.
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	var in scim.User
+	if err := decodeSCIM(w, r, &in); err != nil {
+		RespondSCIMError(w, r, err)
+		return
+	}
+
+	user, err := h.Service.ReplaceUser(r.Context(), id, &in)
+	if err != nil {
+		RespondSCIMError(w, r, err)
+		return
+	}
+	h.respondUser(w, http.StatusOK, user)
+}
+
+// PatchUser changes some attributes of a user.
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	var req scim.PatchRequest
+	if err := decodeSCIM(w, r, &req); err != nil {
+		RespondSCIMError(w, r, err)
+		return
+	}
+
+	user, err := h.Service.PatchUser(r.Context(), id, req.Operations)
+	if err != nil {
+		RespondSCIMError(w, r, err)
+		return
+	}
+	h.respondUser(w, http.StatusOK, user)
+}
+
+// DeleteUser deactivates a user. Accounts are never deleted over SCIM.
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeactivateUser(r.Context(), id); err != nil {
+		RespondSCIMError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetServiceProviderConfig describes the supported SCIM features.
+func (h *SCIMHandler) GetServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, http.StatusOK, serviceProviderConfig)
+}
+
+// userID parses the id URL parameter. SCIM ids are opaque strings, so ids
+// that are not numbers name users that do not exist.
+func (h *SCIMHandler) userID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondSCIMError(w, r, services.NewNotFoundError("user_not_found", "user not found"))
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *SCIMHandler) setLocation(user *scim.User) {
+	if user.Meta != nil {
+		user.Meta.Location = h.BaseURL + "/Users/" + user.ID
+	}
+}
+
+func (h *SCIMHandler) respondUser(w http.ResponseWriter, status int, user *scim.User) {
+	h.setLocation(user)
+	if user.Meta != nil {
+		w.Header().Set("Location", user.Meta.Location)
+	}
+	h.respond(w, status, user)
+}
+
+func (h *SCIMHandler) respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// decodeSCIM decodes a SCIM request body into dst. Unlike decodeJSON it
+// accepts unknown attributes, which identity providers send freely.
+func decodeSCIM(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return err
+		}
+		return errSCIMInvalidSyntax
+	}
+	return nil
+}
+
+// RespondSCIMError writes err as a SCIM error response. Like RespondError,
+// errors that are not domain errors are logged and reported generically.
+func RespondSCIMError(w http.ResponseWriter, r *http.Request, err error) {
+	body := scim.Error{Schemas: []string{scim.ErrorSchema}}
+	status := http.StatusInternalServerError
+
+	var domainErr *services.Error
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		status = http.StatusRequestEntityTooLarge
+		body.Detail = "request body is too large"
+	} else if errors.As(err, &domainErr) && domainErr.Kind != services.KindInternal {
+		status = statusByKind[domainErr.Kind]
+		body.ScimType = scimTypeByCode[domainErr.Code]
+		body.Detail = domainErr.Message
+	} else {
+		log.Printf("internal error [request_id=%s]: %v", middleware.GetReqID(r.Context()), err)
+		body.Detail = "an unexpected error occurred"
+	}
+	body.Status = strconv.Itoa(status)
+
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+var _ SCIMHandlerInterface = (*SCIMHandler)(nil)
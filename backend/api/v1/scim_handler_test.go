@@ -0,0 +1,186 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"todo_app_backend/internal/app/services"
+	"todo_app_backend/internal/scim"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSCIMService is a mock implementation of SCIMServiceInterface.
+type MockSCIMService struct {
+	mock.Mock
+}
+
+func (m *MockSCIMService) ListUsers(ctx context.Context, filter string, startIndex, count int) (*scim.ListResponse, error) {
+	args := m.Called(ctx, filter, startIndex, count)
+	return args.Get(0).(*scim.ListResponse), args.Error(1)
+}
+
+func (m *MockSCIMService) GetUser(ctx context.Context, id int) (*scim.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*scim.User), args.Error(1)
+}
+
+func (m *MockSCIMService) CreateUser(ctx context.Context, in *scim.User) (*scim.User, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*scim.User), args.Error(1)
+}
+
+func (m *MockSCIMService) ReplaceUser(ctx context.Context, id int, in *scim.User) (*scim.User, error) {
+	args := m.Called(ctx, id, in)
+	return args.Get(0).(*scim.User), args.Error(1)
+}
+
+func (m *MockSCIMService) PatchUser(ctx context.Context, id int, operations []scim.PatchOperation) (*scim.User, error) {
+	args := m.Called(ctx, id, operations)
+	return args.Get(0).(*scim.User), args.Error(1)
+}
+
+func (m *MockSCIMService) DeactivateUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+const scimBaseURL = "https://api.example.com/api/v1/scim/v2"
+
+func withUserID(req *http.Request, id string) *http.Request {
+	return withURLParams(req, map[string]string{"id": id})
+}
+
+func scimUser(id string) *scim.User {
+	return &scim.User{Schemas: []string{scim.UserSchema}, ID: id, UserName: "jane@example.com", Meta: &scim.Meta{ResourceType: "User"}}
+}
+
+func TestSCIMListUsers(t *testing.T) {
+	mockService := new(MockSCIMService)
+	handler := NewSCIMHandler(mockService, scimBaseURL)
+
+	mockService.On("ListUsers", mock.Anything, `userName eq "jane@example.com"`, 1, -1).
+		Return(&scim.ListResponse{Schemas: []string{scim.ListResponseSchema}, TotalResults: 1, StartIndex: 1, ItemsPerPage: 1,
+			Resources: []scim.User{*scimUser("3")}}, nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, `/Users?startIndex=1&filter=userName+eq+%22jane%40example.com%22`, nil)
+	handler.ListUsers(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, scim.ContentType, rr.Header().Get("Content-Type"))
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, float64(1), body["totalResults"])
+	resource := body["Resources"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, scimBaseURL+"/Users/3", resource["meta"].(map[string]interface{})["location"])
+}
+
+func TestSCIMListUsersInvalidFilter(t *testing.T) {
+	mockService := new(MockSCIMService)
+	handler := NewSCIMHandler(mockService, scimBaseURL)
+
+	mockService.On("ListUsers", mock.Anything, "bogus", 0, 10).
+		Return((*scim.ListResponse)(nil), &services.Error{Kind: services.KindValidation, Code: services.SCIMInvalidFilter, Message: "invalid filter"})
+
+	rr := httptest.NewRecorder()
+	handler.ListUsers(rr, httptest.NewRequest(http.MethodGet, "/Users?filter=bogus&count=10", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body scim.Error
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, []string{scim.ErrorSchema}, body.Schemas)
+	assert.Equal(t, "400", body.Status)
+	assert.Equal(t, "invalidFilter", body.ScimType)
+}
+
+func TestSCIMCreateUser(t *testing.T) {
+	mockService := new(MockSCIMService)
+	handler := NewSCIMHandler(mockService, scimBaseURL)
+
+	mockService.On("CreateUser", mock.Anything, mock.MatchedBy(func(u *scim.User) bool {
+		return u.UserName == "jane@example.com" && u.ExternalID == "ext-1"
+	})).Return(scimUser("7"), nil)
+
+	// Unknown attributes such as enterprise extensions are ignored.
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/Users", strings.NewReader(
+		`{"schemas":["`+scim.UserSchema+`"],"userName":"jane@example.com","externalId":"ext-1","title":"Engineer"}`))
+	handler.CreateUser(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, scimBaseURL+"/Users/7", rr.Header().Get("Location"))
+}
+
+func TestSCIMCreateUserConflict(t *testing.T) {
+	mockService := new(MockSCIMService)
+	handler := NewSCIMHandler(mockService, scimBaseURL)
+
+	mockService.On("CreateUser", mock.Anything, mock.Anything).
+		Return((*scim.User)(nil), services.NewConflictError("email_taken", "email is already registered"))
+
+	rr := httptest.NewRecorder()
+	handler.CreateUser(rr, httptest.NewRequest(http.MethodPost, "/Users", strings.NewReader(`{"userName":"jane@example.com"}`)))
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	var body scim.Error
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "uniqueness", body.ScimType)
+}
+
+func TestSCIMCreateUserInvalidJSON(t *testing.T) {
+	handler := NewSCIMHandler(new(MockSCIMService), scimBaseURL)
+
+	rr := httptest.NewRecorder()
+	handler.CreateUser(rr, httptest.NewRequest(http.MethodPost, "/Users", strings.NewReader(`{"userName":`)))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body scim.Error
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, "invalidSyntax", body.ScimType)
+}
+
+func TestSCIMPatchUser(t *testing.T) {
+	mockService := new(MockSCIMService)
+	handler := NewSCIMHandler(mockService, scimBaseURL)
+
+	mockService.On("PatchUser", mock.Anything, 7, mock.MatchedBy(func(ops []scim.PatchOperation) bool {
+		return len(ops) == 1 && ops[0].Op == "replace" && ops[0].Path == "active" && string(ops[0].Value) == "false"
+	})).Return(scimUser("7"), nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/Users/7", strings.NewReader(
+		`{"schemas":["`+scim.PatchOpSchema+`"],"Operations":[{"op":"replace","path":"active","value":false}]}`))
+	handler.PatchUser(rr, withUserID(req, "7"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestSCIMGetUserUnknownID(t *testing.T) {
+	handler := NewSCIMHandler(new(MockSCIMService), scimBaseURL)
+
+	rr := httptest.NewRecorder()
+	handler.GetUser(rr, withUserID(httptest.NewRequest(http.MethodGet, "/Users/abc", nil), "abc"))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, scim.ContentType, rr.Header().Get("Content-Type"))
+}
+
+func TestSCIMDeleteUser(t *testing.T) {
+	mockService := new(MockSCIMService)
+	handler := NewSCIMHandler(mockService, scimBaseURL)
+
+	mockService.On("DeactivateUser", mock.Anything, 7).Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.DeleteUser(rr, withUserID(httptest.NewRequest(http.MethodDelete, "/Users/7", nil), "7"))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
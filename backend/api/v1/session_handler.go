@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SessionHandler struct {
+	Service services.SessionServiceInterface
+}
+
+// NewSessionHandler initializes a new SessionHandler.
+func NewSessionHandler(service services.SessionServiceInterface) *SessionHandler {
+	return &SessionHandler{Service: service}
+}
+
+// ListSessions lists the active sessions of the authenticated user.
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	h.listSessions(w, r, userID)
+}
+
+// RevokeSession signs the authenticated user out of one of their sessions.
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	h.revokeSession(w, r, userID)
+}
+
+// ListUserSessions lists the active sessions of any user. Admin only.
+func (h *SessionHandler) ListUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+	h.listSessions(w, r, userID)
+}
+
+// RevokeUserSession signs any user out of one of their sessions. Admin only.
+func (h *SessionHandler) RevokeUserSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+	h.revokeSession(w, r, userID)
+}
+
+func (h *SessionHandler) listSessions(w http.ResponseWriter, r *http.Request, userID int) {
+	sessionID, _ := r.Context().Value("sessionID").(int)
+	sessions, err := h.Service.ListSessions(r.Context(), userID, sessionID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(sessions)
+}
+
+func (h *SessionHandler) revokeSession(w http.ResponseWriter, r *http.Request, userID int) {
+	sessionID, err := strconv.Atoi(chi.URLParam(r, "session_id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.Service.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var _ SessionHandlerInterface = (*SessionHandler)(nil)
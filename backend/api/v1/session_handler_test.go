@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func withURLParams(r *http.Request, params map[string]string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestListSessions(t *testing.T) {
+	mockService := new(MockSessionService)
+	handler := NewSessionHandler(mockService)
+
+	mockService.On("ListSessions", mock.Anything, 1, 10).
+		Return([]models.Session{{ID: 10, UserID: 1, Device: "Firefox on Windows", Current: true}, {ID: 11, UserID: 1}}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ListSessions(rr, withSession(httptest.NewRequest(http.MethodGet, "/me/sessions", nil)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body []map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Len(t, body, 2)
+	assert.Equal(t, "Firefox on Windows", body[0]["device"])
+	assert.Equal(t, true, body[0]["current"])
+}
+
+func TestRevokeSession(t *testing.T) {
+	mockService := new(MockSessionService)
+	handler := NewSessionHandler(mockService)
+
+	mockService.On("RevokeSession", mock.Anything, 1, 11).Return(nil)
+	mockService.On("RevokeSession", mock.Anything, 1, 12).Return(services.NewNotFoundError("session_not_found", "session not found"))
+
+	rr := httptest.NewRecorder()
+	req := withURLParams(httptest.NewRequest(http.MethodDelete, "/me/sessions/11", nil), map[string]string{"session_id": "11"})
+	handler.RevokeSession(rr, withSession(req))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	req = withURLParams(httptest.NewRequest(http.MethodDelete, "/me/sessions/12", nil), map[string]string{"session_id": "12"})
+	handler.RevokeSession(rr, withSession(req))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	req = withURLParams(httptest.NewRequest(http.MethodDelete, "/me/sessions/abc", nil), map[string]string{"session_id": "abc"})
+	handler.RevokeSession(rr, withSession(req))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestAdminRevokeUserSession(t *testing.T) {
+	mockService := new(MockSessionService)
+	handler := NewSessionHandler(mockService)
+
+	mockService.On("ListSessions", mock.Anything, 5, 10).Return([]models.Session{{ID: 20, UserID: 5}}, nil)
+	mockService.On("RevokeSession", mock.Anything, 5, 20).Return(nil)
+
+	rr := httptest.NewRecorder()
+	req := withURLParams(httptest.NewRequest(http.MethodGet, "/users/5/sessions", nil), map[string]string{"id": "5"})
+	handler.ListUserSessions(rr, withSession(req))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	req = withURLParams(httptest.NewRequest(http.MethodDelete, "/users/5/sessions/20", nil), map[string]string{"id": "5", "session_id": "20"})
+	handler.RevokeUserSession(rr, withSession(req))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
@@ -2,15 +2,32 @@ package v1
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 	"todo_app_backend/internal/app/models"
 	"todo_app_backend/internal/app/services"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// todoRequest is the body accepted when creating or updating a todo. Updates
+// leaving out the tags or the due date keep them; null clears them.
+type todoRequest struct {
+	Title   string                      `json:"title" validate:"required,max=255"`
+	Content string                      `json:"content" validate:"max=65535"`
+	Status  string                      `json:"status" validate:"max=50"`
+	Tags    models.Optional[[]string]   `json:"tags"`
+	DueDate models.Optional[*time.Time] `json:"due_date"`
+
+	// The frontend sends todos back with their read-only fields, which
+	// are accepted and ignored.
+	ID        json.RawMessage `json:"id"`
+	CreatedAt json.RawMessage `json:"created_at"`
+	UpdatedAt json.RawMessage `json:"updated_at"`
+}
+
 type TodoHandler struct {
 	Service services.TodoServiceInterface
 }
@@ -22,22 +39,21 @@ func NewTodoHandler(service services.TodoServiceInterface) *TodoHandler {
 
 // CreateTodo handles the creation of a new todo.
 func (h *TodoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
-	var todo models.Todo
-	if err := json.NewDecoder(r.Body).Decode(&todo); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var todo todoRequest
+	if err := decodeJSON(w, r, &todo); err != nil {
+		RespondError(w, r, err)
 		return
 	}
 
 	userId, ok := r.Context().Value("userID").(int)
 	if !ok {
-		log.Println("\n\n Error getting userID from context")
-		http.Error(w, "Error getting userID from context", http.StatusInternalServerError)
+		RespondError(w, r, errors.New("error getting userID from context"))
 		return
 	}
 
-	createdTodo, err := h.Service.CreateTodo(r.Context(), userId, todo.Title, todo.Content)
+	createdTodo, err := h.Service.CreateTodo(r.Context(), userId, todo.Title, todo.Content, todo.Tags.Value, todo.DueDate.Value)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		RespondError(w, r, err)
 		return
 	}
 
@@ -51,7 +67,7 @@ func (h *TodoHandler) GetAllTodos(w http.ResponseWriter, r *http.Request) {
 
 	todos, err := h.Service.GetAllTodos(r.Context(), userId)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		RespondError(w, r, err)
 		return
 	}
 
@@ -67,7 +83,7 @@ func (h *TodoHandler) GetAllTodos(w http.ResponseWriter, r *http.Request) {
 func (h *TodoHandler) GetTodoByID(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		RespondError(w, r, errInvalidID)
 		return
 	}
 
@@ -75,7 +91,7 @@ func (h *TodoHandler) GetTodoByID(w http.ResponseWriter, r *http.Request) {
 
 	todo, err := h.Service.GetTodoByID(r.Context(), userId, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		RespondError(w, r, err)
 		return
 	}
 
@@ -87,20 +103,20 @@ func (h *TodoHandler) GetTodoByID(w http.ResponseWriter, r *http.Request) {
 func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		RespondError(w, r, errInvalidID)
 		return
 	}
 
-	var todo models.Todo
-	if err := json.NewDecoder(r.Body).Decode(&todo); err != nil {
-		http.Error(w, "invalid json", http.StatusBadRequest)
+	var todo todoRequest
+	if err := decodeJSON(w, r, &todo); err != nil {
+		RespondError(w, r, err)
 		return
 	}
 
 	userId := r.Context().Value("userID").(int)
 
-	if err := h.Service.UpdateTodo(r.Context(), userId, id, todo.Title, todo.Content, todo.Status); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.Service.UpdateTodo(r.Context(), userId, id, todo.Title, todo.Content, todo.Status, todo.Tags, todo.DueDate); err != nil {
+		RespondError(w, r, err)
 		return
 	}
 
@@ -111,14 +127,14 @@ func (h *TodoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 func (h *TodoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		RespondError(w, r, errInvalidID)
 		return
 	}
 
 	userId := r.Context().Value("userID").(int)
 
 	if err := h.Service.DeleteTodo(r.Context(), userId, id); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		RespondError(w, r, err)
 		return
 	}
 
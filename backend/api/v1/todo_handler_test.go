@@ -4,14 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
-	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 	v1 "todo_app_backend/api/v1"
 	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -23,8 +23,8 @@ type MockTodoService struct {
 	mock.Mock
 }
 
-func (m *MockTodoService) CreateTodo(ctx context.Context, userId int, title, content string) (*models.Todo, error) {
-	args := m.Called(ctx, userId, title, content)
+func (m *MockTodoService) CreateTodo(ctx context.Context, userId int, title, content string, tags []string, dueDate *time.Time) (*models.Todo, error) {
+	args := m.Called(ctx, userId, title, content, tags, dueDate)
 	return args.Get(0).(*models.Todo), args.Error(1)
 }
 
@@ -38,8 +38,8 @@ func (m *MockTodoService) GetTodoByID(ctx context.Context, userId, id int) (*mod
 	return args.Get(0).(*models.Todo), args.Error(1)
 }
 
-func (m *MockTodoService) UpdateTodo(ctx context.Context, userId, id int, title, content, status string) error {
-	args := m.Called(ctx, userId, id, title, content, status)
+func (m *MockTodoService) UpdateTodo(ctx context.Context, userId, id int, title, content, status string, tags models.Optional[[]string], dueDate models.Optional[*time.Time]) error {
+	args := m.Called(ctx, userId, id, title, content, status, tags, dueDate)
 	return args.Error(0)
 }
 
@@ -52,10 +52,13 @@ func TestCreateTodo(t *testing.T) {
 	mockService := new(MockTodoService)
 	handler := v1.NewTodoHandler(mockService)
 
-	todo := models.Todo{Title: "Test Todo", Content: "Content of test todo"}
-	mockService.On("CreateTodo", mock.Anything, 1, todo.Title, todo.Content).Return(&todo, nil)
+	due := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	todo := models.Todo{Title: "Test Todo", Content: "Content of test todo", Tags: []string{"home"}, DueDate: &due}
+	mockService.On("CreateTodo", mock.Anything, 1, todo.Title, todo.Content, todo.Tags, &due).Return(&todo, nil)
 
-	body, _ := json.Marshal(todo)
+	body, _ := json.Marshal(map[string]interface{}{
+		"title": todo.Title, "content": todo.Content, "tags": todo.Tags, "due_date": "2024-05-15T09:00:00Z",
+	})
 	req := httptest.NewRequest(http.MethodPost, "/todos", bytes.NewBuffer(body))
 	req = req.WithContext(context.WithValue(req.Context(), "userID", 1))
 	resp := httptest.NewRecorder()
@@ -68,6 +71,41 @@ func TestCreateTodo(t *testing.T) {
 	json.NewDecoder(resp.Body).Decode(&createdTodo)
 	assert.Equal(t, todo.Title, createdTodo.Title)
 	assert.Equal(t, todo.Content, createdTodo.Content)
+	assert.Equal(t, todo.Tags, createdTodo.Tags)
+	assert.True(t, due.Equal(*createdTodo.DueDate))
+}
+
+func TestCreateTodo_ReadOnlyFields(t *testing.T) {
+	mockService := new(MockTodoService)
+	handler := v1.NewTodoHandler(mockService)
+
+	mockService.On("CreateTodo", mock.Anything, 1, "Buy milk", "", []string(nil), (*time.Time)(nil)).
+		Return(&models.Todo{ID: 3, Title: "Buy milk"}, nil)
+
+	// The body the frontend creates todos with
+	req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(
+		`{"id": 0, "title": "Buy milk", "content": "", "status": "pending", "created_at": "", "updated_at": ""}`))
+	req = req.WithContext(context.WithValue(req.Context(), "userID", 1))
+	resp := httptest.NewRecorder()
+
+	handler.CreateTodo(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateTodo_InvalidDueDate(t *testing.T) {
+	mockService := new(MockTodoService)
+	handler := v1.NewTodoHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(`{"title": "a", "due_date": "tomorrow"}`))
+	req = req.WithContext(context.WithValue(req.Context(), "userID", 1))
+	resp := httptest.NewRecorder()
+
+	handler.CreateTodo(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	mockService.AssertNotCalled(t, "CreateTodo")
 }
 
 func TestCreateTodo_BadRequest(t *testing.T) {
@@ -82,6 +120,60 @@ func TestCreateTodo_BadRequest(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 }
 
+func TestCreateTodo_ValidationErrors(t *testing.T) {
+	mockService := new(MockTodoService)
+	handler := v1.NewTodoHandler(mockService)
+
+	body := `{"title": "", "status": "` + strings.Repeat("x", 51) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), "userID", 1))
+	resp := httptest.NewRecorder()
+
+	handler.CreateTodo(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var problem v1.Problem
+	json.NewDecoder(resp.Body).Decode(&problem)
+	assert.Equal(t, "validation_failed", problem.Code)
+	assert.Equal(t, []services.FieldError{
+		{Field: "title", Message: "is required"},
+		{Field: "status", Message: "must be at most 50 characters"},
+	}, problem.Errors)
+	mockService.AssertNotCalled(t, "CreateTodo")
+}
+
+func TestCreateTodo_UnknownField(t *testing.T) {
+	mockService := new(MockTodoService)
+	handler := v1.NewTodoHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(`{"title": "a", "owner": 2}`))
+	req = req.WithContext(context.WithValue(req.Context(), "userID", 1))
+	resp := httptest.NewRecorder()
+
+	handler.CreateTodo(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var problem v1.Problem
+	json.NewDecoder(resp.Body).Decode(&problem)
+	assert.Equal(t, []services.FieldError{{Field: "owner", Message: "is not allowed"}}, problem.Errors)
+}
+
+func TestCreateTodo_BodyTooLarge(t *testing.T) {
+	mockService := new(MockTodoService)
+	handler := v1.NewTodoHandler(mockService)
+
+	body := `{"title": "a", "content": "` + strings.Repeat("x", 2<<20) + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/todos", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), "userID", 1))
+	resp := httptest.NewRecorder()
+
+	handler.CreateTodo(resp, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+}
+
 func TestGetAllTodos_Success(t *testing.T) {
 	mockService := new(MockTodoService)
 	handler := v1.NewTodoHandler(mockService)
@@ -131,7 +223,7 @@ func TestGetTodoByID_NotFound(t *testing.T) {
 	mockService := new(MockTodoService)
 	handler := v1.NewTodoHandler(mockService)
 
-	mockService.On("GetTodoByID", mock.Anything, 1, 999).Return(&models.Todo{}, errors.New("todo not founs"))
+	mockService.On("GetTodoByID", mock.Anything, 1, 999).Return(&models.Todo{}, services.NewNotFoundError("todo_not_found", "todo not found"))
 
 	req := httptest.NewRequest(http.MethodGet, "/todos/999", nil)
 	req = req.WithContext(context.WithValue(req.Context(), "userID", 1))
@@ -148,10 +240,13 @@ func TestUpdateTodo_Success(t *testing.T) {
 	mockService := new(MockTodoService)
 	handler := v1.NewTodoHandler(mockService)
 
+	due := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
 	todo := models.Todo{Title: "Updated Todo", Content: "Updated Content"}
-	mockService.On("UpdateTodo", mock.Anything, 1, 1, todo.Title, todo.Content, "").Return(nil)
+	mockService.On("UpdateTodo", mock.Anything, 1, 1, todo.Title, todo.Content, "completed", models.Some([]string{"work"}), models.Some(&due)).Return(nil)
 
-	body, _ := json.Marshal(todo)
+	body, _ := json.Marshal(map[string]interface{}{
+		"title": todo.Title, "content": todo.Content, "status": "completed", "tags": []string{"work"}, "due_date": "2024-05-15T09:00:00Z",
+	})
 	req := httptest.NewRequest(http.MethodPut, "/todos/{id}", bytes.NewBuffer(body))
 	req = req.WithContext(context.WithValue(req.Context(), "userID", 1))
 	resp := httptest.NewRecorder()
@@ -166,6 +261,27 @@ func TestUpdateTodo_Success(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, resp.Code)
 }
 
+func TestUpdateTodo_KeepsTagsAndDueDate(t *testing.T) {
+	mockService := new(MockTodoService)
+	handler := v1.NewTodoHandler(mockService)
+
+	// The frontend only sends the fields it edits
+	mockService.On("UpdateTodo", mock.Anything, 1, 1, "Buy milk", "", "completed", models.Optional[[]string]{}, models.Optional[*time.Time]{}).Return(nil)
+
+	body, _ := json.Marshal(map[string]interface{}{"title": "Buy milk", "content": "", "status": "completed"})
+	req := httptest.NewRequest(http.MethodPut, "/todos/{id}", bytes.NewBuffer(body))
+	req = req.WithContext(context.WithValue(req.Context(), "userID", 1))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	resp := httptest.NewRecorder()
+
+	handler.UpdateTodo(resp, req)
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	mockService.AssertExpectations(t)
+}
+
 func TestUpdateTodo_BadRequest(t *testing.T) {
 	mockService := new(MockTodoService)
 	handler := v1.NewTodoHandler(mockService)
@@ -181,9 +297,12 @@ func TestUpdateTodo_BadRequest(t *testing.T) {
 	handler.UpdateTodo(resp, req)
 
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, v1.ProblemContentType, resp.Header().Get("Content-Type"))
 
-	body, _ := io.ReadAll(resp.Body)
-	assert.Equal(t, "invalid json", strings.Trim(string(body), "\n"))
+	var problem v1.Problem
+	json.NewDecoder(resp.Body).Decode(&problem)
+	assert.Equal(t, "invalid_json", problem.Code)
+	assert.Equal(t, "invalid json", problem.Detail)
 }
 
 func TestDeleteTodo_Success(t *testing.T) {
@@ -210,7 +329,7 @@ func TestDeleteTodo_NotFound(t *testing.T) {
 	mockService := new(MockTodoService)
 	handler := v1.NewTodoHandler(mockService)
 
-	mockService.On("DeleteTodo", mock.Anything, 1, 999).Return(errors.New("todo not founs"))
+	mockService.On("DeleteTodo", mock.Anything, 1, 999).Return(services.NewNotFoundError("todo_not_found", "todo not found"))
 
 	req := httptest.NewRequest(http.MethodDelete, "/todos/{id}", nil)
 	req = req.WithContext(context.WithValue(req.Context(), "userID", 1))
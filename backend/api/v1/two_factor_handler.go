@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"todo_app_backend/internal/app/services"
+)
+
+// confirmTwoFactorRequest is the body accepted by ConfirmEnrollment.
+type confirmTwoFactorRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+// passwordRequest is the body of requests that only need the password of
+// the user to be re-entered.
+type passwordRequest struct {
+	Password string `json:"password" validate:"required,max=255"`
+}
+
+type TwoFactorHandler struct {
+	TwoFactorService services.TwoFactorServiceInterface
+}
+
+// NewTwoFactorHandler initializes a new TwoFactorHandler.
+func NewTwoFactorHandler(twoFactorService services.TwoFactorServiceInterface) *TwoFactorHandler {
+	return &TwoFactorHandler{TwoFactorService: twoFactorService}
+}
+
+// GetStatus describes the two-factor setup of the authenticated user.
+func (h *TwoFactorHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	status, err := h.TwoFactorService.Status(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+// BeginEnrollment returns a new TOTP secret together with an otpauth URI and
+// its QR code for authenticator apps.
+func (h *TwoFactorHandler) BeginEnrollment(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	enrollment, err := h.TwoFactorService.BeginEnrollment(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ConfirmEnrollment enables two-factor authentication and responds with the
+// recovery codes, which are shown only this once.
+func (h *TwoFactorHandler) ConfirmEnrollment(w http.ResponseWriter, r *http.Request) {
+	var req confirmTwoFactorRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	codes, err := h.TwoFactorService.ConfirmEnrollment(r.Context(), userID, req.Code)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the authenticated
+// user.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req passwordRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	codes, err := h.TwoFactorService.RegenerateRecoveryCodes(r.Context(), userID, req.Password)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{"recovery_codes": codes})
+}
+
+// Disable turns off two-factor authentication of the authenticated user.
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	var req passwordRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	if err := h.TwoFactorService.Disable(r.Context(), userID, req.Password); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var _ TwoFactorHandlerInterface = (*TwoFactorHandler)(nil)
@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTwoFactorService is a mock implementation of TwoFactorServiceInterface.
+type MockTwoFactorService struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorService) Status(ctx context.Context, userID int) (*models.TwoFactorStatus, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.TwoFactorStatus), args.Error(1)
+}
+
+func (m *MockTwoFactorService) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorService) BeginEnrollment(ctx context.Context, userID int) (*models.TOTPEnrollment, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.TOTPEnrollment), args.Error(1)
+}
+
+func (m *MockTwoFactorService) ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockTwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, password string) ([]string, error) {
+	args := m.Called(ctx, userID, password)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockTwoFactorService) Disable(ctx context.Context, userID int, password string) error {
+	args := m.Called(ctx, userID, password)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorService) Reset(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorService) NewChallenge(ctx context.Context, userID int) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTwoFactorService) ParseChallenge(challenge string) (int, error) {
+	args := m.Called(challenge)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTwoFactorService) Verify(ctx context.Context, userID int, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func TestBeginTwoFactorEnrollment(t *testing.T) {
+	mockService := new(MockTwoFactorService)
+	handler := NewTwoFactorHandler(mockService)
+
+	mockService.On("BeginEnrollment", mock.Anything, 1).Return(&models.TOTPEnrollment{
+		Secret: "JBSWY3DPEHPK3PXP",
+		URI:    "otpauth://totp/Todo%20App:john@example.com?secret=JBSWY3DPEHPK3PXP",
+		QRCode: []byte{0x89, 'P', 'N', 'G'},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.BeginEnrollment(rr, withSession(httptest.NewRequest(http.MethodPost, "/me/2fa/totp", nil)))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var body map[string]interface{}
+	json.NewDecoder(rr.Body).Decode(&body)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", body["secret"])
+	assert.NotEmpty(t, body["otpauth_uri"])
+	assert.NotEmpty(t, body["qr_code_png"])
+}
+
+func TestConfirmTwoFactorEnrollment(t *testing.T) {
+	mockService := new(MockTwoFactorService)
+	handler := NewTwoFactorHandler(mockService)
+
+	mockService.On("ConfirmEnrollment", mock.Anything, 1, "123456").Return([]string{"abcde-fghij"}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ConfirmEnrollment(rr, withSession(jsonRequest(http.MethodPost, "/me/2fa/totp/confirm", confirmTwoFactorRequest{Code: "123456"})))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	json.NewDecoder(rr.Body).Decode(&body)
+	assert.Equal(t, []string{"abcde-fghij"}, body.RecoveryCodes)
+}
+
+func TestConfirmTwoFactorEnrollment_InvalidCode(t *testing.T) {
+	mockService := new(MockTwoFactorService)
+	handler := NewTwoFactorHandler(mockService)
+
+	mockService.On("ConfirmEnrollment", mock.Anything, 1, "000000").Return([]string(nil),
+		services.NewValidationError("invalid authentication code", services.FieldError{Field: "code", Message: "is invalid"}))
+
+	rr := httptest.NewRecorder()
+	handler.ConfirmEnrollment(rr, withSession(jsonRequest(http.MethodPost, "/me/2fa/totp/confirm", confirmTwoFactorRequest{Code: "000000"})))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	mockService := new(MockTwoFactorService)
+	handler := NewTwoFactorHandler(mockService)
+
+	mockService.On("Disable", mock.Anything, 1, "password").Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.Disable(rr, withSession(jsonRequest(http.MethodDelete, "/me/2fa", passwordRequest{Password: "password"})))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
@@ -2,40 +2,82 @@ package v1
 
 import (
 	"encoding/json"
+	"log"
+	"net"
 	"net/http"
 	"strconv"
-	"time"
 	"todo_app_backend/internal/app/models"
 	"todo_app_backend/internal/app/services"
-	"todo_app_backend/internal/app/utils"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// signupRequest is the body accepted by CreateUser.
+type signupRequest struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,max=255"`
+	// InviteCode is required in invite-only registration mode.
+	InviteCode string `json:"invite_code" validate:"max=255"`
+}
+
+// loginRequest is the body accepted by LoginHandler.
+type loginRequest struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required,max=255"`
+}
+
+// roleRequest is the body accepted by SetRole.
+type roleRequest struct {
+	Role string `json:"role" validate:"required,max=32"`
+}
+
+// mfaLoginRequest is the body accepted by LoginMFAHandler. Code is either a
+// TOTP code or a recovery code.
+type mfaLoginRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required,max=1024"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
 type UserHandler struct {
-	UserService services.UserServiceInterface
+	UserService      services.UserServiceInterface
+	LoginGuard       services.LoginGuardInterface
+	AccountService   services.AccountServiceInterface
+	SessionService   services.SessionServiceInterface
+	TwoFactorService services.TwoFactorServiceInterface
 }
 
 // NewUserHandler initializes a new UserHandler.
-func NewUserHandler(userService services.UserServiceInterface) *UserHandler {
-	return &UserHandler{UserService: userService}
+func NewUserHandler(userService services.UserServiceInterface, loginGuard services.LoginGuardInterface, accountService services.AccountServiceInterface, sessionService services.SessionServiceInterface, twoFactorService services.TwoFactorServiceInterface) *UserHandler {
+	return &UserHandler{
+		UserService:      userService,
+		LoginGuard:       loginGuard,
+		AccountService:   accountService,
+		SessionService:   sessionService,
+		TwoFactorService: twoFactorService,
+	}
 }
 
 // CreateUser handles the user registration request.
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
-	var req models.User
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var req signupRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
 		return
 	}
 
-	user, err := h.UserService.CreateUser(r.Context(), req.Name, req.Email, req.Password)
+	user, err := h.UserService.CreateUser(r.Context(), req.Name, req.Email, req.Password, req.InviteCode)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		RespondError(w, r, err)
 		return
 	}
 
+	// The account exists even if the email cannot be sent; the user can
+	// request a new verification link later
+	if err := h.AccountService.SendVerificationEmail(r.Context(), user); err != nil {
+		log.Printf("failed to send verification email: %v", err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(user)
 }
@@ -44,13 +86,13 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		RespondError(w, r, errInvalidID)
 		return
 	}
 
 	user, err := h.UserService.GetUserByID(r.Context(), id)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		RespondError(w, r, err)
 		return
 	}
 
@@ -60,23 +102,100 @@ func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 
 // LoginHandler handles Login request.
 func (h *UserHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
-	var req models.User
+	var req loginRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	ip := clientIP(r)
+	if err := h.LoginGuard.Check(r.Context(), req.Email, ip); err != nil {
+		RespondError(w, r, err)
 		return
 	}
 
 	user, err := h.UserService.GetUserByCreds(r.Context(), req.Email, req.Password)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		if services.KindOf(err) == services.KindUnauthorized {
+			if guardErr := h.LoginGuard.RecordFailure(r.Context(), req.Email, ip); guardErr != nil {
+				log.Printf("failed to record login failure: %v", guardErr)
+			}
+		}
+		RespondError(w, r, err)
+		return
+	}
+	h.LoginGuard.RecordSuccess(r.Context(), req.Email, ip)
+
+	// Users with two-factor authentication get a challenge to answer with
+	// LoginMFAHandler instead of a session
+	mfaEnabled, err := h.TwoFactorService.IsEnabled(r.Context(), user.ID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+	if mfaEnabled {
+		challenge, err := h.TwoFactorService.NewChallenge(r.Context(), user.ID)
+		if err != nil {
+			RespondError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"mfa_required": true,
+			"mfa_token":    challenge,
+		})
+		return
+	}
+
+	h.startSession(w, r, user)
+}
+
+// LoginMFAHandler completes a login of a user with two-factor
+// authentication, given the challenge returned by LoginHandler.
+func (h *UserHandler) LoginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var req mfaLoginRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, err := h.TwoFactorService.ParseChallenge(req.MFAToken)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+	user, err := h.UserService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	// Wrong codes count as failed logins so that codes cannot be brute-forced
+	ip := clientIP(r)
+	if err := h.LoginGuard.Check(r.Context(), user.Email, ip); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+	if err := h.TwoFactorService.Verify(r.Context(), user.ID, req.Code); err != nil {
+		if services.KindOf(err) == services.KindUnauthorized {
+			if guardErr := h.LoginGuard.RecordFailure(r.Context(), user.Email, ip); guardErr != nil {
+				log.Printf("failed to record login failure: %v", guardErr)
+			}
+		}
+		RespondError(w, r, err)
 		return
 	}
+	h.LoginGuard.RecordSuccess(r.Context(), user.Email, ip)
 
-	tokenDetails := models.Token{UserID: user.ID, Exp: time.Now().Add(time.Minute * 15)}
-	token, err := utils.GenerateToken(tokenDetails)
+	h.startSession(w, r, user)
+}
+
+// startSession logs user in and responds with the session token.
+func (h *UserHandler) startSession(w http.ResponseWriter, r *http.Request, user *models.User) {
+	token, err := h.SessionService.StartSession(r.Context(), user.ID, clientIP(r), r.UserAgent())
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		RespondError(w, r, err)
 		return
 	}
 
@@ -93,7 +212,7 @@ func (h *UserHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := h.UserService.GetAllUsers(r.Context())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		RespondError(w, r, err)
 		return
 	}
 
@@ -105,17 +224,130 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		RespondError(w, r, errInvalidID)
 		return
 	}
 
 	if err := h.UserService.DeleteUser(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"})
+}
+
+// SetRole makes a user an admin or a regular user.
+func (h *UserHandler) SetRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+	var req roleRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	adminID, _ := r.Context().Value("userID").(int)
+	user, err := h.UserService.SetRole(r.Context(), adminID, id, req.Role)
+	if err != nil {
+		RespondError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("User deleted successfully"))
+	json.NewEncoder(w).Encode(user)
+}
+
+// ListPendingUsers lists the users awaiting approval.
+func (h *UserHandler) ListPendingUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.UserService.ListPendingUsers(r.Context())
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+// ApproveUser lets a user awaiting approval sign in.
+func (h *UserHandler) ApproveUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	user, err := h.UserService.ApproveUser(r.Context(), id)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
+}
+
+// RejectUser deletes the account of a user awaiting approval.
+func (h *UserHandler) RejectUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.UserService.RejectUser(r.Context(), id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetTwoFactor turns off two-factor authentication of a user who lost
+// access to their authenticator app.
+func (h *UserHandler) ResetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	if err := h.TwoFactorService.Reset(r.Context(), id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlockUser lifts a login lockout on a user's account.
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	adminID, _ := r.Context().Value("userID").(int)
+	if err := h.LoginGuard.Unlock(r.Context(), id, adminID); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientIP returns the client address of r without its port. The RealIP
+// middleware has already replaced RemoteAddr with the address forwarded by
+// a trusted proxy, if any.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
 }
 
 var _ UserHandlerInterface = (*UserHandler)(nil)
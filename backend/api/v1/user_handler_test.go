@@ -8,10 +8,11 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
-	"strings"
 	"testing"
+	"time"
 
 	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -23,8 +24,8 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserService) CreateUser(ctx context.Context, name, email, password string) (*models.User, error) {
-	args := m.Called(ctx, name, email, password)
+func (m *MockUserService) CreateUser(ctx context.Context, name, email, password, inviteCode string) (*models.User, error) {
+	args := m.Called(ctx, name, email, password, inviteCode)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
@@ -48,16 +49,38 @@ func (m *MockUserService) DeleteUser(ctx context.Context, id int) error {
 	return args.Error(0)
 }
 
+func (m *MockUserService) SetRole(ctx context.Context, adminID int, id int, role string) (*models.User, error) {
+	args := m.Called(ctx, adminID, id, role)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) ListPendingUsers(ctx context.Context) ([]models.User, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockUserService) ApproveUser(ctx context.Context, id int) (*models.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserService) RejectUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 func TestCreateUser(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := NewUserHandler(mockService)
+	mockAccounts := new(MockAccountService)
+	handler := NewUserHandler(mockService, nil, mockAccounts, nil, nil)
 
-	reqBody := models.User{Name: "John Doe", Email: "john@example.com", Password: "password123"}
+	reqBody := signupRequest{Name: "John Doe", Email: "john@example.com", Password: "password123"}
 	jsonBody, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(jsonBody))
 	rr := httptest.NewRecorder()
 
-	mockService.On("CreateUser", mock.Anything, reqBody.Name, reqBody.Email, reqBody.Password).Return(&models.User{ID: 1, Name: reqBody.Name, Email: reqBody.Email}, nil)
+	mockService.On("CreateUser", mock.Anything, reqBody.Name, reqBody.Email, reqBody.Password, "").Return(&models.User{ID: 1, Name: reqBody.Name, Email: reqBody.Email}, nil)
+	mockAccounts.On("SendVerificationEmail", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil)
 
 	handler.CreateUser(rr, req)
 
@@ -68,11 +91,12 @@ func TestCreateUser(t *testing.T) {
 	json.NewDecoder(res.Body).Decode(&user)
 	assert.Equal(t, reqBody.Name, user.Name)
 	assert.Equal(t, reqBody.Email, user.Email)
+	mockAccounts.AssertExpectations(t)
 }
 
 func TestCreateUser_InvalidJSON(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader([]byte(`{"invalid": "json"`)))
 	rr := httptest.NewRecorder()
@@ -83,9 +107,31 @@ func TestCreateUser_InvalidJSON(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
 }
 
+func TestCreateUser_InvalidFields(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader([]byte(`{"name": "", "email": "not-an-email", "password": ""}`)))
+	rr := httptest.NewRecorder()
+
+	handler.CreateUser(rr, req)
+
+	res := rr.Result()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	var problem Problem
+	json.NewDecoder(res.Body).Decode(&problem)
+	assert.Equal(t, []services.FieldError{
+		{Field: "name", Message: "is required"},
+		{Field: "email", Message: "must be a valid email address"},
+		{Field: "password", Message: "is required"},
+	}, problem.Errors)
+	mockService.AssertNotCalled(t, "CreateUser")
+}
+
 func TestGetUserByID(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/users/{id}", nil)
@@ -110,7 +156,7 @@ func TestGetUserByID(t *testing.T) {
 
 func TestGetUserByID_InvalidID(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/users/{id}", nil)
@@ -128,7 +174,7 @@ func TestGetUserByID_InvalidID(t *testing.T) {
 
 func TestGetAllUsers(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/users", nil)
 	rr := httptest.NewRecorder()
@@ -150,7 +196,7 @@ func TestGetAllUsers(t *testing.T) {
 
 func TestDeleteUser(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodDelete, "/users/1", nil)
 	rr := httptest.NewRecorder()
@@ -160,7 +206,7 @@ func TestDeleteUser(t *testing.T) {
 
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
 
-	mockService.On("DeleteUser", mock.Anything, 1).Return(fmt.Errorf("user not found")).Once()
+	mockService.On("DeleteUser", mock.Anything, 1).Return(fmt.Errorf("pq: connection refused")).Once()
 
 	handler.DeleteUser(rr, req)
 
@@ -168,12 +214,36 @@ func TestDeleteUser(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
 
 	body, _ := io.ReadAll(res.Body)
-	assert.Equal(t, "user not found", strings.Trim(string(body), "\n"))
+	assert.NotContains(t, string(body), "connection refused")
+}
+
+func TestDeleteUser_NotFound(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/2", nil)
+	rr := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "2")
+
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	mockService.On("DeleteUser", mock.Anything, 2).Return(services.NewNotFoundError("user_not_found", "user not found")).Once()
+
+	handler.DeleteUser(rr, req)
+
+	res := rr.Result()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	var problem Problem
+	json.NewDecoder(res.Body).Decode(&problem)
+	assert.Equal(t, "user_not_found", problem.Code)
 }
 
 func TestDeleteUser_InvalidID(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodDelete, "/users/invalid", nil)
 	rr := httptest.NewRecorder()
@@ -183,3 +253,293 @@ func TestDeleteUser_InvalidID(t *testing.T) {
 	res := rr.Result()
 	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
 }
+
+func TestSetRole(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
+
+	mockService.On("SetRole", mock.Anything, 1, 5, "admin").Return(&models.User{ID: 5, IsAdmin: true}, nil).Once()
+	mockService.On("SetRole", mock.Anything, 1, 1, "user").
+		Return((*models.User)(nil), services.NewForbiddenError("own_role", "admins cannot change their own role")).Once()
+
+	rr := httptest.NewRecorder()
+	req := withURLParams(jsonRequest(http.MethodPut, "/users/5/role", map[string]string{"role": "admin"}), map[string]string{"id": "5"})
+	handler.SetRole(rr, withSession(req))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var user models.User
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&user))
+	assert.True(t, user.IsAdmin)
+
+	rr = httptest.NewRecorder()
+	req = withURLParams(jsonRequest(http.MethodPut, "/users/1/role", map[string]string{"role": "user"}), map[string]string{"id": "1"})
+	handler.SetRole(rr, withSession(req))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = httptest.NewRecorder()
+	req = withURLParams(jsonRequest(http.MethodPut, "/users/5/role", map[string]string{}), map[string]string{"id": "5"})
+	handler.SetRole(rr, withSession(req))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestCreateUser_InviteRequired(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := NewUserHandler(mockService, nil, new(MockAccountService), nil, nil)
+
+	mockService.On("CreateUser", mock.Anything, "Jane", "jane@example.com", "correct horse battery staple", "").
+		Return((*models.User)(nil), services.NewForbiddenError("invite_required", "an invite code is required to sign up"))
+
+	rr := httptest.NewRecorder()
+	handler.CreateUser(rr, jsonRequest(http.MethodPost, "/auth/signup", signupRequest{Name: "Jane", Email: "jane@example.com", Password: "correct horse battery staple"}))
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	var problem Problem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&problem))
+	assert.Equal(t, "invite_required", problem.Code)
+}
+
+func TestPendingUsers(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
+
+	mockService.On("ListPendingUsers", mock.Anything).Return([]models.User{{ID: 5, Name: "Jane", PendingApproval: true}}, nil)
+	mockService.On("ApproveUser", mock.Anything, 5).Return(&models.User{ID: 5, Name: "Jane"}, nil)
+	mockService.On("RejectUser", mock.Anything, 6).Return(nil)
+	mockService.On("RejectUser", mock.Anything, 7).Return(services.NewConflictError("user_not_pending", "user is not awaiting approval"))
+
+	rr := httptest.NewRecorder()
+	handler.ListPendingUsers(rr, withSession(httptest.NewRequest(http.MethodGet, "/users/pending", nil)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var users []models.User
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&users))
+	assert.True(t, users[0].PendingApproval)
+
+	rr = httptest.NewRecorder()
+	handler.ApproveUser(rr, withURLParams(httptest.NewRequest(http.MethodPost, "/users/5/approve", nil), map[string]string{"id": "5"}))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.RejectUser(rr, withURLParams(httptest.NewRequest(http.MethodPost, "/users/6/reject", nil), map[string]string{"id": "6"}))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.RejectUser(rr, withURLParams(httptest.NewRequest(http.MethodPost, "/users/7/reject", nil), map[string]string{"id": "7"}))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+// MockLoginGuard is a mock implementation of LoginGuardInterface.
+type MockLoginGuard struct {
+	mock.Mock
+}
+
+func (m *MockLoginGuard) Check(ctx context.Context, email, ip string) error {
+	args := m.Called(ctx, email, ip)
+	return args.Error(0)
+}
+
+func (m *MockLoginGuard) RecordFailure(ctx context.Context, email, ip string) error {
+	args := m.Called(ctx, email, ip)
+	return args.Error(0)
+}
+
+func (m *MockLoginGuard) RecordSuccess(ctx context.Context, email, ip string) {
+	m.Called(ctx, email, ip)
+}
+
+func (m *MockLoginGuard) Unlock(ctx context.Context, userID int, unlockedBy int) error {
+	args := m.Called(ctx, userID, unlockedBy)
+	return args.Error(0)
+}
+
+func newLoginRequest(email, password string) *http.Request {
+	body, _ := json.Marshal(loginRequest{Email: email, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+	req.RemoteAddr = "203.0.113.7:51234"
+	return req
+}
+
+// MockSessionService is a mock implementation of SessionServiceInterface.
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) StartSession(ctx context.Context, userID int, ip, userAgent string) (string, error) {
+	args := m.Called(ctx, userID, ip, userAgent)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockSessionService) ValidateSession(ctx context.Context, token *models.Token) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) RevokeOtherSessions(ctx context.Context, userID int, currentSessionID int) error {
+	args := m.Called(ctx, userID, currentSessionID)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) RevokeAllSessions(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) ListSessions(ctx context.Context, userID int, currentSessionID int) ([]models.Session, error) {
+	args := m.Called(ctx, userID, currentSessionID)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *MockSessionService) RevokeSession(ctx context.Context, userID int, sessionID int) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func TestLoginHandler(t *testing.T) {
+	mockService := new(MockUserService)
+	mockGuard := new(MockLoginGuard)
+	mockSessions := new(MockSessionService)
+	mockTwoFactor := new(MockTwoFactorService)
+	handler := NewUserHandler(mockService, mockGuard, nil, mockSessions, mockTwoFactor)
+
+	mockGuard.On("Check", mock.Anything, "john@example.com", "203.0.113.7").Return(nil)
+	mockService.On("GetUserByCreds", mock.Anything, "john@example.com", "password123").Return(&models.User{ID: 1, Email: "john@example.com"}, nil)
+	mockGuard.On("RecordSuccess", mock.Anything, "john@example.com", "203.0.113.7").Return()
+	mockTwoFactor.On("IsEnabled", mock.Anything, 1).Return(false, nil)
+	mockSessions.On("StartSession", mock.Anything, 1, "203.0.113.7", "test-agent").Return("session-token", nil)
+
+	req := newLoginRequest("john@example.com", "password123")
+	req.Header.Set("User-Agent", "test-agent")
+	rr := httptest.NewRecorder()
+	handler.LoginHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Bearer session-token", rr.Header().Get("Authorization"))
+	mockGuard.AssertExpectations(t)
+	mockSessions.AssertExpectations(t)
+}
+
+func TestLoginHandler_MFARequired(t *testing.T) {
+	mockService := new(MockUserService)
+	mockGuard := new(MockLoginGuard)
+	mockSessions := new(MockSessionService)
+	mockTwoFactor := new(MockTwoFactorService)
+	handler := NewUserHandler(mockService, mockGuard, nil, mockSessions, mockTwoFactor)
+
+	mockGuard.On("Check", mock.Anything, "john@example.com", "203.0.113.7").Return(nil)
+	mockService.On("GetUserByCreds", mock.Anything, "john@example.com", "password123").Return(&models.User{ID: 1, Email: "john@example.com"}, nil)
+	mockGuard.On("RecordSuccess", mock.Anything, "john@example.com", "203.0.113.7").Return()
+	mockTwoFactor.On("IsEnabled", mock.Anything, 1).Return(true, nil)
+	mockTwoFactor.On("NewChallenge", mock.Anything, 1).Return("mfa-challenge", nil)
+
+	rr := httptest.NewRecorder()
+	handler.LoginHandler(rr, newLoginRequest("john@example.com", "password123"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Authorization"))
+	var body map[string]interface{}
+	json.NewDecoder(rr.Body).Decode(&body)
+	assert.Equal(t, true, body["mfa_required"])
+	assert.Equal(t, "mfa-challenge", body["mfa_token"])
+	mockSessions.AssertNotCalled(t, "StartSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoginMFAHandler(t *testing.T) {
+	mockService := new(MockUserService)
+	mockGuard := new(MockLoginGuard)
+	mockSessions := new(MockSessionService)
+	mockTwoFactor := new(MockTwoFactorService)
+	handler := NewUserHandler(mockService, mockGuard, nil, mockSessions, mockTwoFactor)
+
+	mockTwoFactor.On("ParseChallenge", "mfa-challenge").Return(1, nil)
+	mockService.On("GetUserByID", mock.Anything, 1).Return(&models.User{ID: 1, Email: "john@example.com"}, nil)
+	mockGuard.On("Check", mock.Anything, "john@example.com", "203.0.113.7").Return(nil)
+	mockTwoFactor.On("Verify", mock.Anything, 1, "123456").Return(nil)
+	mockGuard.On("RecordSuccess", mock.Anything, "john@example.com", "203.0.113.7").Return()
+	mockSessions.On("StartSession", mock.Anything, 1, "203.0.113.7", mock.Anything).Return("session-token", nil)
+
+	req := jsonRequest(http.MethodPost, "/auth/login/mfa", mfaLoginRequest{MFAToken: "mfa-challenge", Code: "123456"})
+	req.RemoteAddr = "203.0.113.7:51234"
+	rr := httptest.NewRecorder()
+	handler.LoginMFAHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Bearer session-token", rr.Header().Get("Authorization"))
+	mockGuard.AssertExpectations(t)
+	mockSessions.AssertExpectations(t)
+}
+
+func TestLoginMFAHandler_InvalidCode(t *testing.T) {
+	mockService := new(MockUserService)
+	mockGuard := new(MockLoginGuard)
+	mockSessions := new(MockSessionService)
+	mockTwoFactor := new(MockTwoFactorService)
+	handler := NewUserHandler(mockService, mockGuard, nil, mockSessions, mockTwoFactor)
+
+	mockTwoFactor.On("ParseChallenge", "mfa-challenge").Return(1, nil)
+	mockService.On("GetUserByID", mock.Anything, 1).Return(&models.User{ID: 1, Email: "john@example.com"}, nil)
+	mockGuard.On("Check", mock.Anything, "john@example.com", "203.0.113.7").Return(nil)
+	mockTwoFactor.On("Verify", mock.Anything, 1, "000000").
+		Return(services.NewUnauthorizedError("invalid_mfa_code", "invalid authentication code"))
+	mockGuard.On("RecordFailure", mock.Anything, "john@example.com", "203.0.113.7").Return(nil)
+
+	req := jsonRequest(http.MethodPost, "/auth/login/mfa", mfaLoginRequest{MFAToken: "mfa-challenge", Code: "000000"})
+	req.RemoteAddr = "203.0.113.7:51234"
+	rr := httptest.NewRecorder()
+	handler.LoginMFAHandler(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockGuard.AssertExpectations(t)
+	mockSessions.AssertNotCalled(t, "StartSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoginHandler_InvalidCredentials(t *testing.T) {
+	mockService := new(MockUserService)
+	mockGuard := new(MockLoginGuard)
+	handler := NewUserHandler(mockService, mockGuard, nil, nil, nil)
+
+	mockGuard.On("Check", mock.Anything, "john@example.com", "203.0.113.7").Return(nil)
+	mockService.On("GetUserByCreds", mock.Anything, "john@example.com", "wrong").
+		Return((*models.User)(nil), services.NewUnauthorizedError("invalid_credentials", "invalid email or password"))
+	mockGuard.On("RecordFailure", mock.Anything, "john@example.com", "203.0.113.7").Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.LoginHandler(rr, newLoginRequest("john@example.com", "wrong"))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockGuard.AssertExpectations(t)
+	mockGuard.AssertNotCalled(t, "RecordSuccess", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoginHandler_Throttled(t *testing.T) {
+	mockService := new(MockUserService)
+	mockGuard := new(MockLoginGuard)
+	handler := NewUserHandler(mockService, mockGuard, nil, nil, nil)
+
+	mockGuard.On("Check", mock.Anything, "john@example.com", "203.0.113.7").
+		Return(services.NewTooManyRequestsError("account_locked", "locked", 90*time.Second))
+
+	rr := httptest.NewRecorder()
+	handler.LoginHandler(rr, newLoginRequest("john@example.com", "password123"))
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+	mockService.AssertNotCalled(t, "GetUserByCreds", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUnlockUser(t *testing.T) {
+	mockService := new(MockUserService)
+	mockGuard := new(MockLoginGuard)
+	handler := NewUserHandler(mockService, mockGuard, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/3/unlock", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "3")
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	req = req.WithContext(context.WithValue(ctx, "userID", -1))
+	rr := httptest.NewRecorder()
+
+	mockGuard.On("Unlock", mock.Anything, 3, -1).Return(nil)
+
+	handler.UnlockUser(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockGuard.AssertExpectations(t)
+}
@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"todo_app_backend/internal/app/services"
+	"unicode/utf8"
+)
+
+// validateStruct checks the exported fields of the struct pointed to by v
+// against their `validate` tags and reports every failing field at once.
+//
+// Supported rules (comma separated):
+//
+//	required    the field must not be empty (or nil, for pointers)
+//	email       the field must be a valid email address
+//	min=N       strings must be at least N characters, numbers at least N
+//	max=N       strings must be at most N characters, numbers at most N
+//	oneof=a b c the field must equal one of the listed values
+//
+// Rules other than required are skipped for empty optional fields.
+// Field names in errors are taken from the `json` tag.
+func validateStruct(v interface{}) []services.FieldError {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fieldErrors []services.FieldError
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+
+		name := jsonFieldName(field)
+		if msg := validateField(val.Field(i), tag); msg != "" {
+			fieldErrors = append(fieldErrors, services.FieldError{Field: name, Message: msg})
+		}
+	}
+	return fieldErrors
+}
+
+// validateField applies the rules in tag to value and returns the first
+// failure message, or "" if value is valid.
+func validateField(value reflect.Value, tag string) string {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			if hasRule(tag, "required") {
+				return "is required"
+			}
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	if value.IsZero() {
+		if hasRule(tag, "required") {
+			return "is required"
+		}
+		return ""
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "email":
+			if addr, err := mail.ParseAddress(value.String()); err != nil || addr.Address != value.String() {
+				return "must be a valid email address"
+			}
+		case "min":
+			if n, ok := measure(value); ok && n < mustAtoi(param) {
+				if value.Kind() == reflect.String {
+					return fmt.Sprintf("must be at least %s characters", param)
+				}
+				return fmt.Sprintf("must be at least %s", param)
+			}
+		case "max":
+			if n, ok := measure(value); ok && n > mustAtoi(param) {
+				if value.Kind() == reflect.String {
+					return fmt.Sprintf("must be at most %s characters", param)
+				}
+				return fmt.Sprintf("must be at most %s", param)
+			}
+		case "oneof":
+			options := strings.Fields(param)
+			if !contains(options, fmt.Sprint(value.Interface())) {
+				return "must be one of: " + strings.Join(options, ", ")
+			}
+		}
+	}
+	return ""
+}
+
+// measure returns the length of strings and slices, or the value of integers.
+func measure(value reflect.Value) (int, bool) {
+	switch value.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(value.String()), true
+	case reflect.Slice, reflect.Map:
+		return value.Len(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(value.Int()), true
+	}
+	return 0, false
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func hasRule(tag, rule string) bool {
+	return contains(strings.Split(tag, ","), rule)
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func mustAtoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		panic(fmt.Sprintf("invalid validation rule parameter %q", s))
+	}
+	return n
+}
@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"todo_app_backend/internal/app/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validateTestRequest struct {
+	Name     string  `json:"name" validate:"required,min=2,max=5"`
+	Email    string  `json:"email" validate:"email"`
+	Role     string  `json:"role" validate:"oneof=user admin"`
+	Priority *int    `json:"priority" validate:"required,max=3"`
+	Note     *string `json:"note" validate:"max=3"`
+	Ignored  string  `json:"ignored"`
+}
+
+func TestValidateStruct(t *testing.T) {
+	three, four := 3, 4
+	long := "long note"
+
+	tests := []struct {
+		name     string
+		input    validateTestRequest
+		expected []services.FieldError
+	}{
+		{"Valid", validateTestRequest{Name: "ann", Priority: &three}, nil},
+		{"Valid With Optionals", validateTestRequest{Name: "ann", Email: "a@b.co", Role: "admin", Priority: &three}, nil},
+		{"All Invalid", validateTestRequest{Name: "a", Email: "nope", Role: "root", Priority: &four, Note: &long}, []services.FieldError{
+			{Field: "name", Message: "must be at least 2 characters"},
+			{Field: "email", Message: "must be a valid email address"},
+			{Field: "role", Message: "must be one of: user, admin"},
+			{Field: "priority", Message: "must be at most 3"},
+			{Field: "note", Message: "must be at most 3 characters"},
+		}},
+		{"Missing Required", validateTestRequest{}, []services.FieldError{
+			{Field: "name", Message: "is required"},
+			{Field: "priority", Message: "is required"},
+		}},
+		{"Email With Display Name", validateTestRequest{Name: "ann", Email: "Ann <a@b.co>", Priority: &three}, []services.FieldError{
+			{Field: "email", Message: "must be a valid email address"},
+		}},
+		{"Counts Characters Not Bytes", validateTestRequest{Name: strings.Repeat("é", 5), Priority: &three}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, validateStruct(&tt.input))
+		})
+	}
+}
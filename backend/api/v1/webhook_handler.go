@@ -0,0 +1,194 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// webhookRequest is the body accepted when creating or updating a webhook.
+// Secrets are generated when none is given on creation; on update, an
+// empty secret keeps the current one.
+type webhookRequest struct {
+	URL        string   `json:"url" validate:"required,max=2048"`
+	EventTypes []string `json:"event_types" validate:"required"`
+	Secret     string   `json:"secret" validate:"max=255"`
+	Active     *bool    `json:"active"`
+}
+
+// createdWebhook is the response of CreateWebhook. It is the only time the
+// secret is revealed.
+type createdWebhook struct {
+	*models.Webhook
+	Secret string `json:"secret"`
+}
+
+type WebhookHandler struct {
+	Service services.WebhookServiceInterface
+}
+
+// NewWebhookHandler initializes a new WebhookHandler.
+func NewWebhookHandler(service services.WebhookServiceInterface) *WebhookHandler {
+	return &WebhookHandler{Service: service}
+}
+
+// CreateWebhook adds a webhook of the authenticated user.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	webhook, secret, err := h.Service.CreateWebhook(r.Context(), userID, req.URL, req.EventTypes, req.Secret)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdWebhook{Webhook: webhook, Secret: secret})
+}
+
+// ListWebhooks lists the webhooks of the authenticated user.
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	webhooks, err := h.Service.ListWebhooks(r.Context(), userID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// GetWebhook retrieves a webhook of the authenticated user.
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	webhook, err := h.Service.GetWebhook(r.Context(), userID, id)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// UpdateWebhook changes a webhook of the authenticated user. Setting active
+// to true re-enables a webhook disabled after failed deliveries.
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	var req webhookRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	webhook, err := h.Service.UpdateWebhook(r.Context(), userID, id, req.URL, req.EventTypes, req.Secret, req.Active)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhook)
+}
+
+// DeleteWebhook removes a webhook of the authenticated user.
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	if err := h.Service.DeleteWebhook(r.Context(), userID, id); err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries lists the deliveries of a webhook, newest first. They are
+// paged through with limit and before, the ID returned as next_before.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+	limit, err := queryInt(r, "limit")
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+	before, err := queryInt(r, "before")
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	deliveries, err := h.Service.ListDeliveries(r.Context(), userID, id, int64(before), limit)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{"deliveries": deliveries}
+	if limit <= 0 {
+		limit = services.DefaultWebhookDeliveryPageSize
+	}
+	if len(deliveries) > 0 && len(deliveries) >= limit {
+		response["next_before"] = deliveries[len(deliveries)-1].ID
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Redeliver queues a past delivery of a webhook again.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "delivery_id"), 10, 64)
+	if err != nil {
+		RespondError(w, r, errInvalidID)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	delivery, err := h.Service.Redeliver(r.Context(), userID, id, deliveryID)
+	if err != nil {
+		RespondError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+var _ WebhookHandlerInterface = (*WebhookHandler)(nil)
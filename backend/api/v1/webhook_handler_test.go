@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWebhookService is a mock implementation of WebhookServiceInterface.
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) CreateWebhook(ctx context.Context, userID int, rawURL string, eventTypes []string, secret string) (*models.Webhook, string, error) {
+	args := m.Called(ctx, userID, rawURL, eventTypes, secret)
+	webhook, _ := args.Get(0).(*models.Webhook)
+	return webhook, args.String(1), args.Error(2)
+}
+
+func (m *MockWebhookService) ListWebhooks(ctx context.Context, userID int) ([]models.Webhook, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookService) GetWebhook(ctx context.Context, userID int, id int) (*models.Webhook, error) {
+	args := m.Called(ctx, userID, id)
+	webhook, _ := args.Get(0).(*models.Webhook)
+	return webhook, args.Error(1)
+}
+
+func (m *MockWebhookService) UpdateWebhook(ctx context.Context, userID int, id int, rawURL string, eventTypes []string, secret string, active *bool) (*models.Webhook, error) {
+	args := m.Called(ctx, userID, id, rawURL, eventTypes, secret, active)
+	webhook, _ := args.Get(0).(*models.Webhook)
+	return webhook, args.Error(1)
+}
+
+func (m *MockWebhookService) DeleteWebhook(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) ListDeliveries(ctx context.Context, userID int, webhookID int, beforeID int64, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(ctx, userID, webhookID, beforeID, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookService) Redeliver(ctx context.Context, userID int, webhookID int, deliveryID int64) (*models.WebhookDelivery, error) {
+	args := m.Called(ctx, userID, webhookID, deliveryID)
+	delivery, _ := args.Get(0).(*models.WebhookDelivery)
+	return delivery, args.Error(1)
+}
+
+// withWebhookID adds the id URL parameter of webhook routes to r.
+func withWebhookID(r *http.Request, id string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	return withSession(r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)))
+}
+
+func TestCreateWebhook(t *testing.T) {
+	mockService := new(MockWebhookService)
+	handler := NewWebhookHandler(mockService)
+
+	eventTypes := []string{models.EventTodoCreated}
+	mockService.On("CreateWebhook", mock.Anything, 1, "https://example.com/hook", eventTypes, "").
+		Return(&models.Webhook{ID: 3, URL: "https://example.com/hook", Secret: "whsec_secret", EventTypes: eventTypes, Active: true}, "whsec_secret", nil)
+
+	rr := httptest.NewRecorder()
+	req := jsonRequest(http.MethodPost, "/webhooks", webhookRequest{URL: "https://example.com/hook", EventTypes: eventTypes})
+	handler.CreateWebhook(rr, withSession(req))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var body map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, float64(3), body["id"])
+	// The secret is revealed once, on creation
+	assert.Equal(t, "whsec_secret", body["secret"])
+	mockService.AssertExpectations(t)
+}
+
+func TestCreateWebhook_PrivateAddress(t *testing.T) {
+	// The real service, which rejects the URL before reaching its repository
+	handler := NewWebhookHandler(services.NewWebhookService(nil))
+
+	rr := httptest.NewRecorder()
+	req := jsonRequest(http.MethodPost, "/webhooks", webhookRequest{URL: "http://169.254.169.254/latest/meta-data", EventTypes: []string{models.EventTodoCreated}})
+	handler.CreateWebhook(rr, withSession(req))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "must not point to a private address")
+}
+
+func TestListWebhooks(t *testing.T) {
+	mockService := new(MockWebhookService)
+	handler := NewWebhookHandler(mockService)
+
+	mockService.On("ListWebhooks", mock.Anything, 1).
+		Return([]models.Webhook{{ID: 3, URL: "https://example.com/hook", Secret: "whsec_secret", Active: true}}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ListWebhooks(rr, withSession(httptest.NewRequest(http.MethodGet, "/webhooks", nil)))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body []map[string]interface{}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Len(t, body, 1)
+	assert.Equal(t, "https://example.com/hook", body[0]["url"])
+	assert.NotContains(t, body[0], "secret")
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	mockService := new(MockWebhookService)
+	handler := NewWebhookHandler(mockService)
+
+	mockService.On("DeleteWebhook", mock.Anything, 1, 3).Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.DeleteWebhook(rr, withWebhookID(httptest.NewRequest(http.MethodDelete, "/webhooks/3", nil), "3"))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestWebhook_OtherUser(t *testing.T) {
+	mockService := new(MockWebhookService)
+	handler := NewWebhookHandler(mockService)
+
+	// Webhooks of other users are looked up with the ID of the
+	// authenticated user and not found
+	notFound := services.NewNotFoundError("webhook_not_found", "webhook not found")
+	mockService.On("GetWebhook", mock.Anything, 1, 8).Return(nil, notFound)
+	mockService.On("DeleteWebhook", mock.Anything, 1, 8).Return(notFound)
+
+	rr := httptest.NewRecorder()
+	handler.GetWebhook(rr, withWebhookID(httptest.NewRequest(http.MethodGet, "/webhooks/8", nil), "8"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "webhook_not_found")
+
+	rr = httptest.NewRecorder()
+	handler.DeleteWebhook(rr, withWebhookID(httptest.NewRequest(http.MethodDelete, "/webhooks/8", nil), "8"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteWebhook_InvalidID(t *testing.T) {
+	handler := NewWebhookHandler(new(MockWebhookService))
+
+	rr := httptest.NewRecorder()
+	handler.DeleteWebhook(rr, withWebhookID(httptest.NewRequest(http.MethodDelete, "/webhooks/abc", nil), "abc"))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,403 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// WebSocketProtocol is the subprotocol spoken on /ws.
+	WebSocketProtocol = "todo.v1"
+	// WebSocketTokenPrefix prefixes the token that browsers, which cannot
+	// set the Authorization header of the handshake, offer as a subprotocol
+	// next to WebSocketProtocol.
+	WebSocketTokenPrefix = "bearer."
+	// DefaultWebSocketPingInterval is how often idle connections are
+	// pinged. Connections that do not answer two pings in a row are closed.
+	DefaultWebSocketPingInterval = 30 * time.Second
+	// wsSendBuffer is the number of messages a client may fall behind
+	// before it is disconnected.
+	wsSendBuffer = 64
+	// wsWriteWait is how long a single message may take to be written.
+	wsWriteWait = 10 * time.Second
+)
+
+var (
+	errWSInvalidRequest    = &services.Error{Kind: services.KindValidation, Code: "invalid_request", Message: "invalid request"}
+	errWSAlreadySubscribed = services.NewConflictError("already_subscribed", "already subscribed to events")
+	errWSWriteScope        = services.NewForbiddenError("insufficient_scope", "access token lacks the required scope")
+)
+
+// wsRequest is a command sent by the client. Responses carry its ID.
+type wsRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// wsResponse answers a wsRequest with either a result or an error.
+type wsResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result,omitempty"`
+	Error  *wsError        `json:"error,omitempty"`
+}
+
+// wsError reports a failed command with the code and fields of the
+// problem details of the REST API.
+type wsError struct {
+	Code    string                `json:"code"`
+	Message string                `json:"message"`
+	Errors  []services.FieldError `json:"errors,omitempty"`
+}
+
+// wsNotification is a message the server sends on its own, such as events.
+type wsNotification struct {
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+// wsTodoParams are the parameters of the todo commands.
+type wsTodoParams struct {
+	ID int `json:"id"`
+	todoRequest
+}
+
+// wsSubscribeParams are the parameters of subscribe. LastEventID resumes
+// the events after the given one, as with the Last-Event-ID header of
+// /events.
+type wsSubscribeParams struct {
+	LastEventID int64 `json:"last_event_id"`
+}
+
+type WebSocketHandler struct {
+	Todos        services.TodoServiceInterface
+	Broker       services.EventBrokerInterface
+	PingInterval time.Duration
+
+	upgrader websocket.Upgrader
+}
+
+// NewWebSocketHandler initializes a new WebSocketHandler.
+func NewWebSocketHandler(todos services.TodoServiceInterface, broker services.EventBrokerInterface) *WebSocketHandler {
+	return &WebSocketHandler{
+		Todos:        todos,
+		Broker:       broker,
+		PingInterval: DefaultWebSocketPingInterval,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{WebSocketProtocol},
+			// Clients authenticate with a token rather than a cookie, so
+			// other sites cannot open connections on their behalf.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// Serve upgrades the request of an authenticated user to a WebSocket
+// connection speaking a JSON-RPC-style protocol. Clients send commands as
+// {"id", "method", "params"} and receive {"id", "result"} or {"id", "error"}
+// in return:
+//
+//   - todo.create takes title and content, and returns the todo
+//   - todo.update takes id, title, content and status, and returns the todo
+//   - todo.delete takes id
+//   - subscribe starts sending events as {"method": "event", "params": event},
+//     resuming after last_event_id if it is given
+//   - unsubscribe stops sending events
+//   - ping returns "pong"
+//
+// Commands are run one at a time, so clients sending faster than they are
+// served are slowed down. Clients that fall too far behind on the messages
+// sent to them are disconnected with close code 1013 and resume with
+// last_event_id.
+func (h *WebSocketHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has responded
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	userID, _ := ctx.Value("userID").(int)
+	scopes, limited := ctx.Value("scopes").([]string)
+	c := &wsConn{
+		h:        h,
+		conn:     conn,
+		ctx:      ctx,
+		cancel:   cancel,
+		userID:   userID,
+		canWrite: !limited || contains(scopes, models.ScopeTodosWrite),
+		send:     make(chan interface{}, wsSendBuffer),
+		reqID:    middleware.GetReqID(ctx),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.writeLoop()
+	}()
+	c.readLoop()
+	c.close(websocket.CloseNormalClosure, "")
+	<-done
+	c.unsubscribe()
+}
+
+// wsConn is a WebSocket connection of a user.
+type wsConn struct {
+	h        *WebSocketHandler
+	conn     *websocket.Conn
+	ctx      context.Context
+	cancel   context.CancelFunc
+	userID   int
+	canWrite bool
+	send     chan interface{}
+	reqID    string
+
+	mu         sync.Mutex
+	sub        *services.EventSubscription
+	closeCode  int
+	closeText  string
+	closeOnce  sync.Once
+	subscribed sync.WaitGroup
+}
+
+// close makes the write loop send a close message with code and text and
+// end the connection.
+func (c *wsConn) close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closeCode, c.closeText = code, text
+		c.mu.Unlock()
+		c.cancel()
+	})
+}
+
+// readLoop runs the commands of the client until the connection fails or is
+// closed.
+func (c *wsConn) readLoop() {
+	pongWait := 2 * c.h.PingInterval
+	c.conn.SetReadLimit(maxBodyBytes)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		messageType, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && c.ctx.Err() == nil {
+				log.Printf("websocket read failed [request_id=%s]: %v", c.reqID, err)
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		if messageType != websocket.TextMessage {
+			c.close(websocket.CloseUnsupportedData, "text messages only")
+			return
+		}
+
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil || req.Method == "" {
+			c.reply(wsResponse{ID: req.ID, Error: c.wsError(errWSInvalidRequest)})
+			continue
+		}
+		result, err := c.handle(req)
+		if err != nil {
+			c.reply(wsResponse{ID: req.ID, Error: c.wsError(err)})
+		} else {
+			c.reply(wsResponse{ID: req.ID, Result: result})
+		}
+		if c.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// handle runs the command req.
+func (c *wsConn) handle(req wsRequest) (interface{}, error) {
+	switch req.Method {
+	case "ping":
+		return "pong", nil
+	case "subscribe":
+		var params wsSubscribeParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return struct{}{}, c.subscribe(params.LastEventID)
+	case "unsubscribe":
+		c.unsubscribe()
+		return struct{}{}, nil
+	case "todo.create", "todo.update", "todo.delete":
+	default:
+		return nil, services.NewNotFoundError("method_not_found", "unknown method "+req.Method)
+	}
+
+	if !c.canWrite {
+		return nil, errWSWriteScope
+	}
+	var params wsTodoParams
+	if err := decodeParams(req.Params, &params); err != nil {
+		return nil, err
+	}
+	if req.Method != "todo.delete" {
+		if fieldErrors := validateStruct(&params.todoRequest); len(fieldErrors) > 0 {
+			return nil, services.NewValidationError("request validation failed", fieldErrors...)
+		}
+	}
+
+	switch req.Method {
+	case "todo.create":
+		return c.h.Todos.CreateTodo(c.ctx, c.userID, params.Title, params.Content, params.Tags.Value, params.DueDate.Value)
+	case "todo.update":
+		if err := c.h.Todos.UpdateTodo(c.ctx, c.userID, params.ID, params.Title, params.Content, params.Status, params.Tags, params.DueDate); err != nil {
+			return nil, err
+		}
+		return c.h.Todos.GetTodoByID(c.ctx, c.userID, params.ID)
+	default:
+		if err := c.h.Todos.DeleteTodo(c.ctx, c.userID, params.ID); err != nil {
+			return nil, err
+		}
+		return map[string]int{"id": params.ID}, nil
+	}
+}
+
+// subscribe starts forwarding the events of the user to the client.
+func (c *wsConn) subscribe(lastEventID int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sub != nil {
+		return errWSAlreadySubscribed
+	}
+
+	sub, replay := c.h.Broker.Subscribe(c.userID, lastEventID)
+	c.sub = sub
+	c.subscribed.Add(1)
+	go func() {
+		defer c.subscribed.Done()
+		for _, event := range replay {
+			c.notify(wsNotification{Method: "event", Params: event})
+		}
+		for event := range sub.C {
+			c.notify(wsNotification{Method: "event", Params: event})
+		}
+		switch sub.End() {
+		case services.EventSubscriptionDropped:
+			c.close(websocket.CloseTryAgainLater, "too slow")
+		case services.EventSubscriptionReset:
+			c.close(websocket.CloseTryAgainLater, "events lost")
+		}
+	}()
+	return nil
+}
+
+// unsubscribe stops forwarding events, once the events already received
+// have been queued.
+func (c *wsConn) unsubscribe() {
+	c.mu.Lock()
+	sub := c.sub
+	c.sub = nil
+	c.mu.Unlock()
+
+	if sub != nil {
+		c.h.Broker.Unsubscribe(sub)
+	}
+	c.subscribed.Wait()
+}
+
+// reply queues a response, waiting for room so that clients not reading
+// their responses stop being served.
+func (c *wsConn) reply(msg wsResponse) {
+	select {
+	case c.send <- msg:
+	case <-c.ctx.Done():
+	}
+}
+
+// notify queues a notification. Clients that have fallen too far behind are
+// disconnected rather than waited for.
+func (c *wsConn) notify(msg wsNotification) {
+	select {
+	case c.send <- msg:
+	default:
+		c.close(websocket.CloseTryAgainLater, "too slow")
+	}
+}
+
+// writeLoop writes the queued messages and pings the client until the
+// connection is closed.
+func (c *wsConn) writeLoop() {
+	defer c.conn.Close()
+
+	ticker := time.NewTicker(c.h.PingInterval)
+	defer ticker.Stop()
+	for {
+		// Closing takes precedence over the queued messages
+		if c.ctx.Err() != nil {
+			c.writeClose()
+			return
+		}
+
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.ctx.Done():
+		}
+	}
+}
+
+// writeClose sends the close message requested with close.
+func (c *wsConn) writeClose() {
+	c.mu.Lock()
+	code, text := c.closeCode, c.closeText
+	c.mu.Unlock()
+	if code == 0 || code == websocket.CloseAbnormalClosure {
+		return
+	}
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(wsWriteWait))
+}
+
+// wsError converts err into the error of a response. Errors that are not
+// domain errors are logged and reported as a generic internal error.
+func (c *wsConn) wsError(err error) *wsError {
+	var domainErr *services.Error
+	if errors.As(err, &domainErr) && domainErr.Kind != services.KindInternal {
+		return &wsError{Code: domainErr.Code, Message: domainErr.Message, Errors: domainErr.Fields}
+	}
+	log.Printf("internal error [request_id=%s]: %v", c.reqID, err)
+	return &wsError{Code: "internal_error", Message: "an unexpected error occurred"}
+}
+
+// decodeParams reads the parameters of a command into dst, rejecting
+// unknown fields. Commands without parameters leave dst unchanged.
+func decodeParams(params json.RawMessage, dst interface{}) error {
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	return nil
+}
+
+var _ WebSocketHandlerInterface = (*WebSocketHandler)(nil)
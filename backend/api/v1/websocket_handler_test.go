@@ -0,0 +1,198 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/services"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockTodoCommands is a mock implementation of TodoServiceInterface. The
+// MockTodoService of the todo handler tests lives in the v1_test package.
+type MockTodoCommands struct {
+	mock.Mock
+}
+
+func (m *MockTodoCommands) CreateTodo(ctx context.Context, userId int, title, content string, tags []string, dueDate *time.Time) (*models.Todo, error) {
+	args := m.Called(ctx, userId, title, content, tags, dueDate)
+	todo, _ := args.Get(0).(*models.Todo)
+	return todo, args.Error(1)
+}
+
+func (m *MockTodoCommands) GetAllTodos(ctx context.Context, userId int) ([]models.Todo, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).([]models.Todo), args.Error(1)
+}
+
+func (m *MockTodoCommands) GetTodoByID(ctx context.Context, userId, id int) (*models.Todo, error) {
+	args := m.Called(ctx, userId, id)
+	todo, _ := args.Get(0).(*models.Todo)
+	return todo, args.Error(1)
+}
+
+func (m *MockTodoCommands) UpdateTodo(ctx context.Context, userId, id int, title, content, status string, tags models.Optional[[]string], dueDate models.Optional[*time.Time]) error {
+	return m.Called(ctx, userId, id, title, content, status, tags, dueDate).Error(0)
+}
+
+func (m *MockTodoCommands) DeleteTodo(ctx context.Context, userId, id int) error {
+	return m.Called(ctx, userId, id).Error(0)
+}
+
+// dialWebSocket serves handler for user 1 with the session or the scopes of
+// an access token, and connects to it.
+func dialWebSocket(t *testing.T, handler *WebSocketHandler, scopes ...string) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = withSession(r)
+		if len(scopes) > 0 {
+			r = r.WithContext(context.WithValue(r.Context(), "scopes", scopes))
+		}
+		handler.Serve(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	dialer := websocket.Dialer{Subprotocols: []string{WebSocketProtocol}}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, WebSocketProtocol, resp.Header.Get("Sec-WebSocket-Protocol"))
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// call sends a command and returns the next message received.
+func call(t *testing.T, conn *websocket.Conn, id interface{}, method string, params interface{}) map[string]interface{} {
+	t.Helper()
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"id": id, "method": method, "params": params}))
+	var msg map[string]interface{}
+	require.NoError(t, conn.ReadJSON(&msg))
+	return msg
+}
+
+func TestWebSocketCommands(t *testing.T) {
+	mockService := new(MockTodoCommands)
+	conn := dialWebSocket(t, NewWebSocketHandler(mockService, services.NewEventBroker(16)))
+
+	mockService.On("CreateTodo", mock.Anything, 1, "Buy milk", "", []string(nil), (*time.Time)(nil)).Return(&models.Todo{ID: 5, Title: "Buy milk"}, nil)
+	msg := call(t, conn, 1, "todo.create", map[string]string{"title": "Buy milk"})
+	assert.Equal(t, float64(1), msg["id"])
+	assert.Equal(t, "Buy milk", msg["result"].(map[string]interface{})["title"])
+
+	due := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	mockService.On("UpdateTodo", mock.Anything, 1, 5, "Buy oat milk", "", "completed", models.Some([]string{"shopping"}), models.Some(&due)).Return(nil)
+	mockService.On("GetTodoByID", mock.Anything, 1, 5).Return(&models.Todo{ID: 5, Title: "Buy oat milk", Status: "completed"}, nil)
+	msg = call(t, conn, 2, "todo.update", map[string]interface{}{
+		"id": 5, "title": "Buy oat milk", "status": "completed", "tags": []string{"shopping"}, "due_date": "2024-05-15T09:00:00Z",
+	})
+	assert.Equal(t, "completed", msg["result"].(map[string]interface{})["status"])
+
+	mockService.On("DeleteTodo", mock.Anything, 1, 6).Return(services.NewNotFoundError("todo_not_found", "todo not found"))
+	msg = call(t, conn, "three", "todo.delete", map[string]int{"id": 6})
+	assert.Equal(t, "three", msg["id"])
+	assert.Equal(t, "todo_not_found", msg["error"].(map[string]interface{})["code"])
+
+	msg = call(t, conn, 4, "todo.create", map[string]string{"content": "no title"})
+	assert.Equal(t, "validation_failed", msg["error"].(map[string]interface{})["code"])
+
+	msg = call(t, conn, 5, "todo.archive", nil)
+	assert.Equal(t, "method_not_found", msg["error"].(map[string]interface{})["code"])
+
+	msg = call(t, conn, 6, "ping", nil)
+	assert.Equal(t, "pong", msg["result"])
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("{")))
+	var invalid map[string]interface{}
+	require.NoError(t, conn.ReadJSON(&invalid))
+	assert.Nil(t, invalid["id"])
+	assert.Equal(t, "invalid_request", invalid["error"].(map[string]interface{})["code"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestWebSocketCommands_ReadOnlyToken(t *testing.T) {
+	mockService := new(MockTodoCommands)
+	conn := dialWebSocket(t, NewWebSocketHandler(mockService, services.NewEventBroker(16)), models.ScopeTodosRead)
+
+	msg := call(t, conn, 1, "todo.create", map[string]string{"title": "Buy milk"})
+	assert.Equal(t, "insufficient_scope", msg["error"].(map[string]interface{})["code"])
+	mockService.AssertNotCalled(t, "CreateTodo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWebSocketSubscribe(t *testing.T) {
+	broker := services.NewEventBroker(16)
+	conn := dialWebSocket(t, NewWebSocketHandler(new(MockTodoCommands), broker))
+
+	first := &models.Event{Type: models.EventTodoCreated, UserID: 1, Data: json.RawMessage(`{"id":5}`)}
+	broker.Publish(context.Background(), first)
+	broker.Publish(context.Background(), &models.Event{Type: models.EventTodoUpdated, UserID: 1, Data: json.RawMessage(`{"id":5}`)})
+
+	// Events after last_event_id are replayed
+	msg := call(t, conn, 1, "subscribe", map[string]int64{"last_event_id": first.ID})
+	assert.Equal(t, map[string]interface{}{}, msg["result"])
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "event", msg["method"])
+	assert.Equal(t, models.EventTodoUpdated, msg["params"].(map[string]interface{})["type"])
+
+	msg = call(t, conn, 2, "subscribe", nil)
+	assert.Equal(t, "already_subscribed", msg["error"].(map[string]interface{})["code"])
+
+	// Only the events of the user are sent
+	broker.Publish(context.Background(), &models.Event{Type: models.EventTodoCreated, UserID: 2, Data: json.RawMessage(`{"id":6}`)})
+	broker.Publish(context.Background(), &models.Event{Type: models.EventTodoDeleted, UserID: 1, Data: json.RawMessage(`{"id":5}`)})
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, models.EventTodoDeleted, msg["params"].(map[string]interface{})["type"])
+
+	msg = call(t, conn, 3, "unsubscribe", nil)
+	assert.Equal(t, float64(3), msg["id"])
+	broker.Publish(context.Background(), &models.Event{Type: models.EventTodoCreated, UserID: 1, Data: json.RawMessage(`{"id":7}`)})
+	msg = call(t, conn, 4, "ping", nil)
+	assert.Equal(t, "pong", msg["result"])
+}
+
+func TestWebSocketSlowConsumer(t *testing.T) {
+	broker := services.NewEventBroker(0)
+	conn := dialWebSocket(t, NewWebSocketHandler(new(MockTodoCommands), broker))
+	call(t, conn, 1, "subscribe", nil)
+
+	// Events are published faster than they are read, until the socket
+	// buffers are full and the client is disconnected
+	data, _ := json.Marshal(map[string]string{"content": strings.Repeat("x", 64<<10)})
+	for i := 0; i < 500; i++ {
+		broker.Publish(context.Background(), &models.Event{Type: models.EventTodoCreated, UserID: 1, Data: data})
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var err error
+	for err == nil {
+		_, _, err = conn.ReadMessage()
+	}
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseTryAgainLater), "unexpected error %v", err)
+}
+
+func TestWebSocketPing(t *testing.T) {
+	handler := NewWebSocketHandler(new(MockTodoCommands), services.NewEventBroker(16))
+	handler.PingInterval = 20 * time.Millisecond
+	conn := dialWebSocket(t, handler)
+
+	pings := make(chan struct{}, 10)
+	conn.SetPingHandler(func(string) error {
+		pings <- struct{}{}
+		return nil
+	})
+
+	// Clients that do not answer pings are disconnected
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	assert.Error(t, err)
+	assert.NotEmpty(t, pings)
+}
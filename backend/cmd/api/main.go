@@ -7,17 +7,27 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
 	"time"
+	// Timezones of users are resolved without zoneinfo in the image
+	_ "time/tzdata"
 	"todo_app_backend/api"
 	v1 "todo_app_backend/api/v1"
 	"todo_app_backend/config"
+	"todo_app_backend/internal/app/models"
 	"todo_app_backend/internal/app/repositories"
 	"todo_app_backend/internal/app/services"
+	"todo_app_backend/internal/app/utils"
 	"todo_app_backend/internal/database"
+	"todo_app_backend/internal/eventbus"
+	"todo_app_backend/internal/mailer"
+	"todo_app_backend/internal/oidc"
+	"todo_app_backend/internal/password"
+	"todo_app_backend/internal/webpush"
 )
 
-func gracefulShutdown(apiServer *http.Server, done chan bool) {
+func gracefulShutdown(apiServer *http.Server, worker *services.JobWorker, drainTimeout time.Duration, done chan bool) {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
@@ -31,11 +41,123 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 		log.Printf("Server forced to shutdown with error: %v", err)
 	}
 
+	// Running jobs get their own time to finish before they are
+	// interrupted and returned to the queue
+	ctx, cancel = context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+	if err := worker.Drain(ctx); err != nil {
+		log.Printf("Jobs interrupted while draining: %v", err)
+	}
+
 	log.Println("Server exiting")
 
 	done <- true
 }
 
+// newMailer returns the mailer selected by the configuration.
+func newMailer(cfg *config.Config) (mailer.Mailer, error) {
+	switch cfg.Mailer {
+	case "log":
+		return mailer.NewLogMailer(), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.MailDir, cfg.MailFrom)
+	case "smtp":
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	}
+	return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+}
+
+// newEventBus returns the event bus selected by the configuration.
+func newEventBus(cfg *config.Config, db *database.PostgreSQLDB) (eventbus.Bus, error) {
+	switch cfg.EventBus {
+	case "memory":
+		return eventbus.NewMemory(), nil
+	case "postgres":
+		return eventbus.NewPostgres(db.GetConn(), cfg.DatabaseURI)
+	}
+	return nil, fmt.Errorf("unknown event bus %q", cfg.EventBus)
+}
+
+// newPasswordHasher returns the password hasher with the costs of the
+// configuration.
+func newPasswordHasher(cfg *config.Config) (*password.Argon2id, error) {
+	if cfg.PasswordHashMemoryKiB < 1 || cfg.PasswordHashTime < 1 || cfg.PasswordHashParallelism < 1 || cfg.PasswordHashParallelism > 255 {
+		return nil, password.ErrInvalidParams
+	}
+
+	params := password.DefaultParams
+	params.Memory = uint32(cfg.PasswordHashMemoryKiB)
+	params.Time = uint32(cfg.PasswordHashTime)
+	params.Parallelism = uint8(cfg.PasswordHashParallelism)
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return password.NewArgon2id(params), nil
+}
+
+// newPasswordPolicy returns the password policy of the configuration,
+// loading the breached password filter if one is configured.
+func newPasswordPolicy(cfg *config.Config) (*password.Policy, error) {
+	policy := &password.Policy{MinLength: cfg.PasswordMinLength, MinStrength: cfg.PasswordMinStrength}
+	if cfg.PasswordBreachedFilter != "" {
+		filter, err := password.LoadBloomFilter(cfg.PasswordBreachedFilter)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = filter
+	}
+	return policy, nil
+}
+
+// newOIDCProviders returns the OpenID Connect providers of the configuration.
+func newOIDCProviders(cfg *config.Config) []*oidc.Provider {
+	providers := make([]*oidc.Provider, 0, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		providers = append(providers, oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			DisplayName:  p.DisplayName,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  cfg.APIBaseURL + "/api/v1/auth/oidc/" + p.Name + "/callback",
+			Scopes:       p.Scopes,
+		}, nil))
+	}
+	return providers
+}
+
+// scheduledJobs are the job types queued by the periodic tasks of the
+// configuration, by task name.
+var scheduledJobs = map[string]string{
+	"purge_deleted_accounts": models.JobPurgeDeletedAccounts,
+	"send_reminders":         models.JobSendReminders,
+	"notify_overdue_todos":   models.JobNotifyOverdueTodos,
+	"send_digests":           models.JobSendDigests,
+}
+
+// newScheduler returns the scheduler of the periodic tasks of the
+// configuration.
+func newScheduler(cfg *config.Config, repo repositories.ScheduleRepoInterface, jobs services.JobEnqueuer) (*services.Scheduler, error) {
+	scheduler := services.NewScheduler(repo, jobs)
+	scheduler.CatchUp = cfg.ScheduleCatchUp
+
+	names := make([]string, 0, len(cfg.Schedules))
+	for name := range cfg.Schedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		jobType, ok := scheduledJobs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown periodic task %q", name)
+		}
+		if err := scheduler.Add(name, cfg.Schedules[name], jobType); err != nil {
+			return nil, err
+		}
+	}
+	return scheduler, nil
+}
+
 func main() {
 	if os.Getenv("ENVIRONMENT") != "PRODUCTION" {
 		err := config.LoadConfigurationFile(".env")
@@ -48,18 +170,199 @@ func main() {
 		log.Fatal("Error loading config : ", err)
 	}
 
+	hasher, err := newPasswordHasher(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up password hashing: %v", err)
+	}
+	utils.SetPasswordHasher(hasher)
+
+	passwordPolicy, err := newPasswordPolicy(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up the password policy: %v", err)
+	}
+
+	if cfg.JobConcurrency < 1 {
+		log.Fatalf("Invalid job concurrency %d: at least one job must be able to run", cfg.JobConcurrency)
+	}
+
 	db, err := database.NewPostgreSQLDB(cfg.DatabaseURI, cfg.MaxIdleConns, cfg.MaxOpenConns)
 	if err != nil {
 		log.Fatalf("Failed to connect to the database: %v", err)
 	}
 	defer db.Close()
 
-	userHandler := v1.NewUserHandler(services.NewUserService(repositories.NewUserRepository(db.GetConn())))
-	todoHandler := v1.NewTodoHandler(services.NewTodoService(repositories.NewTodoRepository(db.GetConn())))
+	userRepo := repositories.NewUserRepository(db.GetConn())
+	auditService := services.NewAuditService(repositories.NewAuditRepository(db.GetConn()))
+
+	registration := &services.Registration{Mode: cfg.RegistrationMode, AllowedDomains: cfg.RegistrationAllowedDomains}
+	inviteRepo := repositories.NewInviteRepository(db.GetConn())
+	registrationService := services.NewRegistrationService(inviteRepo, registration)
+	registrationService.Audit = auditService
+
+	guardConfig := services.DefaultLoginGuardConfig()
+	guardConfig.MaxAccountFailures = cfg.LoginMaxFailures
+	guardConfig.MaxIPFailures = cfg.LoginMaxIPFailures
+	guardConfig.LockoutDuration = cfg.LoginLockoutDuration
+	loginGuard := services.NewLoginGuard(guardConfig, repositories.NewLockoutRepository(db.GetConn()), userRepo)
+
+	m, err := newMailer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up the mailer: %v", err)
+	}
+	sessionService := services.NewSessionService(repositories.NewSessionRepository(db.GetConn()))
+	sessionService.Audit = auditService
+	accountService := services.NewAccountService(userRepo, repositories.NewUserTokenRepository(db.GetConn()), sessionService, m, cfg.AppBaseURL)
+	accountService.PasswordPolicy = passwordPolicy
+
+	bus, err := newEventBus(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to set up the event bus: %v", err)
+	}
+	defer bus.Close()
+	eventBroker := services.NewEventBroker(services.DefaultEventReplaySize)
+	bus.Subscribe(eventBroker.Deliver)
+
+	webhookRepo := repositories.NewWebhookRepository(db.GetConn())
+	webhookService := services.NewWebhookService(webhookRepo)
+	webhookService.Audit = auditService
+	webhookService.AllowPrivateNetworks = cfg.WebhookAllowPrivateNetworks
+	webhookDispatcher := services.NewWebhookDispatcher(webhookRepo, services.NewWebhookClient(cfg.WebhookAllowPrivateNetworks))
+
+	todoRepo := repositories.NewTodoRepository(db.GetConn())
+	todoService := services.NewTodoService(todoRepo)
+	notificationService := services.NewNotificationService(repositories.NewNotificationRepository(db.GetConn()))
+	notificationService.Events = bus
+	todoService.Events = services.EventPublishers{bus, webhookService, notificationService}
+
+	inboundService := services.NewInboundWebhookService(repositories.NewInboundWebhookRepository(db.GetConn()), todoRepo)
+	inboundService.Events = todoService.Events
+	inboundService.Audit = auditService
+
+	userService := services.NewUserService(userRepo)
+	userService.RequireVerifiedEmail = cfg.RequireEmailVerification
+	userService.PasswordPolicy = passwordPolicy
+	userService.Audit = auditService
+	userService.Registration = registration
+	userService.InviteRepo = inviteRepo
+
+	profileService := services.NewProfileService(userRepo, sessionService, accountService)
+	profileService.DeletionGracePeriod = cfg.AccountDeletionGracePeriod
+	profileService.PasswordPolicy = passwordPolicy
+
+	accessTokenService := services.NewAccessTokenService(repositories.NewAccessTokenRepository(db.GetConn()), userRepo)
+	accessTokenService.Audit = auditService
+	twoFactorService := services.NewTwoFactorService(repositories.NewTwoFactorRepository(db.GetConn()), userRepo, cfg.TOTPIssuer)
+	twoFactorService.Audit = auditService
+	oidcService := services.NewOIDCService(newOIDCProviders(cfg), repositories.NewOIDCRepository(db.GetConn()), userRepo)
+	oidcService.Registration = registration
+	oauthService := services.NewOAuthService(repositories.NewOAuthRepository(db.GetConn()))
+	oauthService.Audit = auditService
+	scimService := services.NewSCIMService(userRepo, sessionService)
+
+	jobRepo := repositories.NewJobRepository(db.GetConn())
+	jobService := services.NewJobService(jobRepo)
+	jobService.Audit = auditService
+	jobWorker := services.NewJobWorker(jobRepo)
+	jobWorker.Concurrency = cfg.JobConcurrency
+	services.HandleJob(jobWorker, models.JobPurgeDeletedAccounts, func(ctx context.Context, _ struct{}) error {
+		n, err := profileService.PurgeDeletedAccounts(ctx)
+		if n > 0 {
+			log.Printf("Purged %d deleted accounts", n)
+		}
+		return err
+	})
+
+	var pushSender *webpush.Sender
+	if cfg.VAPIDPrivateKey != "" {
+		pushSender, err = webpush.NewSender(cfg.VAPIDPrivateKey, cfg.VAPIDSubject, services.NewWebhookClient(cfg.WebhookAllowPrivateNetworks))
+		if err != nil {
+			log.Fatalf("Failed to set up Web Push: %v", err)
+		}
+	}
+	pushService := services.NewPushService(repositories.NewPushSubscriptionRepository(db.GetConn()), pushSender)
+	prefsRepo := repositories.NewNotificationPreferenceRepository(db.GetConn())
+	reminderService := services.NewReminderService(repositories.NewReminderRepository(db.GetConn()), prefsRepo, jobService)
+	reminderService.Channels[models.ChannelEmail] = &services.EmailReminderChannel{Users: userRepo, Mailer: m, BaseURL: cfg.AppBaseURL}
+	reminderService.Channels[models.ChannelWebhook] = &services.EventReminderChannel{Events: webhookService}
+	reminderService.Channels[models.ChannelInApp] = &services.EventReminderChannel{Events: services.EventPublishers{bus, notificationService}}
+	if pushSender != nil {
+		reminderService.Channels[models.ChannelPush] = pushService
+	}
+	services.HandleJob(jobWorker, models.JobSendReminders, func(ctx context.Context, _ models.ScheduledRun) error {
+		n, err := reminderService.SendDueReminders(ctx)
+		if n > 0 {
+			log.Printf("Sent %d reminders", n)
+		}
+		return err
+	})
+	services.HandleJob(jobWorker, models.JobDeliverReminder, func(ctx context.Context, delivery services.ReminderDelivery) error {
+		return reminderService.DeliverReminder(ctx, &delivery)
+	})
+	services.HandleJob(jobWorker, models.JobNotifyOverdueTodos, func(ctx context.Context, _ models.ScheduledRun) error {
+		n, err := todoService.NotifyOverdueTodos(ctx)
+		if n > 0 {
+			log.Printf("Reported %d overdue todos", n)
+		}
+		return err
+	})
+
+	digestService := services.NewDigestService(prefsRepo, todoRepo, userRepo, jobService, m)
+	digestService.BaseURL = cfg.AppBaseURL
+	digestService.APIBaseURL = cfg.APIBaseURL
+	digestService.Hour = cfg.DigestHour
+	services.HandleJob(jobWorker, models.JobSendDigests, func(ctx context.Context, _ models.ScheduledRun) error {
+		n, err := digestService.SendDueDigests(ctx)
+		if n > 0 {
+			log.Printf("Queued %d digests", n)
+		}
+		return err
+	})
+	services.HandleJob(jobWorker, models.JobDeliverDigest, func(ctx context.Context, recipient models.DigestRecipient) error {
+		return digestService.SendDigest(ctx, &recipient)
+	})
+
+	scheduler, err := newScheduler(cfg, repositories.NewScheduleRepository(db.GetConn()), jobService)
+	if err != nil {
+		log.Fatalf("Invalid schedules: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go jobWorker.Run(ctx)
+	go scheduler.Run(ctx)
+	go webhookDispatcher.Run(ctx)
+
+	handlers := api.Handlers{
+		User:          v1.NewUserHandler(userService, loginGuard, accountService, sessionService, twoFactorService),
+		Todo:          v1.NewTodoHandler(todoService),
+		Account:       v1.NewAccountHandler(accountService),
+		Profile:       v1.NewProfileHandler(profileService),
+		Tokens:        v1.NewAccessTokenHandler(accessTokenService),
+		TwoFactor:     v1.NewTwoFactorHandler(twoFactorService),
+		OIDC:          v1.NewOIDCHandler(oidcService, sessionService, twoFactorService, cfg.AppBaseURL),
+		OAuth:         v1.NewOAuthHandler(oauthService, cfg.AppBaseURL),
+		Sessions:      v1.NewSessionHandler(sessionService),
+		Audit:         v1.NewAuditHandler(auditService),
+		SCIM:          v1.NewSCIMHandler(scimService, cfg.APIBaseURL+"/api/v1/scim/v2"),
+		Registration:  v1.NewRegistrationHandler(registrationService),
+		Events:        v1.NewEventHandler(eventBroker),
+		WebSocket:     v1.NewWebSocketHandler(todoService, eventBroker),
+		Webhooks:      v1.NewWebhookHandler(webhookService),
+		Inbound:       v1.NewInboundWebhookHandler(inboundService),
+		Jobs:          v1.NewJobHandler(jobService),
+		Schedules:     v1.NewScheduleHandler(scheduler),
+		Reminders:     v1.NewReminderHandler(reminderService),
+		Push:          v1.NewPushHandler(pushService),
+		Notifications: v1.NewNotificationHandler(notificationService),
+		Digests:       v1.NewDigestHandler(digestService),
+	}
+
+	auth := api.NewAuthenticator(sessionService, accessTokenService, oauthService, userService)
+	auth.SCIMToken = cfg.SCIMToken
 
 	server := http.Server{
 		Addr:         ":8080",
-		Handler:      api.SetupRouter(userHandler, todoHandler),
+		Handler:      api.SetupRouter(handlers, auth, cfg.TrustedProxies),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 20 * time.Second,
 		IdleTimeout:  time.Minute,
@@ -67,7 +370,7 @@ func main() {
 
 	done := make(chan bool, 1)
 
-	go gracefulShutdown(&server, done)
+	go gracefulShutdown(&server, jobWorker, cfg.JobDrainTimeout, done)
 
 	err = server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
@@ -0,0 +1,110 @@
+// Command breached-filter builds the breached password filter loaded from
+// PASSWORD_BREACHED_FILTER out of SHA-1 hash lists such as Have I Been
+// Pwned's Pwned Passwords.
+//
+// Each input line is a hex SHA-1 hash optionally followed by ":count". Files
+// named after a five character hash prefix, as served by the k-anonymity
+// range API, may hold only the hash suffixes.
+//
+//	go run ./cmd/breached-filter -o breached.bloom pwned-passwords-sha1.txt
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"todo_app_backend/internal/password"
+)
+
+const prefixLength = 5
+
+// eachHash calls fn with every hash in the file at path seen at least
+// minCount times.
+func eachHash(path string, minCount int, fn func(sum [sha1.Size]byte)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	prefix := ""
+	if base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)); len(base) == prefixLength {
+		if _, err := hex.DecodeString(base + "0"); err == nil {
+			prefix = base
+		}
+	}
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		hash, count, hasCount := strings.Cut(text, ":")
+		if hasCount && minCount > 1 {
+			if n, err := strconv.Atoi(count); err == nil && n < minCount {
+				continue
+			}
+		}
+		if len(hash) == 2*sha1.Size-prefixLength {
+			hash = prefix + hash
+		}
+
+		var sum [sha1.Size]byte
+		if n, err := hex.Decode(sum[:], []byte(hash)); err != nil || n != sha1.Size {
+			return fmt.Errorf("%s:%d: invalid SHA-1 hash %q", path, line, hash)
+		}
+		fn(sum)
+	}
+	return scanner.Err()
+}
+
+func main() {
+	output := flag.String("o", "breached.bloom", "path of the filter to write")
+	falsePositiveRate := flag.Float64("fp", 0.001, "false positive rate of the filter")
+	minCount := flag.Int("min-count", 1, "skip hashes seen fewer times than this in breaches")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		log.Fatalf("Usage: breached-filter [-o file] [-fp rate] [-min-count n] hashes.txt...")
+	}
+
+	// The filter is sized from a first pass over the input.
+	n := 0
+	for _, path := range flag.Args() {
+		if err := eachHash(path, *minCount, func([sha1.Size]byte) { n++ }); err != nil {
+			log.Fatalf("Failed to read hashes: %v", err)
+		}
+	}
+
+	filter := password.NewBloomFilter(n, *falsePositiveRate)
+	for _, path := range flag.Args() {
+		if err := eachHash(path, *minCount, filter.AddHash); err != nil {
+			log.Fatalf("Failed to read hashes: %v", err)
+		}
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *output, err)
+	}
+	w := bufio.NewWriter(f)
+	if _, err := filter.WriteTo(w); err != nil {
+		log.Fatalf("Failed to write %s: %v", *output, err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Failed to write %s: %v", *output, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Failed to write %s: %v", *output, err)
+	}
+	log.Printf("Wrote %d hashes to %s", n, *output)
+}
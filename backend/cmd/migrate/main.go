@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"todo_app_backend/config"
 	"todo_app_backend/internal/database"
 )
@@ -26,6 +29,64 @@ func executeMigration(db *sql.DB, filePath string) error {
 	return nil
 }
 
+// migrationFiles returns the migration files for the given direction ("up" or
+// "down") in the order they must be applied.
+func migrationFiles(direction string) ([]string, error) {
+	files, err := filepath.Glob(fmt.Sprintf("db/migrations/*.%s.sql", direction))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migration files: %w", err)
+	}
+
+	sort.Strings(files)
+	if direction == "down" {
+		sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	}
+	return files, nil
+}
+
+// executeMigrations runs every migration file for the given direction that
+// has not been applied yet ("up") or is currently applied ("down"). Applied
+// migrations are tracked in the schema_migrations table.
+func executeMigrations(db *sql.DB, direction string) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	files, err := migrationFiles(direction)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		version := strings.SplitN(filepath.Base(file), ".", 2)[0]
+
+		var applied bool
+		if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied); err != nil {
+			return fmt.Errorf("failed to check migration %s: %w", version, err)
+		}
+		if applied == (direction == "up") {
+			continue
+		}
+
+		if err := executeMigration(db, file); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		record := `INSERT INTO schema_migrations (version) VALUES ($1)`
+		if direction == "down" {
+			record = `DELETE FROM schema_migrations WHERE version = $1`
+		}
+		if _, err := db.Exec(record, version); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", version, err)
+		}
+		log.Printf("Applied %s", file)
+	}
+	return nil
+}
+
 func main() {
 	if os.Getenv("ENVIRONMENT") != "PRODUCTION" {
 		err := config.LoadConfigurationFile(".env")
@@ -54,12 +115,12 @@ func main() {
 	action := os.Args[1]
 	switch action {
 	case "up":
-		if err := executeMigration(conn, "db/migrations/001_init.up.sql"); err != nil {
+		if err := executeMigrations(conn, "up"); err != nil {
 			log.Fatalf("Migration up failed: %v", err)
 		}
 		log.Println("Migration up applied successfully")
 	case "down":
-		if err := executeMigration(conn, "db/migrations/001_init.down.sql"); err != nil {
+		if err := executeMigrations(conn, "down"); err != nil {
 			log.Fatalf("Migration down failed: %v", err)
 		}
 		log.Println("Migration down applied successfully")
@@ -0,0 +1,20 @@
+// Command vapid-keys generates the VAPID key pair Web Push messages are
+// signed with. The private key goes in VAPID_PRIVATE_KEY; the public key is
+// derived from it and served to browsers by the API.
+//
+//	go run ./cmd/vapid-keys
+package main
+
+import (
+	"fmt"
+	"log"
+	"todo_app_backend/internal/webpush"
+)
+
+func main() {
+	public, private, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("VAPID_PRIVATE_KEY=%s\n# Public key: %s\n", private, public)
+}
@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+	"todo_app_backend/internal/cron"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +19,121 @@ type Config struct {
 	MaxOpenConns int
 	MaxIdleConns int
 	TokenSecret  []byte
+
+	// Login brute-force protection
+	LoginMaxFailures     int
+	LoginMaxIPFailures   int
+	LoginLockoutDuration time.Duration
+
+	// TrustedProxies are the addresses of the reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers are believed. Requests from any
+	// other peer are attributed to the peer itself, so that clients cannot
+	// choose the address their logins are throttled by
+	TrustedProxies []netip.Prefix
+
+	// AppBaseURL is the public URL of the frontend, used to build links in emails
+	AppBaseURL string
+
+	// Outgoing mail
+	Mailer       string // "log", "file" or "smtp"
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// EventBus carries domain events between instances: "memory" when a
+	// single instance runs, "postgres" to use LISTEN/NOTIFY across replicas
+	EventBus string
+
+	// WebhookAllowPrivateNetworks lets webhooks be sent to loopback and
+	// private addresses, which are refused by default
+	WebhookAllowPrivateNetworks bool
+
+	// Background jobs: JobConcurrency jobs run at once on each instance,
+	// and shutdowns wait up to JobDrainTimeout for running jobs to finish
+	JobConcurrency  int
+	JobDrainTimeout time.Duration
+
+	// Schedules are the cron expressions, in UTC, of the periodic tasks by
+	// name, set with SCHEDULE_<NAME>. Tasks scheduled "off" do not run.
+	Schedules map[string]string
+	// ScheduleCatchUp decides what becomes of the runs missed while no
+	// instance led the scheduler: "skip", "once" or "all"
+	ScheduleCatchUp string
+
+	// VAPIDPrivateKey signs the Web Push messages of reminders, generated
+	// with cmd/vapid-keys. Web Push is disabled when it is empty.
+	VAPIDPrivateKey string
+	// VAPIDSubject is the contact push services reach the operator at, a
+	// mailto: or https: URL
+	VAPIDSubject string
+
+	// DigestHour is the hour, in the timezone of each user, the email
+	// digests of todos are sent at
+	DigestHour int
+
+	// RequireEmailVerification blocks logins until the email address is verified
+	RequireEmailVerification bool
+
+	// RegistrationMode decides who may sign up: "open", "invite_only",
+	// "domain_allowlist" or "approval"
+	RegistrationMode string
+	// RegistrationAllowedDomains are the email domains that may sign up in
+	// "domain_allowlist" mode
+	RegistrationAllowedDomains []string
+
+	// AccountDeletionGracePeriod is how long self-deleted accounts can be restored
+	AccountDeletionGracePeriod time.Duration
+
+	// Argon2id costs of password hashes. Existing hashes are upgraded on
+	// the next login when they change.
+	PasswordHashMemoryKiB   int
+	PasswordHashTime        int
+	PasswordHashParallelism int
+
+	// Password policy of signup, password change and password reset.
+	// PasswordMinStrength is a zxcvbn-style score from 0 to 4.
+	PasswordMinLength   int
+	PasswordMinStrength int
+	// PasswordBreachedFilter is the path of a Bloom filter of breached
+	// passwords built with cmd/breached-filter. The check is disabled when
+	// it is empty.
+	PasswordBreachedFilter string
+
+	// TOTPIssuer names the app in authenticator apps
+	TOTPIssuer string
+
+	// APIBaseURL is the public URL of this API, used to build OpenID Connect redirect URLs
+	APIBaseURL string
+	// OIDCProviders are the identity providers users can sign in with
+	OIDCProviders []OIDCProvider
+
+	// SCIMToken authenticates identity providers provisioning users over
+	// SCIM. SCIM is disabled when it is empty.
+	SCIMToken string
+}
+
+// OIDCProvider configures sign in with an OpenID Connect identity provider.
+type OIDCProvider struct {
+	// Name identifies the provider in URLs, e.g. /api/v1/auth/oidc/{name}/login
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// defaultSchedules are the periodic tasks with their default schedules.
+var defaultSchedules = map[string]string{
+	"purge_deleted_accounts": "@hourly",
+	"send_reminders":         "* * * * *",
+	"notify_overdue_todos":   "*/5 * * * *",
+	// Every quarter hour so that digests go out on time in timezones
+	// offset by a half or a quarter of an hour
+	"send_digests": "*/15 * * * *",
 }
 
 var configInstance *Config
@@ -53,6 +172,225 @@ func GetConfig() (*Config, error) {
 			return nil, fmt.Errorf("missing required environment variable MAX_OPEN_CONNS: %w", err)
 		}
 
+		loginMaxFailures := 5 // Default failed logins before an account is locked
+		if val, err := getInt("LOGIN_MAX_FAILURES", &loginMaxFailures); err == nil {
+			instance.LoginMaxFailures = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable LOGIN_MAX_FAILURES: %w", err)
+		}
+
+		loginMaxIPFailures := 20 // Default failed logins before an IP address is locked
+		if val, err := getInt("LOGIN_MAX_IP_FAILURES", &loginMaxIPFailures); err == nil {
+			instance.LoginMaxIPFailures = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable LOGIN_MAX_IP_FAILURES: %w", err)
+		}
+
+		loginLockoutMinutes := 15 // Default lockout duration in minutes
+		if val, err := getInt("LOGIN_LOCKOUT_MINUTES", &loginLockoutMinutes); err == nil {
+			instance.LoginLockoutDuration = time.Duration(val) * time.Minute
+		} else {
+			return nil, fmt.Errorf("invalid environment variable LOGIN_LOCKOUT_MINUTES: %w", err)
+		}
+
+		for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+			if proxy = strings.TrimSpace(proxy); proxy == "" {
+				continue
+			}
+			prefix, err := parsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid environment variable TRUSTED_PROXIES: %w", err)
+			}
+			instance.TrustedProxies = append(instance.TrustedProxies, prefix)
+		}
+
+		appBaseURL := "http://localhost:5173" // Default value for the frontend URL
+		if val, err := getStr("APP_BASE_URL", &appBaseURL); err == nil {
+			instance.AppBaseURL = strings.TrimRight(val, "/")
+		}
+
+		mailer := "log" // Default mailer only logs outgoing mail
+		if val, err := getStr("MAILER", &mailer); err == nil {
+			instance.Mailer = val
+		}
+
+		mailFrom := "Todo App <no-reply@localhost>"
+		if val, err := getStr("MAIL_FROM", &mailFrom); err == nil {
+			instance.MailFrom = val
+		}
+
+		mailDir := "mail"
+		if val, err := getStr("MAIL_DIR", &mailDir); err == nil {
+			instance.MailDir = val
+		}
+
+		smtpHost := "localhost"
+		if val, err := getStr("SMTP_HOST", &smtpHost); err == nil {
+			instance.SMTPHost = val
+		}
+
+		smtpPort := 25
+		if val, err := getInt("SMTP_PORT", &smtpPort); err == nil {
+			instance.SMTPPort = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable SMTP_PORT: %w", err)
+		}
+
+		instance.SMTPUsername = os.Getenv("SMTP_USERNAME")
+		instance.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+
+		eventBus := "memory" // Default bus only reaches the clients of this instance
+		if val, err := getStr("EVENT_BUS", &eventBus); err == nil {
+			instance.EventBus = val
+		}
+
+		webhookAllowPrivateNetworks := false
+		if val, err := getBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", &webhookAllowPrivateNetworks); err == nil {
+			instance.WebhookAllowPrivateNetworks = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable WEBHOOK_ALLOW_PRIVATE_NETWORKS: %w", err)
+		}
+
+		jobConcurrency := 4 // Default jobs run at once on each instance
+		if val, err := getInt("JOB_CONCURRENCY", &jobConcurrency); err == nil {
+			instance.JobConcurrency = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable JOB_CONCURRENCY: %w", err)
+		}
+
+		jobDrainSeconds := 30 // Default seconds shutdowns wait for running jobs
+		if val, err := getInt("JOB_DRAIN_SECONDS", &jobDrainSeconds); err == nil {
+			instance.JobDrainTimeout = time.Duration(val) * time.Second
+		} else {
+			return nil, fmt.Errorf("invalid environment variable JOB_DRAIN_SECONDS: %w", err)
+		}
+
+		instance.Schedules = make(map[string]string)
+		for name, spec := range defaultSchedules {
+			key := "SCHEDULE_" + strings.ToUpper(name)
+			spec, _ = getStr(key, &spec)
+			if spec == "off" {
+				continue
+			}
+			if _, err := cron.Parse(spec); err != nil {
+				return nil, fmt.Errorf("invalid environment variable %s: %w", key, err)
+			}
+			instance.Schedules[name] = spec
+		}
+
+		scheduleCatchUp := "once" // Default makes up for missed runs with a single run
+		if val, err := getStr("SCHEDULE_CATCH_UP", &scheduleCatchUp); err == nil {
+			instance.ScheduleCatchUp = val
+		}
+		switch instance.ScheduleCatchUp {
+		case "skip", "once", "all":
+		default:
+			return nil, fmt.Errorf("invalid environment variable SCHEDULE_CATCH_UP: %q is not one of skip, once or all", instance.ScheduleCatchUp)
+		}
+
+		instance.VAPIDPrivateKey = os.Getenv("VAPID_PRIVATE_KEY")
+		vapidSubject := "mailto:no-reply@localhost"
+		if val, err := getStr("VAPID_SUBJECT", &vapidSubject); err == nil {
+			instance.VAPIDSubject = val
+		}
+
+		digestHour := 7 // Default sends digests in the morning
+		if val, err := getInt("DIGEST_HOUR", &digestHour); err == nil {
+			instance.DigestHour = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable DIGEST_HOUR: %w", err)
+		}
+		if instance.DigestHour < 0 || instance.DigestHour > 23 {
+			return nil, fmt.Errorf("invalid environment variable DIGEST_HOUR: %d is not an hour between 0 and 23", instance.DigestHour)
+		}
+
+		requireEmailVerification := false
+		if val, err := getBool("REQUIRE_EMAIL_VERIFICATION", &requireEmailVerification); err == nil {
+			instance.RequireEmailVerification = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable REQUIRE_EMAIL_VERIFICATION: %w", err)
+		}
+
+		registrationMode := "open"
+		if val, err := getStr("REGISTRATION_MODE", &registrationMode); err == nil {
+			instance.RegistrationMode = val
+		}
+		switch instance.RegistrationMode {
+		case "open", "invite_only", "domain_allowlist", "approval":
+		default:
+			return nil, fmt.Errorf("invalid environment variable REGISTRATION_MODE: %q is not one of open, invite_only, domain_allowlist or approval", instance.RegistrationMode)
+		}
+		for _, domain := range strings.Split(os.Getenv("REGISTRATION_ALLOWED_DOMAINS"), ",") {
+			if domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@")); domain != "" {
+				instance.RegistrationAllowedDomains = append(instance.RegistrationAllowedDomains, domain)
+			}
+		}
+		if instance.RegistrationMode == "domain_allowlist" && len(instance.RegistrationAllowedDomains) == 0 {
+			return nil, fmt.Errorf("missing required environment variable REGISTRATION_ALLOWED_DOMAINS: required by REGISTRATION_MODE domain_allowlist")
+		}
+
+		deletionGraceDays := 14 // Default days before a self-deleted account is removed
+		if val, err := getInt("ACCOUNT_DELETION_GRACE_DAYS", &deletionGraceDays); err == nil {
+			instance.AccountDeletionGracePeriod = time.Duration(val) * 24 * time.Hour
+		} else {
+			return nil, fmt.Errorf("invalid environment variable ACCOUNT_DELETION_GRACE_DAYS: %w", err)
+		}
+
+		passwordHashMemoryKiB := 64 * 1024 // Default Argon2id memory cost of 64 MiB
+		if val, err := getInt("PASSWORD_HASH_MEMORY_KIB", &passwordHashMemoryKiB); err == nil {
+			instance.PasswordHashMemoryKiB = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable PASSWORD_HASH_MEMORY_KIB: %w", err)
+		}
+
+		passwordHashTime := 3 // Default Argon2id passes over the memory
+		if val, err := getInt("PASSWORD_HASH_TIME", &passwordHashTime); err == nil {
+			instance.PasswordHashTime = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable PASSWORD_HASH_TIME: %w", err)
+		}
+
+		passwordHashParallelism := 2
+		if val, err := getInt("PASSWORD_HASH_PARALLELISM", &passwordHashParallelism); err == nil {
+			instance.PasswordHashParallelism = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable PASSWORD_HASH_PARALLELISM: %w", err)
+		}
+
+		passwordMinLength := 8
+		if val, err := getInt("PASSWORD_MIN_LENGTH", &passwordMinLength); err == nil {
+			instance.PasswordMinLength = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable PASSWORD_MIN_LENGTH: %w", err)
+		}
+
+		passwordMinStrength := 2 // Default rejects passwords guessable in under 10^6 attempts
+		if val, err := getInt("PASSWORD_MIN_STRENGTH", &passwordMinStrength); err == nil {
+			instance.PasswordMinStrength = val
+		} else {
+			return nil, fmt.Errorf("invalid environment variable PASSWORD_MIN_STRENGTH: %w", err)
+		}
+
+		instance.PasswordBreachedFilter = os.Getenv("PASSWORD_BREACHED_FILTER")
+
+		totpIssuer := "Todo App"
+		if val, err := getStr("TOTP_ISSUER", &totpIssuer); err == nil {
+			instance.TOTPIssuer = val
+		}
+
+		apiBaseURL := "http://localhost:8080"
+		if val, err := getStr("API_BASE_URL", &apiBaseURL); err == nil {
+			instance.APIBaseURL = strings.TrimRight(val, "/")
+		}
+
+		oidcProviders, err := getOIDCProviders()
+		if err != nil {
+			return nil, err
+		}
+		instance.OIDCProviders = oidcProviders
+
+		instance.SCIMToken = os.Getenv("SCIM_TOKEN")
+
 		configInstance = instance
 	}
 	return configInstance, nil
@@ -66,6 +404,46 @@ func LoadConfigurationFile(filePath string) error {
 	return nil
 }
 
+// getOIDCProviders reads the providers named in OIDC_PROVIDERS, a comma
+// separated list. Each provider NAME is configured with OIDC_NAME_ISSUER,
+// OIDC_NAME_CLIENT_ID and optionally OIDC_NAME_CLIENT_SECRET,
+// OIDC_NAME_DISPLAY_NAME and OIDC_NAME_SCOPES.
+func getOIDCProviders() ([]OIDCProvider, error) {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if strings.Trim(name, "abcdefghijklmnopqrstuvwxyz0123456789-") != "" {
+			return nil, fmt.Errorf("invalid OIDC provider name %q: use letters, digits and dashes", name)
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		issuer, err := getStr(prefix+"ISSUER", nil)
+		if err != nil {
+			return nil, err
+		}
+		clientID, err := getStr(prefix+"CLIENT_ID", nil)
+		if err != nil {
+			return nil, err
+		}
+		displayName, _ := getStr(prefix+"DISPLAY_NAME", &name)
+		scopes := "email profile"
+		scopes, _ = getStr(prefix+"SCOPES", &scopes)
+
+		providers = append(providers, OIDCProvider{
+			Name:         name,
+			DisplayName:  displayName,
+			Issuer:       issuer,
+			ClientID:     clientID,
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.FieldsFunc(scopes, func(r rune) bool { return r == ' ' || r == ',' }),
+		})
+	}
+	return providers, nil
+}
+
 // getStr retrieves an environment variable by key; returns fallback if not found.
 // retuns error if missing environment variable and fallback is nil.
 func getStr(key string, fallback *string) (string, error) {
@@ -94,3 +472,32 @@ func getInt(key string, fallback *int) (int, error) {
 
 	return strconv.Atoi(value)
 }
+
+// getBool retrieves an environment variable by key; returns fallback if not found.
+// retuns error if missing environment variable and fallback is nil.
+func getBool(key string, fallback *bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		if fallback == nil {
+			return false, fmt.Errorf("missing required environment variable: %s", key)
+		} else {
+			return *fallback, nil
+		}
+	}
+
+	return strconv.ParseBool(value)
+}
+
+// parsePrefix parses a CIDR range, or a single IP address as the range of
+// that address alone.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"net/netip"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -92,4 +94,204 @@ func TestConfig_GetConfigWithDefault(t *testing.T) {
 	assert.NotEmpty(t, config.DatabaseURI)     // Should not be empty as DB_URI is set
 	assert.Equal(t, 0, config.MaxIdleConns)    // Default should be 0 since it was unset
 	assert.Equal(t, 0, config.MaxOpenConns)    // Default should be 0 since it was unset
+	assert.Equal(t, "memory", config.EventBus) // Default bus of a single instance
+
+	// Webhooks only reach public addresses by default
+	assert.False(t, config.WebhookAllowPrivateNetworks)
+
+	assert.Equal(t, 4, config.JobConcurrency)
+	assert.Equal(t, 30*time.Second, config.JobDrainTimeout)
+
+	assert.Equal(t, map[string]string{
+		"purge_deleted_accounts": "@hourly",
+		"send_reminders":         "* * * * *",
+		"notify_overdue_todos":   "*/5 * * * *",
+		"send_digests":           "*/15 * * * *",
+	}, config.Schedules)
+	assert.Equal(t, "once", config.ScheduleCatchUp)
+
+	// Web Push is off until VAPID keys are generated
+	assert.Empty(t, config.VAPIDPrivateKey)
+	assert.Equal(t, "mailto:no-reply@localhost", config.VAPIDSubject)
+
+	assert.Equal(t, 7, config.DigestHour)
+}
+
+// TestConfig_GetConfigWithSchedules tests the periodic task settings
+func TestConfig_GetConfigWithSchedules(t *testing.T) {
+	setup(t)
+	t.Setenv("SCHEDULE_PURGE_DELETED_ACCOUNTS", "off")
+	t.Setenv("SCHEDULE_SEND_REMINDERS", "off")
+	t.Setenv("SCHEDULE_NOTIFY_OVERDUE_TODOS", "off")
+	t.Setenv("SCHEDULE_SEND_DIGESTS", "off")
+	t.Setenv("SCHEDULE_CATCH_UP", "all")
+
+	configInstance = nil
+	config, err := GetConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, config.Schedules)
+	assert.Equal(t, "all", config.ScheduleCatchUp)
+
+	t.Setenv("SCHEDULE_PURGE_DELETED_ACCOUNTS", "0 25 * * *")
+	configInstance = nil
+	_, err = GetConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SCHEDULE_PURGE_DELETED_ACCOUNTS")
+
+	t.Setenv("SCHEDULE_PURGE_DELETED_ACCOUNTS", "*/30 * * * *")
+	t.Setenv("SCHEDULE_CATCH_UP", "sometimes")
+	configInstance = nil
+	_, err = GetConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SCHEDULE_CATCH_UP")
+}
+
+// TestConfig_GetConfigWithOIDCProviders tests loading identity providers
+func TestConfig_GetConfigWithOIDCProviders(t *testing.T) {
+	setup(t)
+	t.Setenv("OIDC_PROVIDERS", "corp, google-workspace")
+	t.Setenv("OIDC_CORP_ISSUER", "https://login.example.com")
+	t.Setenv("OIDC_CORP_CLIENT_ID", "todo")
+	t.Setenv("OIDC_CORP_CLIENT_SECRET", "s3cret")
+	t.Setenv("OIDC_CORP_DISPLAY_NAME", "Example Corp")
+	t.Setenv("OIDC_GOOGLE_WORKSPACE_ISSUER", "https://accounts.google.com")
+	t.Setenv("OIDC_GOOGLE_WORKSPACE_CLIENT_ID", "todo.apps.googleusercontent.com")
+	t.Setenv("OIDC_GOOGLE_WORKSPACE_SCOPES", "email,profile,groups")
+
+	configInstance = nil
+	config, err := GetConfig()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []OIDCProvider{
+		{Name: "corp", DisplayName: "Example Corp", Issuer: "https://login.example.com", ClientID: "todo", ClientSecret: "s3cret", Scopes: []string{"email", "profile"}},
+		{Name: "google-workspace", DisplayName: "google-workspace", Issuer: "https://accounts.google.com", ClientID: "todo.apps.googleusercontent.com", Scopes: []string{"email", "profile", "groups"}},
+	}, config.OIDCProviders)
+}
+
+// TestConfig_GetConfigWithIncompleteOIDCProvider tests that providers must have an issuer
+func TestConfig_GetConfigWithIncompleteOIDCProvider(t *testing.T) {
+	setup(t)
+	t.Setenv("OIDC_PROVIDERS", "corp")
+	t.Setenv("OIDC_CORP_CLIENT_ID", "todo")
+
+	configInstance = nil
+	_, err := GetConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "missing required environment variable: OIDC_CORP_ISSUER")
+}
+
+// TestConfig_GetConfigWithSCIMToken tests that SCIM is disabled unless a token is set
+func TestConfig_GetConfigWithSCIMToken(t *testing.T) {
+	setup(t)
+
+	configInstance = nil
+	config, err := GetConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, config.SCIMToken)
+
+	t.Setenv("SCIM_TOKEN", "scim-secret")
+	configInstance = nil
+	config, err = GetConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "scim-secret", config.SCIMToken)
+}
+
+// TestConfig_GetConfigWithPasswordHashCosts tests the Argon2id cost settings
+func TestConfig_GetConfigWithPasswordHashCosts(t *testing.T) {
+	setup(t)
+
+	configInstance = nil
+	config, err := GetConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 64*1024, config.PasswordHashMemoryKiB)
+	assert.Equal(t, 3, config.PasswordHashTime)
+	assert.Equal(t, 2, config.PasswordHashParallelism)
+
+	t.Setenv("PASSWORD_HASH_TIME", "many")
+	configInstance = nil
+	_, err = GetConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "PASSWORD_HASH_TIME")
+}
+
+// TestConfig_GetConfigWithPasswordPolicy tests the password policy settings
+func TestConfig_GetConfigWithPasswordPolicy(t *testing.T) {
+	setup(t)
+
+	configInstance = nil
+	config, err := GetConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 8, config.PasswordMinLength)
+	assert.Equal(t, 2, config.PasswordMinStrength)
+	assert.Empty(t, config.PasswordBreachedFilter)
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("PASSWORD_BREACHED_FILTER", "/var/lib/todo/breached.bloom")
+	configInstance = nil
+	config, err = GetConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 12, config.PasswordMinLength)
+	assert.Equal(t, "/var/lib/todo/breached.bloom", config.PasswordBreachedFilter)
+
+	t.Setenv("PASSWORD_MIN_STRENGTH", "strong")
+	configInstance = nil
+	_, err = GetConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "PASSWORD_MIN_STRENGTH")
+}
+
+// TestConfig_GetConfigWithTrustedProxies tests the parsing of the reverse
+// proxies trusted with client addresses
+func TestConfig_GetConfigWithTrustedProxies(t *testing.T) {
+	setup(t)
+
+	configInstance = nil
+	config, err := GetConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, config.TrustedProxies)
+
+	t.Setenv("TRUSTED_PROXIES", "10.1.2.3/8, 192.168.0.1,::1")
+	configInstance = nil
+	config, err = GetConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.0.1/32"),
+		netip.MustParsePrefix("::1/128"),
+	}, config.TrustedProxies)
+
+	t.Setenv("TRUSTED_PROXIES", "proxy.internal")
+	configInstance = nil
+	_, err = GetConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "TRUSTED_PROXIES")
+}
+
+// TestConfig_GetConfigWithRegistrationMode tests the signup restrictions
+func TestConfig_GetConfigWithRegistrationMode(t *testing.T) {
+	setup(t)
+
+	configInstance = nil
+	config, err := GetConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "open", config.RegistrationMode)
+	assert.Empty(t, config.RegistrationAllowedDomains)
+
+	t.Setenv("REGISTRATION_MODE", "domain_allowlist")
+	configInstance = nil
+	_, err = GetConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "REGISTRATION_ALLOWED_DOMAINS")
+
+	t.Setenv("REGISTRATION_ALLOWED_DOMAINS", "example.com, @Example.org")
+	configInstance = nil
+	config, err = GetConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"example.com", "example.org"}, config.RegistrationAllowedDomains)
+
+	t.Setenv("REGISTRATION_MODE", "closed")
+	configInstance = nil
+	_, err = GetConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "REGISTRATION_MODE")
 }
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS login_lockouts;
\ No newline at end of file
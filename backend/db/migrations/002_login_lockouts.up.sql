@@ -0,0 +1,14 @@
+CREATE TABLE login_lockouts (
+    id SERIAL PRIMARY KEY,
+    reason VARCHAR(20) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    ip VARCHAR(64) NOT NULL,
+    failures INT NOT NULL,
+    locked_until TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    unlocked_at TIMESTAMP,
+    unlocked_by INT
+);
+
+CREATE INDEX idx_login_lockouts_email ON login_lockouts(email);
+CREATE INDEX idx_login_lockouts_ip ON login_lockouts(ip);
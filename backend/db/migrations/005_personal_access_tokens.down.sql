@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS personal_access_tokens;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,54 @@
+-- Third-party applications that act on behalf of users through OAuth2.
+-- Public clients, such as single page and mobile apps, have no secret and
+-- must use PKCE; confidential clients also authenticate with a secret of
+-- which only a hash is stored.
+CREATE TABLE oauth_clients (
+    id SERIAL PRIMARY KEY,
+    client_id VARCHAR(64) UNIQUE NOT NULL,
+    secret_hash VARCHAR(64),
+    owner_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    redirect_uris TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_oauth_clients_owner_id ON oauth_clients(owner_id);
+
+-- Scopes a user has consented to give a client. Deleting a grant revokes
+-- every code and token issued under it.
+CREATE TABLE oauth_grants (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id INT NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, client_id)
+);
+
+-- Authorization codes waiting to be redeemed at the token endpoint.
+CREATE TABLE oauth_authorization_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    grant_id INT NOT NULL REFERENCES oauth_grants(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Access and refresh token pairs. Refreshing replaces the row, so a refresh
+-- token can be used only once.
+CREATE TABLE oauth_tokens (
+    id SERIAL PRIMARY KEY,
+    grant_id INT NOT NULL REFERENCES oauth_grants(id) ON DELETE CASCADE,
+    access_token_hash VARCHAR(64) UNIQUE NOT NULL,
+    refresh_token_hash VARCHAR(64) UNIQUE NOT NULL,
+    scopes TEXT[] NOT NULL,
+    access_expires_at TIMESTAMP NOT NULL,
+    refresh_expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_oauth_tokens_grant_id ON oauth_tokens(grant_id);
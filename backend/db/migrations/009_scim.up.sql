@@ -0,0 +1,5 @@
+-- Users provisioned from a directory over SCIM. external_id is the
+-- identifier of the user in the directory. Deprovisioned users are
+-- deactivated rather than deleted, so that their data can be restored.
+ALTER TABLE users ADD COLUMN external_id VARCHAR(255) UNIQUE;
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMP;
@@ -0,0 +1,3 @@
+-- Describe sessions so that users can recognise and revoke them
+ALTER TABLE sessions ADD COLUMN device VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN last_seen_at TIMESTAMP NOT NULL DEFAULT NOW();
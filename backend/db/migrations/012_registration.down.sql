@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS invite_codes;
+DROP INDEX IF EXISTS idx_users_approval_pending;
+ALTER TABLE users DROP COLUMN IF EXISTS approval_pending;
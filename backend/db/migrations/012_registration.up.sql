@@ -0,0 +1,19 @@
+-- Accounts created in approval mode cannot sign in until an admin
+-- approves them.
+ALTER TABLE users ADD COLUMN approval_pending BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX idx_users_approval_pending ON users(created_at) WHERE approval_pending;
+
+-- Single-use invite codes handed out by admins in invite-only mode. Only a
+-- hash of each code is stored; prefix holds its first characters for
+-- identification.
+CREATE TABLE invite_codes (
+    id SERIAL PRIMARY KEY,
+    prefix VARCHAR(32) NOT NULL,
+    code_hash VARCHAR(64) UNIQUE NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    used_at TIMESTAMP,
+    used_by INT REFERENCES users(id) ON DELETE SET NULL
+);
@@ -0,0 +1,10 @@
+-- Events too large for a NOTIFY payload. The notification carries the ID
+-- of the row instead. Rows are removed by later publishers once every
+-- instance has had time to read them.
+CREATE TABLE event_bus_payloads (
+    id BIGSERIAL PRIMARY KEY,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_event_bus_payloads_created_at ON event_bus_payloads(created_at);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS inbound_webhooks;
+
+DROP INDEX IF EXISTS idx_todos_due_date;
+DROP INDEX IF EXISTS idx_todos_external_key;
+ALTER TABLE todos DROP COLUMN IF EXISTS external_key;
+ALTER TABLE todos DROP COLUMN IF EXISTS due_date;
+ALTER TABLE todos DROP COLUMN IF EXISTS tags;
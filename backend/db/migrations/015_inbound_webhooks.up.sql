@@ -0,0 +1,25 @@
+-- Tags and due dates of todos. external_key identifies the todos created
+-- from inbound webhook payloads so that repeated payloads update them.
+ALTER TABLE todos ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE todos ADD COLUMN due_date TIMESTAMPTZ;
+ALTER TABLE todos ADD COLUMN external_key TEXT;
+
+CREATE UNIQUE INDEX idx_todos_external_key ON todos(user_id, external_key) WHERE external_key IS NOT NULL;
+CREATE INDEX idx_todos_due_date ON todos(due_date) WHERE due_date IS NOT NULL;
+
+-- Inbound webhooks creating todos from external systems. The secret, if
+-- any, verifies payload signatures, so it is kept in the clear.
+CREATE TABLE inbound_webhooks (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(32) NOT NULL,
+    token_hash VARCHAR(255) NOT NULL UNIQUE,
+    secret VARCHAR(255) NOT NULL DEFAULT '',
+    mapping JSONB NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_inbound_webhooks_user_id ON inbound_webhooks(user_id);
@@ -0,0 +1,9 @@
+-- Runs of the periodic tasks of the scheduler, kept so that a newly elected
+-- leader knows which runs were missed.
+CREATE TABLE schedules (
+    name VARCHAR(64) PRIMARY KEY,
+    spec VARCHAR(255) NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    next_run_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,42 @@
+-- Reminders of todos, going off at remind_at or before_minutes before the
+-- due date of their todo, unless snoozed until later. fired_at is the time
+-- a reminder last went off for, so that it goes off again when it is
+-- snoozed or the due date moves.
+CREATE TABLE reminders (
+    id SERIAL PRIMARY KEY,
+    todo_id INT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    remind_at TIMESTAMPTZ,
+    before_minutes INT,
+    snoozed_until TIMESTAMPTZ,
+    fired_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((remind_at IS NULL) <> (before_minutes IS NULL))
+);
+
+CREATE INDEX idx_reminders_todo_id ON reminders(todo_id);
+
+-- Channels and quiet hours of the reminders of each user. Users without a
+-- row get the defaults.
+CREATE TABLE notification_preferences (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    channels TEXT[] NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    quiet_hours_start VARCHAR(5) NOT NULL DEFAULT '',
+    quiet_hours_end VARCHAR(5) NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Browsers subscribed to Web Push messages. An endpoint belongs to a
+-- single browser, and so to a single user.
+CREATE TABLE push_subscriptions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    endpoint TEXT NOT NULL UNIQUE,
+    p256dh VARCHAR(255) NOT NULL,
+    auth VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_push_subscriptions_user_id ON push_subscriptions(user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE todos DROP COLUMN IF EXISTS overdue_notified_at;
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,19 @@
+-- In-app notifications of users, such as reminders going off or todos
+-- becoming overdue. Unread notifications have no read_at.
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(64) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    data JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, id DESC);
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- The due date a todo was last reported overdue for, so that it is
+-- reported again when the due date moves.
+ALTER TABLE todos ADD COLUMN overdue_notified_at TIMESTAMPTZ;
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS todo_completed_at ON todos;
+DROP FUNCTION IF EXISTS update_todo_completed_at();
+ALTER TABLE todos DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE notification_preferences
+    DROP COLUMN IF EXISTS digest_sent_on,
+    DROP COLUMN IF EXISTS digest_frequency;
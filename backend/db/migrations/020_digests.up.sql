@@ -0,0 +1,29 @@
+-- How often each user gets the email digest of their todos, and the day in
+-- their timezone it was last sent for, so that it is sent once.
+ALTER TABLE notification_preferences
+    ADD COLUMN digest_frequency VARCHAR(16) NOT NULL DEFAULT 'off',
+    ADD COLUMN digest_sent_on DATE;
+
+-- When a todo was completed, so that digests list the todos completed the
+-- day before. It is cleared when a todo is reopened.
+ALTER TABLE todos ADD COLUMN completed_at TIMESTAMPTZ;
+UPDATE todos SET completed_at = updated_at WHERE status = 'completed';
+
+CREATE OR REPLACE FUNCTION update_todo_completed_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.status = 'completed' AND (TG_OP = 'INSERT' OR OLD.status <> 'completed') THEN
+        NEW.completed_at = NOW();
+    ELSIF NEW.status <> 'completed' THEN
+        NEW.completed_at = NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER todo_completed_at
+BEFORE INSERT OR UPDATE ON todos
+FOR EACH ROW
+EXECUTE FUNCTION update_todo_completed_at();
+
+CREATE INDEX idx_todos_user_id_completed_at ON todos(user_id, completed_at) WHERE completed_at IS NOT NULL;
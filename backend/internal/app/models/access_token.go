@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// AccessTokenPrefix starts every personal access token so that it can be
+// told apart from session tokens.
+const AccessTokenPrefix = "todo_pat_"
+
+const (
+	ScopeTodosRead  = "todos:read"
+	ScopeTodosWrite = "todos:write"
+	ScopeAdmin      = "admin"
+)
+
+// Scopes lists every scope a personal access token can be granted.
+var Scopes = []string{ScopeTodosRead, ScopeTodosWrite, ScopeAdmin}
+
+// PersonalAccessToken is a long-lived credential limited to a set of scopes.
+// Only the hash of the token is stored.
+type PersonalAccessToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the token was granted scope.
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Audited actions.
+const (
+	AuditLogin          = "auth.login"
+	AuditLoginFailed    = "auth.login_failed"
+	AuditTokenIssued    = "token.issued"
+	AuditUserRoleChange = "user.role_changed"
+	AuditUserDeleted    = "user.deleted"
+	AuditUserApproved   = "user.approved"
+	AuditUserRejected   = "user.rejected"
+	AuditInviteCreated  = "invite.created"
+	AuditWebhookCreated = "webhook.created"
+	AuditWebhookDeleted = "webhook.deleted"
+	AuditLogExported    = "audit.exported"
+
+	AuditInboundWebhookCreated = "inbound_webhook.created"
+	AuditInboundWebhookDeleted = "inbound_webhook.deleted"
+	AuditJobRetried            = "job.retried"
+)
+
+// Targets of audited actions.
+const (
+	AuditTargetUser       = "user"
+	AuditTargetToken      = "access_token"
+	AuditTargetOAuthGrant = "oauth_grant"
+	AuditTargetInvite     = "invite_code"
+	AuditTargetWebhook    = "webhook"
+
+	AuditTargetInboundWebhook = "inbound_webhook"
+	AuditTargetJob            = "job"
+)
+
+// AuditEntry records a security or admin action. Entries are chained:
+// Hash covers the entry and the Hash of the previous entry, PrevHash.
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Action    string    `json:"action"`
+	// ActorID is the user who acted, if known.
+	ActorID    *int   `json:"actor_id,omitempty"`
+	TargetType string `json:"target_type,omitempty"`
+	TargetID   string `json:"target_id,omitempty"`
+	IP         string `json:"ip,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	// Metadata describes the action, e.g. the state of the target before
+	// and after it.
+	Metadata json.RawMessage `json:"metadata"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+}
+
+// ComputeHash returns the hex SHA-256 hash of the entry, including
+// PrevHash but not ID and Hash.
+func (e *AuditEntry) ComputeHash() string {
+	payload, _ := json.Marshal(struct {
+		PrevHash   string          `json:"prev_hash"`
+		CreatedAt  string          `json:"created_at"`
+		Action     string          `json:"action"`
+		ActorID    *int            `json:"actor_id"`
+		TargetType string          `json:"target_type"`
+		TargetID   string          `json:"target_id"`
+		IP         string          `json:"ip"`
+		RequestID  string          `json:"request_id"`
+		Metadata   json.RawMessage `json:"metadata"`
+	}{e.PrevHash, e.CreatedAt.UTC().Format(time.RFC3339Nano), e.Action, e.ActorID, e.TargetType, e.TargetID, e.IP, e.RequestID, e.Metadata})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditFilter selects audit log entries. Zero fields match every entry.
+type AuditFilter struct {
+	Action     string
+	ActorID    int
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+	// BeforeID selects entries older than the entry with this ID, for
+	// paging through listings.
+	BeforeID int64
+}
+
+// AuditVerification is the result of checking the hash chain.
+type AuditVerification struct {
+	Valid   bool `json:"valid"`
+	Entries int  `json:"entries"`
+	// BrokenAt is the ID of the first entry that does not match its hash
+	// or the previous entry.
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+}
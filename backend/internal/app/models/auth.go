@@ -2,7 +2,13 @@ package models
 
 import "time"
 
+// TokenPurposeMFA marks the short-lived token handed out after the password
+// step of a login that still needs a second factor.
+const TokenPurposeMFA = "mfa"
+
 type Token struct {
-	UserID int
-	Exp    time.Time
+	UserID    int
+	SessionID int    `json:",omitempty"`
+	Purpose   string `json:",omitempty"`
+	Exp       time.Time
 }
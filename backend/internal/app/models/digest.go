@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Frequencies of the email digest of todos.
+const (
+	DigestOff   = "off"
+	DigestDaily = "daily"
+	// DigestWeekly digests are sent on Mondays and cover the week ahead.
+	DigestWeekly = "weekly"
+)
+
+// DigestFrequencies lists every digest frequency.
+var DigestFrequencies = []string{DigestOff, DigestDaily, DigestWeekly}
+
+// DigestRecipient is a user whose digest is due for the day Date, a
+// "2006-01-02" date in their timezone.
+type DigestRecipient struct {
+	UserID    int    `json:"user_id"`
+	Frequency string `json:"frequency"`
+	Timezone  string `json:"timezone"`
+	Date      string `json:"date"`
+}
+
+// DigestTodo is a todo listed in a digest.
+type DigestTodo struct {
+	ID          int        `json:"id"`
+	Title       string     `json:"title"`
+	DueDate     *time.Time `json:"due_date"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// Digest is the summary of the todos of a user for a period: the todos due
+// during the period, those overdue at its start, and those completed during
+// the period before.
+type Digest struct {
+	Due       []DigestTodo
+	Overdue   []DigestTodo
+	Completed []DigestTodo
+}
+
+// Empty reports whether the digest lists no todo.
+func (d *Digest) Empty() bool {
+	return len(d.Due) == 0 && len(d.Overdue) == 0 && len(d.Completed) == 0
+}
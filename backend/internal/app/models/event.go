@@ -0,0 +1,30 @@
+package models
+
+import "encoding/json"
+
+// Types of events pushed to the live clients of a user.
+const (
+	EventTodoCreated = "todo.created"
+	EventTodoUpdated = "todo.updated"
+	EventTodoDeleted = "todo.deleted"
+	// EventTodoReminder is a reminder of a todo going off.
+	EventTodoReminder = "todo.reminder"
+	// EventTodoOverdue is a todo passing its due date undone.
+	EventTodoOverdue = "todo.overdue"
+	// EventNotificationCreated is a new notification in the inbox, and
+	// EventNotificationsRead notifications being marked read.
+	EventNotificationCreated = "notification.created"
+	EventNotificationsRead   = "notifications.read"
+	// EventResync tells a client that events it missed can no longer be
+	// replayed, so it must reload its data.
+	EventResync = "resync"
+)
+
+// Event is a change to the data of a user. Events are numbered in the order
+// they are published.
+type Event struct {
+	ID     int64           `json:"id"`
+	Type   string          `json:"type"`
+	UserID int             `json:"-"`
+	Data   json.RawMessage `json:"data"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// InboundWebhookTokenPrefix starts every inbound webhook path token.
+const InboundWebhookTokenPrefix = "whin_"
+
+// InboundMapping turns the JSON payloads received by an inbound webhook into
+// todos. Each field is a text/template executed with the decoded payload,
+// so that {{.alert.name}} reads the name of the alert object.
+//
+// Tags render to a comma separated list and DueDate to an RFC 3339 time or
+// a YYYY-MM-DD date. When DedupKey renders to a non-empty key, payloads with
+// the same key update the todo created by the first one.
+type InboundMapping struct {
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Tags     string `json:"tags"`
+	DueDate  string `json:"due_date"`
+	DedupKey string `json:"dedup_key"`
+}
+
+// InboundWebhook lets external systems create todos for a user by posting to
+// a URL holding a secret token. Only the hash of the token is stored. When
+// Secret is set, payloads must also be signed with it.
+type InboundWebhook struct {
+	ID                int            `json:"id"`
+	UserID            int            `json:"-"`
+	Name              string         `json:"name"`
+	Prefix            string         `json:"prefix"`
+	TokenHash         string         `json:"-"`
+	Secret            string         `json:"-"`
+	SignatureRequired bool           `json:"signature_required"`
+	Mapping           InboundMapping `json:"mapping"`
+	LastUsedAt        *time.Time     `json:"last_used_at"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Registration modes decide who may sign up.
+const (
+	// RegistrationOpen lets anyone sign up.
+	RegistrationOpen = "open"
+	// RegistrationInviteOnly requires an invite code handed out by an admin.
+	RegistrationInviteOnly = "invite_only"
+	// RegistrationDomainAllowlist only accepts email addresses of allowed domains.
+	RegistrationDomainAllowlist = "domain_allowlist"
+	// RegistrationApproval lets anyone sign up, but new accounts cannot sign
+	// in until an admin approves them.
+	RegistrationApproval = "approval"
+)
+
+// InviteCodePrefix starts every invite code.
+const InviteCodePrefix = "todo_inv_"
+
+// InviteCode lets one person sign up in invite-only mode. Only the hash of
+// the code is stored.
+type InviteCode struct {
+	ID        int        `json:"id"`
+	Prefix    string     `json:"prefix"`
+	CodeHash  string     `json:"-"`
+	Note      string     `json:"note"`
+	CreatedBy *int       `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	UsedBy    *int       `json:"used_by"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Statuses of background jobs.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	// JobDead jobs have used up their attempts and wait for an admin to
+	// retry them.
+	JobDead = "dead"
+)
+
+// Types of background jobs.
+const (
+	JobPurgeDeletedAccounts = "accounts.purge_deleted"
+	JobSendReminders        = "reminders.send"
+	JobDeliverReminder      = "reminders.deliver"
+	JobNotifyOverdueTodos   = "todos.notify_overdue"
+	JobSendDigests          = "digests.send"
+	JobDeliverDigest        = "digests.deliver"
+)
+
+// JobStatuses lists every status of a job.
+var JobStatuses = []string{JobPending, JobRunning, JobSucceeded, JobDead}
+
+// Job is a unit of background work, run by the handler registered for its
+// type once RunAt is reached. Failed jobs are retried until they have been
+// attempted MaxAttempts times. Only one pending or running job of a type
+// can have a given UniqueKey.
+type Job struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	UniqueKey   *string         `json:"unique_key"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LockedAt    *time.Time      `json:"locked_at"`
+	LockedBy    string          `json:"locked_by"`
+	LastError   string          `json:"last_error"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+}
+
+// JobFilter selects the jobs listed to admins.
+type JobFilter struct {
+	Status string
+	Type   string
+	// BeforeID selects jobs older than the job with this ID, for paging
+	// through listings.
+	BeforeID int64
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	LockoutReasonAccount = "account"
+	LockoutReasonIP      = "ip"
+)
+
+// Lockout records a temporary block on logins for an account or an IP address.
+type Lockout struct {
+	ID          int        `json:"id"`
+	Reason      string     `json:"reason"`
+	Email       string     `json:"email"`
+	IP          string     `json:"ip"`
+	Failures    int        `json:"failures"`
+	LockedUntil time.Time  `json:"locked_until"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UnlockedAt  *time.Time `json:"unlocked_at,omitempty"`
+	UnlockedBy  *int       `json:"unlocked_by,omitempty"`
+}
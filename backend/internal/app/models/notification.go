@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification is an entry of the in-app inbox of a user. Its type is the
+// type of the event it was made from, such as EventTodoReminder.
+type Notification struct {
+	ID        int64           `json:"id"`
+	UserID    int             `json:"-"`
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data"`
+	ReadAt    *time.Time      `json:"read_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// OverdueTodo is a todo that became overdue.
+type OverdueTodo struct {
+	ID      int       `json:"id"`
+	UserID  int       `json:"-"`
+	Title   string    `json:"title"`
+	DueDate time.Time `json:"due_date"`
+}
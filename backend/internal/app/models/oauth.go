@@ -0,0 +1,118 @@
+package models
+
+import "time"
+
+const (
+	// OAuthAccessTokenPrefix starts every access token issued to OAuth2
+	// clients so that it can be told apart from other tokens.
+	OAuthAccessTokenPrefix = "todo_oat_"
+	// OAuthRefreshTokenPrefix starts every refresh token issued to OAuth2 clients.
+	OAuthRefreshTokenPrefix = "todo_ort_"
+	// OAuthClientSecretPrefix starts every secret of a confidential client.
+	OAuthClientSecretPrefix = "todo_ocs_"
+)
+
+// OAuthScopes lists the scopes that OAuth2 clients can request, with the
+// description shown to users on the consent screen. Applications can never
+// be granted the admin scope.
+var OAuthScopes = map[string]string{
+	ScopeTodosRead:  "Read your todos",
+	ScopeTodosWrite: "Create, update and delete your todos",
+}
+
+// OAuthClient is an application registered to act on behalf of users.
+// Public clients have no secret.
+type OAuthClient struct {
+	ID           int       `json:"-"`
+	ClientID     string    `json:"client_id"`
+	SecretHash   string    `json:"-"`
+	Confidential bool      `json:"confidential"`
+	OwnerID      int       `json:"-"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OAuthGrant holds the scopes a user has given a client. ClientID refers to
+// OAuthClient.ID.
+type OAuthGrant struct {
+	ID        int
+	UserID    int
+	ClientID  int
+	Scopes    []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// OAuthAuthorizationCode is an authorization code waiting to be redeemed.
+// Only the hash of the code is stored. UserID and ClientID are those of its
+// grant.
+type OAuthAuthorizationCode struct {
+	CodeHash      string
+	GrantID       int
+	UserID        int
+	ClientID      int
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+// OAuthToken is an access and refresh token pair issued to a client. Only
+// the hashes of the tokens are stored. UserID and ClientID are those of its
+// grant.
+type OAuthToken struct {
+	ID               int
+	GrantID          int
+	UserID           int
+	ClientID         int
+	AccessTokenHash  string
+	RefreshTokenHash string
+	Scopes           []string
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+	LastUsedAt       *time.Time
+}
+
+// OAuthTokenResponse is the successful response of the token endpoint.
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// OAuthScope describes a scope on the consent screen.
+type OAuthScope struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Granted is true if the user already gave the scope to the client.
+	Granted bool `json:"granted"`
+}
+
+// OAuthConsent is what a user is asked to approve on the consent screen.
+type OAuthConsent struct {
+	ClientID   string       `json:"client_id"`
+	ClientName string       `json:"client_name"`
+	Scopes     []OAuthScope `json:"scopes"`
+}
+
+// AuthorizedApp is a client that a user has given access to their account.
+type AuthorizedApp struct {
+	ClientID     string     `json:"client_id"`
+	Name         string     `json:"name"`
+	Scopes       []string   `json:"scopes"`
+	AuthorizedAt time.Time  `json:"authorized_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+}
+
+// HasRedirectURI reports whether uri exactly matches a registered redirect URI.
+func (c *OAuthClient) HasRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// OIDCProvider is an identity provider that users can sign in with.
+type OIDCProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// UserIdentity links a user to their account at an OpenID Connect provider.
+type UserIdentity struct {
+	ID          int
+	UserID      int
+	Provider    string
+	Subject     string
+	Email       string
+	LastLoginAt *time.Time
+	CreatedAt   time.Time
+}
+
+// OIDCLoginState holds the secrets of an OpenID Connect login in progress.
+// Only the hash of the state parameter is stored.
+type OIDCLoginState struct {
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,20 @@
+package models
+
+import "encoding/json"
+
+// Optional is a field of a partial update, which is only changed when Set.
+// Decoded from JSON, it is Set whenever the field is present, even as null.
+type Optional[T any] struct {
+	Value T
+	Set   bool
+}
+
+// Some returns an Optional set to value.
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{Value: value, Set: true}
+}
+
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// Channels reminders are delivered on.
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	// ChannelInApp adds to the in-app inbox of the user, and delivers to
+	// their live clients.
+	ChannelInApp = "in_app"
+	// ChannelPush delivers Web Push messages to the subscribed browsers of
+	// the user.
+	ChannelPush = "push"
+)
+
+// NotificationChannels lists every channel.
+var NotificationChannels = []string{ChannelEmail, ChannelWebhook, ChannelInApp, ChannelPush}
+
+// DefaultNotificationChannels are the channels of users who have not chosen
+// theirs.
+var DefaultNotificationChannels = []string{ChannelEmail, ChannelInApp}
+
+// Reminder goes off at RemindAt, or BeforeMinutes before the due date of its
+// todo, unless it is snoozed until later.
+type Reminder struct {
+	ID            int        `json:"id"`
+	TodoID        int        `json:"todo_id"`
+	UserID        int        `json:"-"`
+	RemindAt      *time.Time `json:"remind_at"`
+	BeforeMinutes *int       `json:"before_minutes"`
+	SnoozedUntil  *time.Time `json:"snoozed_until"`
+	// FireAt is when the reminder goes off. It is nil for reminders
+	// relative to the due date of a todo without one.
+	FireAt *time.Time `json:"fire_at"`
+	// FiredAt is the time the reminder last went off for. The reminder goes
+	// off again once FireAt changes.
+	FiredAt   *time.Time `json:"fired_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// DueReminder is a reminder that went off, with the todo it is about.
+type DueReminder struct {
+	ReminderID int        `json:"reminder_id"`
+	TodoID     int        `json:"todo_id"`
+	UserID     int        `json:"-"`
+	Title      string     `json:"title"`
+	DueDate    *time.Time `json:"due_date"`
+	FireAt     time.Time  `json:"fire_at"`
+}
+
+// NotificationPreferences are the choices of a user about the reminders
+// and digests sent to them. Quiet hours are "HH:MM" times in Timezone,
+// during which reminders wait; there are none when they are empty.
+type NotificationPreferences struct {
+	UserID          int      `json:"-"`
+	Channels        []string `json:"channels"`
+	Timezone        string   `json:"timezone"`
+	QuietHoursStart string   `json:"quiet_hours_start"`
+	QuietHoursEnd   string   `json:"quiet_hours_end"`
+	// DigestFrequency is how often the email digest of todos is sent, one
+	// of DigestFrequencies.
+	DigestFrequency string `json:"digest_frequency"`
+}
+
+// PushSubscription is a browser subscribed to the Web Push messages of a
+// user, with the keys its messages are encrypted for.
+type PushSubscription struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Endpoint  string    `json:"endpoint"`
+	P256dh    string    `json:"-"`
+	Auth      string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Schedule is a periodic task of the scheduler, which queues a job of
+// JobType each time its cron expression Spec fires.
+type Schedule struct {
+	Name      string     `json:"name"`
+	Spec      string     `json:"spec"`
+	JobType   string     `json:"job_type"`
+	LastRunAt *time.Time `json:"last_run_at"`
+	NextRunAt *time.Time `json:"next_run_at"`
+}
+
+// ScheduledRun is the payload of the jobs queued by the scheduler.
+type ScheduledRun struct {
+	Schedule    string    `json:"schedule"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
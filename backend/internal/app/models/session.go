@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Session is a login of a user. Access tokens carry the session ID so that
+// revoking the session invalidates them.
+type Session struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	// Device describes the user agent, e.g. "Firefox on Windows".
+	Device     string     `json:"device"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// Current marks the session of the request in listings.
+	Current bool `json:"current"`
+}
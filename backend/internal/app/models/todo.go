@@ -1,10 +1,31 @@
 package models
 
+import "time"
+
+// Statuses of todos, as set by the frontend. Clients may use others.
+const (
+	TodoPending = "pending"
+	// TodoCompleted todos get no reminders and are never overdue.
+	TodoCompleted = "completed"
+)
+
 type Todo struct {
-	ID        int    `json:"id"`
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	Status    string `json:"status"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Status    string     `json:"status"`
+	Tags      []string   `json:"tags,omitempty"`
+	DueDate   *time.Time `json:"due_date,omitempty"`
+	CreatedAt string     `json:"created_at"`
+	UpdatedAt string     `json:"updated_at"`
+}
+
+// TodoUpdate holds the changes made to a todo. Its tags and due date are
+// kept unless set.
+type TodoUpdate struct {
+	Title   string
+	Content string
+	Status  string
+	Tags    Optional[[]string]
+	DueDate Optional[*time.Time]
 }
@@ -1,9 +1,36 @@
 package models
 
+import "time"
+
+// Roles of users. Admins manage every account.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
 type User struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
 	Email     string `json:"email"`
-	Password  string `json:"password"`
+	Password  string `json:"-"`
 	CreatedAt string `json:"created_at"`
+
+	EmailVerified bool `json:"email_verified"`
+	IsAdmin       bool `json:"is_admin"`
+	// DeletionScheduledAt is when a self-deleted account will be removed.
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+
+	// ExternalID identifies users provisioned over SCIM in the directory.
+	ExternalID string `json:"external_id,omitempty"`
+	// DeactivatedAt is when the user was deprovisioned. Deactivated users
+	// cannot sign in.
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	// PendingApproval is set on accounts that signed up in approval mode
+	// until an admin approves them. Pending users cannot sign in.
+	PendingApproval bool `json:"pending_approval"`
+}
+
+// IsActive reports whether the user may sign in.
+func (u *User) IsActive() bool {
+	return u.DeactivatedAt == nil
 }
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+	// TokenPurposeChangeEmail tokens carry the new address in Data.
+	TokenPurposeChangeEmail = "change_email"
+	// TokenPurposeUnsubscribeDigest tokens are not stored: they carry the
+	// ID of the user they unsubscribe from digests.
+	TokenPurposeUnsubscribeDigest = "unsubscribe_digest"
+)
+
+// UserToken is a single-use token sent to a user by email.
+// Only the hash of the token is stored.
+type UserToken struct {
+	ID        int
+	UserID    int
+	Purpose   string
+	TokenHash string
+	Data      string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookSecretPrefix starts every generated webhook signing secret.
+const WebhookSecretPrefix = "whsec_"
+
+// WebhookEventTypes lists the event types webhooks can subscribe to.
+var WebhookEventTypes = []string{EventTodoCreated, EventTodoUpdated, EventTodoDeleted, EventTodoReminder, EventTodoOverdue}
+
+// Statuses of webhook deliveries.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	// WebhookDeliveryFailed deliveries have used up their attempts.
+	WebhookDeliveryFailed = "failed"
+)
+
+// Webhook sends the events of a user to a URL. Webhooks are disabled after
+// too many failed attempts in a row.
+type Webhook struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"-"`
+	URL          string     `json:"url"`
+	Secret       string     `json:"-"`
+	EventTypes   []string   `json:"event_types"`
+	Active       bool       `json:"active"`
+	FailureCount int        `json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// WebhookDelivery is an event sent, or to be sent, to a webhook. Deliveries
+// are queued in the database and retried until they succeed or run out of
+// attempts.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at"`
+	ResponseStatus *int            `json:"response_status"`
+	ResponseBody   string          `json:"response_body"`
+	Error          string          `json:"error"`
+	CreatedAt      time.Time       `json:"created_at"`
+
+	// URL and Secret are those of the webhook when the delivery is claimed
+	// for sending.
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+	// ClaimedUntil is the end of the lease of a claimed delivery. It
+	// identifies the claim when the attempt is recorded.
+	ClaimedUntil time.Time `json:"-"`
+}
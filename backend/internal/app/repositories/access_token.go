@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+// accessTokenColumns lists the columns read by scanAccessToken, in order.
+const accessTokenColumns = `id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at`
+
+// scanAccessToken reads a row selected with accessTokenColumns into token.
+func scanAccessToken(row rowScanner, token *models.PersonalAccessToken) error {
+	return row.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &token.TokenHash,
+		(*pq.StringArray)(&token.Scopes), &token.ExpiresAt, &token.LastUsedAt, &token.CreatedAt)
+}
+
+type AccessTokenRepository struct {
+	DB *sql.DB
+}
+
+func NewAccessTokenRepository(db *sql.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{DB: db}
+}
+
+// CreateAccessToken stores a new personal access token
+func (r *AccessTokenRepository) CreateAccessToken(ctx context.Context, token *models.PersonalAccessToken) error {
+	query := `INSERT INTO personal_access_tokens (user_id, name, prefix, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+	err := r.DB.QueryRowContext(ctx, query, token.UserID, token.Name, token.Prefix, token.TokenHash, pq.StringArray(token.Scopes), token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create access token: %w", err)
+	}
+	return nil
+}
+
+// ListAccessTokens retrieves the tokens of a user, newest first
+func (r *AccessTokenRepository) ListAccessTokens(ctx context.Context, userID int) ([]models.PersonalAccessToken, error) {
+	query := `SELECT ` + accessTokenColumns + ` FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.PersonalAccessToken{}
+	for rows.Next() {
+		var token models.PersonalAccessToken
+		if err := scanAccessToken(rows, &token); err != nil {
+			return nil, fmt.Errorf("failed to scan access token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return tokens, nil
+}
+
+// DeleteAccessToken removes a token of a user
+func (r *AccessTokenRepository) DeleteAccessToken(ctx context.Context, userID int, id int) error {
+	query := `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`
+	result, err := r.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete access token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected during delete: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// UseAccessToken looks up an unexpired token by its hash and records that
+// it was used
+func (r *AccessTokenRepository) UseAccessToken(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	token := &models.PersonalAccessToken{}
+	query := `UPDATE personal_access_tokens SET last_used_at = NOW()
+		WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > NOW())
+		AND user_id IN (SELECT id FROM users WHERE deactivated_at IS NULL)
+		RETURNING ` + accessTokenColumns
+	err := scanAccessToken(r.DB.QueryRowContext(ctx, query, tokenHash), token)
+	if err == sql.ErrNoRows {
+		return nil, ErrAccessTokenNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to use access token: %w", err)
+	}
+	return token, nil
+}
+
+var _ AccessTokenRepoInterface = (*AccessTokenRepository)(nil)
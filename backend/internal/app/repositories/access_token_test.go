@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var accessTokenRowColumns = []string{"id", "user_id", "name", "prefix", "token_hash", "scopes", "expires_at", "last_used_at", "created_at"}
+
+func TestAccessTokenRepository_CreateAccessToken(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewAccessTokenRepository(mockDB)
+
+	token := &models.PersonalAccessToken{UserID: 1, Name: "ci", Prefix: "todo_pat_abc", TokenHash: "hash", Scopes: []string{models.ScopeTodosWrite}}
+
+	mock.ExpectQuery(`INSERT INTO personal_access_tokens .*`).
+		WithArgs(1, "ci", "todo_pat_abc", "hash", `{"todos:write"}`, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Now()))
+
+	err = repo.CreateAccessToken(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, token.ID)
+}
+
+func TestAccessTokenRepository_ListAccessTokens(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewAccessTokenRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM personal_access_tokens WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(accessTokenRowColumns).
+			AddRow(3, 1, "ci", "todo_pat_abc", "hash", `{todos:read,todos:write}`, nil, now, now))
+
+	tokens, err := repo.ListAccessTokens(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1)
+	assert.Equal(t, []string{models.ScopeTodosRead, models.ScopeTodosWrite}, tokens[0].Scopes)
+	assert.Nil(t, tokens[0].ExpiresAt)
+}
+
+func TestAccessTokenRepository_DeleteAccessToken(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewAccessTokenRepository(mockDB)
+
+	mock.ExpectExec(`DELETE FROM personal_access_tokens WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.DeleteAccessToken(context.Background(), 1, 3))
+
+	// Test token of another user scenario
+	mock.ExpectExec(`DELETE FROM personal_access_tokens WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(3, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.DeleteAccessToken(context.Background(), 2, 3), ErrAccessTokenNotFound)
+}
+
+func TestAccessTokenRepository_UseAccessToken(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewAccessTokenRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE personal_access_tokens SET last_used_at = NOW\(\)\s+WHERE token_hash = \$1 AND \(expires_at IS NULL OR expires_at > NOW\(\)\)`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows(accessTokenRowColumns).
+			AddRow(3, 1, "ci", "todo_pat_abc", "hash", `{admin}`, now.Add(time.Hour), now, now))
+
+	token, err := repo.UseAccessToken(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, token.UserID)
+	assert.True(t, token.HasScope(models.ScopeAdmin))
+
+	// Test unknown or expired token scenario
+	mock.ExpectQuery(`UPDATE personal_access_tokens SET last_used_at = NOW\(\)`).
+		WithArgs("other").
+		WillReturnError(sql.ErrNoRows)
+
+	token, err = repo.UseAccessToken(context.Background(), "other")
+	assert.Nil(t, token)
+	assert.ErrorIs(t, err, ErrAccessTokenNotFound)
+}
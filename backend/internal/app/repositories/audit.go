@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"todo_app_backend/internal/app/models"
+)
+
+// auditLogLockID is the advisory lock that serializes appends to the audit
+// log, so that every entry chains to the one before it.
+const auditLogLockID = 0x61756469746c6f67 // "auditlog"
+
+// auditColumns lists the columns read by scanAuditEntry, in order.
+const auditColumns = `id, created_at, action, actor_id, target_type, target_id, ip, request_id, metadata, prev_hash, hash`
+
+func scanAuditEntry(row rowScanner, entry *models.AuditEntry) error {
+	var actorID sql.NullInt64
+	var metadata []byte
+	err := row.Scan(&entry.ID, &entry.CreatedAt, &entry.Action, &actorID, &entry.TargetType, &entry.TargetID, &entry.IP, &entry.RequestID,
+		&metadata, &entry.PrevHash, &entry.Hash)
+	if err != nil {
+		return err
+	}
+	if actorID.Valid {
+		id := int(actorID.Int64)
+		entry.ActorID = &id
+	}
+	entry.Metadata = metadata
+	return nil
+}
+
+type AuditRepository struct {
+	DB *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{DB: db}
+}
+
+// AppendEntry chains an entry to the last one and inserts it. Its PrevHash,
+// Hash and ID are set.
+func (r *AuditRepository) AppendEntry(ctx context.Context, entry *models.AuditEntry) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, int64(auditLogLockID)); err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&entry.PrevHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		entry.PrevHash = ""
+	} else if err != nil {
+		return fmt.Errorf("failed to get last audit entry: %w", err)
+	}
+	entry.Hash = entry.ComputeHash()
+
+	query := `INSERT INTO audit_log (created_at, action, actor_id, target_type, target_id, ip, request_id, metadata, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
+	err = tx.QueryRowContext(ctx, query, entry.CreatedAt, entry.Action, entry.ActorID, entry.TargetType, entry.TargetID, entry.IP, entry.RequestID,
+		string(entry.Metadata), entry.PrevHash, entry.Hash).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ListEntries retrieves at most limit entries matching filter, newest first
+func (r *AuditRepository) ListEntries(ctx context.Context, filter models.AuditFilter, limit int) ([]models.AuditEntry, error) {
+	where, args := auditFilterSQL(filter)
+	query := fmt.Sprintf(`SELECT %s FROM audit_log WHERE %s ORDER BY id DESC LIMIT $%d`, auditColumns, where, len(args)+1)
+	rows, err := r.DB.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err := scanAuditEntry(rows, &entry); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return entries, nil
+}
+
+// EachEntry calls fn with every entry matching filter, oldest first, and
+// stops at the first error fn returns. Entries are read as they are
+// consumed, so the whole log never has to fit in memory.
+func (r *AuditRepository) EachEntry(ctx context.Context, filter models.AuditFilter, fn func(entry *models.AuditEntry) error) error {
+	where, args := auditFilterSQL(filter)
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+auditColumns+` FROM audit_log WHERE `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err := scanAuditEntry(rows, &entry); err != nil {
+			return fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+	return nil
+}
+
+// auditFilterSQL translates filter into a WHERE condition and its
+// arguments.
+func auditFilterSQL(filter models.AuditFilter) (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.ActorID != 0 {
+		add("actor_id = $%d", filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		add("target_id = $%d", filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+	if filter.BeforeID != 0 {
+		add("id < $%d", filter.BeforeID)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+var _ AuditRepoInterface = (*AuditRepository)(nil)
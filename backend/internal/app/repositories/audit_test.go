@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var auditRowColumns = []string{"id", "created_at", "action", "actor_id", "target_type", "target_id", "ip", "request_id", "metadata", "prev_hash", "hash"}
+
+func TestAuditRepository_AppendEntry(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewAuditRepository(mockDB)
+
+	actorID := 1
+	entry := &models.AuditEntry{
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Action:    models.AuditUserDeleted,
+		ActorID:   &actorID,
+		Metadata:  json.RawMessage(`{"before":{"email":"john@example.com"}}`),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).
+		WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("previous"))
+	mock.ExpectQuery(`INSERT INTO audit_log .* RETURNING id`).
+		WithArgs(entry.CreatedAt, entry.Action, entry.ActorID, "", "", "", "", `{"before":{"email":"john@example.com"}}`, "previous", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.AppendEntry(context.Background(), entry))
+	assert.Equal(t, int64(8), entry.ID)
+	assert.Equal(t, "previous", entry.PrevHash)
+	assert.Equal(t, entry.ComputeHash(), entry.Hash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditRepository_AppendFirstEntry(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewAuditRepository(mockDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT hash FROM audit_log`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`INSERT INTO audit_log`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	entry := &models.AuditEntry{Action: models.AuditLogin, Metadata: json.RawMessage(`{}`)}
+	assert.NoError(t, repo.AppendEntry(context.Background(), entry))
+	assert.Empty(t, entry.PrevHash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditRepository_ListEntries(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewAuditRepository(mockDB)
+
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM audit_log WHERE TRUE AND action = \$1 AND actor_id = \$2 AND created_at >= \$3 AND id < \$4 ORDER BY id DESC LIMIT \$5`).
+		WithArgs(models.AuditLoginFailed, 3, since, int64(100), 20).
+		WillReturnRows(sqlmock.NewRows(auditRowColumns).
+			AddRow(99, now, models.AuditLoginFailed, 3, "user", "3", "10.0.0.1", "req-1", []byte(`{"reason":"invalid_credentials"}`), "a", "b").
+			AddRow(98, now, models.AuditLoginFailed, nil, "", "", "", "", []byte(`{}`), "", "a"))
+
+	entries, err := repo.ListEntries(context.Background(), models.AuditFilter{
+		Action: models.AuditLoginFailed, ActorID: 3, Since: since, BeforeID: 100,
+	}, 20)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, 3, *entries[0].ActorID)
+	assert.JSONEq(t, `{"reason":"invalid_credentials"}`, string(entries[0].Metadata))
+	assert.Nil(t, entries[1].ActorID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditRepository_EachEntry(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewAuditRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM audit_log WHERE TRUE AND target_type = \$1 AND target_id = \$2 ORDER BY id$`).
+		WithArgs("user", "7").
+		WillReturnRows(sqlmock.NewRows(auditRowColumns).
+			AddRow(1, now, models.AuditLogin, 7, "user", "7", "", "", []byte(`{}`), "", "a").
+			AddRow(2, now, models.AuditUserDeleted, 1, "user", "7", "", "", []byte(`{}`), "a", "b"))
+
+	var ids []int64
+	err = repo.EachEntry(context.Background(), models.AuditFilter{TargetType: "user", TargetID: "7"}, func(entry *models.AuditEntry) error {
+		ids = append(ids, entry.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+)
+
+// inboundWebhookColumns lists the columns read by scanInboundWebhook, in order.
+const inboundWebhookColumns = `id, user_id, name, prefix, token_hash, secret, mapping, last_used_at, created_at, updated_at`
+
+// scanInboundWebhook reads a row selected with inboundWebhookColumns into
+// webhook.
+func scanInboundWebhook(row rowScanner, webhook *models.InboundWebhook) error {
+	var mapping []byte
+	err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.Name, &webhook.Prefix, &webhook.TokenHash, &webhook.Secret,
+		&mapping, &webhook.LastUsedAt, &webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	webhook.SignatureRequired = webhook.Secret != ""
+	return json.Unmarshal(mapping, &webhook.Mapping)
+}
+
+type InboundWebhookRepository struct {
+	DB *sql.DB
+}
+
+func NewInboundWebhookRepository(db *sql.DB) *InboundWebhookRepository {
+	return &InboundWebhookRepository{DB: db}
+}
+
+// CreateInboundWebhook stores a new inbound webhook
+func (r *InboundWebhookRepository) CreateInboundWebhook(ctx context.Context, webhook *models.InboundWebhook) error {
+	mapping, err := json.Marshal(webhook.Mapping)
+	if err != nil {
+		return fmt.Errorf("failed to encode inbound webhook mapping: %w", err)
+	}
+	query := `INSERT INTO inbound_webhooks (user_id, name, prefix, token_hash, secret, mapping)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING ` + inboundWebhookColumns
+	err = scanInboundWebhook(r.DB.QueryRowContext(ctx, query, webhook.UserID, webhook.Name, webhook.Prefix,
+		webhook.TokenHash, webhook.Secret, string(mapping)), webhook)
+	if err != nil {
+		return fmt.Errorf("failed to create inbound webhook: %w", err)
+	}
+	return nil
+}
+
+// ListInboundWebhooks retrieves the inbound webhooks of a user, oldest first
+func (r *InboundWebhookRepository) ListInboundWebhooks(ctx context.Context, userID int) ([]models.InboundWebhook, error) {
+	query := `SELECT ` + inboundWebhookColumns + ` FROM inbound_webhooks WHERE user_id = $1 ORDER BY id`
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inbound webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.InboundWebhook{}
+	for rows.Next() {
+		var webhook models.InboundWebhook
+		if err := scanInboundWebhook(rows, &webhook); err != nil {
+			return nil, fmt.Errorf("failed to scan inbound webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetInboundWebhook retrieves an inbound webhook of a user
+func (r *InboundWebhookRepository) GetInboundWebhook(ctx context.Context, userID int, id int) (*models.InboundWebhook, error) {
+	webhook := &models.InboundWebhook{}
+	query := `SELECT ` + inboundWebhookColumns + ` FROM inbound_webhooks WHERE id = $1 AND user_id = $2`
+	err := scanInboundWebhook(r.DB.QueryRowContext(ctx, query, id, userID), webhook)
+	if err == sql.ErrNoRows {
+		return nil, ErrInboundWebhookNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get inbound webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// UseInboundWebhook retrieves the inbound webhook with the given token hash
+// and records that it was used
+func (r *InboundWebhookRepository) UseInboundWebhook(ctx context.Context, tokenHash string) (*models.InboundWebhook, error) {
+	webhook := &models.InboundWebhook{}
+	query := `UPDATE inbound_webhooks SET last_used_at = NOW() WHERE token_hash = $1 RETURNING ` + inboundWebhookColumns
+	err := scanInboundWebhook(r.DB.QueryRowContext(ctx, query, tokenHash), webhook)
+	if err == sql.ErrNoRows {
+		return nil, ErrInboundWebhookNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to use inbound webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// UpdateInboundWebhook saves the name, secret and mapping of an inbound
+// webhook
+func (r *InboundWebhookRepository) UpdateInboundWebhook(ctx context.Context, webhook *models.InboundWebhook) error {
+	mapping, err := json.Marshal(webhook.Mapping)
+	if err != nil {
+		return fmt.Errorf("failed to encode inbound webhook mapping: %w", err)
+	}
+	query := `UPDATE inbound_webhooks SET name = $3, secret = $4, mapping = $5, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2 RETURNING updated_at`
+	err = r.DB.QueryRowContext(ctx, query, webhook.ID, webhook.UserID, webhook.Name, webhook.Secret, string(mapping)).
+		Scan(&webhook.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrInboundWebhookNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to update inbound webhook: %w", err)
+	}
+	webhook.SignatureRequired = webhook.Secret != ""
+	return nil
+}
+
+// DeleteInboundWebhook removes an inbound webhook of a user. The todos it
+// created are kept.
+func (r *InboundWebhookRepository) DeleteInboundWebhook(ctx context.Context, userID int, id int) error {
+	query := `DELETE FROM inbound_webhooks WHERE id = $1 AND user_id = $2`
+	result, err := r.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete inbound webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected during delete: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrInboundWebhookNotFound
+	}
+	return nil
+}
+
+var _ InboundWebhookRepoInterface = (*InboundWebhookRepository)(nil)
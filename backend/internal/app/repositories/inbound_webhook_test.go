@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var inboundWebhookRowColumns = []string{"id", "user_id", "name", "prefix", "token_hash", "secret", "mapping", "last_used_at", "created_at", "updated_at"}
+
+func TestInboundWebhookRepository_CreateInboundWebhook(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewInboundWebhookRepository(mockDB)
+
+	webhook := &models.InboundWebhook{
+		UserID: 1, Name: "Alerts", Prefix: "whin_abcdef", TokenHash: "hash",
+		Mapping: models.InboundMapping{Title: "{{.title}}"},
+	}
+	mapping := `{"title":"{{.title}}","content":"","tags":"","due_date":"","dedup_key":""}`
+	mock.ExpectQuery(`INSERT INTO inbound_webhooks .* RETURNING id, user_id, name`).
+		WithArgs(1, "Alerts", "whin_abcdef", "hash", "", mapping).
+		WillReturnRows(sqlmock.NewRows(inboundWebhookRowColumns).
+			AddRow(4, 1, "Alerts", "whin_abcdef", "hash", "", []byte(mapping), nil, time.Now(), time.Now()))
+
+	assert.NoError(t, repo.CreateInboundWebhook(context.Background(), webhook))
+	assert.Equal(t, 4, webhook.ID)
+	assert.False(t, webhook.SignatureRequired)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInboundWebhookRepository_UseInboundWebhook(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewInboundWebhookRepository(mockDB)
+
+	mock.ExpectQuery(`UPDATE inbound_webhooks SET last_used_at = NOW\(\) WHERE token_hash = \$1`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows(inboundWebhookRowColumns).
+			AddRow(4, 1, "Alerts", "whin_abcdef", "hash", "whsec_secret", []byte(`{"title":"{{.alert}}","dedup_key":"{{.id}}"}`),
+				time.Now(), time.Now(), time.Now()))
+
+	webhook, err := repo.UseInboundWebhook(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.True(t, webhook.SignatureRequired)
+	assert.Equal(t, models.InboundMapping{Title: "{{.alert}}", DedupKey: "{{.id}}"}, webhook.Mapping)
+
+	mock.ExpectQuery(`UPDATE inbound_webhooks SET last_used_at`).WithArgs("other").WillReturnError(sql.ErrNoRows)
+	_, err = repo.UseInboundWebhook(context.Background(), "other")
+	assert.ErrorIs(t, err, ErrInboundWebhookNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+// inviteColumns lists the columns read by scanInvite, in order.
+const inviteColumns = `id, prefix, code_hash, note, created_by, created_at, expires_at, used_at, used_by`
+
+// scanInvite reads a row selected with inviteColumns into invite.
+func scanInvite(row rowScanner, invite *models.InviteCode) error {
+	return row.Scan(&invite.ID, &invite.Prefix, &invite.CodeHash, &invite.Note, &invite.CreatedBy, &invite.CreatedAt,
+		&invite.ExpiresAt, &invite.UsedAt, &invite.UsedBy)
+}
+
+type InviteRepository struct {
+	DB *sql.DB
+}
+
+func NewInviteRepository(db *sql.DB) *InviteRepository {
+	return &InviteRepository{DB: db}
+}
+
+// CreateInvite stores a new invite code
+func (r *InviteRepository) CreateInvite(ctx context.Context, invite *models.InviteCode) error {
+	query := `INSERT INTO invite_codes (prefix, code_hash, note, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	err := r.DB.QueryRowContext(ctx, query, invite.Prefix, invite.CodeHash, invite.Note, invite.CreatedBy, invite.ExpiresAt).
+		Scan(&invite.ID, &invite.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create invite code: %w", err)
+	}
+	return nil
+}
+
+// ListInvites retrieves every invite code, newest first
+func (r *InviteRepository) ListInvites(ctx context.Context) ([]models.InviteCode, error) {
+	query := `SELECT ` + inviteColumns + ` FROM invite_codes ORDER BY created_at DESC, id DESC`
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invite codes: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []models.InviteCode{}
+	for rows.Next() {
+		var invite models.InviteCode
+		if err := scanInvite(rows, &invite); err != nil {
+			return nil, fmt.Errorf("failed to scan invite code: %w", err)
+		}
+		invites = append(invites, invite)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return invites, nil
+}
+
+// DeleteInvite removes an invite code
+func (r *InviteRepository) DeleteInvite(ctx context.Context, id int) error {
+	query := `DELETE FROM invite_codes WHERE id = $1`
+	result, err := r.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete invite code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected during delete: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrInviteNotFound
+	}
+	return nil
+}
+
+// CreateInvitedUser redeems the unused, unexpired invite code with the given
+// hash and creates the user it invites. Neither happens unless both do.
+func (r *InviteRepository) CreateInvitedUser(ctx context.Context, user *models.User, codeHash string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var inviteID int
+	query := `UPDATE invite_codes SET used_at = NOW()
+		WHERE code_hash = $1 AND used_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING id`
+	err = tx.QueryRowContext(ctx, query, codeHash).Scan(&inviteID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInviteNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to redeem invite code: %w", err)
+	}
+
+	query = `INSERT INTO users (name, email, password) VALUES ($1, $2, $3) RETURNING id`
+	err = tx.QueryRowContext(ctx, query, user.Name, user.Email, user.Password).Scan(&user.ID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrDuplicateEmail
+	} else if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE invite_codes SET used_by = $1 WHERE id = $2`, user.ID, inviteID); err != nil {
+		return fmt.Errorf("failed to redeem invite code: %w", err)
+	}
+	user.Password = ""
+	return tx.Commit()
+}
+
+var _ InviteRepoInterface = (*InviteRepository)(nil)
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInviteRepository_CreateInvite(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewInviteRepository(mockDB)
+
+	createdBy := 1
+	invite := &models.InviteCode{Prefix: "todo_inv_abcdef", CodeHash: "hash", Note: "for Jane", CreatedBy: &createdBy}
+	mock.ExpectQuery(`INSERT INTO invite_codes .* RETURNING id, created_at`).
+		WithArgs("todo_inv_abcdef", "hash", "for Jane", &createdBy, invite.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(4, time.Now()))
+
+	assert.NoError(t, repo.CreateInvite(context.Background(), invite))
+	assert.Equal(t, 4, invite.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInviteRepository_ListInvites(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewInviteRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM invite_codes ORDER BY created_at DESC, id DESC`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "prefix", "code_hash", "note", "created_by", "created_at", "expires_at", "used_at", "used_by"}).
+			AddRow(2, "todo_inv_abcdef", "hash2", "", 1, now, nil, now, 9).
+			AddRow(1, "todo_inv_ghijkl", "hash1", "for Jane", nil, now, now, nil, nil))
+
+	invites, err := repo.ListInvites(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, invites, 2)
+	assert.Equal(t, 9, *invites[0].UsedBy)
+	assert.Nil(t, invites[1].CreatedBy)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInviteRepository_DeleteInvite(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewInviteRepository(mockDB)
+
+	mock.ExpectExec(`DELETE FROM invite_codes WHERE id = \$1`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.DeleteInvite(context.Background(), 2))
+
+	mock.ExpectExec(`DELETE FROM invite_codes WHERE id = \$1`).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.DeleteInvite(context.Background(), 3), ErrInviteNotFound)
+}
+
+func TestInviteRepository_CreateInvitedUser(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewInviteRepository(mockDB)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE invite_codes SET used_at = NOW\(\) WHERE code_hash = \$1 AND used_at IS NULL .* RETURNING id`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery(`INSERT INTO users \(name, email, password\)`).
+		WithArgs("Jane", "jane@example.com", "hashed").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec(`UPDATE invite_codes SET used_by = \$1 WHERE id = \$2`).WithArgs(7, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	user := &models.User{Name: "Jane", Email: "jane@example.com", Password: "hashed"}
+	assert.NoError(t, repo.CreateInvitedUser(context.Background(), user, "hash"))
+	assert.Equal(t, 7, user.ID)
+	assert.Empty(t, user.Password)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInviteRepository_CreateInvitedUserInvalidCode(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewInviteRepository(mockDB)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE invite_codes SET used_at`).WithArgs("used").WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err = repo.CreateInvitedUser(context.Background(), &models.User{Name: "Jane", Email: "jane@example.com"}, "used")
+	assert.ErrorIs(t, err, ErrInviteNotFound)
+
+	// The invite is not used up by a taken email address
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE invite_codes SET used_at`).WithArgs("hash").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery(`INSERT INTO users`).WillReturnError(&pq.Error{Code: uniqueViolation})
+	mock.ExpectRollback()
+
+	err = repo.CreateInvitedUser(context.Background(), &models.User{Name: "Jane", Email: "jane@example.com"}, "hash")
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
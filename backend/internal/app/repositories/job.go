@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+// jobColumns lists the columns read by scanJob, in order.
+const jobColumns = `id, type, payload, status, unique_key, attempts, max_attempts, run_at, locked_at, locked_by,
+	last_error, created_at, updated_at, finished_at`
+
+// scanJob reads a row selected with jobColumns into job.
+func scanJob(row rowScanner, job *models.Job) error {
+	var payload []byte
+	err := row.Scan(&job.ID, &job.Type, &payload, &job.Status, &job.UniqueKey, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &job.LockedAt, &job.LockedBy, &job.LastError, &job.CreatedAt, &job.UpdatedAt, &job.FinishedAt)
+	job.Payload = payload
+	return err
+}
+
+type JobRepository struct {
+	DB *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{DB: db}
+}
+
+// EnqueueJob stores a new pending job. ErrDuplicateJob is returned when a
+// pending or running job of the same type has the same unique key.
+func (r *JobRepository) EnqueueJob(ctx context.Context, job *models.Job) error {
+	query := `INSERT INTO jobs (type, payload, unique_key, max_attempts, run_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (type, unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running') DO NOTHING
+		RETURNING ` + jobColumns
+	err := scanJob(r.DB.QueryRowContext(ctx, query, job.Type, string(job.Payload), job.UniqueKey, job.MaxAttempts, job.RunAt), job)
+	if err == sql.ErrNoRows {
+		return ErrDuplicateJob
+	} else if err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return nil
+}
+
+// ClaimJobs takes up to limit due pending jobs of the given types for
+// worker, marking them running and counting the attempt. The jobs returned
+// identify their claim when their outcome is recorded.
+func (r *JobRepository) ClaimJobs(ctx context.Context, types []string, limit int, worker string) ([]models.Job, error) {
+	query := `UPDATE jobs SET status = 'running', attempts = attempts + 1, locked_at = NOW(), locked_by = $3, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM jobs WHERE status = 'pending' AND run_at <= NOW() AND type = ANY($1)
+			ORDER BY run_at, id LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+	rows, err := r.DB.QueryContext(ctx, query, pq.StringArray(types), limit, worker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	return scanJobs(rows)
+}
+
+// CompleteJob marks a claimed job as succeeded. ErrJobLost is returned when
+// the claim is no longer held.
+func (r *JobRepository) CompleteJob(ctx context.Context, job *models.Job) error {
+	query := `UPDATE jobs SET status = 'succeeded', locked_at = NULL, last_error = '', finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND locked_by = $2 AND attempts = $3`
+	result, err := r.DB.ExecContext(ctx, query, job.ID, job.LockedBy, job.Attempts)
+	if err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	return jobUpdated(result)
+}
+
+// FailJob records a failed attempt at a claimed job, saving its status, its
+// next run and its error. Dead jobs are finished. ErrJobLost is returned
+// when the claim is no longer held.
+func (r *JobRepository) FailJob(ctx context.Context, job *models.Job) error {
+	query := `UPDATE jobs SET status = $2, run_at = $3, last_error = $4, locked_at = NULL, updated_at = NOW(),
+		finished_at = CASE WHEN $2 = 'dead' THEN NOW() END
+		WHERE id = $1 AND status = 'running' AND locked_by = $5 AND attempts = $6`
+	result, err := r.DB.ExecContext(ctx, query, job.ID, job.Status, job.RunAt, job.LastError, job.LockedBy, job.Attempts)
+	if err != nil {
+		return fmt.Errorf("failed to fail job: %w", err)
+	}
+	return jobUpdated(result)
+}
+
+// ReleaseJob returns a claimed job to the queue without counting the
+// attempt, for jobs interrupted by a shutdown. ErrJobLost is returned when
+// the claim is no longer held.
+func (r *JobRepository) ReleaseJob(ctx context.Context, job *models.Job) error {
+	query := `UPDATE jobs SET status = 'pending', attempts = GREATEST(attempts - 1, 0), locked_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND locked_by = $2 AND attempts = $3`
+	result, err := r.DB.ExecContext(ctx, query, job.ID, job.LockedBy, job.Attempts)
+	if err != nil {
+		return fmt.Errorf("failed to release job: %w", err)
+	}
+	return jobUpdated(result)
+}
+
+// jobUpdated returns ErrJobLost when the update of a claimed job matched no
+// row: the job was recovered from its worker, and possibly claimed again,
+// even by the same worker. A claim is identified by the worker and the
+// attempt it counted, as each claim counts one more.
+func jobUpdated(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	if n == 0 {
+		return ErrJobLost
+	}
+	return nil
+}
+
+// RecoverStaleJobs returns the jobs running since before lockedBefore, whose
+// worker is presumed lost, to the queue, or kills them when they have no
+// attempts left. It returns the number of jobs recovered.
+func (r *JobRepository) RecoverStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	query := `UPDATE jobs SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END,
+		finished_at = CASE WHEN attempts >= max_attempts THEN NOW() END,
+		last_error = 'worker lost while running the job', locked_at = NULL, run_at = NOW(), updated_at = NOW()
+		WHERE status = 'running' AND locked_at < $1`
+	result, err := r.DB.ExecContext(ctx, query, lockedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recover stale jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteSucceededJobs removes the jobs that succeeded before the given time
+// and returns how many were removed. Dead jobs are kept for inspection.
+func (r *JobRepository) DeleteSucceededJobs(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM jobs WHERE status = 'succeeded' AND finished_at < $1`
+	result, err := r.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete succeeded jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ListJobs retrieves up to limit jobs matching filter, newest first
+func (r *JobRepository) ListJobs(ctx context.Context, filter models.JobFilter, limit int) ([]models.Job, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+	if filter.Type != "" {
+		add("type = $%d", filter.Type)
+	}
+	if filter.BeforeID > 0 {
+		add("id < $%d", filter.BeforeID)
+	}
+
+	query := `SELECT ` + jobColumns + ` FROM jobs`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	jobs, err := scanJobs(rows)
+	if jobs == nil && err == nil {
+		jobs = []models.Job{}
+	}
+	return jobs, err
+}
+
+// GetJob retrieves a job by ID
+func (r *JobRepository) GetJob(ctx context.Context, id int64) (*models.Job, error) {
+	job := &models.Job{}
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+	err := scanJob(r.DB.QueryRowContext(ctx, query, id), job)
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return job, nil
+}
+
+// RetryJob queues a dead or pending job to run now with a fresh set of
+// attempts. ErrJobNotFound is returned for other jobs, and ErrDuplicateJob
+// when another job holds its unique key.
+func (r *JobRepository) RetryJob(ctx context.Context, id int64) (*models.Job, error) {
+	job := &models.Job{}
+	query := `UPDATE jobs SET status = 'pending', attempts = 0, run_at = NOW(), finished_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status IN ('dead', 'pending') RETURNING ` + jobColumns
+	err := scanJob(r.DB.QueryRowContext(ctx, query, id), job)
+	var pqErr *pq.Error
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotFound
+	} else if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return nil, ErrDuplicateJob
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to retry job: %w", err)
+	}
+	return job, nil
+}
+
+// scanJobs reads and closes rows selected with jobColumns.
+func scanJobs(rows *sql.Rows) ([]models.Job, error) {
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		var job models.Job
+		if err := scanJob(rows, &job); err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return jobs, nil
+}
+
+var _ JobRepoInterface = (*JobRepository)(nil)
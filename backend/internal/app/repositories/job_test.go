@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var jobRowColumns = []string{"id", "type", "payload", "status", "unique_key", "attempts", "max_attempts", "run_at", "locked_at",
+	"locked_by", "last_error", "created_at", "updated_at", "finished_at"}
+
+func TestJobRepository_EnqueueJob(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewJobRepository(mockDB)
+
+	key := "accounts"
+	runAt := time.Now()
+	job := &models.Job{Type: models.JobPurgeDeletedAccounts, Payload: []byte(`{}`), UniqueKey: &key, MaxAttempts: 10, RunAt: runAt}
+	mock.ExpectQuery(`INSERT INTO jobs .* ON CONFLICT \(type, unique_key\) WHERE unique_key IS NOT NULL AND status IN \('pending', 'running'\) DO NOTHING`).
+		WithArgs(models.JobPurgeDeletedAccounts, `{}`, &key, 10, runAt).
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(5, models.JobPurgeDeletedAccounts, []byte(`{}`), models.JobPending, key, 0, 10, runAt, nil, "", "", runAt, runAt, nil))
+
+	assert.NoError(t, repo.EnqueueJob(context.Background(), job))
+	assert.Equal(t, int64(5), job.ID)
+
+	// The unique key is held by the job queued above
+	mock.ExpectQuery(`INSERT INTO jobs`).WillReturnRows(sqlmock.NewRows(jobRowColumns))
+	assert.ErrorIs(t, repo.EnqueueJob(context.Background(), job), ErrDuplicateJob)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobRepository_ClaimJobs(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewJobRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE jobs SET status = 'running', attempts = attempts \+ 1, .* FOR UPDATE SKIP LOCKED`).
+		WithArgs(pq.StringArray{"a", "b"}, 3, "host:1").
+		WillReturnRows(sqlmock.NewRows(jobRowColumns).
+			AddRow(5, "a", []byte(`{"n":1}`), models.JobRunning, nil, 1, 10, now, now, "host:1", "", now, now, nil))
+
+	jobs, err := repo.ClaimJobs(context.Background(), []string{"a", "b"}, 3, "host:1")
+	assert.NoError(t, err)
+	if assert.Len(t, jobs, 1) {
+		assert.Equal(t, 1, jobs[0].Attempts)
+		assert.JSONEq(t, `{"n":1}`, string(jobs[0].Payload))
+		assert.Nil(t, jobs[0].UniqueKey)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobRepository_CompleteJob(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewJobRepository(mockDB)
+
+	job := &models.Job{ID: 5, LockedBy: "host:1", Attempts: 1}
+	mock.ExpectExec(`UPDATE jobs SET status = 'succeeded', .* WHERE id = \$1 AND status = 'running' AND locked_by = \$2 AND attempts = \$3`).
+		WithArgs(int64(5), "host:1", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.CompleteJob(context.Background(), job))
+
+	// The job was presumed lost, recovered and claimed again by the same
+	// worker, which counted a second attempt
+	mock.ExpectExec(`UPDATE jobs SET status = 'succeeded'`).
+		WithArgs(int64(5), "host:1", 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.CompleteJob(context.Background(), job), ErrJobLost)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobRepository_FailJob(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewJobRepository(mockDB)
+
+	runAt := time.Now().Add(time.Minute)
+	job := &models.Job{ID: 5, Status: models.JobPending, RunAt: runAt, LastError: "timeout", LockedBy: "host:1", Attempts: 2}
+	mock.ExpectExec(`UPDATE jobs SET status = \$2, .* WHERE id = \$1 AND status = 'running' AND locked_by = \$5 AND attempts = \$6`).
+		WithArgs(int64(5), models.JobPending, runAt, "timeout", "host:1", 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.FailJob(context.Background(), job))
+
+	mock.ExpectExec(`UPDATE jobs SET status = \$2`).
+		WithArgs(int64(5), models.JobPending, runAt, "timeout", "host:1", 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.FailJob(context.Background(), job), ErrJobLost)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobRepository_ReleaseJob(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewJobRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE jobs SET status = 'pending', attempts = GREATEST\(attempts - 1, 0\), .* WHERE id = \$1 AND status = 'running' AND locked_by = \$2 AND attempts = \$3`).
+		WithArgs(int64(5), "host:1", 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.ReleaseJob(context.Background(), &models.Job{ID: 5, LockedBy: "host:1", Attempts: 3}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobRepository_ListJobs(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewJobRepository(mockDB)
+
+	mock.ExpectQuery(`SELECT .* FROM jobs WHERE status = \$1 AND id < \$2 ORDER BY id DESC LIMIT \$3`).
+		WithArgs(models.JobDead, int64(40), 50).
+		WillReturnRows(sqlmock.NewRows(jobRowColumns))
+
+	jobs, err := repo.ListJobs(context.Background(), models.JobFilter{Status: models.JobDead, BeforeID: 40}, 50)
+	assert.NoError(t, err)
+	assert.NotNil(t, jobs)
+	assert.Empty(t, jobs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobRepository_RetryJob(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewJobRepository(mockDB)
+
+	mock.ExpectQuery(`UPDATE jobs SET status = 'pending', attempts = 0, .* WHERE id = \$1 AND status IN \('dead', 'pending'\)`).
+		WithArgs(int64(5)).
+		WillReturnError(&pq.Error{Code: uniqueViolation})
+	_, err = repo.RetryJob(context.Background(), 5)
+	assert.ErrorIs(t, err, ErrDuplicateJob)
+
+	mock.ExpectQuery(`UPDATE jobs SET status = 'pending'`).WithArgs(int64(6)).WillReturnRows(sqlmock.NewRows(jobRowColumns))
+	_, err = repo.RetryJob(context.Background(), 6)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobRepository_RecoverStaleJobs(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewJobRepository(mockDB)
+
+	lockedBefore := time.Now().Add(-15 * time.Minute)
+	mock.ExpectExec(`UPDATE jobs SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'pending' END.* WHERE status = 'running' AND locked_at < \$1`).
+		WithArgs(lockedBefore).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	n, err := repo.RecoverStaleJobs(context.Background(), lockedBefore)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
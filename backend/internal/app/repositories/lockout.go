@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+)
+
+type LockoutRepository struct {
+	DB *sql.DB
+}
+
+func NewLockoutRepository(db *sql.DB) *LockoutRepository {
+	return &LockoutRepository{DB: db}
+}
+
+// CreateLockout records a new lockout event
+func (r *LockoutRepository) CreateLockout(ctx context.Context, lockout *models.Lockout) error {
+	query := `INSERT INTO login_lockouts (reason, email, ip, failures, locked_until) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	err := r.DB.QueryRowContext(ctx, query, lockout.Reason, lockout.Email, lockout.IP, lockout.Failures, lockout.LockedUntil).
+		Scan(&lockout.ID, &lockout.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create lockout: %w", err)
+	}
+	return nil
+}
+
+// GetActiveLockout returns the longest running lockout that blocks the
+// given account or IP address, or ErrNoLockout if there is none
+func (r *LockoutRepository) GetActiveLockout(ctx context.Context, email, ip string) (*models.Lockout, error) {
+	lockout := &models.Lockout{}
+	query := `SELECT id, reason, email, ip, failures, locked_until, created_at FROM login_lockouts
+		WHERE ((reason = 'account' AND email = $1) OR (reason = 'ip' AND ip = $2))
+		AND unlocked_at IS NULL AND locked_until > NOW()
+		ORDER BY locked_until DESC LIMIT 1`
+	err := r.DB.QueryRowContext(ctx, query, email, ip).
+		Scan(&lockout.ID, &lockout.Reason, &lockout.Email, &lockout.IP, &lockout.Failures, &lockout.LockedUntil, &lockout.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoLockout
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get active lockout: %w", err)
+	}
+	return lockout, nil
+}
+
+// UnlockAccount lifts every active account lockout for email
+func (r *LockoutRepository) UnlockAccount(ctx context.Context, email string, unlockedBy int) error {
+	query := `UPDATE login_lockouts SET unlocked_at = NOW(), unlocked_by = $1 WHERE reason = 'account' AND email = $2 AND unlocked_at IS NULL`
+	if _, err := r.DB.ExecContext(ctx, query, unlockedBy, email); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	return nil
+}
+
+var _ LockoutRepoInterface = (*LockoutRepository)(nil)
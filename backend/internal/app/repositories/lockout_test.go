@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutRepository_CreateLockout(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewLockoutRepository(mockDB)
+
+	lockout := &models.Lockout{
+		Reason:      models.LockoutReasonAccount,
+		Email:       "testuser@example.com",
+		IP:          "10.0.0.1",
+		Failures:    5,
+		LockedUntil: time.Now().Add(15 * time.Minute),
+	}
+
+	mock.ExpectQuery(`INSERT INTO login_lockouts .*`).
+		WithArgs(lockout.Reason, lockout.Email, lockout.IP, lockout.Failures, lockout.LockedUntil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+
+	err = repo.CreateLockout(context.Background(), lockout)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, lockout.ID)
+
+	// Test error scenario
+	mock.ExpectQuery(`INSERT INTO login_lockouts .*`).WillReturnError(errors.New("db error"))
+	err = repo.CreateLockout(context.Background(), lockout)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create lockout")
+}
+
+func TestLockoutRepository_GetActiveLockout(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewLockoutRepository(mockDB)
+
+	lockedUntil := time.Now().Add(time.Minute)
+	mock.ExpectQuery(`SELECT .* FROM login_lockouts WHERE .*`).
+		WithArgs("testuser@example.com", "10.0.0.1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "reason", "email", "ip", "failures", "locked_until", "created_at"}).
+			AddRow(1, "account", "testuser@example.com", "10.0.0.1", 5, lockedUntil, time.Now()))
+
+	lockout, err := repo.GetActiveLockout(context.Background(), "testuser@example.com", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.LockoutReasonAccount, lockout.Reason)
+	assert.True(t, lockedUntil.Equal(lockout.LockedUntil))
+
+	// Test not found scenario
+	mock.ExpectQuery(`SELECT .* FROM login_lockouts WHERE .*`).
+		WithArgs("testuser@example.com", "10.0.0.1").
+		WillReturnError(sql.ErrNoRows)
+
+	lockout, err = repo.GetActiveLockout(context.Background(), "testuser@example.com", "10.0.0.1")
+	assert.Nil(t, lockout)
+	assert.ErrorIs(t, err, ErrNoLockout)
+}
+
+func TestLockoutRepository_UnlockAccount(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewLockoutRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE login_lockouts SET unlocked_at = NOW\(\), unlocked_by = \$1 WHERE .*`).
+		WithArgs(-1, "testuser@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.UnlockAccount(context.Background(), "testuser@example.com", -1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+)
+
+const notificationColumns = `id, user_id, type, title, body, data, read_at, created_at`
+
+// scanNotification reads a row selected with notificationColumns into
+// notification.
+func scanNotification(row rowScanner, notification *models.Notification) error {
+	var data []byte
+	err := row.Scan(&notification.ID, &notification.UserID, &notification.Type, &notification.Title, &notification.Body,
+		&data, &notification.ReadAt, &notification.CreatedAt)
+	notification.Data = data
+	return err
+}
+
+type NotificationRepository struct {
+	DB *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{DB: db}
+}
+
+// CreateNotification adds a notification to the inbox of its user
+func (r *NotificationRepository) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	data := []byte(notification.Data)
+	if len(data) == 0 {
+		data = []byte("{}")
+	}
+	query := `INSERT INTO notifications (user_id, type, title, body, data) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+	err := r.DB.QueryRowContext(ctx, query, notification.UserID, notification.Type, notification.Title, notification.Body, data).
+		Scan(&notification.ID, &notification.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+// ListNotifications retrieves up to limit notifications of the user older
+// than beforeID, newest first. A zero beforeID starts with the newest
+// notification.
+func (r *NotificationRepository) ListNotifications(ctx context.Context, userID int, unreadOnly bool, beforeID int64, limit int) ([]models.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications
+		WHERE user_id = $1 AND ($2 = 0 OR id < $2) AND (NOT $3 OR read_at IS NULL)
+		ORDER BY id DESC LIMIT $4`
+	rows, err := r.DB.QueryContext(ctx, query, userID, beforeID, unreadOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		if err := scanNotification(rows, &notification); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return notifications, nil
+}
+
+// CountUnreadNotifications counts the unread notifications of the user
+func (r *NotificationRepository) CountUnreadNotifications(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkNotificationRead marks a notification of the user read, unless it
+// already is
+func (r *NotificationRepository) MarkNotificationRead(ctx context.Context, userID int, id int64) (*models.Notification, error) {
+	notification := &models.Notification{}
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2
+		RETURNING ` + notificationColumns
+	err := scanNotification(r.DB.QueryRowContext(ctx, query, id, userID), notification)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotificationNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	return notification, nil
+}
+
+// MarkAllNotificationsRead marks the unread notifications of the user up to
+// upToID read, or all of them when upToID is zero. It returns the number
+// of notifications marked.
+func (r *NotificationRepository) MarkAllNotificationsRead(ctx context.Context, userID int, upToID int64) (int64, error) {
+	query := `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL AND ($2 = 0 OR id <= $2)`
+	result, err := r.DB.ExecContext(ctx, query, userID, upToID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return n, nil
+}
+
+var _ NotificationRepoInterface = (*NotificationRepository)(nil)
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+type NotificationPreferenceRepository struct {
+	DB *sql.DB
+}
+
+func NewNotificationPreferenceRepository(db *sql.DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{DB: db}
+}
+
+// GetNotificationPreferences retrieves the preferences of the user, or the
+// defaults when the user has not set any.
+func (r *NotificationPreferenceRepository) GetNotificationPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error) {
+	prefs := &models.NotificationPreferences{UserID: userID}
+	query := `SELECT channels, timezone, quiet_hours_start, quiet_hours_end, digest_frequency FROM notification_preferences WHERE user_id = $1`
+	err := r.DB.QueryRowContext(ctx, query, userID).
+		Scan((*pq.StringArray)(&prefs.Channels), &prefs.Timezone, &prefs.QuietHoursStart, &prefs.QuietHoursEnd, &prefs.DigestFrequency)
+	if err == sql.ErrNoRows {
+		prefs.Channels = append([]string{}, models.DefaultNotificationChannels...)
+		prefs.Timezone = "UTC"
+		prefs.DigestFrequency = models.DigestOff
+		return prefs, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// SaveNotificationPreferences stores the preferences of a user
+func (r *NotificationPreferenceRepository) SaveNotificationPreferences(ctx context.Context, prefs *models.NotificationPreferences) error {
+	query := `INSERT INTO notification_preferences (user_id, channels, timezone, quiet_hours_start, quiet_hours_end, digest_frequency)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET channels = EXCLUDED.channels, timezone = EXCLUDED.timezone,
+			quiet_hours_start = EXCLUDED.quiet_hours_start, quiet_hours_end = EXCLUDED.quiet_hours_end,
+			digest_frequency = EXCLUDED.digest_frequency, updated_at = NOW()`
+	_, err := r.DB.ExecContext(ctx, query, prefs.UserID, pq.StringArray(prefs.Channels), prefs.Timezone, prefs.QuietHoursStart, prefs.QuietHoursEnd, prefs.DigestFrequency)
+	if err != nil {
+		return fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+	return nil
+}
+
+// SetDigestFrequency changes how often the user gets the email digest,
+// keeping their other preferences
+func (r *NotificationPreferenceRepository) SetDigestFrequency(ctx context.Context, userID int, frequency string) error {
+	query := `INSERT INTO notification_preferences (user_id, channels, digest_frequency)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET digest_frequency = EXCLUDED.digest_frequency, updated_at = NOW()`
+	_, err := r.DB.ExecContext(ctx, query, userID, pq.StringArray(models.DefaultNotificationChannels), frequency)
+	if err != nil {
+		return fmt.Errorf("failed to set digest frequency: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueDigests marks up to limit digests due at now as sent and returns
+// their recipients. Daily digests are due from hour o'clock in the timezone
+// of their user, and weekly ones from hour o'clock on Mondays; digests are
+// due once a day.
+func (r *NotificationPreferenceRepository) ClaimDueDigests(ctx context.Context, now time.Time, hour int, limit int) ([]models.DigestRecipient, error) {
+	query := `UPDATE notification_preferences SET digest_sent_on = ($1::timestamptz AT TIME ZONE timezone)::date
+		WHERE user_id IN (
+			SELECT user_id FROM notification_preferences
+			WHERE digest_frequency IN ($2, $3)
+				AND EXTRACT(HOUR FROM $1::timestamptz AT TIME ZONE timezone) >= $4
+				AND (digest_frequency = $2 OR EXTRACT(ISODOW FROM $1::timestamptz AT TIME ZONE timezone) = 1)
+				AND (digest_sent_on IS NULL OR digest_sent_on < ($1::timestamptz AT TIME ZONE timezone)::date)
+			ORDER BY user_id LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING user_id, digest_frequency, timezone, to_char(digest_sent_on, 'YYYY-MM-DD')`
+	rows, err := r.DB.QueryContext(ctx, query, now, models.DigestDaily, models.DigestWeekly, hour, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due digests: %w", err)
+	}
+	defer rows.Close()
+
+	var recipients []models.DigestRecipient
+	for rows.Next() {
+		var recipient models.DigestRecipient
+		if err := rows.Scan(&recipient.UserID, &recipient.Frequency, &recipient.Timezone, &recipient.Date); err != nil {
+			return nil, fmt.Errorf("failed to scan digest recipient: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return recipients, nil
+}
+
+var _ NotificationPreferenceRepoInterface = (*NotificationPreferenceRepository)(nil)
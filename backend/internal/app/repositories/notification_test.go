@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var notificationRowColumns = []string{"id", "user_id", "type", "title", "body", "data", "read_at", "created_at"}
+
+func TestNotificationRepository_CreateAndListNotifications(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewNotificationRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO notifications \(user_id, type, title, body, data\)`).
+		WithArgs(1, models.EventTodoOverdue, `"Pay rent" is overdue`, "", []byte("{}")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(12, now))
+	notification := &models.Notification{UserID: 1, Type: models.EventTodoOverdue, Title: `"Pay rent" is overdue`}
+	require.NoError(t, repo.CreateNotification(context.Background(), notification))
+	assert.Equal(t, int64(12), notification.ID)
+
+	mock.ExpectQuery(`SELECT .* FROM notifications WHERE user_id = \$1 AND \(\$2 = 0 OR id < \$2\) AND \(NOT \$3 OR read_at IS NULL\)`).
+		WithArgs(1, int64(20), true, 10).
+		WillReturnRows(sqlmock.NewRows(notificationRowColumns).
+			AddRow(12, 1, models.EventTodoOverdue, `"Pay rent" is overdue`, "", []byte(`{"id":3}`), nil, now))
+	notifications, err := repo.ListNotifications(context.Background(), 1, true, 20, 10)
+	require.NoError(t, err)
+	if assert.Len(t, notifications, 1) {
+		assert.JSONEq(t, `{"id":3}`, string(notifications[0].Data))
+		assert.Nil(t, notifications[0].ReadAt)
+	}
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM notifications WHERE user_id = \$1 AND read_at IS NULL`).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	count, err := repo.CountUnreadNotifications(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotificationRepository_MarkRead(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewNotificationRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE notifications SET read_at = COALESCE\(read_at, NOW\(\)\) WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(int64(12), 1).
+		WillReturnRows(sqlmock.NewRows(notificationRowColumns).
+			AddRow(12, 1, models.EventTodoOverdue, "title", "", []byte(`{}`), now, now))
+	notification, err := repo.MarkNotificationRead(context.Background(), 1, 12)
+	require.NoError(t, err)
+	assert.NotNil(t, notification.ReadAt)
+
+	mock.ExpectQuery(`UPDATE notifications SET read_at`).WithArgs(int64(13), 1).WillReturnRows(sqlmock.NewRows(notificationRowColumns))
+	_, err = repo.MarkNotificationRead(context.Background(), 1, 13)
+	assert.ErrorIs(t, err, ErrNotificationNotFound)
+
+	mock.ExpectExec(`UPDATE notifications SET read_at = NOW\(\) WHERE user_id = \$1 AND read_at IS NULL AND \(\$2 = 0 OR id <= \$2\)`).
+		WithArgs(1, int64(12)).WillReturnResult(sqlmock.NewResult(0, 3))
+	n, err := repo.MarkAllNotificationsRead(context.Background(), 1, 12)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,277 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+// oauthClientColumns lists the columns read by scanOAuthClient, in order.
+const oauthClientColumns = `id, client_id, secret_hash, owner_id, name, redirect_uris, created_at`
+
+// scanOAuthClient reads a row selected with oauthClientColumns into client.
+func scanOAuthClient(row rowScanner, client *models.OAuthClient) error {
+	var secretHash sql.NullString
+	err := row.Scan(&client.ID, &client.ClientID, &secretHash, &client.OwnerID, &client.Name,
+		(*pq.StringArray)(&client.RedirectURIs), &client.CreatedAt)
+	client.SecretHash, client.Confidential = secretHash.String, secretHash.Valid
+	return err
+}
+
+// oauthTokenColumns lists the columns of oauth_tokens t joined with
+// oauth_grants g read by scanOAuthToken, in order.
+const oauthTokenColumns = `t.id, t.grant_id, g.user_id, g.client_id, t.access_token_hash, t.refresh_token_hash,
+	t.scopes, t.access_expires_at, t.refresh_expires_at, t.last_used_at`
+
+// scanOAuthToken reads a row selected with oauthTokenColumns into token.
+func scanOAuthToken(row rowScanner, token *models.OAuthToken) error {
+	return row.Scan(&token.ID, &token.GrantID, &token.UserID, &token.ClientID, &token.AccessTokenHash, &token.RefreshTokenHash,
+		(*pq.StringArray)(&token.Scopes), &token.AccessExpiresAt, &token.RefreshExpiresAt, &token.LastUsedAt)
+}
+
+type OAuthRepository struct {
+	DB *sql.DB
+}
+
+func NewOAuthRepository(db *sql.DB) *OAuthRepository {
+	return &OAuthRepository{DB: db}
+}
+
+// CreateClient registers a new client. Public clients have an empty
+// SecretHash
+func (r *OAuthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	secretHash := sql.NullString{String: client.SecretHash, Valid: client.SecretHash != ""}
+	query := `INSERT INTO oauth_clients (client_id, secret_hash, owner_id, name, redirect_uris)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	err := r.DB.QueryRowContext(ctx, query, client.ClientID, secretHash, client.OwnerID, client.Name, pq.StringArray(client.RedirectURIs)).
+		Scan(&client.ID, &client.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth client: %w", err)
+	}
+	client.Confidential = secretHash.Valid
+	return nil
+}
+
+// GetClient retrieves a client by its public identifier
+func (r *OAuthRepository) GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	client := &models.OAuthClient{}
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE client_id = $1`
+	err := scanOAuthClient(r.DB.QueryRowContext(ctx, query, clientID), client)
+	if err == sql.ErrNoRows {
+		return nil, ErrOAuthClientNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+	return client, nil
+}
+
+// ListClients retrieves the clients registered by a user, newest first
+func (r *OAuthRepository) ListClients(ctx context.Context, ownerID int) ([]models.OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE owner_id = $1 ORDER BY created_at DESC, id DESC`
+	rows, err := r.DB.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list oauth clients: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []models.OAuthClient{}
+	for rows.Next() {
+		var client models.OAuthClient
+		if err := scanOAuthClient(rows, &client); err != nil {
+			return nil, fmt.Errorf("failed to scan oauth client: %w", err)
+		}
+		clients = append(clients, client)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return clients, nil
+}
+
+// DeleteClient removes a client registered by a user, together with every
+// grant and token issued to it
+func (r *OAuthRepository) DeleteClient(ctx context.Context, ownerID int, clientID string) error {
+	query := `DELETE FROM oauth_clients WHERE client_id = $1 AND owner_id = $2`
+	result, err := r.DB.ExecContext(ctx, query, clientID, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete oauth client: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected during delete: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+// GetGrant retrieves the scopes a user has given a client
+func (r *OAuthRepository) GetGrant(ctx context.Context, userID int, clientID int) (*models.OAuthGrant, error) {
+	grant := &models.OAuthGrant{}
+	query := `SELECT id, user_id, client_id, scopes, created_at, updated_at FROM oauth_grants WHERE user_id = $1 AND client_id = $2`
+	err := r.DB.QueryRowContext(ctx, query, userID, clientID).
+		Scan(&grant.ID, &grant.UserID, &grant.ClientID, (*pq.StringArray)(&grant.Scopes), &grant.CreatedAt, &grant.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrOAuthGrantNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get oauth grant: %w", err)
+	}
+	return grant, nil
+}
+
+// SaveGrant creates the grant of a user to a client, or replaces its scopes
+// if one exists
+func (r *OAuthRepository) SaveGrant(ctx context.Context, grant *models.OAuthGrant) error {
+	query := `INSERT INTO oauth_grants (user_id, client_id, scopes) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes, updated_at = NOW()
+		RETURNING id, created_at, updated_at`
+	err := r.DB.QueryRowContext(ctx, query, grant.UserID, grant.ClientID, pq.StringArray(grant.Scopes)).
+		Scan(&grant.ID, &grant.CreatedAt, &grant.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save oauth grant: %w", err)
+	}
+	return nil
+}
+
+// ListAuthorizedApps retrieves the clients a user has given access to, most
+// recently authorized first
+func (r *OAuthRepository) ListAuthorizedApps(ctx context.Context, userID int) ([]models.AuthorizedApp, error) {
+	query := `SELECT c.client_id, c.name, g.scopes, g.updated_at, MAX(t.last_used_at)
+		FROM oauth_grants g
+		JOIN oauth_clients c ON c.id = g.client_id
+		LEFT JOIN oauth_tokens t ON t.grant_id = g.id
+		WHERE g.user_id = $1
+		GROUP BY g.id, c.id
+		ORDER BY g.updated_at DESC, g.id DESC`
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list authorized apps: %w", err)
+	}
+	defer rows.Close()
+
+	apps := []models.AuthorizedApp{}
+	for rows.Next() {
+		var app models.AuthorizedApp
+		if err := rows.Scan(&app.ClientID, &app.Name, (*pq.StringArray)(&app.Scopes), &app.AuthorizedAt, &app.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan authorized app: %w", err)
+		}
+		apps = append(apps, app)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return apps, nil
+}
+
+// DeleteGrant revokes the access of a client to the account of a user,
+// together with every code and token issued under the grant
+func (r *OAuthRepository) DeleteGrant(ctx context.Context, userID int, clientID string) error {
+	query := `DELETE FROM oauth_grants g USING oauth_clients c
+		WHERE g.client_id = c.id AND g.user_id = $1 AND c.client_id = $2`
+	result, err := r.DB.ExecContext(ctx, query, userID, clientID)
+	if err != nil {
+		return fmt.Errorf("failed to delete oauth grant: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected during delete: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrOAuthGrantNotFound
+	}
+	return nil
+}
+
+// CreateAuthorizationCode stores a new authorization code, discarding codes
+// that were never redeemed
+func (r *OAuthRepository) CreateAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM oauth_authorization_codes WHERE expires_at <= NOW()`); err != nil {
+		return fmt.Errorf("failed to delete expired authorization codes: %w", err)
+	}
+
+	query := `INSERT INTO oauth_authorization_codes (code_hash, grant_id, redirect_uri, scopes, code_challenge, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.DB.ExecContext(ctx, query, code.CodeHash, code.GrantID, code.RedirectURI, pq.StringArray(code.Scopes), code.CodeChallenge, code.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create authorization code: %w", err)
+	}
+	return nil
+}
+
+// ConsumeAuthorizationCode atomically removes an unexpired authorization
+// code and returns it, so that each code can be redeemed at most once
+func (r *OAuthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error) {
+	code := &models.OAuthAuthorizationCode{}
+	query := `DELETE FROM oauth_authorization_codes c USING oauth_grants g
+		WHERE c.code_hash = $1 AND c.expires_at > NOW() AND g.id = c.grant_id
+		RETURNING c.code_hash, c.grant_id, g.user_id, g.client_id, c.redirect_uri, c.scopes, c.code_challenge, c.expires_at`
+	err := r.DB.QueryRowContext(ctx, query, codeHash).Scan(&code.CodeHash, &code.GrantID, &code.UserID, &code.ClientID,
+		&code.RedirectURI, (*pq.StringArray)(&code.Scopes), &code.CodeChallenge, &code.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrOAuthCodeNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to consume authorization code: %w", err)
+	}
+	return code, nil
+}
+
+// CreateToken stores a new token pair, discarding pairs whose refresh token
+// has expired
+func (r *OAuthRepository) CreateToken(ctx context.Context, token *models.OAuthToken) error {
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM oauth_tokens WHERE refresh_expires_at <= NOW()`); err != nil {
+		return fmt.Errorf("failed to delete expired oauth tokens: %w", err)
+	}
+
+	query := `INSERT INTO oauth_tokens (grant_id, access_token_hash, refresh_token_hash, scopes, access_expires_at, refresh_expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	err := r.DB.QueryRowContext(ctx, query, token.GrantID, token.AccessTokenHash, token.RefreshTokenHash,
+		pq.StringArray(token.Scopes), token.AccessExpiresAt, token.RefreshExpiresAt).Scan(&token.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth token: %w", err)
+	}
+	return nil
+}
+
+// ConsumeRefreshToken atomically removes the token pair with an unexpired
+// refresh token and returns it, so that each refresh token can be used at
+// most once
+func (r *OAuthRepository) ConsumeRefreshToken(ctx context.Context, refreshTokenHash string) (*models.OAuthToken, error) {
+	token := &models.OAuthToken{}
+	query := `DELETE FROM oauth_tokens t USING oauth_grants g
+		WHERE t.refresh_token_hash = $1 AND t.refresh_expires_at > NOW() AND g.id = t.grant_id
+		RETURNING ` + oauthTokenColumns
+	err := scanOAuthToken(r.DB.QueryRowContext(ctx, query, refreshTokenHash), token)
+	if err == sql.ErrNoRows {
+		return nil, ErrOAuthTokenNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to consume refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// UseAccessToken looks up the token pair with an unexpired access token and
+// records that it was used
+func (r *OAuthRepository) UseAccessToken(ctx context.Context, accessTokenHash string) (*models.OAuthToken, error) {
+	token := &models.OAuthToken{}
+	query := `UPDATE oauth_tokens t SET last_used_at = NOW() FROM oauth_grants g
+		WHERE t.access_token_hash = $1 AND t.access_expires_at > NOW() AND g.id = t.grant_id
+		AND g.user_id IN (SELECT id FROM users WHERE deactivated_at IS NULL)
+		RETURNING ` + oauthTokenColumns
+	err := scanOAuthToken(r.DB.QueryRowContext(ctx, query, accessTokenHash), token)
+	if err == sql.ErrNoRows {
+		return nil, ErrOAuthTokenNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to use access token: %w", err)
+	}
+	return token, nil
+}
+
+var _ OAuthRepoInterface = (*OAuthRepository)(nil)
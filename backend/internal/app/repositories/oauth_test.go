@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var oauthClientRowColumns = []string{"id", "client_id", "secret_hash", "owner_id", "name", "redirect_uris", "created_at"}
+
+var oauthTokenRowColumns = []string{"id", "grant_id", "user_id", "client_id", "access_token_hash", "refresh_token_hash",
+	"scopes", "access_expires_at", "refresh_expires_at", "last_used_at"}
+
+func TestOAuthRepository_CreateClient(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOAuthRepository(mockDB)
+
+	client := &models.OAuthClient{ClientID: "client-1", OwnerID: 5, Name: "SPA", RedirectURIs: []string{"https://app.example.com/cb"}}
+	mock.ExpectQuery(`INSERT INTO oauth_clients .*`).
+		WithArgs("client-1", nil, 5, "SPA", `{"https://app.example.com/cb"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, time.Now()))
+
+	err = repo.CreateClient(context.Background(), client)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, client.ID)
+	assert.False(t, client.Confidential)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOAuthRepository_GetClient(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOAuthRepository(mockDB)
+
+	mock.ExpectQuery(`SELECT .* FROM oauth_clients WHERE client_id = \$1`).
+		WithArgs("client-1").
+		WillReturnRows(sqlmock.NewRows(oauthClientRowColumns).
+			AddRow(2, "client-1", "hash", 5, "Server", `{https://a.example.com/cb,https://b.example.com/cb}`, time.Now()))
+
+	client, err := repo.GetClient(context.Background(), "client-1")
+	assert.NoError(t, err)
+	assert.True(t, client.Confidential)
+	assert.Equal(t, "hash", client.SecretHash)
+	assert.Equal(t, []string{"https://a.example.com/cb", "https://b.example.com/cb"}, client.RedirectURIs)
+
+	mock.ExpectQuery(`SELECT .* FROM oauth_clients WHERE client_id = \$1`).
+		WithArgs("unknown").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.GetClient(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrOAuthClientNotFound)
+}
+
+func TestOAuthRepository_SaveGrant(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOAuthRepository(mockDB)
+
+	now := time.Now()
+	grant := &models.OAuthGrant{UserID: 1, ClientID: 2, Scopes: []string{models.ScopeTodosRead}}
+	mock.ExpectQuery(`INSERT INTO oauth_grants .* ON CONFLICT \(user_id, client_id\) DO UPDATE`).
+		WithArgs(1, 2, `{"todos:read"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(4, now, now))
+
+	err = repo.SaveGrant(context.Background(), grant)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, grant.ID)
+}
+
+func TestOAuthRepository_DeleteGrant(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOAuthRepository(mockDB)
+
+	mock.ExpectExec(`DELETE FROM oauth_grants g USING oauth_clients c`).
+		WithArgs(1, "client-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.DeleteGrant(context.Background(), 1, "client-1"))
+
+	mock.ExpectExec(`DELETE FROM oauth_grants g USING oauth_clients c`).
+		WithArgs(1, "other").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.DeleteGrant(context.Background(), 1, "other"), ErrOAuthGrantNotFound)
+}
+
+func TestOAuthRepository_ConsumeAuthorizationCode(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOAuthRepository(mockDB)
+
+	mock.ExpectQuery(`DELETE FROM oauth_authorization_codes c USING oauth_grants g .* RETURNING`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"code_hash", "grant_id", "user_id", "client_id", "redirect_uri", "scopes", "code_challenge", "expires_at"}).
+			AddRow("hash", 4, 1, 2, "https://app.example.com/cb", `{todos:read}`, "challenge", time.Now().Add(time.Minute)))
+
+	code, err := repo.ConsumeAuthorizationCode(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, code.UserID)
+	assert.Equal(t, 2, code.ClientID)
+	assert.Equal(t, []string{models.ScopeTodosRead}, code.Scopes)
+
+	mock.ExpectQuery(`DELETE FROM oauth_authorization_codes`).
+		WithArgs("hash").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.ConsumeAuthorizationCode(context.Background(), "hash")
+	assert.ErrorIs(t, err, ErrOAuthCodeNotFound)
+}
+
+func TestOAuthRepository_CreateToken(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOAuthRepository(mockDB)
+
+	now := time.Now()
+	token := &models.OAuthToken{GrantID: 4, AccessTokenHash: "a", RefreshTokenHash: "r", Scopes: []string{models.ScopeTodosRead},
+		AccessExpiresAt: now.Add(time.Hour), RefreshExpiresAt: now.Add(24 * time.Hour)}
+
+	mock.ExpectExec(`DELETE FROM oauth_tokens WHERE refresh_expires_at <= NOW\(\)`).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery(`INSERT INTO oauth_tokens .*`).
+		WithArgs(4, "a", "r", `{"todos:read"}`, token.AccessExpiresAt, token.RefreshExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+
+	assert.NoError(t, repo.CreateToken(context.Background(), token))
+	assert.Equal(t, 8, token.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOAuthRepository_UseAccessToken(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOAuthRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE oauth_tokens t SET last_used_at = NOW\(\) FROM oauth_grants g .* RETURNING`).
+		WithArgs("a").
+		WillReturnRows(sqlmock.NewRows(oauthTokenRowColumns).
+			AddRow(8, 4, 1, 2, "a", "r", `{todos:read,todos:write}`, now.Add(time.Hour), now.Add(24*time.Hour), now))
+
+	token, err := repo.UseAccessToken(context.Background(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, token.UserID)
+	assert.Equal(t, []string{models.ScopeTodosRead, models.ScopeTodosWrite}, token.Scopes)
+
+	mock.ExpectQuery(`UPDATE oauth_tokens`).
+		WithArgs("expired").
+		WillReturnError(sql.ErrNoRows)
+	_, err = repo.UseAccessToken(context.Background(), "expired")
+	assert.ErrorIs(t, err, ErrOAuthTokenNotFound)
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+type OIDCRepository struct {
+	DB *sql.DB
+}
+
+func NewOIDCRepository(db *sql.DB) *OIDCRepository {
+	return &OIDCRepository{DB: db}
+}
+
+// CreateLoginState stores the secrets of a new login, discarding those of
+// logins that were never completed
+func (r *OIDCRepository) CreateLoginState(ctx context.Context, state *models.OIDCLoginState) error {
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at <= NOW()`); err != nil {
+		return fmt.Errorf("failed to delete expired login states: %w", err)
+	}
+
+	query := `INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	_, err := r.DB.ExecContext(ctx, query, state.StateHash, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create login state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeLoginState atomically removes an unexpired login state and returns
+// it, so that each login can be completed at most once
+func (r *OIDCRepository) ConsumeLoginState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	state := &models.OIDCLoginState{}
+	query := `DELETE FROM oidc_login_states WHERE state_hash = $1 AND expires_at > NOW()
+		RETURNING state_hash, provider, nonce, code_verifier, expires_at`
+	err := r.DB.QueryRowContext(ctx, query, stateHash).
+		Scan(&state.StateHash, &state.Provider, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrLoginStateNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to consume login state: %w", err)
+	}
+	return state, nil
+}
+
+// GetIdentity retrieves the identity with the given subject at a provider
+func (r *OIDCRepository) GetIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{}
+	query := `SELECT id, user_id, provider, subject, email, last_login_at, created_at FROM user_identities WHERE provider = $1 AND subject = $2`
+	err := r.DB.QueryRowContext(ctx, query, provider, subject).
+		Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.LastLoginAt, &identity.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrIdentityNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return identity, nil
+}
+
+// CreateIdentity links an identity to an existing user
+func (r *OIDCRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	return createIdentity(ctx, r.DB, identity)
+}
+
+// RecordIdentityLogin updates the email and last login time of an identity
+func (r *OIDCRepository) RecordIdentityLogin(ctx context.Context, id int, email string) error {
+	query := `UPDATE user_identities SET email = $1, last_login_at = NOW() WHERE id = $2`
+	result, err := r.DB.ExecContext(ctx, query, email, id)
+	if err != nil {
+		return fmt.Errorf("failed to record identity login: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected during update: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+// CreateUserWithIdentity creates a user who signs in through a provider
+// together with their identity. The email address is verified by the
+// provider and the user has no password until they set one.
+func (r *OIDCRepository) CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO users (name, email, password, email_verified_at, approval_pending) VALUES ($1, $2, '', NOW(), $3) RETURNING id, created_at`
+	err = tx.QueryRowContext(ctx, query, user.Name, user.Email, user.PendingApproval).Scan(&user.ID, &user.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrDuplicateEmail
+	} else if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	user.EmailVerified = true
+
+	identity.UserID = user.ID
+	if err := createIdentity(ctx, tx, identity); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryRower is implemented by *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func createIdentity(ctx context.Context, db queryRower, identity *models.UserIdentity) error {
+	query := `INSERT INTO user_identities (user_id, provider, subject, email, last_login_at) VALUES ($1, $2, $3, $4, NOW()) RETURNING id, created_at`
+	err := db.QueryRowContext(ctx, query, identity.UserID, identity.Provider, identity.Subject, identity.Email).
+		Scan(&identity.ID, &identity.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrDuplicateIdentity
+	} else if err != nil {
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+	return nil
+}
+
+var _ OIDCRepoInterface = (*OIDCRepository)(nil)
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOIDCRepository_ConsumeLoginState(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOIDCRepository(mockDB)
+
+	expires := time.Now().Add(time.Minute)
+	mock.ExpectQuery(`DELETE FROM oidc_login_states WHERE state_hash = \$1 AND expires_at > NOW\(\) RETURNING .*`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"state_hash", "provider", "nonce", "code_verifier", "expires_at"}).
+			AddRow("hash", "corp", "nonce", "verifier", expires))
+
+	state, err := repo.ConsumeLoginState(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, "corp", state.Provider)
+	assert.Equal(t, "verifier", state.CodeVerifier)
+
+	mock.ExpectQuery(`DELETE FROM oidc_login_states .*`).
+		WithArgs("hash").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.ConsumeLoginState(context.Background(), "hash")
+	assert.ErrorIs(t, err, ErrLoginStateNotFound)
+}
+
+func TestOIDCRepository_GetIdentity_NotFound(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOIDCRepository(mockDB)
+
+	mock.ExpectQuery(`SELECT .* FROM user_identities WHERE provider = \$1 AND subject = \$2`).
+		WithArgs("corp", "42").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.GetIdentity(context.Background(), "corp", "42")
+	assert.ErrorIs(t, err, ErrIdentityNotFound)
+}
+
+func TestOIDCRepository_CreateUserWithIdentity(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOIDCRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users \(name, email, password, email_verified_at, approval_pending\) VALUES \(\$1, \$2, '', NOW\(\), \$3\)`).
+		WithArgs("Jane", "jane@example.com", false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, now))
+	mock.ExpectQuery(`INSERT INTO user_identities .*`).
+		WithArgs(7, "corp", "42", "jane@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now))
+	mock.ExpectCommit()
+
+	user := &models.User{Name: "Jane", Email: "jane@example.com"}
+	identity := &models.UserIdentity{Provider: "corp", Subject: "42", Email: "jane@example.com"}
+	err = repo.CreateUserWithIdentity(context.Background(), user, identity)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, user.ID)
+	assert.True(t, user.EmailVerified)
+	assert.Equal(t, 7, identity.UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOIDCRepository_CreateUserWithIdentity_DuplicateEmail(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewOIDCRepository(mockDB)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users .*`).
+		WithArgs("Jane", "jane@example.com", false).
+		WillReturnError(&pq.Error{Code: uniqueViolation})
+	mock.ExpectRollback()
+
+	err = repo.CreateUserWithIdentity(context.Background(), &models.User{Name: "Jane", Email: "jane@example.com"}, &models.UserIdentity{})
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
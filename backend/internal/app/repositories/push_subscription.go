@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+)
+
+type PushSubscriptionRepository struct {
+	DB *sql.DB
+}
+
+func NewPushSubscriptionRepository(db *sql.DB) *PushSubscriptionRepository {
+	return &PushSubscriptionRepository{DB: db}
+}
+
+// SavePushSubscription stores a subscription. A browser subscribing again
+// replaces its previous subscription, even one of another user.
+func (r *PushSubscriptionRepository) SavePushSubscription(ctx context.Context, sub *models.PushSubscription) error {
+	query := `INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (endpoint) DO UPDATE SET user_id = EXCLUDED.user_id, p256dh = EXCLUDED.p256dh, auth = EXCLUDED.auth,
+			created_at = NOW()
+		RETURNING id, created_at`
+	err := r.DB.QueryRowContext(ctx, query, sub.UserID, sub.Endpoint, sub.P256dh, sub.Auth).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save push subscription: %w", err)
+	}
+	return nil
+}
+
+// ListPushSubscriptions retrieves the subscriptions of the user
+func (r *PushSubscriptionRepository) ListPushSubscriptions(ctx context.Context, userID int) ([]models.PushSubscription, error) {
+	query := `SELECT id, user_id, endpoint, p256dh, auth, created_at FROM push_subscriptions WHERE user_id = $1 ORDER BY id`
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list push subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []models.PushSubscription{}
+	for rows.Next() {
+		var sub models.PushSubscription
+		if err := rows.Scan(&sub.ID, &sub.UserID, &sub.Endpoint, &sub.P256dh, &sub.Auth, &sub.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan push subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return subs, nil
+}
+
+// DeletePushSubscription removes a subscription of the user
+func (r *PushSubscriptionRepository) DeletePushSubscription(ctx context.Context, userID int, id int) error {
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM push_subscriptions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	} else if n == 0 {
+		return ErrPushSubscriptionNotFound
+	}
+	return nil
+}
+
+// DeletePushSubscriptionByEndpoint removes the subscription of an endpoint
+// that the push service no longer knows
+func (r *PushSubscriptionRepository) DeletePushSubscriptionByEndpoint(ctx context.Context, endpoint string) error {
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM push_subscriptions WHERE endpoint = $1`, endpoint); err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+	return nil
+}
+
+var _ PushSubscriptionRepoInterface = (*PushSubscriptionRepository)(nil)
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushSubscriptionRepository(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewPushSubscriptionRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO push_subscriptions .* ON CONFLICT \(endpoint\) DO UPDATE`).
+		WithArgs(1, "https://push.example.com/abc", "key", "secret").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, now))
+	sub := &models.PushSubscription{UserID: 1, Endpoint: "https://push.example.com/abc", P256dh: "key", Auth: "secret"}
+	require.NoError(t, repo.SavePushSubscription(context.Background(), sub))
+	assert.Equal(t, 5, sub.ID)
+
+	mock.ExpectQuery(`SELECT id, user_id, endpoint, p256dh, auth, created_at FROM push_subscriptions WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "endpoint", "p256dh", "auth", "created_at"}).
+			AddRow(5, 1, "https://push.example.com/abc", "key", "secret", now))
+	subs, err := repo.ListPushSubscriptions(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []models.PushSubscription{*sub}, subs)
+
+	mock.ExpectExec(`DELETE FROM push_subscriptions WHERE id = \$1 AND user_id = \$2`).WithArgs(5, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.DeletePushSubscription(context.Background(), 2, 5), ErrPushSubscriptionNotFound)
+
+	mock.ExpectExec(`DELETE FROM push_subscriptions WHERE endpoint = \$1`).WithArgs("https://push.example.com/abc").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.DeletePushSubscriptionByEndpoint(context.Background(), "https://push.example.com/abc"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"todo_app_backend/internal/app/models"
+)
+
+// reminderFireAt is when reminder r of todo t goes off.
+const reminderFireAt = `COALESCE(r.snoozed_until, r.remind_at, t.due_date - r.before_minutes * INTERVAL '1 minute')`
+
+// reminderColumns lists the columns of reminder r of todo t read by
+// scanReminder, in order.
+const reminderColumns = `r.id, r.todo_id, r.user_id, r.remind_at, r.before_minutes, r.snoozed_until, ` +
+	reminderFireAt + `, r.fired_at, r.created_at`
+
+// scanReminder reads a row selected with reminderColumns into reminder.
+func scanReminder(row rowScanner, reminder *models.Reminder) error {
+	return row.Scan(&reminder.ID, &reminder.TodoID, &reminder.UserID, &reminder.RemindAt, &reminder.BeforeMinutes,
+		&reminder.SnoozedUntil, &reminder.FireAt, &reminder.FiredAt, &reminder.CreatedAt)
+}
+
+type ReminderRepository struct {
+	DB *sql.DB
+}
+
+func NewReminderRepository(db *sql.DB) *ReminderRepository {
+	return &ReminderRepository{DB: db}
+}
+
+// CreateReminder stores a new reminder of a todo of the user.
+// ErrTodoNotFound is returned when the user has no such todo.
+func (r *ReminderRepository) CreateReminder(ctx context.Context, reminder *models.Reminder) error {
+	query := `WITH r AS (
+			INSERT INTO reminders (todo_id, user_id, remind_at, before_minutes)
+			SELECT id, user_id, $3, $4 FROM todos WHERE id = $1 AND user_id = $2
+			RETURNING *
+		)
+		SELECT ` + reminderColumns + ` FROM r JOIN todos t ON t.id = r.todo_id`
+	row := r.DB.QueryRowContext(ctx, query, reminder.TodoID, reminder.UserID, reminder.RemindAt, reminder.BeforeMinutes)
+	err := scanReminder(row, reminder)
+	if err == sql.ErrNoRows {
+		return ErrTodoNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to create reminder: %w", err)
+	}
+	return nil
+}
+
+// ListReminders retrieves the reminders of a todo of the user, soonest first
+func (r *ReminderRepository) ListReminders(ctx context.Context, userID int, todoID int) ([]models.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders r JOIN todos t ON t.id = r.todo_id
+		WHERE r.todo_id = $1 AND r.user_id = $2 ORDER BY 7 NULLS LAST, r.id`
+	rows, err := r.DB.QueryContext(ctx, query, todoID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reminders: %w", err)
+	}
+	defer rows.Close()
+
+	reminders := []models.Reminder{}
+	for rows.Next() {
+		var reminder models.Reminder
+		if err := scanReminder(rows, &reminder); err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return reminders, nil
+}
+
+// DeleteReminder removes a reminder of a todo of the user
+func (r *ReminderRepository) DeleteReminder(ctx context.Context, userID int, todoID int, id int) error {
+	result, err := r.DB.ExecContext(ctx, `DELETE FROM reminders WHERE id = $1 AND todo_id = $2 AND user_id = $3`, id, todoID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete reminder: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete reminder: %w", err)
+	} else if n == 0 {
+		return ErrReminderNotFound
+	}
+	return nil
+}
+
+// SnoozeReminder postpones a reminder of a todo of the user until the given
+// time, so that it goes off again then.
+func (r *ReminderRepository) SnoozeReminder(ctx context.Context, userID int, todoID int, id int, until time.Time) (*models.Reminder, error) {
+	reminder := &models.Reminder{}
+	query := `UPDATE reminders r SET snoozed_until = $4, updated_at = NOW() FROM todos t
+		WHERE r.id = $1 AND r.todo_id = $2 AND r.user_id = $3 AND t.id = r.todo_id
+		RETURNING ` + reminderColumns
+	err := scanReminder(r.DB.QueryRowContext(ctx, query, id, todoID, userID, until), reminder)
+	if err == sql.ErrNoRows {
+		return nil, ErrReminderNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to snooze reminder: %w", err)
+	}
+	return reminder, nil
+}
+
+// ClaimDueReminders marks up to limit reminders that went off between since
+// and now as fired and returns them. Reminders of completed todos are skipped.
+func (r *ReminderRepository) ClaimDueReminders(ctx context.Context, since, now time.Time, limit int) ([]models.DueReminder, error) {
+	query := `UPDATE reminders r SET fired_at = due.fire_at, updated_at = NOW()
+		FROM (
+			SELECT r.id, ` + reminderFireAt + ` AS fire_at FROM reminders r JOIN todos t ON t.id = r.todo_id
+			WHERE t.status <> $4 AND ` + reminderFireAt + ` > $1 AND ` + reminderFireAt + ` <= $2
+				AND r.fired_at IS DISTINCT FROM ` + reminderFireAt + `
+			ORDER BY fire_at LIMIT $3
+			FOR UPDATE OF r SKIP LOCKED
+		) due, todos t
+		WHERE r.id = due.id AND t.id = r.todo_id
+		RETURNING r.id, r.todo_id, r.user_id, t.title, t.due_date, due.fire_at`
+	rows, err := r.DB.QueryContext(ctx, query, since, now, limit, models.TodoCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var reminders []models.DueReminder
+	for rows.Next() {
+		var reminder models.DueReminder
+		if err := rows.Scan(&reminder.ReminderID, &reminder.TodoID, &reminder.UserID, &reminder.Title, &reminder.DueDate, &reminder.FireAt); err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return reminders, nil
+}
+
+var _ ReminderRepoInterface = (*ReminderRepository)(nil)
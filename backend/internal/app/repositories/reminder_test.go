@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var reminderRowColumns = []string{"id", "todo_id", "user_id", "remind_at", "before_minutes", "snoozed_until", "fire_at", "fired_at", "created_at"}
+
+func TestReminderRepository_CreateReminder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewReminderRepository(mockDB)
+
+	before := 30
+	fireAt := time.Date(2024, 5, 15, 9, 30, 0, 0, time.UTC)
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO reminders .* SELECT id, user_id, \$3, \$4 FROM todos WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(3, 1, nil, &before).
+		WillReturnRows(sqlmock.NewRows(reminderRowColumns).AddRow(7, 3, 1, nil, 30, nil, fireAt, nil, now))
+
+	reminder := &models.Reminder{TodoID: 3, UserID: 1, BeforeMinutes: &before}
+	require.NoError(t, repo.CreateReminder(context.Background(), reminder))
+	assert.Equal(t, 7, reminder.ID)
+	assert.Equal(t, fireAt, *reminder.FireAt)
+
+	// Todos of other users are not found
+	mock.ExpectQuery(`INSERT INTO reminders`).WithArgs(4, 1, nil, &before).WillReturnRows(sqlmock.NewRows(reminderRowColumns))
+	err = repo.CreateReminder(context.Background(), &models.Reminder{TodoID: 4, UserID: 1, BeforeMinutes: &before})
+	assert.ErrorIs(t, err, ErrTodoNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReminderRepository_SnoozeAndDeleteReminder(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewReminderRepository(mockDB)
+
+	until := time.Date(2024, 5, 15, 9, 40, 0, 0, time.UTC)
+	mock.ExpectQuery(`UPDATE reminders r SET snoozed_until = \$4`).WithArgs(7, 3, 1, until).
+		WillReturnRows(sqlmock.NewRows(reminderRowColumns).AddRow(7, 3, 1, until.Add(-time.Hour), nil, until, until, nil, time.Now()))
+	reminder, err := repo.SnoozeReminder(context.Background(), 1, 3, 7, until)
+	require.NoError(t, err)
+	assert.Equal(t, until, *reminder.FireAt)
+
+	mock.ExpectQuery(`UPDATE reminders r SET snoozed_until = \$4`).WithArgs(8, 3, 1, until).WillReturnRows(sqlmock.NewRows(reminderRowColumns))
+	_, err = repo.SnoozeReminder(context.Background(), 1, 3, 8, until)
+	assert.ErrorIs(t, err, ErrReminderNotFound)
+
+	mock.ExpectExec(`DELETE FROM reminders WHERE id = \$1 AND todo_id = \$2 AND user_id = \$3`).WithArgs(7, 3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.DeleteReminder(context.Background(), 1, 3, 7), ErrReminderNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReminderRepository_ClaimDueReminders(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewReminderRepository(mockDB)
+
+	now := time.Date(2024, 5, 15, 9, 30, 0, 0, time.UTC)
+	since := now.Add(-24 * time.Hour)
+	mock.ExpectQuery(`UPDATE reminders r SET fired_at = due.fire_at.* FOR UPDATE OF r SKIP LOCKED`).
+		WithArgs(since, now, 100, models.TodoCompleted).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "todo_id", "user_id", "title", "due_date", "fire_at"}).
+			AddRow(7, 3, 1, "Pay rent", nil, now))
+
+	reminders, err := repo.ClaimDueReminders(context.Background(), since, now, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []models.DueReminder{{ReminderID: 7, TodoID: 3, UserID: 1, Title: "Pay rent", FireAt: now}}, reminders)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReminderRepository_ClaimDueReminders_CompletedTodo(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewReminderRepository(mockDB)
+
+	// Todos marked completed in the app get no reminders
+	now := time.Date(2024, 5, 15, 9, 30, 0, 0, time.UTC)
+	since := now.Add(-24 * time.Hour)
+	mock.ExpectQuery(`WHERE t.status <> \$4 AND`).
+		WithArgs(since, now, 100, "completed").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "todo_id", "user_id", "title", "due_date", "fire_at"}))
+
+	reminders, err := repo.ClaimDueReminders(context.Background(), since, now, 100)
+	require.NoError(t, err)
+	assert.Empty(t, reminders)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotificationPreferenceRepository(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewNotificationPreferenceRepository(mockDB)
+	columns := []string{"channels", "timezone", "quiet_hours_start", "quiet_hours_end", "digest_frequency"}
+
+	// Users without preferences get the defaults
+	mock.ExpectQuery(`SELECT channels, timezone, quiet_hours_start, quiet_hours_end, digest_frequency FROM notification_preferences`).
+		WithArgs(1).WillReturnRows(sqlmock.NewRows(columns))
+	prefs, err := repo.GetNotificationPreferences(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultNotificationChannels, prefs.Channels)
+	assert.Equal(t, "UTC", prefs.Timezone)
+	assert.Equal(t, models.DigestOff, prefs.DigestFrequency)
+
+	mock.ExpectQuery(`SELECT channels`).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("{push,webhook}", "Europe/Paris", "22:00", "07:00", "daily"))
+	prefs, err = repo.GetNotificationPreferences(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"push", "webhook"}, prefs.Channels)
+	assert.Equal(t, "22:00", prefs.QuietHoursStart)
+	assert.Equal(t, models.DigestDaily, prefs.DigestFrequency)
+
+	mock.ExpectExec(`INSERT INTO notification_preferences .* ON CONFLICT \(user_id\) DO UPDATE`).
+		WithArgs(2, `{"push","webhook"}`, "Europe/Paris", "22:00", "07:00", "daily").WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.SaveNotificationPreferences(context.Background(), prefs))
+
+	// Unsubscribing keeps the other preferences
+	mock.ExpectExec(`INSERT INTO notification_preferences .* DO UPDATE SET digest_frequency = EXCLUDED.digest_frequency,`).
+		WithArgs(2, `{"email","in_app"}`, models.DigestOff).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.SetDigestFrequency(context.Background(), 2, models.DigestOff))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotificationPreferenceRepository_ClaimDueDigests(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewNotificationPreferenceRepository(mockDB)
+
+	now := time.Date(2024, 5, 15, 7, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`UPDATE notification_preferences SET digest_sent_on = .* FOR UPDATE SKIP LOCKED`).
+		WithArgs(now, models.DigestDaily, models.DigestWeekly, 7, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "digest_frequency", "timezone", "to_char"}).
+			AddRow(1, models.DigestDaily, "Europe/Paris", "2024-05-15"))
+
+	recipients, err := repo.ClaimDueDigests(context.Background(), now, 7, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []models.DigestRecipient{
+		{UserID: 1, Frequency: models.DigestDaily, Timezone: "Europe/Paris", Date: "2024-05-15"},
+	}, recipients)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
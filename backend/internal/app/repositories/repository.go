@@ -2,7 +2,45 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/scim"
+)
+
+var (
+	ErrTodoNotFound             = errors.New("todo not found or does not belong to user")
+	ErrUserNotFound             = errors.New("user not found")
+	ErrDuplicateEmail           = errors.New("email already registered")
+	ErrDuplicateExternalID      = errors.New("external id already in use")
+	ErrUnsupportedFilter        = errors.New("unsupported filter")
+	ErrUserDeactivated          = errors.New("user deactivated")
+	ErrNoLockout                = errors.New("no active lockout")
+	ErrTokenNotFound            = errors.New("token not found, used or expired")
+	ErrSessionNotFound          = errors.New("session not found")
+	ErrAccessTokenNotFound      = errors.New("access token not found or expired")
+	ErrTOTPNotFound             = errors.New("totp not found")
+	ErrTOTPAlreadyEnabled       = errors.New("totp already enabled")
+	ErrTOTPStepUsed             = errors.New("totp code already used")
+	ErrRecoveryCodeNotFound     = errors.New("recovery code not found or used")
+	ErrLoginStateNotFound       = errors.New("login state not found or expired")
+	ErrIdentityNotFound         = errors.New("identity not found")
+	ErrDuplicateIdentity        = errors.New("identity already linked")
+	ErrOAuthClientNotFound      = errors.New("oauth client not found")
+	ErrOAuthGrantNotFound       = errors.New("oauth grant not found")
+	ErrOAuthCodeNotFound        = errors.New("authorization code not found, used or expired")
+	ErrOAuthTokenNotFound       = errors.New("oauth token not found or expired")
+	ErrInviteNotFound           = errors.New("invite code not found, used or expired")
+	ErrWebhookNotFound          = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound  = errors.New("webhook delivery not found")
+	ErrWebhookDeliveryLost      = errors.New("webhook delivery claimed again after its lease ended")
+	ErrInboundWebhookNotFound   = errors.New("inbound webhook not found")
+	ErrJobNotFound              = errors.New("job not found")
+	ErrDuplicateJob             = errors.New("job with the same unique key already queued")
+	ErrJobLost                  = errors.New("job no longer running for this worker")
+	ErrReminderNotFound         = errors.New("reminder not found")
+	ErrPushSubscriptionNotFound = errors.New("push subscription not found")
+	ErrNotificationNotFound     = errors.New("notification not found")
 )
 
 type TodoRepoInterface interface {
@@ -10,7 +48,10 @@ type TodoRepoInterface interface {
 	DeleteTodo(ctx context.Context, userId int, id int) error
 	GetAllTodos(ctx context.Context, userId int) ([]models.Todo, error)
 	GetTodoByID(ctx context.Context, userId int, id int) (*models.Todo, error)
-	UpdateTodo(ctx context.Context, userId int, id int, todo *models.Todo) error
+	UpdateTodo(ctx context.Context, userId int, id int, update *models.TodoUpdate) error
+	UpsertTodo(ctx context.Context, userId int, externalKey string, todo *models.Todo) (bool, error)
+	ClaimOverdueTodos(ctx context.Context, since, now time.Time, limit int) ([]models.OverdueTodo, error)
+	GetDigest(ctx context.Context, userId int, start, end, completedSince time.Time) (*models.Digest, error)
 }
 
 type UserRepoInterface interface {
@@ -19,4 +60,174 @@ type UserRepoInterface interface {
 	GetAllUsers(ctx context.Context) ([]models.User, error)
 	GetUserByID(ctx context.Context, id int) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	MarkEmailVerified(ctx context.Context, id int) error
+	UpdatePassword(ctx context.Context, id int, passwordHash string) error
+	UpdateName(ctx context.Context, id int, name string) error
+	UpdateEmail(ctx context.Context, id int, email string) error
+	ScheduleDeletion(ctx context.Context, id int, at time.Time) error
+	CancelDeletion(ctx context.Context, id int) error
+	DeleteScheduledUsers(ctx context.Context, before time.Time) (int64, error)
+	CreateProvisionedUser(ctx context.Context, user *models.User) error
+	UpdateUser(ctx context.Context, user *models.User) error
+	SetUserActive(ctx context.Context, id int, active bool) error
+	SetUserAdmin(ctx context.Context, id int, admin bool) error
+	ListPendingUsers(ctx context.Context) ([]models.User, error)
+	ApproveUser(ctx context.Context, id int) error
+	SearchUsers(ctx context.Context, filter scim.Filter, offset, limit int) ([]models.User, int, error)
+}
+
+type LockoutRepoInterface interface {
+	CreateLockout(ctx context.Context, lockout *models.Lockout) error
+	GetActiveLockout(ctx context.Context, email string, ip string) (*models.Lockout, error)
+	UnlockAccount(ctx context.Context, email string, unlockedBy int) error
+}
+
+type UserTokenRepoInterface interface {
+	CreateToken(ctx context.Context, token *models.UserToken) error
+	GetToken(ctx context.Context, purpose string, tokenHash string) (*models.UserToken, error)
+	ConsumeToken(ctx context.Context, purpose string, tokenHash string) (*models.UserToken, error)
+	InvalidateTokens(ctx context.Context, userID int, purpose string) error
+}
+
+type SessionRepoInterface interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, id int) (*models.Session, error)
+	RevokeUserSessions(ctx context.Context, userID int, exceptID int) error
+	ListActiveSessions(ctx context.Context, userID int) ([]models.Session, error)
+	TouchSession(ctx context.Context, id int) error
+	RevokeSession(ctx context.Context, userID int, id int) error
+}
+
+type AccessTokenRepoInterface interface {
+	CreateAccessToken(ctx context.Context, token *models.PersonalAccessToken) error
+	ListAccessTokens(ctx context.Context, userID int) ([]models.PersonalAccessToken, error)
+	DeleteAccessToken(ctx context.Context, userID int, id int) error
+	UseAccessToken(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error)
+}
+
+type TwoFactorRepoInterface interface {
+	GetTOTP(ctx context.Context, userID int) (*models.TOTP, error)
+	SavePendingTOTP(ctx context.Context, userID int, secret string) error
+	ConfirmTOTP(ctx context.Context, userID int, step int64) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+	DeleteTwoFactor(ctx context.Context, userID int) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+}
+
+type OIDCRepoInterface interface {
+	CreateLoginState(ctx context.Context, state *models.OIDCLoginState) error
+	ConsumeLoginState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error)
+	GetIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *models.UserIdentity) error
+	RecordIdentityLogin(ctx context.Context, id int, email string) error
+	CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) error
+}
+
+type OAuthRepoInterface interface {
+	CreateClient(ctx context.Context, client *models.OAuthClient) error
+	GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	ListClients(ctx context.Context, ownerID int) ([]models.OAuthClient, error)
+	DeleteClient(ctx context.Context, ownerID int, clientID string) error
+	GetGrant(ctx context.Context, userID int, clientID int) (*models.OAuthGrant, error)
+	SaveGrant(ctx context.Context, grant *models.OAuthGrant) error
+	ListAuthorizedApps(ctx context.Context, userID int) ([]models.AuthorizedApp, error)
+	DeleteGrant(ctx context.Context, userID int, clientID string) error
+	CreateAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error)
+	CreateToken(ctx context.Context, token *models.OAuthToken) error
+	ConsumeRefreshToken(ctx context.Context, refreshTokenHash string) (*models.OAuthToken, error)
+	UseAccessToken(ctx context.Context, accessTokenHash string) (*models.OAuthToken, error)
+}
+
+type AuditRepoInterface interface {
+	AppendEntry(ctx context.Context, entry *models.AuditEntry) error
+	ListEntries(ctx context.Context, filter models.AuditFilter, limit int) ([]models.AuditEntry, error)
+	EachEntry(ctx context.Context, filter models.AuditFilter, fn func(entry *models.AuditEntry) error) error
+}
+
+type InviteRepoInterface interface {
+	CreateInvite(ctx context.Context, invite *models.InviteCode) error
+	ListInvites(ctx context.Context) ([]models.InviteCode, error)
+	DeleteInvite(ctx context.Context, id int) error
+	CreateInvitedUser(ctx context.Context, user *models.User, codeHash string) error
+}
+
+type WebhookRepoInterface interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	ListWebhooks(ctx context.Context, userID int) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, userID int, id int) (*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook *models.Webhook) error
+	DeleteWebhook(ctx context.Context, userID int, id int) error
+	EnqueueDeliveries(ctx context.Context, userID int, eventType string, payload []byte) (int64, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	CompleteDelivery(ctx context.Context, delivery *models.WebhookDelivery, disableAfter int) (bool, error)
+	ListDeliveries(ctx context.Context, webhookID int, beforeID int64, limit int) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, webhookID int, id int64) (*models.WebhookDelivery, error)
+}
+
+type InboundWebhookRepoInterface interface {
+	CreateInboundWebhook(ctx context.Context, webhook *models.InboundWebhook) error
+	ListInboundWebhooks(ctx context.Context, userID int) ([]models.InboundWebhook, error)
+	GetInboundWebhook(ctx context.Context, userID int, id int) (*models.InboundWebhook, error)
+	UseInboundWebhook(ctx context.Context, tokenHash string) (*models.InboundWebhook, error)
+	UpdateInboundWebhook(ctx context.Context, webhook *models.InboundWebhook) error
+	DeleteInboundWebhook(ctx context.Context, userID int, id int) error
+}
+
+type JobRepoInterface interface {
+	EnqueueJob(ctx context.Context, job *models.Job) error
+	ClaimJobs(ctx context.Context, types []string, limit int, worker string) ([]models.Job, error)
+	CompleteJob(ctx context.Context, job *models.Job) error
+	FailJob(ctx context.Context, job *models.Job) error
+	ReleaseJob(ctx context.Context, job *models.Job) error
+	RecoverStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error)
+	DeleteSucceededJobs(ctx context.Context, before time.Time) (int64, error)
+	ListJobs(ctx context.Context, filter models.JobFilter, limit int) ([]models.Job, error)
+	GetJob(ctx context.Context, id int64) (*models.Job, error)
+	RetryJob(ctx context.Context, id int64) (*models.Job, error)
+}
+
+type ScheduleRepoInterface interface {
+	ListSchedules(ctx context.Context) ([]models.Schedule, error)
+	SaveSchedule(ctx context.Context, schedule *models.Schedule) error
+	TryAdvisoryLock(ctx context.Context, key int64) (AdvisoryLock, error)
+}
+
+type ReminderRepoInterface interface {
+	CreateReminder(ctx context.Context, reminder *models.Reminder) error
+	ListReminders(ctx context.Context, userID int, todoID int) ([]models.Reminder, error)
+	DeleteReminder(ctx context.Context, userID int, todoID int, id int) error
+	SnoozeReminder(ctx context.Context, userID int, todoID int, id int, until time.Time) (*models.Reminder, error)
+	ClaimDueReminders(ctx context.Context, since, now time.Time, limit int) ([]models.DueReminder, error)
+}
+
+type NotificationPreferenceRepoInterface interface {
+	GetNotificationPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error)
+	SaveNotificationPreferences(ctx context.Context, prefs *models.NotificationPreferences) error
+	SetDigestFrequency(ctx context.Context, userID int, frequency string) error
+	ClaimDueDigests(ctx context.Context, now time.Time, hour int, limit int) ([]models.DigestRecipient, error)
+}
+
+type PushSubscriptionRepoInterface interface {
+	SavePushSubscription(ctx context.Context, sub *models.PushSubscription) error
+	ListPushSubscriptions(ctx context.Context, userID int) ([]models.PushSubscription, error)
+	DeletePushSubscription(ctx context.Context, userID int, id int) error
+	DeletePushSubscriptionByEndpoint(ctx context.Context, endpoint string) error
+}
+
+type NotificationRepoInterface interface {
+	CreateNotification(ctx context.Context, notification *models.Notification) error
+	ListNotifications(ctx context.Context, userID int, unreadOnly bool, beforeID int64, limit int) ([]models.Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID int) (int, error)
+	MarkNotificationRead(ctx context.Context, userID int, id int64) (*models.Notification, error)
+	MarkAllNotificationsRead(ctx context.Context, userID int, upToID int64) (int64, error)
+}
+
+// AdvisoryLock is a Postgres advisory lock held by this instance.
+type AdvisoryLock interface {
+	// Check returns an error when the lock has been lost.
+	Check(ctx context.Context) error
+	Release(ctx context.Context) error
 }
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+)
+
+type ScheduleRepository struct {
+	DB *sql.DB
+}
+
+func NewScheduleRepository(db *sql.DB) *ScheduleRepository {
+	return &ScheduleRepository{DB: db}
+}
+
+// ListSchedules retrieves the stored runs of every schedule. JobType is not
+// stored and left empty.
+func (r *ScheduleRepository) ListSchedules(ctx context.Context) ([]models.Schedule, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT name, spec, last_run_at, next_run_at FROM schedules ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []models.Schedule{}
+	for rows.Next() {
+		var schedule models.Schedule
+		if err := rows.Scan(&schedule.Name, &schedule.Spec, &schedule.LastRunAt, &schedule.NextRunAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return schedules, nil
+}
+
+// SaveSchedule stores the runs of a schedule
+func (r *ScheduleRepository) SaveSchedule(ctx context.Context, schedule *models.Schedule) error {
+	query := `INSERT INTO schedules (name, spec, last_run_at, next_run_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET spec = EXCLUDED.spec, last_run_at = EXCLUDED.last_run_at,
+			next_run_at = EXCLUDED.next_run_at, updated_at = NOW()`
+	if _, err := r.DB.ExecContext(ctx, query, schedule.Name, schedule.Spec, schedule.LastRunAt, schedule.NextRunAt); err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+	return nil
+}
+
+// TryAdvisoryLock takes the session-level advisory lock key on a connection
+// of its own, which it keeps out of the pool until the lock is released. It
+// returns nil when another session holds the lock.
+func (r *ScheduleRepository) TryAdvisoryLock(ctx context.Context, key int64) (AdvisoryLock, error) {
+	conn, err := r.DB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a connection for advisory lock: %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to take advisory lock: %w", err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, nil
+	}
+	return &advisoryLock{conn: conn, key: key}, nil
+}
+
+// advisoryLock is a session-level advisory lock held on conn.
+type advisoryLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+// Check returns an error when the connection holding the lock, and so the
+// lock, is lost
+func (l *advisoryLock) Check(ctx context.Context) error {
+	if _, err := l.conn.ExecContext(ctx, `SELECT 1`); err != nil {
+		return fmt.Errorf("advisory lock connection lost: %w", err)
+	}
+	return nil
+}
+
+// Release unlocks the lock and returns its connection to the pool
+func (l *advisoryLock) Release(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key)
+	if err != nil {
+		// The connection cannot go back to the pool with the lock held
+		l.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		err = fmt.Errorf("failed to release advisory lock: %w", err)
+	}
+	l.conn.Close()
+	return err
+}
+
+var _ ScheduleRepoInterface = (*ScheduleRepository)(nil)
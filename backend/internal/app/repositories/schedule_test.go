@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleRepository_ListAndSaveSchedules(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewScheduleRepository(mockDB)
+
+	next := time.Date(2024, 5, 15, 11, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT name, spec, last_run_at, next_run_at FROM schedules ORDER BY name`).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "spec", "last_run_at", "next_run_at"}).
+			AddRow("purge_deleted_accounts", "@hourly", nil, next))
+
+	schedules, err := repo.ListSchedules(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, schedules, 1) {
+		assert.Nil(t, schedules[0].LastRunAt)
+		assert.Equal(t, next, *schedules[0].NextRunAt)
+	}
+
+	last := next.Add(-time.Hour)
+	schedule := &models.Schedule{Name: "purge_deleted_accounts", Spec: "@hourly", LastRunAt: &last, NextRunAt: &next}
+	mock.ExpectExec(`INSERT INTO schedules .* ON CONFLICT \(name\) DO UPDATE`).
+		WithArgs("purge_deleted_accounts", "@hourly", &last, &next).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.SaveSchedule(context.Background(), schedule))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestScheduleRepository_TryAdvisoryLock(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewScheduleRepository(mockDB)
+
+	// Held by another session
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+	lock, err := repo.TryAdvisoryLock(context.Background(), 42)
+	assert.NoError(t, err)
+	assert.Nil(t, lock)
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock\(\$1\)`).WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+	lock, err = repo.TryAdvisoryLock(context.Background(), 42)
+	require.NoError(t, err)
+	require.NotNil(t, lock)
+
+	mock.ExpectExec(`SELECT 1`).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, lock.Check(context.Background()))
+	mock.ExpectExec(`SELECT 1`).WillReturnError(errors.New("connection reset"))
+	assert.Error(t, lock.Check(context.Background()))
+
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(int64(42)).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.NoError(t, lock.Release(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+)
+
+type SessionRepository struct {
+	DB *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{DB: db}
+}
+
+// CreateSession inserts a new session unless its user has been deactivated
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	query := `INSERT INTO sessions (user_id, ip, user_agent, device, expires_at)
+		SELECT id, $2, $3, $4, $5 FROM users WHERE id = $1 AND deactivated_at IS NULL
+		RETURNING id, created_at, last_seen_at`
+	err := r.DB.QueryRowContext(ctx, query, session.UserID, session.IP, session.UserAgent, session.Device, session.ExpiresAt).
+		Scan(&session.ID, &session.CreatedAt, &session.LastSeenAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserDeactivated
+	} else if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSession retrieves a session by ID, including revoked and expired ones
+func (r *SessionRepository) GetSession(ctx context.Context, id int) (*models.Session, error) {
+	session := &models.Session{}
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+	err := scanSession(r.DB.QueryRowContext(ctx, query, id), session)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// ListActiveSessions retrieves the sessions of a user that are neither
+// revoked nor expired, most recently used first
+func (r *SessionRepository) ListActiveSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC, id DESC`
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return sessions, nil
+}
+
+// TouchSession records that a session has just been used. It is written at
+// most once a minute to keep authenticated requests cheap.
+func (r *SessionRepository) TouchSession(ctx context.Context, id int) error {
+	query := `UPDATE sessions SET last_seen_at = NOW() WHERE id = $1 AND last_seen_at < NOW() - INTERVAL '1 minute'`
+	if _, err := r.DB.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	return nil
+}
+
+// RevokeSession revokes a single active session of a user
+func (r *SessionRepository) RevokeSession(ctx context.Context, userID int, id int) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	result, err := r.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of a user except the one
+// with ID exceptID. Pass 0 to revoke all of them.
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, userID int, exceptID int) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL`
+	if _, err := r.DB.ExecContext(ctx, query, userID, exceptID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+const sessionColumns = `id, user_id, ip, user_agent, device, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row rowScanner, session *models.Session) error {
+	return row.Scan(&session.ID, &session.UserID, &session.IP, &session.UserAgent, &session.Device,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt)
+}
+
+var _ SessionRepoInterface = (*SessionRepository)(nil)
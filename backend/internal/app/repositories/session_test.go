@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var sessionRowColumns = []string{"id", "user_id", "ip", "user_agent", "device", "created_at", "last_seen_at", "expires_at", "revoked_at"}
+
+func TestSessionRepository_CreateSession(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewSessionRepository(mockDB)
+
+	session := &models.Session{UserID: 1, IP: "10.0.0.1", UserAgent: "curl", Device: "curl", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mock.ExpectQuery(`INSERT INTO sessions .*`).
+		WithArgs(session.UserID, session.IP, session.UserAgent, session.Device, session.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "last_seen_at"}).AddRow(7, time.Now(), time.Now()))
+
+	err = repo.CreateSession(context.Background(), session)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, session.ID)
+
+	mock.ExpectQuery(`INSERT INTO sessions .* FROM users WHERE id = \$1 AND deactivated_at IS NULL`).
+		WithArgs(session.UserID, session.IP, session.UserAgent, session.Device, session.ExpiresAt).
+		WillReturnError(sql.ErrNoRows)
+	err = repo.CreateSession(context.Background(), session)
+	assert.ErrorIs(t, err, ErrUserDeactivated)
+}
+
+func TestSessionRepository_GetSession(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewSessionRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM sessions WHERE id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(sessionRowColumns).
+			AddRow(7, 1, "10.0.0.1", "curl", "curl", now, now, now.Add(time.Hour), nil))
+
+	session, err := repo.GetSession(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, session.UserID)
+	assert.Nil(t, session.RevokedAt)
+
+	// Test not found scenario
+	mock.ExpectQuery(`SELECT .* FROM sessions WHERE id = \$1`).
+		WithArgs(8).
+		WillReturnError(sql.ErrNoRows)
+
+	session, err = repo.GetSession(context.Background(), 8)
+	assert.Nil(t, session)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestSessionRepository_RevokeUserSessions(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewSessionRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = NOW\(\) WHERE user_id = \$1 AND id <> \$2 AND revoked_at IS NULL`).
+		WithArgs(1, 7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = repo.RevokeUserSessions(context.Background(), 1, 7)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionRepository_ListActiveSessions(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewSessionRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM sessions\s+WHERE user_id = \$1 AND revoked_at IS NULL AND expires_at > NOW\(\)\s+ORDER BY last_seen_at DESC`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(sessionRowColumns).
+			AddRow(8, 1, "10.0.0.2", "Firefox", "Firefox", now, now, now.Add(time.Hour), nil).
+			AddRow(7, 1, "10.0.0.1", "curl", "curl", now, now.Add(-time.Hour), now.Add(time.Hour), nil))
+
+	sessions, err := repo.ListActiveSessions(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, 8, sessions[0].ID)
+	assert.Equal(t, "curl", sessions[1].Device)
+}
+
+func TestSessionRepository_TouchSession(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewSessionRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE sessions SET last_seen_at = NOW\(\) WHERE id = \$1 AND last_seen_at < NOW\(\) - INTERVAL '1 minute'`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.TouchSession(context.Background(), 7))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionRepository_RevokeSession(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewSessionRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = NOW\(\) WHERE id = \$1 AND user_id = \$2 AND revoked_at IS NULL`).
+		WithArgs(7, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.RevokeSession(context.Background(), 1, 7))
+
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = NOW\(\) WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(7, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.RevokeSession(context.Background(), 2, 7), ErrSessionNotFound)
+}
@@ -4,9 +4,11 @@ package repositories
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
+	"time"
 	"todo_app_backend/internal/app/models"
+
+	"github.com/lib/pq"
 )
 
 type TodoRepository struct {
@@ -19,8 +21,8 @@ func NewTodoRepository(db *sql.DB) *TodoRepository {
 
 // CreateTodo inserts a new todo into the database
 func (r *TodoRepository) CreateTodo(ctx context.Context, userId int, todo *models.Todo) error {
-	query := `INSERT INTO todos (user_id, title, content, status) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at;`
-	err := r.DB.QueryRowContext(ctx, query, userId, todo.Title, todo.Content, todo.Status).
+	query := `INSERT INTO todos (user_id, title, content, status, tags, due_date) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at;`
+	err := r.DB.QueryRowContext(ctx, query, userId, todo.Title, todo.Content, todo.Status, pq.StringArray(todo.Tags), todo.DueDate).
 		Scan(&todo.ID, &todo.CreatedAt, &todo.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create todo: %w", err)
@@ -31,12 +33,12 @@ func (r *TodoRepository) CreateTodo(ctx context.Context, userId int, todo *model
 // GetTodoByID retrieves a todo by ID
 func (r *TodoRepository) GetTodoByID(ctx context.Context, userId, id int) (*models.Todo, error) {
 	todo := &models.Todo{}
-	query := `SELECT id, title, content, status, created_at, updated_at FROM todos WHERE id = $1 AND user_id = $2`
+	query := `SELECT id, title, content, status, tags, due_date, created_at, updated_at FROM todos WHERE id = $1 AND user_id = $2`
 	err := r.DB.QueryRowContext(ctx, query, id, userId).
-		Scan(&todo.ID, &todo.Title, &todo.Content, &todo.Status, &todo.CreatedAt, &todo.UpdatedAt)
+		Scan(&todo.ID, &todo.Title, &todo.Content, &todo.Status, (*pq.StringArray)(&todo.Tags), &todo.DueDate, &todo.CreatedAt, &todo.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("todo not found or does not belong to user")
+		return nil, ErrTodoNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to get todo by ID: %w", err)
 	}
@@ -47,7 +49,7 @@ func (r *TodoRepository) GetTodoByID(ctx context.Context, userId, id int) (*mode
 // GetAllTodos retrieves all todos
 func (r *TodoRepository) GetAllTodos(ctx context.Context, userId int) ([]models.Todo, error) {
 	var todos []models.Todo
-	query := `SELECT id, title, content, status, created_at, updated_at FROM todos WHERE user_id = $1`
+	query := `SELECT id, title, content, status, tags, due_date, created_at, updated_at FROM todos WHERE user_id = $1`
 	rows, err := r.DB.QueryContext(ctx, query, userId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all todos: %w", err)
@@ -56,7 +58,8 @@ func (r *TodoRepository) GetAllTodos(ctx context.Context, userId int) ([]models.
 
 	for rows.Next() {
 		var todo models.Todo
-		if err := rows.Scan(&todo.ID, &todo.Title, &todo.Content, &todo.Status, &todo.CreatedAt, &todo.UpdatedAt); err != nil {
+		err := rows.Scan(&todo.ID, &todo.Title, &todo.Content, &todo.Status, (*pq.StringArray)(&todo.Tags), &todo.DueDate, &todo.CreatedAt, &todo.UpdatedAt)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan todo: %w", err)
 		}
 		todos = append(todos, todo)
@@ -69,10 +72,33 @@ func (r *TodoRepository) GetAllTodos(ctx context.Context, userId int) ([]models.
 	return todos, nil
 }
 
-// UpdateTodo updates the todo with the provided ID
-func (r *TodoRepository) UpdateTodo(ctx context.Context, userId, id int, todo *models.Todo) error {
-	query := `UPDATE todos SET title = $1, content = $2, status = $3 WHERE id = $4 AND user_id = $5`
-	result, err := r.DB.ExecContext(ctx, query, todo.Title, todo.Content, todo.Status, id, userId)
+// UpsertTodo creates a todo identified by externalKey, or updates the title,
+// content, tags and due date of the todo of the user created with that key.
+// An empty key always creates a todo. It reports whether the todo was created.
+func (r *TodoRepository) UpsertTodo(ctx context.Context, userId int, externalKey string, todo *models.Todo) (bool, error) {
+	query := `INSERT INTO todos (user_id, title, content, status, tags, due_date, external_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, external_key) WHERE external_key IS NOT NULL DO UPDATE
+		SET title = EXCLUDED.title, content = EXCLUDED.content, tags = EXCLUDED.tags, due_date = EXCLUDED.due_date
+		RETURNING id, status, created_at, updated_at, xmax = 0`
+	key := sql.NullString{String: externalKey, Valid: externalKey != ""}
+	var created bool
+	err := r.DB.QueryRowContext(ctx, query, userId, todo.Title, todo.Content, todo.Status, pq.StringArray(todo.Tags), todo.DueDate, key).
+		Scan(&todo.ID, &todo.Status, &todo.CreatedAt, &todo.UpdatedAt, &created)
+	if err != nil {
+		return false, fmt.Errorf("failed to upsert todo: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateTodo updates the todo with the provided ID. Its tags and due date
+// are only changed when set in update.
+func (r *TodoRepository) UpdateTodo(ctx context.Context, userId, id int, update *models.TodoUpdate) error {
+	query := `UPDATE todos SET title = $1, content = $2, status = $3,
+		tags = CASE WHEN $4 THEN $5 ELSE tags END, due_date = CASE WHEN $6 THEN $7 ELSE due_date END
+		WHERE id = $8 AND user_id = $9`
+	result, err := r.DB.ExecContext(ctx, query, update.Title, update.Content, update.Status,
+		update.Tags.Set, pq.StringArray(update.Tags.Value), update.DueDate.Set, update.DueDate.Value, id, userId)
 	if err != nil {
 		return fmt.Errorf("failed to update todo: %w", err)
 	}
@@ -82,7 +108,7 @@ func (r *TodoRepository) UpdateTodo(ctx context.Context, userId, id int, todo *m
 		return fmt.Errorf("failed to get rows affected during update: %w", err)
 	}
 	if rowsAffected == 0 {
-		return errors.New("todo not found or does not belong to user")
+		return ErrTodoNotFound
 	}
 
 	return nil
@@ -101,10 +127,78 @@ func (r *TodoRepository) DeleteTodo(ctx context.Context, userId, id int) error {
 		return fmt.Errorf("failed to get rows affected during delete: %w", err)
 	}
 	if rowsAffected == 0 {
-		return errors.New("todo not found or does not belong to user")
+		return ErrTodoNotFound
 	}
 
 	return nil
 }
 
+// ClaimOverdueTodos marks up to limit uncompleted todos whose due date passed
+// between since and now as reported overdue and returns them. Todos are
+// reported again once their due date moves.
+func (r *TodoRepository) ClaimOverdueTodos(ctx context.Context, since, now time.Time, limit int) ([]models.OverdueTodo, error) {
+	query := `UPDATE todos SET overdue_notified_at = due_date
+		WHERE id IN (
+			SELECT id FROM todos
+			WHERE status <> $4 AND due_date > $1 AND due_date <= $2 AND overdue_notified_at IS DISTINCT FROM due_date
+			ORDER BY due_date LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, title, due_date`
+	rows, err := r.DB.QueryContext(ctx, query, since, now, limit, models.TodoCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim overdue todos: %w", err)
+	}
+	defer rows.Close()
+
+	var todos []models.OverdueTodo
+	for rows.Next() {
+		var todo models.OverdueTodo
+		if err := rows.Scan(&todo.ID, &todo.UserID, &todo.Title, &todo.DueDate); err != nil {
+			return nil, fmt.Errorf("failed to scan todo: %w", err)
+		}
+		todos = append(todos, todo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return todos, nil
+}
+
+// GetDigest retrieves the digest of the todos of a user for the period from
+// start to end: the uncompleted todos due before end, split between due and
+// overdue at start, and the todos completed from completedSince to start.
+func (r *TodoRepository) GetDigest(ctx context.Context, userId int, start, end, completedSince time.Time) (*models.Digest, error) {
+	query := `SELECT id, title, due_date, completed_at FROM todos
+		WHERE user_id = $1 AND ((status <> $5 AND due_date < $3) OR (completed_at >= $4 AND completed_at < $2))
+		ORDER BY due_date NULLS LAST, completed_at, id`
+	rows, err := r.DB.QueryContext(ctx, query, userId, start, end, completedSince, models.TodoCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest: %w", err)
+	}
+	defer rows.Close()
+
+	digest := &models.Digest{}
+	for rows.Next() {
+		var todo models.DigestTodo
+		if err := rows.Scan(&todo.ID, &todo.Title, &todo.DueDate, &todo.CompletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan todo: %w", err)
+		}
+		switch {
+		case todo.CompletedAt != nil:
+			digest.Completed = append(digest.Completed, todo)
+		case todo.DueDate.Before(start):
+			digest.Overdue = append(digest.Overdue, todo)
+		default:
+			digest.Due = append(digest.Due, todo)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return digest, nil
+}
+
 var _ TodoRepoInterface = (*TodoRepository)(nil)
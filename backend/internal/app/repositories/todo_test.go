@@ -21,14 +21,17 @@ func TestTodoRepository_CreateTodo(t *testing.T) {
 
 	repo := NewTodoRepository(mockDB)
 
+	due := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
 	todo := &models.Todo{
 		Title:   "Test Todo",
 		Content: "This is a test",
 		Status:  "Pending",
+		Tags:    []string{"home", "urgent"},
+		DueDate: &due,
 	}
 
-	mock.ExpectQuery(`INSERT INTO todos .*`).
-		WithArgs(1, todo.Title, todo.Content, todo.Status).
+	mock.ExpectQuery(`INSERT INTO todos \(user_id, title, content, status, tags, due_date\)`).
+		WithArgs(1, todo.Title, todo.Content, todo.Status, `{"home","urgent"}`, due).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).
 			AddRow(1, time.Now(), time.Now()))
 
@@ -59,12 +62,14 @@ func TestTodoRepository_GetTodoByID(t *testing.T) {
 
 	mock.ExpectQuery(`SELECT .* FROM todos WHERE id = \$1 AND user_id = \$2`).
 		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "status", "created_at", "updated_at"}).
-			AddRow(todo.ID, todo.Title, todo.Content, todo.Status, time.Now(), time.Now()))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "status", "tags", "due_date", "created_at", "updated_at"}).
+			AddRow(todo.ID, todo.Title, todo.Content, todo.Status, "{alert,ops}", nil, time.Now(), time.Now()))
 
 	result, err := repo.GetTodoByID(context.Background(), 1, 1)
 	assert.NoError(t, err)
 	assert.Equal(t, todo.ID, result.ID)
+	assert.Equal(t, []string{"alert", "ops"}, result.Tags)
+	assert.Nil(t, result.DueDate)
 
 	// Test not found scenario
 	mock.ExpectQuery(`SELECT .* FROM todos WHERE id = \$1 AND user_id = \$2`).
@@ -82,9 +87,10 @@ func TestTodoRepository_GetAllTodos(t *testing.T) {
 
 	repo := NewTodoRepository(mockDB)
 
-	rows := sqlmock.NewRows([]string{"id", "title", "content", "status", "created_at", "updated_at"}).
-		AddRow(1, "Todo 1", "Content 1", "Pending", time.Now(), time.Now()).
-		AddRow(2, "Todo 2", "Content 2", "Completed", time.Now(), time.Now())
+	dueDate := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "title", "content", "status", "tags", "due_date", "created_at", "updated_at"}).
+		AddRow(1, "Todo 1", "Content 1", "Pending", "{}", dueDate, time.Now(), time.Now()).
+		AddRow(2, "Todo 2", "Content 2", "Completed", "{}", nil, time.Now(), time.Now())
 
 	mock.ExpectQuery(`SELECT .* FROM todos WHERE user_id = \$1`).
 		WithArgs(1).
@@ -97,6 +103,28 @@ func TestTodoRepository_GetAllTodos(t *testing.T) {
 	// Check if the returned todos match the expected values
 	assert.Equal(t, "Todo 1", todos[0].Title)
 	assert.Equal(t, "Content 1", todos[0].Content)
+	assert.Equal(t, dueDate, *todos[0].DueDate)
+}
+
+func TestTodoRepository_UpsertTodo(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewTodoRepository(mockDB)
+
+	todo := &models.Todo{Title: "Disk full", Status: "pending", Tags: []string{"alert"}}
+	mock.ExpectQuery(`INSERT INTO todos .* ON CONFLICT \(user_id, external_key\) WHERE external_key IS NOT NULL DO UPDATE`).
+		WithArgs(1, "Disk full", "", "pending", "{\"alert\"}", nil, "inbound:3:host-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at", "updated_at", "created"}).
+			AddRow(9, "done", time.Now(), time.Now(), false))
+
+	created, err := repo.UpsertTodo(context.Background(), 1, "inbound:3:host-1", todo)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, 9, todo.ID)
+	assert.Equal(t, "done", todo.Status) // Updates keep the status
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestTodoRepository_UpdateTodo(t *testing.T) {
@@ -106,27 +134,39 @@ func TestTodoRepository_UpdateTodo(t *testing.T) {
 
 	repo := NewTodoRepository(mockDB)
 
-	todo := &models.Todo{
+	due := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	update := &models.TodoUpdate{
 		Title:   "Updated Todo",
 		Content: "Updated Content",
 		Status:  "Pending",
+		Tags:    models.Some([]string{"home"}),
+		DueDate: models.Some(&due),
 	}
 
-	mock.ExpectExec(`UPDATE todos SET title = \$1, content = \$2, status = \$3 WHERE id = \$4 AND user_id = \$5`).
-		WithArgs(todo.Title, todo.Content, todo.Status, 1, 1).
+	mock.ExpectExec(`UPDATE todos SET title = \$1, content = \$2, status = \$3,\s+tags = CASE WHEN \$4 THEN \$5 ELSE tags END, due_date = CASE WHEN \$6 THEN \$7 ELSE due_date END\s+WHERE id = \$8 AND user_id = \$9`).
+		WithArgs(update.Title, update.Content, update.Status, true, `{"home"}`, true, due, 1, 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = repo.UpdateTodo(context.Background(), 1, 1, todo)
+	err = repo.UpdateTodo(context.Background(), 1, 1, update)
+	assert.NoError(t, err)
+
+	// Tags and due date left out are kept
+	mock.ExpectExec(`UPDATE todos SET`).
+		WithArgs("Renamed", "", "completed", false, nil, false, nil, 2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.UpdateTodo(context.Background(), 1, 2, &models.TodoUpdate{Title: "Renamed", Status: "completed"})
 	assert.NoError(t, err)
 
 	// Test not found scenario
-	mock.ExpectExec(`UPDATE todos SET title = \$1, content = \$2, status = \$3 WHERE id = \$4 AND user_id = \$5`).
-		WithArgs(todo.Title, todo.Content, todo.Status, 1, 1).
+	mock.ExpectExec(`UPDATE todos SET`).
+		WithArgs(update.Title, update.Content, update.Status, true, `{"home"}`, true, due, 1, 1).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	err = repo.UpdateTodo(context.Background(), 1, 1, todo)
+	err = repo.UpdateTodo(context.Background(), 1, 1, update)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "todo not found or does not belong to user")
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestTodoRepository_DeleteTodo(t *testing.T) {
@@ -169,3 +209,72 @@ func TestTodoRepository_GetAllTodos_Error(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to get all todos")
 	assert.Nil(t, todos)
 }
+
+func TestTodoRepository_ClaimOverdueTodos(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewTodoRepository(mockDB)
+
+	now := time.Date(2024, 5, 15, 9, 30, 0, 0, time.UTC)
+	since := now.Add(-24 * time.Hour)
+	due := now.Add(-time.Minute)
+	mock.ExpectQuery(`UPDATE todos SET overdue_notified_at = due_date .* FOR UPDATE SKIP LOCKED`).
+		WithArgs(since, now, 100, models.TodoCompleted).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "due_date"}).AddRow(3, 1, "Pay rent", due))
+
+	todos, err := repo.ClaimOverdueTodos(context.Background(), since, now, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.OverdueTodo{{ID: 3, UserID: 1, Title: "Pay rent", DueDate: due}}, todos)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTodoRepository_ClaimOverdueTodos_CompletedTodo(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewTodoRepository(mockDB)
+
+	// Todos marked completed in the app are never overdue
+	now := time.Date(2024, 5, 15, 9, 30, 0, 0, time.UTC)
+	since := now.Add(-24 * time.Hour)
+	mock.ExpectQuery(`WHERE status <> \$4 AND due_date > \$1`).
+		WithArgs(since, now, 100, "completed").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "due_date"}))
+
+	todos, err := repo.ClaimOverdueTodos(context.Background(), since, now, 100)
+	assert.NoError(t, err)
+	assert.Empty(t, todos)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTodoRepository_GetDigest(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewTodoRepository(mockDB)
+
+	start := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	since := start.Add(-24 * time.Hour)
+	overdue := start.Add(-time.Hour)
+	due := start.Add(9 * time.Hour)
+	completed := since.Add(15 * time.Hour)
+	// Todos marked completed in the app are listed as completed, not overdue
+	mock.ExpectQuery(`SELECT id, title, due_date, completed_at FROM todos WHERE user_id = \$1`).
+		WithArgs(1, start, end, since, "completed").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "due_date", "completed_at"}).
+			AddRow(3, "Pay rent", overdue, nil).
+			AddRow(4, "Call mum", due, nil).
+			AddRow(5, "Buy milk", nil, completed))
+
+	digest, err := repo.GetDigest(context.Background(), 1, start, end, since)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.DigestTodo{{ID: 3, Title: "Pay rent", DueDate: &overdue}}, digest.Overdue)
+	assert.Equal(t, []models.DigestTodo{{ID: 4, Title: "Call mum", DueDate: &due}}, digest.Due)
+	assert.Equal(t, []models.DigestTodo{{ID: 5, Title: "Buy milk", CompletedAt: &completed}}, digest.Completed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
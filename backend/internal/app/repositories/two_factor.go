@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+)
+
+type TwoFactorRepository struct {
+	DB *sql.DB
+}
+
+func NewTwoFactorRepository(db *sql.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{DB: db}
+}
+
+// GetTOTP retrieves the TOTP enrolment of a user, confirmed or not
+func (r *TwoFactorRepository) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	totp := &models.TOTP{}
+	query := `SELECT user_id, secret, confirmed_at, last_used_step, created_at FROM user_totp WHERE user_id = $1`
+	err := r.DB.QueryRowContext(ctx, query, userID).
+		Scan(&totp.UserID, &totp.Secret, &totp.ConfirmedAt, &totp.LastUsedStep, &totp.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTOTPNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get totp: %w", err)
+	}
+	return totp, nil
+}
+
+// SavePendingTOTP stores a new unconfirmed secret for a user, replacing any
+// earlier pending one. A confirmed enrolment is never replaced.
+func (r *TwoFactorRepository) SavePendingTOTP(ctx context.Context, userID int, secret string) error {
+	query := `INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL`
+	result, err := r.DB.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save totp: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected during save: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+// ConfirmTOTP enables a pending enrolment, recording step as used
+func (r *TwoFactorRepository) ConfirmTOTP(ctx context.Context, userID int, step int64) error {
+	query := `UPDATE user_totp SET confirmed_at = NOW(), last_used_step = $2 WHERE user_id = $1 AND confirmed_at IS NULL`
+	return r.execTwoFactorUpdate(ctx, "failed to confirm totp", ErrTOTPNotFound, query, userID, step)
+}
+
+// UseTOTPStep records step as used. It fails with ErrTOTPStepUsed if a code
+// of the same or a later step has already been accepted.
+func (r *TwoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	query := `UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2`
+	return r.execTwoFactorUpdate(ctx, "failed to use totp step", ErrTOTPStepUsed, query, userID, step)
+}
+
+// DeleteTwoFactor removes the TOTP enrolment and recovery codes of a user
+func (r *TwoFactorRepository) DeleteTwoFactor(ctx context.Context, userID int) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete totp: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes discards the recovery codes of a user and stores new ones
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode marks an unused recovery code of a user as used
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	query := `UPDATE user_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	return r.execTwoFactorUpdate(ctx, "failed to use recovery code", ErrRecoveryCodeNotFound, query, userID, codeHash)
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (r *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	if err := r.DB.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+// execTwoFactorUpdate runs an update, reporting notFound if no row was affected
+func (r *TwoFactorRepository) execTwoFactorUpdate(ctx context.Context, errMsg string, notFound error, query string, args ...interface{}) error {
+	result, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected during update: %w", err)
+	}
+	if rowsAffected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+var _ TwoFactorRepoInterface = (*TwoFactorRepository)(nil)
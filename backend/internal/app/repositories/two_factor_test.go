@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorRepository_GetTOTP(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewTwoFactorRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM user_totp WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret", "confirmed_at", "last_used_step", "created_at"}).
+			AddRow(1, "JBSWY3DPEHPK3PXP", now, 42, now))
+
+	totp, err := repo.GetTOTP(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", totp.Secret)
+	assert.Equal(t, int64(42), totp.LastUsedStep)
+	assert.NotNil(t, totp.ConfirmedAt)
+
+	mock.ExpectQuery(`SELECT .* FROM user_totp WHERE user_id = \$1`).
+		WithArgs(2).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.GetTOTP(context.Background(), 2)
+	assert.ErrorIs(t, err, ErrTOTPNotFound)
+}
+
+func TestTwoFactorRepository_SavePendingTOTP_AlreadyEnabled(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewTwoFactorRepository(mockDB)
+
+	mock.ExpectExec(`INSERT INTO user_totp .* WHERE user_totp.confirmed_at IS NULL`).
+		WithArgs(1, "secret").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.SavePendingTOTP(context.Background(), 1, "secret")
+	assert.ErrorIs(t, err, ErrTOTPAlreadyEnabled)
+}
+
+func TestTwoFactorRepository_UseTOTPStep(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewTwoFactorRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE user_totp SET last_used_step = \$2 WHERE .* last_used_step < \$2`).
+		WithArgs(1, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE user_totp SET last_used_step = \$2 WHERE .* last_used_step < \$2`).
+		WithArgs(1, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.UseTOTPStep(context.Background(), 1, 100))
+	assert.ErrorIs(t, repo.UseTOTPStep(context.Background(), 1, 100), ErrTOTPStepUsed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorRepository_ReplaceRecoveryCodes(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewTwoFactorRepository(mockDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM user_recovery_codes WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec(`INSERT INTO user_recovery_codes`).
+		WithArgs(1, "hash1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO user_recovery_codes`).
+		WithArgs(1, "hash2").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	err = repo.ReplaceRecoveryCodes(context.Background(), 1, []string{"hash1", "hash2"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorRepository_UseRecoveryCode_NotFound(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewTwoFactorRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE user_recovery_codes SET used_at = NOW\(\) WHERE .* used_at IS NULL`).
+		WithArgs(1, "hash").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.UseRecoveryCode(context.Background(), 1, "hash")
+	assert.ErrorIs(t, err, ErrRecoveryCodeNotFound)
+}
@@ -5,9 +5,32 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/scim"
+
+	"github.com/lib/pq"
 )
 
+// uniqueViolation is the PostgreSQL error code for unique constraint violations.
+const uniqueViolation = "23505"
+
+// userColumns lists the columns read by scanUser, in order.
+const userColumns = `id, name, email, password, created_at, email_verified_at IS NOT NULL, is_admin, deletion_scheduled_at, COALESCE(external_id, ''), deactivated_at, approval_pending`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads a row selected with userColumns into a user.
+func scanUser(row rowScanner, user *models.User) error {
+	return row.Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &user.EmailVerified, &user.IsAdmin, &user.DeletionScheduledAt,
+		&user.ExternalID, &user.DeactivatedAt, &user.PendingApproval)
+}
+
 type UserRepository struct {
 	DB *sql.DB
 }
@@ -18,9 +41,12 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 // CreateUser inserts a new user into the database
 func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
-	query := `INSERT INTO users (name, email, password) VALUES ($1, $2, $3) RETURNING id`
-	err := r.DB.QueryRowContext(ctx, query, user.Name, user.Email, user.Password).Scan(&user.ID)
-	if err != nil {
+	query := `INSERT INTO users (name, email, password, approval_pending) VALUES ($1, $2, $3, $4) RETURNING id`
+	err := r.DB.QueryRowContext(ctx, query, user.Name, user.Email, user.Password, user.PendingApproval).Scan(&user.ID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrDuplicateEmail
+	} else if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
@@ -31,10 +57,10 @@ func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) erro
 // GetUserByID retrieves a user by ID
 func (r *UserRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, name, email, password, created_at FROM users WHERE id = $1`
-	err := r.DB.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.CreatedAt)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	err := scanUser(r.DB.QueryRowContext(ctx, query, id), user)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to get user by ID: %w", err)
 	}
@@ -44,10 +70,10 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id int) (*models.User,
 // GetUserByEmail retrieves a user by Email
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, name, email, password, created_at FROM users WHERE email = $1`
-	err := r.DB.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.CreatedAt)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	err := scanUser(r.DB.QueryRowContext(ctx, query, email), user)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
@@ -57,7 +83,7 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 // GetAllUsers retrieves all users
 func (r *UserRepository) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	query := `SELECT id, name, email, password, created_at FROM users`
+	query := `SELECT ` + userColumns + ` FROM users`
 	rows, err := r.DB.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all users: %w", err)
@@ -66,7 +92,7 @@ func (r *UserRepository) GetAllUsers(ctx context.Context) ([]models.User, error)
 
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.CreatedAt); err != nil {
+		if err := scanUser(rows, &user); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
 		users = append(users, user)
@@ -79,6 +105,199 @@ func (r *UserRepository) GetAllUsers(ctx context.Context) ([]models.User, error)
 	return users, nil
 }
 
+// UpdatePassword replaces the password hash of a user
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int, passwordHash string) error {
+	query := `UPDATE users SET password = $1 WHERE id = $2`
+	return r.execUserUpdate(ctx, "failed to update password", query, passwordHash, id)
+}
+
+// MarkEmailVerified records that the user has confirmed their email address
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, id int) error {
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`
+	return r.execUserUpdate(ctx, "failed to mark email verified", query, id)
+}
+
+// UpdateName changes the display name of a user
+func (r *UserRepository) UpdateName(ctx context.Context, id int, name string) error {
+	query := `UPDATE users SET name = $1 WHERE id = $2`
+	return r.execUserUpdate(ctx, "failed to update name", query, name, id)
+}
+
+// UpdateEmail changes the email address of a user. The new address must
+// have been confirmed, so it is marked verified.
+func (r *UserRepository) UpdateEmail(ctx context.Context, id int, email string) error {
+	query := `UPDATE users SET email = $1, email_verified_at = NOW() WHERE id = $2`
+	err := r.execUserUpdate(ctx, "failed to update email", query, email, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrDuplicateEmail
+	}
+	return err
+}
+
+// ScheduleDeletion marks a user to be deleted at the given time
+func (r *UserRepository) ScheduleDeletion(ctx context.Context, id int, at time.Time) error {
+	query := `UPDATE users SET deletion_scheduled_at = $1 WHERE id = $2`
+	return r.execUserUpdate(ctx, "failed to schedule deletion", query, at, id)
+}
+
+// CancelDeletion clears a scheduled deletion of a user
+func (r *UserRepository) CancelDeletion(ctx context.Context, id int) error {
+	query := `UPDATE users SET deletion_scheduled_at = NULL WHERE id = $1`
+	return r.execUserUpdate(ctx, "failed to cancel deletion", query, id)
+}
+
+// DeleteScheduledUsers removes every user whose deletion was scheduled at
+// or before the given time. Their todos are removed by the foreign key cascade.
+func (r *UserRepository) DeleteScheduledUsers(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM users WHERE deletion_scheduled_at <= $1`
+	result, err := r.DB.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete scheduled users: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// CreateProvisionedUser inserts a user provisioned from a directory. The
+// directory vouches for the email address, so it is marked verified.
+func (r *UserRepository) CreateProvisionedUser(ctx context.Context, user *models.User) error {
+	query := `INSERT INTO users (name, email, password, external_id, email_verified_at, deactivated_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NOW(), $5) RETURNING id, created_at`
+	err := r.DB.QueryRowContext(ctx, query, user.Name, user.Email, user.Password, user.ExternalID, user.DeactivatedAt).
+		Scan(&user.ID, &user.CreatedAt)
+	if err != nil {
+		return duplicateUserError(fmt.Errorf("failed to create user: %w", err))
+	}
+
+	user.EmailVerified = true
+	user.Password = ""
+	return nil
+}
+
+// UpdateUser replaces the name, email address and external ID of a user.
+// A changed email address comes from the directory and is marked verified.
+func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	query := `UPDATE users SET name = $1, email = $2, external_id = NULLIF($3, ''),
+		email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NOW() END
+		WHERE id = $4`
+	err := r.execUserUpdate(ctx, "failed to update user", query, user.Name, user.Email, user.ExternalID, user.ID)
+	return duplicateUserError(err)
+}
+
+// SetUserActive deactivates or reactivates a user
+func (r *UserRepository) SetUserActive(ctx context.Context, id int, active bool) error {
+	query := `UPDATE users SET deactivated_at = CASE WHEN $1 THEN NULL ELSE COALESCE(deactivated_at, NOW()) END WHERE id = $2`
+	return r.execUserUpdate(ctx, "failed to set user active", query, active, id)
+}
+
+// SetUserAdmin grants or revokes the admin role of a user
+func (r *UserRepository) SetUserAdmin(ctx context.Context, id int, admin bool) error {
+	query := `UPDATE users SET is_admin = $1 WHERE id = $2`
+	return r.execUserUpdate(ctx, "failed to set user admin", query, admin, id)
+}
+
+// ListPendingUsers retrieves the users awaiting approval, oldest first
+func (r *UserRepository) ListPendingUsers(ctx context.Context) ([]models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE approval_pending ORDER BY created_at, id`
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return users, nil
+}
+
+// ApproveUser lets a user awaiting approval sign in
+func (r *UserRepository) ApproveUser(ctx context.Context, id int) error {
+	query := `UPDATE users SET approval_pending = FALSE WHERE id = $1`
+	return r.execUserUpdate(ctx, "failed to approve user", query, id)
+}
+
+// SearchUsers retrieves the users matching a SCIM filter, ordered by ID,
+// together with the number of matching users. A nil filter matches every
+// user.
+func (r *UserRepository) SearchUsers(ctx context.Context, filter scim.Filter, offset, limit int) ([]models.User, int, error) {
+	where := "TRUE"
+	var args []interface{}
+	if filter != nil {
+		var err error
+		if where, err = userFilterSQL(filter, &args); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var total int
+	err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE %s ORDER BY id LIMIT $%d OFFSET $%d`, userColumns, where, len(args)+1, len(args)+2)
+	rows, err := r.DB.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("row iteration error: %w", err)
+	}
+	return users, total, nil
+}
+
+// duplicateUserError reports unique violations on the email address or
+// external ID of a user as ErrDuplicateEmail or ErrDuplicateExternalID.
+func duplicateUserError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != uniqueViolation {
+		return err
+	}
+	if strings.Contains(pqErr.Constraint, "external_id") {
+		return ErrDuplicateExternalID
+	}
+	return ErrDuplicateEmail
+}
+
+// execUserUpdate runs an update on a single user, reporting ErrUserNotFound
+// if no row was affected
+func (r *UserRepository) execUserUpdate(ctx context.Context, errMsg string, query string, args ...interface{}) error {
+	result, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", errMsg, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected during update: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
 // DeleteUser removes a user by ID
 func (r *UserRepository) DeleteUser(ctx context.Context, id int) error {
 	query := `DELETE FROM users WHERE id = $1`
@@ -92,10 +311,126 @@ func (r *UserRepository) DeleteUser(ctx context.Context, id int) error {
 		return fmt.Errorf("failed to get rows affected during delete: %w", err)
 	}
 	if rowsAffected == 0 {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
 }
 
 var _ UserRepoInterface = (*UserRepository)(nil)
+
+// userFilterColumns maps the SCIM attributes that can be filtered on to
+// user columns.
+var userFilterColumns = map[string]string{
+	"id":             "id",
+	"username":       "email",
+	"emails":         "email",
+	"emails.value":   "email",
+	"externalid":     "external_id",
+	"displayname":    "name",
+	"name.formatted": "name",
+	"meta.created":   "created_at",
+}
+
+// userFilterSQL compiles a SCIM filter to a WHERE clause, appending its
+// parameters to args. String comparisons are case-insensitive.
+func userFilterSQL(filter scim.Filter, args *[]interface{}) (string, error) {
+	switch f := filter.(type) {
+	case *scim.Logical:
+		left, err := userFilterSQL(f.Left, args)
+		if err != nil {
+			return "", err
+		}
+		right, err := userFilterSQL(f.Right, args)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s %s %s)", left, strings.ToUpper(f.Op), right), nil
+	case *scim.Not:
+		inner, err := userFilterSQL(f.Filter, args)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT COALESCE(%s, FALSE)", inner), nil
+	case *scim.Comparison:
+		return userComparisonSQL(f, args)
+	}
+	return "", fmt.Errorf("%w: %T", ErrUnsupportedFilter, filter)
+}
+
+func userComparisonSQL(c *scim.Comparison, args *[]interface{}) (string, error) {
+	if c.Path == "active" {
+		active, ok := c.Value.(bool)
+		switch {
+		case c.Op == "pr":
+			return "TRUE", nil
+		case !ok || (c.Op != "eq" && c.Op != "ne"):
+			return "", fmt.Errorf("%w: active only supports eq and ne with a boolean", ErrUnsupportedFilter)
+		case active == (c.Op == "eq"):
+			return "deactivated_at IS NULL", nil
+		default:
+			return "deactivated_at IS NOT NULL", nil
+		}
+	}
+
+	column, ok := userFilterColumns[c.Path]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown attribute %q", ErrUnsupportedFilter, c.Path)
+	}
+	if c.Op == "pr" {
+		return fmt.Sprintf("(%s IS NOT NULL AND %s::text <> '')", column, column), nil
+	}
+
+	param := func(value interface{}) string {
+		*args = append(*args, value)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+
+	op, ordered := sqlOperators[c.Op]
+	switch value := c.Value.(type) {
+	case string:
+		switch {
+		case column == "id":
+			id, err := strconv.Atoi(value)
+			if err != nil || !ordered {
+				return "", fmt.Errorf("%w: id %s %q", ErrUnsupportedFilter, c.Op, value)
+			}
+			return fmt.Sprintf("id %s %s", op, param(id)), nil
+		case column == "created_at":
+			created, err := time.Parse(time.RFC3339, value)
+			if err != nil || !ordered {
+				return "", fmt.Errorf("%w: meta.created %s %q", ErrUnsupportedFilter, c.Op, value)
+			}
+			return fmt.Sprintf("created_at %s %s", op, param(created.UTC())), nil
+		case c.Op == "co":
+			return fmt.Sprintf(`LOWER(%s) LIKE '%%' || %s || '%%'`, column, param(escapeLike(strings.ToLower(value)))), nil
+		case c.Op == "sw":
+			return fmt.Sprintf(`LOWER(%s) LIKE %s || '%%'`, column, param(escapeLike(strings.ToLower(value)))), nil
+		case c.Op == "ew":
+			return fmt.Sprintf(`LOWER(%s) LIKE '%%' || %s`, column, param(escapeLike(strings.ToLower(value)))), nil
+		}
+		return fmt.Sprintf("LOWER(%s) %s %s", column, op, param(strings.ToLower(value))), nil
+	case float64:
+		if column != "id" || !ordered {
+			return "", fmt.Errorf("%w: %s %s with a number", ErrUnsupportedFilter, c.Path, c.Op)
+		}
+		return fmt.Sprintf("id %s %s", op, param(value)), nil
+	case nil:
+		switch c.Op {
+		case "eq":
+			return fmt.Sprintf("%s IS NULL", column), nil
+		case "ne":
+			return fmt.Sprintf("%s IS NOT NULL", column), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s %s %v", ErrUnsupportedFilter, c.Path, c.Op, c.Value)
+}
+
+var sqlOperators = map[string]string{
+	"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<=",
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
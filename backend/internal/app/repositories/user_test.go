@@ -9,8 +9,10 @@ import (
 	"time"
 
 	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/scim"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,7 +30,7 @@ func TestUserRepository_CreateUser(t *testing.T) {
 	}
 
 	mock.ExpectQuery(`INSERT INTO users .*`).
-		WithArgs(user.Name, user.Email, user.Password).
+		WithArgs(user.Name, user.Email, user.Password, false).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	err = repo.CreateUser(context.Background(), user)
@@ -40,6 +42,11 @@ func TestUserRepository_CreateUser(t *testing.T) {
 	err = repo.CreateUser(context.Background(), user)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to create user")
+
+	// Test duplicate email scenario
+	mock.ExpectQuery(`INSERT INTO users .*`).WillReturnError(&pq.Error{Code: "23505"})
+	err = repo.CreateUser(context.Background(), user)
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
 }
 
 func TestUserRepository_GetUserByID(t *testing.T) {
@@ -58,18 +65,19 @@ func TestUserRepository_GetUserByID(t *testing.T) {
 		CreatedAt: "time.Now()",
 	}
 
-	mock.ExpectQuery(`SELECT id, name, email, password, created_at FROM users WHERE id = \$1`).
+	mock.ExpectQuery(`SELECT id, name, email, password, created_at, email_verified_at IS NOT NULL, is_admin, deletion_scheduled_at, COALESCE\(external_id, ''\), deactivated_at, approval_pending FROM users WHERE id = \$1`).
 		WithArgs(userID).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at"}).
-			AddRow(expectedUser.ID, expectedUser.Name, expectedUser.Email, expectedUser.Password, expectedUser.CreatedAt))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "email_verified", "is_admin", "deletion_scheduled_at", "external_id", "deactivated_at", "approval_pending"}).
+			AddRow(expectedUser.ID, expectedUser.Name, expectedUser.Email, expectedUser.Password, expectedUser.CreatedAt, true, false, nil, "", nil, false))
 
 	result, err := repo.GetUserByID(context.Background(), userID)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedUser.ID, result.ID)
 	assert.Equal(t, expectedUser.Name, result.Name)
+	assert.True(t, result.EmailVerified)
 
 	// Test not found scenario
-	mock.ExpectQuery(`SELECT id, name, email, password, created_at FROM users WHERE id = \$1`).
+	mock.ExpectQuery(`SELECT id, name, email, password, created_at, email_verified_at IS NOT NULL, is_admin, deletion_scheduled_at, COALESCE\(external_id, ''\), deactivated_at, approval_pending FROM users WHERE id = \$1`).
 		WithArgs(userID).
 		WillReturnError(sql.ErrNoRows)
 
@@ -86,11 +94,11 @@ func TestUserRepository_GetAllUsers(t *testing.T) {
 
 	repo := NewUserRepository(mockDB)
 
-	rows := sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at"}).
-		AddRow(1, "User 1", "user1@example.com", "password1", time.Now()).
-		AddRow(2, "User 2", "user2@example.com", "password2", time.Now())
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "email_verified", "is_admin", "deletion_scheduled_at", "external_id", "deactivated_at", "approval_pending"}).
+		AddRow(1, "User 1", "user1@example.com", "password1", time.Now(), true, true, nil, "", nil, false).
+		AddRow(2, "User 2", "user2@example.com", "password2", time.Now(), false, false, time.Now(), "ext-2", time.Now(), false)
 
-	mock.ExpectQuery(`SELECT id, name, email, password, created_at FROM users`).
+	mock.ExpectQuery(`SELECT id, name, email, password, created_at, email_verified_at IS NOT NULL, is_admin, deletion_scheduled_at, COALESCE\(external_id, ''\), deactivated_at, approval_pending FROM users`).
 		WillReturnRows(rows)
 
 	users, err := repo.GetAllUsers(context.Background())
@@ -100,7 +108,7 @@ func TestUserRepository_GetAllUsers(t *testing.T) {
 	assert.Equal(t, "User 2", users[1].Name)
 
 	// Test error scenario
-	mock.ExpectQuery(`SELECT id, name, email, password, created_at FROM users`).
+	mock.ExpectQuery(`SELECT id, name, email, password, created_at, email_verified_at IS NOT NULL, is_admin, deletion_scheduled_at, COALESCE\(external_id, ''\), deactivated_at, approval_pending FROM users`).
 		WillReturnError(errors.New("db error"))
 
 	users, err = repo.GetAllUsers(context.Background())
@@ -143,3 +151,214 @@ func TestUserRepository_DeleteUser(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to delete user")
 }
+
+func TestUserRepository_UpdatePassword(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE users SET password = \$1 WHERE id = \$2`).
+		WithArgs("newhash", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.UpdatePassword(context.Background(), 1, "newhash")
+	assert.NoError(t, err)
+
+	// Test not found scenario
+	mock.ExpectExec(`UPDATE users SET password = \$1 WHERE id = \$2`).
+		WithArgs("newhash", 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.UpdatePassword(context.Background(), 2, "newhash")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestUserRepository_MarkEmailVerified(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE users SET email_verified_at = COALESCE\(email_verified_at, NOW\(\)\) WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.MarkEmailVerified(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepository_UpdateEmail(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE users SET email = \$1, email_verified_at = NOW\(\) WHERE id = \$2`).
+		WithArgs("new@example.com", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = repo.UpdateEmail(context.Background(), 1, "new@example.com")
+	assert.NoError(t, err)
+
+	// Test duplicate email scenario
+	mock.ExpectExec(`UPDATE users SET email = \$1`).WillReturnError(&pq.Error{Code: "23505"})
+	err = repo.UpdateEmail(context.Background(), 1, "taken@example.com")
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+}
+
+func TestUserRepository_ScheduledDeletion(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserRepository(mockDB)
+	at := time.Now().Add(24 * time.Hour)
+
+	mock.ExpectExec(`UPDATE users SET deletion_scheduled_at = \$1 WHERE id = \$2`).
+		WithArgs(at, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.ScheduleDeletion(context.Background(), 1, at))
+
+	mock.ExpectExec(`UPDATE users SET deletion_scheduled_at = NULL WHERE id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.CancelDeletion(context.Background(), 1))
+
+	mock.ExpectExec(`DELETE FROM users WHERE deletion_scheduled_at <= \$1`).
+		WithArgs(at).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	deleted, err := repo.DeleteScheduledUsers(context.Background(), at)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+}
+
+func TestUserRepository_CreateProvisionedUser(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserRepository(mockDB)
+
+	user := &models.User{Name: "Jane", Email: "jane@example.com", ExternalID: "ext-1"}
+	mock.ExpectQuery(`INSERT INTO users \(name, email, password, external_id, email_verified_at, deactivated_at\)`).
+		WithArgs("Jane", "jane@example.com", "", "ext-1", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+
+	assert.NoError(t, repo.CreateProvisionedUser(context.Background(), user))
+	assert.Equal(t, 7, user.ID)
+	assert.True(t, user.EmailVerified)
+
+	mock.ExpectQuery(`INSERT INTO users`).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "users_external_id_key"})
+	err = repo.CreateProvisionedUser(context.Background(), &models.User{Name: "Jane", Email: "jane2@example.com", ExternalID: "ext-1"})
+	assert.ErrorIs(t, err, ErrDuplicateExternalID)
+}
+
+func TestUserRepository_SetUserActive(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE users SET deactivated_at = CASE WHEN \$1 THEN NULL ELSE COALESCE\(deactivated_at, NOW\(\)\) END WHERE id = \$2`).
+		WithArgs(false, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.SetUserActive(context.Background(), 7, false))
+
+	mock.ExpectExec(`UPDATE users SET deactivated_at`).
+		WithArgs(true, 8).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.SetUserActive(context.Background(), 8, true), ErrUserNotFound)
+}
+
+func TestUserRepository_SearchUsers(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserRepository(mockDB)
+
+	filter, err := scim.ParseFilter(`userName eq "Jane@Example.com" and active eq true`)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE \(LOWER\(email\) = \$1 AND deactivated_at IS NULL\)`).
+		WithArgs("jane@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT .* FROM users WHERE \(LOWER\(email\) = \$1 AND deactivated_at IS NULL\) ORDER BY id LIMIT \$2 OFFSET \$3`).
+		WithArgs("jane@example.com", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "email_verified", "is_admin", "deletion_scheduled_at", "external_id", "deactivated_at", "approval_pending"}).
+			AddRow(3, "Jane", "jane@example.com", "", time.Now(), true, false, nil, "ext-1", nil, false))
+
+	users, total, err := repo.SearchUsers(context.Background(), filter, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, "ext-1", users[0].ExternalID)
+	assert.True(t, users[0].IsActive())
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	filter, _ = scim.ParseFilter(`title eq "Engineer"`)
+	_, _, err = repo.SearchUsers(context.Background(), filter, 0, 10)
+	assert.ErrorIs(t, err, ErrUnsupportedFilter)
+}
+
+func TestUserFilterSQL(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+		args   []interface{}
+	}{
+		{`externalId eq "ext-1"`, `LOWER(external_id) = $1`, []interface{}{"ext-1"}},
+		{`emails.value co "50%_off"`, `LOWER(email) LIKE '%' || $1 || '%'`, []interface{}{`50\%\_off`}},
+		{`displayName sw "J" or id eq "3"`, `(LOWER(name) LIKE $1 || '%' OR id = $2)`, []interface{}{"j", 3}},
+		{`not (active eq false)`, `NOT COALESCE(deactivated_at IS NOT NULL, FALSE)`, nil},
+		{`externalId pr`, `(external_id IS NOT NULL AND external_id::text <> '')`, nil},
+		{`meta.created gt "2024-01-02T03:04:05Z"`, `created_at > $1`, []interface{}{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}},
+	}
+	for _, tt := range tests {
+		filter, err := scim.ParseFilter(tt.filter)
+		assert.NoError(t, err)
+
+		var args []interface{}
+		got, err := userFilterSQL(filter, &args)
+		assert.NoError(t, err, tt.filter)
+		assert.Equal(t, tt.want, got, tt.filter)
+		assert.Equal(t, tt.args, args, tt.filter)
+	}
+
+	for _, invalid := range []string{`id eq "abc"`, `active co "t"`, `userName gt 3`, `meta.created gt "yesterday"`} {
+		filter, err := scim.ParseFilter(invalid)
+		assert.NoError(t, err)
+		_, err = userFilterSQL(filter, new([]interface{}))
+		assert.ErrorIs(t, err, ErrUnsupportedFilter, invalid)
+	}
+}
+
+func TestUserRepository_PendingUsers(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserRepository(mockDB)
+
+	mock.ExpectQuery(`SELECT .* FROM users WHERE approval_pending ORDER BY created_at, id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "email_verified", "is_admin", "deletion_scheduled_at", "external_id", "deactivated_at", "approval_pending"}).
+			AddRow(5, "Jane", "jane@example.com", "hash", time.Now(), false, false, nil, "", nil, true))
+
+	users, err := repo.ListPendingUsers(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.True(t, users[0].PendingApproval)
+
+	mock.ExpectExec(`UPDATE users SET approval_pending = FALSE WHERE id = \$1`).WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, repo.ApproveUser(context.Background(), 5))
+
+	mock.ExpectExec(`UPDATE users SET approval_pending = FALSE WHERE id = \$1`).WithArgs(6).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.ApproveUser(context.Background(), 6), ErrUserNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
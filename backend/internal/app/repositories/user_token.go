@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+)
+
+type UserTokenRepository struct {
+	DB *sql.DB
+}
+
+func NewUserTokenRepository(db *sql.DB) *UserTokenRepository {
+	return &UserTokenRepository{DB: db}
+}
+
+// CreateToken stores a new single-use token
+func (r *UserTokenRepository) CreateToken(ctx context.Context, token *models.UserToken) error {
+	query := `INSERT INTO user_tokens (user_id, purpose, token_hash, data, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+	err := r.DB.QueryRowContext(ctx, query, token.UserID, token.Purpose, token.TokenHash, token.Data, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	return nil
+}
+
+// GetToken returns an unused, unexpired token without consuming it.
+func (r *UserTokenRepository) GetToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	token := &models.UserToken{}
+	query := `SELECT id, user_id, purpose, token_hash, data, expires_at, used_at, created_at FROM user_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()`
+	err := r.DB.QueryRowContext(ctx, query, tokenHash, purpose).
+		Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.Data, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTokenNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	return token, nil
+}
+
+// ConsumeToken atomically marks an unused, unexpired token as used and
+// returns it. A token can therefore be consumed at most once.
+func (r *UserTokenRepository) ConsumeToken(ctx context.Context, purpose, tokenHash string) (*models.UserToken, error) {
+	token := &models.UserToken{}
+	query := `UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, purpose, token_hash, data, expires_at, used_at, created_at`
+	err := r.DB.QueryRowContext(ctx, query, tokenHash, purpose).
+		Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.Data, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTokenNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to consume token: %w", err)
+	}
+	return token, nil
+}
+
+// InvalidateTokens marks every unused token of a user with the given purpose as used
+func (r *UserTokenRepository) InvalidateTokens(ctx context.Context, userID int, purpose string) error {
+	query := `UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	if _, err := r.DB.ExecContext(ctx, query, userID, purpose); err != nil {
+		return fmt.Errorf("failed to invalidate tokens: %w", err)
+	}
+	return nil
+}
+
+var _ UserTokenRepoInterface = (*UserTokenRepository)(nil)
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserTokenRepository_CreateToken(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserTokenRepository(mockDB)
+
+	token := &models.UserToken{
+		UserID:    1,
+		Purpose:   models.TokenPurposeResetPassword,
+		TokenHash: "hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mock.ExpectQuery(`INSERT INTO user_tokens .*`).
+		WithArgs(token.UserID, token.Purpose, token.TokenHash, token.Data, token.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+
+	err = repo.CreateToken(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, token.ID)
+}
+
+func TestUserTokenRepository_GetToken(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserTokenRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM user_tokens\s+WHERE token_hash = \$1 AND purpose = \$2 AND used_at IS NULL AND expires_at > NOW\(\)`).
+		WithArgs("hash", models.TokenPurposeResetPassword).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "purpose", "token_hash", "data", "expires_at", "used_at", "created_at"}).
+			AddRow(1, 7, models.TokenPurposeResetPassword, "hash", "", now.Add(time.Hour), nil, now))
+
+	token, err := repo.GetToken(context.Background(), models.TokenPurposeResetPassword, "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, token.UserID)
+	assert.Nil(t, token.UsedAt)
+
+	// Test used or expired token scenario
+	mock.ExpectQuery(`SELECT .* FROM user_tokens`).
+		WithArgs("hash", models.TokenPurposeResetPassword).
+		WillReturnError(sql.ErrNoRows)
+
+	token, err = repo.GetToken(context.Background(), models.TokenPurposeResetPassword, "hash")
+	assert.Nil(t, token)
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+}
+
+func TestUserTokenRepository_ConsumeToken(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserTokenRepository(mockDB)
+
+	now := time.Now()
+	mock.ExpectQuery(`UPDATE user_tokens SET used_at = NOW\(\)\s+WHERE token_hash = \$1 AND purpose = \$2 AND used_at IS NULL AND expires_at > NOW\(\)`).
+		WithArgs("hash", models.TokenPurposeVerifyEmail).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "purpose", "token_hash", "data", "expires_at", "used_at", "created_at"}).
+			AddRow(1, 7, models.TokenPurposeVerifyEmail, "hash", "", now.Add(time.Hour), now, now))
+
+	token, err := repo.ConsumeToken(context.Background(), models.TokenPurposeVerifyEmail, "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, token.UserID)
+	assert.NotNil(t, token.UsedAt)
+
+	// Test used or expired token scenario
+	mock.ExpectQuery(`UPDATE user_tokens SET used_at = NOW\(\)`).
+		WithArgs("hash", models.TokenPurposeVerifyEmail).
+		WillReturnError(sql.ErrNoRows)
+
+	token, err = repo.ConsumeToken(context.Background(), models.TokenPurposeVerifyEmail, "hash")
+	assert.Nil(t, token)
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+}
+
+func TestUserTokenRepository_InvalidateTokens(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewUserTokenRepository(mockDB)
+
+	mock.ExpectExec(`UPDATE user_tokens SET used_at = NOW\(\) WHERE user_id = \$1 AND purpose = \$2 AND used_at IS NULL`).
+		WithArgs(7, models.TokenPurposeResetPassword).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = repo.InvalidateTokens(context.Background(), 7, models.TokenPurposeResetPassword)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,274 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+// webhookColumns lists the columns read by scanWebhook, in order.
+const webhookColumns = `id, user_id, url, secret, event_types, active, failure_count, disabled_at, created_at, updated_at`
+
+// webhookDeliveryColumns lists the columns read by scanWebhookDelivery, in order.
+const webhookDeliveryColumns = `id, webhook_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at,
+	response_status, response_body, error, created_at`
+
+// scanWebhook reads a row selected with webhookColumns into webhook.
+func scanWebhook(row rowScanner, webhook *models.Webhook) error {
+	return row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &webhook.Secret, (*pq.StringArray)(&webhook.EventTypes),
+		&webhook.Active, &webhook.FailureCount, &webhook.DisabledAt, &webhook.CreatedAt, &webhook.UpdatedAt)
+}
+
+// scanWebhookDelivery reads a row selected with webhookDeliveryColumns into
+// delivery.
+func scanWebhookDelivery(row rowScanner, delivery *models.WebhookDelivery) error {
+	var payload []byte
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventType, &payload, &delivery.Status, &delivery.Attempts,
+		&delivery.NextAttemptAt, &delivery.LastAttemptAt, &delivery.ResponseStatus, &delivery.ResponseBody, &delivery.Error, &delivery.CreatedAt)
+	delivery.Payload = payload
+	return err
+}
+
+type WebhookRepository struct {
+	DB *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{DB: db}
+}
+
+// CreateWebhook stores a new webhook
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	query := `INSERT INTO webhooks (user_id, url, secret, event_types, active)
+		VALUES ($1, $2, $3, $4, $5) RETURNING ` + webhookColumns
+	err := scanWebhook(r.DB.QueryRowContext(ctx, query, webhook.UserID, webhook.URL, webhook.Secret,
+		pq.StringArray(webhook.EventTypes), webhook.Active), webhook)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+// ListWebhooks retrieves the webhooks of a user, oldest first
+func (r *WebhookRepository) ListWebhooks(ctx context.Context, userID int) ([]models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE user_id = $1 ORDER BY id`
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := scanWebhook(rows, &webhook); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhook retrieves a webhook of a user
+func (r *WebhookRepository) GetWebhook(ctx context.Context, userID int, id int) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1 AND user_id = $2`
+	err := scanWebhook(r.DB.QueryRowContext(ctx, query, id, userID), webhook)
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook saves the settings and the failure state of a webhook
+func (r *WebhookRepository) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	query := `UPDATE webhooks SET url = $3, secret = $4, event_types = $5, active = $6, failure_count = $7,
+		disabled_at = $8, updated_at = NOW() WHERE id = $1 AND user_id = $2 RETURNING updated_at`
+	err := r.DB.QueryRowContext(ctx, query, webhook.ID, webhook.UserID, webhook.URL, webhook.Secret,
+		pq.StringArray(webhook.EventTypes), webhook.Active, webhook.FailureCount, webhook.DisabledAt).Scan(&webhook.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrWebhookNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebhook removes a webhook of a user together with its deliveries
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, userID int, id int) error {
+	query := `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`
+	result, err := r.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected during delete: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// EnqueueDeliveries queues payload for every active webhook of a user
+// subscribed to eventType and returns the number of deliveries queued
+func (r *WebhookRepository) EnqueueDeliveries(ctx context.Context, userID int, eventType string, payload []byte) (int64, error) {
+	query := `INSERT INTO webhook_deliveries (webhook_id, event_type, payload, next_attempt_at)
+		SELECT id, $2, $3, NOW() FROM webhooks WHERE user_id = $1 AND active AND $2 = ANY(event_types)`
+	result, err := r.DB.ExecContext(ctx, query, userID, eventType, string(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ClaimDeliveries takes up to limit pending deliveries of active webhooks
+// that are due, and postpones them by lease so that no other instance
+// sends them in the meantime. The end of the lease identifies the claim. The URL and secret of their webhook are set.
+func (r *WebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	query := `WITH claimed AS (
+			UPDATE webhook_deliveries SET next_attempt_at = $2
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				AND webhook_id IN (SELECT id FROM webhooks WHERE active)
+				ORDER BY next_attempt_at LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING ` + webhookDeliveryColumns + `
+		)
+		SELECT claimed.*, webhooks.url, webhooks.secret FROM claimed
+		JOIN webhooks ON webhooks.id = claimed.webhook_id ORDER BY claimed.id`
+	// Leases are stored to the microsecond, and compared when the attempt
+	// is recorded
+	rows, err := r.DB.QueryContext(ctx, query, limit, time.Now().Add(lease).Truncate(time.Microsecond))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var payload []byte
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventType, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastAttemptAt,
+			&d.ResponseStatus, &d.ResponseBody, &d.Error, &d.CreatedAt, &d.URL, &d.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		d.Payload = payload
+		if d.NextAttemptAt != nil {
+			d.ClaimedUntil = *d.NextAttemptAt
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return deliveries, nil
+}
+
+// CompleteDelivery records an attempt at sending a claimed delivery. The
+// failure count of its webhook is reset when the attempt succeeded, and
+// increased otherwise; reaching disableAfter disables the webhook, which is
+// reported. ErrWebhookDeliveryLost is returned, and nothing recorded, when
+// the lease of the claim ended and the delivery was claimed again.
+func (r *WebhookRepository) CompleteDelivery(ctx context.Context, delivery *models.WebhookDelivery, disableAfter int) (bool, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE webhook_deliveries SET status = $2, attempts = $3, next_attempt_at = $4, last_attempt_at = $5,
+		response_status = $6, response_body = $7, error = $8
+		WHERE id = $1 AND status = 'pending' AND next_attempt_at = $9`
+	result, err := tx.ExecContext(ctx, query, delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt,
+		delivery.LastAttemptAt, delivery.ResponseStatus, delivery.ResponseBody, delivery.Error, delivery.ClaimedUntil)
+	if err != nil {
+		return false, fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return false, fmt.Errorf("failed to update webhook delivery: %w", err)
+	} else if n == 0 {
+		return false, ErrWebhookDeliveryLost
+	}
+
+	disabled := false
+	if delivery.Status == models.WebhookDeliverySucceeded {
+		_, err = tx.ExecContext(ctx, `UPDATE webhooks SET failure_count = 0 WHERE id = $1`, delivery.WebhookID)
+	} else {
+		query = `UPDATE webhooks SET failure_count = failure_count + 1,
+			active = active AND failure_count + 1 < $2,
+			disabled_at = CASE WHEN active AND failure_count + 1 >= $2 THEN NOW() ELSE disabled_at END
+			WHERE id = $1 RETURNING active`
+		var active bool
+		err = tx.QueryRowContext(ctx, query, delivery.WebhookID, disableAfter).Scan(&active)
+		disabled = !active
+		if err == sql.ErrNoRows {
+			// The webhook has been deleted along with the delivery
+			err, disabled = nil, false
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update webhook failure count: %w", err)
+	}
+	return disabled, tx.Commit()
+}
+
+// ListDeliveries retrieves up to limit deliveries of a webhook older than
+// beforeID, newest first. A zero beforeID starts with the newest delivery.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID int, beforeID int64, limit int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2 = 0 OR id < $2) ORDER BY id DESC LIMIT $3`
+	rows, err := r.DB.QueryContext(ctx, query, webhookID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &delivery); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver queues a new delivery of the payload of a past delivery of a
+// webhook
+func (r *WebhookRepository) Redeliver(ctx context.Context, webhookID int, id int64) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	query := `INSERT INTO webhook_deliveries (webhook_id, event_type, payload, next_attempt_at)
+		SELECT webhook_id, event_type, payload, NOW() FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2
+		RETURNING ` + webhookDeliveryColumns
+	err := scanWebhookDelivery(r.DB.QueryRowContext(ctx, query, id, webhookID), delivery)
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookDeliveryNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+var _ WebhookRepoInterface = (*WebhookRepository)(nil)
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var webhookDeliveryRowColumns = []string{"id", "webhook_id", "event_type", "payload", "status", "attempts", "next_attempt_at",
+	"last_attempt_at", "response_status", "response_body", "error", "created_at"}
+
+func TestWebhookRepository_EnqueueDeliveries(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewWebhookRepository(mockDB)
+
+	mock.ExpectExec(`INSERT INTO webhook_deliveries .* SELECT id, \$2, \$3, NOW\(\) FROM webhooks WHERE user_id = \$1 AND active AND \$2 = ANY\(event_types\)`).
+		WithArgs(1, models.EventTodoCreated, `{"id":"evt_1"}`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	n, err := repo.EnqueueDeliveries(context.Background(), 1, models.EventTodoCreated, []byte(`{"id":"evt_1"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_ClaimDeliveries(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewWebhookRepository(mockDB)
+
+	now := time.Now()
+	columns := append(append([]string{}, webhookDeliveryRowColumns...), "url", "secret")
+	mock.ExpectQuery(`FOR UPDATE SKIP LOCKED`).
+		WithArgs(20, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(3, 7, models.EventTodoCreated, []byte(`{}`), models.WebhookDeliveryPending, 1, now, now, 500, "oops", "", now,
+				"https://example.com/hook", "whsec_secret"))
+
+	deliveries, err := repo.ClaimDeliveries(context.Background(), 20, time.Minute)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, int64(3), deliveries[0].ID)
+		assert.Equal(t, "https://example.com/hook", deliveries[0].URL)
+		assert.Equal(t, "whsec_secret", deliveries[0].Secret)
+		assert.Equal(t, 500, *deliveries[0].ResponseStatus)
+		assert.Equal(t, now, deliveries[0].ClaimedUntil)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_CompleteDelivery(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewWebhookRepository(mockDB)
+
+	lease := time.Now().Truncate(time.Microsecond)
+	delivery := &models.WebhookDelivery{ID: 3, WebhookID: 7, Status: models.WebhookDeliveryPending, Attempts: 2, ClaimedUntil: lease}
+
+	// The failure that reaches the threshold disables the webhook
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE webhook_deliveries SET status = \$2, .* WHERE id = \$1 AND status = 'pending' AND next_attempt_at = \$9`).
+		WithArgs(int64(3), models.WebhookDeliveryPending, 2, nil, nil, nil, "", "", lease).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE webhooks SET failure_count = failure_count \+ 1`).
+		WithArgs(7, 25).
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(false))
+	mock.ExpectCommit()
+
+	disabled, err := repo.CompleteDelivery(context.Background(), delivery, 25)
+	assert.NoError(t, err)
+	assert.True(t, disabled)
+
+	// Successes reset the failure count
+	delivery.Status = models.WebhookDeliverySucceeded
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE webhook_deliveries SET status = \$2`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE webhooks SET failure_count = 0 WHERE id = \$1`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	disabled, err = repo.CompleteDelivery(context.Background(), delivery, 25)
+	assert.NoError(t, err)
+	assert.False(t, disabled)
+
+	// The lease ended and another instance claimed the delivery: neither
+	// the delivery nor the failure count of its webhook is touched
+	delivery.Status = models.WebhookDeliveryPending
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE webhook_deliveries SET status = \$2`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	disabled, err = repo.CompleteDelivery(context.Background(), delivery, 25)
+	assert.ErrorIs(t, err, ErrWebhookDeliveryLost)
+	assert.False(t, disabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_RedeliverNotFound(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	repo := NewWebhookRepository(mockDB)
+
+	mock.ExpectQuery(`INSERT INTO webhook_deliveries .* FROM webhook_deliveries WHERE id = \$1 AND webhook_id = \$2`).
+		WithArgs(int64(3), 7).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.Redeliver(context.Background(), 7, 3)
+	assert.ErrorIs(t, err, ErrWebhookDeliveryNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+)
+
+// accessTokenDisplayLength is how many leading characters of a token are
+// stored in the clear so that users can recognise it in listings.
+const accessTokenDisplayLength = len(models.AccessTokenPrefix) + 6
+
+var errInvalidAccessToken = NewUnauthorizedError("invalid_token", "invalid authorization token")
+
+// AccessTokenService manages personal access tokens.
+type AccessTokenService struct {
+	TokenRepo repositories.AccessTokenRepoInterface
+	UserRepo  repositories.UserRepoInterface
+	// Audit records issued tokens. It is optional.
+	Audit AuditRecorder
+}
+
+// NewAccessTokenService initializes a new AccessTokenService.
+func NewAccessTokenService(tokenRepo repositories.AccessTokenRepoInterface, userRepo repositories.UserRepoInterface) *AccessTokenService {
+	return &AccessTokenService{TokenRepo: tokenRepo, UserRepo: userRepo}
+}
+
+// CreateToken issues a new token for the user and returns it together with
+// its secret value, which is not stored and cannot be retrieved again.
+// The admin scope can only be granted by admins.
+func (s *AccessTokenService) CreateToken(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*models.PersonalAccessToken, string, error) {
+	var fields []FieldError
+	if strings.TrimSpace(name) == "" {
+		fields = append(fields, FieldError{Field: "name", Message: "is required"})
+	}
+	if len(scopes) == 0 {
+		fields = append(fields, FieldError{Field: "scopes", Message: "is required"})
+	}
+	for _, scope := range scopes {
+		if !isKnownScope(scope) {
+			fields = append(fields, FieldError{Field: "scopes", Message: fmt.Sprintf("unknown scope %q", scope)})
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		fields = append(fields, FieldError{Field: "expires_at", Message: "must be in the future"})
+	}
+	if len(fields) > 0 {
+		return nil, "", NewValidationError("invalid access token request", fields...)
+	}
+
+	if containsString(scopes, models.ScopeAdmin) {
+		user, err := s.UserRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, "", userRepoError(err)
+		}
+		if !user.IsAdmin {
+			return nil, "", NewForbiddenError("admin_required", "only admins can grant the admin scope")
+		}
+	}
+
+	secret, err := generateToken(models.AccessTokenPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := &models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    secret[:accessTokenDisplayLength],
+		TokenHash: utils.HashToken(secret),
+		Scopes:    dedupeStrings(scopes),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.TokenRepo.CreateAccessToken(ctx, token); err != nil {
+		return nil, "", err
+	}
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditTokenIssued,
+		ActorID:    &userID,
+		TargetType: models.AuditTargetToken,
+		TargetID:   strconv.Itoa(token.ID),
+		Metadata: auditMetadata(map[string]interface{}{
+			"type": "personal_access_token", "name": token.Name, "prefix": token.Prefix, "scopes": token.Scopes, "expires_at": token.ExpiresAt,
+		}),
+	})
+	return token, secret, nil
+}
+
+// ListTokens retrieves the tokens of the user.
+func (s *AccessTokenService) ListTokens(ctx context.Context, userID int) ([]models.PersonalAccessToken, error) {
+	return s.TokenRepo.ListAccessTokens(ctx, userID)
+}
+
+// DeleteToken revokes a token of the user.
+func (s *AccessTokenService) DeleteToken(ctx context.Context, userID int, id int) error {
+	err := s.TokenRepo.DeleteAccessToken(ctx, userID, id)
+	if errors.Is(err, repositories.ErrAccessTokenNotFound) {
+		return NewNotFoundError("access_token_not_found", "access token not found")
+	}
+	return err
+}
+
+// Authenticate returns the unexpired token matching secret and records its use.
+func (s *AccessTokenService) Authenticate(ctx context.Context, secret string) (*models.PersonalAccessToken, error) {
+	if !strings.HasPrefix(secret, models.AccessTokenPrefix) {
+		return nil, errInvalidAccessToken
+	}
+
+	token, err := s.TokenRepo.UseAccessToken(ctx, utils.HashToken(secret))
+	if errors.Is(err, repositories.ErrAccessTokenNotFound) {
+		return nil, errInvalidAccessToken
+	}
+	return token, err
+}
+
+// generateToken returns a random secret that starts with prefix.
+func generateToken(prefix string) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("error generating token: %w", err)
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+func isKnownScope(scope string) bool {
+	return containsString(models.Scopes, scope)
+}
+
+var _ AccessTokenServiceInterface = (*AccessTokenService)(nil)
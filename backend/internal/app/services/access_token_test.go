@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockAccessTokenRepo is a mock implementation of repositories.AccessTokenRepoInterface.
+type mockAccessTokenRepo struct {
+	mock.Mock
+}
+
+func (m *mockAccessTokenRepo) CreateAccessToken(ctx context.Context, token *models.PersonalAccessToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *mockAccessTokenRepo) ListAccessTokens(ctx context.Context, userID int) ([]models.PersonalAccessToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *mockAccessTokenRepo) DeleteAccessToken(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *mockAccessTokenRepo) UseAccessToken(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*models.PersonalAccessToken), args.Error(1)
+}
+
+func TestAccessTokenService_CreateToken(t *testing.T) {
+	tokenRepo := new(mockAccessTokenRepo)
+	service := NewAccessTokenService(tokenRepo, new(mockUserRepo))
+
+	var stored *models.PersonalAccessToken
+	tokenRepo.On("CreateAccessToken", mock.Anything, mock.AnythingOfType("*models.PersonalAccessToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*models.PersonalAccessToken) }).
+		Return(nil)
+
+	token, secret, err := service.CreateToken(context.Background(), 1, "ci", []string{models.ScopeTodosWrite, models.ScopeTodosWrite}, nil)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(secret, models.AccessTokenPrefix))
+	assert.True(t, strings.HasPrefix(secret, token.Prefix))
+	assert.Equal(t, utils.HashToken(secret), stored.TokenHash)
+	assert.NotContains(t, stored.TokenHash, secret)
+	assert.Equal(t, []string{models.ScopeTodosWrite}, stored.Scopes)
+}
+
+func TestAccessTokenService_CreateToken_Invalid(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	service := NewAccessTokenService(new(mockAccessTokenRepo), userRepo)
+	past := time.Now().Add(-time.Hour)
+
+	_, _, err := service.CreateToken(context.Background(), 1, "ci", []string{"todos:delete"}, nil)
+	assert.Equal(t, KindValidation, KindOf(err))
+
+	_, _, err = service.CreateToken(context.Background(), 1, "ci", []string{models.ScopeTodosRead}, &past)
+	assert.Equal(t, KindValidation, KindOf(err))
+
+	userRepo.On("GetUserByID", mock.Anything, 1).Return(&models.User{ID: 1}, nil)
+	_, _, err = service.CreateToken(context.Background(), 1, "ci", []string{models.ScopeAdmin}, nil)
+	assert.Equal(t, KindForbidden, KindOf(err))
+}
+
+func TestAccessTokenService_Authenticate(t *testing.T) {
+	tokenRepo := new(mockAccessTokenRepo)
+	service := NewAccessTokenService(tokenRepo, nil)
+
+	secret := models.AccessTokenPrefix + "valid"
+	tokenRepo.On("UseAccessToken", mock.Anything, utils.HashToken(secret)).Return(&models.PersonalAccessToken{ID: 3, UserID: 1}, nil)
+	tokenRepo.On("UseAccessToken", mock.Anything, mock.Anything).Return((*models.PersonalAccessToken)(nil), repositories.ErrAccessTokenNotFound)
+
+	token, err := service.Authenticate(context.Background(), secret)
+	require.NoError(t, err)
+	assert.Equal(t, 1, token.UserID)
+
+	_, err = service.Authenticate(context.Background(), models.AccessTokenPrefix+"revoked")
+	assert.Equal(t, KindUnauthorized, KindOf(err))
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/mailer"
+	"todo_app_backend/internal/password"
+)
+
+const (
+	verifyEmailTokenTTL   = 24 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+	changeEmailTokenTTL   = 24 * time.Hour
+)
+
+var errInvalidToken = NewValidationError("token is invalid or has expired",
+	FieldError{Field: "token", Message: "is invalid or has expired"})
+
+// AccountService handles email verification, email changes and password reset.
+type AccountService struct {
+	UserRepo  repositories.UserRepoInterface
+	TokenRepo repositories.UserTokenRepoInterface
+	Sessions  SessionServiceInterface
+	Mailer    mailer.Mailer
+	// BaseURL is the frontend URL that links in emails point to.
+	BaseURL string
+	// PasswordPolicy decides which passwords are accepted on reset.
+	PasswordPolicy *password.Policy
+}
+
+// NewAccountService initializes a new AccountService.
+func NewAccountService(userRepo repositories.UserRepoInterface, tokenRepo repositories.UserTokenRepoInterface, sessions SessionServiceInterface, m mailer.Mailer, baseURL string) *AccountService {
+	return &AccountService{UserRepo: userRepo, TokenRepo: tokenRepo, Sessions: sessions, Mailer: m, BaseURL: baseURL, PasswordPolicy: password.DefaultPolicy}
+}
+
+// SendVerificationEmail emails user a link to confirm their address.
+func (s *AccountService) SendVerificationEmail(ctx context.Context, user *models.User) error {
+	if user.EmailVerified {
+		return nil
+	}
+	return s.sendTokenEmail(ctx, user, user.Email, models.TokenPurposeVerifyEmail, "", verifyEmailTokenTTL, "/verify-email", "verify_email")
+}
+
+// ResendVerificationEmail sends a new verification link to email. Unknown or
+// already verified addresses are silently ignored so that the response does
+// not reveal which accounts exist.
+func (s *AccountService) ResendVerificationEmail(ctx context.Context, email string) error {
+	user, err := s.UserRepo.GetUserByEmail(ctx, email)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	s.sendInBackground(func(ctx context.Context) error {
+		return s.SendVerificationEmail(ctx, user)
+	})
+	return nil
+}
+
+// RequestEmailChange emails a confirmation link to newEmail. The address of
+// the user only changes once the link has been followed.
+func (s *AccountService) RequestEmailChange(ctx context.Context, user *models.User, newEmail string) error {
+	_, err := s.UserRepo.GetUserByEmail(ctx, newEmail)
+	if err == nil {
+		return userRepoError(repositories.ErrDuplicateEmail)
+	} else if !errors.Is(err, repositories.ErrUserNotFound) {
+		return err
+	}
+
+	// Only the most recently requested address can be confirmed
+	if err := s.TokenRepo.InvalidateTokens(ctx, user.ID, models.TokenPurposeChangeEmail); err != nil {
+		return err
+	}
+	return s.sendTokenEmail(ctx, user, newEmail, models.TokenPurposeChangeEmail, newEmail, changeEmailTokenTTL, "/verify-email", "change_email")
+}
+
+// VerifyEmail consumes a verification token and marks the address verified.
+// Tokens sent by RequestEmailChange replace the address of the user instead.
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	if _, err := utils.VerifySignedToken(models.TokenPurposeChangeEmail, token); err == nil {
+		userToken, err := s.consumeToken(ctx, models.TokenPurposeChangeEmail, token)
+		if err != nil {
+			return err
+		}
+		return userRepoError(s.UserRepo.UpdateEmail(ctx, userToken.UserID, userToken.Data))
+	}
+
+	userToken, err := s.consumeToken(ctx, models.TokenPurposeVerifyEmail, token)
+	if err != nil {
+		return err
+	}
+	return userRepoError(s.UserRepo.MarkEmailVerified(ctx, userToken.UserID))
+}
+
+// RequestPasswordReset emails a password reset link to email. Unknown
+// addresses are silently ignored and the email is sent in the background so
+// that neither the response nor its timing reveal which accounts exist.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.UserRepo.GetUserByEmail(ctx, email)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	s.sendInBackground(func(ctx context.Context) error {
+		return s.sendTokenEmail(ctx, user, user.Email, models.TokenPurposeResetPassword, "", resetPasswordTokenTTL, "/reset-password", "reset_password")
+	})
+	return nil
+}
+
+// ResetPassword consumes a reset token and sets a new password. Any other
+// outstanding reset tokens of the user are invalidated and all of their
+// sessions are revoked.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	if password == "" {
+		return NewValidationError("password is required", FieldError{Field: "password", Message: "is required"})
+	}
+
+	// The token is only used up once the password is accepted, so that a
+	// rejected password does not cost the user their reset link.
+	userToken, err := s.lookupToken(ctx, models.TokenPurposeResetPassword, token)
+	if err != nil {
+		return err
+	}
+	user, err := s.UserRepo.GetUserByID(ctx, userToken.UserID)
+	if err != nil {
+		return userRepoError(err)
+	}
+	if err := checkPassword(s.PasswordPolicy, "password", password, user.Name, user.Email); err != nil {
+		return err
+	}
+	if _, err := s.TokenRepo.ConsumeToken(ctx, models.TokenPurposeResetPassword, userToken.TokenHash); errors.Is(err, repositories.ErrTokenNotFound) {
+		// Used concurrently
+		return errInvalidToken
+	} else if err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.UserRepo.UpdatePassword(ctx, userToken.UserID, hashedPassword); err != nil {
+		return userRepoError(err)
+	}
+
+	// Receiving the reset link proves ownership of the address
+	if err := s.UserRepo.MarkEmailVerified(ctx, userToken.UserID); err != nil {
+		return userRepoError(err)
+	}
+	if err := s.TokenRepo.InvalidateTokens(ctx, userToken.UserID, models.TokenPurposeResetPassword); err != nil {
+		return err
+	}
+	return s.Sessions.RevokeAllSessions(ctx, userToken.UserID)
+}
+
+// sendTokenEmail creates a token for purpose carrying data and emails a link
+// containing it to the address to.
+func (s *AccountService) sendTokenEmail(ctx context.Context, user *models.User, to string, purpose string, data string, ttl time.Duration, path string, templateName string) error {
+	token, hash, err := utils.GenerateSignedToken(purpose)
+	if err != nil {
+		return err
+	}
+
+	if err := s.TokenRepo.CreateToken(ctx, &models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		Data:      data,
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return err
+	}
+
+	msg, err := mailer.NewMessage(to, templateName, map[string]interface{}{
+		"Name":      user.Name,
+		"Link":      s.BaseURL + path + "?token=" + url.QueryEscape(token),
+		"Email":     to,
+		"ExpiresIn": formatTTL(ttl),
+	})
+	if err != nil {
+		return err
+	}
+	return s.Mailer.Send(ctx, msg)
+}
+
+// consumeToken verifies the signature of token and marks it used.
+func (s *AccountService) consumeToken(ctx context.Context, purpose, token string) (*models.UserToken, error) {
+	hash, err := utils.VerifySignedToken(purpose, token)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	userToken, err := s.TokenRepo.ConsumeToken(ctx, purpose, hash)
+	if errors.Is(err, repositories.ErrTokenNotFound) {
+		return nil, errInvalidToken
+	}
+	return userToken, err
+}
+
+// lookupToken verifies the signature of token and returns it without
+// marking it used.
+func (s *AccountService) lookupToken(ctx context.Context, purpose, token string) (*models.UserToken, error) {
+	hash, err := utils.VerifySignedToken(purpose, token)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	userToken, err := s.TokenRepo.GetToken(ctx, purpose, hash)
+	if errors.Is(err, repositories.ErrTokenNotFound) {
+		return nil, errInvalidToken
+	}
+	return userToken, err
+}
+
+// sendInBackground runs send detached from the request so that slow mail
+// delivery neither delays nor is cancelled with the response.
+func (s *AccountService) sendInBackground(send func(ctx context.Context) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := send(ctx); err != nil {
+			log.Printf("failed to send account email: %v", err)
+		}
+	}()
+}
+
+func formatTTL(ttl time.Duration) string {
+	if ttl%time.Hour == 0 {
+		if hours := int(ttl / time.Hour); hours != 1 {
+			return fmt.Sprintf("%d hours", hours)
+		}
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d minutes", int(ttl/time.Minute))
+}
+
+var _ AccountServiceInterface = (*AccountService)(nil)
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/mailer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockUserTokenRepo is a mock implementation of repositories.UserTokenRepoInterface.
+type mockUserTokenRepo struct {
+	mock.Mock
+}
+
+func (m *mockUserTokenRepo) CreateToken(ctx context.Context, token *models.UserToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *mockUserTokenRepo) GetToken(ctx context.Context, purpose, hash string) (*models.UserToken, error) {
+	args := m.Called(ctx, purpose, hash)
+	return args.Get(0).(*models.UserToken), args.Error(1)
+}
+
+func (m *mockUserTokenRepo) ConsumeToken(ctx context.Context, purpose, hash string) (*models.UserToken, error) {
+	args := m.Called(ctx, purpose, hash)
+	return args.Get(0).(*models.UserToken), args.Error(1)
+}
+
+func (m *mockUserTokenRepo) InvalidateTokens(ctx context.Context, userID int, purpose string) error {
+	args := m.Called(ctx, userID, purpose)
+	return args.Error(0)
+}
+
+// chanMailer delivers sent messages on a channel.
+type chanMailer chan mailer.Message
+
+func (c chanMailer) Send(ctx context.Context, msg mailer.Message) error {
+	c <- msg
+	return nil
+}
+
+func setTestConfigEnv(t *testing.T) {
+	t.Setenv("DB_URI", "postgres://test")
+	t.Setenv("MAX_IDLE_CONNS", "1")
+	t.Setenv("MAX_OPEN_CONNS", "1")
+}
+
+// tokenFromMessage extracts the token query parameter from the link in msg.
+func tokenFromMessage(t *testing.T, msg mailer.Message) string {
+	start := strings.Index(msg.Text, "http://app.test/")
+	require.GreaterOrEqual(t, start, 0, "message contains no link")
+	link := strings.Fields(msg.Text[start:])[0]
+
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	return parsed.Query().Get("token")
+}
+
+func receive(t *testing.T, sent chanMailer) mailer.Message {
+	select {
+	case msg := <-sent:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no email was sent")
+		return mailer.Message{}
+	}
+}
+
+func TestAccountService_PasswordReset(t *testing.T) {
+	setTestConfigEnv(t)
+	userRepo := new(mockUserRepo)
+	tokenRepo := new(mockUserTokenRepo)
+	sessions := new(mockSessionService)
+	sent := make(chanMailer, 1)
+	service := NewAccountService(userRepo, tokenRepo, sessions, sent, "http://app.test")
+
+	user := &models.User{ID: 7, Name: "John", Email: "john@example.com"}
+	userRepo.On("GetUserByEmail", mock.Anything, user.Email).Return(user, nil)
+
+	var stored *models.UserToken
+	tokenRepo.On("CreateToken", mock.Anything, mock.AnythingOfType("*models.UserToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*models.UserToken) }).
+		Return(nil)
+
+	require.NoError(t, service.RequestPasswordReset(context.Background(), user.Email))
+
+	msg := receive(t, sent)
+	assert.Equal(t, []string{user.Email}, msg.To)
+	assert.Contains(t, msg.HTML, "/reset-password?token=")
+	token := tokenFromMessage(t, msg)
+	assert.NotContains(t, stored.TokenHash, token, "only the hash must be stored")
+	assert.Equal(t, models.TokenPurposeResetPassword, stored.Purpose)
+
+	tokenRepo.On("GetToken", mock.Anything, models.TokenPurposeResetPassword, stored.TokenHash).
+		Return(&models.UserToken{UserID: user.ID, TokenHash: stored.TokenHash}, nil)
+	userRepo.On("GetUserByID", mock.Anything, user.ID).Return(user, nil)
+
+	// A weak password is rejected without using up the token
+	err := service.ResetPassword(context.Background(), token, "password1")
+	assert.Equal(t, KindValidation, KindOf(err))
+	// So is a password containing the name or email of the user
+	err = service.ResetPassword(context.Background(), token, "john@example.com")
+	assert.Equal(t, KindValidation, KindOf(err))
+	tokenRepo.AssertNotCalled(t, "ConsumeToken", mock.Anything, mock.Anything, mock.Anything)
+
+	tokenRepo.On("ConsumeToken", mock.Anything, models.TokenPurposeResetPassword, stored.TokenHash).
+		Return(&models.UserToken{UserID: user.ID}, nil).Once()
+	tokenRepo.On("InvalidateTokens", mock.Anything, user.ID, models.TokenPurposeResetPassword).Return(nil)
+	userRepo.On("UpdatePassword", mock.Anything, user.ID, mock.AnythingOfType("string")).Return(nil)
+	userRepo.On("MarkEmailVerified", mock.Anything, user.ID).Return(nil)
+	sessions.On("RevokeAllSessions", mock.Anything, user.ID).Return(nil)
+
+	require.NoError(t, service.ResetPassword(context.Background(), token, "new-password"))
+
+	// A token used concurrently is rejected
+	tokenRepo.On("ConsumeToken", mock.Anything, models.TokenPurposeResetPassword, stored.TokenHash).
+		Return((*models.UserToken)(nil), repositories.ErrTokenNotFound)
+	err = service.ResetPassword(context.Background(), token, "new-password")
+	assert.Equal(t, KindValidation, KindOf(err))
+
+	userRepo.AssertExpectations(t)
+	tokenRepo.AssertExpectations(t)
+	sessions.AssertExpectations(t)
+}
+
+func TestAccountService_RequestPasswordReset_UnknownEmail(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	tokenRepo := new(mockUserTokenRepo)
+	service := NewAccountService(userRepo, tokenRepo, nil, make(chanMailer), "http://app.test")
+
+	userRepo.On("GetUserByEmail", mock.Anything, "nobody@example.com").Return((*models.User)(nil), repositories.ErrUserNotFound)
+
+	assert.NoError(t, service.RequestPasswordReset(context.Background(), "nobody@example.com"))
+	tokenRepo.AssertNotCalled(t, "CreateToken", mock.Anything, mock.Anything)
+}
+
+func TestAccountService_VerifyEmail(t *testing.T) {
+	setTestConfigEnv(t)
+	userRepo := new(mockUserRepo)
+	tokenRepo := new(mockUserTokenRepo)
+	sessions := new(mockSessionService)
+	sent := make(chanMailer, 1)
+	service := NewAccountService(userRepo, tokenRepo, sessions, sent, "http://app.test")
+
+	user := &models.User{ID: 3, Name: "Jane", Email: "jane@example.com"}
+	var stored *models.UserToken
+	tokenRepo.On("CreateToken", mock.Anything, mock.AnythingOfType("*models.UserToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*models.UserToken) }).
+		Return(nil)
+
+	require.NoError(t, service.SendVerificationEmail(context.Background(), user))
+	token := tokenFromMessage(t, receive(t, sent))
+
+	// A token issued for another purpose is rejected before hitting the database
+	err := service.ResetPassword(context.Background(), token, "new-password")
+	assert.Equal(t, KindValidation, KindOf(err))
+
+	tokenRepo.On("ConsumeToken", mock.Anything, models.TokenPurposeVerifyEmail, stored.TokenHash).
+		Return(&models.UserToken{UserID: user.ID}, nil)
+	userRepo.On("MarkEmailVerified", mock.Anything, user.ID).Return(nil)
+
+	require.NoError(t, service.VerifyEmail(context.Background(), token))
+	userRepo.AssertExpectations(t)
+}
+
+func TestAccountService_VerifyEmail_Tampered(t *testing.T) {
+	setTestConfigEnv(t)
+	service := NewAccountService(new(mockUserRepo), new(mockUserTokenRepo), nil, make(chanMailer), "http://app.test")
+
+	err := service.VerifyEmail(context.Background(), "bm9uY2U.c2lnbmF0dXJl")
+	assert.Equal(t, KindValidation, KindOf(err))
+}
+
+func TestAccountService_EmailChange(t *testing.T) {
+	setTestConfigEnv(t)
+	userRepo := new(mockUserRepo)
+	tokenRepo := new(mockUserTokenRepo)
+	sent := make(chanMailer, 1)
+	service := NewAccountService(userRepo, tokenRepo, nil, sent, "http://app.test")
+
+	user := &models.User{ID: 3, Name: "Jane", Email: "jane@example.com"}
+	userRepo.On("GetUserByEmail", mock.Anything, "taken@example.com").Return(&models.User{ID: 4}, nil)
+	userRepo.On("GetUserByEmail", mock.Anything, "new@example.com").Return((*models.User)(nil), repositories.ErrUserNotFound)
+	tokenRepo.On("InvalidateTokens", mock.Anything, user.ID, models.TokenPurposeChangeEmail).Return(nil)
+
+	var stored *models.UserToken
+	tokenRepo.On("CreateToken", mock.Anything, mock.AnythingOfType("*models.UserToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*models.UserToken) }).
+		Return(nil)
+
+	err := service.RequestEmailChange(context.Background(), user, "taken@example.com")
+	assert.Equal(t, KindConflict, KindOf(err))
+
+	require.NoError(t, service.RequestEmailChange(context.Background(), user, "new@example.com"))
+	msg := receive(t, sent)
+	assert.Equal(t, []string{"new@example.com"}, msg.To)
+	assert.Equal(t, "new@example.com", stored.Data)
+
+	tokenRepo.On("ConsumeToken", mock.Anything, models.TokenPurposeChangeEmail, stored.TokenHash).
+		Return(&models.UserToken{UserID: user.ID, Data: "new@example.com"}, nil)
+	userRepo.On("UpdateEmail", mock.Anything, user.ID, "new@example.com").Return(nil)
+
+	require.NoError(t, service.VerifyEmail(context.Background(), tokenFromMessage(t, msg)))
+	userRepo.AssertExpectations(t)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAuditRepo struct {
+	mock.Mock
+}
+
+func (m *mockAuditRepo) AppendEntry(ctx context.Context, entry *models.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *mockAuditRepo) ListEntries(ctx context.Context, filter models.AuditFilter, limit int) ([]models.AuditEntry, error) {
+	args := m.Called(ctx, filter, limit)
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+func (m *mockAuditRepo) EachEntry(ctx context.Context, filter models.AuditFilter, fn func(entry *models.AuditEntry) error) error {
+	args := m.Called(ctx, filter, fn)
+	for _, entry := range args.Get(0).([]models.AuditEntry) {
+		entry := entry
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+// recordedAudit returns an AuditService that keeps the entries it records.
+func recordedAudit() (*AuditService, *[]models.AuditEntry) {
+	repo := new(mockAuditRepo)
+	var entries []models.AuditEntry
+	repo.On("AppendEntry", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { entries = append(entries, *args.Get(1).(*models.AuditEntry)) }).
+		Return(nil)
+	return NewAuditService(repo), &entries
+}
+
+func TestAuditService_Record(t *testing.T) {
+	service, entries := recordedAudit()
+
+	ctx := WithRequestInfo(context.Background(), RequestInfo{IP: "10.0.0.1", RequestID: "req-1"})
+	ctx = context.WithValue(ctx, "userID", 4)
+	service.Record(ctx, &models.AuditEntry{Action: models.AuditUserDeleted, TargetType: models.AuditTargetUser, TargetID: "7"})
+
+	require.Len(t, *entries, 1)
+	entry := (*entries)[0]
+	assert.Equal(t, "10.0.0.1", entry.IP)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, 4, *entry.ActorID)
+	assert.Equal(t, json.RawMessage("{}"), entry.Metadata)
+	assert.False(t, entry.CreatedAt.IsZero())
+
+	// Actors named by the entry are kept
+	actorID := 9
+	service.Record(ctx, &models.AuditEntry{Action: models.AuditLogin, ActorID: &actorID})
+	assert.Equal(t, 9, *(*entries)[1].ActorID)
+}
+
+func TestAuditService_Verify(t *testing.T) {
+	chain := make([]models.AuditEntry, 3)
+	prevHash := ""
+	for i := range chain {
+		chain[i] = models.AuditEntry{ID: int64(i + 1), Action: models.AuditLogin, Metadata: json.RawMessage(`{}`), PrevHash: prevHash}
+		chain[i].Hash = chain[i].ComputeHash()
+		prevHash = chain[i].Hash
+	}
+
+	repo := new(mockAuditRepo)
+	service := NewAuditService(repo)
+	repo.On("EachEntry", mock.Anything, models.AuditFilter{}, mock.Anything).Return(chain, nil).Once()
+
+	result, err := service.Verify(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, &models.AuditVerification{Valid: true, Entries: 3}, result)
+
+	// Altering an entry breaks its hash
+	tampered := append([]models.AuditEntry(nil), chain...)
+	tampered[1].Action = models.AuditLoginFailed
+	repo.On("EachEntry", mock.Anything, models.AuditFilter{}, mock.Anything).Return(tampered, nil).Once()
+
+	result, err = service.Verify(context.Background())
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(2), *result.BrokenAt)
+
+	// Removing an entry breaks the chain
+	removed := []models.AuditEntry{chain[0], chain[2]}
+	repo.On("EachEntry", mock.Anything, models.AuditFilter{}, mock.Anything).Return(removed, nil).Once()
+
+	result, err = service.Verify(context.Background())
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(3), *result.BrokenAt)
+}
+
+func TestAuditService_ExportIsAudited(t *testing.T) {
+	repo := new(mockAuditRepo)
+	service := NewAuditService(repo)
+
+	filter := models.AuditFilter{Action: models.AuditUserDeleted}
+	repo.On("AppendEntry", mock.Anything, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.AuditLogExported && string(entry.Metadata) == `{"filter":{"action":"user.deleted"}}`
+	})).Return(nil)
+	repo.On("EachEntry", mock.Anything, filter, mock.Anything).Return([]models.AuditEntry{{ID: 1}, {ID: 2}}, nil)
+
+	exported := 0
+	err := service.Export(context.Background(), filter, func(entry *models.AuditEntry) error {
+		exported++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, exported)
+	repo.AssertExpectations(t)
+}
+
+func TestAuditService_ListEntriesLimit(t *testing.T) {
+	repo := new(mockAuditRepo)
+	service := NewAuditService(repo)
+
+	repo.On("ListEntries", mock.Anything, models.AuditFilter{}, DefaultAuditPageSize).Return([]models.AuditEntry{}, nil)
+	repo.On("ListEntries", mock.Anything, models.AuditFilter{}, MaxAuditPageSize).Return([]models.AuditEntry{}, nil)
+
+	_, err := service.ListEntries(context.Background(), models.AuditFilter{}, 0)
+	assert.NoError(t, err)
+	_, err = service.ListEntries(context.Background(), models.AuditFilter{}, 10000)
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
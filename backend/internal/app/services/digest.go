@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/mailer"
+)
+
+const (
+	// DefaultDigestHour is the hour digests are sent at in the timezone of
+	// their user.
+	DefaultDigestHour = 7
+	// digestBatchSize is the number of due digests claimed at once.
+	digestBatchSize = 100
+	// maxDigestItems caps the todos listed in each section of a digest.
+	maxDigestItems = 10
+)
+
+// DigestService sends users the email digest of their todos at the
+// frequency they chose: the todos due that day or week, the overdue ones,
+// and those completed the day or week before.
+type DigestService struct {
+	Preferences repositories.NotificationPreferenceRepoInterface
+	Todos       repositories.TodoRepoInterface
+	Users       repositories.UserRepoInterface
+	Jobs        JobEnqueuer
+	Mailer      mailer.Mailer
+	// BaseURL is the frontend URL that links in emails point to.
+	BaseURL string
+	// APIBaseURL is the public URL of this API, which mail clients post
+	// one-click unsubscriptions to.
+	APIBaseURL string
+	// Hour is the hour digests are sent at in the timezone of their user.
+	Hour int
+
+	now func() time.Time
+}
+
+// NewDigestService initializes a new DigestService.
+func NewDigestService(prefs repositories.NotificationPreferenceRepoInterface, todos repositories.TodoRepoInterface, users repositories.UserRepoInterface, jobs JobEnqueuer, m mailer.Mailer) *DigestService {
+	return &DigestService{
+		Preferences: prefs,
+		Todos:       todos,
+		Users:       users,
+		Jobs:        jobs,
+		Mailer:      m,
+		Hour:        DefaultDigestHour,
+		now:         time.Now,
+	}
+}
+
+// SendDueDigests queues the sending of the digests that are due. It
+// returns the number of digests queued.
+func (s *DigestService) SendDueDigests(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		due, err := s.Preferences.ClaimDueDigests(ctx, s.now(), s.Hour, digestBatchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, recipient := range due {
+			key := fmt.Sprintf("digest:%d@%s", recipient.UserID, recipient.Date)
+			_, err := s.Jobs.Enqueue(ctx, models.JobDeliverDigest, recipient, JobOptions{UniqueKey: key})
+			if err != nil && !errors.Is(err, repositories.ErrDuplicateJob) {
+				return sent, err
+			}
+			sent++
+		}
+
+		if len(due) < digestBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// digestItem is a todo as listed in a digest email.
+type digestItem struct {
+	Title string
+	When  string
+}
+
+// SendDigest composes the digest of a recipient and emails it. Nothing is
+// sent when the digest lists no todo.
+func (s *DigestService) SendDigest(ctx context.Context, recipient *models.DigestRecipient) error {
+	user, err := s.Users.GetUserByID(ctx, recipient.UserID)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !user.IsActive() {
+		return nil
+	}
+
+	loc, err := time.LoadLocation(recipient.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, err := time.ParseInLocation("2006-01-02", recipient.Date, loc)
+	if err != nil {
+		return PermanentJobError(fmt.Errorf("invalid digest date %q", recipient.Date))
+	}
+	weekly := recipient.Frequency == models.DigestWeekly
+	days := 1
+	if weekly {
+		days = 7
+	}
+	digest, err := s.Todos.GetDigest(ctx, user.ID, start, start.AddDate(0, 0, days), start.AddDate(0, 0, -days))
+	if err != nil {
+		return err
+	}
+	if digest.Empty() {
+		return nil
+	}
+
+	token, err := utils.SignValue(models.TokenPurposeUnsubscribeDigest, strconv.Itoa(user.ID))
+	if err != nil {
+		return err
+	}
+
+	dueFormat, date := "15:04", start.Format("Monday, January 2")
+	if weekly {
+		dueFormat, date = "Mon 15:04", start.Format("January 2")
+	}
+	data := map[string]interface{}{
+		"Name":            user.Name,
+		"Weekly":          weekly,
+		"Date":            date,
+		"Link":            s.BaseURL,
+		"UnsubscribeLink": s.BaseURL + "/digest/unsubscribe?token=" + url.QueryEscape(token),
+	}
+	data["Overdue"], data["OverdueMore"] = digestItems(digest.Overdue, func(todo models.DigestTodo) string {
+		return "due " + todo.DueDate.In(loc).Format("Mon Jan 2")
+	})
+	data["Due"], data["DueMore"] = digestItems(digest.Due, func(todo models.DigestTodo) string {
+		return todo.DueDate.In(loc).Format(dueFormat)
+	})
+	data["Completed"], data["CompletedMore"] = digestItems(digest.Completed, func(models.DigestTodo) string { return "" })
+
+	msg, err := mailer.NewMessage(user.Email, "digest", data)
+	if err != nil {
+		return PermanentJobError(err)
+	}
+	// One-click unsubscription as specified in RFC 8058
+	msg.Headers = map[string]string{
+		"List-Unsubscribe":      "<" + s.APIBaseURL + "/api/v1/digest/unsubscribe?token=" + url.QueryEscape(token) + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	return s.Mailer.Send(ctx, msg)
+}
+
+// digestItems returns up to maxDigestItems of todos as listed in a digest,
+// and the number of todos left out.
+func digestItems(todos []models.DigestTodo, when func(models.DigestTodo) string) ([]digestItem, int) {
+	items := make([]digestItem, 0, maxDigestItems)
+	for i, todo := range todos {
+		if i == maxDigestItems {
+			return items, len(todos) - maxDigestItems
+		}
+		items = append(items, digestItem{Title: todo.Title, When: when(todo)})
+	}
+	return items, 0
+}
+
+// Unsubscribe turns off the digest of the user an unsubscribe link was
+// sent to.
+func (s *DigestService) Unsubscribe(ctx context.Context, token string) error {
+	value, err := utils.VerifySignedValue(models.TokenPurposeUnsubscribeDigest, token)
+	if err != nil {
+		return errInvalidToken
+	}
+	userID, err := strconv.Atoi(value)
+	if err != nil {
+		return errInvalidToken
+	}
+	if _, err := s.Users.GetUserByID(ctx, userID); errors.Is(err, repositories.ErrUserNotFound) {
+		// Deleted users get no digest anyway
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return s.Preferences.SetDigestFrequency(ctx, userID, models.DigestOff)
+}
+
+var _ DigestServiceInterface = (*DigestService)(nil)
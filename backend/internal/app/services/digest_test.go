@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestService_SendDueDigests(t *testing.T) {
+	prefs := new(mockPreferenceRepo)
+	jobs := new(mockJobEnqueuer)
+	service := NewDigestService(prefs, new(mockTodoRepo), new(mockUserRepo), jobs, make(chanMailer))
+	service.now = func() time.Time { return timeAt(7, 0) }
+
+	recipient := models.DigestRecipient{UserID: 1, Frequency: models.DigestDaily, Timezone: "UTC", Date: "2024-05-15"}
+	prefs.On("ClaimDueDigests", mock.Anything, timeAt(7, 0), DefaultDigestHour, digestBatchSize).
+		Return([]models.DigestRecipient{recipient}, nil)
+	jobs.On("Enqueue", mock.Anything, models.JobDeliverDigest, recipient, JobOptions{UniqueKey: "digest:1@2024-05-15"}).Return(nil)
+
+	n, err := service.SendDueDigests(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	jobs.AssertExpectations(t)
+}
+
+func TestDigestService_SendDigest(t *testing.T) {
+	setTestConfigEnv(t)
+	prefs := new(mockPreferenceRepo)
+	todos := new(mockTodoRepo)
+	users := new(mockUserRepo)
+	sent := make(chanMailer, 1)
+	service := NewDigestService(prefs, todos, users, new(mockJobEnqueuer), sent)
+	service.BaseURL = "http://app.test"
+	service.APIBaseURL = "http://api.test"
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	start := time.Date(2024, 5, 15, 0, 0, 0, 0, paris)
+	overdue := time.Date(2024, 5, 13, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2024, 5, 15, 16, 0, 0, 0, time.UTC)
+	completed := time.Date(2024, 5, 14, 10, 0, 0, 0, time.UTC)
+	digest := &models.Digest{
+		Overdue:   []models.DigestTodo{{ID: 1, Title: "Pay rent", DueDate: &overdue}},
+		Due:       []models.DigestTodo{{ID: 2, Title: "Call mum", DueDate: &due}},
+		Completed: []models.DigestTodo{{ID: 3, Title: "Buy milk", CompletedAt: &completed}},
+	}
+	for i := 0; i < maxDigestItems+2; i++ {
+		digest.Due = append(digest.Due, models.DigestTodo{ID: 10 + i, Title: fmt.Sprintf("Todo %d", i), DueDate: &due})
+	}
+	users.On("GetUserByID", mock.Anything, 1).Return(&models.User{ID: 1, Name: "John", Email: "john@example.com"}, nil)
+	todos.On("GetDigest", mock.Anything, 1, start, start.AddDate(0, 0, 1), start.AddDate(0, 0, -1)).Return(digest, nil)
+
+	err = service.SendDigest(context.Background(), &models.DigestRecipient{
+		UserID: 1, Frequency: models.DigestDaily, Timezone: "Europe/Paris", Date: "2024-05-15",
+	})
+	require.NoError(t, err)
+
+	msg := receive(t, sent)
+	assert.Equal(t, []string{"john@example.com"}, msg.To)
+	assert.Equal(t, "Your todos for Wednesday, May 15", msg.Subject)
+	// Dates are shown in the timezone of the user
+	assert.Contains(t, msg.Text, "Pay rent (due Mon May 13)")
+	assert.Contains(t, msg.Text, "Due today:\n  - Call mum (18:00)")
+	assert.Contains(t, msg.Text, "and 3 more")
+	assert.Contains(t, msg.Text, "Completed yesterday:\n  - Buy milk")
+	assert.Contains(t, msg.HTML, "<li>Buy milk</li>")
+	assert.Equal(t, "List-Unsubscribe=One-Click", msg.Headers["List-Unsubscribe-Post"])
+	assert.Regexp(t, `^<http://api\.test/api/v1/digest/unsubscribe\?token=.+>$`, msg.Headers["List-Unsubscribe"])
+
+	// The unsubscribe link turns the digest off
+	prefs.On("SetDigestFrequency", mock.Anything, 1, models.DigestOff).Return(nil)
+	require.NoError(t, service.Unsubscribe(context.Background(), tokenFromMessage(t, msg)))
+	prefs.AssertExpectations(t)
+}
+
+func TestDigestService_SendDigest_Weekly(t *testing.T) {
+	setTestConfigEnv(t)
+	todos := new(mockTodoRepo)
+	users := new(mockUserRepo)
+	sent := make(chanMailer, 1)
+	service := NewDigestService(new(mockPreferenceRepo), todos, users, new(mockJobEnqueuer), sent)
+
+	start := time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)
+	completed := time.Date(2024, 5, 10, 10, 0, 0, 0, time.UTC)
+	users.On("GetUserByID", mock.Anything, 1).Return(&models.User{ID: 1, Name: "John", Email: "john@example.com"}, nil)
+	todos.On("GetDigest", mock.Anything, 1, start, start.AddDate(0, 0, 7), start.AddDate(0, 0, -7)).
+		Return(&models.Digest{Completed: []models.DigestTodo{{ID: 3, Title: "Buy milk", CompletedAt: &completed}}}, nil)
+
+	err := service.SendDigest(context.Background(), &models.DigestRecipient{
+		UserID: 1, Frequency: models.DigestWeekly, Timezone: "UTC", Date: "2024-05-13",
+	})
+	require.NoError(t, err)
+
+	msg := receive(t, sent)
+	assert.Equal(t, "Your todos for the week of May 13", msg.Subject)
+	assert.Contains(t, msg.Text, "Completed last week:\n  - Buy milk")
+}
+
+func TestDigestService_SendDigest_Empty(t *testing.T) {
+	todos := new(mockTodoRepo)
+	users := new(mockUserRepo)
+	sent := make(chanMailer, 1)
+	service := NewDigestService(new(mockPreferenceRepo), todos, users, new(mockJobEnqueuer), sent)
+
+	users.On("GetUserByID", mock.Anything, 1).Return(&models.User{ID: 1, Name: "John", Email: "john@example.com"}, nil)
+	todos.On("GetDigest", mock.Anything, 1, mock.Anything, mock.Anything, mock.Anything).Return(&models.Digest{}, nil)
+
+	err := service.SendDigest(context.Background(), &models.DigestRecipient{
+		UserID: 1, Frequency: models.DigestDaily, Timezone: "UTC", Date: "2024-05-15",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, sent)
+}
+
+func TestDigestService_Unsubscribe_Tampered(t *testing.T) {
+	setTestConfigEnv(t)
+	service := NewDigestService(new(mockPreferenceRepo), new(mockTodoRepo), new(mockUserRepo), new(mockJobEnqueuer), make(chanMailer))
+
+	err := service.Unsubscribe(context.Background(), "MQ.c2lnbmF0dXJl")
+	assert.Equal(t, KindValidation, KindOf(err))
+}
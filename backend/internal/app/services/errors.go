@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrorKind classifies a domain error so that transport layers can map it
+// to an appropriate response without inspecting error strings.
+type ErrorKind int
+
+const (
+	KindInternal ErrorKind = iota
+	KindValidation
+	KindNotFound
+	KindConflict
+	KindForbidden
+	KindUnauthorized
+	KindTooManyRequests
+)
+
+// FieldError describes a single invalid input field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a typed domain error returned by the service layer.
+// Code is a stable, machine readable identifier (e.g. "todo_not_found").
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+
+	// RetryAfter tells the client when to try again (KindTooManyRequests only).
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewValidationError returns an error describing invalid input.
+func NewValidationError(message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: "validation_failed", Message: message, Fields: fields}
+}
+
+// NewNotFoundError returns an error for a missing resource.
+func NewNotFoundError(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+// NewConflictError returns an error for a request that conflicts with existing state.
+func NewConflictError(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// NewForbiddenError returns an error for an action the caller may not perform.
+func NewForbiddenError(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+// NewUnauthorizedError returns an error for missing or invalid credentials.
+func NewUnauthorizedError(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// NewTooManyRequestsError returns an error for a client that must back off for retryAfter.
+func NewTooManyRequestsError(code, message string, retryAfter time.Duration) *Error {
+	return &Error{Kind: KindTooManyRequests, Code: code, Message: message, RetryAfter: retryAfter}
+}
+
+// KindOf returns the kind of err, or KindInternal if err is not a domain error.
+func KindOf(err error) ErrorKind {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind
+	}
+	return KindInternal
+}
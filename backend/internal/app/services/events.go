@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"todo_app_backend/internal/app/models"
+)
+
+const (
+	// DefaultEventReplaySize is the number of recent events kept for clients
+	// resuming a stream.
+	DefaultEventReplaySize = 1024
+	// eventSubscriptionBuffer is the number of events a subscriber may fall
+	// behind before it is dropped.
+	eventSubscriptionBuffer = 64
+)
+
+// EventPublisher publishes domain events, usually on an eventbus.Bus.
+type EventPublisher interface {
+	Publish(ctx context.Context, event *models.Event) error
+}
+
+// EventPublishers publishes events with each of its publishers in turn.
+type EventPublishers []EventPublisher
+
+// Publish publishes event with every publisher, even if some of them fail.
+func (p EventPublishers) Publish(ctx context.Context, event *models.Event) error {
+	var errs []error
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// publishEvent publishes an event of the given type with data for userID,
+// unless events are disabled. Failures are logged rather than returned, as
+// the change the event describes has already been made.
+func publishEvent(ctx context.Context, publisher EventPublisher, userID int, eventType string, data interface{}) {
+	if publisher == nil {
+		return
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("failed to encode %s event: %v", eventType, err)
+		return
+	}
+	if err := publisher.Publish(ctx, &models.Event{Type: eventType, UserID: userID, Data: encoded}); err != nil {
+		log.Printf("failed to publish %s event: %v", eventType, err)
+	}
+}
+
+// EventSubscriptionEnd is the reason an EventSubscription ended.
+type EventSubscriptionEnd int
+
+const (
+	// EventSubscriptionCancelled subscriptions were unsubscribed.
+	EventSubscriptionCancelled EventSubscriptionEnd = iota
+	// EventSubscriptionDropped subscribers fell too far behind.
+	EventSubscriptionDropped
+	// EventSubscriptionReset subscriptions were ended by a reset of the
+	// broker, after events may have been lost.
+	EventSubscriptionReset
+)
+
+// EventSubscription receives the events of a user. C is closed when the
+// subscription ends; End tells why.
+type EventSubscription struct {
+	C <-chan models.Event
+
+	userID int
+	events chan models.Event
+	end    EventSubscriptionEnd
+}
+
+// End reports why the subscription ended. It may only be called once C is
+// closed.
+func (s *EventSubscription) End() EventSubscriptionEnd {
+	return s.end
+}
+
+// EventBroker fans events out to the subscribers of their user and keeps the
+// most recent ones so that clients can resume after a disconnection.
+type EventBroker struct {
+	mu          sync.Mutex
+	lastID      int64
+	replay      []models.Event
+	next        int // index of replay the next event is written to
+	subscribers map[int]map[*EventSubscription]struct{}
+}
+
+// NewEventBroker initializes a new EventBroker keeping the last replaySize
+// events.
+func NewEventBroker(replaySize int) *EventBroker {
+	return &EventBroker{
+		// Each broker numbers events from a random point, so that the IDs
+		// seen by clients of another instance or from before a restart
+		// are unknown and lead to a resync rather than a wrong replay.
+		// IDs stay below 2^53 for JavaScript clients.
+		lastID:      rand.Int63n(1<<20) << 33,
+		replay:      make([]models.Event, 0, replaySize),
+		subscribers: make(map[int]map[*EventSubscription]struct{}),
+	}
+}
+
+// Deliver publishes an event received from the event bus. A resync event
+// without a user means that the bus may have lost events: every subscriber
+// is dropped and the events kept for replay are forgotten, so that clients
+// reconnect and reload their data.
+func (b *EventBroker) Deliver(event models.Event) {
+	if event.Type == models.EventResync && event.UserID == 0 {
+		b.reset()
+		return
+	}
+	b.Publish(context.Background(), &event)
+}
+
+// reset drops every subscriber and makes the IDs of all past events too
+// old to replay.
+func (b *EventBroker) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	b.replay, b.next = b.replay[:0], 0
+	for _, subs := range b.subscribers {
+		for sub := range subs {
+			sub.end = EventSubscriptionReset
+			b.remove(sub)
+		}
+	}
+}
+
+// Publish numbers event and delivers it to the subscribers of its user.
+// Subscribers too slow to take it are dropped rather than waited for.
+func (b *EventBroker) Publish(ctx context.Context, event *models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	if len(b.replay) < cap(b.replay) {
+		b.replay = append(b.replay, *event)
+	} else if cap(b.replay) > 0 {
+		b.replay[b.next] = *event
+		b.next = (b.next + 1) % cap(b.replay)
+	}
+
+	for sub := range b.subscribers[event.UserID] {
+		select {
+		case sub.events <- *event:
+		default:
+			sub.end = EventSubscriptionDropped
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe starts delivering the events of userID. If lastEventID is not
+// zero, the events published after it are returned for replay. If some of
+// them are no longer kept, a single resync event is returned instead.
+func (b *EventBroker) Subscribe(userID int, lastEventID int64) (*EventSubscription, []models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan models.Event, eventSubscriptionBuffer)
+	sub := &EventSubscription{C: events, userID: userID, events: events}
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[*EventSubscription]struct{})
+	}
+	b.subscribers[userID][sub] = struct{}{}
+
+	if lastEventID == 0 || lastEventID == b.lastID {
+		return sub, nil
+	}
+	oldest := b.lastID + 1
+	if len(b.replay) > 0 {
+		oldest = b.replay[b.next%len(b.replay)].ID
+	}
+	if lastEventID < oldest-1 || lastEventID > b.lastID {
+		return sub, []models.Event{{ID: b.lastID, Type: models.EventResync, UserID: userID, Data: json.RawMessage("{}")}}
+	}
+
+	var replay []models.Event
+	for i := range b.replay {
+		event := b.replay[(b.next+i)%len(b.replay)]
+		if event.ID > lastEventID && event.UserID == userID {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay
+}
+
+// Unsubscribe stops delivering events to sub and closes its channel.
+func (b *EventBroker) Unsubscribe(sub *EventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// remove forgets sub and closes its channel. b.mu must be held.
+func (b *EventBroker) remove(sub *EventSubscription) {
+	subs, ok := b.subscribers[sub.userID]
+	if _, subscribed := subs[sub]; !ok || !subscribed {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subscribers, sub.userID)
+	}
+	close(sub.events)
+}
+
+var _ EventBrokerInterface = (*EventBroker)(nil)
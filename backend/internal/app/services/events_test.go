@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBroker_PublishAndReplay(t *testing.T) {
+	broker := NewEventBroker(3)
+
+	sub, replay := broker.Subscribe(1, 0)
+	assert.Empty(t, replay)
+
+	var ids []int64
+	for i := 0; i < 4; i++ {
+		event := &models.Event{Type: models.EventTodoCreated, UserID: 1 + i%2}
+		broker.Publish(context.Background(), event)
+		ids = append(ids, event.ID)
+	}
+
+	// Only the events of the subscriber's user are delivered
+	assert.Equal(t, ids[0], (<-sub.C).ID)
+	assert.Equal(t, ids[2], (<-sub.C).ID)
+	broker.Unsubscribe(sub)
+	_, open := <-sub.C
+	assert.False(t, open)
+	assert.Equal(t, EventSubscriptionCancelled, sub.End())
+
+	// Events after the last one seen are replayed
+	_, replay = broker.Subscribe(1, ids[1])
+	require.Len(t, replay, 1)
+	assert.Equal(t, ids[2], replay[0].ID)
+
+	// The first event is no longer kept, so resuming after it is not
+	// possible, nor is resuming from an unknown ID
+	_, replay = broker.Subscribe(1, ids[0]-1)
+	require.Len(t, replay, 1)
+	assert.Equal(t, models.EventResync, replay[0].Type)
+	assert.Equal(t, ids[3], replay[0].ID)
+	_, replay = broker.Subscribe(1, ids[3]+100)
+	assert.Equal(t, models.EventResync, replay[0].Type)
+
+	_, replay = broker.Subscribe(2, ids[3])
+	assert.Empty(t, replay)
+}
+
+func TestEventBroker_DropsSlowSubscribers(t *testing.T) {
+	broker := NewEventBroker(0)
+	sub, _ := broker.Subscribe(1, 0)
+
+	for i := 0; i <= eventSubscriptionBuffer; i++ {
+		broker.Publish(context.Background(), &models.Event{Type: models.EventTodoCreated, UserID: 1})
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Equal(t, eventSubscriptionBuffer, received)
+	assert.Equal(t, EventSubscriptionDropped, sub.End())
+
+	// Unsubscribing a dropped subscription is harmless
+	broker.Unsubscribe(sub)
+}
+
+func TestEventBroker_DeliverResync(t *testing.T) {
+	broker := NewEventBroker(8)
+	event := models.Event{Type: models.EventTodoCreated, UserID: 1}
+	broker.Deliver(event)
+	sub, _ := broker.Subscribe(1, 0)
+
+	// Events lost by the bus cannot be replayed
+	broker.Deliver(models.Event{Type: models.EventResync})
+	_, open := <-sub.C
+	assert.False(t, open)
+	assert.Equal(t, EventSubscriptionReset, sub.End())
+
+	_, replay := broker.Subscribe(1, broker.lastID-1)
+	require.Len(t, replay, 1)
+	assert.Equal(t, models.EventResync, replay[0].Type)
+}
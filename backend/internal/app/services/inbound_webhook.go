@@ -0,0 +1,372 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"unicode/utf8"
+)
+
+const (
+	// inboundWebhookDisplayLength is how many leading characters of a path
+	// token are stored in the clear so that users can recognise it.
+	inboundWebhookDisplayLength = len(models.InboundWebhookTokenPrefix) + 6
+	// InboundSignatureTolerance is how far the timestamp of a signed payload
+	// may be from the current time, which limits replays.
+	InboundSignatureTolerance = 5 * time.Minute
+	// maxInboundFieldLength caps the rendered size of each mapped field.
+	maxInboundFieldLength = 64 << 10
+	// maxInboundTags caps the number of tags of a todo created by a payload.
+	maxInboundTags = 32
+	// maxTodoTitleLength is the size of the title column. Longer titles
+	// rendered from payloads are cut.
+	maxTodoTitleLength = 255
+	// maxDedupKeyLength caps the rendered deduplication key.
+	maxDedupKeyLength = 255
+)
+
+var errInboundWebhookNotFound = NewNotFoundError("inbound_webhook_not_found", "inbound webhook not found")
+
+// inboundTemplateFuncs are the functions mapping templates can call besides
+// the text/template builtins.
+var inboundTemplateFuncs = template.FuncMap{
+	// join joins the elements of a JSON array with sep.
+	"join": func(sep string, list interface{}) string {
+		values, _ := list.([]interface{})
+		parts := make([]string, 0, len(values))
+		for _, v := range values {
+			parts = append(parts, fmt.Sprint(v))
+		}
+		return strings.Join(parts, sep)
+	},
+	// default returns value unless it is missing or empty.
+	"default": func(fallback string, value interface{}) string {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return fmt.Sprint(value)
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// errFieldTooLong stops templates rendering more than maxInboundFieldLength.
+var errFieldTooLong = errors.New("rendered value is too long")
+
+// cappedBuffer is a bytes.Buffer refusing to grow past maxInboundFieldLength.
+type cappedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > maxInboundFieldLength {
+		return 0, errFieldTooLong
+	}
+	return b.Buffer.Write(p)
+}
+
+// InboundWebhookService manages the inbound webhooks of users and turns the
+// payloads they receive into todos.
+type InboundWebhookService struct {
+	Repo     repositories.InboundWebhookRepoInterface
+	TodoRepo repositories.TodoRepoInterface
+	// Events publishes the todos created and updated by payloads. It is
+	// optional.
+	Events EventPublisher
+	// Audit records created and deleted inbound webhooks. It is optional.
+	Audit AuditRecorder
+}
+
+// NewInboundWebhookService initializes a new InboundWebhookService.
+func NewInboundWebhookService(repo repositories.InboundWebhookRepoInterface, todoRepo repositories.TodoRepoInterface) *InboundWebhookService {
+	return &InboundWebhookService{Repo: repo, TodoRepo: todoRepo}
+}
+
+// CreateInboundWebhook adds an inbound webhook turning payloads into todos
+// with mapping. It is returned with its path token and, when signed is set,
+// the secret payloads must be signed with; neither is revealed again.
+func (s *InboundWebhookService) CreateInboundWebhook(ctx context.Context, userID int, name string, mapping models.InboundMapping, signed bool) (*models.InboundWebhook, string, string, error) {
+	if err := validateInboundWebhook(name, mapping); err != nil {
+		return nil, "", "", err
+	}
+
+	token, err := generateToken(models.InboundWebhookTokenPrefix)
+	if err != nil {
+		return nil, "", "", err
+	}
+	secret := ""
+	if signed {
+		if secret, err = generateToken(models.WebhookSecretPrefix); err != nil {
+			return nil, "", "", err
+		}
+	}
+
+	webhook := &models.InboundWebhook{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    token[:inboundWebhookDisplayLength],
+		TokenHash: utils.HashToken(token),
+		Secret:    secret,
+		Mapping:   mapping,
+	}
+	if err := s.Repo.CreateInboundWebhook(ctx, webhook); err != nil {
+		return nil, "", "", err
+	}
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditInboundWebhookCreated,
+		TargetType: models.AuditTargetInboundWebhook,
+		TargetID:   strconv.Itoa(webhook.ID),
+		Metadata:   auditMetadata(map[string]interface{}{"name": webhook.Name, "signature_required": signed}),
+	})
+	return webhook, token, secret, nil
+}
+
+// ListInboundWebhooks retrieves the inbound webhooks of a user.
+func (s *InboundWebhookService) ListInboundWebhooks(ctx context.Context, userID int) ([]models.InboundWebhook, error) {
+	return s.Repo.ListInboundWebhooks(ctx, userID)
+}
+
+// GetInboundWebhook retrieves an inbound webhook of a user.
+func (s *InboundWebhookService) GetInboundWebhook(ctx context.Context, userID int, id int) (*models.InboundWebhook, error) {
+	webhook, err := s.Repo.GetInboundWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, inboundWebhookRepoError(err)
+	}
+	return webhook, nil
+}
+
+// UpdateInboundWebhook changes the name and mapping of an inbound webhook.
+// Setting signed on a webhook without a secret generates one, which is
+// returned; clearing it removes the secret.
+func (s *InboundWebhookService) UpdateInboundWebhook(ctx context.Context, userID int, id int, name string, mapping models.InboundMapping, signed bool) (*models.InboundWebhook, string, error) {
+	if err := validateInboundWebhook(name, mapping); err != nil {
+		return nil, "", err
+	}
+	webhook, err := s.GetInboundWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	webhook.Name, webhook.Mapping = strings.TrimSpace(name), mapping
+	secret := ""
+	if !signed {
+		webhook.Secret = ""
+	} else if webhook.Secret == "" {
+		if secret, err = generateToken(models.WebhookSecretPrefix); err != nil {
+			return nil, "", err
+		}
+		webhook.Secret = secret
+	}
+	if err := s.Repo.UpdateInboundWebhook(ctx, webhook); err != nil {
+		return nil, "", inboundWebhookRepoError(err)
+	}
+	return webhook, secret, nil
+}
+
+// DeleteInboundWebhook removes an inbound webhook of a user.
+func (s *InboundWebhookService) DeleteInboundWebhook(ctx context.Context, userID int, id int) error {
+	if err := s.Repo.DeleteInboundWebhook(ctx, userID, id); err != nil {
+		return inboundWebhookRepoError(err)
+	}
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditInboundWebhookDeleted,
+		TargetType: models.AuditTargetInboundWebhook,
+		TargetID:   strconv.Itoa(id),
+	})
+	return nil
+}
+
+// Receive turns a payload posted to the inbound webhook of token into a
+// todo. Payloads of webhooks with a secret must carry its signature, made
+// like those of outgoing webhooks. The todo is returned and whether it was
+// created rather than updated through its deduplication key.
+func (s *InboundWebhookService) Receive(ctx context.Context, token string, body []byte, timestamp, signature string) (*models.Todo, bool, error) {
+	webhook, err := s.Repo.UseInboundWebhook(ctx, utils.HashToken(token))
+	if errors.Is(err, repositories.ErrInboundWebhookNotFound) {
+		return nil, false, errInboundWebhookNotFound
+	} else if err != nil {
+		return nil, false, err
+	}
+	if webhook.Secret != "" {
+		if err := verifyInboundSignature(webhook.Secret, body, timestamp, signature, time.Now()); err != nil {
+			return nil, false, err
+		}
+	}
+
+	var payload interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, false, NewValidationError("payload must be JSON")
+	}
+
+	todo, key, err := renderInboundTodo(webhook.Mapping, payload)
+	if err != nil {
+		return nil, false, err
+	}
+	if key != "" {
+		// Keys are scoped to the webhook
+		key = fmt.Sprintf("inbound:%d:%s", webhook.ID, key)
+	}
+
+	created, err := s.TodoRepo.UpsertTodo(ctx, webhook.UserID, key, todo)
+	if err != nil {
+		return nil, false, err
+	}
+
+	eventType := models.EventTodoUpdated
+	if created {
+		eventType = models.EventTodoCreated
+	}
+	publishEvent(ctx, s.Events, webhook.UserID, eventType, todo)
+	return todo, created, nil
+}
+
+// verifyInboundSignature checks that signature signs body at timestamp with
+// secret, and that timestamp is close to now.
+func verifyInboundSignature(secret string, body []byte, timestamp, signature string, now time.Time) error {
+	errInvalid := NewUnauthorizedError("invalid_signature", "missing or invalid payload signature")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return errInvalid
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > InboundSignatureTolerance || skew < -InboundSignatureTolerance {
+		return errInvalid
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignWebhook(secret, ts, body))) {
+		return errInvalid
+	}
+	return nil
+}
+
+// renderInboundTodo executes mapping with payload and returns the todo and
+// the deduplication key it describes.
+func renderInboundTodo(mapping models.InboundMapping, payload interface{}) (*models.Todo, string, error) {
+	var fields []FieldError
+	render := func(field, text string) string {
+		if text == "" {
+			return ""
+		}
+		value, err := executeInboundTemplate(field, text, payload)
+		if err != nil {
+			fields = append(fields, FieldError{Field: "mapping." + field, Message: err.Error()})
+		}
+		return strings.TrimSpace(value)
+	}
+
+	todo := &models.Todo{
+		Title:   render("title", mapping.Title),
+		Content: render("content", mapping.Content),
+		Status:  "pending",
+		Tags:    []string{},
+	}
+	for _, tag := range strings.Split(render("tags", mapping.Tags), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			todo.Tags = append(todo.Tags, tag)
+		}
+	}
+	todo.Tags = dedupeStrings(todo.Tags)
+	dueDate := render("due_date", mapping.DueDate)
+	key := render("dedup_key", mapping.DedupKey)
+	if len(fields) > 0 {
+		return nil, "", NewValidationError("payload does not fit the mapping", fields...)
+	}
+
+	if todo.Title == "" {
+		fields = append(fields, FieldError{Field: "mapping.title", Message: "rendered an empty title"})
+	}
+	for utf8.RuneCountInString(todo.Title) > maxTodoTitleLength {
+		_, size := utf8.DecodeLastRuneInString(todo.Title)
+		todo.Title = todo.Title[:len(todo.Title)-size]
+	}
+	if len(todo.Tags) > maxInboundTags {
+		fields = append(fields, FieldError{Field: "mapping.tags", Message: fmt.Sprintf("rendered more than %d tags", maxInboundTags)})
+	}
+	if dueDate != "" {
+		if t, err := parseDueDate(dueDate); err != nil {
+			fields = append(fields, FieldError{Field: "mapping.due_date", Message: fmt.Sprintf("rendered %q, not a date", dueDate)})
+		} else {
+			todo.DueDate = &t
+		}
+	}
+	if len(key) > maxDedupKeyLength {
+		fields = append(fields, FieldError{Field: "mapping.dedup_key", Message: fmt.Sprintf("rendered more than %d characters", maxDedupKeyLength)})
+	}
+	if len(fields) > 0 {
+		return nil, "", NewValidationError("payload does not fit the mapping", fields...)
+	}
+	return todo, key, nil
+}
+
+// executeInboundTemplate renders a mapping template. Values missing from
+// the payload render empty.
+func executeInboundTemplate(name, text string, payload interface{}) (string, error) {
+	tmpl, err := template.New(name).Funcs(inboundTemplateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out cappedBuffer
+	if err := tmpl.Execute(&out, payload); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(out.String(), "<no value>", ""), nil
+}
+
+// parseDueDate reads an RFC 3339 time or a date, taken as midnight UTC.
+func parseDueDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// validateInboundWebhook checks the name and the templates of an inbound
+// webhook.
+func validateInboundWebhook(name string, mapping models.InboundMapping) error {
+	var fields []FieldError
+	if strings.TrimSpace(name) == "" {
+		fields = append(fields, FieldError{Field: "name", Message: "is required"})
+	}
+	if strings.TrimSpace(mapping.Title) == "" {
+		fields = append(fields, FieldError{Field: "mapping.title", Message: "is required"})
+	}
+	templates := []struct{ field, text string }{
+		{"title", mapping.Title},
+		{"content", mapping.Content},
+		{"tags", mapping.Tags},
+		{"due_date", mapping.DueDate},
+		{"dedup_key", mapping.DedupKey},
+	}
+	for _, t := range templates {
+		if _, err := template.New(t.field).Funcs(inboundTemplateFuncs).Parse(t.text); err != nil {
+			fields = append(fields, FieldError{Field: "mapping." + t.field, Message: err.Error()})
+		}
+	}
+	if len(fields) > 0 {
+		return NewValidationError("invalid inbound webhook", fields...)
+	}
+	return nil
+}
+
+// inboundWebhookRepoError translates repository errors into domain errors.
+func inboundWebhookRepoError(err error) error {
+	if errors.Is(err, repositories.ErrInboundWebhookNotFound) {
+		return errInboundWebhookNotFound
+	}
+	return err
+}
+
+var _ InboundWebhookServiceInterface = (*InboundWebhookService)(nil)
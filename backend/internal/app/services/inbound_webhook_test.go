@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/eventbus"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockInboundWebhookRepo is a mock implementation of repositories.InboundWebhookRepoInterface.
+type mockInboundWebhookRepo struct {
+	mock.Mock
+}
+
+func (m *mockInboundWebhookRepo) CreateInboundWebhook(ctx context.Context, webhook *models.InboundWebhook) error {
+	args := m.Called(ctx, webhook)
+	return args.Error(0)
+}
+
+func (m *mockInboundWebhookRepo) ListInboundWebhooks(ctx context.Context, userID int) ([]models.InboundWebhook, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.InboundWebhook), args.Error(1)
+}
+
+func (m *mockInboundWebhookRepo) GetInboundWebhook(ctx context.Context, userID int, id int) (*models.InboundWebhook, error) {
+	args := m.Called(ctx, userID, id)
+	webhook, _ := args.Get(0).(*models.InboundWebhook)
+	return webhook, args.Error(1)
+}
+
+func (m *mockInboundWebhookRepo) UseInboundWebhook(ctx context.Context, tokenHash string) (*models.InboundWebhook, error) {
+	args := m.Called(ctx, tokenHash)
+	webhook, _ := args.Get(0).(*models.InboundWebhook)
+	return webhook, args.Error(1)
+}
+
+func (m *mockInboundWebhookRepo) UpdateInboundWebhook(ctx context.Context, webhook *models.InboundWebhook) error {
+	args := m.Called(ctx, webhook)
+	return args.Error(0)
+}
+
+func (m *mockInboundWebhookRepo) DeleteInboundWebhook(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+var alertMapping = models.InboundMapping{
+	Title:    `{{.alert.name}}`,
+	Content:  `{{.alert.description}}`,
+	Tags:     `alert,{{lower .severity}},{{join "," .labels}}`,
+	DueDate:  `{{.due}}`,
+	DedupKey: `{{.alert.fingerprint}}`,
+}
+
+func TestInboundWebhookService_CreateInboundWebhook(t *testing.T) {
+	repo := new(mockInboundWebhookRepo)
+	service := NewInboundWebhookService(repo, new(mockTodoRepo))
+
+	repo.On("CreateInboundWebhook", mock.Anything, mock.Anything).Return(nil)
+
+	webhook, token, secret, err := service.CreateInboundWebhook(context.Background(), 1, " Alerts ", alertMapping, true)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, models.InboundWebhookTokenPrefix))
+	assert.True(t, strings.HasPrefix(secret, models.WebhookSecretPrefix))
+	assert.Equal(t, "Alerts", webhook.Name)
+	assert.Equal(t, utils.HashToken(token), webhook.TokenHash)
+	assert.True(t, strings.HasPrefix(token, webhook.Prefix))
+
+	_, _, _, err = service.CreateInboundWebhook(context.Background(), 1, "Alerts", models.InboundMapping{Title: "{{.title", Tags: "{{nope}}"}, false)
+	var serviceErr *Error
+	require.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, "validation_failed", serviceErr.Code)
+	assert.Len(t, serviceErr.Fields, 2)
+}
+
+func TestInboundWebhookService_Receive(t *testing.T) {
+	repo := new(mockInboundWebhookRepo)
+	todoRepo := new(mockTodoRepo)
+	service := NewInboundWebhookService(repo, todoRepo)
+	bus := eventbus.NewMemory()
+	service.Events = bus
+	ctx := context.Background()
+
+	var events []models.Event
+	bus.Subscribe(func(event models.Event) { events = append(events, event) })
+
+	repo.On("UseInboundWebhook", ctx, utils.HashToken("whin_token")).Return(&models.InboundWebhook{ID: 3, UserID: 1, Mapping: alertMapping}, nil)
+	todoRepo.On("UpsertTodo", ctx, 1, "inbound:3:abc123", mock.MatchedBy(func(todo *models.Todo) bool {
+		return todo.Title == "Disk full" && todo.Content == "" && todo.Status == "pending" &&
+			assert.ObjectsAreEqual([]string{"alert", "critical", "host-1", "disk"}, todo.Tags) &&
+			todo.DueDate.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	})).Run(func(args mock.Arguments) {
+		args.Get(3).(*models.Todo).ID = 9
+	}).Return(true, nil).Once()
+
+	// Missing values render empty
+	body := []byte(`{"severity":"CRITICAL","alert":{"name":"Disk full","fingerprint":"abc123"},"labels":["host-1","disk","alert"],"due":"2024-06-01"}`)
+	todo, created, err := service.Receive(ctx, "whin_token", body, "", "")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, 9, todo.ID)
+
+	// Repeated payloads update the todo
+	todoRepo.On("UpsertTodo", ctx, 1, "inbound:3:abc123", mock.Anything).Return(false, nil).Once()
+	_, created, err = service.Receive(ctx, "whin_token", body, "", "")
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventTodoCreated, events[0].Type)
+	assert.Equal(t, models.EventTodoUpdated, events[1].Type)
+}
+
+func TestInboundWebhookService_ReceiveInvalidPayload(t *testing.T) {
+	repo := new(mockInboundWebhookRepo)
+	todoRepo := new(mockTodoRepo)
+	service := NewInboundWebhookService(repo, todoRepo)
+
+	repo.On("UseInboundWebhook", mock.Anything, utils.HashToken("whin_token")).Return(&models.InboundWebhook{ID: 3, UserID: 1, Mapping: alertMapping}, nil)
+	repo.On("UseInboundWebhook", mock.Anything, mock.Anything).Return(nil, repositories.ErrInboundWebhookNotFound)
+
+	tests := []struct {
+		name  string
+		token string
+		body  string
+		code  string
+	}{
+		{"unknown token", "whin_other", `{}`, "inbound_webhook_not_found"},
+		{"not JSON", "whin_token", `alert`, "validation_failed"},
+		{"empty title", "whin_token", `{"severity":"","alert":{"name":""}}`, "validation_failed"},
+		{"missing object", "whin_token", `{"severity":"low"}`, "validation_failed"},
+		{"bad due date", "whin_token", `{"severity":"low","alert":{"name":"x"},"due":"tomorrow"}`, "validation_failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := service.Receive(context.Background(), tt.token, []byte(tt.body), "", "")
+			var serviceErr *Error
+			require.ErrorAs(t, err, &serviceErr)
+			assert.Equal(t, tt.code, serviceErr.Code)
+		})
+	}
+	todoRepo.AssertNotCalled(t, "UpsertTodo", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestInboundWebhookService_ReceiveSigned(t *testing.T) {
+	repo := new(mockInboundWebhookRepo)
+	todoRepo := new(mockTodoRepo)
+	service := NewInboundWebhookService(repo, todoRepo)
+
+	repo.On("UseInboundWebhook", mock.Anything, mock.Anything).Return(&models.InboundWebhook{
+		ID: 3, UserID: 1, Secret: "whsec_secret", Mapping: models.InboundMapping{Title: "{{.title}}"},
+	}, nil)
+	todoRepo.On("UpsertTodo", mock.Anything, 1, "", mock.Anything).Return(true, nil)
+
+	body := []byte(`{"title":"Call back"}`)
+	now := time.Now().Unix()
+	stale := time.Now().Add(-InboundSignatureTolerance - time.Minute).Unix()
+
+	_, _, err := service.Receive(context.Background(), "whin_token", body, strconv.FormatInt(now, 10), SignWebhook("whsec_secret", now, body))
+	assert.NoError(t, err)
+
+	for _, signed := range []struct{ timestamp, signature string }{
+		{"", ""},
+		{strconv.FormatInt(now, 10), SignWebhook("whsec_other", now, body)},
+		{strconv.FormatInt(stale, 10), SignWebhook("whsec_secret", stale, body)},
+	} {
+		_, _, err := service.Receive(context.Background(), "whin_token", body, signed.timestamp, signed.signature)
+		var serviceErr *Error
+		require.ErrorAs(t, err, &serviceErr)
+		assert.Equal(t, "invalid_signature", serviceErr.Code)
+	}
+	todoRepo.AssertNumberOfCalls(t, "UpsertTodo", 1)
+}
+
+func TestRenderInboundTodo_LongTitle(t *testing.T) {
+	todo, key, err := renderInboundTodo(models.InboundMapping{Title: "{{.title}}"}, map[string]interface{}{"title": strings.Repeat("é", 300)})
+	require.NoError(t, err)
+	assert.Equal(t, "", key)
+	assert.Equal(t, strings.Repeat("é", maxTodoTitleLength), todo.Title)
+	assert.Empty(t, todo.Tags)
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+)
+
+const (
+	// DefaultJobMaxAttempts is the number of times a job is attempted when
+	// it is enqueued without a limit.
+	DefaultJobMaxAttempts = 10
+	// DefaultJobPageSize is the number of jobs listed when the client does
+	// not ask for a number.
+	DefaultJobPageSize = 50
+	// MaxJobPageSize caps the number of jobs listed at once.
+	MaxJobPageSize = 200
+)
+
+// JobOptions tune an enqueued job. The zero value runs the job as soon as
+// possible, without a unique key, with DefaultJobMaxAttempts attempts.
+type JobOptions struct {
+	RunAt time.Time
+	// UniqueKey keeps the job from being queued while a pending or running
+	// job of the same type has the same key.
+	UniqueKey   string
+	MaxAttempts int
+}
+
+// JobEnqueuer queues background jobs.
+type JobEnqueuer interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}, opts JobOptions) (*models.Job, error)
+}
+
+// JobService queues background jobs and lets admins inspect and retry them.
+type JobService struct {
+	Repo repositories.JobRepoInterface
+	// Audit records retried jobs. It is optional.
+	Audit AuditRecorder
+}
+
+// NewJobService initializes a new JobService.
+func NewJobService(repo repositories.JobRepoInterface) *JobService {
+	return &JobService{Repo: repo}
+}
+
+// Enqueue queues a job of jobType with payload encoded as JSON. When the
+// unique key of opts is held by another job, repositories.ErrDuplicateJob
+// is returned.
+func (s *JobService) Enqueue(ctx context.Context, jobType string, payload interface{}, opts JobOptions) (*models.Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &models.Job{Type: jobType, Payload: encoded, MaxAttempts: opts.MaxAttempts, RunAt: opts.RunAt}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultJobMaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+	if err := s.Repo.EnqueueJob(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ListJobs retrieves up to limit jobs matching filter, newest first.
+func (s *JobService) ListJobs(ctx context.Context, filter models.JobFilter, limit int) ([]models.Job, error) {
+	if filter.Status != "" && !isJobStatus(filter.Status) {
+		return nil, NewValidationError("invalid job filter", FieldError{Field: "status", Message: "unknown status"})
+	}
+	if limit <= 0 {
+		limit = DefaultJobPageSize
+	} else if limit > MaxJobPageSize {
+		limit = MaxJobPageSize
+	}
+	return s.Repo.ListJobs(ctx, filter, limit)
+}
+
+// GetJob retrieves a job.
+func (s *JobService) GetJob(ctx context.Context, id int64) (*models.Job, error) {
+	job, err := s.Repo.GetJob(ctx, id)
+	if err != nil {
+		return nil, jobRepoError(err)
+	}
+	return job, nil
+}
+
+// RetryJob runs a dead job again with a fresh set of attempts, or a pending
+// job waiting for its next attempt right away.
+func (s *JobService) RetryJob(ctx context.Context, id int64) (*models.Job, error) {
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.JobDead && job.Status != models.JobPending {
+		return nil, NewConflictError("job_not_retryable", "only dead and pending jobs can be retried")
+	}
+
+	job, err = s.Repo.RetryJob(ctx, id)
+	if errors.Is(err, repositories.ErrJobNotFound) {
+		// The job started or finished in the meantime
+		return nil, NewConflictError("job_not_retryable", "only dead and pending jobs can be retried")
+	} else if err != nil {
+		return nil, jobRepoError(err)
+	}
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditJobRetried,
+		TargetType: models.AuditTargetJob,
+		TargetID:   strconv.FormatInt(job.ID, 10),
+		Metadata:   auditMetadata(map[string]interface{}{"type": job.Type}),
+	})
+	return job, nil
+}
+
+// jobRepoError translates repository errors into domain errors.
+func jobRepoError(err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrJobNotFound):
+		return NewNotFoundError("job_not_found", "job not found")
+	case errors.Is(err, repositories.ErrDuplicateJob):
+		return NewConflictError("duplicate_job", "another job with the same unique key is queued")
+	}
+	return err
+}
+
+// isJobStatus reports whether status is one of models.JobStatuses.
+func isJobStatus(status string) bool {
+	return containsString(models.JobStatuses, status)
+}
+
+var _ JobServiceInterface = (*JobService)(nil)
+var _ JobEnqueuer = (*JobService)(nil)
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockJobRepo is a mock implementation of repositories.JobRepoInterface.
+type mockJobRepo struct {
+	mock.Mock
+}
+
+func (m *mockJobRepo) EnqueueJob(ctx context.Context, job *models.Job) error {
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+func (m *mockJobRepo) ClaimJobs(ctx context.Context, types []string, limit int, worker string) ([]models.Job, error) {
+	args := m.Called(ctx, types, limit, worker)
+	return args.Get(0).([]models.Job), args.Error(1)
+}
+
+func (m *mockJobRepo) CompleteJob(ctx context.Context, job *models.Job) error {
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+func (m *mockJobRepo) FailJob(ctx context.Context, job *models.Job) error {
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+func (m *mockJobRepo) ReleaseJob(ctx context.Context, job *models.Job) error {
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+// jobWithID matches the job with the given ID.
+func jobWithID(id int64) interface{} {
+	return mock.MatchedBy(func(job *models.Job) bool { return job.ID == id })
+}
+
+func (m *mockJobRepo) RecoverStaleJobs(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	args := m.Called(ctx, lockedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockJobRepo) DeleteSucceededJobs(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockJobRepo) ListJobs(ctx context.Context, filter models.JobFilter, limit int) ([]models.Job, error) {
+	args := m.Called(ctx, filter, limit)
+	return args.Get(0).([]models.Job), args.Error(1)
+}
+
+func (m *mockJobRepo) GetJob(ctx context.Context, id int64) (*models.Job, error) {
+	args := m.Called(ctx, id)
+	job, _ := args.Get(0).(*models.Job)
+	return job, args.Error(1)
+}
+
+func (m *mockJobRepo) RetryJob(ctx context.Context, id int64) (*models.Job, error) {
+	args := m.Called(ctx, id)
+	job, _ := args.Get(0).(*models.Job)
+	return job, args.Error(1)
+}
+
+func TestJobService_Enqueue(t *testing.T) {
+	repo := new(mockJobRepo)
+	service := NewJobService(repo)
+
+	repo.On("EnqueueJob", mock.Anything, mock.MatchedBy(func(job *models.Job) bool {
+		return job.Type == "email.send" && string(job.Payload) == `{"to":"a@example.com"}` &&
+			job.MaxAttempts == DefaultJobMaxAttempts && !job.RunAt.IsZero() && *job.UniqueKey == "a"
+	})).Return(nil).Once()
+
+	job, err := service.Enqueue(context.Background(), "email.send", map[string]string{"to": "a@example.com"}, JobOptions{UniqueKey: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "email.send", job.Type)
+
+	repo.On("EnqueueJob", mock.Anything, mock.Anything).Return(repositories.ErrDuplicateJob).Once()
+	_, err = service.Enqueue(context.Background(), "email.send", nil, JobOptions{UniqueKey: "a"})
+	assert.ErrorIs(t, err, repositories.ErrDuplicateJob)
+}
+
+func TestJobService_RetryJob(t *testing.T) {
+	repo := new(mockJobRepo)
+	service := NewJobService(repo)
+	audit := new(mockAuditRepo)
+	service.Audit = NewAuditService(audit)
+
+	repo.On("GetJob", mock.Anything, int64(5)).Return(&models.Job{ID: 5, Status: models.JobDead}, nil)
+	repo.On("RetryJob", mock.Anything, int64(5)).Return(&models.Job{ID: 5, Type: "email.send", Status: models.JobPending}, nil)
+	audit.On("AppendEntry", mock.Anything, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+		return entry.Action == models.AuditJobRetried && entry.TargetID == "5"
+	})).Return(nil)
+
+	job, err := service.RetryJob(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobPending, job.Status)
+	audit.AssertExpectations(t)
+
+	// Running and succeeded jobs are not retried
+	repo.On("GetJob", mock.Anything, int64(6)).Return(&models.Job{ID: 6, Status: models.JobSucceeded}, nil)
+	_, err = service.RetryJob(context.Background(), 6)
+	var serviceErr *Error
+	require.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, "job_not_retryable", serviceErr.Code)
+
+	repo.On("GetJob", mock.Anything, int64(7)).Return(nil, repositories.ErrJobNotFound)
+	_, err = service.RetryJob(context.Background(), 7)
+	require.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, "job_not_found", serviceErr.Code)
+	repo.AssertNumberOfCalls(t, "RetryJob", 1)
+}
+
+func TestJobService_ListJobs(t *testing.T) {
+	repo := new(mockJobRepo)
+	service := NewJobService(repo)
+
+	repo.On("ListJobs", mock.Anything, models.JobFilter{Status: models.JobDead}, MaxJobPageSize).Return([]models.Job{}, nil)
+	_, err := service.ListJobs(context.Background(), models.JobFilter{Status: models.JobDead}, 1000)
+	assert.NoError(t, err)
+
+	_, err = service.ListJobs(context.Background(), models.JobFilter{Status: "lost"}, 0)
+	var serviceErr *Error
+	require.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, "validation_failed", serviceErr.Code)
+}
+
+func TestJobWorker_Outcomes(t *testing.T) {
+	type payload struct {
+		Fail string `json:"fail"`
+	}
+
+	tests := []struct {
+		name     string
+		job      models.Job
+		status   string
+		errorHas string
+	}{
+		{"succeeded", models.Job{Payload: []byte(`{}`)}, models.JobSucceeded, ""},
+		{"retried", models.Job{Payload: []byte(`{"fail":"boom"}`), Attempts: 2}, models.JobPending, "boom"},
+		{"out of attempts", models.Job{Payload: []byte(`{"fail":"boom"}`), Attempts: 3}, models.JobDead, "boom"},
+		{"permanent", models.Job{Payload: []byte(`{"fail":"permanent"}`), Attempts: 1}, models.JobDead, "permanent"},
+		{"invalid payload", models.Job{Payload: []byte(`[]`), Attempts: 1}, models.JobDead, "invalid payload"},
+		{"panic", models.Job{Payload: []byte(`{"fail":"panic"}`), Attempts: 1}, models.JobPending, "panicked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockJobRepo)
+			worker := NewJobWorker(repo)
+			HandleJob(worker, "test", func(ctx context.Context, p payload) error {
+				switch p.Fail {
+				case "":
+					return nil
+				case "permanent":
+					return PermanentJobError(errors.New("permanent"))
+				case "panic":
+					panic("oops")
+				}
+				return errors.New(p.Fail)
+			})
+
+			job := tt.job
+			job.ID, job.Type, job.MaxAttempts = 5, "test", 3
+			if tt.status == models.JobSucceeded {
+				repo.On("CompleteJob", mock.Anything, jobWithID(5)).Return(nil)
+			} else {
+				repo.On("FailJob", mock.Anything, mock.MatchedBy(func(failed *models.Job) bool {
+					if failed.Status != tt.status || !strings.Contains(failed.LastError, tt.errorHas) {
+						return false
+					}
+					// Retries wait for the backoff of the attempt
+					wait := time.Until(failed.RunAt)
+					return tt.status != models.JobPending || (wait > 0 && wait <= worker.backoff(job.Attempts))
+				})).Return(nil)
+			}
+
+			worker.run(&job)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestJobWorker_RunAndDrain(t *testing.T) {
+	repo := new(mockJobRepo)
+	worker := NewJobWorker(repo)
+	worker.PollInterval = 10 * time.Millisecond
+
+	started := make(chan struct{})
+	worker.Register("slow", func(ctx context.Context, job *models.Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	repo.On("RecoverStaleJobs", mock.Anything, mock.Anything).Return(int64(0), nil)
+	repo.On("DeleteSucceededJobs", mock.Anything, mock.Anything).Return(int64(0), nil)
+	repo.On("ClaimJobs", mock.Anything, []string{"slow"}, 4, worker.ID).
+		Return([]models.Job{{ID: 5, Type: "slow", Attempts: 1, MaxAttempts: 3}}, nil).Once()
+	repo.On("ClaimJobs", mock.Anything, []string{"slow"}, 3, worker.ID).Return([]models.Job{}, nil)
+	repo.On("ReleaseJob", mock.Anything, jobWithID(5)).Return(nil)
+
+	go worker.Run(context.Background())
+	<-started
+
+	// The job does not finish in time, so it is interrupted and released
+	// without counting the attempt
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, worker.Drain(ctx), context.DeadlineExceeded)
+	repo.AssertCalled(t, "ReleaseJob", mock.Anything, jobWithID(5))
+	repo.AssertNotCalled(t, "FailJob", mock.Anything, mock.Anything)
+}
+
+func TestJobWorker_DrainWaitsForJobs(t *testing.T) {
+	repo := new(mockJobRepo)
+	worker := NewJobWorker(repo)
+	worker.PollInterval = 10 * time.Millisecond
+
+	started, finish := make(chan struct{}), make(chan struct{})
+	worker.Register("quick", func(ctx context.Context, job *models.Job) error {
+		close(started)
+		<-finish
+		return nil
+	})
+
+	repo.On("RecoverStaleJobs", mock.Anything, mock.Anything).Return(int64(0), nil)
+	repo.On("DeleteSucceededJobs", mock.Anything, mock.Anything).Return(int64(0), nil)
+	repo.On("ClaimJobs", mock.Anything, mock.Anything, 4, worker.ID).Return([]models.Job{{ID: 5, Type: "quick"}}, nil).Once()
+	repo.On("ClaimJobs", mock.Anything, mock.Anything, mock.Anything, worker.ID).Return([]models.Job{}, nil)
+	repo.On("CompleteJob", mock.Anything, jobWithID(5)).Return(nil)
+
+	go worker.Run(context.Background())
+	<-started
+	time.AfterFunc(20*time.Millisecond, func() { close(finish) })
+
+	assert.NoError(t, worker.Drain(context.Background()))
+	repo.AssertCalled(t, "CompleteJob", mock.Anything, jobWithID(5))
+}
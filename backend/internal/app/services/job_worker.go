@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+)
+
+// JobHandler runs a job. A returned error fails the attempt, and the job is
+// retried later unless it has no attempts left or the error is permanent.
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+// permanentJobError marks errors that retrying a job cannot fix.
+type permanentJobError struct {
+	err error
+}
+
+func (e *permanentJobError) Error() string { return e.err.Error() }
+func (e *permanentJobError) Unwrap() error { return e.err }
+
+// PermanentJobError wraps err so that the failed job is not retried and
+// goes straight to the dead jobs.
+func PermanentJobError(err error) error {
+	return &permanentJobError{err: err}
+}
+
+// HandleJob registers fn for the jobs of jobType on worker, with their
+// payload decoded into a T. Payloads that do not decode are dead on arrival.
+func HandleJob[T any](worker *JobWorker, jobType string, fn func(ctx context.Context, payload T) error) {
+	worker.Register(jobType, func(ctx context.Context, job *models.Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return PermanentJobError(fmt.Errorf("invalid payload: %w", err))
+		}
+		return fn(ctx, payload)
+	})
+}
+
+// JobWorker runs the queued jobs of the types it has handlers for. Several
+// workers can run at once: each job is claimed by one of them.
+type JobWorker struct {
+	Repo repositories.JobRepoInterface
+	// ID identifies the worker in the locked_by column of the jobs it runs.
+	ID string
+
+	Concurrency  int
+	PollInterval time.Duration
+	// Timeout limits each attempt at a job.
+	Timeout time.Duration
+	// Retries wait RetryBackoff after the first failed attempt, twice as
+	// long after the second and so on, up to MaxRetryBackoff.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// Jobs running for longer than StaleAfter are presumed abandoned by a
+	// lost worker and queued again. It must exceed Timeout.
+	StaleAfter time.Duration
+	// Retention is how long succeeded jobs are kept.
+	Retention time.Duration
+
+	handlers map[string]JobHandler
+	stop     chan struct{}
+	stopOnce sync.Once
+	running  sync.WaitGroup
+	// jobs is the context of running jobs. It outlives the context of Run
+	// so that draining lets them finish, and is cancelled when draining
+	// takes too long.
+	jobs       context.Context
+	cancelJobs context.CancelFunc
+}
+
+// NewJobWorker initializes a new JobWorker.
+func NewJobWorker(repo repositories.JobRepoInterface) *JobWorker {
+	host, _ := os.Hostname()
+	jobs, cancel := context.WithCancel(context.Background())
+	return &JobWorker{
+		Repo:            repo,
+		ID:              fmt.Sprintf("%s:%d", host, os.Getpid()),
+		Concurrency:     4,
+		PollInterval:    time.Second,
+		Timeout:         5 * time.Minute,
+		RetryBackoff:    10 * time.Second,
+		MaxRetryBackoff: time.Hour,
+		StaleAfter:      15 * time.Minute,
+		Retention:       7 * 24 * time.Hour,
+		handlers:        make(map[string]JobHandler),
+		stop:            make(chan struct{}),
+		jobs:            jobs,
+		cancelJobs:      cancel,
+	}
+}
+
+// Register sets the handler of the jobs of jobType. Handlers must be
+// registered before Run is called.
+func (w *JobWorker) Register(jobType string, handler JobHandler) {
+	w.handlers[jobType] = handler
+}
+
+// Run claims and runs due jobs until ctx is cancelled or Drain is called.
+func (w *JobWorker) Run(ctx context.Context) {
+	w.running.Add(1)
+	defer w.running.Done()
+
+	types := make([]string, 0, len(w.handlers))
+	for jobType := range w.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+	slots := make(chan struct{}, w.Concurrency)
+	var lastMaintenance time.Time
+
+	for {
+		if time.Since(lastMaintenance) >= time.Minute {
+			w.maintain(ctx)
+			lastMaintenance = time.Now()
+		}
+
+		claimed, free := 0, w.Concurrency-len(slots)
+		if free > 0 && len(types) > 0 {
+			jobs, err := w.Repo.ClaimJobs(ctx, types, free, w.ID)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Failed to claim jobs: %v", err)
+			}
+			claimed = len(jobs)
+			for i := range jobs {
+				slots <- struct{}{}
+				w.running.Add(1)
+				go func(job *models.Job) {
+					defer func() { <-slots }()
+					defer w.running.Done()
+					w.run(job)
+				}(&jobs[i])
+			}
+		}
+
+		// Full batches are followed right away by the next one
+		if claimed > 0 && claimed == free {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.stop:
+				return
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain stops claiming jobs and waits for the running ones to finish. When
+// ctx ends first, the running jobs are cancelled and returned to the queue,
+// and ctx's error is returned.
+func (w *JobWorker) Drain(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		w.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		w.cancelJobs()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// run attempts job and records the outcome.
+func (w *JobWorker) run(job *models.Job) {
+	ctx, cancel := context.WithTimeout(w.jobs, w.Timeout)
+	err := w.call(ctx, job)
+	cancel()
+
+	// The outcome is recorded even when the jobs have been cancelled
+	record, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch {
+	case err == nil:
+		err = w.Repo.CompleteJob(record, job)
+	case w.jobs.Err() != nil:
+		// Interrupted by draining, the attempt does not count
+		err = w.Repo.ReleaseJob(record, job)
+	default:
+		var permanent *permanentJobError
+		job.LastError = err.Error()
+		if job.Attempts >= job.MaxAttempts || errors.As(err, &permanent) {
+			job.Status = models.JobDead
+			log.Printf("Job %d (%s) is dead after %d attempts: %v", job.ID, job.Type, job.Attempts, err)
+		} else {
+			job.Status, job.RunAt = models.JobPending, time.Now().Add(w.backoff(job.Attempts))
+		}
+		err = w.Repo.FailJob(record, job)
+	}
+	if errors.Is(err, repositories.ErrJobLost) {
+		// Presumed lost and recovered while it ran, the job is left to its
+		// next attempt
+		log.Printf("Job %d was recovered before attempt %d finished", job.ID, job.Attempts)
+	} else if err != nil {
+		log.Printf("Failed to record the outcome of job %d: %v", job.ID, err)
+	}
+}
+
+// call runs the handler of job, turning panics into errors.
+func (w *JobWorker) call(ctx context.Context, job *models.Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job handler panicked: %v", p)
+		}
+	}()
+	return w.handlers[job.Type](ctx, job)
+}
+
+// maintain queues the jobs of lost workers again and removes old succeeded
+// jobs.
+func (w *JobWorker) maintain(ctx context.Context) {
+	if n, err := w.Repo.RecoverStaleJobs(ctx, time.Now().Add(-w.StaleAfter)); err != nil {
+		log.Printf("Failed to recover stale jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("Recovered %d jobs of lost workers", n)
+	}
+	if _, err := w.Repo.DeleteSucceededJobs(ctx, time.Now().Add(-w.Retention)); err != nil {
+		log.Printf("Failed to delete succeeded jobs: %v", err)
+	}
+}
+
+// backoff returns how long to wait before the next attempt after the given
+// number of failed attempts.
+func (w *JobWorker) backoff(attempts int) time.Duration {
+	wait := w.RetryBackoff
+	for i := 1; i < attempts && wait < w.MaxRetryBackoff; i++ {
+		wait *= 2
+	}
+	if wait > w.MaxRetryBackoff {
+		wait = w.MaxRetryBackoff
+	}
+	return wait
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+)
+
+// LoginGuardConfig controls brute-force protection on login.
+type LoginGuardConfig struct {
+	// MaxAccountFailures is the number of failed logins for one email
+	// before that account is locked.
+	MaxAccountFailures int
+	// MaxIPFailures is the number of failed logins from one IP address
+	// before that address is locked.
+	MaxIPFailures int
+	// FreeFailures is the number of failures tolerated before progressive
+	// delays are imposed between attempts.
+	FreeFailures int
+	// BaseDelay is the first delay; it doubles with each further failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// LockoutDuration is how long a lockout lasts. It is also the window
+	// after which failure counters are forgotten.
+	LockoutDuration time.Duration
+}
+
+// DefaultLoginGuardConfig returns sensible defaults for LoginGuardConfig.
+func DefaultLoginGuardConfig() LoginGuardConfig {
+	return LoginGuardConfig{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      20,
+		FreeFailures:       2,
+		BaseDelay:          time.Second,
+		MaxDelay:           30 * time.Second,
+		LockoutDuration:    15 * time.Minute,
+	}
+}
+
+// maxTrackedAttempts bounds the number of counters kept before stale ones are pruned.
+const maxTrackedAttempts = 10000
+
+// attemptState tracks recent failures for a single account or IP address.
+type attemptState struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// LoginGuard throttles failed logins per account and per IP address.
+// Failure counters are kept in memory; lockouts are persisted so that they
+// are recorded, survive restarts and are honoured by every instance.
+type LoginGuard struct {
+	Config      LoginGuardConfig
+	LockoutRepo repositories.LockoutRepoInterface
+	UserRepo    repositories.UserRepoInterface
+
+	mu       sync.Mutex
+	attempts map[string]*attemptState
+	now      func() time.Time
+}
+
+// NewLoginGuard initializes a new LoginGuard.
+func NewLoginGuard(config LoginGuardConfig, lockoutRepo repositories.LockoutRepoInterface, userRepo repositories.UserRepoInterface) *LoginGuard {
+	return &LoginGuard{
+		Config:      config,
+		LockoutRepo: lockoutRepo,
+		UserRepo:    userRepo,
+		attempts:    make(map[string]*attemptState),
+		now:         time.Now,
+	}
+}
+
+// Check returns an error if a login for email from ip must be rejected
+// because of a lockout or a pending progressive delay.
+func (g *LoginGuard) Check(ctx context.Context, email, ip string) error {
+	email = normalizeEmail(email)
+
+	lockout, err := g.LockoutRepo.GetActiveLockout(ctx, email, ip)
+	if err == nil {
+		return NewTooManyRequestsError("account_locked", "too many failed login attempts, try again later", lockout.LockedUntil.Sub(g.now()))
+	} else if !errors.Is(err, repositories.ErrNoLockout) {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	var wait time.Duration
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		if state, ok := g.attempts[key]; ok && state.blockedUntil.After(now) {
+			if d := state.blockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	if wait > 0 {
+		return NewTooManyRequestsError("login_throttled", "too many failed login attempts, slow down", wait)
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login, imposing a progressive delay and
+// recording a lockout once the account or IP threshold is reached.
+func (g *LoginGuard) RecordFailure(ctx context.Context, email, ip string) error {
+	email = normalizeEmail(email)
+
+	accountFailures := g.fail(accountKey(email))
+	ipFailures := g.fail(ipKey(ip))
+
+	if accountFailures >= g.Config.MaxAccountFailures {
+		g.reset(accountKey(email))
+		if err := g.lock(ctx, models.LockoutReasonAccount, email, ip, accountFailures); err != nil {
+			return err
+		}
+	}
+	if ipFailures >= g.Config.MaxIPFailures {
+		g.reset(ipKey(ip))
+		if err := g.lock(ctx, models.LockoutReasonIP, email, ip, ipFailures); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSuccess clears the failure counter of the account. The IP counter is
+// kept so that an attacker cannot reset it by logging into their own account.
+func (g *LoginGuard) RecordSuccess(ctx context.Context, email, ip string) {
+	g.reset(accountKey(normalizeEmail(email)))
+}
+
+// Unlock lifts any lockout on the account of the user with the given ID.
+func (g *LoginGuard) Unlock(ctx context.Context, userID int, unlockedBy int) error {
+	user, err := g.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return userRepoError(err)
+	}
+
+	email := normalizeEmail(user.Email)
+	if err := g.LockoutRepo.UnlockAccount(ctx, email, unlockedBy); err != nil {
+		return err
+	}
+	g.reset(accountKey(email))
+	return nil
+}
+
+// fail increments the failure counter for key and returns the new count.
+func (g *LoginGuard) fail(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	if len(g.attempts) >= maxTrackedAttempts {
+		g.prune(now)
+	}
+
+	state, ok := g.attempts[key]
+	if !ok || now.Sub(state.lastFailure) > g.Config.LockoutDuration {
+		state = &attemptState{}
+		g.attempts[key] = state
+	}
+
+	state.failures++
+	state.lastFailure = now
+	if extra := state.failures - g.Config.FreeFailures; extra > 0 {
+		delay := g.Config.MaxDelay
+		if extra <= 30 {
+			if d := g.Config.BaseDelay << (extra - 1); d < delay {
+				delay = d
+			}
+		}
+		state.blockedUntil = now.Add(delay)
+	}
+	return state.failures
+}
+
+// prune forgets counters whose last failure is outside the lockout window.
+// The caller must hold g.mu.
+func (g *LoginGuard) prune(now time.Time) {
+	for key, state := range g.attempts {
+		if now.Sub(state.lastFailure) > g.Config.LockoutDuration {
+			delete(g.attempts, key)
+		}
+	}
+}
+
+func (g *LoginGuard) reset(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.attempts, key)
+}
+
+func (g *LoginGuard) lock(ctx context.Context, reason, email, ip string, failures int) error {
+	return g.LockoutRepo.CreateLockout(ctx, &models.Lockout{
+		Reason:      reason,
+		Email:       email,
+		IP:          ip,
+		Failures:    failures,
+		LockedUntil: g.now().Add(g.Config.LockoutDuration),
+	})
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func accountKey(email string) string {
+	return "account:" + email
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+var _ LoginGuardInterface = (*LoginGuard)(nil)
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockLockoutRepo is a mock implementation of repositories.LockoutRepoInterface.
+type mockLockoutRepo struct {
+	mock.Mock
+}
+
+func (m *mockLockoutRepo) CreateLockout(ctx context.Context, lockout *models.Lockout) error {
+	args := m.Called(ctx, lockout)
+	return args.Error(0)
+}
+
+func (m *mockLockoutRepo) GetActiveLockout(ctx context.Context, email, ip string) (*models.Lockout, error) {
+	args := m.Called(ctx, email, ip)
+	return args.Get(0).(*models.Lockout), args.Error(1)
+}
+
+func (m *mockLockoutRepo) UnlockAccount(ctx context.Context, email string, unlockedBy int) error {
+	args := m.Called(ctx, email, unlockedBy)
+	return args.Error(0)
+}
+
+func newTestLoginGuard(lockoutRepo *mockLockoutRepo, userRepo *mockUserRepo) (*LoginGuard, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard := NewLoginGuard(LoginGuardConfig{
+		MaxAccountFailures: 4,
+		MaxIPFailures:      6,
+		FreeFailures:       1,
+		BaseDelay:          time.Second,
+		MaxDelay:           3 * time.Second,
+		LockoutDuration:    time.Minute,
+	}, lockoutRepo, userRepo)
+	guard.now = func() time.Time { return now }
+	return guard, &now
+}
+
+func TestLoginGuard_ProgressiveDelay(t *testing.T) {
+	lockoutRepo := new(mockLockoutRepo)
+	guard, now := newTestLoginGuard(lockoutRepo, new(mockUserRepo))
+	ctx := context.Background()
+
+	lockoutRepo.On("GetActiveLockout", ctx, "john@example.com", "10.0.0.1").Return((*models.Lockout)(nil), repositories.ErrNoLockout)
+
+	// The first failure is free
+	assert.NoError(t, guard.RecordFailure(ctx, "John@Example.com", "10.0.0.1"))
+	assert.NoError(t, guard.Check(ctx, "john@example.com", "10.0.0.1"))
+
+	// Further failures double the delay
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second} {
+		assert.NoError(t, guard.RecordFailure(ctx, "john@example.com", "10.0.0.1"))
+
+		err := guard.Check(ctx, "john@example.com", "10.0.0.1")
+		assert.Equal(t, KindTooManyRequests, KindOf(err))
+		assert.Equal(t, "login_throttled", err.(*Error).Code)
+		assert.Equal(t, expected, err.(*Error).RetryAfter)
+
+		*now = now.Add(expected)
+		assert.NoError(t, guard.Check(ctx, "john@example.com", "10.0.0.1"))
+	}
+
+	// The delay is capped at the maximum
+	lockoutRepo.On("CreateLockout", ctx, mock.Anything).Return(nil).Once()
+	assert.NoError(t, guard.RecordFailure(ctx, "john@example.com", "10.0.0.1"))
+
+	err := guard.Check(ctx, "john@example.com", "10.0.0.1")
+	assert.Equal(t, 3*time.Second, err.(*Error).RetryAfter)
+}
+
+func TestLoginGuard_LocksAccount(t *testing.T) {
+	lockoutRepo := new(mockLockoutRepo)
+	guard, now := newTestLoginGuard(lockoutRepo, new(mockUserRepo))
+	ctx := context.Background()
+
+	lockoutRepo.On("CreateLockout", ctx, mock.MatchedBy(func(l *models.Lockout) bool {
+		return l.Reason == models.LockoutReasonAccount && l.Email == "john@example.com" &&
+			l.Failures == 4 && l.LockedUntil.Equal(now.Add(time.Minute))
+	})).Return(nil).Once()
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, guard.RecordFailure(ctx, "john@example.com", "10.0.0.1"))
+	}
+	lockoutRepo.AssertExpectations(t)
+
+	// An active lockout rejects logins even with no failures in memory
+	lockoutRepo.On("GetActiveLockout", ctx, "john@example.com", "10.0.0.2").
+		Return(&models.Lockout{LockedUntil: now.Add(30 * time.Second)}, nil)
+
+	err := guard.Check(ctx, "john@example.com", "10.0.0.2")
+	assert.Equal(t, KindTooManyRequests, KindOf(err))
+	assert.Equal(t, "account_locked", err.(*Error).Code)
+	assert.Equal(t, 30*time.Second, err.(*Error).RetryAfter)
+}
+
+func TestLoginGuard_SuccessResetsAccount(t *testing.T) {
+	lockoutRepo := new(mockLockoutRepo)
+	guard, _ := newTestLoginGuard(lockoutRepo, new(mockUserRepo))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, guard.RecordFailure(ctx, "john@example.com", "10.0.0.1"))
+	}
+	guard.RecordSuccess(ctx, "john@example.com", "10.0.0.1")
+
+	// Only the IP counter remains, so one more failure must not lock the account
+	assert.NoError(t, guard.RecordFailure(ctx, "john@example.com", "10.0.0.1"))
+	lockoutRepo.AssertNotCalled(t, "CreateLockout", mock.Anything, mock.Anything)
+}
+
+func TestLoginGuard_Unlock(t *testing.T) {
+	lockoutRepo := new(mockLockoutRepo)
+	userRepo := new(mockUserRepo)
+	guard, _ := newTestLoginGuard(lockoutRepo, userRepo)
+	ctx := context.Background()
+
+	userRepo.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1, Email: "John@example.com"}, nil)
+	lockoutRepo.On("UnlockAccount", ctx, "john@example.com", -1).Return(nil)
+
+	assert.NoError(t, guard.Unlock(ctx, 1, -1))
+	lockoutRepo.AssertExpectations(t)
+
+	userRepo.On("GetUserByID", ctx, 2).Return((*models.User)(nil), repositories.ErrUserNotFound)
+	assert.Equal(t, KindNotFound, KindOf(guard.Unlock(ctx, 2, -1)))
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+)
+
+const (
+	// DefaultNotificationPageSize is the number of notifications listed
+	// when no limit is given.
+	DefaultNotificationPageSize = 20
+	// MaxNotificationPageSize caps the number of notifications listed at once.
+	MaxNotificationPageSize = 100
+)
+
+// NotificationService keeps the in-app inbox of users. It is an
+// EventPublisher: the domain events worth telling users about, such as
+// reminders going off, become notifications.
+type NotificationService struct {
+	Repo repositories.NotificationRepoInterface
+	// Events pushes new and read notifications to the live clients of the
+	// user. It is optional.
+	Events EventPublisher
+}
+
+// NewNotificationService initializes a new NotificationService.
+func NewNotificationService(repo repositories.NotificationRepoInterface) *NotificationService {
+	return &NotificationService{Repo: repo}
+}
+
+// Publish adds a notification to the inbox of the user of event when its
+// type is one users are notified of, and ignores other events.
+func (s *NotificationService) Publish(ctx context.Context, event *models.Event) error {
+	notification := &models.Notification{UserID: event.UserID, Type: event.Type, Data: event.Data}
+	switch event.Type {
+	case models.EventTodoReminder:
+		var reminder models.DueReminder
+		if err := json.Unmarshal(event.Data, &reminder); err != nil {
+			return fmt.Errorf("invalid %s event: %w", event.Type, err)
+		}
+		notification.Title = "Reminder: " + reminder.Title
+	case models.EventTodoOverdue:
+		var todo models.OverdueTodo
+		if err := json.Unmarshal(event.Data, &todo); err != nil {
+			return fmt.Errorf("invalid %s event: %w", event.Type, err)
+		}
+		notification.Title = fmt.Sprintf("%q is overdue", todo.Title)
+	default:
+		return nil
+	}
+
+	if err := s.Repo.CreateNotification(ctx, notification); err != nil {
+		return err
+	}
+	publishEvent(ctx, s.Events, notification.UserID, models.EventNotificationCreated, notification)
+	return nil
+}
+
+// ListNotifications retrieves up to limit notifications of the user older
+// than beforeID, newest first, along with the number of unread ones.
+func (s *NotificationService) ListNotifications(ctx context.Context, userID int, unreadOnly bool, beforeID int64, limit int) ([]models.Notification, int, error) {
+	if limit <= 0 {
+		limit = DefaultNotificationPageSize
+	} else if limit > MaxNotificationPageSize {
+		limit = MaxNotificationPageSize
+	}
+
+	notifications, err := s.Repo.ListNotifications(ctx, userID, unreadOnly, beforeID, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	unread, err := s.Repo.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return notifications, unread, nil
+}
+
+// UnreadCount counts the unread notifications of the user.
+func (s *NotificationService) UnreadCount(ctx context.Context, userID int) (int, error) {
+	return s.Repo.CountUnreadNotifications(ctx, userID)
+}
+
+// MarkRead marks a notification of the user read.
+func (s *NotificationService) MarkRead(ctx context.Context, userID int, id int64) (*models.Notification, error) {
+	notification, err := s.Repo.MarkNotificationRead(ctx, userID, id)
+	if errors.Is(err, repositories.ErrNotificationNotFound) {
+		return nil, NewNotFoundError("notification_not_found", "notification not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	s.publishRead(ctx, userID, map[string]interface{}{"ids": []int64{id}})
+	return notification, nil
+}
+
+// MarkAllRead marks the notifications of the user up to upToID read, or
+// all of them when upToID is zero, so that clients can leave out the
+// notifications that arrived after they last listed them. It returns the
+// number of notifications marked.
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID int, upToID int64) (int64, error) {
+	n, err := s.Repo.MarkAllNotificationsRead(ctx, userID, upToID)
+	if err != nil {
+		return 0, err
+	}
+
+	if n > 0 {
+		s.publishRead(ctx, userID, map[string]interface{}{"up_to_id": upToID})
+	}
+	return n, nil
+}
+
+// publishRead tells the live clients of the user that notifications were
+// read, with the new unread count so that other tabs update their badge.
+func (s *NotificationService) publishRead(ctx context.Context, userID int, data map[string]interface{}) {
+	if s.Events == nil {
+		return
+	}
+	unread, err := s.Repo.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return
+	}
+	data["unread_count"] = unread
+	publishEvent(ctx, s.Events, userID, models.EventNotificationsRead, data)
+}
+
+var _ NotificationServiceInterface = (*NotificationService)(nil)
+var _ EventPublisher = (*NotificationService)(nil)
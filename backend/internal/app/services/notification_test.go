@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/eventbus"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockNotificationRepo is a mock implementation of
+// repositories.NotificationRepoInterface.
+type mockNotificationRepo struct {
+	mock.Mock
+}
+
+func (m *mockNotificationRepo) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	args := m.Called(ctx, notification)
+	return args.Error(0)
+}
+
+func (m *mockNotificationRepo) ListNotifications(ctx context.Context, userID int, unreadOnly bool, beforeID int64, limit int) ([]models.Notification, error) {
+	args := m.Called(ctx, userID, unreadOnly, beforeID, limit)
+	return args.Get(0).([]models.Notification), args.Error(1)
+}
+
+func (m *mockNotificationRepo) CountUnreadNotifications(ctx context.Context, userID int) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockNotificationRepo) MarkNotificationRead(ctx context.Context, userID int, id int64) (*models.Notification, error) {
+	args := m.Called(ctx, userID, id)
+	notification, _ := args.Get(0).(*models.Notification)
+	return notification, args.Error(1)
+}
+
+func (m *mockNotificationRepo) MarkAllNotificationsRead(ctx context.Context, userID int, upToID int64) (int64, error) {
+	args := m.Called(ctx, userID, upToID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestNotificationService_Publish(t *testing.T) {
+	repo := new(mockNotificationRepo)
+	service := NewNotificationService(repo)
+	bus := eventbus.NewMemory()
+	service.Events = bus
+	ctx := context.Background()
+
+	var events []models.Event
+	bus.Subscribe(func(event models.Event) { events = append(events, event) })
+
+	repo.On("CreateNotification", ctx, mock.MatchedBy(func(n *models.Notification) bool {
+		return n.UserID == 1 && n.Type == models.EventTodoReminder && n.Title == "Reminder: Pay rent"
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Notification).ID = 12
+	}).Return(nil).Once()
+	repo.On("CreateNotification", ctx, mock.MatchedBy(func(n *models.Notification) bool {
+		return n.Type == models.EventTodoOverdue && n.Title == `"Call mom" is overdue`
+	})).Return(nil).Once()
+
+	reminder, _ := json.Marshal(models.DueReminder{ReminderID: 7, TodoID: 3, Title: "Pay rent"})
+	require.NoError(t, service.Publish(ctx, &models.Event{Type: models.EventTodoReminder, UserID: 1, Data: reminder}))
+	overdue, _ := json.Marshal(models.OverdueTodo{ID: 4, Title: "Call mom", DueDate: time.Now()})
+	require.NoError(t, service.Publish(ctx, &models.Event{Type: models.EventTodoOverdue, UserID: 1, Data: overdue}))
+	// Changes made by the user themselves are not worth a notification
+	require.NoError(t, service.Publish(ctx, &models.Event{Type: models.EventTodoCreated, UserID: 1, Data: []byte(`{"id":5}`)}))
+	repo.AssertNumberOfCalls(t, "CreateNotification", 2)
+
+	// New notifications are pushed to live clients
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventNotificationCreated, events[0].Type)
+	assert.Equal(t, 1, events[0].UserID)
+	assert.Contains(t, string(events[0].Data), `"id":12`)
+	assert.Contains(t, string(events[0].Data), `"todo_id":3`)
+}
+
+func TestNotificationService_ListNotifications(t *testing.T) {
+	repo := new(mockNotificationRepo)
+	service := NewNotificationService(repo)
+	ctx := context.Background()
+
+	repo.On("ListNotifications", ctx, 1, false, int64(0), DefaultNotificationPageSize).Return([]models.Notification{{ID: 12}}, nil)
+	repo.On("ListNotifications", ctx, 1, true, int64(12), MaxNotificationPageSize).Return([]models.Notification{}, nil)
+	repo.On("CountUnreadNotifications", ctx, 1).Return(3, nil)
+
+	notifications, unread, err := service.ListNotifications(ctx, 1, false, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, 3, unread)
+
+	_, _, err = service.ListNotifications(ctx, 1, true, 12, 1000)
+	assert.NoError(t, err)
+}
+
+func TestNotificationService_MarkRead(t *testing.T) {
+	repo := new(mockNotificationRepo)
+	service := NewNotificationService(repo)
+	bus := eventbus.NewMemory()
+	service.Events = bus
+	ctx := context.Background()
+
+	var events []models.Event
+	bus.Subscribe(func(event models.Event) { events = append(events, event) })
+
+	now := time.Now()
+	repo.On("MarkNotificationRead", ctx, 1, int64(12)).Return(&models.Notification{ID: 12, ReadAt: &now}, nil)
+	repo.On("MarkNotificationRead", ctx, 1, int64(13)).Return(nil, repositories.ErrNotificationNotFound)
+	repo.On("MarkAllNotificationsRead", ctx, 1, int64(20)).Return(int64(2), nil).Once()
+	repo.On("MarkAllNotificationsRead", ctx, 1, int64(0)).Return(int64(0), nil).Once()
+	repo.On("CountUnreadNotifications", ctx, 1).Return(1, nil)
+
+	_, err := service.MarkRead(ctx, 1, 12)
+	require.NoError(t, err)
+	_, err = service.MarkRead(ctx, 1, 13)
+	var serviceErr *Error
+	require.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, "notification_not_found", serviceErr.Code)
+
+	n, err := service.MarkAllRead(ctx, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	_, err = service.MarkAllRead(ctx, 1, 0)
+	require.NoError(t, err)
+
+	// Other clients of the user learn of read notifications, unless none were
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventNotificationsRead, events[0].Type)
+	assert.JSONEq(t, `{"ids":[12],"unread_count":1}`, string(events[0].Data))
+	assert.JSONEq(t, `{"up_to_id":20,"unread_count":1}`, string(events[1].Data))
+}
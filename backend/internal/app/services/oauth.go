@@ -0,0 +1,474 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/oidc"
+)
+
+const (
+	oauthCodeTTL         = 10 * time.Minute
+	oauthAccessTokenTTL  = time.Hour
+	oauthRefreshTokenTTL = 30 * 24 * time.Hour
+
+	// oauthMaxRedirectURIs limits how many redirect URIs a client can register.
+	oauthMaxRedirectURIs = 10
+	// oauthCodeChallengeLength is the length of a base64url encoded SHA-256
+	// PKCE challenge.
+	oauthCodeChallengeLength = 43
+)
+
+var (
+	errOAuthClientNotFound   = NewNotFoundError("oauth_client_not_found", "oauth client not found")
+	errAuthorizedAppNotFound = NewNotFoundError("authorized_app_not_found", "authorized app not found")
+	errInvalidRedirectURI    = NewValidationError("invalid authorization request",
+		FieldError{Field: "redirect_uri", Message: "is not registered for the client"})
+
+	errInvalidOAuthClient = &OAuthError{Code: "invalid_client", Description: "client authentication failed"}
+)
+
+// OAuthError is an error defined by the OAuth 2.0 specification (RFC 6749).
+// Clients receive it as {"error": Code, "error_description": Description}.
+type OAuthError struct {
+	Code        string
+	Description string
+	// RedirectURI is set on errors of authorization requests. It sends the
+	// user back to the client with the error in its query.
+	RedirectURI string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// OAuthAuthorizationRequest holds the parameters a client sends the user to
+// the authorization endpoint with. PKCE with S256 is required from every
+// client, and redirect_uri must exactly match a registered URI.
+type OAuthAuthorizationRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// OAuthTokenRequest holds the parameters of a request to the token endpoint.
+type OAuthTokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+
+	// authorization_code grant
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+
+	// refresh_token grant
+	RefreshToken string
+	Scope        string
+}
+
+// OAuthService lets users authorize third-party applications to access
+// their todos through the OAuth 2.0 authorization code flow.
+type OAuthService struct {
+	Repo repositories.OAuthRepoInterface
+	// Audit records issued tokens. It is optional.
+	Audit AuditRecorder
+}
+
+// NewOAuthService initializes a new OAuthService.
+func NewOAuthService(repo repositories.OAuthRepoInterface) *OAuthService {
+	return &OAuthService{Repo: repo}
+}
+
+// RegisterClient registers an application owned by the user. Confidential
+// clients get a secret, which is returned once and cannot be retrieved
+// again.
+func (s *OAuthService) RegisterClient(ctx context.Context, ownerID int, name string, redirectURIs []string, confidential bool) (*models.OAuthClient, string, error) {
+	var fields []FieldError
+	if strings.TrimSpace(name) == "" {
+		fields = append(fields, FieldError{Field: "name", Message: "is required"})
+	}
+	if len(redirectURIs) == 0 || len(redirectURIs) > oauthMaxRedirectURIs {
+		fields = append(fields, FieldError{Field: "redirect_uris", Message: fmt.Sprintf("must list between 1 and %d URIs", oauthMaxRedirectURIs)})
+	}
+	for _, uri := range redirectURIs {
+		if message := checkRedirectURI(uri); message != "" {
+			fields = append(fields, FieldError{Field: "redirect_uris", Message: fmt.Sprintf("%q %s", uri, message)})
+		}
+	}
+	if len(fields) > 0 {
+		return nil, "", NewValidationError("invalid oauth client", fields...)
+	}
+
+	clientID, err := generateToken("")
+	if err != nil {
+		return nil, "", err
+	}
+	client := &models.OAuthClient{
+		ClientID:     clientID,
+		OwnerID:      ownerID,
+		Name:         strings.TrimSpace(name),
+		RedirectURIs: redirectURIs,
+	}
+
+	var secret string
+	if confidential {
+		if secret, err = generateToken(models.OAuthClientSecretPrefix); err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = utils.HashToken(secret)
+	}
+
+	if err := s.Repo.CreateClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+// ListClients retrieves the applications registered by the user.
+func (s *OAuthService) ListClients(ctx context.Context, ownerID int) ([]models.OAuthClient, error) {
+	return s.Repo.ListClients(ctx, ownerID)
+}
+
+// DeleteClient removes an application registered by the user, revoking its
+// access to every account.
+func (s *OAuthService) DeleteClient(ctx context.Context, ownerID int, clientID string) error {
+	err := s.Repo.DeleteClient(ctx, ownerID, clientID)
+	if errors.Is(err, repositories.ErrOAuthClientNotFound) {
+		return errOAuthClientNotFound
+	}
+	return err
+}
+
+// ValidateAuthorizationRequest checks a request to the authorization
+// endpoint. Errors that may be reported to the client are *OAuthError with
+// a RedirectURI; an unknown client or redirect URI is never redirected to.
+func (s *OAuthService) ValidateAuthorizationRequest(ctx context.Context, req *OAuthAuthorizationRequest) error {
+	_, _, err := s.checkAuthorizationRequest(ctx, req)
+	return err
+}
+
+// DescribeConsent returns what the user is asked to approve for req.
+func (s *OAuthService) DescribeConsent(ctx context.Context, userID int, req *OAuthAuthorizationRequest) (*models.OAuthConsent, error) {
+	client, scopes, err := s.checkAuthorizationRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var granted []string
+	grant, err := s.Repo.GetGrant(ctx, userID, client.ID)
+	if err == nil {
+		granted = grant.Scopes
+	} else if !errors.Is(err, repositories.ErrOAuthGrantNotFound) {
+		return nil, err
+	}
+
+	consent := &models.OAuthConsent{ClientID: client.ClientID, ClientName: client.Name}
+	for _, scope := range scopes {
+		consent.Scopes = append(consent.Scopes, models.OAuthScope{
+			Name:        scope,
+			Description: models.OAuthScopes[scope],
+			Granted:     containsString(granted, scope),
+		})
+	}
+	return consent, nil
+}
+
+// Authorize records the decision of the user on req and returns the URL of
+// the client that the user is sent back to, carrying either an authorization
+// code or an error.
+func (s *OAuthService) Authorize(ctx context.Context, userID int, req *OAuthAuthorizationRequest, approve bool) (string, error) {
+	client, scopes, err := s.checkAuthorizationRequest(ctx, req)
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		return oauthErr.RedirectURI, nil
+	} else if err != nil {
+		return "", err
+	}
+	if !approve {
+		return authorizationError(req, "access_denied", "the user denied the request").RedirectURI, nil
+	}
+
+	// Scopes granted earlier stay granted, so that approving a narrower
+	// request does not break other installations of the client
+	grant := &models.OAuthGrant{UserID: userID, ClientID: client.ID, Scopes: scopes}
+	existing, err := s.Repo.GetGrant(ctx, userID, client.ID)
+	if err == nil {
+		grant.Scopes = dedupeStrings(append(existing.Scopes, scopes...))
+	} else if !errors.Is(err, repositories.ErrOAuthGrantNotFound) {
+		return "", err
+	}
+	if err := s.Repo.SaveGrant(ctx, grant); err != nil {
+		return "", err
+	}
+
+	code, err := generateToken("")
+	if err != nil {
+		return "", err
+	}
+	err = s.Repo.CreateAuthorizationCode(ctx, &models.OAuthAuthorizationCode{
+		CodeHash:      utils.HashToken(code),
+		GrantID:       grant.ID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oauthCodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return redirectURL(req.RedirectURI, req.State, url.Values{"code": {code}}), nil
+}
+
+// Token handles a request to the token endpoint. Errors meant for the
+// client are *OAuthError.
+func (s *OAuthService) Token(ctx context.Context, req *OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		return s.exchangeCode(ctx, client, req)
+	case "refresh_token":
+		return s.refreshToken(ctx, client, req)
+	}
+	return nil, &OAuthError{Code: "unsupported_grant_type", Description: "grant_type must be authorization_code or refresh_token"}
+}
+
+// AuthenticateAccessToken returns the unexpired token pair whose access
+// token is secret and records its use.
+func (s *OAuthService) AuthenticateAccessToken(ctx context.Context, secret string) (*models.OAuthToken, error) {
+	if !strings.HasPrefix(secret, models.OAuthAccessTokenPrefix) {
+		return nil, errInvalidAccessToken
+	}
+
+	token, err := s.Repo.UseAccessToken(ctx, utils.HashToken(secret))
+	if errors.Is(err, repositories.ErrOAuthTokenNotFound) {
+		return nil, errInvalidAccessToken
+	}
+	return token, err
+}
+
+// ListAuthorizedApps retrieves the applications the user has given access
+// to their account.
+func (s *OAuthService) ListAuthorizedApps(ctx context.Context, userID int) ([]models.AuthorizedApp, error) {
+	return s.Repo.ListAuthorizedApps(ctx, userID)
+}
+
+// RevokeAuthorizedApp revokes the access of an application to the account
+// of the user, invalidating every token it holds.
+func (s *OAuthService) RevokeAuthorizedApp(ctx context.Context, userID int, clientID string) error {
+	err := s.Repo.DeleteGrant(ctx, userID, clientID)
+	if errors.Is(err, repositories.ErrOAuthGrantNotFound) {
+		return errAuthorizedAppNotFound
+	}
+	return err
+}
+
+// checkAuthorizationRequest validates req and returns its client and the
+// requested scopes.
+func (s *OAuthService) checkAuthorizationRequest(ctx context.Context, req *OAuthAuthorizationRequest) (*models.OAuthClient, []string, error) {
+	client, err := s.Repo.GetClient(ctx, req.ClientID)
+	if errors.Is(err, repositories.ErrOAuthClientNotFound) {
+		return nil, nil, errOAuthClientNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return nil, nil, errInvalidRedirectURI
+	}
+
+	// The redirect URI is trusted from here on, so errors are reported to
+	// the client
+	switch {
+	case req.ResponseType != "code":
+		return nil, nil, authorizationError(req, "unsupported_response_type", "response_type must be code")
+	case req.CodeChallengeMethod != "S256" || len(req.CodeChallenge) != oauthCodeChallengeLength:
+		return nil, nil, authorizationError(req, "invalid_request", "a PKCE code_challenge with code_challenge_method S256 is required")
+	}
+
+	scopes, scopeErr := parseOAuthScope(req.Scope)
+	if scopeErr != nil {
+		return nil, nil, authorizationError(req, scopeErr.Code, scopeErr.Description)
+	}
+	return client, scopes, nil
+}
+
+// authenticateClient returns the client identified by clientID. Confidential
+// clients must present their secret.
+func (s *OAuthService) authenticateClient(ctx context.Context, clientID, secret string) (*models.OAuthClient, error) {
+	client, err := s.Repo.GetClient(ctx, clientID)
+	if errors.Is(err, repositories.ErrOAuthClientNotFound) {
+		return nil, errInvalidOAuthClient
+	} else if err != nil {
+		return nil, err
+	}
+
+	if client.Confidential && subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, errInvalidOAuthClient
+	}
+	return client, nil
+}
+
+// exchangeCode redeems an authorization code issued to client.
+func (s *OAuthService) exchangeCode(ctx context.Context, client *models.OAuthClient, req *OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	code, err := s.Repo.ConsumeAuthorizationCode(ctx, utils.HashToken(req.Code))
+	if errors.Is(err, repositories.ErrOAuthCodeNotFound) {
+		return nil, &OAuthError{Code: "invalid_grant", Description: "authorization code is invalid or has expired"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI {
+		return nil, &OAuthError{Code: "invalid_grant", Description: "authorization code was issued to another client or redirect_uri"}
+	}
+	if subtle.ConstantTimeCompare([]byte(oidc.CodeChallenge(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+		return nil, &OAuthError{Code: "invalid_grant", Description: "PKCE verification failed"}
+	}
+	return s.issueToken(ctx, code.GrantID, code.Scopes)
+}
+
+// refreshToken replaces a token pair issued to client. The refresh token is
+// consumed even if another client presents it, since it has then leaked.
+func (s *OAuthService) refreshToken(ctx context.Context, client *models.OAuthClient, req *OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	token, err := s.Repo.ConsumeRefreshToken(ctx, utils.HashToken(req.RefreshToken))
+	if errors.Is(err, repositories.ErrOAuthTokenNotFound) || (err == nil && token.ClientID != client.ID) {
+		return nil, &OAuthError{Code: "invalid_grant", Description: "refresh token is invalid or has expired"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Clients may narrow the scopes of the new access token
+	scopes := token.Scopes
+	if req.Scope != "" {
+		requested, oauthErr := parseOAuthScope(req.Scope)
+		if oauthErr != nil {
+			return nil, oauthErr
+		}
+		for _, scope := range requested {
+			if !containsString(token.Scopes, scope) {
+				return nil, &OAuthError{Code: "invalid_scope", Description: fmt.Sprintf("scope %q was not granted", scope)}
+			}
+		}
+		scopes = requested
+	}
+	return s.issueToken(ctx, token.GrantID, scopes)
+}
+
+// issueToken creates a new token pair under a grant.
+func (s *OAuthService) issueToken(ctx context.Context, grantID int, scopes []string) (*models.OAuthTokenResponse, error) {
+	accessToken, err := generateToken(models.OAuthAccessTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := generateToken(models.OAuthRefreshTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = s.Repo.CreateToken(ctx, &models.OAuthToken{
+		GrantID:          grantID,
+		AccessTokenHash:  utils.HashToken(accessToken),
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		Scopes:           scopes,
+		AccessExpiresAt:  now.Add(oauthAccessTokenTTL),
+		RefreshExpiresAt: now.Add(oauthRefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditTokenIssued,
+		TargetType: models.AuditTargetOAuthGrant,
+		TargetID:   strconv.Itoa(grantID),
+		Metadata:   auditMetadata(map[string]interface{}{"type": "oauth", "scopes": scopes}),
+	})
+	return &models.OAuthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauthAccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	}, nil
+}
+
+// parseOAuthScope parses the space separated scope parameter.
+func parseOAuthScope(scope string) ([]string, *OAuthError) {
+	scopes := dedupeStrings(strings.Fields(scope))
+	if len(scopes) == 0 {
+		return nil, &OAuthError{Code: "invalid_scope", Description: "scope is required"}
+	}
+	for _, s := range scopes {
+		if _, ok := models.OAuthScopes[s]; !ok {
+			return nil, &OAuthError{Code: "invalid_scope", Description: fmt.Sprintf("unknown scope %q", s)}
+		}
+	}
+	return scopes, nil
+}
+
+// checkRedirectURI returns why uri cannot be registered as a redirect URI,
+// or "" if it can. Plain HTTP is only allowed for loopback addresses;
+// native apps may use private schemes named after a domain they own, such
+// as com.example.app:/callback (RFC 8252).
+func checkRedirectURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() {
+		return "must be an absolute URL"
+	}
+	if u.Fragment != "" {
+		return "must not contain a fragment"
+	}
+
+	switch u.Scheme {
+	case "https":
+		return ""
+	case "http":
+		if host := u.Hostname(); host == "localhost" || host == "127.0.0.1" || host == "::1" {
+			return ""
+		}
+		return "must use https"
+	}
+	if !strings.Contains(u.Scheme, ".") {
+		return "must use https or a reverse domain name scheme"
+	}
+	return ""
+}
+
+// authorizationError returns an error of an authorization request that is
+// reported to the client at its redirect URI.
+func authorizationError(req *OAuthAuthorizationRequest, code, description string) *OAuthError {
+	params := url.Values{"error": {code}, "error_description": {description}}
+	return &OAuthError{Code: code, Description: description, RedirectURI: redirectURL(req.RedirectURI, req.State, params)}
+}
+
+// redirectURL adds params and state to the query of a validated redirect URI.
+func redirectURL(redirectURI, state string, params url.Values) string {
+	u, _ := url.Parse(redirectURI)
+	q := u.Query()
+	for key, values := range params {
+		q[key] = values
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+var _ OAuthServiceInterface = (*OAuthService)(nil)
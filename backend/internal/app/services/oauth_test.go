@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/oidc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockOAuthRepo is a mock implementation of repositories.OAuthRepoInterface.
+type mockOAuthRepo struct {
+	mock.Mock
+}
+
+func (m *mockOAuthRepo) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	args := m.Called(ctx, client)
+	return args.Error(0)
+}
+
+func (m *mockOAuthRepo) GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	args := m.Called(ctx, clientID)
+	return args.Get(0).(*models.OAuthClient), args.Error(1)
+}
+
+func (m *mockOAuthRepo) ListClients(ctx context.Context, ownerID int) ([]models.OAuthClient, error) {
+	args := m.Called(ctx, ownerID)
+	return args.Get(0).([]models.OAuthClient), args.Error(1)
+}
+
+func (m *mockOAuthRepo) DeleteClient(ctx context.Context, ownerID int, clientID string) error {
+	args := m.Called(ctx, ownerID, clientID)
+	return args.Error(0)
+}
+
+func (m *mockOAuthRepo) GetGrant(ctx context.Context, userID int, clientID int) (*models.OAuthGrant, error) {
+	args := m.Called(ctx, userID, clientID)
+	return args.Get(0).(*models.OAuthGrant), args.Error(1)
+}
+
+func (m *mockOAuthRepo) SaveGrant(ctx context.Context, grant *models.OAuthGrant) error {
+	args := m.Called(ctx, grant)
+	return args.Error(0)
+}
+
+func (m *mockOAuthRepo) ListAuthorizedApps(ctx context.Context, userID int) ([]models.AuthorizedApp, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.AuthorizedApp), args.Error(1)
+}
+
+func (m *mockOAuthRepo) DeleteGrant(ctx context.Context, userID int, clientID string) error {
+	args := m.Called(ctx, userID, clientID)
+	return args.Error(0)
+}
+
+func (m *mockOAuthRepo) CreateAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	args := m.Called(ctx, code)
+	return args.Error(0)
+}
+
+func (m *mockOAuthRepo) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error) {
+	args := m.Called(ctx, codeHash)
+	return args.Get(0).(*models.OAuthAuthorizationCode), args.Error(1)
+}
+
+func (m *mockOAuthRepo) CreateToken(ctx context.Context, token *models.OAuthToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *mockOAuthRepo) ConsumeRefreshToken(ctx context.Context, refreshTokenHash string) (*models.OAuthToken, error) {
+	args := m.Called(ctx, refreshTokenHash)
+	return args.Get(0).(*models.OAuthToken), args.Error(1)
+}
+
+func (m *mockOAuthRepo) UseAccessToken(ctx context.Context, accessTokenHash string) (*models.OAuthToken, error) {
+	args := m.Called(ctx, accessTokenHash)
+	return args.Get(0).(*models.OAuthToken), args.Error(1)
+}
+
+const testRedirectURI = "https://app.example.com/callback"
+
+// testOAuthClient is a public client registered by user 5.
+func testOAuthClient() *models.OAuthClient {
+	return &models.OAuthClient{ID: 2, ClientID: "client-1", OwnerID: 5, Name: "Todo Sync", RedirectURIs: []string{testRedirectURI}}
+}
+
+// testAuthorizationRequest returns a valid authorization request of
+// testOAuthClient and its PKCE code verifier.
+func testAuthorizationRequest(t *testing.T) (*OAuthAuthorizationRequest, string) {
+	verifier, err := oidc.RandomString()
+	require.NoError(t, err)
+	return &OAuthAuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            "client-1",
+		RedirectURI:         testRedirectURI,
+		Scope:               "todos:read todos:write",
+		State:               "xyz",
+		CodeChallenge:       oidc.CodeChallenge(verifier),
+		CodeChallengeMethod: "S256",
+	}, verifier
+}
+
+func TestOAuthService_RegisterClient(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	service := NewOAuthService(repo)
+	repo.On("CreateClient", mock.Anything, mock.AnythingOfType("*models.OAuthClient")).Return(nil)
+
+	client, secret, err := service.RegisterClient(context.Background(), 5, " CLI ", []string{"http://localhost:8000/cb", "com.example.todo:/cb"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, "CLI", client.Name)
+	assert.NotEmpty(t, client.ClientID)
+	assert.True(t, strings.HasPrefix(secret, models.OAuthClientSecretPrefix))
+	assert.Equal(t, utils.HashToken(secret), client.SecretHash)
+
+	client, secret, err = service.RegisterClient(context.Background(), 5, "SPA", []string{testRedirectURI}, false)
+	require.NoError(t, err)
+	assert.Empty(t, secret)
+	assert.Empty(t, client.SecretHash)
+}
+
+func TestOAuthService_RegisterClient_InvalidRedirectURIs(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	service := NewOAuthService(repo)
+
+	for _, uri := range []string{"http://app.example.com/cb", "https://app.example.com/cb#frag", "javascript:alert(1)", "/relative"} {
+		_, _, err := service.RegisterClient(context.Background(), 5, "App", []string{uri}, false)
+		assert.Equal(t, KindValidation, KindOf(err), uri)
+	}
+	_, _, err := service.RegisterClient(context.Background(), 5, "App", nil, false)
+	assert.Equal(t, KindValidation, KindOf(err))
+	repo.AssertNotCalled(t, "CreateClient", mock.Anything, mock.Anything)
+}
+
+func TestOAuthService_ValidateAuthorizationRequest(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	service := NewOAuthService(repo)
+	repo.On("GetClient", mock.Anything, "client-1").Return(testOAuthClient(), nil)
+	repo.On("GetClient", mock.Anything, "unknown").Return((*models.OAuthClient)(nil), repositories.ErrOAuthClientNotFound)
+
+	req, _ := testAuthorizationRequest(t)
+	assert.NoError(t, service.ValidateAuthorizationRequest(context.Background(), req))
+
+	// Users are never sent to unknown clients or unregistered URIs
+	req.ClientID = "unknown"
+	assert.Equal(t, KindNotFound, KindOf(service.ValidateAuthorizationRequest(context.Background(), req)))
+	req, _ = testAuthorizationRequest(t)
+	req.RedirectURI = "https://evil.example.com/callback"
+	assert.Equal(t, KindValidation, KindOf(service.ValidateAuthorizationRequest(context.Background(), req)))
+
+	tests := map[string]struct {
+		change func(*OAuthAuthorizationRequest)
+		code   string
+	}{
+		"implicit flow": {func(r *OAuthAuthorizationRequest) { r.ResponseType = "token" }, "unsupported_response_type"},
+		"missing PKCE":  {func(r *OAuthAuthorizationRequest) { r.CodeChallenge = "" }, "invalid_request"},
+		"plain PKCE":    {func(r *OAuthAuthorizationRequest) { r.CodeChallengeMethod = "plain" }, "invalid_request"},
+		"admin scope":   {func(r *OAuthAuthorizationRequest) { r.Scope = "todos:read admin" }, "invalid_scope"},
+		"missing scope": {func(r *OAuthAuthorizationRequest) { r.Scope = "" }, "invalid_scope"},
+		"unknown scope": {func(r *OAuthAuthorizationRequest) { r.Scope = "email" }, "invalid_scope"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req, _ := testAuthorizationRequest(t)
+			tt.change(req)
+
+			var oauthErr *OAuthError
+			require.True(t, errors.As(service.ValidateAuthorizationRequest(context.Background(), req), &oauthErr))
+			assert.Equal(t, tt.code, oauthErr.Code)
+
+			redirect, err := url.Parse(oauthErr.RedirectURI)
+			require.NoError(t, err)
+			assert.Equal(t, "app.example.com", redirect.Host)
+			assert.Equal(t, tt.code, redirect.Query().Get("error"))
+			assert.Equal(t, "xyz", redirect.Query().Get("state"))
+		})
+	}
+}
+
+func TestOAuthService_DescribeConsent(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	service := NewOAuthService(repo)
+	repo.On("GetClient", mock.Anything, "client-1").Return(testOAuthClient(), nil)
+	repo.On("GetGrant", mock.Anything, 1, 2).Return(&models.OAuthGrant{Scopes: []string{models.ScopeTodosRead}}, nil)
+
+	req, _ := testAuthorizationRequest(t)
+	consent, err := service.DescribeConsent(context.Background(), 1, req)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Todo Sync", consent.ClientName)
+	assert.Equal(t, []models.OAuthScope{
+		{Name: models.ScopeTodosRead, Description: models.OAuthScopes[models.ScopeTodosRead], Granted: true},
+		{Name: models.ScopeTodosWrite, Description: models.OAuthScopes[models.ScopeTodosWrite], Granted: false},
+	}, consent.Scopes)
+}
+
+func TestOAuthService_AuthorizationCodeFlow(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	service := NewOAuthService(repo)
+	ctx := context.Background()
+	repo.On("GetClient", mock.Anything, "client-1").Return(testOAuthClient(), nil)
+
+	// The user approves a narrower request than they approved before
+	repo.On("GetGrant", mock.Anything, 1, 2).Return(&models.OAuthGrant{ID: 4, Scopes: []string{models.ScopeTodosWrite}}, nil)
+	repo.On("SaveGrant", mock.Anything, &models.OAuthGrant{UserID: 1, ClientID: 2, Scopes: []string{models.ScopeTodosWrite, models.ScopeTodosRead}}).
+		Run(func(args mock.Arguments) { args.Get(1).(*models.OAuthGrant).ID = 4 }).
+		Return(nil)
+	var stored *models.OAuthAuthorizationCode
+	repo.On("CreateAuthorizationCode", mock.Anything, mock.AnythingOfType("*models.OAuthAuthorizationCode")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*models.OAuthAuthorizationCode) }).
+		Return(nil)
+
+	req, verifier := testAuthorizationRequest(t)
+	req.Scope = models.ScopeTodosRead
+	redirectTo, err := service.Authorize(ctx, 1, req, true)
+	require.NoError(t, err)
+
+	redirect, err := url.Parse(redirectTo)
+	require.NoError(t, err)
+	code := redirect.Query().Get("code")
+	assert.Equal(t, "xyz", redirect.Query().Get("state"))
+	assert.Equal(t, utils.HashToken(code), stored.CodeHash)
+	assert.Equal(t, 4, stored.GrantID)
+	assert.Equal(t, []string{models.ScopeTodosRead}, stored.Scopes)
+
+	// Redeem the code at the token endpoint
+	issued := &models.OAuthAuthorizationCode{GrantID: 4, UserID: 1, ClientID: 2, RedirectURI: stored.RedirectURI, Scopes: stored.Scopes, CodeChallenge: stored.CodeChallenge}
+	repo.On("ConsumeAuthorizationCode", mock.Anything, stored.CodeHash).Return(issued, nil).Once()
+	var token *models.OAuthToken
+	repo.On("CreateToken", mock.Anything, mock.AnythingOfType("*models.OAuthToken")).
+		Run(func(args mock.Arguments) { token = args.Get(1).(*models.OAuthToken) }).
+		Return(nil)
+
+	resp, err := service.Token(ctx, &OAuthTokenRequest{GrantType: "authorization_code", ClientID: "client-1", Code: code, RedirectURI: testRedirectURI, CodeVerifier: verifier})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp.AccessToken, models.OAuthAccessTokenPrefix))
+	assert.True(t, strings.HasPrefix(resp.RefreshToken, models.OAuthRefreshTokenPrefix))
+	assert.Equal(t, "Bearer", resp.TokenType)
+	assert.Equal(t, 3600, resp.ExpiresIn)
+	assert.Equal(t, models.ScopeTodosRead, resp.Scope)
+	assert.Equal(t, utils.HashToken(resp.AccessToken), token.AccessTokenHash)
+	assert.Equal(t, utils.HashToken(resp.RefreshToken), token.RefreshTokenHash)
+	assert.Equal(t, 4, token.GrantID)
+
+	// A stolen code is useless without the verifier
+	repo.On("ConsumeAuthorizationCode", mock.Anything, stored.CodeHash).Return(issued, nil).Once()
+	_, err = service.Token(ctx, &OAuthTokenRequest{GrantType: "authorization_code", ClientID: "client-1", Code: code, RedirectURI: testRedirectURI, CodeVerifier: "guessed"})
+	var oauthErr *OAuthError
+	require.True(t, errors.As(err, &oauthErr))
+	assert.Equal(t, "invalid_grant", oauthErr.Code)
+}
+
+func TestOAuthService_Authorize_Denied(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	service := NewOAuthService(repo)
+	repo.On("GetClient", mock.Anything, "client-1").Return(testOAuthClient(), nil)
+
+	req, _ := testAuthorizationRequest(t)
+	redirectTo, err := service.Authorize(context.Background(), 1, req, false)
+	require.NoError(t, err)
+
+	redirect, err := url.Parse(redirectTo)
+	require.NoError(t, err)
+	assert.Equal(t, "access_denied", redirect.Query().Get("error"))
+	assert.Empty(t, redirect.Query().Get("code"))
+	repo.AssertNotCalled(t, "SaveGrant", mock.Anything, mock.Anything)
+}
+
+func TestOAuthService_Token_Errors(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	service := NewOAuthService(repo)
+	confidential := testOAuthClient()
+	confidential.ClientID, confidential.Confidential, confidential.SecretHash = "server-app", true, utils.HashToken("s3cret")
+	repo.On("GetClient", mock.Anything, "client-1").Return(testOAuthClient(), nil)
+	repo.On("GetClient", mock.Anything, "server-app").Return(confidential, nil)
+	repo.On("GetClient", mock.Anything, "unknown").Return((*models.OAuthClient)(nil), repositories.ErrOAuthClientNotFound)
+	repo.On("ConsumeAuthorizationCode", mock.Anything, utils.HashToken("used")).Return((*models.OAuthAuthorizationCode)(nil), repositories.ErrOAuthCodeNotFound)
+	repo.On("ConsumeAuthorizationCode", mock.Anything, utils.HashToken("other-client")).
+		Return(&models.OAuthAuthorizationCode{ClientID: 3, RedirectURI: testRedirectURI}, nil)
+
+	tests := map[string]struct {
+		req  OAuthTokenRequest
+		code string
+	}{
+		"unknown client": {OAuthTokenRequest{GrantType: "authorization_code", ClientID: "unknown"}, "invalid_client"},
+		"missing secret": {OAuthTokenRequest{GrantType: "authorization_code", ClientID: "server-app"}, "invalid_client"},
+		"wrong secret":   {OAuthTokenRequest{GrantType: "authorization_code", ClientID: "server-app", ClientSecret: "guess"}, "invalid_client"},
+		"password grant": {OAuthTokenRequest{GrantType: "password", ClientID: "client-1"}, "unsupported_grant_type"},
+		"used code":      {OAuthTokenRequest{GrantType: "authorization_code", ClientID: "client-1", Code: "used", RedirectURI: testRedirectURI}, "invalid_grant"},
+		"other client":   {OAuthTokenRequest{GrantType: "authorization_code", ClientID: "client-1", Code: "other-client", RedirectURI: testRedirectURI}, "invalid_grant"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.Token(context.Background(), &tt.req)
+			var oauthErr *OAuthError
+			require.True(t, errors.As(err, &oauthErr), err)
+			assert.Equal(t, tt.code, oauthErr.Code)
+		})
+	}
+}
+
+func TestOAuthService_RefreshToken(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	service := NewOAuthService(repo)
+	ctx := context.Background()
+	repo.On("GetClient", mock.Anything, "client-1").Return(testOAuthClient(), nil)
+	repo.On("CreateToken", mock.Anything, mock.AnythingOfType("*models.OAuthToken")).Return(nil)
+
+	current := &models.OAuthToken{GrantID: 4, UserID: 1, ClientID: 2, Scopes: []string{models.ScopeTodosRead, models.ScopeTodosWrite}}
+	repo.On("ConsumeRefreshToken", mock.Anything, utils.HashToken("todo_ort_a")).Return(current, nil)
+	resp, err := service.Token(ctx, &OAuthTokenRequest{GrantType: "refresh_token", ClientID: "client-1", RefreshToken: "todo_ort_a", Scope: models.ScopeTodosRead})
+	require.NoError(t, err)
+	assert.Equal(t, models.ScopeTodosRead, resp.Scope, "clients may narrow the scope")
+	repo.AssertCalled(t, "CreateToken", mock.Anything, mock.MatchedBy(func(token *models.OAuthToken) bool {
+		return token.GrantID == 4 && token.RefreshTokenHash == utils.HashToken(resp.RefreshToken)
+	}))
+
+	narrowed := &models.OAuthToken{GrantID: 4, UserID: 1, ClientID: 2, Scopes: []string{models.ScopeTodosRead}}
+	repo.On("ConsumeRefreshToken", mock.Anything, utils.HashToken("todo_ort_b")).Return(narrowed, nil)
+	_, err = service.Token(ctx, &OAuthTokenRequest{GrantType: "refresh_token", ClientID: "client-1", RefreshToken: "todo_ort_b", Scope: "todos:read todos:write"})
+	var oauthErr *OAuthError
+	require.True(t, errors.As(err, &oauthErr))
+	assert.Equal(t, "invalid_scope", oauthErr.Code)
+
+	leaked := &models.OAuthToken{GrantID: 7, UserID: 1, ClientID: 3, Scopes: []string{models.ScopeTodosRead}}
+	repo.On("ConsumeRefreshToken", mock.Anything, utils.HashToken("todo_ort_c")).Return(leaked, nil)
+	_, err = service.Token(ctx, &OAuthTokenRequest{GrantType: "refresh_token", ClientID: "client-1", RefreshToken: "todo_ort_c"})
+	require.True(t, errors.As(err, &oauthErr))
+	assert.Equal(t, "invalid_grant", oauthErr.Code)
+
+	repo.On("ConsumeRefreshToken", mock.Anything, utils.HashToken("todo_ort_used")).Return((*models.OAuthToken)(nil), repositories.ErrOAuthTokenNotFound)
+	_, err = service.Token(ctx, &OAuthTokenRequest{GrantType: "refresh_token", ClientID: "client-1", RefreshToken: "todo_ort_used"})
+	require.True(t, errors.As(err, &oauthErr))
+	assert.Equal(t, "invalid_grant", oauthErr.Code)
+}
+
+func TestOAuthService_AuthenticateAccessToken(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	service := NewOAuthService(repo)
+	token := &models.OAuthToken{UserID: 1, Scopes: []string{models.ScopeTodosRead}}
+	repo.On("UseAccessToken", mock.Anything, utils.HashToken("todo_oat_valid")).Return(token, nil)
+	repo.On("UseAccessToken", mock.Anything, utils.HashToken("todo_oat_expired")).Return((*models.OAuthToken)(nil), repositories.ErrOAuthTokenNotFound)
+
+	got, err := service.AuthenticateAccessToken(context.Background(), "todo_oat_valid")
+	require.NoError(t, err)
+	assert.Equal(t, token, got)
+
+	_, err = service.AuthenticateAccessToken(context.Background(), "todo_oat_expired")
+	assert.Equal(t, KindUnauthorized, KindOf(err))
+	_, err = service.AuthenticateAccessToken(context.Background(), "todo_pat_valid")
+	assert.Equal(t, KindUnauthorized, KindOf(err))
+}
+
+func TestOAuthService_RevokeAuthorizedApp(t *testing.T) {
+	repo := new(mockOAuthRepo)
+	service := NewOAuthService(repo)
+	repo.On("DeleteGrant", mock.Anything, 1, "client-1").Return(nil)
+	repo.On("DeleteGrant", mock.Anything, 1, "unknown").Return(repositories.ErrOAuthGrantNotFound)
+
+	assert.NoError(t, service.RevokeAuthorizedApp(context.Background(), 1, "client-1"))
+	assert.Equal(t, KindNotFound, KindOf(service.RevokeAuthorizedApp(context.Background(), 1, "unknown")))
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/oidc"
+)
+
+// oidcLoginStateTTL is how long a user may take to sign in at the provider.
+const oidcLoginStateTTL = 10 * time.Minute
+
+var (
+	errOIDCProviderNotFound = NewNotFoundError("oidc_provider_not_found", "identity provider not found")
+	errInvalidOIDCState     = NewUnauthorizedError("invalid_oidc_state", "login request is invalid or has expired")
+)
+
+// OIDCService signs users in through OpenID Connect providers. Identities
+// are linked to existing accounts by verified email address, and users
+// without an account get one on their first login.
+type OIDCService struct {
+	Repo     repositories.OIDCRepoInterface
+	UserRepo repositories.UserRepoInterface
+	// Registration decides who gets an account on their first login. Nil
+	// lets anyone sign up. Invites cannot be presented through a provider,
+	// so in invite-only mode only existing accounts can sign in.
+	Registration *Registration
+
+	providers map[string]*oidc.Provider
+	// order keeps providers listed in configuration order.
+	order []string
+}
+
+// NewOIDCService initializes a new OIDCService.
+func NewOIDCService(providers []*oidc.Provider, repo repositories.OIDCRepoInterface, userRepo repositories.UserRepoInterface) *OIDCService {
+	s := &OIDCService{Repo: repo, UserRepo: userRepo, providers: make(map[string]*oidc.Provider, len(providers))}
+	for _, provider := range providers {
+		s.providers[provider.Name] = provider
+		s.order = append(s.order, provider.Name)
+	}
+	return s
+}
+
+// ListProviders returns the providers users can sign in with.
+func (s *OIDCService) ListProviders() []models.OIDCProvider {
+	providers := make([]models.OIDCProvider, 0, len(s.order))
+	for _, name := range s.order {
+		displayName := s.providers[name].DisplayName
+		if displayName == "" {
+			displayName = name
+		}
+		providers = append(providers, models.OIDCProvider{Name: name, DisplayName: displayName})
+	}
+	return providers
+}
+
+// BeginLogin starts a login with a provider. It returns the URL of the
+// provider's login page and the state that the provider will send back.
+func (s *OIDCService) BeginLogin(ctx context.Context, providerName string) (authURL string, state string, err error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", errOIDCProviderNotFound
+	}
+
+	var nonce, verifier string
+	for _, value := range []*string{&state, &nonce, &verifier} {
+		if *value, err = oidc.RandomString(); err != nil {
+			return "", "", err
+		}
+	}
+
+	authURL, err = provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.Repo.CreateLoginState(ctx, &models.OIDCLoginState{
+		StateHash:    utils.HashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcLoginStateTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// CompleteLogin redeems the authorization code sent back by the provider
+// and returns the user it identifies.
+func (s *OIDCService) CompleteLogin(ctx context.Context, providerName, state, code string) (*models.User, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, errOIDCProviderNotFound
+	}
+
+	loginState, err := s.Repo.ConsumeLoginState(ctx, utils.HashToken(state))
+	if errors.Is(err, repositories.ErrLoginStateNotFound) {
+		return nil, errInvalidOIDCState
+	} else if err != nil {
+		return nil, err
+	}
+	if loginState.Provider != providerName {
+		return nil, errInvalidOIDCState
+	}
+
+	rawIDToken, err := provider.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		return nil, oidcError(err)
+	}
+	claims, err := provider.Verify(ctx, rawIDToken, loginState.Nonce)
+	if err != nil {
+		return nil, oidcError(err)
+	}
+
+	user, err := s.signIn(ctx, providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+	if user.PendingApproval {
+		return nil, errAccountPendingApproval
+	}
+	user.Password = ""
+	return user, nil
+}
+
+// signIn finds or creates the user identified by claims.
+func (s *OIDCService) signIn(ctx context.Context, providerName string, claims *oidc.Claims) (*models.User, error) {
+	identity, err := s.Repo.GetIdentity(ctx, providerName, claims.Subject)
+	if err == nil {
+		if err := s.Repo.RecordIdentityLogin(ctx, identity.ID, claims.Email); err != nil {
+			return nil, err
+		}
+		return s.getUser(ctx, identity.UserID)
+	} else if !errors.Is(err, repositories.ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	// Linking or creating accounts by email is only safe if the provider
+	// vouches for the address
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, NewForbiddenError("oidc_email_unverified", "the identity provider has not verified your email address")
+	}
+	identity = &models.UserIdentity{Provider: providerName, Subject: claims.Subject, Email: claims.Email}
+
+	user, err := s.UserRepo.GetUserByEmail(ctx, claims.Email)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		user = &models.User{Name: oidcUserName(claims), Email: claims.Email}
+		if err := s.Registration.admit(user, false); err != nil {
+			return nil, err
+		}
+		if err := s.Repo.CreateUserWithIdentity(ctx, user, identity); err != nil {
+			return nil, userRepoError(err)
+		}
+		return user, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	identity.UserID = user.ID
+	if err := s.Repo.CreateIdentity(ctx, identity); err != nil {
+		return nil, err
+	}
+	if !user.EmailVerified {
+		if err := s.UserRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			return nil, userRepoError(err)
+		}
+		user.EmailVerified = true
+	}
+	return user, nil
+}
+
+func (s *OIDCService) getUser(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, userRepoError(err)
+	}
+	return user, nil
+}
+
+// oidcUserName picks a name for a new user, falling back to the local part
+// of their email address.
+func oidcUserName(claims *oidc.Claims) string {
+	if name := strings.TrimSpace(claims.Name); name != "" {
+		return name
+	}
+	local, _, _ := strings.Cut(claims.Email, "@")
+	return local
+}
+
+// oidcError translates errors of the provider into domain errors. Other
+// errors, such as an unreachable provider, are left as internal errors.
+func oidcError(err error) error {
+	if errors.Is(err, oidc.ErrExchangeFailed) || errors.Is(err, oidc.ErrInvalidIDToken) {
+		return &Error{Kind: KindUnauthorized, Code: "oidc_login_failed", Message: "sign in with the identity provider failed", Err: err}
+	}
+	return err
+}
+
+var _ OIDCServiceInterface = (*OIDCService)(nil)
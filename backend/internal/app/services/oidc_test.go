@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/oidc"
+	"todo_app_backend/internal/oidc/oidctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockOIDCRepo is a mock implementation of repositories.OIDCRepoInterface.
+type mockOIDCRepo struct {
+	mock.Mock
+}
+
+func (m *mockOIDCRepo) CreateLoginState(ctx context.Context, state *models.OIDCLoginState) error {
+	args := m.Called(ctx, state)
+	return args.Error(0)
+}
+
+func (m *mockOIDCRepo) ConsumeLoginState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	args := m.Called(ctx, stateHash)
+	return args.Get(0).(*models.OIDCLoginState), args.Error(1)
+}
+
+func (m *mockOIDCRepo) GetIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	args := m.Called(ctx, provider, subject)
+	return args.Get(0).(*models.UserIdentity), args.Error(1)
+}
+
+func (m *mockOIDCRepo) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *mockOIDCRepo) RecordIdentityLogin(ctx context.Context, id int, email string) error {
+	args := m.Called(ctx, id, email)
+	return args.Error(0)
+}
+
+func (m *mockOIDCRepo) CreateUserWithIdentity(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	args := m.Called(ctx, user, identity)
+	return args.Error(0)
+}
+
+// newTestOIDCService returns an OIDCService with a single provider "corp"
+// backed by a fake identity provider.
+func newTestOIDCService(t *testing.T, repo *mockOIDCRepo, userRepo *mockUserRepo) (*OIDCService, *oidctest.Server) {
+	idp := oidctest.NewServer("todo-app", "s3cret")
+	t.Cleanup(idp.Close)
+
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "corp",
+		DisplayName:  "Example Corp",
+		Issuer:       idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  "http://api.test/api/v1/auth/oidc/corp/callback",
+	}, nil)
+	return NewOIDCService([]*oidc.Provider{provider}, repo, userRepo), idp
+}
+
+// signInAtProvider starts a login, signs user in at the fake provider by
+// following the login URL and returns the state and code sent back.
+func signInAtProvider(t *testing.T, service *OIDCService, repo *mockOIDCRepo, idp *oidctest.Server, user oidctest.User) (state, code string) {
+	t.Helper()
+
+	var stored *models.OIDCLoginState
+	repo.On("CreateLoginState", mock.Anything, mock.AnythingOfType("*models.OIDCLoginState")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*models.OIDCLoginState) }).
+		Return(nil).Once()
+
+	authURL, state, err := service.BeginLogin(context.Background(), "corp")
+	require.NoError(t, err)
+	require.Equal(t, utils.HashToken(state), stored.StateHash)
+	assert.NotContains(t, authURL, stored.CodeVerifier, "the code verifier must never leave the server")
+
+	idp.User = user
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, state, callback.Query().Get("state"))
+
+	repo.On("ConsumeLoginState", mock.Anything, stored.StateHash).Return(stored, nil).Once()
+	return state, callback.Query().Get("code")
+}
+
+func TestOIDCService_ListProviders(t *testing.T) {
+	service, _ := newTestOIDCService(t, new(mockOIDCRepo), new(mockUserRepo))
+
+	assert.Equal(t, []models.OIDCProvider{{Name: "corp", DisplayName: "Example Corp"}}, service.ListProviders())
+}
+
+func TestOIDCService_CompleteLogin_ProvisionsNewUser(t *testing.T) {
+	repo := new(mockOIDCRepo)
+	userRepo := new(mockUserRepo)
+	service, idp := newTestOIDCService(t, repo, userRepo)
+
+	state, code := signInAtProvider(t, service, repo, idp, oidctest.User{Subject: "42", Email: "jane@example.com", EmailVerified: true, Name: "Jane Doe"})
+
+	repo.On("GetIdentity", mock.Anything, "corp", "42").Return((*models.UserIdentity)(nil), repositories.ErrIdentityNotFound)
+	userRepo.On("GetUserByEmail", mock.Anything, "jane@example.com").Return((*models.User)(nil), repositories.ErrUserNotFound)
+	repo.On("CreateUserWithIdentity", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			user := args.Get(1).(*models.User)
+			user.ID, user.EmailVerified = 7, true
+		}).
+		Return(nil)
+
+	user, err := service.CompleteLogin(context.Background(), "corp", state, code)
+	require.NoError(t, err)
+
+	assert.Equal(t, 7, user.ID)
+	assert.Equal(t, "Jane Doe", user.Name)
+	assert.Equal(t, "jane@example.com", user.Email)
+	repo.AssertCalled(t, "CreateUserWithIdentity", mock.Anything, mock.Anything,
+		&models.UserIdentity{Provider: "corp", Subject: "42", Email: "jane@example.com"})
+}
+
+func TestOIDCService_CompleteLogin_LinksVerifiedEmail(t *testing.T) {
+	repo := new(mockOIDCRepo)
+	userRepo := new(mockUserRepo)
+	service, idp := newTestOIDCService(t, repo, userRepo)
+
+	state, code := signInAtProvider(t, service, repo, idp, oidctest.User{Subject: "42", Email: "john@example.com", EmailVerified: true})
+
+	repo.On("GetIdentity", mock.Anything, "corp", "42").Return((*models.UserIdentity)(nil), repositories.ErrIdentityNotFound)
+	userRepo.On("GetUserByEmail", mock.Anything, "john@example.com").Return(&models.User{ID: 3, Email: "john@example.com", Password: "hash"}, nil)
+	repo.On("CreateIdentity", mock.Anything, &models.UserIdentity{UserID: 3, Provider: "corp", Subject: "42", Email: "john@example.com"}).Return(nil)
+	userRepo.On("MarkEmailVerified", mock.Anything, 3).Return(nil)
+
+	user, err := service.CompleteLogin(context.Background(), "corp", state, code)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, user.ID)
+	assert.True(t, user.EmailVerified)
+	assert.Empty(t, user.Password)
+	repo.AssertExpectations(t)
+	userRepo.AssertExpectations(t)
+}
+
+func TestOIDCService_CompleteLogin_RefusesUnverifiedEmail(t *testing.T) {
+	repo := new(mockOIDCRepo)
+	userRepo := new(mockUserRepo)
+	service, idp := newTestOIDCService(t, repo, userRepo)
+
+	state, code := signInAtProvider(t, service, repo, idp, oidctest.User{Subject: "42", Email: "john@example.com", EmailVerified: false})
+
+	repo.On("GetIdentity", mock.Anything, "corp", "42").Return((*models.UserIdentity)(nil), repositories.ErrIdentityNotFound)
+
+	_, err := service.CompleteLogin(context.Background(), "corp", state, code)
+	assert.Equal(t, KindForbidden, KindOf(err))
+	userRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "CreateIdentity", mock.Anything, mock.Anything)
+}
+
+func TestOIDCService_CompleteLogin_KnownIdentity(t *testing.T) {
+	repo := new(mockOIDCRepo)
+	userRepo := new(mockUserRepo)
+	service, idp := newTestOIDCService(t, repo, userRepo)
+
+	// The email at the provider no longer matches the account, but the
+	// subject identifies the user
+	state, code := signInAtProvider(t, service, repo, idp, oidctest.User{Subject: "42", Email: "renamed@example.com", EmailVerified: false})
+
+	repo.On("GetIdentity", mock.Anything, "corp", "42").Return(&models.UserIdentity{ID: 9, UserID: 3, Provider: "corp", Subject: "42"}, nil)
+	repo.On("RecordIdentityLogin", mock.Anything, 9, "renamed@example.com").Return(nil)
+	userRepo.On("GetUserByID", mock.Anything, 3).Return(&models.User{ID: 3, Email: "john@example.com"}, nil)
+
+	user, err := service.CompleteLogin(context.Background(), "corp", state, code)
+	require.NoError(t, err)
+	assert.Equal(t, 3, user.ID)
+}
+
+func TestOIDCService_CompleteLogin_InvalidState(t *testing.T) {
+	repo := new(mockOIDCRepo)
+	service, _ := newTestOIDCService(t, repo, new(mockUserRepo))
+
+	repo.On("ConsumeLoginState", mock.Anything, utils.HashToken("forged")).
+		Return((*models.OIDCLoginState)(nil), repositories.ErrLoginStateNotFound)
+	_, err := service.CompleteLogin(context.Background(), "corp", "forged", "code")
+	assert.Equal(t, KindUnauthorized, KindOf(err))
+
+	// States are bound to the provider they were created for
+	repo.On("ConsumeLoginState", mock.Anything, utils.HashToken("other")).
+		Return(&models.OIDCLoginState{Provider: "google", Nonce: "n", CodeVerifier: "v"}, nil)
+	_, err = service.CompleteLogin(context.Background(), "corp", "other", "code")
+	assert.Equal(t, KindUnauthorized, KindOf(err))
+
+	_, err = service.CompleteLogin(context.Background(), "unknown", "state", "code")
+	assert.Equal(t, KindNotFound, KindOf(err))
+}
+
+func TestOIDCService_CompleteLogin_RejectedCode(t *testing.T) {
+	repo := new(mockOIDCRepo)
+	service, idp := newTestOIDCService(t, repo, new(mockUserRepo))
+
+	state, _ := signInAtProvider(t, service, repo, idp, idp.User)
+
+	_, err := service.CompleteLogin(context.Background(), "corp", state, "made-up-code")
+	assert.Equal(t, KindUnauthorized, KindOf(err))
+	assert.ErrorIs(t, err, oidc.ErrExchangeFailed)
+}
+
+func TestOIDCService_CompleteLogin_RespectsRegistrationMode(t *testing.T) {
+	repo := new(mockOIDCRepo)
+	userRepo := new(mockUserRepo)
+	service, idp := newTestOIDCService(t, repo, userRepo)
+	service.Registration = &Registration{Mode: models.RegistrationInviteOnly}
+
+	repo.On("GetIdentity", mock.Anything, "corp", "42").Return((*models.UserIdentity)(nil), repositories.ErrIdentityNotFound)
+	userRepo.On("GetUserByEmail", mock.Anything, "jane@example.com").Return((*models.User)(nil), repositories.ErrUserNotFound)
+
+	// Invites cannot be presented through the provider
+	state, code := signInAtProvider(t, service, repo, idp, oidctest.User{Subject: "42", Email: "jane@example.com", EmailVerified: true})
+	_, err := service.CompleteLogin(context.Background(), "corp", state, code)
+	assert.Equal(t, "invite_required", err.(*Error).Code)
+	repo.AssertNotCalled(t, "CreateUserWithIdentity", mock.Anything, mock.Anything, mock.Anything)
+
+	// New accounts await approval before they can sign in
+	service.Registration = &Registration{Mode: models.RegistrationApproval}
+	repo.On("CreateUserWithIdentity", mock.Anything, mock.MatchedBy(func(user *models.User) bool { return user.PendingApproval }), mock.Anything).
+		Return(nil).Once()
+
+	state, code = signInAtProvider(t, service, repo, idp, oidctest.User{Subject: "42", Email: "jane@example.com", EmailVerified: true})
+	_, err = service.CompleteLogin(context.Background(), "corp", state, code)
+	assert.Equal(t, "account_pending_approval", err.(*Error).Code)
+	repo.AssertExpectations(t)
+}
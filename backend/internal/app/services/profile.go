@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/password"
+)
+
+// DefaultDeletionGracePeriod is how long a self-deleted account is kept
+// before it is removed for good.
+const DefaultDeletionGracePeriod = 14 * 24 * time.Hour
+
+// ProfileUpdate holds the profile fields a user wants to change. Nil fields
+// are left unchanged.
+type ProfileUpdate struct {
+	Name  *string
+	Email *string
+}
+
+// ProfileService lets users manage their own account.
+type ProfileService struct {
+	UserRepo repositories.UserRepoInterface
+	Sessions SessionServiceInterface
+	Accounts AccountServiceInterface
+
+	// DeletionGracePeriod is how long a deleted account can still be restored.
+	DeletionGracePeriod time.Duration
+	// PasswordPolicy decides which new passwords are accepted.
+	PasswordPolicy *password.Policy
+}
+
+// NewProfileService initializes a new ProfileService.
+func NewProfileService(userRepo repositories.UserRepoInterface, sessions SessionServiceInterface, accounts AccountServiceInterface) *ProfileService {
+	return &ProfileService{
+		UserRepo:            userRepo,
+		Sessions:            sessions,
+		Accounts:            accounts,
+		DeletionGracePeriod: DefaultDeletionGracePeriod,
+		PasswordPolicy:      password.DefaultPolicy,
+	}
+}
+
+// GetProfile retrieves the user's own account.
+func (s *ProfileService) GetProfile(ctx context.Context, userID int) (*models.User, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, userRepoError(err)
+	}
+	user.Password = ""
+	return user, nil
+}
+
+// UpdateProfile applies update to the user's account. A new email address
+// is not applied right away: a confirmation link is sent to it instead and
+// the returned flag reports that the change is pending.
+func (s *ProfileService) UpdateProfile(ctx context.Context, userID int, update ProfileUpdate) (*models.User, bool, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" {
+			return nil, false, NewValidationError("name must not be empty", FieldError{Field: "name", Message: "must not be empty"})
+		}
+		if name != user.Name {
+			if err := s.UserRepo.UpdateName(ctx, userID, name); err != nil {
+				return nil, false, userRepoError(err)
+			}
+			user.Name = name
+		}
+	}
+
+	emailPending := false
+	if update.Email != nil && !strings.EqualFold(*update.Email, user.Email) {
+		if err := s.Accounts.RequestEmailChange(ctx, user, *update.Email); err != nil {
+			return nil, false, err
+		}
+		emailPending = true
+	}
+	return user, emailPending, nil
+}
+
+// ChangePassword replaces the user's password after checking the current
+// one. Every session other than sessionID is revoked.
+func (s *ProfileService) ChangePassword(ctx context.Context, userID int, sessionID int, currentPassword, newPassword string) error {
+	if newPassword == "" {
+		return NewValidationError("new password is required", FieldError{Field: "new_password", Message: "is required"})
+	}
+	user, err := verifyUserPassword(ctx, s.UserRepo, userID, "current_password", currentPassword)
+	if err != nil {
+		return err
+	}
+	if err := checkPassword(s.PasswordPolicy, "new_password", newPassword, user.Name, user.Email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.UserRepo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		return userRepoError(err)
+	}
+	return s.Sessions.RevokeOtherSessions(ctx, userID, sessionID)
+}
+
+// DeleteAccount schedules the user's account for deletion once the grace
+// period has passed and signs out every other session. Until then the user
+// can log in and restore the account with CancelDeletion.
+func (s *ProfileService) DeleteAccount(ctx context.Context, userID int, sessionID int, password string) (*models.User, error) {
+	if _, err := verifyUserPassword(ctx, s.UserRepo, userID, "password", password); err != nil {
+		return nil, err
+	}
+
+	at := time.Now().Add(s.DeletionGracePeriod).UTC()
+	if err := s.UserRepo.ScheduleDeletion(ctx, userID, at); err != nil {
+		return nil, userRepoError(err)
+	}
+	if err := s.Sessions.RevokeOtherSessions(ctx, userID, sessionID); err != nil {
+		return nil, err
+	}
+	return s.GetProfile(ctx, userID)
+}
+
+// CancelDeletion restores an account scheduled for deletion.
+func (s *ProfileService) CancelDeletion(ctx context.Context, userID int) (*models.User, error) {
+	if err := s.UserRepo.CancelDeletion(ctx, userID); err != nil {
+		return nil, userRepoError(err)
+	}
+	return s.GetProfile(ctx, userID)
+}
+
+// PurgeDeletedAccounts removes accounts whose grace period has ended,
+// together with their todos.
+func (s *ProfileService) PurgeDeletedAccounts(ctx context.Context) (int64, error) {
+	return s.UserRepo.DeleteScheduledUsers(ctx, time.Now())
+}
+
+// verifyUserPassword returns a validation error on field unless password
+// is the password of the user.
+func verifyUserPassword(ctx context.Context, userRepo repositories.UserRepoInterface, userID int, field string, password string) (*models.User, error) {
+	user, err := userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, userRepoError(err)
+	}
+	if !utils.CheckPasswordHash(password, user.Password) {
+		return nil, NewValidationError("password is incorrect", FieldError{Field: field, Message: "is incorrect"})
+	}
+	return user, nil
+}
+
+var _ ProfileServiceInterface = (*ProfileService)(nil)
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockAccountService is a mock implementation of AccountServiceInterface.
+type mockAccountService struct {
+	mock.Mock
+}
+
+func (m *mockAccountService) SendVerificationEmail(ctx context.Context, user *models.User) error {
+	return m.Called(ctx, user).Error(0)
+}
+
+func (m *mockAccountService) ResendVerificationEmail(ctx context.Context, email string) error {
+	return m.Called(ctx, email).Error(0)
+}
+
+func (m *mockAccountService) VerifyEmail(ctx context.Context, token string) error {
+	return m.Called(ctx, token).Error(0)
+}
+
+func (m *mockAccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	return m.Called(ctx, email).Error(0)
+}
+
+func (m *mockAccountService) ResetPassword(ctx context.Context, token, password string) error {
+	return m.Called(ctx, token, password).Error(0)
+}
+
+func (m *mockAccountService) RequestEmailChange(ctx context.Context, user *models.User, newEmail string) error {
+	return m.Called(ctx, user, newEmail).Error(0)
+}
+
+func newTestUserWithPassword(t *testing.T, password string) *models.User {
+	hash, err := utils.HashPassword(password)
+	require.NoError(t, err)
+	return &models.User{ID: 1, Name: "John", Email: "john@example.com", Password: hash}
+}
+
+func TestProfileService_UpdateProfile(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	accounts := new(mockAccountService)
+	service := NewProfileService(userRepo, nil, accounts)
+
+	userRepo.On("GetUserByID", mock.Anything, 1).Return(&models.User{ID: 1, Name: "John", Email: "john@example.com"}, nil)
+	userRepo.On("UpdateName", mock.Anything, 1, "Johnny").Return(nil)
+	accounts.On("RequestEmailChange", mock.Anything, mock.AnythingOfType("*models.User"), "johnny@example.com").Return(nil)
+
+	name, email := " Johnny ", "johnny@example.com"
+	user, emailPending, err := service.UpdateProfile(context.Background(), 1, ProfileUpdate{Name: &name, Email: &email})
+	require.NoError(t, err)
+	assert.True(t, emailPending)
+	assert.Equal(t, "Johnny", user.Name)
+	assert.Equal(t, "john@example.com", user.Email, "email changes only once confirmed")
+
+	empty := ""
+	_, _, err = service.UpdateProfile(context.Background(), 1, ProfileUpdate{Name: &empty})
+	assert.Equal(t, KindValidation, KindOf(err))
+
+	userRepo.AssertExpectations(t)
+	accounts.AssertExpectations(t)
+}
+
+func TestProfileService_ChangePassword(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	sessions := new(mockSessionService)
+	service := NewProfileService(userRepo, sessions, nil)
+
+	userRepo.On("GetUserByID", mock.Anything, 1).Return(newTestUserWithPassword(t, "old-password"), nil)
+
+	err := service.ChangePassword(context.Background(), 1, 10, "wrong", "new-password")
+	assert.Equal(t, KindValidation, KindOf(err))
+	userRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+
+	err = service.ChangePassword(context.Background(), 1, 10, "old-password", "abc")
+	assert.Equal(t, KindValidation, KindOf(err))
+	assert.Equal(t, []FieldError{
+		{Field: "new_password", Message: "must be at least 8 characters"},
+		{Field: "new_password", Message: "is too easy to guess"},
+	}, err.(*Error).Fields)
+	userRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+
+	userRepo.On("UpdatePassword", mock.Anything, 1, mock.MatchedBy(func(hash string) bool {
+		return utils.CheckPasswordHash("new-password", hash)
+	})).Return(nil)
+	sessions.On("RevokeOtherSessions", mock.Anything, 1, 10).Return(nil)
+
+	require.NoError(t, service.ChangePassword(context.Background(), 1, 10, "old-password", "new-password"))
+	userRepo.AssertExpectations(t)
+	sessions.AssertExpectations(t)
+}
+
+func TestProfileService_DeleteAccount(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	sessions := new(mockSessionService)
+	service := NewProfileService(userRepo, sessions, nil)
+	service.DeletionGracePeriod = 48 * time.Hour
+
+	user := newTestUserWithPassword(t, "password")
+	userRepo.On("GetUserByID", mock.Anything, 1).Return(user, nil)
+	userRepo.On("ScheduleDeletion", mock.Anything, 1, mock.MatchedBy(func(at time.Time) bool {
+		return at.Sub(time.Now()) > 47*time.Hour
+	})).Return(nil)
+	sessions.On("RevokeOtherSessions", mock.Anything, 1, 10).Return(nil)
+
+	_, err := service.DeleteAccount(context.Background(), 1, 10, "password")
+	require.NoError(t, err)
+
+	userRepo.On("CancelDeletion", mock.Anything, 1).Return(nil)
+	_, err = service.CancelDeletion(context.Background(), 1)
+	require.NoError(t, err)
+
+	userRepo.AssertExpectations(t)
+	sessions.AssertExpectations(t)
+}
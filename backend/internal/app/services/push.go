@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/webpush"
+)
+
+// DefaultPushTTL is how long push services keep reminders for offline
+// browsers.
+const DefaultPushTTL = time.Hour
+
+// PushService manages the Web Push subscriptions of browsers and sends them
+// reminders.
+type PushService struct {
+	Repo repositories.PushSubscriptionRepoInterface
+	// Sender is nil when Web Push is not configured, in which case browsers
+	// cannot subscribe.
+	Sender *webpush.Sender
+	// TTL is how long push services keep messages for offline browsers.
+	TTL time.Duration
+}
+
+// NewPushService initializes a new PushService.
+func NewPushService(repo repositories.PushSubscriptionRepoInterface, sender *webpush.Sender) *PushService {
+	return &PushService{Repo: repo, Sender: sender, TTL: DefaultPushTTL}
+}
+
+// PublicKey returns the VAPID public key browsers subscribe with, or "" when
+// Web Push is not configured.
+func (s *PushService) PublicKey() string {
+	if s.Sender == nil {
+		return ""
+	}
+	return s.Sender.PublicKey()
+}
+
+// ListSubscriptions retrieves the browsers subscribed by the user.
+func (s *PushService) ListSubscriptions(ctx context.Context, userID int) ([]models.PushSubscription, error) {
+	return s.Repo.ListPushSubscriptions(ctx, userID)
+}
+
+// Subscribe registers a browser subscription of the user, with the endpoint
+// and keys returned by PushSubscription.toJSON() in the browser.
+func (s *PushService) Subscribe(ctx context.Context, userID int, endpoint, p256dh, auth string) (*models.PushSubscription, error) {
+	if s.Sender == nil {
+		return nil, NewConflictError("push_not_configured", "web push is not configured on this server")
+	}
+
+	var fields []FieldError
+	if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		fields = append(fields, FieldError{Field: "endpoint", Message: "must be an http or https URL"})
+	}
+	// Keys may come padded or not
+	p256dh, auth = strings.TrimRight(p256dh, "="), strings.TrimRight(auth, "=")
+	if key, err := base64.RawURLEncoding.DecodeString(p256dh); err != nil || len(key) != 65 {
+		fields = append(fields, FieldError{Field: "keys.p256dh", Message: "must be a base64url encoded P-256 public key"})
+	}
+	if secret, err := base64.RawURLEncoding.DecodeString(auth); err != nil || len(secret) != 16 {
+		fields = append(fields, FieldError{Field: "keys.auth", Message: "must be a base64url encoded 16 byte secret"})
+	}
+	if len(fields) > 0 {
+		return nil, NewValidationError("invalid push subscription", fields...)
+	}
+
+	sub := &models.PushSubscription{UserID: userID, Endpoint: endpoint, P256dh: p256dh, Auth: auth}
+	if err := s.Repo.SavePushSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Unsubscribe removes a browser subscription of the user.
+func (s *PushService) Unsubscribe(ctx context.Context, userID int, id int) error {
+	err := s.Repo.DeletePushSubscription(ctx, userID, id)
+	if errors.Is(err, repositories.ErrPushSubscriptionNotFound) {
+		return NewNotFoundError("push_subscription_not_found", "push subscription not found")
+	}
+	return err
+}
+
+// pushReminder is the push message of a reminder, in the shape of the
+// options of showNotification() so that service workers can pass it on.
+type pushReminder struct {
+	Title   string       `json:"title"`
+	Body    string       `json:"body"`
+	Tag     string       `json:"tag"`
+	Data    pushData     `json:"data"`
+	Actions []pushAction `json:"actions"`
+}
+
+type pushData struct {
+	TodoID     int `json:"todo_id"`
+	ReminderID int `json:"reminder_id"`
+}
+
+type pushAction struct {
+	Action string `json:"action"`
+	Title  string `json:"title"`
+}
+
+// SendReminder sends the reminder of a delivery to every browser of its
+// user. Subscriptions that push services no longer know are removed. It
+// fails only when no browser could be reached.
+func (s *PushService) SendReminder(ctx context.Context, delivery *ReminderDelivery) error {
+	if s.Sender == nil {
+		return nil
+	}
+	subs, err := s.Repo.ListPushSubscriptions(ctx, delivery.UserID)
+	if err != nil {
+		return err
+	}
+
+	reminder := delivery.Reminder
+	msg := pushReminder{
+		Title: reminder.Title,
+		Tag:   "reminder-" + strconv.Itoa(reminder.ReminderID),
+		Data:  pushData{TodoID: reminder.TodoID, ReminderID: reminder.ReminderID},
+		Actions: []pushAction{
+			{Action: "snooze", Title: fmt.Sprintf("Snooze %d min", int(DefaultSnooze/time.Minute))},
+		},
+	}
+	if reminder.DueDate != nil {
+		msg.Body = "Due " + reminder.DueDate.In(delivery.Location()).Format("Mon Jan 2 at 15:04")
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return PermanentJobError(err)
+	}
+
+	var errs []error
+	for _, sub := range subs {
+		err := s.Sender.Send(ctx, webpush.Subscription{Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth}, payload, s.TTL)
+		switch {
+		case errors.Is(err, webpush.ErrSubscriptionGone):
+			if err := s.Repo.DeletePushSubscriptionByEndpoint(ctx, sub.Endpoint); err != nil {
+				log.Printf("Failed to remove expired push subscription %d: %v", sub.ID, err)
+			}
+		case err != nil:
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 && len(errs) == len(subs) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+var _ PushServiceInterface = (*PushService)(nil)
+var _ ReminderChannel = (*PushService)(nil)
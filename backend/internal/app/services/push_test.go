@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/webpush"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockPushSubscriptionRepo is a mock implementation of
+// repositories.PushSubscriptionRepoInterface.
+type mockPushSubscriptionRepo struct {
+	mock.Mock
+}
+
+func (m *mockPushSubscriptionRepo) SavePushSubscription(ctx context.Context, sub *models.PushSubscription) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *mockPushSubscriptionRepo) ListPushSubscriptions(ctx context.Context, userID int) ([]models.PushSubscription, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.PushSubscription), args.Error(1)
+}
+
+func (m *mockPushSubscriptionRepo) DeletePushSubscription(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *mockPushSubscriptionRepo) DeletePushSubscriptionByEndpoint(ctx context.Context, endpoint string) error {
+	args := m.Called(ctx, endpoint)
+	return args.Error(0)
+}
+
+// newPushKeys returns the base64url encoded keys of a new browser
+// subscription.
+func newPushKeys(t *testing.T) (p256dh, auth string) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	secret := make([]byte, 16)
+	_, err = rand.Read(secret)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), base64.RawURLEncoding.EncodeToString(secret)
+}
+
+func newTestPushSender(t *testing.T, client *http.Client) *webpush.Sender {
+	_, private, err := webpush.GenerateVAPIDKeys()
+	require.NoError(t, err)
+	sender, err := webpush.NewSender(private, "mailto:admin@example.com", client)
+	require.NoError(t, err)
+	return sender
+}
+
+func TestPushService_Subscribe(t *testing.T) {
+	repo := new(mockPushSubscriptionRepo)
+	ctx := context.Background()
+	p256dh, auth := newPushKeys(t)
+
+	// Browsers cannot subscribe without VAPID keys
+	_, err := NewPushService(repo, nil).Subscribe(ctx, 1, "https://push.example.com/abc", p256dh, auth)
+	assert.Equal(t, KindConflict, KindOf(err))
+
+	service := NewPushService(repo, newTestPushSender(t, http.DefaultClient))
+	assert.NotEmpty(t, service.PublicKey())
+
+	_, err = service.Subscribe(ctx, 1, "ftp://push.example.com/abc", "short", auth+"AAAA")
+	var serviceErr *Error
+	require.ErrorAs(t, err, &serviceErr)
+	assert.Len(t, serviceErr.Fields, 3)
+
+	repo.On("SavePushSubscription", ctx, mock.MatchedBy(func(sub *models.PushSubscription) bool {
+		return sub.UserID == 1 && sub.P256dh == p256dh && sub.Auth == auth
+	})).Return(nil)
+	// Padded keys are accepted
+	_, err = service.Subscribe(ctx, 1, "https://push.example.com/abc", p256dh+"=", auth+"==")
+	assert.NoError(t, err)
+}
+
+func TestPushService_SendReminder(t *testing.T) {
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.URL.Path)
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		assert.Equal(t, "aes128gcm", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "3600", r.Header.Get("TTL"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	repo := new(mockPushSubscriptionRepo)
+	service := NewPushService(repo, newTestPushSender(t, srv.Client()))
+	ctx := context.Background()
+
+	p256dh, auth := newPushKeys(t)
+	repo.On("ListPushSubscriptions", ctx, 1).Return([]models.PushSubscription{
+		{ID: 1, UserID: 1, Endpoint: srv.URL + "/live", P256dh: p256dh, Auth: auth},
+		{ID: 2, UserID: 1, Endpoint: srv.URL + "/gone", P256dh: p256dh, Auth: auth},
+	}, nil)
+	repo.On("DeletePushSubscriptionByEndpoint", ctx, srv.URL+"/gone").Return(nil)
+
+	delivery := &ReminderDelivery{Channel: models.ChannelPush, UserID: 1, Reminder: models.DueReminder{ReminderID: 7, TodoID: 3, Title: "Pay rent"}}
+	require.NoError(t, service.SendReminder(ctx, delivery))
+	assert.Equal(t, []string{"/live", "/gone"}, received)
+	// Browsers that unsubscribed are forgotten
+	repo.AssertCalled(t, "DeletePushSubscriptionByEndpoint", ctx, srv.URL+"/gone")
+
+	// Failing to reach every browser fails the delivery, so that it is retried
+	srv.Close()
+	assert.Error(t, service.SendReminder(ctx, delivery))
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+)
+
+// inviteCodeDisplayLength is how many leading characters of an invite code
+// are stored in the clear so that admins can recognise it in listings.
+const inviteCodeDisplayLength = len(models.InviteCodePrefix) + 6
+
+var (
+	errInviteRequired         = NewForbiddenError("invite_required", "an invite code is required to sign up")
+	errInvalidInviteCode      = NewValidationError("invalid invite code", FieldError{Field: "invite_code", Message: "is invalid, used or expired"})
+	errEmailDomainNotAllowed  = NewForbiddenError("email_domain_not_allowed", "sign up is restricted to email addresses of approved domains")
+	errAccountPendingApproval = NewForbiddenError("account_pending_approval", "account is awaiting approval by an administrator")
+)
+
+// Registration decides who may create an account. A nil Registration lets
+// anyone sign up.
+type Registration struct {
+	// Mode is one of the models.Registration* modes.
+	Mode string
+	// AllowedDomains are the email domains that may sign up in domain
+	// allowlist mode. Subdomains must be listed separately.
+	AllowedDomains []string
+}
+
+func (r *Registration) mode() string {
+	if r == nil || r.Mode == "" {
+		return models.RegistrationOpen
+	}
+	return r.Mode
+}
+
+// admit checks that user may sign up and marks them pending if admins
+// approve new accounts. hasInvite tells whether the user presented an
+// invite code; the code is checked when it is redeemed.
+func (r *Registration) admit(user *models.User, hasInvite bool) error {
+	switch r.mode() {
+	case models.RegistrationInviteOnly:
+		if !hasInvite {
+			return errInviteRequired
+		}
+	case models.RegistrationDomainAllowlist:
+		_, domain, _ := strings.Cut(strings.ToLower(user.Email), "@")
+		for _, allowed := range r.AllowedDomains {
+			if domain == allowed {
+				return nil
+			}
+		}
+		return errEmailDomainNotAllowed
+	case models.RegistrationApproval:
+		user.PendingApproval = true
+	}
+	return nil
+}
+
+// RegistrationService manages the invite codes of invite-only mode.
+type RegistrationService struct {
+	InviteRepo   repositories.InviteRepoInterface
+	Registration *Registration
+	// Audit records created invite codes. It is optional.
+	Audit AuditRecorder
+}
+
+// NewRegistrationService initializes a new RegistrationService.
+func NewRegistrationService(inviteRepo repositories.InviteRepoInterface, registration *Registration) *RegistrationService {
+	return &RegistrationService{InviteRepo: inviteRepo, Registration: registration}
+}
+
+// Mode returns the registration mode, so that clients can tell whether to
+// ask for an invite code.
+func (s *RegistrationService) Mode() string {
+	return s.Registration.mode()
+}
+
+// CreateInvite issues a single-use invite code and returns it together with
+// its secret value, which is not stored and cannot be retrieved again.
+func (s *RegistrationService) CreateInvite(ctx context.Context, adminID int, note string, expiresAt *time.Time) (*models.InviteCode, string, error) {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", NewValidationError("invalid invite code request", FieldError{Field: "expires_at", Message: "must be in the future"})
+	}
+
+	code, err := generateToken(models.InviteCodePrefix)
+	if err != nil {
+		return nil, "", err
+	}
+
+	invite := &models.InviteCode{
+		Prefix:    code[:inviteCodeDisplayLength],
+		CodeHash:  utils.HashToken(code),
+		Note:      strings.TrimSpace(note),
+		CreatedBy: &adminID,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.InviteRepo.CreateInvite(ctx, invite); err != nil {
+		return nil, "", err
+	}
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditInviteCreated,
+		TargetType: models.AuditTargetInvite,
+		TargetID:   strconv.Itoa(invite.ID),
+		Metadata:   auditMetadata(map[string]interface{}{"prefix": invite.Prefix, "note": invite.Note, "expires_at": invite.ExpiresAt}),
+	})
+	return invite, code, nil
+}
+
+// ListInvites retrieves every invite code, used or not.
+func (s *RegistrationService) ListInvites(ctx context.Context) ([]models.InviteCode, error) {
+	return s.InviteRepo.ListInvites(ctx)
+}
+
+// DeleteInvite revokes an invite code.
+func (s *RegistrationService) DeleteInvite(ctx context.Context, id int) error {
+	err := s.InviteRepo.DeleteInvite(ctx, id)
+	if errors.Is(err, repositories.ErrInviteNotFound) {
+		return NewNotFoundError("invite_not_found", "invite code not found")
+	}
+	return err
+}
+
+var _ RegistrationServiceInterface = (*RegistrationService)(nil)
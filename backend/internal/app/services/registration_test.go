@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockInviteRepo is a mock implementation of repositories.InviteRepoInterface.
+type mockInviteRepo struct {
+	mock.Mock
+}
+
+func (m *mockInviteRepo) CreateInvite(ctx context.Context, invite *models.InviteCode) error {
+	args := m.Called(ctx, invite)
+	return args.Error(0)
+}
+
+func (m *mockInviteRepo) ListInvites(ctx context.Context) ([]models.InviteCode, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.InviteCode), args.Error(1)
+}
+
+func (m *mockInviteRepo) DeleteInvite(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockInviteRepo) CreateInvitedUser(ctx context.Context, user *models.User, codeHash string) error {
+	args := m.Called(ctx, user, codeHash)
+	return args.Error(0)
+}
+
+const strongPassword = "correct horse battery staple"
+
+func TestCreateUser_InviteOnly(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	inviteRepo := new(mockInviteRepo)
+	userService := NewUserService(userRepo)
+	userService.Registration = &Registration{Mode: models.RegistrationInviteOnly}
+	userService.InviteRepo = inviteRepo
+
+	_, err := userService.CreateUser(context.Background(), "Jane", "jane@example.com", strongPassword, "")
+	assert.Equal(t, "invite_required", err.(*Error).Code)
+
+	inviteRepo.On("CreateInvitedUser", mock.Anything, mock.Anything, utils.HashToken("todo_inv_valid")).Return(nil).Once()
+	inviteRepo.On("CreateInvitedUser", mock.Anything, mock.Anything, utils.HashToken("todo_inv_used")).Return(repositories.ErrInviteNotFound).Once()
+
+	user, err := userService.CreateUser(context.Background(), "Jane", "jane@example.com", strongPassword, "todo_inv_valid")
+	assert.NoError(t, err)
+	assert.False(t, user.PendingApproval)
+
+	_, err = userService.CreateUser(context.Background(), "Jane", "jane@example.com", strongPassword, "todo_inv_used")
+	assert.Equal(t, KindValidation, KindOf(err))
+	assert.Equal(t, "invite_code", err.(*Error).Fields[0].Field)
+
+	inviteRepo.AssertExpectations(t)
+	userRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
+
+func TestCreateUser_DomainAllowlist(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	userService := NewUserService(userRepo)
+	userService.Registration = &Registration{Mode: models.RegistrationDomainAllowlist, AllowedDomains: []string{"example.com"}}
+
+	userRepo.On("CreateUser", mock.Anything, mock.Anything).Return(nil).Once()
+
+	_, err := userService.CreateUser(context.Background(), "Jane", "Jane@Example.com", strongPassword, "")
+	assert.NoError(t, err)
+
+	_, err = userService.CreateUser(context.Background(), "Jane", "jane@sub.example.com", strongPassword, "")
+	assert.Equal(t, "email_domain_not_allowed", err.(*Error).Code)
+	_, err = userService.CreateUser(context.Background(), "Jane", "jane@example.com.evil.test", strongPassword, "")
+	assert.Equal(t, "email_domain_not_allowed", err.(*Error).Code)
+	userRepo.AssertExpectations(t)
+}
+
+func TestCreateUser_Approval(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	userService := NewUserService(userRepo)
+	userService.Registration = &Registration{Mode: models.RegistrationApproval}
+
+	userRepo.On("CreateUser", mock.Anything, mock.MatchedBy(func(user *models.User) bool { return user.PendingApproval })).Return(nil).Once()
+
+	user, err := userService.CreateUser(context.Background(), "Jane", "jane@example.com", strongPassword, "")
+	assert.NoError(t, err)
+	assert.True(t, user.PendingApproval)
+	userRepo.AssertExpectations(t)
+}
+
+// TestGetUserByCredsPendingApproval tests that pending users cannot log in.
+func TestGetUserByCredsPendingApproval(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	userService := NewUserService(userRepo)
+
+	hash, err := utils.HashPassword(strongPassword)
+	require.NoError(t, err)
+	userRepo.On("GetUserByEmail", mock.Anything, "jane@example.com").
+		Return(&models.User{ID: 5, Email: "jane@example.com", Password: hash, PendingApproval: true}, nil)
+
+	_, err = userService.GetUserByCreds(context.Background(), "jane@example.com", strongPassword)
+	assert.Equal(t, KindForbidden, KindOf(err))
+	assert.Equal(t, "account_pending_approval", err.(*Error).Code)
+
+	// Strangers only learn that the password is wrong
+	_, err = userService.GetUserByCreds(context.Background(), "jane@example.com", "wrong")
+	assert.Equal(t, KindUnauthorized, KindOf(err))
+}
+
+func TestApproveAndRejectUser(t *testing.T) {
+	userRepo := new(mockUserRepo)
+	userService := NewUserService(userRepo)
+	audit, entries := recordedAudit()
+	userService.Audit = audit
+
+	userRepo.On("GetUserByID", mock.Anything, 5).Return(&models.User{ID: 5, Name: "Jane", Email: "jane@example.com", PendingApproval: true}, nil)
+	userRepo.On("GetUserByID", mock.Anything, 6).Return(&models.User{ID: 6, Name: "John", Email: "john@example.com", PendingApproval: true}, nil)
+	userRepo.On("GetUserByID", mock.Anything, 7).Return(&models.User{ID: 7, Name: "Joe", Email: "joe@example.com"}, nil)
+	userRepo.On("ApproveUser", mock.Anything, 5).Return(nil).Once()
+	userRepo.On("DeleteUser", mock.Anything, 6).Return(nil).Once()
+
+	user, err := userService.ApproveUser(context.Background(), 5)
+	assert.NoError(t, err)
+	assert.False(t, user.PendingApproval)
+
+	assert.NoError(t, userService.RejectUser(context.Background(), 6))
+
+	// Only pending users can be approved or rejected
+	_, err = userService.ApproveUser(context.Background(), 7)
+	assert.Equal(t, KindConflict, KindOf(err))
+	assert.Equal(t, KindConflict, KindOf(userService.RejectUser(context.Background(), 7)))
+
+	require.Len(t, *entries, 2)
+	assert.Equal(t, models.AuditUserApproved, (*entries)[0].Action)
+	assert.Equal(t, models.AuditUserRejected, (*entries)[1].Action)
+	userRepo.AssertExpectations(t)
+}
+
+func TestRegistrationService_CreateInvite(t *testing.T) {
+	inviteRepo := new(mockInviteRepo)
+	service := NewRegistrationService(inviteRepo, &Registration{Mode: models.RegistrationInviteOnly})
+
+	var stored *models.InviteCode
+	inviteRepo.On("CreateInvite", mock.Anything, mock.AnythingOfType("*models.InviteCode")).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*models.InviteCode) }).
+		Return(nil).Once()
+
+	invite, code, err := service.CreateInvite(context.Background(), 1, " for Jane ", nil)
+	require.NoError(t, err)
+	assert.True(t, len(code) > len(models.InviteCodePrefix))
+	assert.Equal(t, code[:len(invite.Prefix)], invite.Prefix)
+	assert.Equal(t, utils.HashToken(code), stored.CodeHash)
+	assert.Equal(t, "for Jane", invite.Note)
+	assert.Equal(t, 1, *invite.CreatedBy)
+
+	past := time.Now().Add(-time.Hour)
+	_, _, err = service.CreateInvite(context.Background(), 1, "", &past)
+	assert.Equal(t, KindValidation, KindOf(err))
+
+	assert.Equal(t, models.RegistrationInviteOnly, service.Mode())
+	assert.Equal(t, models.RegistrationOpen, NewRegistrationService(inviteRepo, nil).Mode())
+	inviteRepo.AssertExpectations(t)
+}
+
+func TestRegistrationService_DeleteInvite(t *testing.T) {
+	inviteRepo := new(mockInviteRepo)
+	service := NewRegistrationService(inviteRepo, nil)
+
+	inviteRepo.On("DeleteInvite", mock.Anything, 3).Return(repositories.ErrInviteNotFound)
+
+	err := service.DeleteInvite(context.Background(), 3)
+	assert.Equal(t, KindNotFound, KindOf(err))
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/mailer"
+)
+
+const (
+	// DefaultSnooze is how long reminders are snoozed for by default.
+	DefaultSnooze = 10 * time.Minute
+	// MaxSnooze is the longest a reminder can be snoozed for.
+	MaxSnooze = 7 * 24 * time.Hour
+	// MaxReminderOffset is the furthest before the due date of its todo a
+	// reminder can go off.
+	MaxReminderOffset = 30 * 24 * time.Hour
+	// MaxReminderLateness is how late a reminder can still go off, such as
+	// after an outage. Older reminders are dropped.
+	MaxReminderLateness = 24 * time.Hour
+	// reminderBatchSize is the number of due reminders claimed at once.
+	reminderBatchSize = 100
+)
+
+var errReminderNotFound = NewNotFoundError("reminder_not_found", "reminder not found")
+
+// ReminderDelivery is the payload of the job delivering a reminder on a
+// channel.
+type ReminderDelivery struct {
+	Channel  string             `json:"channel"`
+	UserID   int                `json:"user_id"`
+	Timezone string             `json:"timezone"`
+	Reminder models.DueReminder `json:"reminder"`
+}
+
+// Location returns the timezone of the user, UTC when it is unknown.
+func (d *ReminderDelivery) Location() *time.Location {
+	if loc, err := time.LoadLocation(d.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// ReminderChannel delivers reminders on a channel.
+type ReminderChannel interface {
+	SendReminder(ctx context.Context, delivery *ReminderDelivery) error
+}
+
+// ReminderService manages the reminders of todos and the notification
+// preferences of users, and sends the reminders that go off.
+type ReminderService struct {
+	Repo        repositories.ReminderRepoInterface
+	Preferences repositories.NotificationPreferenceRepoInterface
+	Jobs        JobEnqueuer
+	// Channels deliver reminders by channel name. Reminders are not sent
+	// on channels missing from it, such as push when Web Push is not
+	// configured.
+	Channels map[string]ReminderChannel
+
+	now func() time.Time
+}
+
+// NewReminderService initializes a new ReminderService.
+func NewReminderService(repo repositories.ReminderRepoInterface, prefs repositories.NotificationPreferenceRepoInterface, jobs JobEnqueuer) *ReminderService {
+	return &ReminderService{
+		Repo:        repo,
+		Preferences: prefs,
+		Jobs:        jobs,
+		Channels:    map[string]ReminderChannel{},
+		now:         time.Now,
+	}
+}
+
+// ListReminders retrieves the reminders of a todo of the user.
+func (s *ReminderService) ListReminders(ctx context.Context, userID int, todoID int) ([]models.Reminder, error) {
+	reminders, err := s.Repo.ListReminders(ctx, userID, todoID)
+	if err != nil {
+		return nil, reminderRepoError(err)
+	}
+	return reminders, nil
+}
+
+// CreateReminder adds a reminder to a todo of the user, going off either at
+// remindAt or beforeMinutes before the due date of the todo.
+func (s *ReminderService) CreateReminder(ctx context.Context, userID int, todoID int, remindAt *time.Time, beforeMinutes *int) (*models.Reminder, error) {
+	switch {
+	case (remindAt == nil) == (beforeMinutes == nil):
+		return nil, NewValidationError("invalid reminder",
+			FieldError{Field: "remind_at", Message: "exactly one of remind_at and before_minutes is required"})
+	case remindAt != nil && !remindAt.After(s.now()):
+		return nil, NewValidationError("invalid reminder", FieldError{Field: "remind_at", Message: "must be in the future"})
+	case beforeMinutes != nil && (*beforeMinutes < 0 || time.Duration(*beforeMinutes)*time.Minute > MaxReminderOffset):
+		return nil, NewValidationError("invalid reminder",
+			FieldError{Field: "before_minutes", Message: fmt.Sprintf("must be between 0 and %d", int(MaxReminderOffset/time.Minute))})
+	}
+
+	reminder := &models.Reminder{TodoID: todoID, UserID: userID, RemindAt: remindAt, BeforeMinutes: beforeMinutes}
+	if err := s.Repo.CreateReminder(ctx, reminder); err != nil {
+		return nil, reminderRepoError(err)
+	}
+	return reminder, nil
+}
+
+// DeleteReminder removes a reminder of a todo of the user.
+func (s *ReminderService) DeleteReminder(ctx context.Context, userID int, todoID int, id int) error {
+	return reminderRepoError(s.Repo.DeleteReminder(ctx, userID, todoID, id))
+}
+
+// SnoozeReminder makes a reminder of a todo of the user go off again after
+// d, or DefaultSnooze when d is zero.
+func (s *ReminderService) SnoozeReminder(ctx context.Context, userID int, todoID int, id int, d time.Duration) (*models.Reminder, error) {
+	if d == 0 {
+		d = DefaultSnooze
+	}
+	if d < time.Minute || d > MaxSnooze {
+		return nil, NewValidationError("invalid snooze",
+			FieldError{Field: "minutes", Message: fmt.Sprintf("must be between 1 and %d", int(MaxSnooze/time.Minute))})
+	}
+
+	reminder, err := s.Repo.SnoozeReminder(ctx, userID, todoID, id, s.now().Add(d).Truncate(time.Second))
+	if err != nil {
+		return nil, reminderRepoError(err)
+	}
+	return reminder, nil
+}
+
+// GetPreferences retrieves the notification preferences of the user.
+func (s *ReminderService) GetPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error) {
+	return s.Preferences.GetNotificationPreferences(ctx, userID)
+}
+
+// UpdatePreferences replaces the notification preferences of a user.
+func (s *ReminderService) UpdatePreferences(ctx context.Context, prefs *models.NotificationPreferences) (*models.NotificationPreferences, error) {
+	var fields []FieldError
+	for _, channel := range prefs.Channels {
+		if !containsString(models.NotificationChannels, channel) {
+			fields = append(fields, FieldError{Field: "channels", Message: fmt.Sprintf("unknown channel %q", channel)})
+		}
+	}
+	if prefs.Timezone == "" {
+		prefs.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(prefs.Timezone); err != nil {
+		fields = append(fields, FieldError{Field: "timezone", Message: "must be an IANA time zone such as Europe/Paris"})
+	}
+	_, startErr := parseClock(prefs.QuietHoursStart)
+	_, endErr := parseClock(prefs.QuietHoursEnd)
+	switch {
+	case startErr != nil:
+		fields = append(fields, FieldError{Field: "quiet_hours_start", Message: "must be a time such as 22:00"})
+	case endErr != nil:
+		fields = append(fields, FieldError{Field: "quiet_hours_end", Message: "must be a time such as 07:00"})
+	case (prefs.QuietHoursStart == "") != (prefs.QuietHoursEnd == ""):
+		fields = append(fields, FieldError{Field: "quiet_hours_end", Message: "quiet hours need both a start and an end"})
+	case prefs.QuietHoursStart != "" && prefs.QuietHoursStart == prefs.QuietHoursEnd:
+		fields = append(fields, FieldError{Field: "quiet_hours_end", Message: "must differ from quiet_hours_start"})
+	}
+	if prefs.DigestFrequency == "" {
+		prefs.DigestFrequency = models.DigestOff
+	}
+	if !containsString(models.DigestFrequencies, prefs.DigestFrequency) {
+		fields = append(fields, FieldError{Field: "digest_frequency", Message: "must be off, daily or weekly"})
+	}
+	if len(fields) > 0 {
+		return nil, NewValidationError("invalid notification preferences", fields...)
+	}
+
+	// No channels at all turns reminders off
+	prefs.Channels = append([]string{}, dedupeStrings(prefs.Channels)...)
+	if err := s.Preferences.SaveNotificationPreferences(ctx, prefs); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// SendDueReminders queues the delivery of the reminders that went off on
+// the channels their users chose. Reminders going off during the quiet
+// hours of their user wait for the end of the quiet hours. It returns the
+// number of reminders sent.
+func (s *ReminderService) SendDueReminders(ctx context.Context) (int, error) {
+	prefs := map[int]*models.NotificationPreferences{}
+	sent := 0
+	for {
+		now := s.now()
+		due, err := s.Repo.ClaimDueReminders(ctx, now.Add(-MaxReminderLateness), now, reminderBatchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, reminder := range due {
+			userPrefs, ok := prefs[reminder.UserID]
+			if !ok {
+				if userPrefs, err = s.Preferences.GetNotificationPreferences(ctx, reminder.UserID); err != nil {
+					return sent, err
+				}
+				prefs[reminder.UserID] = userPrefs
+			}
+
+			if until, quiet := quietHoursEnd(userPrefs, now); quiet {
+				if _, err := s.Repo.SnoozeReminder(ctx, reminder.UserID, reminder.TodoID, reminder.ReminderID, until); err != nil &&
+					!errors.Is(err, repositories.ErrReminderNotFound) {
+					return sent, err
+				}
+				continue
+			}
+			if err := s.enqueueDeliveries(ctx, userPrefs, reminder); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+
+		if len(due) < reminderBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// enqueueDeliveries queues a job delivering reminder on each channel the
+// user chose.
+func (s *ReminderService) enqueueDeliveries(ctx context.Context, prefs *models.NotificationPreferences, reminder models.DueReminder) error {
+	for _, channel := range prefs.Channels {
+		if _, ok := s.Channels[channel]; !ok {
+			continue
+		}
+		delivery := ReminderDelivery{Channel: channel, UserID: reminder.UserID, Timezone: prefs.Timezone, Reminder: reminder}
+		key := fmt.Sprintf("reminder:%d@%s:%s", reminder.ReminderID, reminder.FireAt.UTC().Format(time.RFC3339), channel)
+		_, err := s.Jobs.Enqueue(ctx, models.JobDeliverReminder, delivery, JobOptions{UniqueKey: key})
+		if err != nil && !errors.Is(err, repositories.ErrDuplicateJob) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverReminder delivers a reminder on its channel.
+func (s *ReminderService) DeliverReminder(ctx context.Context, delivery *ReminderDelivery) error {
+	channel, ok := s.Channels[delivery.Channel]
+	if !ok {
+		return PermanentJobError(fmt.Errorf("unknown reminder channel %q", delivery.Channel))
+	}
+	return channel.SendReminder(ctx, delivery)
+}
+
+// quietHoursEnd reports whether t falls in the quiet hours of prefs, and if
+// so when they end.
+func quietHoursEnd(prefs *models.NotificationPreferences, t time.Time) (time.Time, bool) {
+	start, err := parseClock(prefs.QuietHoursStart)
+	if err != nil || start < 0 {
+		return time.Time{}, false
+	}
+	end, err := parseClock(prefs.QuietHoursEnd)
+	if err != nil || end < 0 {
+		return time.Time{}, false
+	}
+
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	quiet := minute >= start && minute < end
+	if start > end {
+		// The quiet hours span midnight
+		quiet = minute >= start || minute < end
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, loc)
+	if !until.After(local) {
+		until = time.Date(local.Year(), local.Month(), local.Day()+1, end/60, end%60, 0, 0, loc)
+	}
+	return until, true
+}
+
+// parseClock parses an "HH:MM" time into minutes after midnight. The empty
+// time is -1.
+func parseClock(clock string) (int, error) {
+	if clock == "" {
+		return -1, nil
+	}
+	hh, mm, ok := strings.Cut(clock, ":")
+	if !ok || len(hh) != 2 || len(mm) != 2 {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	hours, err := strconv.Atoi(hh)
+	if err != nil || hours < 0 || hours > 23 {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	minutes, err := strconv.Atoi(mm)
+	if err != nil || minutes < 0 || minutes > 59 {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	return hours*60 + minutes, nil
+}
+
+// EmailReminderChannel emails reminders to their user.
+type EmailReminderChannel struct {
+	Users  repositories.UserRepoInterface
+	Mailer mailer.Mailer
+	// BaseURL is the frontend URL that links in emails point to.
+	BaseURL string
+}
+
+// SendReminder emails the reminder of a delivery.
+func (c *EmailReminderChannel) SendReminder(ctx context.Context, delivery *ReminderDelivery) error {
+	user, err := c.Users.GetUserByID(ctx, delivery.UserID)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !user.IsActive() {
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"Name":  user.Name,
+		"Title": delivery.Reminder.Title,
+		"Link":  c.BaseURL,
+	}
+	if due := delivery.Reminder.DueDate; due != nil {
+		data["Due"] = due.In(delivery.Location()).Format("Mon Jan 2, 2006 at 15:04 MST")
+	}
+	msg, err := mailer.NewMessage(user.Email, "reminder", data)
+	if err != nil {
+		return PermanentJobError(err)
+	}
+	return c.Mailer.Send(ctx, msg)
+}
+
+// EventReminderChannel publishes reminders as todo.reminder events, to the
+// live clients of their user or to their webhooks depending on Events.
+type EventReminderChannel struct {
+	Events EventPublisher
+}
+
+// SendReminder publishes the reminder of a delivery.
+func (c *EventReminderChannel) SendReminder(ctx context.Context, delivery *ReminderDelivery) error {
+	data, err := json.Marshal(delivery.Reminder)
+	if err != nil {
+		return PermanentJobError(err)
+	}
+	return c.Events.Publish(ctx, &models.Event{Type: models.EventTodoReminder, UserID: delivery.UserID, Data: data})
+}
+
+func reminderRepoError(err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrReminderNotFound):
+		return errReminderNotFound
+	case errors.Is(err, repositories.ErrTodoNotFound):
+		return NewNotFoundError("todo_not_found", "todo not found")
+	}
+	return err
+}
+
+var _ ReminderServiceInterface = (*ReminderService)(nil)
+var _ ReminderChannel = (*EmailReminderChannel)(nil)
+var _ ReminderChannel = (*EventReminderChannel)(nil)
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/eventbus"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockReminderRepo is a mock implementation of repositories.ReminderRepoInterface.
+type mockReminderRepo struct {
+	mock.Mock
+}
+
+func (m *mockReminderRepo) CreateReminder(ctx context.Context, reminder *models.Reminder) error {
+	args := m.Called(ctx, reminder)
+	return args.Error(0)
+}
+
+func (m *mockReminderRepo) ListReminders(ctx context.Context, userID int, todoID int) ([]models.Reminder, error) {
+	args := m.Called(ctx, userID, todoID)
+	return args.Get(0).([]models.Reminder), args.Error(1)
+}
+
+func (m *mockReminderRepo) DeleteReminder(ctx context.Context, userID int, todoID int, id int) error {
+	args := m.Called(ctx, userID, todoID, id)
+	return args.Error(0)
+}
+
+func (m *mockReminderRepo) SnoozeReminder(ctx context.Context, userID int, todoID int, id int, until time.Time) (*models.Reminder, error) {
+	args := m.Called(ctx, userID, todoID, id, until)
+	reminder, _ := args.Get(0).(*models.Reminder)
+	return reminder, args.Error(1)
+}
+
+func (m *mockReminderRepo) ClaimDueReminders(ctx context.Context, since, now time.Time, limit int) ([]models.DueReminder, error) {
+	args := m.Called(ctx, since, now, limit)
+	return args.Get(0).([]models.DueReminder), args.Error(1)
+}
+
+// mockPreferenceRepo is a mock implementation of
+// repositories.NotificationPreferenceRepoInterface.
+type mockPreferenceRepo struct {
+	mock.Mock
+}
+
+func (m *mockPreferenceRepo) GetNotificationPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error) {
+	args := m.Called(ctx, userID)
+	prefs, _ := args.Get(0).(*models.NotificationPreferences)
+	return prefs, args.Error(1)
+}
+
+func (m *mockPreferenceRepo) SaveNotificationPreferences(ctx context.Context, prefs *models.NotificationPreferences) error {
+	args := m.Called(ctx, prefs)
+	return args.Error(0)
+}
+
+func (m *mockPreferenceRepo) SetDigestFrequency(ctx context.Context, userID int, frequency string) error {
+	args := m.Called(ctx, userID, frequency)
+	return args.Error(0)
+}
+
+func (m *mockPreferenceRepo) ClaimDueDigests(ctx context.Context, now time.Time, hour int, limit int) ([]models.DigestRecipient, error) {
+	args := m.Called(ctx, now, hour, limit)
+	return args.Get(0).([]models.DigestRecipient), args.Error(1)
+}
+
+// channelFunc is a ReminderChannel calling a function.
+type channelFunc func(ctx context.Context, delivery *ReminderDelivery) error
+
+func (f channelFunc) SendReminder(ctx context.Context, delivery *ReminderDelivery) error {
+	return f(ctx, delivery)
+}
+
+func TestReminderService_CreateReminder(t *testing.T) {
+	repo := new(mockReminderRepo)
+	service := NewReminderService(repo, new(mockPreferenceRepo), new(mockJobEnqueuer))
+	now := timeAt(9, 0)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	negative, tooFar, ok := -5, 31*24*60, 30
+	for _, tt := range []struct {
+		name          string
+		remindAt      *time.Time
+		beforeMinutes *int
+		field         string
+	}{
+		{"neither", nil, nil, "remind_at"},
+		{"both", &future, &ok, "remind_at"},
+		{"past", &past, nil, "remind_at"},
+		{"negative offset", nil, &negative, "before_minutes"},
+		{"offset too far", nil, &tooFar, "before_minutes"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateReminder(ctx, 1, 3, tt.remindAt, tt.beforeMinutes)
+			var serviceErr *Error
+			require.ErrorAs(t, err, &serviceErr)
+			require.Len(t, serviceErr.Fields, 1)
+			assert.Equal(t, tt.field, serviceErr.Fields[0].Field)
+		})
+	}
+
+	repo.On("CreateReminder", ctx, mock.MatchedBy(func(r *models.Reminder) bool { return r.TodoID == 3 })).Return(nil).Once()
+	reminder, err := service.CreateReminder(ctx, 1, 3, nil, &ok)
+	require.NoError(t, err)
+	assert.Equal(t, 30, *reminder.BeforeMinutes)
+
+	repo.On("CreateReminder", ctx, mock.Anything).Return(repositories.ErrTodoNotFound).Once()
+	_, err = service.CreateReminder(ctx, 1, 4, &future, nil)
+	assert.Equal(t, KindNotFound, KindOf(err))
+}
+
+func TestReminderService_SnoozeReminder(t *testing.T) {
+	repo := new(mockReminderRepo)
+	service := NewReminderService(repo, new(mockPreferenceRepo), new(mockJobEnqueuer))
+	now := timeAt(9, 0)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	repo.On("SnoozeReminder", ctx, 1, 3, 7, now.Add(DefaultSnooze)).Return(&models.Reminder{ID: 7}, nil)
+	_, err := service.SnoozeReminder(ctx, 1, 3, 7, 0)
+	assert.NoError(t, err)
+
+	_, err = service.SnoozeReminder(ctx, 1, 3, 7, 8*24*time.Hour)
+	assert.Equal(t, KindValidation, KindOf(err))
+
+	repo.On("SnoozeReminder", ctx, 1, 3, 8, now.Add(time.Hour)).Return(nil, repositories.ErrReminderNotFound)
+	_, err = service.SnoozeReminder(ctx, 1, 3, 8, time.Hour)
+	var serviceErr *Error
+	require.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, "reminder_not_found", serviceErr.Code)
+}
+
+func TestReminderService_UpdatePreferences(t *testing.T) {
+	prefsRepo := new(mockPreferenceRepo)
+	service := NewReminderService(new(mockReminderRepo), prefsRepo, new(mockJobEnqueuer))
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name  string
+		prefs models.NotificationPreferences
+		field string
+	}{
+		{"unknown channel", models.NotificationPreferences{Channels: []string{"sms"}}, "channels"},
+		{"unknown timezone", models.NotificationPreferences{Timezone: "Mars/Olympus"}, "timezone"},
+		{"invalid start", models.NotificationPreferences{QuietHoursStart: "25:00", QuietHoursEnd: "07:00"}, "quiet_hours_start"},
+		{"missing end", models.NotificationPreferences{QuietHoursStart: "22:00"}, "quiet_hours_end"},
+		{"empty quiet hours", models.NotificationPreferences{QuietHoursStart: "22:00", QuietHoursEnd: "22:00"}, "quiet_hours_end"},
+		{"unknown digest frequency", models.NotificationPreferences{DigestFrequency: "hourly"}, "digest_frequency"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.UpdatePreferences(ctx, &tt.prefs)
+			var serviceErr *Error
+			require.ErrorAs(t, err, &serviceErr)
+			require.Len(t, serviceErr.Fields, 1)
+			assert.Equal(t, tt.field, serviceErr.Fields[0].Field)
+		})
+	}
+
+	prefsRepo.On("SaveNotificationPreferences", ctx, mock.Anything).Return(nil)
+	prefs, err := service.UpdatePreferences(ctx, &models.NotificationPreferences{
+		UserID: 1, Channels: []string{"push", "push", "email"}, QuietHoursStart: "22:00", QuietHoursEnd: "07:00",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"push", "email"}, prefs.Channels)
+	assert.Equal(t, "UTC", prefs.Timezone)
+	assert.Equal(t, models.DigestOff, prefs.DigestFrequency)
+
+	// Turning every channel off is stored as an empty list
+	prefs, err = service.UpdatePreferences(ctx, &models.NotificationPreferences{UserID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, prefs.Channels)
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	overnight := &models.NotificationPreferences{Timezone: "Europe/Paris", QuietHoursStart: "22:00", QuietHoursEnd: "07:00"}
+	daytime := &models.NotificationPreferences{Timezone: "UTC", QuietHoursStart: "12:00", QuietHoursEnd: "14:00"}
+	for _, tt := range []struct {
+		name  string
+		prefs *models.NotificationPreferences
+		at    time.Time
+		until time.Time
+	}{
+		{"before overnight quiet hours", overnight, time.Date(2024, 5, 15, 21, 59, 0, 0, paris), time.Time{}},
+		{"evening", overnight, time.Date(2024, 5, 15, 23, 30, 0, 0, paris), time.Date(2024, 5, 16, 7, 0, 0, 0, paris)},
+		{"after midnight", overnight, time.Date(2024, 5, 16, 1, 0, 0, 0, paris), time.Date(2024, 5, 16, 7, 0, 0, 0, paris)},
+		{"in utc", overnight, time.Date(2024, 5, 15, 20, 30, 0, 0, time.UTC), time.Date(2024, 5, 16, 7, 0, 0, 0, paris)},
+		{"end of quiet hours", overnight, time.Date(2024, 5, 16, 7, 0, 0, 0, paris), time.Time{}},
+		{"daytime", daytime, timeAt(13, 0), timeAt(14, 0)},
+		{"after daytime quiet hours", daytime, timeAt(14, 0), time.Time{}},
+		{"no quiet hours", &models.NotificationPreferences{Timezone: "UTC"}, timeAt(13, 0), time.Time{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := quietHoursEnd(tt.prefs, tt.at)
+			assert.Equal(t, !tt.until.IsZero(), quiet)
+			if quiet {
+				assert.True(t, tt.until.Equal(until), "quiet until %v, want %v", until, tt.until)
+			}
+		})
+	}
+}
+
+func TestReminderService_SendDueReminders(t *testing.T) {
+	repo, prefsRepo, jobs := new(mockReminderRepo), new(mockPreferenceRepo), new(mockJobEnqueuer)
+	service := NewReminderService(repo, prefsRepo, jobs)
+	service.Channels[models.ChannelEmail] = channelFunc(nil)
+	service.Channels[models.ChannelInApp] = channelFunc(nil)
+	now := timeAt(13, 0)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	due := []models.DueReminder{
+		{ReminderID: 7, TodoID: 3, UserID: 1, Title: "Pay rent", FireAt: now},
+		{ReminderID: 8, TodoID: 4, UserID: 2, Title: "Call mom", FireAt: now},
+		{ReminderID: 9, TodoID: 5, UserID: 1, Title: "Water plants", FireAt: now},
+	}
+	repo.On("ClaimDueReminders", ctx, now.Add(-MaxReminderLateness), now, reminderBatchSize).Return(due, nil)
+	// Push is not configured, so user 1 only gets emails
+	prefsRepo.On("GetNotificationPreferences", ctx, 1).
+		Return(&models.NotificationPreferences{UserID: 1, Channels: []string{"push", "email"}, Timezone: "UTC"}, nil).Once()
+	prefsRepo.On("GetNotificationPreferences", ctx, 2).
+		Return(&models.NotificationPreferences{UserID: 2, Channels: []string{"in_app"}, Timezone: "UTC", QuietHoursStart: "12:00", QuietHoursEnd: "14:00"}, nil).Once()
+	jobs.On("Enqueue", ctx, models.JobDeliverReminder, mock.Anything, mock.Anything).Return(nil)
+	repo.On("SnoozeReminder", ctx, 2, 4, 8, timeAt(14, 0)).Return(&models.Reminder{ID: 8}, nil)
+
+	sent, err := service.SendDueReminders(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	// User 2 is in quiet hours, so their reminder waits for them to end
+	repo.AssertCalled(t, "SnoozeReminder", ctx, 2, 4, 8, timeAt(14, 0))
+	jobs.AssertNumberOfCalls(t, "Enqueue", 2)
+	jobs.AssertCalled(t, "Enqueue", ctx, models.JobDeliverReminder,
+		ReminderDelivery{Channel: models.ChannelEmail, UserID: 1, Timezone: "UTC", Reminder: due[0]},
+		JobOptions{UniqueKey: "reminder:7@2024-05-15T13:00:00Z:email"})
+}
+
+func TestReminderService_DeliverReminder(t *testing.T) {
+	service := NewReminderService(new(mockReminderRepo), new(mockPreferenceRepo), new(mockJobEnqueuer))
+	bus := eventbus.NewMemory()
+	var events []models.Event
+	bus.Subscribe(func(event models.Event) { events = append(events, event) })
+	service.Channels[models.ChannelInApp] = &EventReminderChannel{Events: bus}
+
+	delivery := &ReminderDelivery{Channel: models.ChannelInApp, UserID: 1, Reminder: models.DueReminder{ReminderID: 7, TodoID: 3, Title: "Pay rent"}}
+	require.NoError(t, service.DeliverReminder(context.Background(), delivery))
+	require.Len(t, events, 1)
+	assert.Equal(t, models.EventTodoReminder, events[0].Type)
+	assert.Equal(t, 1, events[0].UserID)
+	assert.Contains(t, string(events[0].Data), `"title":"Pay rent"`)
+
+	// Jobs for channels that are no longer configured are not retried
+	delivery.Channel = models.ChannelPush
+	var permanent *permanentJobError
+	assert.ErrorAs(t, service.DeliverReminder(context.Background(), delivery), &permanent)
+}
+
+func TestEmailReminderChannel(t *testing.T) {
+	users := new(mockUserRepo)
+	sent := make(chanMailer, 1)
+	channel := &EmailReminderChannel{Users: users, Mailer: sent, BaseURL: "http://app.test"}
+
+	due := time.Date(2024, 5, 15, 16, 0, 0, 0, time.UTC)
+	users.On("GetUserByID", mock.Anything, 1).Return(&models.User{ID: 1, Name: "John", Email: "john@example.com"}, nil)
+	err := channel.SendReminder(context.Background(), &ReminderDelivery{
+		Channel: models.ChannelEmail, UserID: 1, Timezone: "Europe/Paris",
+		Reminder: models.DueReminder{ReminderID: 7, TodoID: 3, Title: "Pay rent", DueDate: &due},
+	})
+	require.NoError(t, err)
+
+	msg := receive(t, sent)
+	assert.Equal(t, []string{"john@example.com"}, msg.To)
+	assert.Equal(t, "Reminder: Pay rent", msg.Subject)
+	// Due dates are shown in the timezone of the user
+	assert.Contains(t, msg.Text, "It is due Wed May 15, 2024 at 18:00 CEST.")
+	assert.Contains(t, msg.Text, "http://app.test")
+}
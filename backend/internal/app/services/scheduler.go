@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/cron"
+)
+
+// Catch-up policies, deciding what becomes of the runs missed while no
+// instance was the scheduler leader.
+const (
+	// CatchUpSkip drops missed runs.
+	CatchUpSkip = "skip"
+	// CatchUpOnce makes up for missed runs with a single run.
+	CatchUpOnce = "once"
+	// CatchUpAll makes up for each missed run, up to MaxCatchUpRuns.
+	CatchUpAll = "all"
+)
+
+const (
+	// SchedulerLockKey is the advisory lock held by the scheduler leader.
+	SchedulerLockKey int64 = 0x7363686564756c65
+	// MaxCatchUpRuns caps the missed runs of a schedule made up for at once.
+	MaxCatchUpRuns = 100
+)
+
+// scheduledTask is a periodic task of the scheduler.
+type scheduledTask struct {
+	name     string
+	spec     string
+	jobType  string
+	schedule *cron.Schedule
+}
+
+// Scheduler queues jobs at the times given by cron expressions. Every
+// instance runs one, and the one holding the scheduler advisory lock leads:
+// only the leader queues jobs, so each run is queued once whatever the
+// number of instances. The runs of each schedule are stored, so that a new
+// leader catches up on the runs missed in between according to CatchUp.
+type Scheduler struct {
+	Repo repositories.ScheduleRepoInterface
+	Jobs JobEnqueuer
+	// CatchUp is the catch-up policy. Unknown policies behave like
+	// CatchUpOnce, the default.
+	CatchUp string
+	// Interval is how often the leader looks for due runs, and the other
+	// instances try to take over the lead.
+	Interval time.Duration
+	// Runs are missed when they are due for longer than MissedAfter.
+	MissedAfter time.Duration
+
+	tasks []scheduledTask
+	now   func() time.Time
+}
+
+// NewScheduler initializes a new Scheduler.
+func NewScheduler(repo repositories.ScheduleRepoInterface, jobs JobEnqueuer) *Scheduler {
+	return &Scheduler{
+		Repo:        repo,
+		Jobs:        jobs,
+		CatchUp:     CatchUpOnce,
+		Interval:    10 * time.Second,
+		MissedAfter: time.Minute,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Add schedules a job of jobType, with a models.ScheduledRun payload, each
+// time the cron expression spec fires in UTC. Schedules must be added
+// before Run is called.
+func (s *Scheduler) Add(name, spec, jobType string) error {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return err
+	}
+	s.tasks = append(s.tasks, scheduledTask{name: name, spec: spec, jobType: jobType, schedule: schedule})
+	return nil
+}
+
+// Run leads the scheduler whenever it can until ctx is cancelled, and then
+// gives up the lead.
+func (s *Scheduler) Run(ctx context.Context) {
+	var lock repositories.AdvisoryLock
+	defer func() {
+		if lock != nil {
+			release, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := lock.Release(release); err != nil {
+				log.Printf("Failed to give up the scheduler lead: %v", err)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		if lock = s.lead(ctx, lock); lock != nil {
+			if err := s.tick(ctx, s.now()); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to run schedules: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead returns the lock of the scheduler leader when this instance leads
+// or takes over the lead, and nil when another instance leads.
+func (s *Scheduler) lead(ctx context.Context, lock repositories.AdvisoryLock) repositories.AdvisoryLock {
+	if lock != nil {
+		err := lock.Check(ctx)
+		if err == nil {
+			return lock
+		}
+		log.Printf("Lost the scheduler lead: %v", err)
+		lock.Release(ctx)
+	}
+
+	lock, err := s.Repo.TryAdvisoryLock(ctx, SchedulerLockKey)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to take the scheduler lead: %v", err)
+		}
+		return nil
+	}
+	if lock != nil {
+		log.Printf("Took the scheduler lead")
+	}
+	return lock
+}
+
+// tick queues the runs that are due at now.
+func (s *Scheduler) tick(ctx context.Context, now time.Time) error {
+	stored, err := s.storedSchedules(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range s.tasks {
+		state, ok := stored[task.name]
+		if !ok || state.Spec != task.spec {
+			// New and changed schedules start at their next run
+			state = models.Schedule{Name: task.name, Spec: task.spec, LastRunAt: state.LastRunAt, NextRunAt: nextRun(task, now)}
+			if err := s.Repo.SaveSchedule(ctx, &state); err != nil {
+				return err
+			}
+			continue
+		}
+		if state.NextRunAt == nil || state.NextRunAt.After(now) {
+			continue
+		}
+
+		due := s.dueRuns(task, *state.NextRunAt, now)
+		for _, at := range due {
+			payload := models.ScheduledRun{Schedule: task.name, ScheduledAt: at}
+			opts := JobOptions{UniqueKey: task.name + "@" + at.UTC().Format(time.RFC3339)}
+			if _, err := s.Jobs.Enqueue(ctx, task.jobType, payload, opts); err != nil && !errors.Is(err, repositories.ErrDuplicateJob) {
+				// The runs are queued again on the next tick
+				return err
+			}
+			at := at
+			state.LastRunAt = &at
+		}
+		state.NextRunAt = nextRun(task, now)
+		if err := s.Repo.SaveSchedule(ctx, &state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dueRuns returns the runs of task from next up to now that are queued
+// under the catch-up policy.
+func (s *Scheduler) dueRuns(task scheduledTask, next, now time.Time) []time.Time {
+	var due []time.Time
+	missed := 0
+	for at := next.UTC(); !at.IsZero() && !at.After(now); at = task.schedule.Next(at) {
+		if now.Sub(at) <= s.MissedAfter {
+			due = append(due, at)
+			continue
+		}
+
+		missed++
+		switch s.CatchUp {
+		case CatchUpAll:
+			due = append(due, at)
+			if len(due) > MaxCatchUpRuns {
+				due = due[1:]
+			}
+		case CatchUpSkip:
+		default:
+			due = append(due[:0], at)
+		}
+	}
+
+	if missed > 0 {
+		log.Printf("Schedule %s missed %d runs, catching up with policy %q", task.name, missed, s.CatchUp)
+	}
+	if s.CatchUp != CatchUpAll && len(due) > 1 {
+		due = due[len(due)-1:]
+	}
+	return due
+}
+
+// ListSchedules retrieves the schedules with their last and next runs.
+func (s *Scheduler) ListSchedules(ctx context.Context) ([]models.Schedule, error) {
+	stored, err := s.storedSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]models.Schedule, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		schedule := models.Schedule{Name: task.name, Spec: task.spec, JobType: task.jobType}
+		state, ok := stored[task.name]
+		schedule.LastRunAt = state.LastRunAt
+		if ok && state.Spec == task.spec {
+			schedule.NextRunAt = state.NextRunAt
+		} else {
+			// Not yet picked up by the leader
+			schedule.NextRunAt = nextRun(task, s.now())
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+// storedSchedules retrieves the stored runs of the schedules by name.
+func (s *Scheduler) storedSchedules(ctx context.Context) (map[string]models.Schedule, error) {
+	schedules, err := s.Repo.ListSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]models.Schedule, len(schedules))
+	for _, schedule := range schedules {
+		stored[schedule.Name] = schedule
+	}
+	return stored, nil
+}
+
+// nextRun returns the first run of task after t, or nil when there is none.
+func nextRun(task scheduledTask, t time.Time) *time.Time {
+	next := task.schedule.Next(t)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+var _ SchedulerServiceInterface = (*Scheduler)(nil)
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockScheduleRepo is a mock implementation of repositories.ScheduleRepoInterface.
+type mockScheduleRepo struct {
+	mock.Mock
+}
+
+func (m *mockScheduleRepo) ListSchedules(ctx context.Context) ([]models.Schedule, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Schedule), args.Error(1)
+}
+
+func (m *mockScheduleRepo) SaveSchedule(ctx context.Context, schedule *models.Schedule) error {
+	args := m.Called(ctx, schedule)
+	return args.Error(0)
+}
+
+func (m *mockScheduleRepo) TryAdvisoryLock(ctx context.Context, key int64) (repositories.AdvisoryLock, error) {
+	args := m.Called(ctx, key)
+	lock, _ := args.Get(0).(repositories.AdvisoryLock)
+	return lock, args.Error(1)
+}
+
+// mockAdvisoryLock is a mock implementation of repositories.AdvisoryLock.
+type mockAdvisoryLock struct {
+	mock.Mock
+}
+
+func (m *mockAdvisoryLock) Check(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockAdvisoryLock) Release(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+// mockJobEnqueuer is a mock implementation of JobEnqueuer.
+type mockJobEnqueuer struct {
+	mock.Mock
+}
+
+func (m *mockJobEnqueuer) Enqueue(ctx context.Context, jobType string, payload interface{}, opts JobOptions) (*models.Job, error) {
+	args := m.Called(ctx, jobType, payload, opts)
+	return &models.Job{Type: jobType}, args.Error(0)
+}
+
+func timeAt(hour, minute int) time.Time {
+	return time.Date(2024, 5, 15, hour, minute, 0, 0, time.UTC)
+}
+
+func TestScheduler_NewSchedule(t *testing.T) {
+	repo, jobs := new(mockScheduleRepo), new(mockJobEnqueuer)
+	scheduler := NewScheduler(repo, jobs)
+	require.NoError(t, scheduler.Add("purge", "@hourly", models.JobPurgeDeletedAccounts))
+
+	// A changed expression is treated as a new schedule
+	last := timeAt(9, 0)
+	repo.On("ListSchedules", mock.Anything).
+		Return([]models.Schedule{{Name: "purge", Spec: "*/5 * * * *", LastRunAt: &last, NextRunAt: &last}}, nil)
+	repo.On("SaveSchedule", mock.Anything, mock.MatchedBy(func(schedule *models.Schedule) bool {
+		return schedule.Spec == "@hourly" && *schedule.LastRunAt == last && *schedule.NextRunAt == timeAt(11, 0)
+	})).Return(nil)
+
+	assert.NoError(t, scheduler.tick(context.Background(), timeAt(10, 30)))
+	repo.AssertExpectations(t)
+	jobs.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestScheduler_DueRun(t *testing.T) {
+	repo, jobs := new(mockScheduleRepo), new(mockJobEnqueuer)
+	scheduler := NewScheduler(repo, jobs)
+	require.NoError(t, scheduler.Add("purge", "@hourly", models.JobPurgeDeletedAccounts))
+
+	next := timeAt(10, 0)
+	repo.On("ListSchedules", mock.Anything).Return([]models.Schedule{{Name: "purge", Spec: "@hourly", NextRunAt: &next}}, nil)
+	jobs.On("Enqueue", mock.Anything, models.JobPurgeDeletedAccounts,
+		models.ScheduledRun{Schedule: "purge", ScheduledAt: next}, JobOptions{UniqueKey: "purge@2024-05-15T10:00:00Z"}).
+		Return(repositories.ErrDuplicateJob)
+	repo.On("SaveSchedule", mock.Anything, mock.MatchedBy(func(schedule *models.Schedule) bool {
+		return *schedule.LastRunAt == next && *schedule.NextRunAt == timeAt(11, 0)
+	})).Return(nil)
+
+	assert.NoError(t, scheduler.tick(context.Background(), timeAt(10, 0).Add(5*time.Second)))
+	repo.AssertExpectations(t)
+	jobs.AssertExpectations(t)
+}
+
+func TestScheduler_CatchUp(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expected []time.Time
+	}{
+		{CatchUpSkip, nil},
+		{CatchUpOnce, []time.Time{timeAt(10, 0)}},
+		{CatchUpAll, []time.Time{timeAt(5, 0), timeAt(6, 0), timeAt(7, 0), timeAt(8, 0), timeAt(9, 0), timeAt(10, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			repo, jobs := new(mockScheduleRepo), new(mockJobEnqueuer)
+			scheduler := NewScheduler(repo, jobs)
+			scheduler.CatchUp = tt.policy
+			require.NoError(t, scheduler.Add("purge", "@hourly", models.JobPurgeDeletedAccounts))
+
+			// No leader since 5 o'clock
+			next := timeAt(5, 0)
+			repo.On("ListSchedules", mock.Anything).Return([]models.Schedule{{Name: "purge", Spec: "@hourly", NextRunAt: &next}}, nil)
+			var queued []time.Time
+			jobs.On("Enqueue", mock.Anything, models.JobPurgeDeletedAccounts, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					queued = append(queued, args.Get(2).(models.ScheduledRun).ScheduledAt)
+				}).Return(nil)
+			repo.On("SaveSchedule", mock.Anything, mock.MatchedBy(func(schedule *models.Schedule) bool {
+				return *schedule.NextRunAt == timeAt(11, 0)
+			})).Return(nil)
+
+			assert.NoError(t, scheduler.tick(context.Background(), timeAt(10, 30)))
+			assert.Equal(t, tt.expected, queued)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestScheduler_Leadership(t *testing.T) {
+	repo, jobs := new(mockScheduleRepo), new(mockJobEnqueuer)
+	scheduler := NewScheduler(repo, jobs)
+	scheduler.Interval = 5 * time.Millisecond
+
+	// Another instance leads at first
+	lock := new(mockAdvisoryLock)
+	repo.On("TryAdvisoryLock", mock.Anything, SchedulerLockKey).Return(nil, nil).Once()
+	repo.On("TryAdvisoryLock", mock.Anything, SchedulerLockKey).Return(lock, nil)
+	lock.On("Check", mock.Anything).Return(nil)
+	lock.On("Release", mock.Anything).Return(nil)
+
+	ticked := make(chan struct{}, 1)
+	repo.On("ListSchedules", mock.Anything).Run(func(mock.Arguments) {
+		select {
+		case ticked <- struct{}{}:
+		default:
+		}
+	}).Return([]models.Schedule{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx)
+		close(done)
+	}()
+	<-ticked
+	cancel()
+	<-done
+
+	repo.AssertNumberOfCalls(t, "TryAdvisoryLock", 2)
+	lock.AssertCalled(t, "Release", mock.Anything)
+}
+
+func TestScheduler_ListSchedules(t *testing.T) {
+	repo, jobs := new(mockScheduleRepo), new(mockJobEnqueuer)
+	scheduler := NewScheduler(repo, jobs)
+	scheduler.now = func() time.Time { return timeAt(10, 30) }
+	require.NoError(t, scheduler.Add("purge", "@hourly", models.JobPurgeDeletedAccounts))
+	require.NoError(t, scheduler.Add("digest", "0 7 * * *", "digest.send"))
+
+	last, next := timeAt(10, 0), timeAt(11, 0)
+	repo.On("ListSchedules", mock.Anything).
+		Return([]models.Schedule{{Name: "purge", Spec: "@hourly", LastRunAt: &last, NextRunAt: &next}}, nil)
+
+	schedules, err := scheduler.ListSchedules(context.Background())
+	require.NoError(t, err)
+	require.Len(t, schedules, 2)
+	assert.Equal(t, models.Schedule{Name: "purge", Spec: "@hourly", JobType: models.JobPurgeDeletedAccounts, LastRunAt: &last, NextRunAt: &next}, schedules[0])
+	// Not run yet
+	assert.Nil(t, schedules[1].LastRunAt)
+	assert.Equal(t, time.Date(2024, 5, 16, 7, 0, 0, 0, time.UTC), *schedules[1].NextRunAt)
+}
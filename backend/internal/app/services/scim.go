@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/scim"
+)
+
+const (
+	// SCIMDefaultCount is the page size of user listings when the client
+	// does not ask for one.
+	SCIMDefaultCount = 100
+	// SCIMMaxCount caps the page size of user listings.
+	SCIMMaxCount = 500
+)
+
+// SCIM error codes. The SCIM handler reports them as the matching scimType.
+const (
+	SCIMInvalidFilter = "invalid_filter"
+	SCIMInvalidValue  = "invalid_value"
+	SCIMInvalidPath   = "invalid_path"
+	SCIMInvalidSyntax = "invalid_syntax"
+	SCIMMutability    = "mutability"
+)
+
+func newSCIMError(code, format string, args ...interface{}) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// SCIMService provisions users from an identity provider over SCIM.
+// Deprovisioned users are deactivated rather than deleted, so that their
+// todos survive until an admin removes the account.
+type SCIMService struct {
+	UserRepo repositories.UserRepoInterface
+	Sessions SessionServiceInterface
+}
+
+// NewSCIMService initializes a new SCIMService.
+func NewSCIMService(userRepo repositories.UserRepoInterface, sessions SessionServiceInterface) *SCIMService {
+	return &SCIMService{UserRepo: userRepo, Sessions: sessions}
+}
+
+// ListUsers returns the users matching filter, starting at the 1-based
+// startIndex. A negative count selects SCIMDefaultCount.
+func (s *SCIMService) ListUsers(ctx context.Context, filter string, startIndex, count int) (*scim.ListResponse, error) {
+	var parsed scim.Filter
+	if strings.TrimSpace(filter) != "" {
+		var err error
+		if parsed, err = scim.ParseFilter(filter); err != nil {
+			return nil, newSCIMError(SCIMInvalidFilter, "%s", err)
+		}
+	}
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = SCIMDefaultCount
+	} else if count > SCIMMaxCount {
+		count = SCIMMaxCount
+	}
+
+	users, total, err := s.UserRepo.SearchUsers(ctx, parsed, startIndex-1, count)
+	if errors.Is(err, repositories.ErrUnsupportedFilter) {
+		return nil, newSCIMError(SCIMInvalidFilter, "%s", err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	resources := make([]scim.User, 0, len(users))
+	for i := range users {
+		resources = append(resources, *toSCIMUser(&users[i]))
+	}
+	return &scim.ListResponse{
+		Schemas:      []string{scim.ListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+// GetUser returns the user with the given ID.
+func (s *SCIMService) GetUser(ctx context.Context, id int) (*scim.User, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, userRepoError(err)
+	}
+	return toSCIMUser(user), nil
+}
+
+// CreateUser provisions a new user. Users without a password can only sign
+// in through single sign-on.
+func (s *SCIMService) CreateUser(ctx context.Context, in *scim.User) (*scim.User, error) {
+	name, email, err := scimIdentity(in)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{Name: name, Email: email, ExternalID: strings.TrimSpace(in.ExternalID)}
+	if in.Password != "" {
+		if user.Password, err = utils.HashPassword(in.Password); err != nil {
+			return nil, err
+		}
+	}
+	if in.Active != nil && !*in.Active {
+		now := time.Now()
+		user.DeactivatedAt = &now
+	}
+
+	if err := s.UserRepo.CreateProvisionedUser(ctx, user); err != nil {
+		return nil, userRepoError(err)
+	}
+	return toSCIMUser(user), nil
+}
+
+// ReplaceUser replaces the attributes of a user with those of in.
+func (s *SCIMService) ReplaceUser(ctx context.Context, id int, in *scim.User) (*scim.User, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, userRepoError(err)
+	}
+	return s.save(ctx, user, in)
+}
+
+// PatchUser applies PATCH operations to a user.
+func (s *SCIMService) PatchUser(ctx context.Context, id int, operations []scim.PatchOperation) (*scim.User, error) {
+	if len(operations) == 0 {
+		return nil, newSCIMError(SCIMInvalidSyntax, "at least one operation is required")
+	}
+
+	user, err := s.UserRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, userRepoError(err)
+	}
+
+	// The name is patched on the display name; setting name components
+	// replaces it.
+	patched := toSCIMUser(user)
+	patched.Name = nil
+	for _, op := range operations {
+		if err := applyPatchOperation(patched, op); err != nil {
+			return nil, err
+		}
+	}
+	return s.save(ctx, user, patched)
+}
+
+// DeactivateUser deactivates a user and signs them out everywhere.
+func (s *SCIMService) DeactivateUser(ctx context.Context, id int) error {
+	if err := s.UserRepo.SetUserActive(ctx, id, false); err != nil {
+		return userRepoError(err)
+	}
+	return s.Sessions.RevokeAllSessions(ctx, id)
+}
+
+// save stores the attributes of in on user.
+func (s *SCIMService) save(ctx context.Context, user *models.User, in *scim.User) (*scim.User, error) {
+	name, email, err := scimIdentity(in)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Name, user.Email, user.ExternalID = name, email, strings.TrimSpace(in.ExternalID)
+	if err := s.UserRepo.UpdateUser(ctx, user); err != nil {
+		return nil, userRepoError(err)
+	}
+
+	if in.Password != "" {
+		hashedPassword, err := utils.HashPassword(in.Password)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.UserRepo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+			return nil, userRepoError(err)
+		}
+	}
+
+	active := in.Active == nil || *in.Active
+	if active != user.IsActive() {
+		if active {
+			if err := s.UserRepo.SetUserActive(ctx, user.ID, true); err != nil {
+				return nil, userRepoError(err)
+			}
+			user.DeactivatedAt = nil
+		} else {
+			if err := s.DeactivateUser(ctx, user.ID); err != nil {
+				return nil, err
+			}
+			now := time.Now()
+			user.DeactivatedAt = &now
+		}
+	}
+	return toSCIMUser(user), nil
+}
+
+// scimIdentity validates the name and email address of a SCIM user. Users
+// without a name are named after their email address.
+func scimIdentity(in *scim.User) (name, email string, err error) {
+	email = strings.TrimSpace(in.PrimaryEmail())
+	if email == "" {
+		return "", "", newSCIMError(SCIMInvalidValue, "userName is required")
+	}
+	if addr, parseErr := mail.ParseAddress(email); parseErr != nil || addr.Address != email || len(email) > 255 {
+		return "", "", newSCIMError(SCIMInvalidValue, "%q is not a valid email address", email)
+	}
+
+	name = in.FullName()
+	if name == "" {
+		name = email
+	}
+	return name, email, nil
+}
+
+// toSCIMUser converts a user to its SCIM representation. The user name is
+// the email address.
+func toSCIMUser(user *models.User) *scim.User {
+	active := user.IsActive()
+	return &scim.User{
+		Schemas:     []string{scim.UserSchema},
+		ID:          strconv.Itoa(user.ID),
+		ExternalID:  user.ExternalID,
+		UserName:    user.Email,
+		Name:        &scim.Name{Formatted: user.Name},
+		DisplayName: user.Name,
+		Emails:      []scim.Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta:        &scim.Meta{ResourceType: "User", Created: user.CreatedAt},
+	}
+}
+
+// applyPatchOperation applies a single PATCH operation to u.
+func applyPatchOperation(u *scim.User, op scim.PatchOperation) error {
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+		if op.Path == "" {
+			var attributes map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &attributes); err != nil {
+				return newSCIMError(SCIMInvalidValue, "value must be an object of attributes when path is omitted")
+			}
+			for path, value := range attributes {
+				if err := setSCIMAttribute(u, scim.NormalizePath(path), value); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return setSCIMAttribute(u, scim.NormalizePath(op.Path), op.Value)
+	case "remove":
+		if op.Path == "" {
+			return newSCIMError(SCIMInvalidPath, "path is required to remove an attribute")
+		}
+		if scim.NormalizePath(op.Path) != "externalid" {
+			return newSCIMError(SCIMMutability, "%q cannot be removed", op.Path)
+		}
+		u.ExternalID = ""
+		return nil
+	}
+	return newSCIMError(SCIMInvalidSyntax, "unknown operation %q", op.Op)
+}
+
+// setSCIMAttribute sets the attribute at the normalized path to value.
+// Attributes that are not stored, such as phone numbers, are ignored.
+func setSCIMAttribute(u *scim.User, path string, value json.RawMessage) error {
+	if u.Name == nil && strings.HasPrefix(path, "name") {
+		u.Name = &scim.Name{}
+	}
+
+	var err error
+	switch {
+	case path == "active":
+		var active bool
+		if active, err = decodeSCIMBool(value); err == nil {
+			u.Active = &active
+		}
+	case path == "username":
+		err = json.Unmarshal(value, &u.UserName)
+		u.Emails = nil
+	case path == "displayname":
+		err = json.Unmarshal(value, &u.DisplayName)
+	case path == "externalid":
+		err = json.Unmarshal(value, &u.ExternalID)
+	case path == "password":
+		err = json.Unmarshal(value, &u.Password)
+	case path == "name":
+		err = json.Unmarshal(value, u.Name)
+	case path == "name.formatted":
+		err = json.Unmarshal(value, &u.Name.Formatted)
+	case path == "name.givenname":
+		err = json.Unmarshal(value, &u.Name.GivenName)
+	case path == "name.familyname":
+		err = json.Unmarshal(value, &u.Name.FamilyName)
+	case path == "emails":
+		err = json.Unmarshal(value, &u.Emails)
+	case strings.HasPrefix(path, "emails[") && strings.HasSuffix(path, "].value"):
+		// Only one email address is stored, so any selected address
+		// replaces it.
+		var email string
+		if err = json.Unmarshal(value, &email); err == nil {
+			u.Emails = []scim.Email{{Value: email, Primary: true}}
+		}
+	case strings.Contains(path, "[") || strings.HasPrefix(path, "urn:"):
+		return nil
+	case path == "id" || strings.HasPrefix(path, "meta"):
+		return newSCIMError(SCIMMutability, "%q is read-only", path)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return newSCIMError(SCIMInvalidValue, "invalid value for %q", path)
+	}
+	return nil
+}
+
+// decodeSCIMBool decodes a JSON boolean. Some identity providers send
+// booleans as the strings "True" and "False".
+func decodeSCIMBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(strings.ToLower(s))
+}
+
+var _ SCIMServiceInterface = (*SCIMService)(nil)
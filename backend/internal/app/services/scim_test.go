@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/scim"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestSCIMService_ListUsers(t *testing.T) {
+	repo := new(mockUserRepo)
+	service := NewSCIMService(repo, new(mockSessionService))
+
+	filter := &scim.Comparison{Path: "username", Op: "eq", Value: "jane@example.com"}
+	repo.On("SearchUsers", mock.Anything, filter, 10, 5).
+		Return([]models.User{{ID: 3, Name: "Jane", Email: "jane@example.com"}}, 12, nil)
+
+	list, err := service.ListUsers(context.Background(), `userName eq "jane@example.com"`, 11, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, list.TotalResults)
+	assert.Equal(t, 11, list.StartIndex)
+	assert.Equal(t, 1, list.ItemsPerPage)
+	assert.Equal(t, "3", list.Resources[0].ID)
+	assert.Equal(t, "jane@example.com", list.Resources[0].UserName)
+	assert.True(t, *list.Resources[0].Active)
+
+	repo.On("SearchUsers", mock.Anything, nil, 0, SCIMDefaultCount).Return([]models.User{}, 0, nil)
+	list, err = service.ListUsers(context.Background(), "", 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, list.Resources)
+	assert.NotNil(t, list.Resources)
+}
+
+func TestSCIMService_ListUsersInvalidFilter(t *testing.T) {
+	repo := new(mockUserRepo)
+	service := NewSCIMService(repo, new(mockSessionService))
+
+	_, err := service.ListUsers(context.Background(), `userName eq`, 1, 10)
+	assert.Equal(t, SCIMInvalidFilter, err.(*Error).Code)
+
+	repo.On("SearchUsers", mock.Anything, mock.Anything, 0, 10).
+		Return([]models.User(nil), 0, repositories.ErrUnsupportedFilter)
+	_, err = service.ListUsers(context.Background(), `title eq "x"`, 1, 10)
+	assert.Equal(t, SCIMInvalidFilter, err.(*Error).Code)
+}
+
+func TestSCIMService_CreateUser(t *testing.T) {
+	repo := new(mockUserRepo)
+	service := NewSCIMService(repo, new(mockSessionService))
+
+	repo.On("CreateProvisionedUser", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+		return u.Name == "Jane Doe" && u.Email == "jane@example.com" && u.ExternalID == "ext-1" && u.Password == "" && u.IsActive()
+	})).Run(func(args mock.Arguments) { args.Get(1).(*models.User).ID = 7 }).Return(nil)
+
+	user, err := service.CreateUser(context.Background(), &scim.User{
+		ExternalID: "ext-1",
+		UserName:   "jane",
+		Name:       &scim.Name{GivenName: "Jane", FamilyName: "Doe"},
+		Emails:     []scim.Email{{Value: "other@example.com"}, {Value: "jane@example.com", Primary: true}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "7", user.ID)
+	assert.Equal(t, "Jane Doe", user.DisplayName)
+	repo.AssertExpectations(t)
+}
+
+func TestSCIMService_CreateUserErrors(t *testing.T) {
+	repo := new(mockUserRepo)
+	service := NewSCIMService(repo, new(mockSessionService))
+
+	_, err := service.CreateUser(context.Background(), &scim.User{UserName: "not an email"})
+	assert.Equal(t, SCIMInvalidValue, err.(*Error).Code)
+
+	repo.On("CreateProvisionedUser", mock.Anything, mock.Anything).Return(repositories.ErrDuplicateEmail)
+	_, err = service.CreateUser(context.Background(), &scim.User{UserName: "jane@example.com"})
+	assert.Equal(t, KindConflict, KindOf(err))
+	assert.Equal(t, "email_taken", err.(*Error).Code)
+}
+
+func TestSCIMService_ReplaceUserDeactivates(t *testing.T) {
+	repo := new(mockUserRepo)
+	sessions := new(mockSessionService)
+	service := NewSCIMService(repo, sessions)
+
+	repo.On("GetUserByID", mock.Anything, 7).Return(&models.User{ID: 7, Name: "Jane", Email: "jane@example.com"}, nil)
+	repo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+		return u.Name == "Jane Roe" && u.Email == "jane.roe@example.com"
+	})).Return(nil)
+	repo.On("SetUserActive", mock.Anything, 7, false).Return(nil)
+	sessions.On("RevokeAllSessions", mock.Anything, 7).Return(nil)
+
+	user, err := service.ReplaceUser(context.Background(), 7, &scim.User{
+		UserName:    "jane.roe@example.com",
+		DisplayName: "Jane Roe",
+		Active:      boolPtr(false),
+	})
+	assert.NoError(t, err)
+	assert.False(t, *user.Active)
+	repo.AssertExpectations(t)
+	sessions.AssertExpectations(t)
+}
+
+func TestSCIMService_PatchUser(t *testing.T) {
+	repo := new(mockUserRepo)
+	sessions := new(mockSessionService)
+	service := NewSCIMService(repo, sessions)
+
+	deactivatedAt := time.Now()
+	repo.On("GetUserByID", mock.Anything, 7).
+		Return(&models.User{ID: 7, Name: "Jane", Email: "jane@example.com", ExternalID: "ext-1", DeactivatedAt: &deactivatedAt}, nil)
+	repo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+		return u.Name == "Jane Roe" && u.Email == "roe@example.com" && u.ExternalID == ""
+	})).Return(nil)
+	repo.On("UpdatePassword", mock.Anything, 7, mock.AnythingOfType("string")).Return(nil)
+	repo.On("SetUserActive", mock.Anything, 7, true).Return(nil)
+
+	user, err := service.PatchUser(context.Background(), 7, []scim.PatchOperation{
+		{Op: "Replace", Value: json.RawMessage(`{"active": "True", "name.givenName": "Jane", "name.familyName": "Roe"}`)},
+		{Op: "replace", Path: `emails[type eq "work"].value`, Value: json.RawMessage(`"roe@example.com"`)},
+		{Op: "add", Path: "password", Value: json.RawMessage(`"n3w-passw0rd"`)},
+		{Op: "remove", Path: "externalId"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, *user.Active)
+	assert.Equal(t, "roe@example.com", user.UserName)
+	repo.AssertExpectations(t)
+	sessions.AssertNotCalled(t, "RevokeAllSessions", mock.Anything, mock.Anything)
+}
+
+func TestSCIMService_PatchUserErrors(t *testing.T) {
+	repo := new(mockUserRepo)
+	service := NewSCIMService(repo, new(mockSessionService))
+	repo.On("GetUserByID", mock.Anything, 7).Return(&models.User{ID: 7, Name: "Jane", Email: "jane@example.com"}, nil)
+
+	tests := []struct {
+		name string
+		op   scim.PatchOperation
+		code string
+	}{
+		{"unknown op", scim.PatchOperation{Op: "move", Path: "active"}, SCIMInvalidSyntax},
+		{"remove required attribute", scim.PatchOperation{Op: "remove", Path: "userName"}, SCIMMutability},
+		{"remove without path", scim.PatchOperation{Op: "remove"}, SCIMInvalidPath},
+		{"invalid boolean", scim.PatchOperation{Op: "replace", Path: "active", Value: json.RawMessage(`"maybe"`)}, SCIMInvalidValue},
+		{"read-only attribute", scim.PatchOperation{Op: "replace", Path: "id", Value: json.RawMessage(`"8"`)}, SCIMMutability},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.PatchUser(context.Background(), 7, []scim.PatchOperation{tt.op})
+			assert.Equal(t, tt.code, err.(*Error).Code)
+		})
+	}
+}
+
+func TestSCIMService_DeactivateUser(t *testing.T) {
+	repo := new(mockUserRepo)
+	sessions := new(mockSessionService)
+	service := NewSCIMService(repo, sessions)
+
+	repo.On("SetUserActive", mock.Anything, 7, false).Return(nil)
+	sessions.On("RevokeAllSessions", mock.Anything, 7).Return(nil)
+	assert.NoError(t, service.DeactivateUser(context.Background(), 7))
+
+	repo.On("SetUserActive", mock.Anything, 8, false).Return(repositories.ErrUserNotFound)
+	err := service.DeactivateUser(context.Background(), 8)
+	assert.Equal(t, KindNotFound, KindOf(err))
+	sessions.AssertNumberOfCalls(t, "RevokeAllSessions", 1)
+}
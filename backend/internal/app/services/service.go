@@ -2,21 +2,185 @@ package services
 
 import (
 	"context"
+	"time"
 	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/scim"
 )
 
 type UserServiceInterface interface {
-	CreateUser(ctx context.Context, name string, email string, password string) (*models.User, error)
+	CreateUser(ctx context.Context, name string, email string, password string, inviteCode string) (*models.User, error)
 	DeleteUser(ctx context.Context, id int) error
 	GetAllUsers(ctx context.Context) ([]models.User, error)
 	GetUserByID(ctx context.Context, id int) (*models.User, error)
 	GetUserByCreds(ctx context.Context, email, password string) (*models.User, error)
+	SetRole(ctx context.Context, adminID int, id int, role string) (*models.User, error)
+	ListPendingUsers(ctx context.Context) ([]models.User, error)
+	ApproveUser(ctx context.Context, id int) (*models.User, error)
+	RejectUser(ctx context.Context, id int) error
+}
+
+type RegistrationServiceInterface interface {
+	Mode() string
+	CreateInvite(ctx context.Context, adminID int, note string, expiresAt *time.Time) (*models.InviteCode, string, error)
+	ListInvites(ctx context.Context) ([]models.InviteCode, error)
+	DeleteInvite(ctx context.Context, id int) error
 }
 
 type TodoServiceInterface interface {
-	CreateTodo(ctx context.Context, userId int, title string, content string) (*models.Todo, error)
+	CreateTodo(ctx context.Context, userId int, title string, content string, tags []string, dueDate *time.Time) (*models.Todo, error)
 	DeleteTodo(ctx context.Context, userId int, id int) error
 	GetAllTodos(ctx context.Context, userId int) ([]models.Todo, error)
 	GetTodoByID(ctx context.Context, userId int, id int) (*models.Todo, error)
-	UpdateTodo(ctx context.Context, userId int, id int, title string, content string, status string) error
+	UpdateTodo(ctx context.Context, userId int, id int, title string, content string, status string, tags models.Optional[[]string], dueDate models.Optional[*time.Time]) error
+}
+
+type EventBrokerInterface interface {
+	Publish(ctx context.Context, event *models.Event)
+	Subscribe(userID int, lastEventID int64) (*EventSubscription, []models.Event)
+	Unsubscribe(sub *EventSubscription)
+}
+
+type LoginGuardInterface interface {
+	Check(ctx context.Context, email string, ip string) error
+	RecordFailure(ctx context.Context, email string, ip string) error
+	RecordSuccess(ctx context.Context, email string, ip string)
+	Unlock(ctx context.Context, userID int, unlockedBy int) error
+}
+
+type AccountServiceInterface interface {
+	SendVerificationEmail(ctx context.Context, user *models.User) error
+	ResendVerificationEmail(ctx context.Context, email string) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, password string) error
+	RequestEmailChange(ctx context.Context, user *models.User, newEmail string) error
+}
+
+type SessionServiceInterface interface {
+	StartSession(ctx context.Context, userID int, ip string, userAgent string) (string, error)
+	ValidateSession(ctx context.Context, token *models.Token) error
+	RevokeOtherSessions(ctx context.Context, userID int, currentSessionID int) error
+	RevokeAllSessions(ctx context.Context, userID int) error
+	ListSessions(ctx context.Context, userID int, currentSessionID int) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID int, sessionID int) error
+}
+
+type ProfileServiceInterface interface {
+	GetProfile(ctx context.Context, userID int) (*models.User, error)
+	UpdateProfile(ctx context.Context, userID int, update ProfileUpdate) (*models.User, bool, error)
+	ChangePassword(ctx context.Context, userID int, sessionID int, currentPassword string, newPassword string) error
+	DeleteAccount(ctx context.Context, userID int, sessionID int, password string) (*models.User, error)
+	CancelDeletion(ctx context.Context, userID int) (*models.User, error)
+	PurgeDeletedAccounts(ctx context.Context) (int64, error)
+}
+
+type AccessTokenServiceInterface interface {
+	CreateToken(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*models.PersonalAccessToken, string, error)
+	ListTokens(ctx context.Context, userID int) ([]models.PersonalAccessToken, error)
+	DeleteToken(ctx context.Context, userID int, id int) error
+	Authenticate(ctx context.Context, secret string) (*models.PersonalAccessToken, error)
+}
+
+type TwoFactorServiceInterface interface {
+	Status(ctx context.Context, userID int) (*models.TwoFactorStatus, error)
+	IsEnabled(ctx context.Context, userID int) (bool, error)
+	BeginEnrollment(ctx context.Context, userID int) (*models.TOTPEnrollment, error)
+	ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID int, password string) ([]string, error)
+	Disable(ctx context.Context, userID int, password string) error
+	Reset(ctx context.Context, userID int) error
+	NewChallenge(ctx context.Context, userID int) (string, error)
+	ParseChallenge(challenge string) (int, error)
+	Verify(ctx context.Context, userID int, code string) error
+}
+
+type OIDCServiceInterface interface {
+	ListProviders() []models.OIDCProvider
+	BeginLogin(ctx context.Context, providerName string) (authURL string, state string, err error)
+	CompleteLogin(ctx context.Context, providerName, state, code string) (*models.User, error)
+}
+
+type OAuthServiceInterface interface {
+	RegisterClient(ctx context.Context, ownerID int, name string, redirectURIs []string, confidential bool) (*models.OAuthClient, string, error)
+	ListClients(ctx context.Context, ownerID int) ([]models.OAuthClient, error)
+	DeleteClient(ctx context.Context, ownerID int, clientID string) error
+	ValidateAuthorizationRequest(ctx context.Context, req *OAuthAuthorizationRequest) error
+	DescribeConsent(ctx context.Context, userID int, req *OAuthAuthorizationRequest) (*models.OAuthConsent, error)
+	Authorize(ctx context.Context, userID int, req *OAuthAuthorizationRequest, approve bool) (string, error)
+	Token(ctx context.Context, req *OAuthTokenRequest) (*models.OAuthTokenResponse, error)
+	AuthenticateAccessToken(ctx context.Context, secret string) (*models.OAuthToken, error)
+	ListAuthorizedApps(ctx context.Context, userID int) ([]models.AuthorizedApp, error)
+	RevokeAuthorizedApp(ctx context.Context, userID int, clientID string) error
+}
+
+type SCIMServiceInterface interface {
+	ListUsers(ctx context.Context, filter string, startIndex, count int) (*scim.ListResponse, error)
+	GetUser(ctx context.Context, id int) (*scim.User, error)
+	CreateUser(ctx context.Context, in *scim.User) (*scim.User, error)
+	ReplaceUser(ctx context.Context, id int, in *scim.User) (*scim.User, error)
+	PatchUser(ctx context.Context, id int, operations []scim.PatchOperation) (*scim.User, error)
+	DeactivateUser(ctx context.Context, id int) error
+}
+
+type AuditServiceInterface interface {
+	Record(ctx context.Context, entry *models.AuditEntry)
+	ListEntries(ctx context.Context, filter models.AuditFilter, limit int) ([]models.AuditEntry, error)
+	Export(ctx context.Context, filter models.AuditFilter, fn func(entry *models.AuditEntry) error) error
+	Verify(ctx context.Context) (*models.AuditVerification, error)
+}
+
+type WebhookServiceInterface interface {
+	CreateWebhook(ctx context.Context, userID int, rawURL string, eventTypes []string, secret string) (*models.Webhook, string, error)
+	ListWebhooks(ctx context.Context, userID int) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, userID int, id int) (*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, userID int, id int, rawURL string, eventTypes []string, secret string, active *bool) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID int, id int) error
+	ListDeliveries(ctx context.Context, userID int, webhookID int, beforeID int64, limit int) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, userID int, webhookID int, deliveryID int64) (*models.WebhookDelivery, error)
+}
+
+type InboundWebhookServiceInterface interface {
+	CreateInboundWebhook(ctx context.Context, userID int, name string, mapping models.InboundMapping, signed bool) (*models.InboundWebhook, string, string, error)
+	ListInboundWebhooks(ctx context.Context, userID int) ([]models.InboundWebhook, error)
+	GetInboundWebhook(ctx context.Context, userID int, id int) (*models.InboundWebhook, error)
+	UpdateInboundWebhook(ctx context.Context, userID int, id int, name string, mapping models.InboundMapping, signed bool) (*models.InboundWebhook, string, error)
+	DeleteInboundWebhook(ctx context.Context, userID int, id int) error
+	Receive(ctx context.Context, token string, body []byte, timestamp, signature string) (*models.Todo, bool, error)
+}
+
+type JobServiceInterface interface {
+	ListJobs(ctx context.Context, filter models.JobFilter, limit int) ([]models.Job, error)
+	GetJob(ctx context.Context, id int64) (*models.Job, error)
+	RetryJob(ctx context.Context, id int64) (*models.Job, error)
+}
+
+type SchedulerServiceInterface interface {
+	ListSchedules(ctx context.Context) ([]models.Schedule, error)
+}
+
+type ReminderServiceInterface interface {
+	ListReminders(ctx context.Context, userID int, todoID int) ([]models.Reminder, error)
+	CreateReminder(ctx context.Context, userID int, todoID int, remindAt *time.Time, beforeMinutes *int) (*models.Reminder, error)
+	DeleteReminder(ctx context.Context, userID int, todoID int, id int) error
+	SnoozeReminder(ctx context.Context, userID int, todoID int, id int, d time.Duration) (*models.Reminder, error)
+	GetPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, prefs *models.NotificationPreferences) (*models.NotificationPreferences, error)
+}
+
+type PushServiceInterface interface {
+	PublicKey() string
+	ListSubscriptions(ctx context.Context, userID int) ([]models.PushSubscription, error)
+	Subscribe(ctx context.Context, userID int, endpoint, p256dh, auth string) (*models.PushSubscription, error)
+	Unsubscribe(ctx context.Context, userID int, id int) error
+}
+
+type NotificationServiceInterface interface {
+	ListNotifications(ctx context.Context, userID int, unreadOnly bool, beforeID int64, limit int) ([]models.Notification, int, error)
+	UnreadCount(ctx context.Context, userID int) (int, error)
+	MarkRead(ctx context.Context, userID int, id int64) (*models.Notification, error)
+	MarkAllRead(ctx context.Context, userID int, upToID int64) (int64, error)
+}
+
+type DigestServiceInterface interface {
+	Unsubscribe(ctx context.Context, token string) error
 }
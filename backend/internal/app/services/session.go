@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+)
+
+// DefaultSessionTTL is how long a login session and its access token last.
+const DefaultSessionTTL = 15 * time.Minute
+
+var errSessionRevoked = NewUnauthorizedError("session_revoked", "session has expired or been revoked")
+
+// SessionService issues access tokens bound to revocable login sessions.
+type SessionService struct {
+	SessionRepo repositories.SessionRepoInterface
+	TTL         time.Duration
+	// Audit records logins. It is optional.
+	Audit AuditRecorder
+}
+
+// NewSessionService initializes a new SessionService.
+func NewSessionService(sessionRepo repositories.SessionRepoInterface) *SessionService {
+	return &SessionService{SessionRepo: sessionRepo, TTL: DefaultSessionTTL}
+}
+
+// StartSession records a new session for the user and returns an access
+// token bound to it.
+func (s *SessionService) StartSession(ctx context.Context, userID int, ip, userAgent string) (string, error) {
+	session := &models.Session{
+		UserID:    userID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Device:    utils.DescribeDevice(userAgent),
+		ExpiresAt: time.Now().Add(s.TTL),
+	}
+	err := s.SessionRepo.CreateSession(ctx, session)
+	if errors.Is(err, repositories.ErrUserDeactivated) {
+		return "", errAccountDeactivated
+	} else if err != nil {
+		return "", err
+	}
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditLogin,
+		ActorID:    &userID,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+		Metadata:   auditMetadata(map[string]interface{}{"session_id": session.ID, "device": session.Device}),
+	})
+	return utils.GenerateToken(models.Token{UserID: userID, SessionID: session.ID, Exp: session.ExpiresAt})
+}
+
+// ValidateSession returns an error unless the session referenced by token
+// belongs to its user and is neither revoked nor expired. Valid sessions
+// are marked as seen.
+func (s *SessionService) ValidateSession(ctx context.Context, token *models.Token) error {
+	if token.SessionID == 0 || token.Purpose != "" {
+		return errSessionRevoked
+	}
+
+	session, err := s.SessionRepo.GetSession(ctx, token.SessionID)
+	if errors.Is(err, repositories.ErrSessionNotFound) {
+		return errSessionRevoked
+	} else if err != nil {
+		return err
+	}
+
+	if session.UserID != token.UserID || session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return errSessionRevoked
+	}
+	return s.SessionRepo.TouchSession(ctx, session.ID)
+}
+
+// ListSessions lists the active sessions of the user, marking the one with
+// ID currentSessionID as current.
+func (s *SessionService) ListSessions(ctx context.Context, userID int, currentSessionID int) ([]models.Session, error) {
+	sessions, err := s.SessionRepo.ListActiveSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs the user out of a single session. Tokens bound to it
+// are rejected from the next request on.
+func (s *SessionService) RevokeSession(ctx context.Context, userID int, sessionID int) error {
+	err := s.SessionRepo.RevokeSession(ctx, userID, sessionID)
+	if errors.Is(err, repositories.ErrSessionNotFound) {
+		return NewNotFoundError("session_not_found", "session not found")
+	}
+	return err
+}
+
+// RevokeOtherSessions signs the user out everywhere except in the session
+// with ID currentSessionID.
+func (s *SessionService) RevokeOtherSessions(ctx context.Context, userID int, currentSessionID int) error {
+	return s.SessionRepo.RevokeUserSessions(ctx, userID, currentSessionID)
+}
+
+// RevokeAllSessions signs the user out everywhere.
+func (s *SessionService) RevokeAllSessions(ctx context.Context, userID int) error {
+	return s.SessionRepo.RevokeUserSessions(ctx, userID, 0)
+}
+
+var _ SessionServiceInterface = (*SessionService)(nil)
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockSessionRepo is a mock implementation of repositories.SessionRepoInterface.
+type mockSessionRepo struct {
+	mock.Mock
+}
+
+func (m *mockSessionRepo) CreateSession(ctx context.Context, session *models.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) GetSession(ctx context.Context, id int) (*models.Session, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *mockSessionRepo) RevokeUserSessions(ctx context.Context, userID int, exceptID int) error {
+	args := m.Called(ctx, userID, exceptID)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) ListActiveSessions(ctx context.Context, userID int) ([]models.Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *mockSessionRepo) TouchSession(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockSessionRepo) RevokeSession(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+// mockSessionService is a mock implementation of SessionServiceInterface.
+type mockSessionService struct {
+	mock.Mock
+}
+
+func (m *mockSessionService) StartSession(ctx context.Context, userID int, ip, userAgent string) (string, error) {
+	args := m.Called(ctx, userID, ip, userAgent)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockSessionService) ValidateSession(ctx context.Context, token *models.Token) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *mockSessionService) RevokeOtherSessions(ctx context.Context, userID int, currentSessionID int) error {
+	args := m.Called(ctx, userID, currentSessionID)
+	return args.Error(0)
+}
+
+func (m *mockSessionService) RevokeAllSessions(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockSessionService) ListSessions(ctx context.Context, userID int, currentSessionID int) ([]models.Session, error) {
+	args := m.Called(ctx, userID, currentSessionID)
+	return args.Get(0).([]models.Session), args.Error(1)
+}
+
+func (m *mockSessionService) RevokeSession(ctx context.Context, userID int, sessionID int) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func TestSessionService_StartSession(t *testing.T) {
+	setTestConfigEnv(t)
+	repo := new(mockSessionRepo)
+	service := NewSessionService(repo)
+
+	repo.On("CreateSession", mock.Anything, mock.MatchedBy(func(s *models.Session) bool {
+		return s.UserID == 5 && s.IP == "10.0.0.1" && s.UserAgent == "curl" && s.Device == utils.DescribeDevice("curl")
+	})).Run(func(args mock.Arguments) { args.Get(1).(*models.Session).ID = 42 }).Return(nil)
+
+	tokenStr, err := service.StartSession(context.Background(), 5, "10.0.0.1", "curl")
+	require.NoError(t, err)
+
+	token, err := utils.ValidateToken(tokenStr)
+	require.NoError(t, err)
+	assert.Equal(t, 5, token.UserID)
+	assert.Equal(t, 42, token.SessionID)
+}
+
+func TestSessionService_ValidateSession(t *testing.T) {
+	repo := new(mockSessionRepo)
+	service := NewSessionService(repo)
+
+	revokedAt := time.Now()
+	repo.On("GetSession", mock.Anything, 1).Return(&models.Session{ID: 1, UserID: 5, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+	repo.On("GetSession", mock.Anything, 2).Return(&models.Session{ID: 2, UserID: 5, ExpiresAt: time.Now().Add(time.Minute), RevokedAt: &revokedAt}, nil)
+	repo.On("GetSession", mock.Anything, 3).Return((*models.Session)(nil), repositories.ErrSessionNotFound)
+	repo.On("TouchSession", mock.Anything, 1).Return(nil)
+
+	tests := []struct {
+		name  string
+		token models.Token
+		valid bool
+	}{
+		{"Active session", models.Token{UserID: 5, SessionID: 1}, true},
+		{"Session of another user", models.Token{UserID: 6, SessionID: 1}, false},
+		{"Revoked session", models.Token{UserID: 5, SessionID: 2}, false},
+		{"Unknown session", models.Token{UserID: 5, SessionID: 3}, false},
+		{"Token without session", models.Token{UserID: 5}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.ValidateSession(context.Background(), &tt.token)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, KindUnauthorized, KindOf(err))
+			}
+		})
+	}
+}
+
+func TestSessionService_ListSessions(t *testing.T) {
+	repo := new(mockSessionRepo)
+	service := NewSessionService(repo)
+
+	repo.On("ListActiveSessions", mock.Anything, 5).Return([]models.Session{{ID: 1, UserID: 5}, {ID: 2, UserID: 5}}, nil)
+
+	sessions, err := service.ListSessions(context.Background(), 5, 2)
+	assert.NoError(t, err)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+}
+
+func TestSessionService_RevokeSession(t *testing.T) {
+	repo := new(mockSessionRepo)
+	service := NewSessionService(repo)
+
+	repo.On("RevokeSession", mock.Anything, 5, 1).Return(nil)
+	repo.On("RevokeSession", mock.Anything, 5, 9).Return(repositories.ErrSessionNotFound)
+
+	assert.NoError(t, service.RevokeSession(context.Background(), 5, 1))
+	assert.Equal(t, KindNotFound, KindOf(service.RevokeSession(context.Background(), 5, 9)))
+	repo.AssertExpectations(t)
+}
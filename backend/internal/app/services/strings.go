@@ -0,0 +1,23 @@
+package services
+
+// containsString reports whether values contains value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeStrings returns values without duplicates, in the order they first
+// appear.
+func dedupeStrings(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if !containsString(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -3,13 +3,28 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 	"todo_app_backend/internal/app/models"
 	"todo_app_backend/internal/app/repositories"
 )
 
+const (
+	// overdueBatchSize is the number of overdue todos claimed at once.
+	overdueBatchSize = 100
+	// maxTodoTags caps the number of tags of a todo.
+	maxTodoTags = 32
+	// maxTodoTagLength caps the length of each tag of a todo.
+	maxTodoTagLength = 64
+)
+
 // TodoService defines methods related to todo operations.
 type TodoService struct {
 	TodoRepo repositories.TodoRepoInterface
+	// Events publishes changes to the live clients of the user. It is optional.
+	Events EventPublisher
 }
 
 // NewTodoService initializes a new TodoService.
@@ -18,25 +33,29 @@ func NewTodoService(todoRepo repositories.TodoRepoInterface) *TodoService {
 }
 
 // CreateTodo creates a new todo.
-func (s *TodoService) CreateTodo(ctx context.Context, userId int, title, content string) (*models.Todo, error) {
+func (s *TodoService) CreateTodo(ctx context.Context, userId int, title, content string, tags []string, dueDate *time.Time) (*models.Todo, error) {
 	// Validate input
-	if title == "" {
-		return nil, errors.New("title is required")
+	tags, err := validateTodo(title, tags)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create todo model
 	todo := &models.Todo{
 		Title:   title,
 		Content: content,
-		Status:  "pending",
+		Status:  models.TodoPending,
+		Tags:    tags,
+		DueDate: dueDate,
 	}
 
 	// Store todo in DB
-	err := s.TodoRepo.CreateTodo(ctx, userId, todo)
+	err = s.TodoRepo.CreateTodo(ctx, userId, todo)
 	if err != nil {
-		return nil, err
+		return nil, todoRepoError(err)
 	}
 
+	publishEvent(ctx, s.Events, userId, models.EventTodoCreated, todo)
 	return todo, nil
 }
 
@@ -44,7 +63,7 @@ func (s *TodoService) CreateTodo(ctx context.Context, userId int, title, content
 func (s *TodoService) GetTodoByID(ctx context.Context, userId, id int) (*models.Todo, error) {
 	todo, err := s.TodoRepo.GetTodoByID(ctx, userId, id)
 	if err != nil {
-		return nil, err
+		return nil, todoRepoError(err)
 	}
 	return todo, nil
 }
@@ -54,24 +73,107 @@ func (s *TodoService) GetAllTodos(ctx context.Context, userId int) ([]models.Tod
 	return s.TodoRepo.GetAllTodos(ctx, userId)
 }
 
-// UpdateTodo updates a todo by ID.
-func (s *TodoService) UpdateTodo(ctx context.Context, userId int, id int, title, content, status string) error {
-	if title == "" {
-		return errors.New("title is required")
+// UpdateTodo updates a todo by ID. Its tags and due date are kept unless
+// set, so that clients unaware of them do not clear them.
+func (s *TodoService) UpdateTodo(ctx context.Context, userId int, id int, title, content, status string, tags models.Optional[[]string], dueDate models.Optional[*time.Time]) error {
+	cleaned, err := validateTodo(title, tags.Value)
+	if err != nil {
+		return err
+	}
+	if tags.Set {
+		tags.Value = cleaned
 	}
 
-	todo := &models.Todo{
+	todo := &models.TodoUpdate{
 		Title:   title,
 		Content: content,
 		Status:  status,
+		Tags:    tags,
+		DueDate: dueDate,
 	}
 
-	return s.TodoRepo.UpdateTodo(ctx, userId, id, todo)
+	if err := s.TodoRepo.UpdateTodo(ctx, userId, id, todo); err != nil {
+		return todoRepoError(err)
+	}
+
+	// Clients are sent the whole todo, including the fields the update
+	// did not change.
+	if s.Events != nil {
+		updated, err := s.TodoRepo.GetTodoByID(ctx, userId, id)
+		if err != nil {
+			log.Printf("failed to publish update of todo %d: %v", id, err)
+			return nil
+		}
+		publishEvent(ctx, s.Events, userId, models.EventTodoUpdated, updated)
+	}
+	return nil
 }
 
 // DeleteTodo deletes a todo by ID.
 func (s *TodoService) DeleteTodo(ctx context.Context, userId int, id int) error {
-	return s.TodoRepo.DeleteTodo(ctx, userId, id)
+	if err := s.TodoRepo.DeleteTodo(ctx, userId, id); err != nil {
+		return todoRepoError(err)
+	}
+
+	publishEvent(ctx, s.Events, userId, models.EventTodoDeleted, map[string]int{"id": id})
+	return nil
+}
+
+// NotifyOverdueTodos publishes a todo.overdue event for each uncompleted todo
+// whose due date passed since it was last checked. Todos overdue for longer
+// than MaxReminderLateness, such as when the check first runs, are not
+// reported. It returns the number of todos reported.
+func (s *TodoService) NotifyOverdueTodos(ctx context.Context) (int, error) {
+	reported := 0
+	for {
+		now := time.Now()
+		todos, err := s.TodoRepo.ClaimOverdueTodos(ctx, now.Add(-MaxReminderLateness), now, overdueBatchSize)
+		if err != nil {
+			return reported, err
+		}
+		for _, todo := range todos {
+			publishEvent(ctx, s.Events, todo.UserID, models.EventTodoOverdue, todo)
+		}
+		reported += len(todos)
+
+		if len(todos) < overdueBatchSize {
+			return reported, nil
+		}
+	}
+}
+
+// validateTodo checks the title and tags of a todo, and returns its tags
+// trimmed and without duplicates.
+func validateTodo(title string, tags []string) ([]string, error) {
+	if title == "" {
+		return nil, NewValidationError("title is required", FieldError{Field: "title", Message: "is required"})
+	}
+
+	cleaned := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || containsString(cleaned, tag) {
+			continue
+		}
+		if len(tag) > maxTodoTagLength {
+			return nil, NewValidationError("invalid tags",
+				FieldError{Field: "tags", Message: fmt.Sprintf("each tag must be at most %d characters", maxTodoTagLength)})
+		}
+		cleaned = append(cleaned, tag)
+	}
+	if len(cleaned) > maxTodoTags {
+		return nil, NewValidationError("invalid tags",
+			FieldError{Field: "tags", Message: fmt.Sprintf("must have at most %d tags", maxTodoTags)})
+	}
+	return cleaned, nil
+}
+
+// todoRepoError translates repository errors into domain errors.
+func todoRepoError(err error) error {
+	if errors.Is(err, repositories.ErrTodoNotFound) {
+		return NewNotFoundError("todo_not_found", "todo not found")
+	}
+	return err
 }
 
 var _ TodoServiceInterface = (*TodoService)(nil)
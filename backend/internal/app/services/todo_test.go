@@ -3,12 +3,17 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/eventbus"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // mockTodoRepo is a mock implementation of the TodoRepoInterface
@@ -31,8 +36,8 @@ func (m *mockTodoRepo) GetAllTodos(ctx context.Context, userId int) ([]models.To
 	return args.Get(0).([]models.Todo), args.Error(1)
 }
 
-func (m *mockTodoRepo) UpdateTodo(ctx context.Context, userId, id int, todo *models.Todo) error {
-	args := m.Called(ctx, userId, id, todo)
+func (m *mockTodoRepo) UpdateTodo(ctx context.Context, userId, id int, update *models.TodoUpdate) error {
+	args := m.Called(ctx, userId, id, update)
 	return args.Error(0)
 }
 
@@ -41,22 +46,50 @@ func (m *mockTodoRepo) DeleteTodo(ctx context.Context, userId, id int) error {
 	return args.Error(0)
 }
 
+func (m *mockTodoRepo) UpsertTodo(ctx context.Context, userId int, externalKey string, todo *models.Todo) (bool, error) {
+	args := m.Called(ctx, userId, externalKey, todo)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockTodoRepo) ClaimOverdueTodos(ctx context.Context, since, now time.Time, limit int) ([]models.OverdueTodo, error) {
+	args := m.Called(ctx, since, now, limit)
+	return args.Get(0).([]models.OverdueTodo), args.Error(1)
+}
+
+func (m *mockTodoRepo) GetDigest(ctx context.Context, userId int, start, end, completedSince time.Time) (*models.Digest, error) {
+	args := m.Called(ctx, userId, start, end, completedSince)
+	digest, _ := args.Get(0).(*models.Digest)
+	return digest, args.Error(1)
+}
+
 func TestTodoService_CreateTodo(t *testing.T) {
 	mockRepo := new(mockTodoRepo)
 	service := NewTodoService(mockRepo)
 	ctx := context.Background()
 
 	// Test for success case
-	todo := &models.Todo{Title: "Test Todo", Content: "Todo Content", Status: "pending"}
+	due := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	todo := &models.Todo{Title: "Test Todo", Content: "Todo Content", Status: "pending", Tags: []string{"home", "urgent"}, DueDate: &due}
 	mockRepo.On("CreateTodo", ctx, 1, todo).Return(nil)
 
-	newTodo, err := service.CreateTodo(ctx, 1, "Test Todo", "Todo Content")
+	// Tags are trimmed and deduplicated
+	newTodo, err := service.CreateTodo(ctx, 1, "Test Todo", "Todo Content", []string{" home", "urgent", "home", ""}, &due)
 	assert.NoError(t, err)
 	assert.Equal(t, todo.Title, newTodo.Title)
+	assert.Equal(t, &due, newTodo.DueDate)
 
 	// Test for error case due to empty title
-	_, err = service.CreateTodo(ctx, 1, "", "Todo Content")
+	_, err = service.CreateTodo(ctx, 1, "", "Todo Content", nil, nil)
 	assert.EqualError(t, err, "title is required")
+	assert.Equal(t, KindValidation, KindOf(err))
+
+	// Too many tags are refused
+	tags := make([]string, maxTodoTags+1)
+	for i := range tags {
+		tags[i] = fmt.Sprintf("tag%d", i)
+	}
+	_, err = service.CreateTodo(ctx, 1, "Test Todo", "", tags, nil)
+	assert.Equal(t, KindValidation, KindOf(err))
 }
 
 func TestTodoService_GetTodoByID(t *testing.T) {
@@ -114,14 +147,32 @@ func TestTodoService_UpdateTodo(t *testing.T) {
 	ctx := context.Background()
 
 	// Test for success case
-	todo := &models.Todo{Title: "Updated Todo", Content: "Updated Content", Status: "pending"}
-	mockRepo.On("UpdateTodo", ctx, 1, 1, todo).Return(nil)
+	due := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	update := &models.TodoUpdate{
+		Title: "Updated Todo", Content: "Updated Content", Status: "pending",
+		Tags: models.Some([]string{"work"}), DueDate: models.Some(&due),
+	}
+	mockRepo.On("UpdateTodo", ctx, 1, 1, update).Return(nil)
 
-	err := service.UpdateTodo(ctx, 1, 1, "Updated Todo", "Updated Content", "pending")
+	err := service.UpdateTodo(ctx, 1, 1, "Updated Todo", "Updated Content", "pending", models.Some([]string{" work ", "work"}), models.Some(&due))
 	assert.NoError(t, err)
 
+	// Leaving the tags and due date out keeps them
+	kept := &models.TodoUpdate{Title: "Updated Todo", Content: "Updated Content", Status: "completed"}
+	mockRepo.On("UpdateTodo", ctx, 1, 2, kept).Return(nil)
+	assert.NoError(t, service.UpdateTodo(ctx, 1, 2, "Updated Todo", "Updated Content", "completed", models.Optional[[]string]{}, models.Optional[*time.Time]{}))
+
+	// Setting them to nothing clears them
+	cleared := &models.TodoUpdate{
+		Title: "Updated Todo", Content: "Updated Content", Status: "pending",
+		Tags: models.Some([]string{}), DueDate: models.Some((*time.Time)(nil)),
+	}
+	mockRepo.On("UpdateTodo", ctx, 1, 3, cleared).Return(nil)
+	assert.NoError(t, service.UpdateTodo(ctx, 1, 3, "Updated Todo", "Updated Content", "pending", models.Some([]string(nil)), models.Some((*time.Time)(nil))))
+	mockRepo.AssertExpectations(t)
+
 	// Test for error case due to empty title
-	err = service.UpdateTodo(ctx, 1, 1, "", "Updated Content", "pending")
+	err = service.UpdateTodo(ctx, 1, 1, "", "Updated Content", "pending", models.Optional[[]string]{}, models.Optional[*time.Time]{})
 	assert.EqualError(t, err, "title is required")
 }
 
@@ -140,4 +191,75 @@ func TestTodoService_DeleteTodo(t *testing.T) {
 	mockRepo.On("DeleteTodo", ctx, 1, 2).Return(errors.New("todo not found"))
 	err = service.DeleteTodo(ctx, 1, 2)
 	assert.Error(t, err)
+	assert.Equal(t, KindInternal, KindOf(err))
+
+	// Test that repository not found errors become domain errors
+	mockRepo.On("DeleteTodo", ctx, 1, 3).Return(repositories.ErrTodoNotFound)
+	err = service.DeleteTodo(ctx, 1, 3)
+	assert.Equal(t, KindNotFound, KindOf(err))
+}
+
+func TestTodoService_PublishesEvents(t *testing.T) {
+	mockRepo := new(mockTodoRepo)
+	service := NewTodoService(mockRepo)
+	bus := eventbus.NewMemory()
+	service.Events = bus
+	ctx := context.Background()
+
+	var events []models.Event
+	bus.Subscribe(func(event models.Event) { events = append(events, event) })
+
+	mockRepo.On("CreateTodo", ctx, 1, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(2).(*models.Todo).ID = 5
+	}).Return(nil)
+	mockRepo.On("UpdateTodo", ctx, 1, 5, mock.Anything).Return(nil)
+	mockRepo.On("GetTodoByID", ctx, 1, 5).Return(&models.Todo{ID: 5, Title: "Renamed", Status: "completed"}, nil)
+	mockRepo.On("DeleteTodo", ctx, 1, 5).Return(nil)
+	mockRepo.On("DeleteTodo", ctx, 1, 6).Return(repositories.ErrTodoNotFound)
+
+	_, err := service.CreateTodo(ctx, 1, "Test Todo", "", nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, service.UpdateTodo(ctx, 1, 5, "Renamed", "", "completed", models.Optional[[]string]{}, models.Optional[*time.Time]{}))
+	assert.NoError(t, service.DeleteTodo(ctx, 1, 5))
+	assert.Error(t, service.DeleteTodo(ctx, 1, 6))
+
+	// Failed changes are not published
+	require.Len(t, events, 3)
+	created, updated, deleted := events[0], events[1], events[2]
+	assert.Equal(t, 1, created.UserID)
+	assert.Equal(t, models.EventTodoCreated, created.Type)
+	assert.Contains(t, string(created.Data), `"id":5`)
+	assert.Equal(t, models.EventTodoUpdated, updated.Type)
+	assert.Contains(t, string(updated.Data), `"title":"Renamed"`)
+	assert.Equal(t, models.EventTodoDeleted, deleted.Type)
+	assert.JSONEq(t, `{"id":5}`, string(deleted.Data))
+}
+
+func TestTodoService_NotifyOverdueTodos(t *testing.T) {
+	mockRepo := new(mockTodoRepo)
+	service := NewTodoService(mockRepo)
+	bus := eventbus.NewMemory()
+	service.Events = bus
+	ctx := context.Background()
+
+	var events []models.Event
+	bus.Subscribe(func(event models.Event) { events = append(events, event) })
+
+	due := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	mockRepo.On("ClaimOverdueTodos", ctx, mock.Anything, mock.Anything, overdueBatchSize).
+		Return([]models.OverdueTodo{{ID: 3, UserID: 1, Title: "Pay rent", DueDate: due}, {ID: 4, UserID: 2, Title: "Call mom", DueDate: due}}, nil)
+
+	n, err := service.NotifyOverdueTodos(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// Todos overdue for too long are not reported
+	since, now := mockRepo.Calls[0].Arguments.Get(1).(time.Time), mockRepo.Calls[0].Arguments.Get(2).(time.Time)
+	assert.Equal(t, MaxReminderLateness, now.Sub(since))
+
+	require.Len(t, events, 2)
+	assert.Equal(t, models.EventTodoOverdue, events[0].Type)
+	assert.Equal(t, 1, events[0].UserID)
+	assert.JSONEq(t, `{"id":3,"title":"Pay rent","due_date":"2024-05-15T09:00:00Z"}`, string(events[0].Data))
+	assert.Equal(t, 2, events[1].UserID)
 }
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/totp"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// mfaChallengeTTL is how long the second login step may take.
+	mfaChallengeTTL = 5 * time.Minute
+	// recoveryCodeCount is how many recovery codes are issued at once.
+	recoveryCodeCount = 10
+	// totpSkew is how many time steps of clock drift are tolerated.
+	totpSkew = 1
+	// qrCodeSize is the width and height of enrolment QR codes in pixels.
+	qrCodeSize = 256
+)
+
+var (
+	errInvalidMFAToken = NewUnauthorizedError("invalid_mfa_token", "login challenge is invalid or has expired")
+	errInvalidMFACode  = NewUnauthorizedError("invalid_mfa_code", "invalid authentication code")
+	errTOTPNotEnabled  = NewConflictError("totp_not_enabled", "two-factor authentication is not enabled")
+)
+
+// TwoFactorService manages TOTP two-factor authentication and recovery codes.
+type TwoFactorService struct {
+	Repo     repositories.TwoFactorRepoInterface
+	UserRepo repositories.UserRepoInterface
+	// Issuer is shown next to the account in authenticator apps.
+	Issuer string
+	// Audit records rejected codes. It is optional.
+	Audit AuditRecorder
+
+	now func() time.Time
+}
+
+// NewTwoFactorService initializes a new TwoFactorService.
+func NewTwoFactorService(repo repositories.TwoFactorRepoInterface, userRepo repositories.UserRepoInterface, issuer string) *TwoFactorService {
+	return &TwoFactorService{Repo: repo, UserRepo: userRepo, Issuer: issuer, now: time.Now}
+}
+
+// Status describes the two-factor setup of the user.
+func (s *TwoFactorService) Status(ctx context.Context, userID int) (*models.TwoFactorStatus, error) {
+	status := &models.TwoFactorStatus{}
+
+	enrolment, err := s.Repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repositories.ErrTOTPNotFound) || (err == nil && enrolment.ConfirmedAt == nil) {
+		return status, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	status.Enabled = true
+	status.EnabledAt = enrolment.ConfirmedAt
+	if status.RecoveryCodesRemaining, err = s.Repo.CountRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// IsEnabled reports whether logins of the user need a second factor.
+func (s *TwoFactorService) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	enrolment, err := s.Repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repositories.ErrTOTPNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return enrolment.ConfirmedAt != nil, nil
+}
+
+// BeginEnrollment generates a new secret for the user. Two-factor
+// authentication is only enabled once a code for it has been confirmed.
+func (s *TwoFactorService) BeginEnrollment(ctx context.Context, userID int) (*models.TOTPEnrollment, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, userRepoError(err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	err = s.Repo.SavePendingTOTP(ctx, userID, secret)
+	if errors.Is(err, repositories.ErrTOTPAlreadyEnabled) {
+		return nil, NewConflictError("totp_already_enabled", "two-factor authentication is already enabled")
+	} else if err != nil {
+		return nil, err
+	}
+
+	uri := totp.URI(s.Issuer, user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render qr code: %w", err)
+	}
+	return &models.TOTPEnrollment{Secret: secret, URI: uri, QRCode: png}, nil
+}
+
+// ConfirmEnrollment enables two-factor authentication once the user proves
+// their app works by entering a code. It returns a fresh set of recovery codes.
+func (s *TwoFactorService) ConfirmEnrollment(ctx context.Context, userID int, code string) ([]string, error) {
+	enrolment, err := s.Repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repositories.ErrTOTPNotFound) {
+		return nil, NewConflictError("totp_not_started", "two-factor enrolment has not been started")
+	} else if err != nil {
+		return nil, err
+	}
+	if enrolment.ConfirmedAt != nil {
+		return nil, NewConflictError("totp_already_enabled", "two-factor authentication is already enabled")
+	}
+
+	step, ok := totp.Validate(enrolment.Secret, code, s.now(), totpSkew)
+	if !ok {
+		return nil, NewValidationError("invalid authentication code", FieldError{Field: "code", Message: "is invalid"})
+	}
+	if err := s.Repo.ConfirmTOTP(ctx, userID, step); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user after
+// checking their password.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, password string) ([]string, error) {
+	if _, err := verifyUserPassword(ctx, s.UserRepo, userID, "password", password); err != nil {
+		return nil, err
+	}
+	if enabled, err := s.IsEnabled(ctx, userID); err != nil {
+		return nil, err
+	} else if !enabled {
+		return nil, errTOTPNotEnabled
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+// Disable turns off two-factor authentication after checking the password.
+func (s *TwoFactorService) Disable(ctx context.Context, userID int, password string) error {
+	if _, err := verifyUserPassword(ctx, s.UserRepo, userID, "password", password); err != nil {
+		return err
+	}
+	return s.Repo.DeleteTwoFactor(ctx, userID)
+}
+
+// Reset turns off two-factor authentication of a user who lost their
+// device. It is meant for admins.
+func (s *TwoFactorService) Reset(ctx context.Context, userID int) error {
+	if _, err := s.UserRepo.GetUserByID(ctx, userID); err != nil {
+		return userRepoError(err)
+	}
+	return s.Repo.DeleteTwoFactor(ctx, userID)
+}
+
+// NewChallenge returns a short-lived token proving that the user passed the
+// password step of a login.
+func (s *TwoFactorService) NewChallenge(ctx context.Context, userID int) (string, error) {
+	return utils.GenerateToken(models.Token{UserID: userID, Purpose: models.TokenPurposeMFA, Exp: s.now().Add(mfaChallengeTTL)})
+}
+
+// ParseChallenge returns the user ID of a token created by NewChallenge.
+func (s *TwoFactorService) ParseChallenge(challenge string) (int, error) {
+	token, err := utils.ValidateToken(challenge)
+	if err != nil || token.Purpose != models.TokenPurposeMFA {
+		return 0, errInvalidMFAToken
+	}
+	return token.UserID, nil
+}
+
+// Verify checks a TOTP code or an unused recovery code of the user. Each
+// code is accepted only once.
+func (s *TwoFactorService) Verify(ctx context.Context, userID int, code string) error {
+	err := s.verify(ctx, userID, code)
+	if err == errInvalidMFACode {
+		recordAudit(ctx, s.Audit, &models.AuditEntry{
+			Action:     models.AuditLoginFailed,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(userID),
+			Metadata:   auditMetadata(map[string]interface{}{"reason": errInvalidMFACode.Code}),
+		})
+	}
+	return err
+}
+
+func (s *TwoFactorService) verify(ctx context.Context, userID int, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(ctx, userID, code)
+	}
+
+	err := s.Repo.UseRecoveryCode(ctx, userID, utils.HashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, repositories.ErrRecoveryCodeNotFound) {
+		return errInvalidMFACode
+	}
+	return err
+}
+
+func (s *TwoFactorService) verifyTOTP(ctx context.Context, userID int, code string) error {
+	enrolment, err := s.Repo.GetTOTP(ctx, userID)
+	if errors.Is(err, repositories.ErrTOTPNotFound) {
+		return errInvalidMFACode
+	} else if err != nil {
+		return err
+	}
+	if enrolment.ConfirmedAt == nil {
+		return errInvalidMFACode
+	}
+
+	step, ok := totp.Validate(enrolment.Secret, code, s.now(), totpSkew)
+	if !ok {
+		return errInvalidMFACode
+	}
+	err = s.Repo.UseTOTPStep(ctx, userID, step)
+	if errors.Is(err, repositories.ErrTOTPStepUsed) {
+		return errInvalidMFACode
+	}
+	return err
+}
+
+// issueRecoveryCodes replaces the recovery codes of the user and returns
+// the new ones. Only their hashes are stored.
+func (s *TwoFactorService) issueRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = utils.HashToken(normalizeRecoveryCode(code))
+	}
+
+	if err := s.Repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code formatted as xxxxx-xxxxx.
+func generateRecoveryCode() (string, error) {
+	random := make([]byte, 7)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("error generating recovery code: %w", err)
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(random))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes so that codes can
+// be typed the way they are read.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+var _ TwoFactorServiceInterface = (*TwoFactorService)(nil)
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/totp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockTwoFactorRepo is a mock implementation of repositories.TwoFactorRepoInterface.
+type mockTwoFactorRepo struct {
+	mock.Mock
+}
+
+func (m *mockTwoFactorRepo) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.TOTP), args.Error(1)
+}
+
+func (m *mockTwoFactorRepo) SavePendingTOTP(ctx context.Context, userID int, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorRepo) ConfirmTOTP(ctx context.Context, userID int, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorRepo) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorRepo) DeleteTwoFactor(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	args := m.Called(ctx, userID, codeHashes)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorRepo) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Error(0)
+}
+
+func (m *mockTwoFactorRepo) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+// newTestTwoFactorService returns a TwoFactorService whose clock is fixed at now.
+func newTestTwoFactorService(repo *mockTwoFactorRepo, userRepo *mockUserRepo, now time.Time) *TwoFactorService {
+	service := NewTwoFactorService(repo, userRepo, "Todo App")
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestTwoFactorService_BeginEnrollment(t *testing.T) {
+	repo := new(mockTwoFactorRepo)
+	userRepo := new(mockUserRepo)
+	service := newTestTwoFactorService(repo, userRepo, time.Now())
+
+	userRepo.On("GetUserByID", mock.Anything, 1).Return(&models.User{ID: 1, Email: "john@example.com"}, nil)
+	repo.On("SavePendingTOTP", mock.Anything, 1, mock.AnythingOfType("string")).Return(nil)
+
+	enrollment, err := service.BeginEnrollment(context.Background(), 1)
+	require.NoError(t, err)
+
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+	assert.Contains(t, enrollment.URI, "john@example.com")
+	assert.Equal(t, []byte("\x89PNG"), enrollment.QRCode[:4])
+	repo.AssertCalled(t, "SavePendingTOTP", mock.Anything, 1, enrollment.Secret)
+}
+
+func TestTwoFactorService_BeginEnrollment_AlreadyEnabled(t *testing.T) {
+	repo := new(mockTwoFactorRepo)
+	userRepo := new(mockUserRepo)
+	service := newTestTwoFactorService(repo, userRepo, time.Now())
+
+	userRepo.On("GetUserByID", mock.Anything, 1).Return(&models.User{ID: 1, Email: "john@example.com"}, nil)
+	repo.On("SavePendingTOTP", mock.Anything, 1, mock.Anything).Return(repositories.ErrTOTPAlreadyEnabled)
+
+	_, err := service.BeginEnrollment(context.Background(), 1)
+	assert.Equal(t, KindConflict, KindOf(err))
+}
+
+func TestTwoFactorService_ConfirmEnrollment(t *testing.T) {
+	repo := new(mockTwoFactorRepo)
+	now := time.Unix(1700000000, 0)
+	service := newTestTwoFactorService(repo, nil, now)
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	code, err := totp.Code(secret, totp.Step(now))
+	require.NoError(t, err)
+
+	var hashes []string
+	repo.On("GetTOTP", mock.Anything, 1).Return(&models.TOTP{UserID: 1, Secret: secret}, nil)
+	repo.On("ConfirmTOTP", mock.Anything, 1, totp.Step(now)).Return(nil)
+	repo.On("ReplaceRecoveryCodes", mock.Anything, 1, mock.Anything).
+		Run(func(args mock.Arguments) { hashes = args.Get(2).([]string) }).
+		Return(nil)
+
+	_, err = service.ConfirmEnrollment(context.Background(), 1, "000000")
+	assert.Equal(t, KindValidation, KindOf(err))
+
+	codes, err := service.ConfirmEnrollment(context.Background(), 1, code)
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+	assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, codes[0])
+	assert.Equal(t, utils.HashToken(normalizeRecoveryCode(codes[0])), hashes[0])
+	assert.NotContains(t, hashes, codes[0])
+}
+
+func TestTwoFactorService_Verify_TOTP(t *testing.T) {
+	repo := new(mockTwoFactorRepo)
+	now := time.Unix(1700000000, 0)
+	service := newTestTwoFactorService(repo, nil, now)
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	code, err := totp.Code(secret, totp.Step(now))
+	require.NoError(t, err)
+
+	confirmedAt := now.Add(-time.Hour)
+	repo.On("GetTOTP", mock.Anything, 1).Return(&models.TOTP{UserID: 1, Secret: secret, ConfirmedAt: &confirmedAt}, nil)
+	repo.On("UseTOTPStep", mock.Anything, 1, totp.Step(now)).Return(nil).Once()
+	repo.On("UseTOTPStep", mock.Anything, 1, totp.Step(now)).Return(repositories.ErrTOTPStepUsed)
+
+	assert.NoError(t, service.Verify(context.Background(), 1, code))
+	assert.Equal(t, KindUnauthorized, KindOf(service.Verify(context.Background(), 1, code)), "codes must not be replayed")
+}
+
+func TestTwoFactorService_Verify_RecoveryCode(t *testing.T) {
+	repo := new(mockTwoFactorRepo)
+	service := newTestTwoFactorService(repo, nil, time.Now())
+
+	repo.On("UseRecoveryCode", mock.Anything, 1, utils.HashToken("abcdefghij")).Return(nil)
+	repo.On("UseRecoveryCode", mock.Anything, 1, mock.Anything).Return(repositories.ErrRecoveryCodeNotFound)
+
+	assert.NoError(t, service.Verify(context.Background(), 1, "ABCDE-FGHIJ"))
+	assert.Equal(t, KindUnauthorized, KindOf(service.Verify(context.Background(), 1, "zzzzz-zzzzz")))
+}
+
+func TestTwoFactorService_Challenge(t *testing.T) {
+	setTestConfigEnv(t)
+	service := newTestTwoFactorService(nil, nil, time.Now())
+
+	challenge, err := service.NewChallenge(context.Background(), 7)
+	require.NoError(t, err)
+
+	userID, err := service.ParseChallenge(challenge)
+	require.NoError(t, err)
+	assert.Equal(t, 7, userID)
+
+	// Session tokens cannot stand in for a challenge
+	sessionToken, err := utils.GenerateToken(models.Token{UserID: 7, SessionID: 1, Exp: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = service.ParseChallenge(sessionToken)
+	assert.Equal(t, KindUnauthorized, KindOf(err))
+}
@@ -3,27 +3,75 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
 	"todo_app_backend/internal/app/models"
 	"todo_app_backend/internal/app/repositories"
 	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/password"
 )
 
+var errInvalidCredentials = NewUnauthorizedError("invalid_credentials", "invalid email or password")
+
+var errAccountDeactivated = NewForbiddenError("account_deactivated", "account has been deactivated")
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash returns a valid hash that no user password matches.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.HashPassword("dummy password used for timing equalization")
+	})
+	return dummyHash
+}
+
 // UserService defines methods related to user operations.
 type UserService struct {
 	UserRepo repositories.UserRepoInterface
 	// hashFunc func(string) string
+
+	// RequireVerifiedEmail rejects logins of users who have not verified their email.
+	RequireVerifiedEmail bool
+	// PasswordPolicy decides which passwords new users may choose.
+	PasswordPolicy *password.Policy
+	// Registration decides who may sign up. Nil lets anyone sign up.
+	Registration *Registration
+	// InviteRepo redeems invite codes in invite-only mode.
+	InviteRepo repositories.InviteRepoInterface
+	// Audit records logins, role changes, approvals and deletions. It is optional.
+	Audit AuditRecorder
 }
 
 // NewUserService initializes a new UserService.
 func NewUserService(userRepo repositories.UserRepoInterface) *UserService {
-	return &UserService{UserRepo: userRepo}
+	return &UserService{UserRepo: userRepo, PasswordPolicy: password.DefaultPolicy}
 }
 
-// CreateUser creates a new user with hashed password.
-func (s *UserService) CreateUser(ctx context.Context, name, email, password string) (*models.User, error) {
+// CreateUser creates a new user with hashed password, if the registration
+// mode lets them sign up. inviteCode is only used in invite-only mode, where
+// it is redeemed together with the creation of the account.
+func (s *UserService) CreateUser(ctx context.Context, name, email, password, inviteCode string) (*models.User, error) {
 	// Validate input
-	if name == "" || email == "" || password == "" {
-		return nil, errors.New("name, email, and password are required")
+	var fields []FieldError
+	if name == "" {
+		fields = append(fields, FieldError{Field: "name", Message: "is required"})
+	}
+	if email == "" {
+		fields = append(fields, FieldError{Field: "email", Message: "is required"})
+	}
+	if password == "" {
+		fields = append(fields, FieldError{Field: "password", Message: "is required"})
+	}
+	if len(fields) > 0 {
+		return nil, NewValidationError("name, email, and password are required", fields...)
+	}
+	if err := checkPassword(s.PasswordPolicy, "password", password, name, email); err != nil {
+		return nil, err
 	}
 
 	// Hash the password
@@ -38,10 +86,20 @@ func (s *UserService) CreateUser(ctx context.Context, name, email, password stri
 		Email:    email,
 		Password: hashedPassword,
 	}
+	if err := s.Registration.admit(user, inviteCode != ""); err != nil {
+		return nil, err
+	}
 
 	// Store user in DB
-	if err := s.UserRepo.CreateUser(ctx, user); err != nil {
-		return nil, err
+	if s.Registration.mode() == models.RegistrationInviteOnly {
+		err = s.InviteRepo.CreateInvitedUser(ctx, user, utils.HashToken(inviteCode))
+	} else {
+		err = s.UserRepo.CreateUser(ctx, user)
+	}
+	if errors.Is(err, repositories.ErrInviteNotFound) {
+		return nil, errInvalidInviteCode
+	} else if err != nil {
+		return nil, userRepoError(err)
 	}
 
 	user.Password = ""
@@ -53,22 +111,69 @@ func (s *UserService) CreateUser(ctx context.Context, name, email, password stri
 func (s *UserService) GetUserByID(ctx context.Context, id int) (*models.User, error) {
 	user, err := s.UserRepo.GetUserByID(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, userRepoError(err)
 	}
 	return user, nil
 }
 
 // GetUserByCreds retrieves a user by Email and Password.
+// Unknown emails still cost a password comparison so that response times do
+// not reveal which accounts exist. Rejected attempts are audited.
 func (s *UserService) GetUserByCreds(ctx context.Context, email, password string) (*models.User, error) {
 	user, err := s.UserRepo.GetUserByEmail(ctx, email)
-	if err != nil {
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		utils.CheckPasswordHash(password, dummyPasswordHash())
+		return nil, s.loginFailed(ctx, email, nil, errInvalidCredentials)
+	} else if err != nil {
 		return nil, err
 	}
-	utils.CheckPasswordHash(password, user.Password)
+
+	if !utils.CheckPasswordHash(password, user.Password) {
+		return nil, s.loginFailed(ctx, email, user, errInvalidCredentials)
+	}
+	if user.PendingApproval {
+		return nil, s.loginFailed(ctx, email, user, errAccountPendingApproval)
+	}
+	if s.RequireVerifiedEmail && !user.EmailVerified {
+		return nil, s.loginFailed(ctx, email, user, NewForbiddenError("email_not_verified", "email address has not been verified"))
+	}
+	if !user.IsActive() {
+		return nil, s.loginFailed(ctx, email, user, errAccountDeactivated)
+	}
+	if utils.PasswordNeedsRehash(user.Password) {
+		s.rehashPassword(ctx, user.ID, password)
+	}
 	user.Password = ""
 	return user, nil
 }
 
+// rehashPassword replaces the stored hash of a user's password with one made
+// with the current algorithm and parameters. Failures only delay the
+// upgrade until the next login.
+func (s *UserService) rehashPassword(ctx context.Context, userID int, password string) {
+	hashedPassword, err := utils.HashPassword(password)
+	if err == nil {
+		err = s.UserRepo.UpdatePassword(ctx, userID, hashedPassword)
+	}
+	if err != nil {
+		log.Printf("failed to upgrade password hash of user %d: %v", userID, err)
+	}
+}
+
+// loginFailed audits a rejected login attempt for email and returns err.
+// user is nil for unknown email addresses.
+func (s *UserService) loginFailed(ctx context.Context, email string, user *models.User, err *Error) error {
+	entry := &models.AuditEntry{
+		Action:   models.AuditLoginFailed,
+		Metadata: auditMetadata(map[string]interface{}{"email": email, "reason": err.Code}),
+	}
+	if user != nil {
+		entry.TargetType, entry.TargetID = models.AuditTargetUser, strconv.Itoa(user.ID)
+	}
+	recordAudit(ctx, s.Audit, entry)
+	return err
+}
+
 // GetAllUsers retrieves all users.
 func (s *UserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	return s.UserRepo.GetAllUsers(ctx)
@@ -76,7 +181,161 @@ func (s *UserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
 
 // DeleteUser deletes a user by ID.
 func (s *UserService) DeleteUser(ctx context.Context, id int) error {
-	return s.UserRepo.DeleteUser(ctx, id)
+	// The deleted account is kept in the audit log.
+	var before *models.User
+	if s.Audit != nil {
+		user, err := s.UserRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return userRepoError(err)
+		}
+		before = user
+	}
+
+	if err := s.UserRepo.DeleteUser(ctx, id); err != nil {
+		return userRepoError(err)
+	}
+
+	if before != nil {
+		recordAudit(ctx, s.Audit, &models.AuditEntry{
+			Action:     models.AuditUserDeleted,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(id),
+			Metadata:   auditMetadata(map[string]interface{}{"before": auditUser(before)}),
+		})
+	}
+	return nil
+}
+
+// SetRole makes a user an admin or a regular user. Admins cannot change
+// their own role, so that the last admin cannot lock everyone out.
+func (s *UserService) SetRole(ctx context.Context, adminID int, id int, role string) (*models.User, error) {
+	if role != models.RoleAdmin && role != models.RoleUser {
+		return nil, NewValidationError("invalid role",
+			FieldError{Field: "role", Message: fmt.Sprintf("must be %q or %q", models.RoleAdmin, models.RoleUser)})
+	}
+	if id == adminID {
+		return nil, NewForbiddenError("own_role", "admins cannot change their own role")
+	}
+
+	user, err := s.UserRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, userRepoError(err)
+	}
+	before := auditUser(user)
+	if user.IsAdmin == (role == models.RoleAdmin) {
+		user.Password = ""
+		return user, nil
+	}
+
+	if err := s.UserRepo.SetUserAdmin(ctx, id, role == models.RoleAdmin); err != nil {
+		return nil, userRepoError(err)
+	}
+	user.IsAdmin = role == models.RoleAdmin
+	user.Password = ""
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditUserRoleChange,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(id),
+		Metadata:   auditMetadata(map[string]interface{}{"before": before, "after": auditUser(user)}),
+	})
+	return user, nil
+}
+
+// ListPendingUsers retrieves the users awaiting approval, oldest first.
+func (s *UserService) ListPendingUsers(ctx context.Context) ([]models.User, error) {
+	users, err := s.UserRepo.ListPendingUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].Password = ""
+	}
+	return users, nil
+}
+
+// ApproveUser lets a user awaiting approval sign in.
+func (s *UserService) ApproveUser(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.getPendingUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.ApproveUser(ctx, id); err != nil {
+		return nil, userRepoError(err)
+	}
+	user.PendingApproval = false
+	user.Password = ""
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditUserApproved,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(id),
+		Metadata:   auditMetadata(map[string]interface{}{"user": auditUser(user)}),
+	})
+	return user, nil
+}
+
+// RejectUser deletes the account of a user awaiting approval.
+func (s *UserService) RejectUser(ctx context.Context, id int) error {
+	user, err := s.getPendingUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.UserRepo.DeleteUser(ctx, id); err != nil {
+		return userRepoError(err)
+	}
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditUserRejected,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(id),
+		Metadata:   auditMetadata(map[string]interface{}{"before": auditUser(user)}),
+	})
+	return nil
+}
+
+// getPendingUser retrieves a user who is awaiting approval.
+func (s *UserService) getPendingUser(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, userRepoError(err)
+	}
+	if !user.PendingApproval {
+		return nil, NewConflictError("user_not_pending", "user is not awaiting approval")
+	}
+	return user, nil
+}
+
+// userRepoError translates repository errors into domain errors.
+func userRepoError(err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrUserNotFound):
+		return NewNotFoundError("user_not_found", "user not found")
+	case errors.Is(err, repositories.ErrDuplicateEmail):
+		return NewConflictError("email_taken", "email is already registered")
+	case errors.Is(err, repositories.ErrDuplicateExternalID):
+		return NewConflictError("external_id_taken", "external id is already in use")
+	}
+	return err
+}
+
+// checkPassword reports every rule of policy that value breaks as an error
+// on field. userInputs are the name and email address of the user. A nil
+// policy is the default policy.
+func checkPassword(policy *password.Policy, field, value string, userInputs ...string) error {
+	if policy == nil {
+		policy = password.DefaultPolicy
+	}
+	violations := policy.Check(value, userInputs...)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	fields := make([]FieldError, 0, len(violations))
+	for _, violation := range violations {
+		fields = append(fields, FieldError{Field: field, Message: violation})
+	}
+	return NewValidationError("password does not meet the requirements", fields...)
 }
 
 var _ UserServiceInterface = (*UserService)(nil)
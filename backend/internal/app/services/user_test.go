@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 
 	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+	"todo_app_backend/internal/app/utils"
+	"todo_app_backend/internal/scim"
 )
 
 // mockUserRepo is a mock implementation of repositories.UserRepoInterface.
@@ -41,6 +46,76 @@ func (m *mockUserRepo) GetUserByEmail(ctx context.Context, email string) (*model
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *mockUserRepo) MarkEmailVerified(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) UpdatePassword(ctx context.Context, id int, password string) error {
+	args := m.Called(ctx, id, password)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) UpdateName(ctx context.Context, id int, name string) error {
+	args := m.Called(ctx, id, name)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) UpdateEmail(ctx context.Context, id int, email string) error {
+	args := m.Called(ctx, id, email)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) ScheduleDeletion(ctx context.Context, id int, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) CancelDeletion(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) DeleteScheduledUsers(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockUserRepo) CreateProvisionedUser(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) UpdateUser(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) SetUserActive(ctx context.Context, id int, active bool) error {
+	args := m.Called(ctx, id, active)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) SetUserAdmin(ctx context.Context, id int, admin bool) error {
+	args := m.Called(ctx, id, admin)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) ListPendingUsers(ctx context.Context) ([]models.User, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *mockUserRepo) ApproveUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockUserRepo) SearchUsers(ctx context.Context, filter scim.Filter, offset, limit int) ([]models.User, int, error) {
+	args := m.Called(ctx, filter, offset, limit)
+	return args.Get(0).([]models.User), args.Int(1), args.Error(2)
+}
+
 // TestCreateUser tests the CreateUser method of UserService.
 func TestCreateUser(t *testing.T) {
 	mockRepo := new(mockUserRepo)
@@ -54,10 +129,13 @@ func TestCreateUser(t *testing.T) {
 		expectedError bool // New field for expected errors
 		expectedUser  *models.User
 	}{
-		{"Valid Input", "John Doe", "john@example.com", "password123", false, &models.User{ID: 1, Name: "John Doe", Email: "john@example.com"}},
-		{"Missing Name", "", "john@example.com", "password123", true, nil},
-		{"Missing Email", "John Doe", "", "password123", true, nil},
+		{"Valid Input", "John Doe", "john@example.com", "correct horse battery staple", false, &models.User{ID: 1, Name: "John Doe", Email: "john@example.com"}},
+		{"Missing Name", "", "john@example.com", "correct horse battery staple", true, nil},
+		{"Missing Email", "John Doe", "", "correct horse battery staple", true, nil},
 		{"Missing Password", "John Doe", "john@example.com", "", true, nil},
+		{"Short Password", "John Doe", "john@example.com", "x", true, nil},
+		{"Weak Password", "John Doe", "john@example.com", "password123", true, nil},
+		{"Password Contains Name", "John Doe", "john@example.com", "johnny-be-good-1987", true, nil},
 	}
 
 	for _, tt := range tests {
@@ -66,7 +144,7 @@ func TestCreateUser(t *testing.T) {
 				mockRepo.On("CreateUser", mock.Anything, mock.Anything).Return(nil).Once()
 			}
 
-			user, err := userService.CreateUser(context.Background(), tt.inputName, tt.inputEmail, tt.inputPassword)
+			user, err := userService.CreateUser(context.Background(), tt.inputName, tt.inputEmail, tt.inputPassword, "")
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -179,3 +257,159 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+// TestGetUserByCreds tests the GetUserByCreds method of UserService.
+func TestGetUserByCreds(t *testing.T) {
+	mockRepo := new(mockUserRepo)
+	userService := NewUserService(mockRepo)
+
+	hash, err := utils.HashPassword("password123")
+	assert.NoError(t, err)
+
+	mockRepo.On("GetUserByEmail", mock.Anything, "john@example.com").Return(&models.User{ID: 1, Email: "john@example.com", Password: hash}, nil)
+	mockRepo.On("GetUserByEmail", mock.Anything, "nobody@example.com").Return((*models.User)(nil), repositories.ErrUserNotFound)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  bool
+	}{
+		{"Valid Credentials", "john@example.com", "password123", false},
+		{"Wrong Password", "john@example.com", "password124", true},
+		{"Unknown Email", "nobody@example.com", "password123", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := userService.GetUserByCreds(context.Background(), tt.email, tt.password)
+			if tt.wantErr {
+				assert.Nil(t, user)
+				assert.Equal(t, KindUnauthorized, KindOf(err))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, 1, user.ID)
+				assert.Empty(t, user.Password)
+			}
+		})
+	}
+}
+
+// TestGetUserByCredsDeactivated tests that deactivated users cannot log in.
+func TestGetUserByCredsDeactivated(t *testing.T) {
+	mockRepo := new(mockUserRepo)
+	userService := NewUserService(mockRepo)
+
+	hash, err := utils.HashPassword("password123")
+	assert.NoError(t, err)
+
+	deactivatedAt := time.Now()
+	mockRepo.On("GetUserByEmail", mock.Anything, "john@example.com").
+		Return(&models.User{ID: 1, Email: "john@example.com", Password: hash, DeactivatedAt: &deactivatedAt}, nil)
+
+	_, err = userService.GetUserByCreds(context.Background(), "john@example.com", "password123")
+	assert.Equal(t, KindForbidden, KindOf(err))
+	assert.Equal(t, "account_deactivated", err.(*Error).Code)
+
+	// The password is checked first so that deactivation is not revealed to strangers.
+	_, err = userService.GetUserByCreds(context.Background(), "john@example.com", "wrong")
+	assert.Equal(t, KindUnauthorized, KindOf(err))
+}
+
+// TestGetUserByCredsRehashesLegacyPassword tests that bcrypt hashes are
+// upgraded on login.
+func TestGetUserByCredsRehashesLegacyPassword(t *testing.T) {
+	mockRepo := new(mockUserRepo)
+	userService := NewUserService(mockRepo)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	mockRepo.On("GetUserByEmail", mock.Anything, "john@example.com").
+		Return(&models.User{ID: 1, Email: "john@example.com", Password: string(legacy)}, nil)
+	mockRepo.On("UpdatePassword", mock.Anything, 1, mock.MatchedBy(func(hash string) bool {
+		return utils.CheckPasswordHash("password123", hash) && !utils.PasswordNeedsRehash(hash)
+	})).Return(nil)
+
+	user, err := userService.GetUserByCreds(context.Background(), "john@example.com", "password123")
+	assert.NoError(t, err)
+	assert.Empty(t, user.Password)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestGetUserByCredsAuditsFailures tests that rejected logins are audited.
+func TestGetUserByCredsAuditsFailures(t *testing.T) {
+	mockRepo := new(mockUserRepo)
+	userService := NewUserService(mockRepo)
+	audit, entries := recordedAudit()
+	userService.Audit = audit
+
+	hash, err := utils.HashPassword("password123")
+	assert.NoError(t, err)
+	mockRepo.On("GetUserByEmail", mock.Anything, "john@example.com").Return(&models.User{ID: 1, Email: "john@example.com", Password: hash}, nil)
+	mockRepo.On("GetUserByEmail", mock.Anything, "nobody@example.com").Return((*models.User)(nil), repositories.ErrUserNotFound)
+
+	_, err = userService.GetUserByCreds(context.Background(), "john@example.com", "wrong")
+	assert.Error(t, err)
+	_, err = userService.GetUserByCreds(context.Background(), "nobody@example.com", "wrong")
+	assert.Error(t, err)
+	_, err = userService.GetUserByCreds(context.Background(), "john@example.com", "password123")
+	assert.NoError(t, err)
+
+	assert.Len(t, *entries, 2)
+	assert.Equal(t, models.AuditLoginFailed, (*entries)[0].Action)
+	assert.Equal(t, "1", (*entries)[0].TargetID)
+	assert.JSONEq(t, `{"email":"john@example.com","reason":"invalid_credentials"}`, string((*entries)[0].Metadata))
+	assert.Empty(t, (*entries)[1].TargetID)
+}
+
+// TestDeleteUserAudited tests that deletions record the deleted account.
+func TestDeleteUserAudited(t *testing.T) {
+	mockRepo := new(mockUserRepo)
+	userService := NewUserService(mockRepo)
+	audit, entries := recordedAudit()
+	userService.Audit = audit
+
+	mockRepo.On("GetUserByID", mock.Anything, 7).Return(&models.User{ID: 7, Name: "Jane", Email: "jane@example.com"}, nil)
+	mockRepo.On("DeleteUser", mock.Anything, 7).Return(nil)
+
+	ctx := context.WithValue(context.Background(), "userID", 1)
+	assert.NoError(t, userService.DeleteUser(ctx, 7))
+
+	assert.Len(t, *entries, 1)
+	entry := (*entries)[0]
+	assert.Equal(t, models.AuditUserDeleted, entry.Action)
+	assert.Equal(t, 1, *entry.ActorID)
+	assert.JSONEq(t, `{"before":{"name":"Jane","email":"jane@example.com","is_admin":false}}`, string(entry.Metadata))
+}
+
+// TestSetRole tests granting and revoking the admin role.
+func TestSetRole(t *testing.T) {
+	mockRepo := new(mockUserRepo)
+	userService := NewUserService(mockRepo)
+	audit, entries := recordedAudit()
+	userService.Audit = audit
+
+	mockRepo.On("GetUserByID", mock.Anything, 7).Return(&models.User{ID: 7, Name: "Jane", Email: "jane@example.com"}, nil)
+	mockRepo.On("SetUserAdmin", mock.Anything, 7, true).Return(nil).Once()
+
+	user, err := userService.SetRole(context.Background(), 1, 7, models.RoleAdmin)
+	assert.NoError(t, err)
+	assert.True(t, user.IsAdmin)
+	assert.Len(t, *entries, 1)
+	assert.Equal(t, models.AuditUserRoleChange, (*entries)[0].Action)
+	assert.JSONEq(t, `{"before":{"name":"Jane","email":"jane@example.com","is_admin":false},"after":{"name":"Jane","email":"jane@example.com","is_admin":true}}`,
+		string((*entries)[0].Metadata))
+
+	// Setting the current role changes nothing
+	mockRepo.On("GetUserByID", mock.Anything, 8).Return(&models.User{ID: 8, Name: "John", Email: "john@example.com"}, nil)
+	_, err = userService.SetRole(context.Background(), 1, 8, models.RoleUser)
+	assert.NoError(t, err)
+	assert.Len(t, *entries, 1)
+
+	_, err = userService.SetRole(context.Background(), 1, 7, "owner")
+	assert.Equal(t, KindValidation, KindOf(err))
+	_, err = userService.SetRole(context.Background(), 1, 1, models.RoleUser)
+	assert.Equal(t, KindForbidden, KindOf(err))
+	mockRepo.AssertExpectations(t)
+}
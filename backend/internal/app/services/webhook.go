@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+)
+
+const (
+	// DefaultWebhookDeliveryPageSize is the number of deliveries listed
+	// when the client does not ask for a number.
+	DefaultWebhookDeliveryPageSize = 50
+	// MaxWebhookDeliveryPageSize caps the number of deliveries listed at once.
+	MaxWebhookDeliveryPageSize = 200
+	// minWebhookSecretLength is the shortest secret users may choose.
+	minWebhookSecretLength = 16
+)
+
+// Headers of webhook deliveries. The signature is the hex encoded
+// HMAC-SHA256 of the timestamp, a dot and the body, keyed with the secret
+// of the webhook and prefixed with "sha256=".
+const (
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// SignWebhook returns the signature of a webhook body sent at timestamp,
+// in Unix seconds.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookPayload is the body of webhook deliveries. ID identifies the event
+// so that receivers can ignore redeliveries.
+type webhookPayload struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// WebhookService manages the outgoing webhooks of users and queues their
+// deliveries. It is an EventPublisher.
+type WebhookService struct {
+	Repo repositories.WebhookRepoInterface
+	// Audit records created and deleted webhooks. It is optional.
+	Audit AuditRecorder
+	// AllowPrivateNetworks accepts URLs with loopback, private and
+	// link-local addresses. It must match the webhook client.
+	AllowPrivateNetworks bool
+}
+
+// NewWebhookService initializes a new WebhookService.
+func NewWebhookService(repo repositories.WebhookRepoInterface) *WebhookService {
+	return &WebhookService{Repo: repo}
+}
+
+// CreateWebhook adds a webhook sending the events of eventTypes to rawURL.
+// A secret is generated when none is given. The webhook is returned with
+// its secret, which is not revealed again.
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID int, rawURL string, eventTypes []string, secret string) (*models.Webhook, string, error) {
+	eventTypes, err := validateWebhook(rawURL, eventTypes, secret, s.AllowPrivateNetworks)
+	if err != nil {
+		return nil, "", err
+	}
+	if secret == "" {
+		if secret, err = generateToken(models.WebhookSecretPrefix); err != nil {
+			return nil, "", err
+		}
+	}
+
+	webhook := &models.Webhook{UserID: userID, URL: rawURL, Secret: secret, EventTypes: eventTypes, Active: true}
+	if err := s.Repo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, "", err
+	}
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditWebhookCreated,
+		TargetType: models.AuditTargetWebhook,
+		TargetID:   strconv.Itoa(webhook.ID),
+		Metadata:   auditMetadata(map[string]interface{}{"url": webhook.URL, "event_types": webhook.EventTypes}),
+	})
+	return webhook, secret, nil
+}
+
+// ListWebhooks retrieves the webhooks of a user.
+func (s *WebhookService) ListWebhooks(ctx context.Context, userID int) ([]models.Webhook, error) {
+	return s.Repo.ListWebhooks(ctx, userID)
+}
+
+// GetWebhook retrieves a webhook of a user.
+func (s *WebhookService) GetWebhook(ctx context.Context, userID int, id int) (*models.Webhook, error) {
+	webhook, err := s.Repo.GetWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, webhookRepoError(err)
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook changes the URL and event types of a webhook, and its
+// secret if one is given. active, if not nil, enables or disables the
+// webhook; enabling a webhook disabled after failures resets its failure
+// count.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, userID int, id int, rawURL string, eventTypes []string, secret string, active *bool) (*models.Webhook, error) {
+	eventTypes, err := validateWebhook(rawURL, eventTypes, secret, s.AllowPrivateNetworks)
+	if err != nil {
+		return nil, err
+	}
+	webhook, err := s.GetWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.URL, webhook.EventTypes = rawURL, eventTypes
+	if secret != "" {
+		webhook.Secret = secret
+	}
+	if active != nil && *active != webhook.Active {
+		webhook.Active = *active
+		webhook.FailureCount, webhook.DisabledAt = 0, nil
+	}
+	if err := s.Repo.UpdateWebhook(ctx, webhook); err != nil {
+		return nil, webhookRepoError(err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook of a user and its deliveries.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userID int, id int) error {
+	if err := s.Repo.DeleteWebhook(ctx, userID, id); err != nil {
+		return webhookRepoError(err)
+	}
+
+	recordAudit(ctx, s.Audit, &models.AuditEntry{
+		Action:     models.AuditWebhookDeleted,
+		TargetType: models.AuditTargetWebhook,
+		TargetID:   strconv.Itoa(id),
+	})
+	return nil
+}
+
+// ListDeliveries retrieves up to limit deliveries of a webhook of a user
+// older than beforeID, newest first.
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID int, webhookID int, beforeID int64, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, userID, webhookID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultWebhookDeliveryPageSize
+	} else if limit > MaxWebhookDeliveryPageSize {
+		limit = MaxWebhookDeliveryPageSize
+	}
+	return s.Repo.ListDeliveries(ctx, webhookID, beforeID, limit)
+}
+
+// Redeliver queues the payload of a past delivery of a webhook of a user
+// again. The new delivery is returned.
+func (s *WebhookService) Redeliver(ctx context.Context, userID int, webhookID int, deliveryID int64) (*models.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, userID, webhookID); err != nil {
+		return nil, err
+	}
+	delivery, err := s.Repo.Redeliver(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, webhookRepoError(err)
+	}
+	return delivery, nil
+}
+
+// Publish queues event for the active webhooks of its user that subscribe
+// to its type.
+func (s *WebhookService) Publish(ctx context.Context, event *models.Event) error {
+	if !containsString(models.WebhookEventTypes, event.Type) {
+		return nil
+	}
+	id, err := generateToken("evt_")
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(webhookPayload{ID: id, Type: event.Type, CreatedAt: time.Now().UTC(), Data: event.Data})
+	if err != nil {
+		return err
+	}
+	_, err = s.Repo.EnqueueDeliveries(ctx, event.UserID, event.Type, payload)
+	return err
+}
+
+// validateWebhook checks the settings of a webhook and returns its event
+// types without duplicates. Unless allowPrivate is set, URLs naming a
+// non-public address are rejected up front; host names resolving to one
+// are refused by the webhook client when delivering.
+func validateWebhook(rawURL string, eventTypes []string, secret string, allowPrivate bool) ([]string, error) {
+	var fields []FieldError
+	if rawURL == "" {
+		fields = append(fields, FieldError{Field: "url", Message: "is required"})
+	} else if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		fields = append(fields, FieldError{Field: "url", Message: "must be an http or https URL"})
+	} else if !allowPrivate && !isPublicHost(u.Hostname()) {
+		fields = append(fields, FieldError{Field: "url", Message: "must not point to a private address"})
+	}
+	if len(eventTypes) == 0 {
+		fields = append(fields, FieldError{Field: "event_types", Message: "is required"})
+	}
+	for _, eventType := range eventTypes {
+		if !containsString(models.WebhookEventTypes, eventType) {
+			fields = append(fields, FieldError{Field: "event_types", Message: fmt.Sprintf("unknown event type %q", eventType)})
+		}
+	}
+	if secret != "" && len(secret) < minWebhookSecretLength {
+		fields = append(fields, FieldError{Field: "secret", Message: fmt.Sprintf("must be at least %d characters", minWebhookSecretLength)})
+	}
+	if len(fields) > 0 {
+		return nil, NewValidationError("invalid webhook", fields...)
+	}
+	return dedupeStrings(eventTypes), nil
+}
+
+// isPublicHost reports whether host may be public: it is a host name other
+// than localhost, or a public IP address.
+func isPublicHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return isPublicIP(ip)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost")
+}
+
+// webhookRepoError translates repository errors into domain errors.
+func webhookRepoError(err error) error {
+	switch {
+	case errors.Is(err, repositories.ErrWebhookNotFound):
+		return NewNotFoundError("webhook_not_found", "webhook not found")
+	case errors.Is(err, repositories.ErrWebhookDeliveryNotFound):
+		return NewNotFoundError("webhook_delivery_not_found", "webhook delivery not found")
+	}
+	return err
+}
+
+var _ WebhookServiceInterface = (*WebhookService)(nil)
+var _ EventPublisher = (*WebhookService)(nil)
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+)
+
+const (
+	// DefaultWebhookMaxAttempts is the number of times a delivery is
+	// attempted before it is marked as failed.
+	DefaultWebhookMaxAttempts = 10
+	// DefaultWebhookDisableAfter is the number of failed attempts in a row
+	// after which a webhook is disabled.
+	DefaultWebhookDisableAfter = 25
+	// webhookTimeout is how long a receiver has to answer.
+	webhookTimeout = 10 * time.Second
+	// webhookResponseLimit is the number of bytes of responses kept in the
+	// delivery log.
+	webhookResponseLimit = 1024
+)
+
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// NewWebhookClient returns the HTTP client deliveries are sent with.
+// Redirects are not followed, and unless allowPrivate is set, receivers on
+// loopback, private and link-local addresses are refused so that webhooks
+// cannot reach internal services.
+func NewWebhookClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicIP(net.ParseIP(host)) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		Timeout:   webhookTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// nonPublicNetworks are the special-purpose ranges that the checks of net.IP
+// leave out: shared (carrier-grade NAT), protocol assignment, benchmarking,
+// documentation, reserved and translation ranges, which may all route to
+// internal hosts.
+var nonPublicNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "192.0.2.0/24", "192.88.99.0/24", "198.18.0.0/15",
+		"198.51.100.0/24", "203.0.113.0/24", "240.0.0.0/4",
+		"64:ff9b::/96", "64:ff9b:1::/48", "100::/64", "2001::/23", "2001:db8::/32", "2002::/16",
+	} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+// isPublicIP reports whether ip is an address webhooks may be sent to.
+func isPublicIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// WebhookDispatcher sends the queued webhook deliveries. Several instances
+// can run at once: each delivery is claimed by one of them.
+type WebhookDispatcher struct {
+	Repo   repositories.WebhookRepoInterface
+	Client *http.Client
+
+	MaxAttempts  int
+	DisableAfter int
+	BatchSize    int
+	PollInterval time.Duration
+	// Retries wait RetryBackoff after the first failed attempt, twice as
+	// long after the second and so on, up to MaxRetryBackoff.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+}
+
+// NewWebhookDispatcher initializes a new WebhookDispatcher.
+func NewWebhookDispatcher(repo repositories.WebhookRepoInterface, client *http.Client) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		Repo:            repo,
+		Client:          client,
+		MaxAttempts:     DefaultWebhookMaxAttempts,
+		DisableAfter:    DefaultWebhookDisableAfter,
+		BatchSize:       20,
+		PollInterval:    2 * time.Second,
+		RetryBackoff:    30 * time.Second,
+		MaxRetryBackoff: 6 * time.Hour,
+	}
+}
+
+// Run sends due deliveries until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := d.DispatchPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to dispatch webhook deliveries: %v", err)
+		}
+		// Full batches are followed right away by the next one
+		if err == nil && n == d.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending sends a batch of due deliveries and returns its size.
+func (d *WebhookDispatcher) DispatchPending(ctx context.Context) (int, error) {
+	// Deliveries still running when the lease ends are sent again
+	deliveries, err := d.Repo.ClaimDeliveries(ctx, d.BatchSize, d.Client.Timeout+time.Minute)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// deliver attempts to send delivery and records the outcome, scheduling a
+// retry if it failed.
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	now := time.Now()
+	status, body, err := d.send(ctx, delivery, now)
+	if ctx.Err() != nil {
+		// Interrupted attempts are retried once the lease ends
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus, delivery.ResponseBody, delivery.Error = nil, body, ""
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		delivery.Status, delivery.NextAttemptAt = models.WebhookDeliverySucceeded, nil
+	case delivery.Attempts >= d.MaxAttempts:
+		delivery.Status, delivery.NextAttemptAt = models.WebhookDeliveryFailed, nil
+	default:
+		next := now.Add(d.backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	} else if delivery.Status != models.WebhookDeliverySucceeded {
+		delivery.Error = fmt.Sprintf("unexpected response status %d", status)
+	}
+
+	disabled, err := d.Repo.CompleteDelivery(ctx, delivery, d.DisableAfter)
+	if errors.Is(err, repositories.ErrWebhookDeliveryLost) {
+		// The attempt outlived its lease and is recorded by the instance
+		// that claimed the delivery next
+		log.Printf("Webhook delivery %d was claimed again before its attempt was recorded", delivery.ID)
+	} else if err != nil {
+		log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+	} else if disabled {
+		log.Printf("Disabled webhook %d after %d failed deliveries in a row", delivery.WebhookID, d.DisableAfter)
+	}
+}
+
+// send posts the payload of delivery, signed at now, and returns the status
+// and the beginning of the body of the response.
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *models.WebhookDelivery, now time.Time) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TodoApp-Webhooks/1.0")
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	// The log is stored as text
+	text := strings.ReplaceAll(strings.ToValidUTF8(string(body), "\uFFFD"), "\x00", "")
+	return resp.StatusCode, text, nil
+}
+
+// backoff returns how long to wait before the next attempt after the given
+// number of failed attempts.
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	wait := d.RetryBackoff
+	for i := 1; i < attempts && wait < d.MaxRetryBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.MaxRetryBackoff {
+		wait = d.MaxRetryBackoff
+	}
+	return wait
+}
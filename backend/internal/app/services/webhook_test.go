@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/app/models"
+	"todo_app_backend/internal/app/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockWebhookRepo is a mock implementation of repositories.WebhookRepoInterface.
+type mockWebhookRepo struct {
+	mock.Mock
+}
+
+func (m *mockWebhookRepo) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	args := m.Called(ctx, webhook)
+	return args.Error(0)
+}
+
+func (m *mockWebhookRepo) ListWebhooks(ctx context.Context, userID int) ([]models.Webhook, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *mockWebhookRepo) GetWebhook(ctx context.Context, userID int, id int) (*models.Webhook, error) {
+	args := m.Called(ctx, userID, id)
+	webhook, _ := args.Get(0).(*models.Webhook)
+	return webhook, args.Error(1)
+}
+
+func (m *mockWebhookRepo) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	args := m.Called(ctx, webhook)
+	return args.Error(0)
+}
+
+func (m *mockWebhookRepo) DeleteWebhook(ctx context.Context, userID int, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *mockWebhookRepo) EnqueueDeliveries(ctx context.Context, userID int, eventType string, payload []byte) (int64, error) {
+	args := m.Called(ctx, userID, eventType, payload)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockWebhookRepo) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	args := m.Called(ctx, limit, lease)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *mockWebhookRepo) CompleteDelivery(ctx context.Context, delivery *models.WebhookDelivery, disableAfter int) (bool, error) {
+	args := m.Called(ctx, delivery, disableAfter)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockWebhookRepo) ListDeliveries(ctx context.Context, webhookID int, beforeID int64, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookID, beforeID, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *mockWebhookRepo) Redeliver(ctx context.Context, webhookID int, id int64) (*models.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookID, id)
+	delivery, _ := args.Get(0).(*models.WebhookDelivery)
+	return delivery, args.Error(1)
+}
+
+func TestWebhookService_CreateWebhook(t *testing.T) {
+	repo := new(mockWebhookRepo)
+	service := NewWebhookService(repo)
+
+	repo.On("CreateWebhook", mock.Anything, mock.MatchedBy(func(w *models.Webhook) bool {
+		return w.UserID == 1 && w.Active && strings.HasPrefix(w.Secret, models.WebhookSecretPrefix) &&
+			assert.ObjectsAreEqual([]string{models.EventTodoCreated}, w.EventTypes)
+	})).Return(nil)
+
+	webhook, secret, err := service.CreateWebhook(context.Background(), 1, "https://example.com/hook",
+		[]string{models.EventTodoCreated, models.EventTodoCreated}, "")
+	require.NoError(t, err)
+	assert.Equal(t, webhook.Secret, secret)
+	repo.AssertExpectations(t)
+}
+
+func TestWebhookService_CreateWebhookValidation(t *testing.T) {
+	service := NewWebhookService(new(mockWebhookRepo))
+
+	_, _, err := service.CreateWebhook(context.Background(), 1, "ftp://example.com", []string{"todo.archived"}, "short")
+	var serviceErr *Error
+	require.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, "validation_failed", serviceErr.Code)
+	assert.Len(t, serviceErr.Fields, 3)
+
+	for _, rawURL := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest", "http://[::1]/hook", "http://localhost/hook"} {
+		_, _, err = service.CreateWebhook(context.Background(), 1, rawURL, []string{"todo.created"}, "")
+		require.ErrorAs(t, err, &serviceErr, rawURL)
+		assert.Equal(t, []FieldError{{Field: "url", Message: "must not point to a private address"}}, serviceErr.Fields, rawURL)
+	}
+}
+
+func TestWebhookService_UpdateWebhookReenables(t *testing.T) {
+	repo := new(mockWebhookRepo)
+	service := NewWebhookService(repo)
+
+	disabledAt := time.Now()
+	repo.On("GetWebhook", mock.Anything, 1, 7).Return(&models.Webhook{
+		ID: 7, UserID: 1, Secret: "whsec_old", FailureCount: 25, DisabledAt: &disabledAt,
+	}, nil)
+	repo.On("UpdateWebhook", mock.Anything, mock.Anything).Return(nil)
+
+	active := true
+	webhook, err := service.UpdateWebhook(context.Background(), 1, 7, "https://example.com/new", []string{models.EventTodoDeleted}, "", &active)
+	require.NoError(t, err)
+	assert.True(t, webhook.Active)
+	assert.Zero(t, webhook.FailureCount)
+	assert.Nil(t, webhook.DisabledAt)
+	assert.Equal(t, "whsec_old", webhook.Secret)
+}
+
+func TestWebhookService_RedeliverOtherUser(t *testing.T) {
+	repo := new(mockWebhookRepo)
+	service := NewWebhookService(repo)
+
+	repo.On("GetWebhook", mock.Anything, 2, 7).Return(nil, repositories.ErrWebhookNotFound)
+
+	_, err := service.Redeliver(context.Background(), 2, 7, 3)
+	var serviceErr *Error
+	require.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, "webhook_not_found", serviceErr.Code)
+	repo.AssertNotCalled(t, "Redeliver", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWebhookService_Publish(t *testing.T) {
+	repo := new(mockWebhookRepo)
+	service := NewWebhookService(repo)
+
+	repo.On("EnqueueDeliveries", mock.Anything, 1, models.EventTodoCreated, mock.MatchedBy(func(payload []byte) bool {
+		var body webhookPayload
+		return json.Unmarshal(payload, &body) == nil && strings.HasPrefix(body.ID, "evt_") &&
+			body.Type == models.EventTodoCreated && string(body.Data) == `{"id":5}`
+	})).Return(int64(1), nil)
+
+	err := service.Publish(context.Background(), &models.Event{Type: models.EventTodoCreated, UserID: 1, Data: json.RawMessage(`{"id":5}`)})
+	assert.NoError(t, err)
+
+	// Other events are not sent to webhooks
+	err = service.Publish(context.Background(), &models.Event{Type: models.EventResync, Data: json.RawMessage(`{}`)})
+	assert.NoError(t, err)
+	repo.AssertNumberOfCalls(t, "EnqueueDeliveries", 1)
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"todo.created"}`)
+	statuses := make(chan int, 3)
+	statuses <- http.StatusInternalServerError
+	statuses <- http.StatusOK
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, err := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+		if err != nil || r.Header.Get(WebhookSignatureHeader) != SignWebhook("whsec_secret", timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "3", r.Header.Get(WebhookDeliveryHeader))
+		assert.Equal(t, models.EventTodoCreated, r.Header.Get(WebhookEventHeader))
+		w.WriteHeader(<-statuses)
+		w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+
+	repo := new(mockWebhookRepo)
+	dispatcher := NewWebhookDispatcher(repo, NewWebhookClient(true))
+	delivery := models.WebhookDelivery{
+		ID: 3, WebhookID: 7, EventType: models.EventTodoCreated, Payload: payload,
+		Status: models.WebhookDeliveryPending, URL: receiver.URL, Secret: "whsec_secret",
+	}
+
+	// The first attempt fails and is retried after the backoff
+	repo.On("ClaimDeliveries", mock.Anything, dispatcher.BatchSize, mock.Anything).Return([]models.WebhookDelivery{delivery}, nil).Once()
+	repo.On("CompleteDelivery", mock.Anything, mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.Attempts == 1 && d.Status == models.WebhookDeliveryPending && *d.ResponseStatus == http.StatusInternalServerError &&
+			d.NextAttemptAt != nil && d.NextAttemptAt.Sub(*d.LastAttemptAt) == dispatcher.RetryBackoff
+	}), dispatcher.DisableAfter).Return(false, nil).Once()
+
+	n, err := dispatcher.DispatchPending(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// The second one succeeds
+	delivery.Attempts = 1
+	repo.On("ClaimDeliveries", mock.Anything, dispatcher.BatchSize, mock.Anything).Return([]models.WebhookDelivery{delivery}, nil).Once()
+	repo.On("CompleteDelivery", mock.Anything, mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.Attempts == 2 && d.Status == models.WebhookDeliverySucceeded && d.NextAttemptAt == nil && d.ResponseBody == "ok"
+	}), dispatcher.DisableAfter).Return(false, nil).Once()
+
+	_, err = dispatcher.DispatchPending(context.Background())
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestWebhookDispatcher_GivesUp(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer receiver.Close()
+
+	repo := new(mockWebhookRepo)
+	dispatcher := NewWebhookDispatcher(repo, NewWebhookClient(true))
+	delivery := models.WebhookDelivery{ID: 3, WebhookID: 7, Attempts: dispatcher.MaxAttempts - 1, URL: receiver.URL, Payload: []byte(`{}`)}
+
+	repo.On("ClaimDeliveries", mock.Anything, mock.Anything, mock.Anything).Return([]models.WebhookDelivery{delivery}, nil)
+	repo.On("CompleteDelivery", mock.Anything, mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.Status == models.WebhookDeliveryFailed && d.NextAttemptAt == nil && d.Error == "unexpected response status 410"
+	}), dispatcher.DisableAfter).Return(true, nil)
+
+	_, err := dispatcher.DispatchPending(context.Background())
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestWebhookClient_RefusesPrivateAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	_, err := NewWebhookClient(false).Get(receiver.URL)
+	assert.ErrorIs(t, err, errPrivateAddress)
+}
+
+// nonPublicAddresses are addresses of each kind webhooks must not reach.
+var nonPublicAddresses = []string{
+	"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0", "0.1.2.3",
+	"100.64.0.1", "100.127.255.254", "192.0.0.8", "192.0.2.1", "198.18.0.1", "198.19.255.255",
+	"198.51.100.7", "203.0.113.7", "240.0.0.1", "255.255.255.255", "224.0.0.1",
+	"::1", "::", "fe80::1", "fc00::1", "::ffff:10.0.0.1", "64:ff9b::a00:1", "2001:db8::1", "2002:a00:1::1",
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, addr := range nonPublicAddresses {
+		assert.False(t, isPublicIP(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "100.128.0.1", "198.20.0.1", "2606:4700::1111"} {
+		assert.True(t, isPublicIP(net.ParseIP(addr)), addr)
+	}
+}
+
+func TestWebhookService_CreateWebhookNonPublicAddresses(t *testing.T) {
+	service := NewWebhookService(new(mockWebhookRepo))
+
+	for _, addr := range nonPublicAddresses {
+		t.Run(addr, func(t *testing.T) {
+			host := addr
+			if strings.Contains(addr, ":") {
+				host = "[" + addr + "]"
+			}
+			_, _, err := service.CreateWebhook(context.Background(), 1, "http://"+host+"/hook", []string{"todo.created"}, "")
+			var serviceErr *Error
+			require.ErrorAs(t, err, &serviceErr)
+			assert.Equal(t, []FieldError{{Field: "url", Message: "must not point to a private address"}}, serviceErr.Fields)
+		})
+	}
+}
+
+func TestWebhookClient_RefusesNonPublicAddresses(t *testing.T) {
+	client := NewWebhookClient(false)
+
+	for _, addr := range nonPublicAddresses {
+		if strings.Contains(addr, ":") {
+			// Dialing IPv6 depends on the network of the host; the check
+			// itself is covered by TestIsPublicIP
+			continue
+		}
+		t.Run(addr, func(t *testing.T) {
+			// The address is refused before anything is sent
+			_, err := client.Get("http://" + addr + ":80/hook")
+			assert.ErrorIs(t, err, errPrivateAddress)
+		})
+	}
+}
+
+func TestWebhookDispatcher_Backoff(t *testing.T) {
+	dispatcher := NewWebhookDispatcher(new(mockWebhookRepo), nil)
+	assert.Equal(t, 30*time.Second, dispatcher.backoff(1))
+	assert.Equal(t, 2*time.Minute, dispatcher.backoff(3))
+	assert.Equal(t, 6*time.Hour, dispatcher.backoff(20))
+}
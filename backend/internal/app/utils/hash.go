@@ -10,21 +10,35 @@ import (
 	"time"
 	"todo_app_backend/config"
 	"todo_app_backend/internal/app/models"
-
-	"golang.org/x/crypto/bcrypt"
+	"todo_app_backend/internal/password"
 )
 
 const (
 	saperator byte = '^'
 )
 
+// passwordHasher hashes and verifies all stored passwords.
+var passwordHasher password.Hasher = password.NewArgon2id(password.DefaultParams)
+
+// SetPasswordHasher replaces the hasher used by HashPassword,
+// CheckPasswordHash and PasswordNeedsRehash. It must be called before the
+// server starts.
+func SetPasswordHasher(hasher password.Hasher) {
+	passwordHasher = hasher
+}
+
 func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hash), err
+	return passwordHasher.Hash(password)
 }
 
 func CheckPasswordHash(password, hash string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	return passwordHasher.Verify(password, hash)
+}
+
+// PasswordNeedsRehash reports whether hash should be replaced by a fresh
+// hash of the password the next time it is known.
+func PasswordNeedsRehash(hash string) bool {
+	return passwordHasher.NeedsRehash(hash)
 }
 
 func GenerateToken(tokenDetails models.Token) (string, error) {
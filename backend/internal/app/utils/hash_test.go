@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,8 +18,11 @@ func TestHashPassword(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("password")); err != nil {
-			t.Errorf("Expected nil error, got %v", err)
+		if !strings.HasPrefix(hash, "$argon2id$") {
+			t.Errorf("Expected an argon2id hash, got %q", hash)
+		}
+		if !CheckPasswordHash("password", hash) || PasswordNeedsRehash(hash) {
+			t.Errorf("Expected a current hash of the password")
 		}
 	})
 	t.Run("Test invalid password", func(t *testing.T) {
@@ -26,9 +30,20 @@ func TestHashPassword(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		err = bcrypt.CompareHashAndPassword([]byte(hash), []byte("wrong_password"))
-		if err == nil {
-			t.Errorf("Expected error, got nil")
+		if CheckPasswordHash("wrong_password", hash) {
+			t.Errorf("Expected false, got true")
+		}
+	})
+	t.Run("Test legacy bcrypt hash", func(t *testing.T) {
+		hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !CheckPasswordHash("password", string(hash)) {
+			t.Errorf("Expected true, got false")
+		}
+		if !PasswordNeedsRehash(string(hash)) {
+			t.Errorf("Expected bcrypt hashes to need a rehash")
 		}
 	})
 }
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"todo_app_backend/config"
+)
+
+// GenerateSignedToken returns a random token bound to purpose and signed with
+// the token secret, together with the hash under which it should be stored.
+// The token itself is never stored, only its hash.
+func GenerateSignedToken(purpose string) (token string, hash string, err error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", fmt.Errorf("error generating token: %w", err)
+	}
+
+	encodedNonce := base64.RawURLEncoding.EncodeToString(nonce)
+	signature, err := signTokenNonce(purpose, encodedNonce)
+	if err != nil {
+		return "", "", err
+	}
+
+	token = encodedNonce + "." + signature
+	return token, HashToken(token), nil
+}
+
+// VerifySignedToken checks that token was generated for purpose with the
+// current token secret and returns its storage hash.
+func VerifySignedToken(purpose, token string) (string, error) {
+	nonce, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", errors.New("invalid token format")
+	}
+
+	expected, err := signTokenNonce(purpose, nonce)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", errors.New("invalid token signature")
+	}
+	return HashToken(token), nil
+}
+
+// SignValue returns a token carrying value, bound to purpose and signed with
+// the token secret. Such tokens need no storage but cannot be revoked, and
+// suit links that must keep working such as unsubscribe links.
+func SignValue(purpose, value string) (string, error) {
+	encodedValue := base64.RawURLEncoding.EncodeToString([]byte(value))
+	signature, err := signTokenNonce(purpose, encodedValue)
+	if err != nil {
+		return "", err
+	}
+	return encodedValue + "." + signature, nil
+}
+
+// VerifySignedValue checks that token was returned by SignValue for purpose
+// with the current token secret and returns its value.
+func VerifySignedValue(purpose, token string) (string, error) {
+	encodedValue, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", errors.New("invalid token format")
+	}
+
+	expected, err := signTokenNonce(purpose, encodedValue)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", errors.New("invalid token signature")
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encodedValue)
+	if err != nil {
+		return "", errors.New("invalid token format")
+	}
+	return string(value), nil
+}
+
+// HashToken returns the hex encoded SHA-256 hash of token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func signTokenNonce(purpose, nonce string) (string, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return "", fmt.Errorf("error getting config: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, cfg.TokenSecret)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
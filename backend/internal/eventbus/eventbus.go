@@ -0,0 +1,79 @@
+// Package eventbus carries domain events to every instance of the backend.
+package eventbus
+
+import (
+	"context"
+	"sync"
+	"todo_app_backend/internal/app/models"
+)
+
+// Handler receives the events published on a bus.
+type Handler func(event models.Event)
+
+// Bus delivers the events published by any instance to the handlers
+// subscribed on every instance. When a bus loses events, for instance
+// while its database connection is down, its handlers receive a resync
+// event without a user.
+type Bus interface {
+	Publish(ctx context.Context, event *models.Event) error
+	// Subscribe calls handler with every event until the returned function
+	// is called. Handlers are called one event at a time and must not block.
+	Subscribe(handler Handler) (unsubscribe func())
+	Close() error
+}
+
+// handlers are the subscribers of a bus.
+type handlers struct {
+	mu   sync.Mutex
+	next int
+	fns  map[int]Handler
+}
+
+func (h *handlers) Subscribe(handler Handler) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.fns == nil {
+		h.fns = make(map[int]Handler)
+	}
+	id := h.next
+	h.next++
+	h.fns[id] = handler
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.fns, id)
+	}
+}
+
+// deliver calls every handler with event. The lock is held so that the
+// handlers see events in the order they were delivered.
+func (h *handlers) deliver(event models.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, handler := range h.fns {
+		handler(event)
+	}
+}
+
+// Memory is a Bus within a single process.
+type Memory struct {
+	handlers
+}
+
+// NewMemory initializes a new Memory bus.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Publish calls the handlers with event before returning.
+func (b *Memory) Publish(ctx context.Context, event *models.Event) error {
+	b.deliver(*event)
+	return nil
+}
+
+func (b *Memory) Close() error {
+	return nil
+}
+
+var _ Bus = (*Memory)(nil)
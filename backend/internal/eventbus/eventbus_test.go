@@ -0,0 +1,25 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	bus := NewMemory()
+
+	var first, second []string
+	unsubscribe := bus.Subscribe(func(event models.Event) { first = append(first, event.Type) })
+	bus.Subscribe(func(event models.Event) { second = append(second, event.Type) })
+
+	assert.NoError(t, bus.Publish(context.Background(), &models.Event{Type: models.EventTodoCreated, UserID: 1}))
+	unsubscribe()
+	assert.NoError(t, bus.Publish(context.Background(), &models.Event{Type: models.EventTodoDeleted, UserID: 1}))
+
+	assert.Equal(t, []string{models.EventTodoCreated}, first)
+	assert.Equal(t, []string{models.EventTodoCreated, models.EventTodoDeleted}, second)
+	assert.NoError(t, bus.Close())
+}
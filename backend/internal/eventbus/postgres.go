@@ -0,0 +1,178 @@
+package eventbus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// Channel is the Postgres notification channel events are sent on.
+	Channel = "todo_events"
+	// maxNotifyPayload is the size above which events are stored in the
+	// event_bus_payloads table. Postgres rejects payloads of 8000 bytes.
+	maxNotifyPayload = 7900
+	// spillRetention is how long stored payloads are kept for the
+	// instances to read them.
+	spillRetention = 10 * time.Minute
+
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	// pingInterval is how long the listener may be idle before its
+	// connection is checked, so that lost connections are noticed even
+	// when no events are published.
+	pingInterval = 90 * time.Second
+)
+
+// message is the payload of a notification.
+type message struct {
+	Type   string          `json:"type,omitempty"`
+	UserID int             `json:"user_id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	// SpillID is the ID of the event_bus_payloads row holding a message
+	// too large to be sent.
+	SpillID int64 `json:"spill_id,omitempty"`
+}
+
+// Postgres is a Bus shared by the instances connected to a database through
+// LISTEN/NOTIFY. Events are delivered to the handlers of the publishing
+// instance through the database as well, so that every instance sees them
+// in the same order.
+type Postgres struct {
+	handlers
+
+	db   *sql.DB
+	ping func() error
+	stop func() error
+	done chan struct{}
+}
+
+// NewPostgres initializes a new Postgres bus publishing events with db and
+// listening for them on a dedicated connection to dsn. The connection is
+// re-established and the channel listened to again whenever it is lost.
+func NewPostgres(db *sql.DB, dsn string) (*Postgres, error) {
+	listener := pq.NewListener(dsn, minReconnectInterval, maxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventDisconnected:
+			log.Printf("event bus disconnected: %v", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			log.Printf("event bus failed to reconnect: %v", err)
+		case pq.ListenerEventReconnected:
+			log.Println("event bus reconnected")
+		}
+	})
+	if err := listener.Listen(Channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return newPostgres(db, listener.NotificationChannel(), listener.Ping, listener.Close), nil
+}
+
+// newPostgres starts a Postgres bus receiving the notifications of the
+// listener closed by stop.
+func newPostgres(db *sql.DB, notifications <-chan *pq.Notification, ping, stop func() error) *Postgres {
+	b := &Postgres{db: db, ping: ping, stop: stop, done: make(chan struct{})}
+	go b.listen(notifications)
+	return b
+}
+
+// Publish sends event to every instance, storing it in the database first
+// if it is too large to be sent.
+func (b *Postgres) Publish(ctx context.Context, event *models.Event) error {
+	payload, err := json.Marshal(message{Type: event.Type, UserID: event.UserID, Data: event.Data})
+	if err != nil {
+		return err
+	}
+	if len(payload) <= maxNotifyPayload {
+		_, err = b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, Channel, string(payload))
+		return err
+	}
+	return b.spill(ctx, payload)
+}
+
+// spill stores payload and sends its ID. Notifications are only sent on
+// commit, so the row is visible to the instances receiving them.
+func (b *Postgres) spill(ctx context.Context, payload []byte) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM event_bus_payloads WHERE created_at < $1`, time.Now().Add(-spillRetention)); err != nil {
+		return err
+	}
+	var id int64
+	if err := tx.QueryRowContext(ctx, `INSERT INTO event_bus_payloads (payload) VALUES ($1) RETURNING id`, string(payload)).Scan(&id); err != nil {
+		return err
+	}
+	notification, _ := json.Marshal(message{SpillID: id})
+	if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, Channel, string(notification)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// listen delivers notifications until the listener is closed. The listener
+// sends nil after reconnecting, as notifications may have been missed.
+func (b *Postgres) listen(notifications <-chan *pq.Notification) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case n, ok := <-notifications:
+			if !ok {
+				return
+			}
+			if n == nil {
+				b.deliver(models.Event{Type: models.EventResync, Data: json.RawMessage("{}")})
+			} else if event, err := b.receive(n.Extra); err != nil {
+				log.Printf("failed to receive event: %v", err)
+			} else {
+				b.deliver(event)
+			}
+			ticker.Reset(pingInterval)
+		case <-ticker.C:
+			// A failed ping makes the listener reconnect.
+			go b.ping()
+		}
+	}
+}
+
+// receive decodes the event sent as payload.
+func (b *Postgres) receive(payload string) (models.Event, error) {
+	var msg message
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return models.Event{}, err
+	}
+	if msg.SpillID != 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var stored string
+		err := b.db.QueryRowContext(ctx, `SELECT payload FROM event_bus_payloads WHERE id = $1`, msg.SpillID).Scan(&stored)
+		if err != nil {
+			return models.Event{}, err
+		}
+		msg = message{}
+		if err := json.Unmarshal([]byte(stored), &msg); err != nil {
+			return models.Event{}, err
+		}
+	}
+	return models.Event{Type: msg.Type, UserID: msg.UserID, Data: msg.Data}, nil
+}
+
+// Close stops listening for events.
+func (b *Postgres) Close() error {
+	err := b.stop()
+	<-b.done
+	return err
+}
+
+var _ Bus = (*Postgres)(nil)
@@ -0,0 +1,95 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"todo_app_backend/internal/app/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPostgres returns a Postgres bus receiving the notifications sent on
+// the returned channel, and the events it delivers.
+func newTestPostgres(t *testing.T) (*Postgres, sqlmock.Sqlmock, chan *pq.Notification, chan models.Event) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	notifications := make(chan *pq.Notification)
+	bus := newPostgres(db, notifications, func() error { return nil }, func() error {
+		close(notifications)
+		return nil
+	})
+	events := make(chan models.Event, 10)
+	bus.Subscribe(func(event models.Event) { events <- event })
+	return bus, mock, notifications, events
+}
+
+func TestPostgres_Publish(t *testing.T) {
+	bus, mock, _, _ := newTestPostgres(t)
+	defer bus.Close()
+
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs(Channel, `{"type":"todo.deleted","user_id":1,"data":{"id":5}}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := bus.Publish(context.Background(), &models.Event{Type: models.EventTodoDeleted, UserID: 1, Data: json.RawMessage(`{"id":5}`)})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_PublishSpillsLargeEvents(t *testing.T) {
+	bus, mock, _, _ := newTestPostgres(t)
+	defer bus.Close()
+
+	data, _ := json.Marshal(map[string]string{"content": strings.Repeat("x", maxNotifyPayload)})
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM event_bus_payloads WHERE created_at < \$1`).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(`INSERT INTO event_bus_payloads \(payload\) VALUES \(\$1\) RETURNING id`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectExec(`SELECT pg_notify`).WithArgs(Channel, `{"spill_id":42}`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := bus.Publish(context.Background(), &models.Event{Type: models.EventTodoCreated, UserID: 1, Data: data})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_Receive(t *testing.T) {
+	bus, mock, notifications, events := newTestPostgres(t)
+
+	notifications <- &pq.Notification{Channel: Channel, Extra: `{"type":"todo.created","user_id":1,"data":{"id":5}}`}
+	event := <-events
+	assert.Equal(t, models.Event{Type: models.EventTodoCreated, UserID: 1, Data: json.RawMessage(`{"id":5}`)}, event)
+
+	// Spilled events are read from the database
+	mock.ExpectQuery(`SELECT payload FROM event_bus_payloads WHERE id = \$1`).WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"payload"}).AddRow(`{"type":"todo.updated","user_id":2,"data":{"id":6}}`))
+	notifications <- &pq.Notification{Channel: Channel, Extra: `{"spill_id":42}`}
+	event = <-events
+	assert.Equal(t, models.EventTodoUpdated, event.Type)
+	assert.Equal(t, 2, event.UserID)
+
+	// Malformed notifications are skipped
+	notifications <- &pq.Notification{Channel: Channel, Extra: `not json`}
+
+	// Notifications may have been missed while reconnecting
+	notifications <- nil
+	event = <-events
+	assert.Equal(t, models.EventResync, event.Type)
+	assert.Zero(t, event.UserID)
+
+	assert.NoError(t, bus.Close())
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %+v", event)
+	case <-time.After(10 * time.Millisecond):
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
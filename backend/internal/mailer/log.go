@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LogMailer writes the plain text of every message to the log instead of
+// sending it. It is meant for development.
+type LogMailer struct{}
+
+// NewLogMailer initializes a new LogMailer.
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs msg.
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Text)
+	return nil
+}
+
+// FileMailer writes every message as an .eml file into Dir.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+// NewFileMailer initializes a new FileMailer, creating dir if needed.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{Dir: dir, From: from}, nil
+}
+
+// Send writes msg to a new file in m.Dir.
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = m.From
+	}
+
+	body, err := msg.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), sanitizeFileName(strings.Join(msg.To, "_")))
+	if err := os.WriteFile(filepath.Join(m.Dir, name), body, 0o644); err != nil {
+		return fmt.Errorf("failed to write mail file: %w", err)
+	}
+	return nil
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '@', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+var (
+	_ Mailer = (*LogMailer)(nil)
+	_ Mailer = (*FileMailer)(nil)
+)
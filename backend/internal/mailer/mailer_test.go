@@ -0,0 +1,156 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMessage(t *testing.T) {
+	msg, err := NewMessage("john@example.com", "reset_password", map[string]interface{}{
+		"Name":      "<John>",
+		"Link":      "http://app.test/reset-password?token=abc",
+		"ExpiresIn": "1 hour",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"john@example.com"}, msg.To)
+	assert.NotEmpty(t, msg.Subject)
+	assert.NotContains(t, msg.Subject, "\n")
+	assert.Contains(t, msg.Text, "http://app.test/reset-password?token=abc")
+	assert.Contains(t, msg.HTML, "&lt;John&gt;", "HTML body must be escaped")
+
+	_, err = NewMessage("john@example.com", "missing", nil)
+	assert.Error(t, err)
+}
+
+func TestMessage_Bytes(t *testing.T) {
+	body, err := Message{
+		From:    "Todo <noreply@example.com>",
+		To:      []string{"john@example.com"},
+		Subject: "Grüße",
+		Text:    "plain",
+		HTML:    "<p>html</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+	}.Bytes()
+	require.NoError(t, err)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(body)))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Grüße", subject)
+	assert.Contains(t, parsed.Header.Get("Message-ID"), "@example.com>")
+	assert.Equal(t, "<https://example.com/unsubscribe>", parsed.Header.Get("List-Unsubscribe"))
+
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(part)
+		require.NoError(t, err)
+		parts = append(parts, part.Header.Get("Content-Type")+"|"+string(data))
+	}
+	assert.Equal(t, []string{"text/plain; charset=utf-8|plain", "text/html; charset=utf-8|<p>html</p>"}, parts)
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, "noreply@example.com")
+	require.NoError(t, err)
+
+	err = m.Send(context.Background(), Message{To: []string{"john@example.com"}, Subject: "Hi", Text: "hello"})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), "-john@example.com.eml"))
+
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "From: noreply@example.com\r\n")
+}
+
+func TestSMTPMailer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go serveSMTP(t, listener, received)
+
+	addr := listener.Addr().(*net.TCPAddr)
+	m := NewSMTPMailer("127.0.0.1", addr.Port, "", "", "Todo <noreply@example.com>")
+
+	err = m.Send(context.Background(), Message{To: []string{"john@example.com"}, Subject: "Hi", Text: "hello"})
+	require.NoError(t, err)
+
+	commands := <-received
+	assert.Contains(t, commands, "MAIL FROM:<noreply@example.com>")
+	assert.Contains(t, commands, "RCPT TO:<john@example.com>")
+}
+
+// serveSMTP accepts a single connection and speaks just enough SMTP for
+// net/smtp.SendMail, reporting the commands it received.
+func serveSMTP(t *testing.T, listener net.Listener, received chan<- []string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP")
+
+	var commands []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+		commands = append(commands, line)
+
+		switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "DATA":
+			reply("354 go ahead")
+			for {
+				data, err := r.ReadString('\n')
+				if err != nil || data == ".\r\n" {
+					break
+				}
+			}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			received <- commands
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+	received <- commands
+}
@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+// SMTPMailer sends messages through an SMTP server.
+type SMTPMailer struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+// NewSMTPMailer initializes a new SMTPMailer. Authentication is only used
+// when a username is given; net/smtp refuses to send credentials over
+// unencrypted connections to hosts other than localhost.
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{Addr: net.JoinHostPort(host, strconv.Itoa(port)), From: from}
+	if username != "" {
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+// Send delivers msg. The context is not honoured by net/smtp once the
+// connection is established.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if msg.From == "" {
+		msg.From = m.From
+	}
+
+	sender, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	body, err := msg.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	if err := smtp.SendMail(m.Addr, m.Auth, sender.Address, msg.To, body); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+var _ Mailer = (*SMTPMailer)(nil)
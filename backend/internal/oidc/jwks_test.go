@@ -0,0 +1,41 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/oidc/oidctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider_Verify_KeyRotation(t *testing.T) {
+	idp := oidctest.NewServer("client", "s3cret")
+	defer idp.Close()
+
+	now := time.Now()
+	provider := NewProvider(Config{Name: "test", Issuer: idp.Issuer(), ClientID: "client"}, nil)
+	provider.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := provider.Verify(ctx, idp.SignIDToken(idp.Claims(idp.User, "nonce")), "nonce")
+	require.NoError(t, err)
+
+	// The provider rotates its key, so new tokens carry an unknown key ID
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp.Key, idp.KeyID = newKey, "rotated-key"
+	token := idp.SignIDToken(idp.Claims(idp.User, "nonce"))
+
+	// Unknown key IDs do not make every request hit the provider
+	_, err = provider.Verify(ctx, token, "nonce")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	now = now.Add(keyRefreshInterval)
+	_, err = provider.Verify(ctx, token, "nonce")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,141 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/url"
+	"testing"
+	"time"
+
+	"todo_app_backend/internal/oidc"
+	"todo_app_backend/internal/oidc/oidctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://api.test/api/v1/auth/oidc/test/callback"
+
+func newProvider(idp *oidctest.Server) *oidc.Provider {
+	return oidc.NewProvider(oidc.Config{
+		Name:         "test",
+		Issuer:       idp.Issuer(),
+		ClientID:     idp.ClientID,
+		ClientSecret: idp.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"email", "profile"},
+	}, nil)
+}
+
+// login runs the authorization step at the fake provider and returns the
+// code and state of the redirect back to the client.
+func login(t *testing.T, idp *oidctest.Server, authURL string, user oidctest.User) (code, state string) {
+	t.Helper()
+	callback, err := idp.Authorize(authURL, user)
+	require.NoError(t, err)
+	u, err := url.Parse(callback)
+	require.NoError(t, err)
+	return u.Query().Get("code"), u.Query().Get("state")
+}
+
+func TestProvider_Login(t *testing.T) {
+	idp := oidctest.NewServer("client", "s3cret")
+	defer idp.Close()
+	provider := newProvider(idp)
+	ctx := context.Background()
+
+	verifier, err := oidc.RandomString()
+	require.NoError(t, err)
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	require.NoError(t, err)
+
+	params, _ := url.ParseQuery(authURL[len(idp.URL+"/authorize?"):])
+	assert.Equal(t, "openid email profile", params.Get("scope"))
+	assert.Equal(t, oidc.CodeChallenge(verifier), params.Get("code_challenge"))
+	assert.Equal(t, redirectURL, params.Get("redirect_uri"))
+
+	user := oidctest.User{Subject: "abc", Email: "jane@example.com", EmailVerified: true, Name: "Jane"}
+	code, state := login(t, idp, authURL, user)
+	assert.Equal(t, "state-1", state)
+
+	rawIDToken, err := provider.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+
+	claims, err := provider.Verify(ctx, rawIDToken, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", claims.Subject)
+	assert.Equal(t, "jane@example.com", claims.Email)
+	assert.True(t, bool(claims.EmailVerified))
+	assert.Equal(t, "Jane", claims.Name)
+
+	_, err = provider.Exchange(ctx, code, verifier)
+	assert.ErrorIs(t, err, oidc.ErrExchangeFailed, "codes must be single use")
+}
+
+func TestProvider_Exchange_PKCEMismatch(t *testing.T) {
+	idp := oidctest.NewServer("client", "")
+	defer idp.Close()
+	provider := newProvider(idp)
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", "nonce", "the-real-verifier")
+	require.NoError(t, err)
+	code, _ := login(t, idp, authURL, idp.User)
+
+	_, err = provider.Exchange(ctx, code, "an-intercepted-code-without-verifier")
+	assert.ErrorIs(t, err, oidc.ErrExchangeFailed)
+}
+
+func TestProvider_Verify_RejectsInvalidTokens(t *testing.T) {
+	idp := oidctest.NewServer("client", "s3cret")
+	defer idp.Close()
+	provider := newProvider(idp)
+	ctx := context.Background()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	claims := func(change func(map[string]interface{})) map[string]interface{} {
+		c := idp.Claims(idp.User, "nonce")
+		change(c)
+		return c
+	}
+
+	tests := map[string]string{
+		"wrong nonce":    idp.SignIDToken(claims(func(c map[string]interface{}) { c["nonce"] = "other" })),
+		"wrong audience": idp.SignIDToken(claims(func(c map[string]interface{}) { c["aud"] = "other-client" })),
+		"wrong issuer":   idp.SignIDToken(claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" })),
+		"expired":        idp.SignIDToken(claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+		"unauthorized party": idp.SignIDToken(claims(func(c map[string]interface{}) {
+			c["aud"] = []string{"client", "other-client"}
+			c["azp"] = "other-client"
+		})),
+		"unknown key":     idp.SignIDTokenWithKey(otherKey, "other-key", claims(func(map[string]interface{}) {})),
+		"forged with kid": idp.SignIDTokenWithKey(otherKey, idp.KeyID, claims(func(map[string]interface{}) {})),
+		"alg none":        "eyJhbGciOiJub25lIn0.eyJzdWIiOiJ4In0.",
+		"malformed":       "not-a-jwt",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := provider.Verify(ctx, token, "nonce")
+			assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		})
+	}
+
+	_, err = provider.Verify(ctx, idp.SignIDToken(claims(func(c map[string]interface{}) {
+		c["aud"] = []string{"client", "other-client"}
+		c["azp"] = "client"
+		c["email_verified"] = "true"
+	})), "nonce")
+	assert.NoError(t, err)
+}
+
+func TestProvider_Discovery_IssuerMismatch(t *testing.T) {
+	idp := oidctest.NewServer("client", "s3cret")
+	defer idp.Close()
+
+	provider := oidc.NewProvider(oidc.Config{Name: "test", Issuer: idp.Issuer() + "/", ClientID: "client"}, nil)
+	_, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// ErrInvalidBloomFilter is returned when reading a file that is not a Bloom
+// filter written by BloomFilter.WriteTo.
+var ErrInvalidBloomFilter = errors.New("invalid breached password filter")
+
+// bloomMagic starts every Bloom filter file.
+const bloomMagic = "PWBLOOM1"
+
+// BloomFilter is a compact set of breached passwords. It is keyed by the
+// SHA-1 hash of each password, the form in which breach corpora such as
+// Have I Been Pwned's Pwned Passwords are published, so that it can be
+// built without ever handling the plain passwords.
+//
+// Contains may report false positives at the rate the filter was sized for,
+// but never false negatives.
+type BloomFilter struct {
+	bits   []byte
+	m      uint64
+	hashes uint32
+}
+
+// NewBloomFilter returns an empty filter sized for n passwords with the
+// given false positive rate.
+func NewBloomFilter(n int, falsePositiveRate float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	m := math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/float64(n)*math.Ln2))
+	bits := (uint64(m) + 7) / 8
+	return &BloomFilter{bits: make([]byte, bits), m: bits * 8, hashes: uint32(k)}
+}
+
+// LoadBloomFilter reads a filter from the file at path.
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBloomFilter(bufio.NewReader(f))
+}
+
+// ReadBloomFilter reads a filter written by WriteTo.
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, len(bloomMagic)+12)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(bloomMagic)]) != bloomMagic {
+		return nil, ErrInvalidBloomFilter
+	}
+	m := binary.BigEndian.Uint64(header[len(bloomMagic):])
+	hashes := binary.BigEndian.Uint32(header[len(bloomMagic)+8:])
+	if m == 0 || m%8 != 0 || hashes == 0 {
+		return nil, ErrInvalidBloomFilter
+	}
+
+	bits := make([]byte, m/8)
+	if _, err := io.ReadFull(r, bits); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBloomFilter, err)
+	}
+	return &BloomFilter{bits: bits, m: m, hashes: hashes}, nil
+}
+
+// WriteTo writes the filter to w: the magic "PWBLOOM1", the number of bits
+// and of hash functions as big-endian uint64 and uint32, and the bits.
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, len(bloomMagic)+12)
+	copy(header, bloomMagic)
+	binary.BigEndian.PutUint64(header[len(bloomMagic):], f.m)
+	binary.BigEndian.PutUint32(header[len(bloomMagic)+8:], f.hashes)
+
+	n, err := w.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+	written, err := w.Write(f.bits)
+	return int64(n + written), err
+}
+
+// AddHash adds the password with the given SHA-1 hash.
+func (f *BloomFilter) AddHash(sum [sha1.Size]byte) {
+	f.locate(sum, func(i uint64) bool {
+		f.bits[i/8] |= 1 << (i % 8)
+		return true
+	})
+}
+
+// Add adds password.
+func (f *BloomFilter) Add(password string) {
+	f.AddHash(sha1.Sum([]byte(password)))
+}
+
+// Contains reports whether password is probably in the filter.
+func (f *BloomFilter) Contains(password string) bool {
+	return f.locate(sha1.Sum([]byte(password)), func(i uint64) bool {
+		return f.bits[i/8]&(1<<(i%8)) != 0
+	})
+}
+
+// locate calls fn with each bit index of sum until fn returns false, and
+// reports whether it never did. The indexes are derived from two halves of
+// the hash by double hashing.
+func (f *BloomFilter) locate(sum [sha1.Size]byte, fn func(i uint64) bool) bool {
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+	for k := uint64(0); k < uint64(f.hashes); k++ {
+		if !fn((h1 + k*h2) % f.m) {
+			return false
+		}
+	}
+	return true
+}
+
+var _ BreachedList = (*BloomFilter)(nil)
@@ -0,0 +1,153 @@
+// Package password hashes passwords for storage. Hashes are encoded in the
+// PHC string format, which records the algorithm and its parameters, so
+// that hashes made with older parameters can still be verified and
+// upgraded.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidParams is returned for hashing parameters that are out of range.
+var ErrInvalidParams = errors.New("invalid password hashing parameters")
+
+// Hasher hashes and verifies passwords.
+type Hasher interface {
+	// Hash returns the encoded hash of password.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches the encoded hash. Empty or
+	// malformed hashes match no password.
+	Verify(password, encoded string) bool
+	// NeedsRehash reports whether encoded was made with another algorithm
+	// or other parameters and should be replaced on the next login.
+	NeedsRehash(encoded string) bool
+}
+
+// Params are the cost parameters of Argon2id.
+type Params struct {
+	// Memory is the memory cost in KiB.
+	Memory uint32
+	// Time is the number of passes over the memory.
+	Time        uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams follow the OWASP recommendation for Argon2id.
+var DefaultParams = Params{Memory: 64 * 1024, Time: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+
+// Validate returns ErrInvalidParams unless p can be used to hash passwords.
+func (p Params) Validate() error {
+	switch {
+	case p.Time < 1:
+		return fmt.Errorf("%w: time must be at least 1", ErrInvalidParams)
+	case p.Parallelism < 1:
+		return fmt.Errorf("%w: parallelism must be at least 1", ErrInvalidParams)
+	case p.Memory < 8*uint32(p.Parallelism):
+		return fmt.Errorf("%w: memory must be at least 8 KiB per thread", ErrInvalidParams)
+	case p.SaltLength < 8 || p.KeyLength < 16:
+		return fmt.Errorf("%w: salt or key too short", ErrInvalidParams)
+	}
+	return nil
+}
+
+// Argon2id hashes passwords with Argon2id and verifies both Argon2id and
+// legacy bcrypt hashes.
+type Argon2id struct {
+	Params Params
+}
+
+// NewArgon2id initializes a new Argon2id hasher.
+func NewArgon2id(params Params) *Argon2id {
+	return &Argon2id{Params: params}
+}
+
+const argon2idPrefix = "$argon2id$"
+
+var b64 = base64.RawStdEncoding
+
+// Hash returns the Argon2id hash of password, e.g.
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.Params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	p := a.Params
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		p.Memory, p.Time, p.Parallelism, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches an Argon2id or bcrypt hash.
+func (a *Argon2id) Verify(password, encoded string) bool {
+	if isBcrypt(encoded) {
+		return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+	}
+
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+	actual := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1
+}
+
+// NeedsRehash reports whether encoded is not an Argon2id hash with the
+// current parameters.
+func (a *Argon2id) NeedsRehash(encoded string) bool {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return p.Memory != a.Params.Memory || p.Time != a.Params.Time || p.Parallelism != a.Params.Parallelism ||
+		uint32(len(salt)) != a.Params.SaltLength || uint32(len(key)) != a.Params.KeyLength
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// decodeArgon2id parses an Argon2id hash in the PHC string format.
+func decodeArgon2id(encoded string) (p Params, salt, key []byte, err error) {
+	errMalformed := errors.New("malformed argon2id hash")
+	if !strings.HasPrefix(encoded, argon2idPrefix) {
+		return p, nil, nil, errMalformed
+	}
+
+	parts := strings.Split(encoded[len(argon2idPrefix):], "$")
+	if len(parts) != 4 {
+		return p, nil, nil, errMalformed
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[0], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errMalformed
+	}
+	if _, err := fmt.Sscanf(parts[1], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Parallelism); err != nil {
+		return p, nil, nil, errMalformed
+	}
+	if salt, err = b64.DecodeString(parts[2]); err != nil {
+		return p, nil, nil, errMalformed
+	}
+	if key, err = b64.DecodeString(parts[3]); err != nil || len(key) == 0 {
+		return p, nil, nil, errMalformed
+	}
+	p.SaltLength, p.KeyLength = uint32(len(salt)), uint32(len(key))
+	if p.Validate() != nil {
+		return p, nil, nil, errMalformed
+	}
+	return p, salt, key, nil
+}
+
+var _ Hasher = (*Argon2id)(nil)
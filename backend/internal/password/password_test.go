@@ -0,0 +1,92 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams keep the tests fast.
+var testParams = Params{Memory: 1024, Time: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2id_HashAndVerify(t *testing.T) {
+	hasher := NewArgon2id(testParams)
+
+	hash, err := hasher.Hash("correct horse battery staple")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+
+	assert.True(t, hasher.Verify("correct horse battery staple", hash))
+	assert.False(t, hasher.Verify("correct horse battery stapl", hash))
+	assert.False(t, hasher.NeedsRehash(hash))
+
+	other, err := hasher.Hash("correct horse battery staple")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "hashes are salted")
+}
+
+func TestArgon2id_LongPasswords(t *testing.T) {
+	hasher := NewArgon2id(testParams)
+
+	// bcrypt ignores everything after 72 bytes.
+	long := strings.Repeat("a", 72)
+	hash, err := hasher.Hash(long + "1")
+	require.NoError(t, err)
+	assert.False(t, hasher.Verify(long+"2", hash))
+}
+
+func TestArgon2id_VerifyBcrypt(t *testing.T) {
+	hasher := NewArgon2id(testParams)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	assert.True(t, hasher.Verify("password123", string(legacy)))
+	assert.False(t, hasher.Verify("password124", string(legacy)))
+	assert.True(t, hasher.NeedsRehash(string(legacy)))
+}
+
+func TestArgon2id_NeedsRehashWithNewParams(t *testing.T) {
+	old, err := NewArgon2id(testParams).Hash("password123")
+	require.NoError(t, err)
+
+	stronger := testParams
+	stronger.Time = 2
+	hasher := NewArgon2id(stronger)
+
+	assert.True(t, hasher.Verify("password123", old), "old parameters are read from the hash")
+	assert.True(t, hasher.NeedsRehash(old))
+}
+
+func TestArgon2id_MalformedHashes(t *testing.T) {
+	hasher := NewArgon2id(testParams)
+
+	for _, encoded := range []string{
+		"",
+		"plaintext",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$not base64!$a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0",
+	} {
+		assert.False(t, hasher.Verify("", encoded), encoded)
+		assert.True(t, hasher.NeedsRehash(encoded), encoded)
+	}
+}
+
+func TestParams_Validate(t *testing.T) {
+	assert.NoError(t, DefaultParams.Validate())
+	assert.NoError(t, testParams.Validate())
+
+	invalid := testParams
+	invalid.Parallelism = 0
+	assert.ErrorIs(t, invalid.Validate(), ErrInvalidParams)
+
+	invalid = testParams
+	invalid.Memory = 4
+	assert.ErrorIs(t, invalid.Validate(), ErrInvalidParams)
+}
@@ -0,0 +1,111 @@
+package password
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Strength scores returned by Strength, from trivially guessable to very
+// strong. They mirror the scores of zxcvbn.
+const (
+	TooGuessable = iota
+	VeryGuessable
+	SomewhatGuessable
+	SafelyUnguessable
+	VeryUnguessable
+)
+
+// Violation messages reported by Policy.Check.
+const (
+	ViolationTooEasy      = "is too easy to guess"
+	ViolationPersonalInfo = "must not contain your email address or name"
+	ViolationBreached     = "has appeared in a data breach, choose a different password"
+)
+
+const (
+	tooShortFormat = "must be at least %d characters"
+	tooLongFormat  = "must be at most %d characters"
+)
+
+// MaxLength caps the length of passwords, in characters, so that hashing
+// and strength estimation stay cheap.
+const MaxLength = 255
+
+// BreachedList reports whether a password is known to have leaked.
+type BreachedList interface {
+	Contains(password string) bool
+}
+
+// Policy decides which passwords users may choose.
+type Policy struct {
+	// MinLength is the minimum number of characters.
+	MinLength int
+	// MinStrength is the minimum score returned by Strength.
+	MinStrength int
+	// Breached rejects leaked passwords. It is optional.
+	Breached BreachedList
+}
+
+// DefaultPolicy requires passwords of at least eight characters that are
+// not trivially guessable.
+var DefaultPolicy = &Policy{MinLength: 8, MinStrength: SomewhatGuessable}
+
+// Check returns the rules that password breaks, as messages completing the
+// sentence "The password ...". userInputs are the name and email address of
+// the user, which must not appear in the password.
+func (p *Policy) Check(password string, userInputs ...string) []string {
+	var violations []string
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, fmt.Sprintf(tooShortFormat, p.MinLength))
+	} else if length > MaxLength {
+		return []string{fmt.Sprintf(tooLongFormat, MaxLength)}
+	}
+
+	inputs := personalInputs(userInputs)
+	if containsAny(strings.ToLower(password), inputs) || containsAny(unleet(password), inputs) {
+		violations = append(violations, ViolationPersonalInfo)
+	} else if Strength(password, userInputs...) < p.MinStrength {
+		violations = append(violations, ViolationTooEasy)
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, ViolationBreached)
+	}
+	return violations
+}
+
+// personalInputs returns the lower-cased user inputs worth matching: names
+// are split into words and email addresses contribute their local part.
+// Inputs shorter than three characters match too many passwords to reject.
+func personalInputs(userInputs []string) []string {
+	var inputs []string
+	add := func(s string) {
+		if s = strings.ToLower(strings.TrimSpace(s)); utf8.RuneCountInString(s) >= 3 {
+			inputs = append(inputs, s)
+		}
+	}
+	for _, input := range userInputs {
+		if addr, err := mail.ParseAddress(input); err == nil {
+			add(addr.Address)
+			add(addr.Address[:strings.LastIndex(addr.Address, "@")])
+			continue
+		}
+		for _, word := range strings.FieldsFunc(input, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+			add(word)
+		}
+	}
+	return inputs
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
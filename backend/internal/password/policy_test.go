@@ -0,0 +1,96 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrength(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"a", TooGuessable},
+		{"password", TooGuessable},
+		{"P@ssw0rd", TooGuessable},
+		{"password123", TooGuessable},
+		{"aaaaaaaaaaaa", TooGuessable},
+		{"qwertyuiop12", VeryGuessable},
+		{"dragon1987", VeryGuessable},
+		{"kx8wq", SomewhatGuessable},
+		{"kx8wq2", SafelyUnguessable},
+		{"correct horse battery staple", VeryUnguessable},
+		{"Tr0ub4dor&3x", VeryUnguessable},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Strength(tt.password), tt.password)
+	}
+
+	assert.Less(t, Strength("zephyrine42", "Zephyrine Doe"), Strength("zephyrine42"))
+}
+
+func TestPolicy_Check(t *testing.T) {
+	policy := &Policy{MinLength: 8, MinStrength: SomewhatGuessable}
+
+	assert.Empty(t, policy.Check("correct horse battery staple", "Jane Doe", "jane@example.com"))
+	assert.Equal(t, []string{"must be at least 8 characters", ViolationTooEasy}, policy.Check("abc"))
+	assert.Equal(t, []string{ViolationTooEasy}, policy.Check("password1"))
+	assert.Equal(t, []string{"must be at most 255 characters"}, policy.Check(string(bytes.Repeat([]byte("x"), 256))))
+
+	for _, pw := range []string{"my-jane@example.com!", "JANE-likes-cats-9", "J4n3-likes-cats-9", "doe:kx8-wq2f-v7"} {
+		assert.Equal(t, []string{ViolationPersonalInfo}, policy.Check(pw, "Jane Doe", "jane@example.com"), pw)
+	}
+	// Names shorter than three characters are too common to reject.
+	assert.Empty(t, policy.Check("al-kx8-wq2f-v7", "Al"))
+}
+
+func TestPolicy_CheckBreached(t *testing.T) {
+	filter := NewBloomFilter(10, 0.001)
+	filter.Add("correct horse battery staple")
+	policy := &Policy{MinLength: 8, Breached: filter}
+
+	assert.Equal(t, []string{ViolationBreached}, policy.Check("correct horse battery staple"))
+	assert.Empty(t, policy.Check("correct horse battery stapler"))
+}
+
+func TestBloomFilter(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		filter.Add(string(rune('a'+i%26)) + string(rune(i)))
+	}
+	filter.AddHash(sha1.Sum([]byte("hunter2")))
+
+	var buf bytes.Buffer
+	_, err := filter.WriteTo(&buf)
+	require.NoError(t, err)
+	loaded, err := ReadBloomFilter(&buf)
+	require.NoError(t, err)
+
+	assert.True(t, loaded.Contains("hunter2"))
+	for i := 0; i < 1000; i++ {
+		assert.True(t, loaded.Contains(string(rune('a'+i%26))+string(rune(i))))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if loaded.Contains("not breached " + string(rune(i))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 300)
+}
+
+func TestReadBloomFilter_Invalid(t *testing.T) {
+	_, err := ReadBloomFilter(bytes.NewReader([]byte("not a filter at all")))
+	assert.ErrorIs(t, err, ErrInvalidBloomFilter)
+
+	var buf bytes.Buffer
+	_, err = NewBloomFilter(10, 0.01).WriteTo(&buf)
+	require.NoError(t, err)
+	_, err = ReadBloomFilter(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.ErrorIs(t, err, ErrInvalidBloomFilter)
+}
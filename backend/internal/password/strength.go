@@ -0,0 +1,198 @@
+package password
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords are frequently used passwords and password fragments,
+// most common first.
+//
+//go:embed common.txt
+var commonPasswords string
+
+// commonRanks maps each common password to its 1-based rank.
+var commonRanks = func() map[string]int {
+	ranks := make(map[string]int)
+	for i, word := range strings.Fields(commonPasswords) {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}()
+
+// keyboardRows are the rows of a QWERTY keyboard, for spotting runs such as
+// "asdf".
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// leetReplacer undoes common character substitutions, e.g. "p@ssw0rd".
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+func unleet(s string) string {
+	return leetReplacer.Replace(strings.ToLower(s))
+}
+
+// minPatternLength is the shortest run, repeat or dictionary word that is
+// treated as a single guess rather than as separate characters.
+const minPatternLength = 3
+
+// Strength estimates how hard password is to guess and returns a score from
+// TooGuessable to VeryUnguessable, in the spirit of zxcvbn.
+//
+// The password is read from left to right. Common passwords and userInputs
+// cost as many guesses as their rank in the dictionary, repeats, sequences
+// ("abc", "987") and keyboard runs cost about as much as one character and
+// years as much as the number of recent years. Every other character costs
+// the size of the character classes used in the password.
+func Strength(password string, userInputs ...string) int {
+	dictionary := make(map[string]int, len(userInputs))
+	for _, input := range personalInputs(userInputs) {
+		dictionary[input] = 1
+	}
+
+	lower := []rune(strings.ToLower(password))
+	plain := []rune(unleet(password))
+	if len(plain) != len(lower) {
+		// Lower-casing changed the length; read the password as is.
+		plain = lower
+	}
+	cardinality := float64(charsetSize(password))
+
+	log10Guesses := 0.0
+	for i := 0; i < len(lower); {
+		if n, rank := dictionaryMatch(plain[i:], dictionary); n > 0 {
+			log10Guesses += math.Log10(float64(rank + 1))
+			i += n
+		} else if n := runLength(lower[i:]); n >= minPatternLength {
+			log10Guesses += math.Log10(cardinality * float64(n))
+			i += n
+		} else if isYear(lower[i:]) {
+			log10Guesses += math.Log10(200)
+			i += 4
+		} else {
+			log10Guesses += math.Log10(cardinality)
+			i++
+		}
+	}
+
+	switch {
+	case log10Guesses < 3:
+		return TooGuessable
+	case log10Guesses < 6:
+		return VeryGuessable
+	case log10Guesses < 8:
+		return SomewhatGuessable
+	case log10Guesses < 10:
+		return SafelyUnguessable
+	}
+	return VeryUnguessable
+}
+
+// dictionaryMatch returns the length and rank of the longest common password
+// or user input that s starts with, or 0 if there is none.
+func dictionaryMatch(s []rune, userInputs map[string]int) (length, rank int) {
+	for n := len(s); n >= minPatternLength; n-- {
+		word := string(s[:n])
+		if r, ok := userInputs[word]; ok {
+			return n, r
+		}
+		if r, ok := commonRanks[word]; ok {
+			return n, r
+		}
+	}
+	return 0, 0
+}
+
+// runLength returns the length of the repeat ("aaa"), sequence ("abc",
+// "321") or keyboard run ("qwer") that s starts with.
+func runLength(s []rune) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+
+	n := 1
+	for n < len(s) && s[n] == s[0] {
+		n++
+	}
+	if n > 1 {
+		return n
+	}
+
+	delta := s[1] - s[0]
+	if delta == 1 || delta == -1 {
+		for n = 1; n < len(s) && s[n]-s[n-1] == delta; n++ {
+		}
+		return n
+	}
+
+	best := 1
+	for _, row := range keyboardRows {
+		keys := []rune(row)
+		for start, key := range keys {
+			if key != s[0] {
+				continue
+			}
+			for _, step := range []int{1, -1} {
+				n := 1
+				for n < len(s) && start+n*step >= 0 && start+n*step < len(keys) && keys[start+n*step] == s[n] {
+					n++
+				}
+				if n > best {
+					best = n
+				}
+			}
+		}
+	}
+	return best
+}
+
+// isYear reports whether s starts with a year between 1900 and 2099.
+func isYear(s []rune) bool {
+	if len(s) < 4 {
+		return false
+	}
+	for _, r := range s[:4] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	century := string(s[:2])
+	return century == "19" || century == "20"
+}
+
+// charsetSize returns the number of characters in the classes that password
+// draws from.
+func charsetSize(password string) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			size += class.size
+		}
+	}
+	if size == 0 {
+		return 1
+	}
+	return size
+}
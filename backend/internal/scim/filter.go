@@ -0,0 +1,277 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidFilter is returned for filters that cannot be parsed.
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Filter is a parsed filter expression (RFC 7644 section 3.4.2.2). It is
+// one of *Comparison, *Logical or *Not.
+type Filter interface {
+	filter()
+}
+
+// Comparison tests an attribute, e.g. userName eq "jane@example.com".
+type Comparison struct {
+	// Path is the lower-cased attribute path without the core schema URN,
+	// e.g. "username" or "emails.value".
+	Path string
+	// Op is one of eq, ne, co, sw, ew, gt, ge, lt, le and pr.
+	Op string
+	// Value is a string, bool, float64 or nil. It is nil for pr.
+	Value interface{}
+}
+
+// Logical combines two filters with "and" or "or".
+type Logical struct {
+	Op          string
+	Left, Right Filter
+}
+
+// Not negates a filter.
+type Not struct {
+	Filter Filter
+}
+
+func (*Comparison) filter() {}
+func (*Logical) filter()    {}
+func (*Not) filter()        {}
+
+var comparisonOps = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true,
+}
+
+// ParseFilter parses a filter. Value filters on multi-valued attributes,
+// such as emails[type eq "work"], are not supported.
+func ParseFilter(s string) (Filter, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFilter, p.tokens[p.pos].text)
+	}
+	return f, nil
+}
+
+// NormalizePath lower-cases an attribute path and strips the URN of the
+// core user schema from it.
+func NormalizePath(path string) string {
+	path = strings.ToLower(path)
+	return strings.TrimPrefix(path, strings.ToLower(UserSchema)+":")
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			kind := tokenOpen
+			if c == ')' {
+				kind = tokenClose
+			}
+			tokens = append(tokens, token{kind: kind, text: string(c)})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidFilter)
+			}
+			var value string
+			if err := json.Unmarshal([]byte(s[i:end+1]), &value); err != nil {
+				return nil, fmt.Errorf("%w: invalid string %s", ErrInvalidFilter, s[i:end+1])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value})
+			i = end + 1
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(s) && strings.ContainsRune("0123456789.eE+-", rune(s[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: s[i:end]})
+			i = end
+		case isWordByte(c):
+			end := i
+			for end < len(s) && isWordByte(s[end]) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: s[i:end]})
+			i = end
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q", ErrInvalidFilter, c)
+		}
+	}
+	return tokens, nil
+}
+
+// isWordByte reports whether c can be part of an attribute path, keyword or
+// literal. Paths may be prefixed with a schema URN.
+func isWordByte(c byte) bool {
+	return c < unicode.MaxASCII && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) || strings.IndexByte("._:-$", c) >= 0)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peekKeyword(keyword string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenWord && strings.EqualFold(p.tokens[p.pos].text, keyword)
+}
+
+func (p *parser) next() (token, error) {
+	if p.pos >= len(p.tokens) {
+		return token{}, fmt.Errorf("%w: unexpected end of filter", ErrInvalidFilter)
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+// parseOr parses filters joined by "or", which binds weaker than "and".
+func (p *parser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Filter, error) {
+	negate := p.peekKeyword("not")
+	if negate {
+		p.pos++
+	}
+
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	var f Filter
+	switch {
+	case tok.kind == tokenOpen:
+		if f, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+		if tok, err := p.next(); err != nil || tok.kind != tokenClose {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidFilter)
+		}
+	case negate:
+		return nil, fmt.Errorf("%w: not must be followed by a parenthesized filter", ErrInvalidFilter)
+	case tok.kind == tokenWord:
+		if f, err = p.parseComparison(tok.text); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: expected an attribute, got %q", ErrInvalidFilter, tok.text)
+	}
+
+	if negate {
+		return &Not{Filter: f}, nil
+	}
+	return f, nil
+}
+
+func (p *parser) parseComparison(path string) (Filter, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(tok.text)
+	if tok.kind == tokenWord && op == "pr" {
+		return &Comparison{Path: NormalizePath(path), Op: op}, nil
+	}
+	if tok.kind != tokenWord || !comparisonOps[op] {
+		return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, tok.text)
+	}
+
+	tok, err = p.next()
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch tok.kind {
+	case tokenString:
+		value = tok.text
+	case tokenNumber:
+		var n float64
+		if _, err := fmt.Sscan(tok.text, &n); err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q", ErrInvalidFilter, tok.text)
+		}
+		value = n
+	case tokenWord:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			return nil, fmt.Errorf("%w: invalid value %q", ErrInvalidFilter, tok.text)
+		}
+	default:
+		return nil, fmt.Errorf("%w: invalid value %q", ErrInvalidFilter, tok.text)
+	}
+	return &Comparison{Path: NormalizePath(path), Op: op, Value: value}, nil
+}
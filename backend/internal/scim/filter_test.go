@@ -0,0 +1,76 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   Filter
+	}{
+		{
+			name:   "equality",
+			filter: `userName eq "jane@example.com"`,
+			want:   &Comparison{Path: "username", Op: "eq", Value: "jane@example.com"},
+		},
+		{
+			name:   "schema urn and operator case",
+			filter: `urn:ietf:params:scim:schemas:core:2.0:User:externalId EQ "abc"`,
+			want:   &Comparison{Path: "externalid", Op: "eq", Value: "abc"},
+		},
+		{
+			name:   "present",
+			filter: `name.formatted pr`,
+			want:   &Comparison{Path: "name.formatted", Op: "pr"},
+		},
+		{
+			name:   "and binds tighter than or",
+			filter: `active eq true or userName sw "j" and emails.value co "example"`,
+			want: &Logical{Op: "or",
+				Left: &Comparison{Path: "active", Op: "eq", Value: true},
+				Right: &Logical{Op: "and",
+					Left:  &Comparison{Path: "username", Op: "sw", Value: "j"},
+					Right: &Comparison{Path: "emails.value", Op: "co", Value: "example"},
+				},
+			},
+		},
+		{
+			name:   "not and parentheses",
+			filter: `not (id gt 5) and displayName eq "Jane \"J\" Doe"`,
+			want: &Logical{Op: "and",
+				Left:  &Not{Filter: &Comparison{Path: "id", Op: "gt", Value: float64(5)}},
+				Right: &Comparison{Path: "displayname", Op: "eq", Value: `Jane "J" Doe`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilter(tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	for _, filter := range []string{
+		``,
+		`userName`,
+		`userName like "j"`,
+		`userName eq`,
+		`userName eq "unterminated`,
+		`userName eq jane`,
+		`(userName eq "j"`,
+		`not userName eq "j"`,
+		`userName eq "j" extra`,
+		`emails[type eq "work"]`,
+	} {
+		_, err := ParseFilter(filter)
+		assert.ErrorIs(t, err, ErrInvalidFilter, filter)
+	}
+}
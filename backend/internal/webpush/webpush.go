@@ -0,0 +1,253 @@
+// Package webpush sends Web Push messages: payloads are encrypted for the
+// subscribed browser as specified in RFC 8291, and the sender identifies
+// itself to push services with VAPID as specified in RFC 8292.
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// recordSize is the size of the single record messages are sent in.
+	recordSize = 4096
+	// MaxPayloadSize is the size of the largest payload that fits in a
+	// message.
+	MaxPayloadSize = recordSize - 16 - 1
+	// tokenLifetime is how long VAPID tokens are valid.
+	tokenLifetime = 12 * time.Hour
+)
+
+var (
+	// ErrSubscriptionGone is returned when the push service no longer
+	// knows the subscription, which should be forgotten.
+	ErrSubscriptionGone = errors.New("push subscription expired or unsubscribed")
+	// ErrPayloadTooLarge is returned for payloads over MaxPayloadSize.
+	ErrPayloadTooLarge = errors.New("push payload too large")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Subscription is a push subscription of a browser, as returned by
+// PushSubscription.toJSON(): the push service endpoint and the keys of the
+// subscriber, base64url encoded.
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// GenerateVAPIDKeys returns a new base64url encoded VAPID key pair.
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("error generating vapid keys: %w", err)
+	}
+	return encoding.EncodeToString(key.PublicKey().Bytes()), encoding.EncodeToString(key.Bytes()), nil
+}
+
+// Sender sends push messages signed with a VAPID key.
+type Sender struct {
+	Client *http.Client
+	// Subject is the contact of the sender for push services, a mailto:
+	// or https: URL.
+	Subject string
+
+	key       *ecdsa.PrivateKey
+	publicKey []byte
+}
+
+// NewSender returns a sender using the base64url encoded VAPID private key.
+func NewSender(privateKey, subject string, client *http.Client) (*Sender, error) {
+	d, err := encoding.DecodeString(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+
+	// The public key is the uncompressed point 0x04 || X || Y
+	public := key.PublicKey().Bytes()
+	signer := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}
+	return &Sender{Client: client, Subject: subject, key: signer, publicKey: public}, nil
+}
+
+// PublicKey returns the base64url encoded VAPID public key, which browsers
+// subscribe with as applicationServerKey.
+func (s *Sender) PublicKey() string {
+	return encoding.EncodeToString(s.publicKey)
+}
+
+// Send encrypts payload for sub and posts it to its push service, which
+// keeps it for up to ttl while the browser is offline.
+func (s *Sender) Send(ctx context.Context, sub Subscription, payload []byte, ttl time.Duration) error {
+	body, err := Encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+	token, err := s.token(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid push endpoint: %w", err)
+	}
+	req.Header.Set("Authorization", "vapid t="+token+", k="+s.PublicKey())
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send push message: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("push service responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// token returns a VAPID token for the push service of endpoint.
+func (s *Sender) token(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid push endpoint %q", endpoint)
+	}
+
+	header := encoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(tokenLifetime).Unix(),
+		"sub": s.Subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + encoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign vapid token: %w", err)
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+// Encrypt encrypts payload for sub with the aes128gcm content encoding, as
+// a single record keyed by a new ephemeral key.
+func Encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+	uaPublic, err := encoding.DecodeString(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid push subscription key: %w", err)
+	}
+	authSecret, err := encoding.DecodeString(sub.Auth)
+	if err != nil || len(authSecret) == 0 {
+		return nil, fmt.Errorf("invalid push subscription auth secret")
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid push subscription key: %w", err)
+	}
+
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encrypt(uaKey, authSecret, asKey, salt, payload)
+}
+
+// encrypt encrypts payload for the subscriber key uaKey and authSecret with
+// the sender key asKey and salt.
+func encrypt(uaKey *ecdh.PublicKey, authSecret []byte, asKey *ecdh.PrivateKey, salt, payload []byte) ([]byte, error) {
+	uaPublic := uaKey.Bytes()
+	asPublic := asKey.PublicKey().Bytes()
+	ecdhSecret, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	cek, nonce := deriveKeys(ecdhSecret, authSecret, salt, uaPublic, asPublic)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header is salt || rs || idlen || keyid, and the only record is
+	// the payload followed by the last record delimiter
+	body := make([]byte, 0, 16+4+1+len(asPublic)+len(payload)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(asPublic)))
+	body = append(body, asPublic...)
+	return gcm.Seal(body, nonce, append(append([]byte{}, payload...), 2), nil), nil
+}
+
+// deriveKeys returns the content encryption key and nonce of a message
+// given the ECDH shared secret, as specified in RFC 8291 section 3.4.
+func deriveKeys(ecdhSecret, authSecret, salt, uaPublic, asPublic []byte) (cek, nonce []byte) {
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm := hkdf(authSecret, ecdhSecret, keyInfo, 32)
+	cek = hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce = hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+	return cek, nonce
+}
+
+// hkdf derives length bytes, at most 32, with HKDF-SHA-256.
+func hkdf(salt, ikm, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	expand := hmac.New(sha256.New, extract.Sum(nil))
+	expand.Write(info)
+	expand.Write([]byte{1})
+	return expand.Sum(nil)[:length]
+}
@@ -0,0 +1,158 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, s string) []byte {
+	b, err := encoding.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// TestEncrypt_RFC8291Vector checks the example of RFC 8291 appendix A.
+func TestEncrypt_RFC8291Vector(t *testing.T) {
+	asKey, err := ecdh.P256().NewPrivateKey(decode(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	require.NoError(t, err)
+	uaKey, err := ecdh.P256().NewPublicKey(decode(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"))
+	require.NoError(t, err)
+
+	body, err := encrypt(uaKey, decode(t, "BTBZMqHH6r4Tts7J_aSIgg"), asKey, decode(t, "DGv6ra1nlYgDCS1FRnbzlw"),
+		[]byte("When I grow up, I want to be a watermelon"))
+	require.NoError(t, err)
+	assert.Equal(t, "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN",
+		encoding.EncodeToString(body))
+}
+
+// subscriber is a browser subscribed to push messages.
+type subscriber struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newSubscriber(t *testing.T, endpoint string) (*subscriber, Subscription) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return &subscriber{key: key, auth: auth}, Subscription{
+		Endpoint: endpoint,
+		P256dh:   encoding.EncodeToString(key.PublicKey().Bytes()),
+		Auth:     encoding.EncodeToString(auth),
+	}
+}
+
+// decrypt decrypts a message as the browser does.
+func (s *subscriber) decrypt(t *testing.T, body []byte) []byte {
+	salt, rs, idlen := body[:16], binary.BigEndian.Uint32(body[16:20]), int(body[20])
+	assert.Equal(t, uint32(recordSize), rs)
+	asPublic := body[21 : 21+idlen]
+
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	require.NoError(t, err)
+	secret, err := s.key.ECDH(asKey)
+	require.NoError(t, err)
+	cek, nonce := deriveKeys(secret, s.auth, salt, s.key.PublicKey().Bytes(), asPublic)
+
+	block, err := aes.NewCipher(cek)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, body[21+idlen:], nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(2), plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
+
+// verifyToken checks a VAPID token with the public key k and returns its
+// claims.
+func verifyToken(t *testing.T, token, k string) map[string]interface{} {
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+
+	public := decode(t, k)
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(public[1:33]), Y: new(big.Int).SetBytes(public[33:])}
+	signature := decode(t, parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.True(t, ecdsa.Verify(key, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])))
+
+	var claims map[string]interface{}
+	require.NoError(t, json.Unmarshal(decode(t, parts[1]), &claims))
+	return claims
+}
+
+func TestSender_Send(t *testing.T) {
+	var received []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		header = r.Header
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	public, private, err := GenerateVAPIDKeys()
+	require.NoError(t, err)
+	sender, err := NewSender(private, "mailto:admin@example.com", server.Client())
+	require.NoError(t, err)
+	assert.Equal(t, public, sender.PublicKey())
+
+	browser, sub := newSubscriber(t, server.URL+"/push/abc")
+	require.NoError(t, sender.Send(context.Background(), sub, []byte(`{"title":"Buy milk"}`), time.Hour))
+
+	assert.Equal(t, `{"title":"Buy milk"}`, string(browser.decrypt(t, received)))
+	assert.Equal(t, "aes128gcm", header.Get("Content-Encoding"))
+	assert.Equal(t, "3600", header.Get("TTL"))
+
+	var token, k string
+	for _, param := range strings.Split(strings.TrimPrefix(header.Get("Authorization"), "vapid "), ", ") {
+		if strings.HasPrefix(param, "t=") {
+			token = param[2:]
+		} else if strings.HasPrefix(param, "k=") {
+			k = param[2:]
+		}
+	}
+	assert.Equal(t, public, k)
+	claims := verifyToken(t, token, k)
+	assert.Equal(t, server.URL, claims["aud"])
+	assert.Equal(t, "mailto:admin@example.com", claims["sub"])
+
+	_, sub = newSubscriber(t, server.URL+"/gone")
+	assert.ErrorIs(t, sender.Send(context.Background(), sub, []byte(`{}`), time.Hour), ErrSubscriptionGone)
+}
+
+func TestEncrypt_Invalid(t *testing.T) {
+	_, sub := newSubscriber(t, "https://push.example.com/abc")
+	_, err := Encrypt(sub, make([]byte, MaxPayloadSize+1))
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+
+	sub.P256dh = "not a key"
+	_, err = Encrypt(sub, []byte(`{}`))
+	assert.Error(t, err)
+
+	_, err = NewSender("short", "mailto:admin@example.com", http.DefaultClient)
+	assert.Error(t, err)
+}